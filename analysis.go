@@ -0,0 +1,222 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	minQuoteLength = 40
+	maxQuoteLength = 400
+	// contextLength is the number of bytes around a name used to guess its kind
+	contextLength = 30
+)
+
+// EntityKind is the kind of a named entity
+type EntityKind int
+
+const (
+	// EntityUnknown is a name that could be a person or a place
+	EntityUnknown EntityKind = iota
+	// EntityPerson is the name of a character
+	EntityPerson
+	// EntityPlace is the name of a place
+	EntityPlace
+)
+
+// Mention is a named entity found on a text by an EntityRecognizer
+type Mention struct {
+	Text string
+	Kind EntityKind
+	// Offset is the position in bytes of the mention on the text
+	Offset int
+}
+
+// EntityRecognizer finds the named entities of a text
+//
+// It is called with the text of each document of the spine, as returned by
+// Text. NameRecognizer is a simple implementation, an NLP library can be
+// plugged implementing this interface.
+type EntityRecognizer interface {
+	Recognize(text string) []Mention
+}
+
+// Entity is a named entity of the book with all the places it appears
+type Entity struct {
+	Text      string
+	Kind      EntityKind
+	Locations []Location
+}
+
+// Quote is a quotation of the text of the book
+type Quote struct {
+	Text     string
+	Location Location
+}
+
+// Analysis is the result of the content analysis of the book
+type Analysis struct {
+	// Entities are sorted by the number of times they appear
+	Entities []Entity
+	// Quotes are on the reading order
+	Quotes []Quote
+}
+
+// Analyze extracts the named entities and the quotes of the book
+//
+// The entities are found by the recognizer and the quotes are the text
+// between quotation marks long enough to be notable.
+func (e Epub) Analyze(recognizer EntityRecognizer) (*Analysis, error) {
+	var analysis Analysis
+	entities := make(map[Mention]*Entity)
+	var order []*Entity
+	for i := 0; i < e.opf.spineLength(); i++ {
+		href := e.opf.spineURL(i)
+		if href == "" {
+			continue
+		}
+		text, err := e.Text(i)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, mention := range recognizer.Recognize(text) {
+			location := Location{SpineIndex: i, Href: href, Offset: mention.Offset}
+			key := Mention{Text: mention.Text, Kind: mention.Kind}
+			entity, ok := entities[key]
+			if !ok {
+				entity = &Entity{Text: mention.Text, Kind: mention.Kind}
+				entities[key] = entity
+				order = append(order, entity)
+			}
+			entity.Locations = append(entity.Locations, location)
+		}
+		for _, quote := range findQuotes(text) {
+			quote.Location.SpineIndex = i
+			quote.Location.Href = href
+			analysis.Quotes = append(analysis.Quotes, quote)
+		}
+	}
+
+	for _, entity := range order {
+		analysis.Entities = append(analysis.Entities, *entity)
+	}
+	sort.SliceStable(analysis.Entities, func(i, j int) bool {
+		return len(analysis.Entities[i].Locations) > len(analysis.Entities[j].Locations)
+	})
+	return &analysis, nil
+}
+
+var quoteRegexp = regexp.MustCompile(`“([^”\n]+)”|"([^"\n]+)"|«([^»\n]+)»|„([^“\n]+)“`)
+
+func findQuotes(text string) []Quote {
+	var quotes []Quote
+	for _, loc := range quoteRegexp.FindAllStringSubmatchIndex(text, -1) {
+		for i := 2; i < len(loc); i += 2 {
+			if loc[i] == -1 {
+				continue
+			}
+			quote := strings.TrimSpace(text[loc[i]:loc[i+1]])
+			length := utf8.RuneCountInString(quote)
+			if length >= minQuoteLength && length <= maxQuoteLength {
+				quotes = append(quotes, Quote{Text: quote, Location: Location{Offset: loc[0]}})
+			}
+		}
+	}
+	return quotes
+}
+
+// NameRecognizer is an EntityRecognizer that finds the sequences of
+// capitalized words that are not at the beginning of a sentence
+//
+// The kind is guessed from the words around the name: titles like "Mr." or
+// verbs like "said" mark a person and prepositions like "in" a place. The
+// names found less than MinMentions times are discarded.
+type NameRecognizer struct {
+	MinMentions int
+}
+
+var (
+	nameRegexp    = regexp.MustCompile(`\p{Lu}[\p{L}'’-]+(?:[ \t]+\p{Lu}[\p{L}'’-]+)*`)
+	personBefore  = regexp.MustCompile(`\b(?:Mr|Mrs|Ms|Miss|Dr|Sir|Lady|Lord|Madame|Mme|Monsieur|Captain|Uncle|Aunt|said|asked|replied|answered|cried)\.?\s+$`)
+	personAfter   = regexp.MustCompile(`^,?\s+(?:said|asked|replied|answered|cried|told|thought|says|asks)\b`)
+	placeBefore   = regexp.MustCompile(`\b(?:in|at|to|from|near|towards|into)\s+(?:the\s+)?$`)
+	stopwordNames = map[string]bool{
+		"I": true, "The": true, "A": true, "An": true, "It": true, "He": true,
+		"She": true, "We": true, "They": true, "You": true, "But": true,
+		"And": true, "Or": true, "If": true, "So": true, "This": true,
+		"That": true, "These": true, "Those": true, "There": true, "Then": true,
+		"What": true, "When": true, "Where": true, "Who": true, "Why": true,
+		"How": true, "In": true, "On": true, "At": true, "Of": true, "To": true,
+		"My": true, "His": true, "Her": true, "Our": true, "Your": true,
+		"Their": true, "Its": true, "Yes": true, "No": true, "Oh": true,
+		"Chapter": true, "Book": true, "Section": true,
+	}
+)
+
+// Recognize implements EntityRecognizer
+func (n NameRecognizer) Recognize(text string) []Mention {
+	var mentions []Mention
+	count := make(map[string]int)
+	kinds := make(map[string]EntityKind)
+	// names only found at the beginning of sentences are not names
+	midSentence := make(map[string]bool)
+	for _, loc := range nameRegexp.FindAllStringIndex(text, -1) {
+		name := text[loc[0]:loc[1]]
+		before := text[:loc[0]]
+		if stopwordNames[name] || strings.ToUpper(name) == name {
+			continue
+		}
+		if len(before) > contextLength {
+			before = before[len(before)-contextLength:]
+		}
+		after := text[loc[1]:]
+		if len(after) > contextLength {
+			after = after[:contextLength]
+		}
+
+		kind := EntityUnknown
+		switch {
+		case personBefore.MatchString(before) || personAfter.MatchString(after):
+			kind = EntityPerson
+		case placeBefore.MatchString(before):
+			kind = EntityPlace
+		}
+		if kinds[name] == EntityUnknown {
+			kinds[name] = kind
+		}
+		if !sentenceStart(text[:loc[0]]) || kind == EntityPerson {
+			midSentence[name] = true
+		}
+		count[name]++
+		mentions = append(mentions, Mention{Text: name, Offset: loc[0]})
+	}
+
+	var result []Mention
+	for _, m := range mentions {
+		if midSentence[m.Text] && count[m.Text] >= n.MinMentions {
+			m.Kind = kinds[m.Text]
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// sentenceStart returns whether a word after the text would start a sentence
+func sentenceStart(text string) bool {
+	trimmed := strings.TrimRightFunc(text, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(`"“«„'‘(`, r)
+	})
+	if trimmed == "" || strings.Contains(text[len(trimmed):], "\n") {
+		return true
+	}
+	last, _ := utf8.DecodeLastRuneInString(trimmed)
+	return strings.ContainsRune(".!?:;—", last)
+}
@@ -0,0 +1,83 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import "strings"
+
+const (
+	analysisText = `Mrs. Gray was thirty. We went to London in the spring.
+Sadie said that Mrs. Gray loved London, and Sadie laughed.
+“It is such a charming home, with pictures and delicate decorations,” said Sadie.`
+)
+
+func TestExtractText(t *testing.T) {
+	html := `<html><head><title>T</title><style>p {}</style></head><body>
+<h1>Chapter&nbsp;I</h1>
+<p>First   <i>paragraph</i>
+ text.</p><p>Second &amp; last.</p></body></html>`
+	expected := "Chapter I\nFirst paragraph text.\nSecond & last."
+	if text := extractText([]byte(html)); text != expected {
+		t.Errorf("extractText() return: %q when was expected: %q", text, expected)
+	}
+}
+
+func TestText(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	text, err := f.Text(1)
+	if err != nil {
+		t.Fatalf("Text() return an error: %v", err)
+	}
+	if !strings.Contains(text, "Aileen Mavourneen") || strings.Contains(text, "<p>") {
+		t.Errorf("Text() return: %v", text)
+	}
+	if _, err := f.Text(10); err == nil {
+		t.Errorf("Text(10) didn't return an error")
+	}
+}
+
+func TestNameRecognizer(t *testing.T) {
+	mentions := NameRecognizer{MinMentions: 2}.Recognize(analysisText)
+	kinds := make(map[string]EntityKind)
+	for _, m := range mentions {
+		kinds[m.Text] = m.Kind
+		if !strings.HasPrefix(analysisText[m.Offset:], m.Text) {
+			t.Errorf("Wrong offset for %v", m.Text)
+		}
+	}
+	if kinds["Gray"] != EntityPerson || kinds["London"] != EntityPlace || kinds["Sadie"] != EntityPerson {
+		t.Errorf("Recognize() return: %v", mentions)
+	}
+	if _, ok := kinds["We"]; ok {
+		t.Errorf("Recognize() return a sentence start: %v", mentions)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	analysis, err := f.Analyze(NameRecognizer{MinMentions: 3})
+	if err != nil {
+		t.Fatalf("Analyze() return an error: %v", err)
+	}
+	if len(analysis.Entities) == 0 || len(analysis.Quotes) == 0 {
+		t.Fatalf("Analyze() return: %v", analysis)
+	}
+	entity := analysis.Entities[0]
+	for _, other := range analysis.Entities {
+		if len(other.Locations) > len(entity.Locations) {
+			t.Errorf("The entities are not sorted")
+		}
+	}
+	quote := analysis.Quotes[0]
+	text, _ := f.Text(quote.Location.SpineIndex)
+	if !strings.Contains(text[quote.Location.Offset:], quote.Text) {
+		t.Errorf("Wrong quote location: %v", quote)
+	}
+}
@@ -0,0 +1,248 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"regexp"
+	"strings"
+)
+
+var indexTypeRegexp = regexp.MustCompile(`\s[\w-]+:type\s*=\s*["'](?:[^"']*\s)?index[\s"']`)
+
+// BookIndex is the back-of-book index of a book
+type BookIndex struct {
+	// Entries are the top level entries in the order of the index documents
+	Entries []IndexEntry
+}
+
+// IndexEntry is an entry of the back-of-book index
+type IndexEntry struct {
+	Term string
+	// Group is the heading of the group of the entry, usually its initial
+	// letter, empty if the index is not grouped
+	Group    string
+	Locators []IndexLocator
+	// See are the preferred terms of the cross references ("see") and
+	// SeeAlso the related ones ("see also")
+	See        []string
+	SeeAlso    []string
+	Subentries []IndexEntry
+}
+
+// IndexLocator is a location of an entry of the index
+type IndexLocator struct {
+	// Label is the text of the locator, usually a page number
+	Label string
+	// Href is the location, a path as used by OpenFile followed by a fragment
+	Href string
+	// End is the location of the end of a range of pages, empty if the
+	// locator is not a range
+	End string
+}
+
+// indexElement is an element open while parsing an index document
+type indexElement struct {
+	name  string
+	tag   string
+	types []string
+	// start is the position of the content of the element
+	start int
+	// locators is the number of locators of the entry when the element was
+	// open
+	locators int
+}
+
+// BookIndex returns the back-of-book index of the book, or nil if it has
+// none
+//
+// The index is parsed from the documents of the spine with an
+// epub:type="index" element and the manifest items with the index property,
+// as defined on EPUB Indexes. The index-entry, index-term, index-locator,
+// index-locator-range, index-xref-preferred, index-xref-related and
+// index-group semantics are used, the documents without them have no
+// entries.
+func (e Epub) BookIndex() (*BookIndex, error) {
+	var docs []string
+	for i := 0; i < e.opf.spineLength(); i++ {
+		if href := e.opf.spineURL(i); href != "" {
+			docs = append(docs, href)
+		}
+	}
+	indexDocs := make(map[string]bool)
+	for _, item := range e.opf.Manifest {
+		if !hasProperty(item.Properties, "index") {
+			continue
+		}
+		indexDocs[item.Href] = true
+		if !contains(docs, item.Href) {
+			docs = append(docs, item.Href)
+		}
+	}
+
+	var index *BookIndex
+	for _, href := range docs {
+		data, err := e.readFile(e.rootPath + href)
+		if err != nil {
+			return nil, err
+		}
+		if !indexDocs[href] && !indexTypeRegexp.Match(data) {
+			continue
+		}
+		if index == nil {
+			index = &BookIndex{}
+		}
+		index.Entries = append(index.Entries, e.parseIndex(data, href)...)
+	}
+	return index, nil
+}
+
+// Lookup returns the entries of the term, compared without case
+//
+// The subentries are found by their term or by the terms of their entries
+// joined by commas, like "birds, migration".
+func (idx BookIndex) Lookup(term string) []IndexEntry {
+	key := indexKey(term)
+	var found []IndexEntry
+	var walk func(entries []IndexEntry, path string)
+	walk = func(entries []IndexEntry, path string) {
+		for _, entry := range entries {
+			entryPath := indexKey(entry.Term)
+			if path != "" {
+				entryPath = path + ", " + entryPath
+			}
+			if indexKey(entry.Term) == key || entryPath == key {
+				found = append(found, entry)
+			}
+			walk(entry.Subentries, entryPath)
+		}
+	}
+	walk(idx.Entries, "")
+	return found
+}
+
+// Terms returns the locators of each term of the index, the subentries by
+// the terms of their entries joined by commas
+func (idx BookIndex) Terms() map[string][]IndexLocator {
+	terms := make(map[string][]IndexLocator)
+	var walk func(entries []IndexEntry, path string)
+	walk = func(entries []IndexEntry, path string) {
+		for _, entry := range entries {
+			entryPath := entry.Term
+			if path != "" {
+				entryPath = path + ", " + entryPath
+			}
+			terms[entryPath] = append(terms[entryPath], entry.Locators...)
+			walk(entry.Subentries, entryPath)
+		}
+	}
+	walk(idx.Entries, "")
+	return terms
+}
+
+// parseIndex returns the entries of the index document data
+func (e Epub) parseIndex(data []byte, docPath string) []IndexEntry {
+	var entries []IndexEntry
+	var stack []indexElement
+	var open []*IndexEntry
+	group := ""
+	inGroup := func() bool {
+		for _, el := range stack {
+			if contains(el.types, "index-group") {
+				return true
+			}
+		}
+		return false
+	}
+
+	closeElement := func(el indexElement, end int) {
+		content := data[el.start:end]
+		var entry *IndexEntry
+		if len(open) > 0 {
+			entry = open[len(open)-1]
+		}
+		if isHeading(el.name) && group == "" && len(open) == 0 && inGroup() {
+			group = cellText(content)
+		}
+		for _, t := range el.types {
+			switch {
+			case t == "index-group":
+				group = ""
+			case t == "index-entry" && entry != nil:
+				open = open[:len(open)-1]
+				if len(open) > 0 {
+					parent := open[len(open)-1]
+					parent.Subentries = append(parent.Subentries, *entry)
+				} else {
+					entries = append(entries, *entry)
+				}
+			case entry == nil:
+			case t == "index-term" && entry.Term == "":
+				entry.Term = cellText(content)
+			case t == "index-locator":
+				href := ""
+				if ref := attrValue(el.tag, "href"); ref != "" {
+					href = e.ResolveHref(docPath, ref)
+				}
+				entry.Locators = append(entry.Locators, IndexLocator{Label: cellText(content), Href: href})
+			case t == "index-locator-range" && len(entry.Locators) >= el.locators+2:
+				locators := entry.Locators[el.locators:]
+				first := locators[0]
+				first.Label = cellText(content)
+				first.End = locators[len(locators)-1].Href
+				entry.Locators = append(entry.Locators[:el.locators], first)
+			case t == "index-xref-preferred":
+				entry.See = append(entry.See, cellText(content))
+			case t == "index-xref-related":
+				entry.SeeAlso = append(entry.SeeAlso, cellText(content))
+			}
+		}
+	}
+
+	for _, loc := range htmlTagRegexp.FindAllSubmatchIndex(data, -1) {
+		closing := loc[3] > loc[2]
+		selfClosing := loc[7] > loc[6]
+		name := strings.ToLower(string(data[loc[4]:loc[5]]))
+		name = name[strings.Index(name, ":")+1:]
+		tag := string(data[loc[0]:loc[1]])
+
+		if closing {
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i].name != name {
+					continue
+				}
+				for j := len(stack) - 1; j >= i; j-- {
+					closeElement(stack[j], loc[0])
+				}
+				stack = stack[:i]
+				break
+			}
+			continue
+		}
+		if selfClosing || voidElements[name] {
+			continue
+		}
+		if len(stack) > maxNestingDepth {
+			return entries
+		}
+
+		el := indexElement{name: name, tag: tag, types: strings.Fields(attrValue(tag, "epub:type")), start: loc[1]}
+		if len(open) > 0 {
+			el.locators = len(open[len(open)-1].Locators)
+		}
+		if contains(el.types, "index-entry") {
+			open = append(open, &IndexEntry{Group: group})
+			if len(open) > 1 {
+				open[len(open)-1].Group = open[0].Group
+			}
+		}
+		stack = append(stack, el)
+	}
+	return entries
+}
+
+// indexKey returns the term normalized to be compared
+func indexKey(term string) string {
+	return strings.ToLower(strings.Join(strings.Fields(term), " "))
+}
@@ -0,0 +1,80 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+const indexDocument = `<html xmlns:epub="http://www.idpf.org/2007/ops"><body>
+<section epub:type="index">
+<h1>Index</h1>
+<div epub:type="index-group"><h2>B</h2>
+<ul epub:type="index-entry-list">
+<li epub:type="index-entry"><span epub:type="index-term">Birds</span>,
+  <a epub:type="index-locator" href="ch1.xhtml#p3">3</a>,
+  <span epub:type="index-locator-range"><a epub:type="index-locator" href="ch1.xhtml#p10">10</a>–<a epub:type="index-locator" href="ch2.xhtml#p15">15</a></span>
+  <ul epub:type="index-entry-list">
+  <li epub:type="index-entry"><span epub:type="index-term">migration</span>, <a epub:type="index-locator" href="ch2.xhtml#p12">12</a>
+    <span>see also <a epub:type="index-xref-related" href="#idx-seasons">Seasons</a></span></li>
+  </ul>
+</li>
+</ul>
+</div>
+<div epub:type="index-group"><h2>F</h2>
+<ul epub:type="index-entry-list">
+<li epub:type="index-entry"><span epub:type="index-term">Fowl</span>, <i>see</i> <a epub:type="index-xref-preferred" href="#idx-birds">Birds</a></li>
+</ul>
+</div>
+</section>
+</body></html>`
+
+func TestBookIndex(t *testing.T) {
+	f := buildEpub(t, epub3OPF, map[string]string{
+		"nav.xhtml":      epub3Nav,
+		"text/ch1.xhtml": `<html><body><p>Chapter 1</p></body></html>`,
+		"text/ch2.xhtml": indexDocument,
+	})
+	index, err := f.BookIndex()
+	if err != nil {
+		t.Fatalf("BookIndex() return an error: %v", err)
+	}
+	if index == nil || len(index.Entries) != 2 {
+		t.Fatalf("BookIndex() return: %v", index)
+	}
+
+	birds := index.Entries[0]
+	if birds.Term != "Birds" || birds.Group != "B" || len(birds.Locators) != 2 {
+		t.Errorf("Wrong entry: %v", birds)
+	}
+	if birds.Locators[1] != (IndexLocator{"10–15", "text/ch1.xhtml#p10", "text/ch2.xhtml#p15"}) {
+		t.Errorf("Wrong range: %v", birds.Locators[1])
+	}
+	if len(birds.Subentries) != 1 || birds.Subentries[0].Group != "B" || len(birds.Subentries[0].SeeAlso) != 1 {
+		t.Errorf("Wrong subentries: %v", birds.Subentries)
+	}
+	if fowl := index.Entries[1]; fowl.Group != "F" || len(fowl.See) != 1 || fowl.See[0] != "Birds" {
+		t.Errorf("Wrong entry: %v", fowl)
+	}
+
+	entries := index.Lookup("birds,  Migration")
+	if len(entries) != 1 || entries[0].Locators[0].Href != "text/ch2.xhtml#p12" {
+		t.Errorf("Lookup() return: %v", entries)
+	}
+	if entries := index.Lookup("migration"); len(entries) != 1 {
+		t.Errorf("Lookup() return: %v", entries)
+	}
+	terms := index.Terms()
+	if len(terms) != 3 || len(terms["Birds, migration"]) != 1 || len(terms["Fowl"]) != 0 {
+		t.Errorf("Terms() return: %v", terms)
+	}
+}
+
+func TestBookIndexNone(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	if index, err := f.BookIndex(); index != nil || err != nil {
+		t.Errorf("BookIndex() return: %v, %v", index, err)
+	}
+}
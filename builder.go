@@ -0,0 +1,101 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"time"
+)
+
+const newContainer = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+const newPackage = `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="uid">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="uid">%s</dc:identifier>
+    <dc:title>%s</dc:title>
+    <dc:language>%s</dc:language>
+    <meta property="dcterms:modified">%s</meta>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+  </manifest>
+  <spine>
+  </spine>
+</package>
+`
+
+const newNav = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="%[1]s" lang="%[1]s">
+  <head>
+    <title>%[2]s</title>
+  </head>
+  <body>
+    <nav epub:type="toc">
+      <h1>%[2]s</h1>
+      <ol>
+      </ol>
+    </nav>
+  </body>
+</html>
+`
+
+// New returns an empty EPUB 3 book with the identifier, title and language
+//
+// The book has only a navigation document with an empty table of contents.
+// The content is added with InsertDocument, AddPage, AddResource, ... and it
+// is written with Repack. An empty identifier is replaced by a random UUID.
+func New(identifier, title, lang string) (*Epub, error) {
+	if identifier == "" {
+		identifier = newUUID()
+	}
+	modified := time.Now().UTC().Format("2006-01-02T15:04:05Z")
+
+	var buff bytes.Buffer
+	w := zip.NewWriter(&buff)
+	files := []struct {
+		name string
+		data string
+	}{
+		{"META-INF/container.xml", newContainer},
+		{"OEBPS/content.opf", fmt.Sprintf(newPackage, escapeXML(identifier), escapeXML(title), escapeXML(lang), modified)},
+		{"OEBPS/nav.xhtml", fmt.Sprintf(newNav, escapeXML(lang), escapeXML(title))},
+	}
+	mimetype, err := w.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return nil, err
+	}
+	mimetype.Write([]byte("application/epub+zip"))
+	for _, file := range files {
+		f, err := w.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+		f.Write([]byte(file.data))
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return Load(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+}
+
+// newUUID returns a random UUID as urn:uuid
+func newUUID() string {
+	var uuid [16]byte
+	rand.Read(uuid[:])
+	uuid[6] = uuid[6]&0x0f | 0x40
+	uuid[8] = uuid[8]&0x3f | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:])
+}
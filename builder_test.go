@@ -0,0 +1,33 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import "strings"
+
+func TestNew(t *testing.T) {
+	f, err := New("", "A & B", "en")
+	if err != nil {
+		t.Fatalf("New() return an error: %v", err)
+	}
+	if err := f.InsertDocument("text/ch1.xhtml", "Chapter 1", []byte(`<html><body><p>Text</p></body></html>`), -1); err != nil {
+		t.Fatalf("InsertDocument() return an error: %v", err)
+	}
+
+	book := repackBook(t, f)
+	if title, _ := book.Metadata("title"); len(title) != 1 || title[0] != "A & B" {
+		t.Errorf("The title is: %v", title)
+	}
+	if id, _ := book.Metadata("identifier"); len(id) != 1 || !strings.HasPrefix(id[0], "urn:uuid:") || len(id[0]) != 45 {
+		t.Errorf("The identifier is: %v", id)
+	}
+	if text, err := book.Text(0); err != nil || text != "Text" {
+		t.Errorf("Text() return: %v, %v", text, err)
+	}
+	if nav := readBookFile(t, book, "nav.xhtml"); !strings.Contains(nav, `<a href="text/ch1.xhtml">Chapter 1</a>`) {
+		t.Errorf("The navigation document is: %v", nav)
+	}
+}
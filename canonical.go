@@ -0,0 +1,252 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+)
+
+const canonicalIndent = "  "
+
+// Canonicalize returns a transform that formats the OPF and the NCX
+// canonically, so the packages generated from the same metadata are byte
+// identical and their diffs are reviewable
+//
+// The files get an UTF-8 XML declaration and are indented by two spaces, the
+// elements with text keep their content as is. The attributes are sorted by
+// name after the namespace declarations, and the namespace prefixes declared
+// with the same URI all over the file are declared on the root element.
+// Comments, processing instructions and the doctype are kept.
+func (e Epub) Canonicalize() Transform {
+	ncxPath := ""
+	if path := e.opf.ncxPath(); path != "" {
+		ncxPath = e.rootPath + path
+	}
+	return func(name, mediaType string, r io.Reader) (io.Reader, error) {
+		if name != e.opfPath && name != ncxPath {
+			return r, nil
+		}
+		data, err := readAll(r)
+		if err != nil {
+			return nil, err
+		}
+		canonical, err := canonicalXML(data)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(canonical), nil
+	}
+}
+
+// xmlNode is a node of the tree built by canonicalXML, an element or the
+// raw XML of a text, comment or processing instruction
+type xmlNode struct {
+	name     string
+	attrs    []xml.Attr
+	children []*xmlNode
+	raw      string
+	text     bool
+}
+
+func canonicalXML(data []byte) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+
+	var (
+		prolog []*xmlNode
+		root   *xmlNode
+		stack  []*xmlNode
+	)
+	add := func(n *xmlNode) {
+		if len(stack) == 0 {
+			if !n.text {
+				prolog = append(prolog, n)
+			}
+			return
+		}
+		parent := stack[len(stack)-1]
+		parent.children = append(parent.children, n)
+	}
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		raw := string(data[offset:decoder.InputOffset()])
+		switch t := token.(type) {
+		case xml.StartElement:
+			n := &xmlNode{name: qualifiedName(t.Name), attrs: t.Attr}
+			if root == nil {
+				root = n
+			} else if len(stack) == 0 {
+				return nil, errors.New("More than one root element")
+			}
+			add(n)
+			stack = append(stack, n)
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			add(&xmlNode{raw: raw, text: true})
+		case xml.ProcInst:
+			if t.Target != "xml" {
+				add(&xmlNode{raw: raw})
+			}
+		case xml.Comment, xml.Directive:
+			add(&xmlNode{raw: raw})
+		}
+	}
+	if root == nil {
+		return nil, errors.New("No root element")
+	}
+	hoistNamespaces(root)
+
+	var buff bytes.Buffer
+	buff.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	for _, n := range prolog {
+		if n == root {
+			writeCanonical(&buff, n, 0, false)
+		} else {
+			buff.WriteString(n.raw)
+		}
+		buff.WriteString("\n")
+	}
+	return buff.Bytes(), nil
+}
+
+// writeCanonical writes the element n, indented by depth unless it is inline
+// on the text of its parent
+func writeCanonical(buff *bytes.Buffer, n *xmlNode, depth int, inline bool) {
+	if n.name == "" {
+		buff.WriteString(n.raw)
+		return
+	}
+
+	buff.WriteString("<" + n.name)
+	for _, attr := range sortedAttrs(n.attrs) {
+		buff.WriteString(" " + qualifiedName(attr.Name) + `="` + escapeAttr(attr.Value) + `"`)
+	}
+	var children []*xmlNode
+	hasText := false
+	for _, child := range n.children {
+		if child.text && strings.TrimSpace(child.raw) == "" && !inline {
+			continue
+		}
+		hasText = hasText || child.text
+		children = append(children, child)
+	}
+	if len(children) == 0 {
+		buff.WriteString("/>")
+		return
+	}
+	buff.WriteString(">")
+
+	if inline || hasText {
+		for _, child := range n.children {
+			writeCanonical(buff, child, depth, true)
+		}
+	} else {
+		for _, child := range children {
+			buff.WriteString("\n" + strings.Repeat(canonicalIndent, depth+1))
+			writeCanonical(buff, child, depth+1, false)
+		}
+		buff.WriteString("\n" + strings.Repeat(canonicalIndent, depth))
+	}
+	buff.WriteString("</" + n.name + ">")
+}
+
+// sortedAttrs returns the attributes with the default namespace declaration
+// first, then the prefixed declarations and the rest sorted by name
+func sortedAttrs(attrs []xml.Attr) []xml.Attr {
+	sorted := append([]xml.Attr(nil), attrs...)
+	rank := func(a xml.Attr) int {
+		switch {
+		case a.Name.Space == "" && a.Name.Local == "xmlns":
+			return 0
+		case a.Name.Space == "xmlns":
+			return 1
+		}
+		return 2
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ri, rj := rank(sorted[i]), rank(sorted[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return qualifiedName(sorted[i].Name) < qualifiedName(sorted[j].Name)
+	})
+	return sorted
+}
+
+// hoistNamespaces moves to the root the declarations of the prefixes bound
+// to a single URI on the whole document
+func hoistNamespaces(root *xmlNode) {
+	uris := make(map[string]map[string]bool)
+	var collect func(n *xmlNode)
+	collect = func(n *xmlNode) {
+		for _, attr := range n.attrs {
+			if attr.Name.Space == "xmlns" {
+				if uris[attr.Name.Local] == nil {
+					uris[attr.Name.Local] = make(map[string]bool)
+				}
+				uris[attr.Name.Local][attr.Value] = true
+			}
+		}
+		for _, child := range n.children {
+			collect(child)
+		}
+	}
+	collect(root)
+
+	hoisted := make(map[string]string)
+	for prefix, values := range uris {
+		if len(values) == 1 {
+			for uri := range values {
+				hoisted[prefix] = uri
+			}
+		}
+	}
+	var strip func(n *xmlNode)
+	strip = func(n *xmlNode) {
+		var attrs []xml.Attr
+		for _, attr := range n.attrs {
+			if attr.Name.Space != "xmlns" || hoisted[attr.Name.Local] == "" {
+				attrs = append(attrs, attr)
+			}
+		}
+		n.attrs = attrs
+		for _, child := range n.children {
+			strip(child)
+		}
+	}
+	strip(root)
+	for prefix, uri := range hoisted {
+		root.attrs = append(root.attrs, xml.Attr{Name: xml.Name{Space: "xmlns", Local: prefix}, Value: uri})
+	}
+}
+
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+var attrEscaper = strings.NewReplacer(`&`, "&amp;", `<`, "&lt;", `>`, "&gt;", `"`, "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
+
+func escapeAttr(s string) string {
+	return attrEscaper.Replace(s)
+}
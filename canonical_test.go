@@ -0,0 +1,78 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import "bytes"
+
+const messyOPF = `<?xml version='1.0' encoding='utf-8'?>
+<package version="2.0" xmlns="http://www.idpf.org/2007/opf" unique-identifier="uid"><metadata>
+<dc:title xmlns:dc="http://purl.org/dc/elements/1.1/">A &amp; B</dc:title>  <!-- note -->
+        <dc:creator xmlns:dc="http://purl.org/dc/elements/1.1/" opf:role="aut" xmlns:opf="http://www.idpf.org/2007/opf" opf:file-as="Doe, Jane">Jane <i>Doe</i></dc:creator>
+</metadata>
+<manifest><item media-type="application/xhtml+xml" id="ch1" href="ch1.xhtml"></item></manifest>
+<spine toc="ncx"><itemref idref="ch1" /></spine></package>
+`
+
+const canonicalOPF = `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:opf="http://www.idpf.org/2007/opf" unique-identifier="uid" version="2.0">
+  <metadata>
+    <dc:title>A &amp; B</dc:title>
+    <!-- note -->
+    <dc:creator opf:file-as="Doe, Jane" opf:role="aut">Jane <i>Doe</i></dc:creator>
+  </metadata>
+  <manifest>
+    <item href="ch1.xhtml" id="ch1" media-type="application/xhtml+xml"/>
+  </manifest>
+  <spine toc="ncx">
+    <itemref idref="ch1"/>
+  </spine>
+</package>
+`
+
+func TestCanonicalXML(t *testing.T) {
+	canonical, err := canonicalXML([]byte(messyOPF))
+	if err != nil {
+		t.Fatalf("canonicalXML() return an error: %v", err)
+	}
+	if string(canonical) != canonicalOPF {
+		t.Errorf("canonicalXML() return:\n%s", canonical)
+	}
+	again, _ := canonicalXML(canonical)
+	if !bytes.Equal(again, canonical) {
+		t.Errorf("canonicalXML() is not idempotent:\n%s", again)
+	}
+	if _, err := canonicalXML([]byte("<a/><b/>")); err == nil {
+		t.Errorf("canonicalXML() with two roots didn't return an error")
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	book := repackBook(t, f, f.Canonicalize())
+	opf, _ := book.readFile(book.opfPath)
+	if canonical, _ := canonicalXML(opf); !bytes.Equal(canonical, opf) {
+		t.Errorf("The OPF is not canonical:\n%s", opf)
+	}
+	ncx, _ := book.readFile(book.rootPath + book.opf.ncxPath())
+	if canonical, _ := canonicalXML(ncx); !bytes.Equal(canonical, ncx) {
+		t.Errorf("The NCX is not canonical:\n%s", ncx)
+	}
+	if title, _ := book.Metadata("title"); title[0] != bookTitle {
+		t.Errorf("Metadata title '%v', the expected was '%v'", title[0], bookTitle)
+	}
+	if _, err := book.Navigation(); err != nil {
+		t.Errorf("Navigation() return an error: %v", err)
+	}
+
+	again := repackBook(t, book, book.Canonicalize())
+	opf2, _ := again.readFile(again.opfPath)
+	if !bytes.Equal(opf, opf2) {
+		t.Errorf("The canonical OPF changed on the second repack")
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"errors"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	cfiRegexp     = regexp.MustCompile(`^epubcfi\(/6/(\d+)(?:\[(?:[^\]^]|\^.)*\])?!((?:/\d+(?:\[(?:[^\]^]|\^.)*\])?)*)(?::(\d+))?\)$`)
+	cfiStepRegexp = regexp.MustCompile(`/(\d+)(?:\[(?:[^\]^]|\^.)*\])?`)
+)
+
+// LocationCFI returns the EPUB canonical fragment identifier of the location
+//
+// The CFI points to the text node of the location and the character on it,
+// like "epubcfi(/6/4[chap01]!/4[body01]/10/3:12)", with the ids of the
+// elements as assertions. The offsets of the characters are in UTF-16 code
+// units of the text as on the DOM, as the reading systems count them. A
+// document without text gets a CFI of the document.
+func (e Epub) LocationCFI(loc Location) (string, error) {
+	if loc.SpineIndex < 0 || loc.SpineIndex >= e.opf.spineLength() {
+		return "", errors.New("Spine index out of range")
+	}
+	data, err := e.spineDocument(loc.SpineIndex)
+	if err != nil {
+		return "", err
+	}
+	cfi := "epubcfi(/6/" + strconv.Itoa((loc.SpineIndex+1)*2) +
+		"[" + cfiEscape(e.opf.Spine.Items[loc.SpineIndex].IDref) + "]!"
+
+	_, spans := layoutText(data)
+	paths := cfiPaths(data, spans)
+	for i := len(spans) - 1; i >= 0; i-- {
+		if spans[i].offset <= loc.Offset || i == 0 {
+			return cfi + paths[i] + ":" + strconv.Itoa(spans[i].domOffset(data, loc.Offset)) + ")", nil
+		}
+	}
+	return cfi + ")", nil
+}
+
+// CFILocation returns the location of the EPUB canonical fragment identifier
+//
+// It is the inverse of LocationCFI. The assertions of the CFI are not
+// checked, the steps are followed. A CFI pointing to an element is located
+// on the beginning of its text.
+func (e Epub) CFILocation(cfi string) (Location, error) {
+	match := cfiRegexp.FindStringSubmatch(cfi)
+	if match == nil {
+		return Location{}, errors.New("Invalid CFI " + cfi)
+	}
+	step, _ := strconv.Atoi(match[1])
+	index := step/2 - 1
+	if step%2 != 0 || index < 0 || index >= e.opf.spineLength() || e.opf.spineURL(index) == "" {
+		return Location{}, errors.New("Invalid CFI " + cfi)
+	}
+	loc := Location{SpineIndex: index, Href: e.opf.spineURL(index)}
+	data, err := e.spineDocument(index)
+	if err != nil {
+		return Location{}, err
+	}
+
+	path := cfiStepRegexp.ReplaceAllString(match[2], "/$1")
+	_, spans := layoutText(data)
+	for i, p := range cfiPaths(data, spans) {
+		p = cfiStepRegexp.ReplaceAllString(p, "/$1")
+		if p == path {
+			units, _ := strconv.Atoi(match[3])
+			loc.Offset = spans[i].textOffset(data, units)
+			return loc, nil
+		}
+		if path != "" && strings.HasPrefix(p, path+"/") {
+			loc.Offset = spans[i].offset
+			return loc, nil
+		}
+	}
+	return loc, nil
+}
+
+// cfiPaths returns the CFI steps inside the document of each text span,
+// like "/4[body01]/10/3"
+//
+// The steps are counted on the elements, the even ones, and the chunks of
+// text between them, the odd ones. The root element has no step.
+func cfiPaths(data []byte, spans []textSpan) []string {
+	type level struct {
+		name     string
+		path     string
+		children int
+	}
+	levels := []level{{}}
+	paths := make([]string, len(spans))
+	tags := htmlTagRegexp.FindAllSubmatchIndex(data, -1)
+	t := 0
+	for i, span := range spans {
+		for ; t < len(tags) && tags[t][0] < span.start; t++ {
+			loc := tags[t]
+			name := strings.ToLower(string(data[loc[4]:loc[5]]))
+			name = name[strings.Index(name, ":")+1:]
+			if loc[3] > loc[2] {
+				for j := len(levels) - 1; j > 0; j-- {
+					if levels[j].name == name {
+						levels = levels[:j]
+						break
+					}
+				}
+				continue
+			}
+
+			top := &levels[len(levels)-1]
+			top.children++
+			path := ""
+			if len(levels) > 1 {
+				path = top.path + "/" + strconv.Itoa(top.children*2)
+				if id := attrValue(string(data[loc[0]:loc[1]]), "id"); id != "" {
+					path += "[" + cfiEscape(id) + "]"
+				}
+			}
+			if loc[7] == loc[6] && !voidElements[name] {
+				levels = append(levels, level{name: name, path: path})
+			}
+		}
+		top := levels[len(levels)-1]
+		paths[i] = top.path + "/" + strconv.Itoa(top.children*2+1)
+	}
+	return paths
+}
+
+// cfiEscape escapes the special characters of the CFI assertions
+func cfiEscape(s string) string {
+	return strings.NewReplacer("^", "^^", "[", "^[", "]", "^]", "(", "^(", ")", "^)",
+		",", "^,", ";", "^;", "=", "^=").Replace(s)
+}
+
+// domOffset returns the offset in UTF-16 code units on the DOM text of the
+// span of the position offset of the text of the document
+func (s textSpan) domOffset(data []byte, offset int) int {
+	result := 0
+	s.walk(data, func(norm, units int) bool {
+		result = units
+		return s.offset+norm >= offset
+	})
+	return result
+}
+
+// textOffset returns the position on the text of the document of the offset
+// in UTF-16 code units on the DOM text of the span
+func (s textSpan) textOffset(data []byte, units int) int {
+	result := s.offset
+	s.walk(data, func(norm, u int) bool {
+		result = s.offset + norm
+		return u >= units
+	})
+	return result
+}
+
+// walk calls fn with the position of each character of the span that is on
+// the text of the document, relative to the span offset, and its position on
+// the DOM text, until fn returns true. The whitespace collapsed is skipped.
+func (s textSpan) walk(data []byte, fn func(norm, units int) bool) {
+	norm, units := 0, 0
+	space := s.trimmed
+	for _, r := range html.UnescapeString(string(data[s.start:s.end])) {
+		isSpace := strings.ContainsRune(" \t\n\f\r", r)
+		if !isSpace || !space {
+			if fn(norm, units) {
+				return
+			}
+			if isSpace {
+				norm++
+			} else {
+				norm += utf8.RuneLen(r)
+			}
+		}
+		space = isSpace
+		units++
+		if r >= 0x10000 {
+			units++
+		}
+	}
+	fn(norm, units)
+}
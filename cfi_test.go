@@ -0,0 +1,58 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"strconv"
+	"strings"
+)
+
+func TestLocationCFI(t *testing.T) {
+	f, _ := New("", "Title", "en")
+	f.InsertDocument("text/ch1.xhtml", "Chapter 1", []byte(`<html><head><title>One</title></head>
+<body id="body01">
+  <h1>Chapter   1</h1>
+  <p id="p[1]">Tom &amp; Jerry <b>run</b>
+    away.</p>
+  <img src="a.png"/>
+  <p>The 𝄞 end</p>
+</body></html>`), -1)
+	book := repackBook(t, f)
+	index := book.spineIndexOf("text/ch1.xhtml")
+	text, _ := book.Text(index)
+	prefix := "epubcfi(/6/" + strconv.Itoa((index+1)*2) + "[" + book.opf.Spine.Items[index].IDref + "]!"
+
+	tests := []struct {
+		word string
+		cfi  string
+	}{
+		{"Chapter", "/4[body01]/2/1:0"},
+		{"1", "/4[body01]/2/1:10"},
+		{"Jerry", "/4[body01]/4[p^[1^]]/1:6"},
+		{"run", "/4[body01]/4[p^[1^]]/2/1:0"},
+		{"away", "/4[body01]/4[p^[1^]]/3:5"},
+		{"end", "/4[body01]/8/1:7"},
+	}
+	for _, test := range tests {
+		offset := strings.Index(text, test.word)
+		cfi, err := book.LocationCFI(Location{SpineIndex: index, Offset: offset})
+		if err != nil || cfi != prefix+test.cfi+")" {
+			t.Errorf("LocationCFI() of %q return: %v, %v", test.word, cfi, err)
+		}
+		loc, err := book.CFILocation(cfi)
+		if err != nil || loc.SpineIndex != index || loc.Offset != offset {
+			t.Errorf("CFILocation(%v) return: %v, %v when was expected the offset %v", cfi, loc, err, offset)
+		}
+	}
+
+	if loc, err := book.CFILocation(prefix + "/4/4)"); err != nil || loc.Offset != strings.Index(text, "Tom") {
+		t.Errorf("CFILocation() of an element return: %v, %v", loc, err)
+	}
+	if _, err := book.CFILocation("epubcfi(/6/40!)"); err == nil {
+		t.Errorf("CFILocation() didn't return an error with an invalid CFI")
+	}
+}
@@ -0,0 +1,300 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// CitationStyle is the format of a citation
+type CitationStyle int
+
+// The supported citation styles
+const (
+	CitationAPA CitationStyle = iota
+	CitationMLA
+	CitationChicago
+	CitationBibTeX
+)
+
+// CitationOptions are the optional locators of a citation
+type CitationOptions struct {
+	// Page is the page label cited
+	Page string
+	// CFI is the position cited, used to look up the page on the page list
+	// of the book if Page is empty, see CFILocation
+	CFI string
+}
+
+// Citation returns a citation of the book in the given style, built from
+// its metadata
+//
+// The citations are plain text, without italics. If the options have a page,
+// or a CFI that can be located on the page list, it is added as locator.
+func (e Epub) Citation(style CitationStyle, opts CitationOptions) (string, error) {
+	page := opts.Page
+	if page == "" && opts.CFI != "" {
+		loc, err := e.CFILocation(opts.CFI)
+		if err != nil {
+			return "", err
+		}
+		page, err = e.PageAt(loc)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	title := ""
+	for _, t := range e.Titles() {
+		if t.Type == "" || t.Type == "main" {
+			title = t.Content
+			break
+		}
+	}
+	authors := e.Contributors(RoleAuthor)
+	publisher := e.first("publisher")
+	year := yearRegexp.FindString(e.first("date"))
+
+	switch style {
+	case CitationAPA:
+		return apaCitation(authors, title, publisher, year, page), nil
+	case CitationMLA:
+		return mlaCitation(authors, title, publisher, year, page), nil
+	case CitationChicago:
+		return chicagoCitation(authors, title, publisher, year, page), nil
+	case CitationBibTeX:
+		return bibtexCitation(authors, title, publisher, year, page, e.Query().ISBN), nil
+	}
+	return "", errors.New("Unknown citation style")
+}
+
+// PageAt returns the label of the page of the page list that contains the
+// location, empty if the book has no page list
+func (e Epub) PageAt(loc Location) (string, error) {
+	pages, err := e.PageList()
+	if err != nil {
+		return "", err
+	}
+
+	label := ""
+	bestIndex, bestOffset := -1, -1
+	docs := make(map[string][]byte)
+	for _, page := range pages {
+		parts := strings.SplitN(page.Href, "#", 2)
+		index := e.opf.spineIndex(parts[0])
+		if index == -1 || index > loc.SpineIndex {
+			continue
+		}
+		offset := 0
+		if len(parts) == 2 {
+			data, ok := docs[parts[0]]
+			if !ok {
+				data, err = e.readFile(e.rootPath + parts[0])
+				if err != nil {
+					return "", err
+				}
+				docs[parts[0]] = data
+			}
+			offset = fragmentOffset(data, parts[1])
+		}
+		if index == loc.SpineIndex && offset > loc.Offset {
+			continue
+		}
+		if index > bestIndex || (index == bestIndex && offset >= bestOffset) {
+			label, bestIndex, bestOffset = page.Label, index, offset
+		}
+	}
+	return label, nil
+}
+
+// nameParts returns the family and given names of the contributor, from
+// its file-as if it has one
+func nameParts(c Contributor) (family, given string) {
+	if parts := strings.SplitN(c.FileAs, ",", 2); len(parts) == 2 {
+		return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+	}
+	words := strings.Fields(c.Name)
+	if len(words) == 0 {
+		return "", ""
+	}
+	return words[len(words)-1], strings.Join(words[:len(words)-1], " ")
+}
+
+func initials(given string) string {
+	var parts []string
+	for _, word := range strings.Fields(given) {
+		r, _ := utf8.DecodeRuneInString(word)
+		if unicode.IsLetter(r) {
+			parts = append(parts, string(r)+".")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// invertedName returns "Family, Given"
+func invertedName(c Contributor) string {
+	family, given := nameParts(c)
+	if given == "" {
+		return family
+	}
+	return family + ", " + given
+}
+
+func apaCitation(authors []Contributor, title, publisher, year, page string) string {
+	var names []string
+	for _, a := range authors {
+		family, given := nameParts(a)
+		name := family
+		if i := initials(given); i != "" {
+			name += ", " + i
+		}
+		names = append(names, name)
+	}
+	var buff strings.Builder
+	switch len(names) {
+	case 0:
+	case 1:
+		buff.WriteString(names[0] + " ")
+	case 2:
+		buff.WriteString(names[0] + ", & " + names[1] + " ")
+	default:
+		buff.WriteString(strings.Join(names[:len(names)-1], ", ") + ", & " + names[len(names)-1] + " ")
+	}
+	if year == "" {
+		year = "n.d."
+	}
+	buff.WriteString("(" + year + "). " + title)
+	if page != "" {
+		buff.WriteString(" (p. " + page + ")")
+	}
+	buff.WriteString(".")
+	if publisher != "" {
+		buff.WriteString(" " + publisher + ".")
+	}
+	return buff.String()
+}
+
+// bibliographyNames returns the authors as used by MLA and Chicago: the
+// first one inverted and the next ones in normal order
+func bibliographyNames(authors []Contributor, etAl int) string {
+	switch {
+	case len(authors) == 0:
+		return ""
+	case len(authors) == 1:
+		return invertedName(authors[0])
+	case len(authors) >= etAl:
+		return invertedName(authors[0]) + ", et al"
+	case len(authors) == 2:
+		return invertedName(authors[0]) + ", and " + authors[1].Name
+	}
+	var names []string
+	for _, a := range authors[1 : len(authors)-1] {
+		names = append(names, a.Name)
+	}
+	return invertedName(authors[0]) + ", " + strings.Join(names, ", ") + ", and " + authors[len(authors)-1].Name
+}
+
+func endSentence(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.HasSuffix(s, ".") {
+		return s
+	}
+	return s + "."
+}
+
+func mlaCitation(authors []Contributor, title, publisher, year, page string) string {
+	var parts []string
+	if names := bibliographyNames(authors, 3); names != "" {
+		parts = append(parts, endSentence(names))
+	}
+	parts = append(parts, endSentence(title))
+	var facts []string
+	for _, fact := range []string{publisher, year} {
+		if fact != "" {
+			facts = append(facts, fact)
+		}
+	}
+	if page != "" {
+		facts = append(facts, "p. "+page)
+	}
+	if len(facts) > 0 {
+		parts = append(parts, strings.Join(facts, ", ")+".")
+	}
+	return strings.Join(parts, " ")
+}
+
+func chicagoCitation(authors []Contributor, title, publisher, year, page string) string {
+	var parts []string
+	if names := bibliographyNames(authors, 11); names != "" {
+		parts = append(parts, endSentence(names))
+	}
+	parts = append(parts, endSentence(title))
+	var facts []string
+	for _, fact := range []string{publisher, year, page} {
+		if fact != "" {
+			facts = append(facts, fact)
+		}
+	}
+	if len(facts) > 0 {
+		parts = append(parts, strings.Join(facts, ", ")+".")
+	}
+	return strings.Join(parts, " ")
+}
+
+func bibtexCitation(authors []Contributor, title, publisher, year, page, isbn string) string {
+	var buff strings.Builder
+	buff.WriteString("@book{" + bibtexKey(authors, year))
+	fields := [][2]string{
+		{"author", bibtexNames(authors)},
+		{"title", title},
+		{"publisher", publisher},
+		{"year", year},
+		{"isbn", isbn},
+		{"pages", page},
+	}
+	for _, field := range fields {
+		if field[1] != "" {
+			buff.WriteString(",\n  " + field[0] + " = {" + bibtexEscape(field[1]) + "}")
+		}
+	}
+	buff.WriteString("\n}\n")
+	return buff.String()
+}
+
+// bibtexKey returns the citation key, the family name of the first author
+// followed by the year
+func bibtexKey(authors []Contributor, year string) string {
+	key := ""
+	if len(authors) > 0 {
+		key, _ = nameParts(authors[0])
+	}
+	key = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, key+year)
+	if key == "" {
+		return "book"
+	}
+	return key
+}
+
+func bibtexNames(contributors []Contributor) string {
+	var names []string
+	for _, c := range contributors {
+		names = append(names, invertedName(c))
+	}
+	return strings.Join(names, " and ")
+}
+
+var bibtexReplacer = strings.NewReplacer(`\`, `\textbackslash{}`, "{", `\{`, "}", `\}`, "&", `\&`, "%", `\%`, "$", `\$`, "#", `\#`, "_", `\_`)
+
+func bibtexEscape(s string) string {
+	return bibtexReplacer.Replace(s)
+}
@@ -0,0 +1,67 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+func TestCitation(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	tests := map[CitationStyle]string{
+		CitationAPA:     "Twain, M. (2004). A Dog's Tale (p. 3).",
+		CitationMLA:     "Twain, Mark. A Dog's Tale. 2004, p. 3.",
+		CitationChicago: "Twain, Mark. A Dog's Tale. 2004, 3.",
+		CitationBibTeX:  "@book{twain2004,\n  author = {Twain, Mark},\n  title = {A Dog's Tale},\n  year = {2004},\n  pages = {3}\n}\n",
+	}
+	for style, expected := range tests {
+		citation, err := f.Citation(style, CitationOptions{Page: "3"})
+		if err != nil {
+			t.Errorf("Citation(%v) return an error: %v", style, err)
+		}
+		if citation != expected {
+			t.Errorf("Citation(%v) return: %q when was expected: %q", style, citation, expected)
+		}
+	}
+}
+
+func TestCitationAuthors(t *testing.T) {
+	authors := []Contributor{{Name: "Ana Pérez"}, {Name: "John Ronald Smith"}, {Name: "Bo Li"}}
+	if c := apaCitation(authors, "Title", "Pub", "", ""); c != "Pérez, A., Smith, J. R., & Li, B. (n.d.). Title. Pub." {
+		t.Errorf("apaCitation() return: %v", c)
+	}
+	if c := mlaCitation(authors, "Title", "Pub", "2020", ""); c != "Pérez, Ana, et al. Title. Pub, 2020." {
+		t.Errorf("mlaCitation() return: %v", c)
+	}
+	if c := chicagoCitation(authors[:2], "Title", "Pub", "2020", ""); c != "Pérez, Ana, and John Ronald Smith. Title. Pub, 2020." {
+		t.Errorf("chicagoCitation() return: %v", c)
+	}
+}
+
+func TestCitationCFI(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	f.ncx.PageList = []pageTarget{
+		{Text: "1", Content: content{Src: htmlFile + "#pgepubid00000"}},
+		{Text: "2", Content: content{Src: htmlFile + "#pgepubid00005"}},
+	}
+	citation, err := f.Citation(CitationMLA, CitationOptions{CFI: "epubcfi(/6/4[item8]!/4/2)"})
+	if err != nil {
+		t.Fatalf("Citation() return an error: %v", err)
+	}
+	if citation != "Twain, Mark. A Dog's Tale. 2004, p. 1." {
+		t.Errorf("Citation() return: %v", citation)
+	}
+
+	text, _ := f.Text(1)
+	page, _ := f.PageAt(Location{SpineIndex: 1, Offset: len(text) - 1})
+	if page != "2" {
+		t.Errorf("PageAt() return: %v", page)
+	}
+	if _, err := f.Citation(CitationMLA, CitationOptions{CFI: "epubcfi(/6/40!)"}); err == nil {
+		t.Errorf("Citation() didn't return an error with an invalid CFI")
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+/*
+Command epubmeta applies a sheet of metadata corrections to a directory of
+epubs.
+
+Usage:
+
+	epubmeta [-replace] [-n] sheet dir
+
+The sheet is a JSON or CSV file (detected by its extension) with the new
+metadata of each epub, identified by its path relative to dir.
+
+The JSON sheet maps each epub to its fields:
+
+	{"tale.epub": {"title": [{"Content": "A Dog's Tale"}]}}
+
+The first column of the CSV sheet is the epub and the header names the field
+of the other columns, a field can be repeated to set several values. Empty
+cells are ignored:
+
+	file,title,subject,subject
+	tale.epub,A Dog's Tale,Dogs,Fiction
+
+By default only the fields the epubs don't have are set, with -replace the
+existing fields are replaced. With -n the changes are reported but the epubs
+are not modified.
+*/
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/meskio/epubgo"
+)
+
+func main() {
+	replace := flag.Bool("replace", false, "replace the existing fields")
+	dryRun := flag.Bool("n", false, "report the changes without writing them")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: epubmeta [-replace] [-n] sheet dir")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	sheet, err := readSheet(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	policy := epubgo.FillMissing
+	if *replace {
+		policy = epubgo.PreferProvider
+	}
+
+	failed := false
+	for _, name := range sortedNames(sheet) {
+		path := filepath.Join(flag.Arg(1), name)
+		if err := apply(path, sheet[name], policy, *dryRun); err != nil {
+			log.Printf("%s: %v", name, err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// apply sets the metadata on the epub in path and rewrites it, the changed
+// fields are reported on the standard output
+//
+// The epub is repacked in memory and closed before being saved over path
+// with Save, that keeps the permissions of the file.
+func apply(path string, updates map[string][]epubgo.MdataElement, policy epubgo.MergePolicy, dryRun bool) error {
+	book, err := epubgo.Open(path)
+	if err != nil {
+		return err
+	}
+	data, err := update(book, path, updates, policy, dryRun)
+	book.Close()
+	if err != nil || data == nil {
+		return err
+	}
+
+	updated, err := epubgo.Load(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+	return updated.Save(path, epubgo.SaveOptions{})
+}
+
+// update reports and applies the metadata changes to the book opened from
+// path, returns the epub repacked or nil on a dry run
+func update(book *epubgo.Epub, path string, updates map[string][]epubgo.MdataElement, policy epubgo.MergePolicy, dryRun bool) ([]byte, error) {
+	for _, field := range sortedFields(updates) {
+		old, err := book.Metadata(field)
+		if len(updates[field]) == 0 || (err == nil && policy == epubgo.FillMissing) {
+			continue
+		}
+		var values []string
+		for _, elem := range updates[field] {
+			values = append(values, elem.Content)
+		}
+		fmt.Printf("%s: %s %q -> %q\n", path, field, old, values)
+	}
+	if dryRun {
+		return nil, nil
+	}
+	if err := book.ApplyMetadata(updates, policy); err != nil {
+		return nil, err
+	}
+
+	var buff bytes.Buffer
+	if err := book.Repack(&buff); err != nil {
+		return nil, err
+	}
+	return buff.Bytes(), nil
+}
+
+func sortedNames(sheet map[string]map[string][]epubgo.MdataElement) []string {
+	names := make([]string, 0, len(sheet))
+	for name := range sheet {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedFields(updates map[string][]epubgo.MdataElement) []string {
+	fields := make([]string, 0, len(updates))
+	for field := range updates {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
@@ -0,0 +1,56 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/meskio/epubgo"
+)
+
+func TestApply(t *testing.T) {
+	data, err := ioutil.ReadFile("../../testdata/a_dogs_tale.epub")
+	if err != nil {
+		t.Fatalf("ReadFile() return an error: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "tale.epub")
+	ioutil.WriteFile(path, data, 0640)
+	os.Chmod(path, 0640)
+
+	updates := map[string][]epubgo.MdataElement{"title": {{Content: "A Cat's Tale"}}}
+	if err := apply(path, updates, epubgo.FillMissing, false); err != nil {
+		t.Fatalf("apply() return an error: %v", err)
+	}
+	if err := apply(path, updates, epubgo.PreferProvider, true); err != nil {
+		t.Fatalf("apply() dry run return an error: %v", err)
+	}
+	book, _ := epubgo.Open(path)
+	if title, _ := book.Metadata("title"); title[0] != "A Dog's Tale" {
+		t.Errorf("apply() replaced the title: %v", title)
+	}
+	book.Close()
+
+	if err := apply(path, updates, epubgo.PreferProvider, false); err != nil {
+		t.Fatalf("apply() return an error: %v", err)
+	}
+	book, err = epubgo.Open(path)
+	if err != nil {
+		t.Fatalf("Open() of the updated epub return an error: %v", err)
+	}
+	defer book.Close()
+	if title, _ := book.Metadata("title"); title[0] != "A Cat's Tale" {
+		t.Errorf("apply() didn't replace the title: %v", title)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0640 {
+		t.Errorf("apply() changed the permissions to: %v", info.Mode())
+	}
+	if files, _ := ioutil.ReadDir(filepath.Dir(path)); len(files) != 1 {
+		t.Errorf("apply() left temporary files: %v", files)
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/meskio/epubgo"
+)
+
+// readSheet reads the metadata sheet from a JSON or CSV file
+func readSheet(path string) (map[string]map[string][]epubgo.MdataElement, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return parseJSONSheet(f)
+	case ".csv":
+		return parseCSVSheet(f)
+	default:
+		return nil, errors.New("Unknown sheet format " + path)
+	}
+}
+
+func parseJSONSheet(r io.Reader) (map[string]map[string][]epubgo.MdataElement, error) {
+	var sheet map[string]map[string][]epubgo.MdataElement
+	err := json.NewDecoder(r).Decode(&sheet)
+	return sheet, err
+}
+
+func parseCSVSheet(r io.Reader) (map[string]map[string][]epubgo.MdataElement, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 || len(rows[0]) < 2 {
+		return nil, errors.New("The CSV sheet has no fields")
+	}
+
+	header := rows[0]
+	sheet := make(map[string]map[string][]epubgo.MdataElement)
+	for _, row := range rows[1:] {
+		if row[0] == "" {
+			continue
+		}
+		updates, ok := sheet[row[0]]
+		if !ok {
+			updates = make(map[string][]epubgo.MdataElement)
+			sheet[row[0]] = updates
+		}
+		for i, value := range row[1:] {
+			if value == "" {
+				continue
+			}
+			field := strings.TrimSpace(header[i+1])
+			updates[field] = append(updates[field], epubgo.MdataElement{Content: value})
+		}
+	}
+	return sheet, nil
+}
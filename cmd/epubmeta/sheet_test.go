@@ -0,0 +1,47 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+import "strings"
+
+const (
+	csvSheet = `file,title,subject,subject
+tale.epub,A Dog's Tale,Dogs,Fiction
+other.epub,,History,
+`
+	jsonSheet = `{"tale.epub": {"title": [{"Content": "A Dog's Tale", "Attr": {"lang": "en"}}]}}`
+)
+
+func TestParseCSVSheet(t *testing.T) {
+	sheet, err := parseCSVSheet(strings.NewReader(csvSheet))
+	if err != nil {
+		t.Fatalf("parseCSVSheet() return an error: %v", err)
+	}
+	if len(sheet) != 2 {
+		t.Errorf("parseCSVSheet() return %v epubs", len(sheet))
+	}
+	if title := sheet["tale.epub"]["title"]; len(title) != 1 || title[0].Content != "A Dog's Tale" {
+		t.Errorf("tale.epub title: %v", title)
+	}
+	if subject := sheet["tale.epub"]["subject"]; len(subject) != 2 || subject[1].Content != "Fiction" {
+		t.Errorf("tale.epub subject: %v", subject)
+	}
+	if _, ok := sheet["other.epub"]["title"]; ok {
+		t.Errorf("The empty title of other.epub was not ignored")
+	}
+}
+
+func TestParseJSONSheet(t *testing.T) {
+	sheet, err := parseJSONSheet(strings.NewReader(jsonSheet))
+	if err != nil {
+		t.Fatalf("parseJSONSheet() return an error: %v", err)
+	}
+	title := sheet["tale.epub"]["title"]
+	if len(title) != 1 || title[0].Content != "A Dog's Tale" || title[0].Attr["lang"] != "en" {
+		t.Errorf("tale.epub title: %v", title)
+	}
+}
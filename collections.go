@@ -0,0 +1,59 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+// Collection is a series or set the book belongs to
+type Collection struct {
+	Name string
+	// Type is the collection-type (series or set), empty if not specified
+	Type string
+	// Position is the group-position of the book on the collection
+	Position string
+}
+
+// Collections returns the collections the book belongs to
+//
+// They come from the EPUB 3 belongs-to-collection metadata and the calibre
+// series metadata, the calibre series is only added if it is not already
+// present as an EPUB 3 collection.
+func (e Epub) Collections() []Collection {
+	var collections []Collection
+	for _, meta := range e.metadata["meta"] {
+		if meta.Attr["property"] != "belongs-to-collection" || meta.Attr["refines"] != "" {
+			continue
+		}
+		id := meta.Attr["id"]
+		collections = append(collections, Collection{
+			Name:     meta.Content,
+			Type:     e.metadata.refinement(id, "collection-type"),
+			Position: e.metadata.refinement(id, "group-position"),
+		})
+	}
+
+	series := e.metaContent("calibre:series")
+	if series == "" {
+		return collections
+	}
+	for _, c := range collections {
+		if c.Name == series {
+			return collections
+		}
+	}
+	return append(collections, Collection{
+		Name:     series,
+		Type:     "series",
+		Position: e.metaContent("calibre:series_index"),
+	})
+}
+
+// metaContent returns the content of the first meta with the given name
+func (e Epub) metaContent(name string) string {
+	for _, meta := range e.metadata["meta"] {
+		if meta.Attr["name"] == name {
+			return meta.Content
+		}
+	}
+	return ""
+}
@@ -0,0 +1,33 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+func TestCollections(t *testing.T) {
+	f := buildEpub(t, epub3OPF, nil)
+
+	collections := f.Collections()
+	if len(collections) != 2 {
+		t.Fatalf("len(Collections()) should be 2, but was %v", len(collections))
+	}
+	expected := Collection{"The Lord of the Rings", "series", "1"}
+	if collections[0] != expected {
+		t.Errorf("Collections()[0] return: %v when was expected: %v", collections[0], expected)
+	}
+	expected = Collection{"Middle-earth Legendarium", "series", "3"}
+	if collections[1] != expected {
+		t.Errorf("Collections()[1] return: %v when was expected: %v", collections[1], expected)
+	}
+}
+
+func TestNoCollections(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	if collections := f.Collections(); len(collections) != 0 {
+		t.Errorf("Collections() return: %v", collections)
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html/template"
+	"image"
+	"strconv"
+)
+
+var comicPageTemplate = template.Must(template.New("comic").Parse(`<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops"{{with .Language}} xml:lang="{{.}}" lang="{{.}}"{{end}}>
+  <head>
+    <title>{{.Title}}</title>
+    <meta name="viewport" content="width={{.Width}}, height={{.Height}}"/>
+    <style type="text/css">
+      html, body { margin: 0; padding: 0; width: {{.Width}}px; height: {{.Height}}px; }
+      img { display: block; width: {{.Width}}px; height: {{.Height}}px; }
+    </style>
+  </head>
+  <body>
+    <img src="{{.Src}}" alt="{{.Title}}"/>
+  </body>
+</html>
+`))
+
+// ComicPage is a page of a comic for NewComic
+type ComicPage struct {
+	Image []byte
+	// MediaType is the type of the image, detected from its content if
+	// empty
+	MediaType string
+	// Title is the entry of the page on the table of contents, like the
+	// title of a chapter. The pages without title have no entry.
+	Title string
+}
+
+// ComicOptions configures NewComic
+type ComicOptions struct {
+	// Identifier, Title and Language are the metadata of the book, as in New
+	Identifier string
+	Title      string
+	Language   string
+	// Metadata are other metadata fields, like "creator" or "publisher"
+	Metadata map[string][]MdataElement
+	// RightToLeft sets the page progression direction to right to left, for
+	// manga
+	RightToLeft bool
+	// Width and Height are the viewport of the pages, by default the size
+	// of each image. They are needed for the images that can't be decoded,
+	// like WebP if its decoder is not registered.
+	Width  int
+	Height int
+	// Spread is the rendition:spread of the book, "landscape" by default
+	Spread string
+}
+
+type comicPageData struct {
+	Title    string
+	Language string
+	Src      string
+	Width    int
+	Height   int
+}
+
+// NewComic returns a fixed layout EPUB 3 book with a page for each image
+//
+// Each image is wrapped on an XHTML page with its size as viewport. The first
+// image is the cover. The pages with title are added to the table of
+// contents, if none has one the first page is added with the title of the
+// book. The book is written with Repack.
+func NewComic(pages []ComicPage, opts ComicOptions) (*Epub, error) {
+	if len(pages) == 0 {
+		return nil, errors.New("The comic has no pages")
+	}
+	e, err := New(opts.Identifier, opts.Title, opts.Language)
+	if err != nil {
+		return nil, err
+	}
+	for field, elems := range opts.Metadata {
+		if err := e.SetMetadata(field, elems); err != nil {
+			return nil, err
+		}
+	}
+
+	spread := opts.Spread
+	if spread == "" {
+		spread = "landscape"
+	}
+	for _, property := range [][2]string{{"rendition:layout", "pre-paginated"}, {"rendition:spread", spread}} {
+		e.metadata["meta"] = append(e.metadata["meta"], MdataElement{
+			Content: property[1],
+			Attr:    map[string]string{"property": property[0]},
+		})
+	}
+	e.opf.Spine.PageProgression = "ltr"
+	if opts.RightToLeft {
+		e.opf.Spine.PageProgression = "rtl"
+	}
+
+	titled := false
+	for _, page := range pages {
+		titled = titled || page.Title != ""
+	}
+	digits := len(strconv.Itoa(len(pages)))
+	if digits < 3 {
+		digits = 3
+	}
+	for i, page := range pages {
+		mediaType := page.MediaType
+		if mediaType == "" {
+			mediaType = DetectMediaType(page.Image)
+		}
+		ext, ok := imageExtensions[mediaType]
+		if !ok {
+			return nil, fmt.Errorf("Page %d is not an image: %s", i+1, mediaType)
+		}
+		number := fmt.Sprintf("%0*d", digits, i+1)
+		id, err := e.AddResource("images/image-"+number+ext, mediaType, bytes.NewReader(page.Image))
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			e.opf.manifestItem(id).Properties = "cover-image"
+			e.setCoverMeta(id)
+		}
+
+		data := comicPageData{
+			Title:    page.Title,
+			Language: opts.Language,
+			Src:      "../images/image-" + number + ext,
+			Width:    opts.Width,
+			Height:   opts.Height,
+		}
+		if data.Width == 0 || data.Height == 0 {
+			config, _, err := image.DecodeConfig(bytes.NewReader(page.Image))
+			if err != nil {
+				return nil, fmt.Errorf("Can't get the size of page %d: %v", i+1, err)
+			}
+			data.Width, data.Height = config.Width, config.Height
+		}
+		if data.Title == "" {
+			data.Title = "Page " + strconv.Itoa(i+1)
+		}
+		title := page.Title
+		if i == 0 && !titled {
+			title = opts.Title
+		}
+
+		var buff bytes.Buffer
+		buff.WriteString(xml.Header)
+		if err := comicPageTemplate.Execute(&buff, data); err != nil {
+			return nil, err
+		}
+		if err := e.InsertDocument("text/page-"+number+".xhtml", title, buff.Bytes(), -1); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"strings"
+)
+
+func testPNG(width, height int) []byte {
+	var buff bytes.Buffer
+	png.Encode(&buff, image.NewGray(image.Rect(0, 0, width, height)))
+	return buff.Bytes()
+}
+
+func TestNewComic(t *testing.T) {
+	pages := []ComicPage{
+		{Image: testPNG(80, 120)},
+		{Image: testPNG(80, 120), Title: "Chapter 1"},
+		{Image: testPNG(160, 120)},
+	}
+	f, err := NewComic(pages, ComicOptions{
+		Title:       "Manga",
+		Language:    "ja",
+		Metadata:    map[string][]MdataElement{"creator": {{Content: "Author"}}},
+		RightToLeft: true,
+	})
+	if err != nil {
+		t.Fatalf("NewComic() return an error: %v", err)
+	}
+
+	book := repackBook(t, f)
+	hints, _ := book.LayoutHints()
+	if !hints.FixedLayout || hints.PageProgression != "rtl" {
+		t.Errorf("LayoutHints() return: %+v", hints)
+	}
+	if creator, _ := book.Metadata("creator"); len(creator) != 1 || creator[0] != "Author" {
+		t.Errorf("The creator is: %v", creator)
+	}
+	if book.opf.spineLength() != 3 || book.coverHref() != "images/image-001.png" {
+		t.Errorf("Wrong spine or cover: %v %v", book.opf.Spine.Items, book.coverHref())
+	}
+	page := readBookFile(t, book, "text/page-003.xhtml")
+	if !strings.Contains(page, `content="width=160, height=120"`) || !strings.Contains(page, `src="../images/image-003.png"`) {
+		t.Errorf("The page is: %v", page)
+	}
+	nav := readBookFile(t, book, "nav.xhtml")
+	if !strings.Contains(nav, "Chapter 1") || strings.Contains(nav, "page-001") {
+		t.Errorf("The navigation document is: %v", nav)
+	}
+
+	if _, err := NewComic([]ComicPage{{Image: []byte("text")}}, ComicOptions{}); err == nil {
+		t.Errorf("NewComic() with a page that is not an image didn't return an error")
+	}
+}
@@ -0,0 +1,261 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"archive/zip"
+	"errors"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Versions of the EPUB specification for Conformance
+const (
+	EPUB201 = "2.0.1"
+	// EPUB32 are the rules of EPUB 3.0 to 3.2
+	EPUB32 = "3.2"
+	EPUB33 = "3.3"
+)
+
+// Checks of the issues of Conformance
+const (
+	CheckCoreMediaType = "core-media-type"
+	CheckNCX           = "ncx"
+	CheckNavDoc        = "nav-doc"
+	CheckGuide         = "guide"
+	CheckMimetype      = "mimetype"
+	CheckFileName      = "file-name"
+)
+
+// conformanceProfile are the rules of a version of the specification
+type conformanceProfile struct {
+	// coreMediaTypes are the media types that don't need a fallback
+	coreMediaTypes []string
+	// exempt are the resources that don't need a fallback even if they are
+	// not core media types, like fonts or video on EPUB 3
+	exempt func(item manifest) bool
+	// ncxRequired is set for EPUB 2, where the NCX is the table of contents
+	ncxRequired bool
+	// navRequired is set for EPUB 3, where the table of contents is the
+	// navigation document
+	navRequired bool
+	// guide is the severity of the presence of a guide, empty if it is
+	// allowed
+	guide string
+	// limitNameLength checks the length of the file names and paths, 255 and
+	// 65535 bytes, removed from the OCF of EPUB 3.3
+	limitNameLength bool
+}
+
+var epub3CoreMediaTypes = []string{
+	"image/gif", "image/jpeg", "image/png", "image/svg+xml",
+	"audio/mpeg", "audio/mp4",
+	"text/css",
+	"font/ttf", "application/font-sfnt", "font/otf", "application/vnd.ms-opentype",
+	"font/woff", "application/font-woff", "font/woff2",
+	"application/xhtml+xml", "application/javascript", "application/ecmascript", "text/javascript",
+	"application/x-dtbncx+xml", "application/smil+xml", "application/pls+xml",
+}
+
+var conformanceProfiles = map[string]conformanceProfile{
+	EPUB201: {
+		coreMediaTypes: []string{
+			"image/gif", "image/jpeg", "image/png", "image/svg+xml",
+			"application/xhtml+xml", "application/x-dtbook+xml", "text/css", "application/xml",
+			"text/x-oeb1-document", "text/x-oeb1-css", "application/x-dtbncx+xml",
+		},
+		exempt:          func(item manifest) bool { return false },
+		ncxRequired:     true,
+		limitNameLength: true,
+	},
+	EPUB32: {
+		coreMediaTypes:  epub3CoreMediaTypes,
+		exempt:          isExemptResource,
+		navRequired:     true,
+		limitNameLength: true,
+	},
+	EPUB33: {
+		coreMediaTypes: append([]string{"image/webp", "audio/ogg"}, epub3CoreMediaTypes...),
+		exempt:         isExemptResource,
+		navRequired:    true,
+		guide:          SeverityWarning,
+	},
+}
+
+// Conformance checks the book against the rules of a version of the EPUB
+// specification: "2.0.1", "3.2" (EPUB 3.0 to 3.2) or "3.3"
+//
+// An empty version checks the rules of the version of the package, EPUB 3.3
+// for all the EPUB 3 books as they keep the version 3.0 on the package. The
+// rules checked are:
+//
+//   - The resources that are not core media types of the version need a
+//     fallback to a core media type, it is an error for the documents of the
+//     spine. WebP images and Opus audio are core media types since EPUB 3.3,
+//     fonts and video are exempt on EPUB 3.
+//   - EPUB 2 needs an NCX and EPUB 3 a navigation document, the NCX is
+//     optional on EPUB 3.
+//   - The guide is a legacy feature on EPUB 3.3, ignored by the reading
+//     systems, it gets a warning to use the landmarks instead.
+//   - The mimetype file is the first of the zip, not compressed, with the
+//     content "application/epub+zip".
+//   - The file names don't have the characters forbidden by the OCF nor end
+//     in a full stop and are unique without case. Before EPUB 3.3 the names
+//     are limited to 255 bytes and the paths to 65535 bytes.
+//
+// The issues use the severities of Preflight. The files of the zip are
+// checked, not the changes pending of Repack.
+func (e Epub) Conformance(version string) ([]PreflightIssue, error) {
+	if version == "" {
+		version = EPUB33
+		if strings.HasPrefix(strings.TrimSpace(e.opf.Version), "2") {
+			version = EPUB201
+		}
+	}
+	p, ok := conformanceProfiles[version]
+	if !ok {
+		return nil, errors.New("Unknown EPUB version " + version)
+	}
+	var issues []PreflightIssue
+	add := func(check, severity, href, message string) {
+		issues = append(issues, PreflightIssue{check, severity, href, message})
+	}
+
+	onSpine := make(map[string]bool)
+	for _, item := range e.opf.Spine.Items {
+		onSpine[item.IDref] = true
+	}
+	for _, item := range e.opf.Manifest {
+		if p.isCore(item.MediaType) || p.exempt(item) || p.hasCoreFallback(e.opf, item) {
+			continue
+		}
+		severity := SeverityWarning
+		message := "The media type " + item.MediaType + " is not a core media type of EPUB " + version + ", it needs a fallback when used"
+		if onSpine[item.ID] {
+			severity = SeverityError
+			message = "The spine document of media type " + item.MediaType + " is not a core media type of EPUB " + version + " and has no fallback"
+		}
+		add(CheckCoreMediaType, severity, item.Href, message)
+	}
+
+	if p.ncxRequired && e.opf.ncxPath() == "" {
+		add(CheckNCX, SeverityError, "", "The book has no NCX")
+	}
+	if p.navRequired && e.navDocPath() == "" {
+		add(CheckNavDoc, SeverityError, "", "The book has no navigation document")
+	}
+	if p.guide != "" && len(e.opf.Guide) != 0 {
+		add(CheckGuide, p.guide, "", "The guide is ignored by EPUB "+version+" reading systems, use the landmarks of the navigation document")
+	}
+
+	issues = append(issues, e.mimetypeIssues()...)
+
+	seen := make(map[string]string)
+	for _, f := range e.zip.File {
+		if message := p.fileNameIssue(f.Name); message != "" {
+			add(CheckFileName, SeverityError, f.Name, message)
+		}
+		folded := strings.ToLower(f.Name)
+		if other, ok := seen[folded]; ok && other != f.Name {
+			add(CheckFileName, SeverityError, f.Name, "The file name differs only in case from "+other)
+		}
+		seen[folded] = f.Name
+	}
+	return issues, nil
+}
+
+// mimetypeIssues checks that the mimetype file is the first of the zip, not
+// compressed and with the right content
+func (e Epub) mimetypeIssues() []PreflightIssue {
+	issue := func(message string) []PreflightIssue {
+		return []PreflightIssue{{CheckMimetype, SeverityError, "mimetype", message}}
+	}
+	if len(e.zip.File) == 0 || e.zip.File[0].Name != "mimetype" {
+		return issue("The mimetype file is not the first file of the zip")
+	}
+	f := e.zip.File[0]
+	if f.Method != zip.Store {
+		return issue("The mimetype file is compressed")
+	}
+	r, err := f.Open()
+	if err != nil {
+		return issue("The mimetype file can't be read: " + err.Error())
+	}
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	if err != nil || string(data) != "application/epub+zip" {
+		return issue("The content of the mimetype file is not application/epub+zip")
+	}
+	return nil
+}
+
+// isCore returns whether the media type, without parameters, is a core
+// media type of the profile
+//
+// Ogg audio is only a core media type with the Opus codec, as
+// "audio/ogg; codecs=opus".
+func (p conformanceProfile) isCore(mediaType string) bool {
+	mediaType = strings.ToLower(mediaType)
+	base := strings.TrimSpace(strings.SplitN(mediaType, ";", 2)[0])
+	if base == "audio/ogg" && !strings.Contains(mediaType, "opus") {
+		return false
+	}
+	return contains(p.coreMediaTypes, base)
+}
+
+// hasCoreFallback returns whether the chain of fallbacks of the item reaches
+// a core media type
+func (p conformanceProfile) hasCoreFallback(opf *xmlOPF, item manifest) bool {
+	seen := map[string]bool{item.ID: true}
+	for item.Fallback != "" && !seen[item.Fallback] {
+		seen[item.Fallback] = true
+		next := opf.manifestItem(item.Fallback)
+		if next == nil {
+			return false
+		}
+		if p.isCore(next.MediaType) {
+			return true
+		}
+		item = *next
+	}
+	return false
+}
+
+// fileNameIssue returns why the path of the zip is not a valid OCF file
+// name, or an empty string if it is valid
+func (p conformanceProfile) fileNameIssue(name string) string {
+	if p.limitNameLength && len(name) > 65535 {
+		return "The path is longer than 65535 bytes"
+	}
+	for _, segment := range strings.Split(strings.TrimSuffix(name, "/"), "/") {
+		if p.limitNameLength && len(segment) > 255 {
+			return "The file name " + segment + " is longer than 255 bytes"
+		}
+		if strings.HasSuffix(segment, ".") {
+			return "The file name " + segment + " ends in a full stop"
+		}
+		for _, r := range segment {
+			if strings.ContainsRune(`"*:<>?\`, r) || r == 0x7f || r == unicode.ReplacementChar || unicode.Is(unicode.Cc, r) {
+				return "The file name " + segment + " has the forbidden character " + strconv.QuoteRune(r)
+			}
+		}
+	}
+	return ""
+}
+
+// isExemptResource returns whether the resource doesn't need a fallback on
+// EPUB 3 even if it is not a core media type: fonts, video and text tracks
+func isExemptResource(item manifest) bool {
+	switch {
+	case isFont(item.MediaType, item.Href),
+		strings.HasPrefix(item.MediaType, "video/"),
+		item.MediaType == "text/vtt", item.MediaType == "application/ttml+xml":
+		return true
+	}
+	return path.Ext(item.Href) == ".vtt"
+}
@@ -0,0 +1,87 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import "strings"
+
+func TestConformance(t *testing.T) {
+	f, err := New("", "Title", "en")
+	if err != nil {
+		t.Fatalf("New() return an error: %v", err)
+	}
+	f.InsertDocument("text/ch1.xhtml", "Chapter 1", []byte(`<html><body><p>Text</p></body></html>`), -1)
+	f.AddResource("images/a.webp", "image/webp", strings.NewReader("RIFF"))
+	f.AddResource("audio/a.ogg", "audio/ogg; codecs=opus", strings.NewReader("OggS"))
+	f.AddResource("fonts/a.ttf", "application/x-font-truetype", strings.NewReader("font"))
+	f.AddResource("text/doc.foo", "application/x-foo", strings.NewReader("foo"))
+	f.AddResource("text/fallback.foo", "application/x-foo", strings.NewReader("foo"))
+	f.opf.manifestItem(f.opf.fileID("text/fallback.foo")).Fallback = f.opf.fileID("text/ch1.xhtml")
+	f.opf.insertSpine(spineItem{IDref: f.opf.fileID("text/doc.foo")}, -1)
+	f.setGuide("text", "Start", "text/ch1.xhtml")
+	book := repackBook(t, f)
+
+	issues, err := book.Conformance("")
+	if err != nil {
+		t.Fatalf("Conformance() return an error: %v", err)
+	}
+	if len(issues) != 2 {
+		t.Fatalf("Conformance() return: %v", issues)
+	}
+	if issues[0].Check != CheckCoreMediaType || issues[0].Severity != SeverityError || issues[0].Href != "text/doc.foo" {
+		t.Errorf("Conformance()[0] return: %v", issues[0])
+	}
+	if issues[1].Check != CheckGuide || issues[1].Severity != SeverityWarning {
+		t.Errorf("Conformance()[1] return: %v", issues[1])
+	}
+
+	issues, _ = book.Conformance(EPUB32)
+	var hrefs []string
+	for _, issue := range issues {
+		hrefs = append(hrefs, issue.Href)
+	}
+	if strings.Join(hrefs, " ") != "images/a.webp audio/a.ogg text/doc.foo" {
+		t.Errorf("Conformance(EPUB32) return: %v", issues)
+	}
+
+	issues, _ = book.Conformance(EPUB201)
+	checks := make(map[string]bool)
+	for _, issue := range issues {
+		checks[issue.Check] = true
+	}
+	if !checks[CheckNCX] || checks[CheckNavDoc] || checks[CheckGuide] {
+		t.Errorf("Conformance(EPUB201) return: %v", issues)
+	}
+
+	if _, err := book.Conformance("4.0"); err == nil {
+		t.Errorf("Conformance() of an unknown version didn't return an error")
+	}
+}
+
+func TestConformanceOCF(t *testing.T) {
+	f := buildEpub(t, "testdata/epub3.opf", map[string]string{
+		"a:b.txt":  "",
+		"Case.txt": "",
+		"case.txt": "",
+		"end.":     "",
+	})
+	issues, err := f.Conformance(EPUB33)
+	if err != nil {
+		t.Fatalf("Conformance() return an error: %v", err)
+	}
+	names := make(map[string]bool)
+	for _, issue := range issues {
+		if issue.Check == CheckFileName {
+			names[issue.Href] = true
+		}
+		if issue.Check == CheckMimetype {
+			t.Errorf("Conformance() return a mimetype issue: %v", issue)
+		}
+	}
+	if len(names) != 3 || !names["OEBPS/a:b.txt"] || !names["OEBPS/end."] {
+		t.Errorf("Conformance() file name issues: %v", issues)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"strings"
+)
+
+// MARC relator codes of the most common roles
+const (
+	RoleAuthor      = "aut"
+	RoleEditor      = "edt"
+	RoleTranslator  = "trl"
+	RoleIllustrator = "ill"
+	RoleNarrator    = "nrt"
+)
+
+// Contributor is a creator or contributor of the book
+type Contributor struct {
+	Name   string
+	FileAs string
+	// Role is the MARC relator code of the contributor
+	Role string
+	// Creator is true for dc:creator and false for dc:contributor
+	Creator bool
+}
+
+// Contributors returns the creators and contributors with the given role
+//
+// The role is a MARC relator code (aut, edt, trl, ill, nrt, ...) taken from
+// the opf:role attribute or the EPUB 3 role refinement. Creators without a
+// role are considered authors. If role is empty all of them are returned.
+func (e Epub) Contributors(role string) []Contributor {
+	var contributors []Contributor
+	for _, field := range []string{"creator", "contributor"} {
+		for _, elem := range e.metadata[field] {
+			c := e.contributor(elem, field == "creator")
+			if role == "" || c.Role == strings.ToLower(role) {
+				contributors = append(contributors, c)
+			}
+		}
+	}
+	return contributors
+}
+
+func (e Epub) contributor(elem MdataElement, creator bool) Contributor {
+	id := elem.Attr["id"]
+	c := Contributor{
+		Name:    elem.Content,
+		FileAs:  elem.Attr["file-as"],
+		Role:    strings.ToLower(elem.Attr["role"]),
+		Creator: creator,
+	}
+	if c.FileAs == "" {
+		c.FileAs = e.metadata.refinement(id, "file-as")
+	}
+	if c.Role == "" {
+		c.Role = strings.ToLower(e.metadata.refinement(id, "role"))
+	}
+	if c.Role == "" && creator {
+		c.Role = RoleAuthor
+	}
+	return c
+}
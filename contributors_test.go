@@ -0,0 +1,40 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+func TestContributors(t *testing.T) {
+	f := buildEpub(t, epub3OPF, nil)
+
+	if all := f.Contributors(""); len(all) != 3 {
+		t.Errorf("len(Contributors(\"\")) should be 3, but was %v", len(all))
+	}
+
+	authors := f.Contributors(RoleAuthor)
+	expected := Contributor{"J. R. R. Tolkien", "Tolkien, J. R. R.", RoleAuthor, true}
+	if len(authors) != 1 || authors[0] != expected {
+		t.Errorf("Contributors(%v) return: %v", RoleAuthor, authors)
+	}
+
+	illustrators := f.Contributors("ILL")
+	if len(illustrators) != 1 || illustrators[0].Name != "Alan Lee" || illustrators[0].Creator {
+		t.Errorf("Contributors(ILL) return: %v", illustrators)
+	}
+
+	if translators := f.Contributors(RoleTranslator); len(translators) != 0 {
+		t.Errorf("Contributors(%v) return: %v", RoleTranslator, translators)
+	}
+}
+
+func TestContributorsOPFRole(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	authors := f.Contributors(RoleAuthor)
+	if len(authors) != 1 || authors[0].Name != bookCreator || authors[0].FileAs != creatorFileAs {
+		t.Errorf("Contributors(%v) return: %v", RoleAuthor, authors)
+	}
+}
@@ -0,0 +1,262 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"errors"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const coverPageTemplate = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml">
+  <head>
+    <title>Cover</title>
+    <style type="text/css">
+      body { margin: 0; padding: 0; text-align: center; }
+      img { max-width: 100%; max-height: 100%; }
+    </style>
+  </head>
+  <body>
+    <div>
+      <img src="{{src}}" alt="Cover"/>
+    </div>
+  </body>
+</html>
+`
+
+var imageExtensions = map[string]string{
+	"image/jpeg":    ".jpg",
+	"image/png":     ".png",
+	"image/gif":     ".gif",
+	"image/svg+xml": ".svg",
+	"image/webp":    ".webp",
+}
+
+// SetCover replaces the cover image of the book, or adds one if it has none
+//
+// The image is written next to the previous cover (or next to the OPF), the
+// manifest, the cover meta and the EPUB 3 cover-image property are updated,
+// the cover page is regenerated if the book has one and the guide is fixed to
+// point to it. The changes are written with Repack.
+func (e *Epub) SetCover(r io.Reader, mediaType string) error {
+	ext, ok := imageExtensions[mediaType]
+	if !ok {
+		return errors.New("Unsupported cover media type " + mediaType)
+	}
+	data, err := readAll(r)
+	if err != nil {
+		return err
+	}
+
+	coverPage := e.coverPage()
+	href := e.coverHref()
+	var item *manifest
+	if href == "" {
+		href = e.opf.uniqueHref("cover" + ext)
+		e.opf.Manifest = append(e.opf.Manifest, manifest{
+			ID:        e.opf.uniqueID("cover-image"),
+			Href:      href,
+			MediaType: mediaType,
+		})
+		item = &e.opf.Manifest[len(e.opf.Manifest)-1]
+	} else {
+		newHref := strings.TrimSuffix(href, path.Ext(href)) + ext
+		if newHref != href {
+			newHref = e.opf.uniqueHref(newHref)
+			err = e.renameFile(e.rootPath+href, e.rootPath+newHref)
+			if err != nil {
+				return err
+			}
+			href = newHref
+		}
+		item = e.opf.manifestItem(e.opf.fileID(href))
+		item.MediaType = mediaType
+	}
+	e.stage(e.rootPath+href, data)
+
+	if strings.HasPrefix(e.opf.Version, "3") {
+		for i := range e.opf.Manifest {
+			e.opf.Manifest[i].Properties = removeProperty(e.opf.Manifest[i].Properties, "cover-image")
+		}
+		item.Properties = strings.TrimSpace(item.Properties + " cover-image")
+	}
+	e.setCoverMeta(item.ID)
+
+	if coverPage != "" {
+		name := e.rootPath + coverPage
+		src := relativeRef(name, e.rootPath+href)
+		e.stage(name, []byte(strings.Replace(coverPageTemplate, "{{src}}", escapeXML(src), 1)))
+		e.setGuide("cover", "Cover", coverPage)
+	}
+	return nil
+}
+
+// coverHref returns the href of the cover image, from the EPUB 3 cover-image
+// property or the EPUB 2 cover meta
+func (e Epub) coverHref() string {
+	for _, item := range e.opf.Manifest {
+		if hasProperty(item.Properties, "cover-image") {
+			return item.Href
+		}
+	}
+	return e.opf.filePath(e.metaContent("cover"))
+}
+
+// coverPage returns the href of the XHTML page showing the cover
+//
+// It is taken from the guide, if not the first page of the spine is used if
+// it shows the cover image.
+func (e Epub) coverPage() string {
+	for _, ref := range e.opf.Guide {
+		if ref.Type == "cover" {
+			href := strings.SplitN(ref.Href, "#", 2)[0]
+			if e.opf.fileID(href) != "" {
+				return href
+			}
+		}
+	}
+
+	cover := e.coverHref()
+	if cover == "" || e.opf.spineLength() == 0 {
+		return ""
+	}
+	first := e.opf.spineURL(0)
+	if first == "" {
+		return ""
+	}
+	data, err := e.readFile(e.rootPath + first)
+	if err != nil {
+		return ""
+	}
+	for _, ref := range references(data, e.rootPath+first) {
+		if ref == e.rootPath+cover {
+			return first
+		}
+	}
+	return ""
+}
+
+func (e *Epub) setCoverMeta(id string) {
+	metas := e.metadata["meta"]
+	for i, m := range metas {
+		if m.Attr["name"] == "cover" {
+			metas[i].Content = id
+			metas[i].Attr["content"] = id
+			return
+		}
+	}
+	e.metadata["meta"] = append(metas, MdataElement{
+		Content: id,
+		Attr:    map[string]string{"name": "cover", "content": id},
+	})
+}
+
+// setGuide points the guide reference of type refType to href, adding it if
+// it doesn't exist
+func (e *Epub) setGuide(refType, title, href string) {
+	for i, ref := range e.opf.Guide {
+		if ref.Type == refType {
+			e.opf.Guide[i].Href = href
+			return
+		}
+	}
+	e.opf.Guide = append(e.opf.Guide, guideRef{Type: refType, Title: title, Href: href})
+}
+
+// renameFile moves a file inside the epub, updating the manifest, the guide,
+// the NCX and the references to it on the other documents. The references of
+// the file itself are fixed for its new location.
+func (e Epub) renameFile(oldName, newName string) error {
+	data, err := e.readFile(oldName)
+	if err != nil {
+		return err
+	}
+
+	renames := map[string]string{oldName: newName}
+	oldHref := strings.TrimPrefix(oldName, e.rootPath)
+	newHref := strings.TrimPrefix(newName, e.rootPath)
+	if isMarkup(e.opf.mediaType(oldHref)) {
+		data = eachRef(data, func(ref string) string { return moveRef(oldName, newName, ref, renames) })
+	}
+	if e.ncx != nil {
+		ncxPath := e.rootPath + e.opf.ncxPath()
+		newNCXPath := ncxPath
+		if ncxPath == oldName {
+			newNCXPath = newName
+		}
+		fixNavPoints(e.ncx.NavMap, func(ref string) string { return moveRef(ncxPath, newNCXPath, ref, renames) })
+	}
+	for _, name := range e.fileNames() {
+		mediaType := e.opf.mediaType(strings.TrimPrefix(name, e.rootPath))
+		if name == oldName || name == e.opfPath || !isMarkup(mediaType) {
+			continue
+		}
+		doc, err := e.readFile(name)
+		if err != nil {
+			return err
+		}
+		if rewritten := rewriteRefs(doc, name, renames); string(rewritten) != string(doc) {
+			e.stage(name, rewritten)
+		}
+	}
+
+	if item := e.opf.manifestItem(e.opf.fileID(oldHref)); item != nil {
+		item.Href = newHref
+	}
+	for i, ref := range e.opf.Guide {
+		parts := strings.SplitN(ref.Href, "#", 2)
+		if parts[0] == oldHref {
+			parts[0] = newHref
+			e.opf.Guide[i].Href = strings.Join(parts, "#")
+		}
+	}
+
+	e.stage(newName, data)
+	e.remove(oldName)
+	return nil
+}
+
+func removeProperty(properties, property string) string {
+	var props []string
+	for _, p := range strings.Fields(properties) {
+		if p != property {
+			props = append(props, p)
+		}
+	}
+	return strings.Join(props, " ")
+}
+
+// uniqueID returns id, or id followed by a number if it is already in use
+func (opf xmlOPF) uniqueID(id string) string {
+	newID := id
+	for i := 1; opf.filePath(newID) != ""; i++ {
+		newID = id + "-" + strconv.Itoa(i)
+	}
+	return newID
+}
+
+// uniqueHref returns href, or href with a number before the extension if
+// it is already in use
+func (opf xmlOPF) uniqueHref(href string) string {
+	ext := path.Ext(href)
+	newHref := href
+	for i := 1; opf.fileID(newHref) != ""; i++ {
+		newHref = strings.TrimSuffix(href, ext) + "-" + strconv.Itoa(i) + ext
+	}
+	return newHref
+}
+
+func (opf *xmlOPF) manifestItem(id string) *manifest {
+	for i := range opf.Manifest {
+		if opf.Manifest[i].ID == id {
+			return &opf.Manifest[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"io/ioutil"
+	"strings"
+)
+
+const (
+	coverPNG     = "\x89PNG\r\n\x1a\nnot really a png"
+	newCoverFile = "@public@vhost@g@gutenberg@html@files@3174@3174-h@images@cover.png"
+)
+
+func TestSetCover(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	if err := f.SetCover(strings.NewReader(coverPNG), "image/bmp"); err == nil {
+		t.Errorf("SetCover(image/bmp) didn't return an error")
+	}
+	if err := f.SetCover(strings.NewReader(coverPNG), "image/png"); err != nil {
+		t.Fatalf("SetCover() return an error: %v", err)
+	}
+
+	book := repackBook(t, f)
+	if href := book.coverHref(); href != newCoverFile {
+		t.Errorf("coverHref() return: %v when was expected: %v", href, newCoverFile)
+	}
+	if mediaType := book.opf.mediaType(newCoverFile); mediaType != "image/png" {
+		t.Errorf("Cover media type: %v", mediaType)
+	}
+	if _, err := book.OpenFile(coverFile); err == nil {
+		t.Errorf("The old cover is still on the epub")
+	}
+
+	cover, err := book.OpenFile(newCoverFile)
+	if err != nil {
+		t.Fatalf("OpenFile(cover) return an error: %v", err)
+	}
+	data, _ := ioutil.ReadAll(cover)
+	cover.Close()
+	if string(data) != coverPNG {
+		t.Errorf("The cover content is not the expected one")
+	}
+
+	page, _ := book.OpenFile("wrap0000.html")
+	html, _ := ioutil.ReadAll(page)
+	page.Close()
+	if !strings.Contains(string(html), `src="`+newCoverFile+`"`) {
+		t.Errorf("The cover page doesn't show the new cover: %s", html)
+	}
+	if page := book.coverPage(); page != "wrap0000.html" {
+		t.Errorf("coverPage() return: %v", page)
+	}
+}
+
+func TestSetCoverEPUB3(t *testing.T) {
+	f := buildEpub(t, epub3OPF, map[string]string{
+		"images/cover.jpg": "jpeg",
+		"text/ch1.xhtml":   `<html><body><img src="../images/cover.jpg"/></body></html>`,
+		"text/ch2.xhtml":   `<html><body><p>text</p></body></html>`,
+	})
+	if err := f.SetCover(strings.NewReader(coverPNG), "image/png"); err != nil {
+		t.Fatalf("SetCover() return an error: %v", err)
+	}
+
+	book := repackBook(t, f)
+	if href := book.coverHref(); href != "images/cover.png" {
+		t.Errorf("coverHref() return: %v", href)
+	}
+	if cover := book.metaContent("cover"); cover != "cover-img" {
+		t.Errorf("The cover meta is: %v", cover)
+	}
+	for _, ref := range book.opf.Guide {
+		if ref.Type == "cover" && ref.Href != "text/ch1.xhtml" {
+			t.Errorf("The guide cover points to: %v", ref.Href)
+		}
+	}
+
+	page, _ := book.OpenFile("text/ch1.xhtml")
+	html, _ := ioutil.ReadAll(page)
+	page.Close()
+	if !strings.Contains(string(html), `src="../images/cover.png"`) {
+		t.Errorf("The cover page doesn't show the new cover: %s", html)
+	}
+}
+
+func TestSetCoverNew(t *testing.T) {
+	f := buildEpub(t, epub3OPF, nil)
+	f.opf.Manifest[1].Properties = ""
+	f.SetMetadata("meta", nil)
+	if err := f.SetCover(strings.NewReader(coverPNG), "image/png"); err != nil {
+		t.Fatalf("SetCover() return an error: %v", err)
+	}
+
+	book := repackBook(t, f)
+	if href := book.coverHref(); href != "cover.png" {
+		t.Errorf("coverHref() return: %v", href)
+	}
+	if cover := book.metaContent("cover"); cover != "cover-image" {
+		t.Errorf("The cover meta is: %v", cover)
+	}
+}
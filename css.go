@@ -0,0 +1,681 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	cssCommentRegexp = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	tagAttrRegexp    = regexp.MustCompile(`\s([\w:.-]+)\s*=\s*("[^"]*"|'[^']*')`)
+)
+
+// cssGroupRules are the at-rules which block contains other rules
+var cssGroupRules = []string{"@media", "@supports", "@document", "@-moz-document"}
+
+// UnusedCSSRule is a style rule of a stylesheet with selectors that don't
+// match any element of the book
+type UnusedCSSRule struct {
+	// Href is the path of the stylesheet, as used by OpenFile
+	Href string
+	// Selector is the selector list of the rule
+	Selector string
+	// Unused are the selectors of the list that don't match any element, all
+	// of them if the whole rule is unused
+	Unused []string
+}
+
+// UnusedCSS returns the rules of the stylesheets of the manifest with
+// selectors not matched by the documents of the book
+//
+// The selectors are matched against the XHTML and SVG documents of the
+// manifest (not only the spine, the navigation document or the cover page
+// use the stylesheets too). The matching errs on the side of the used
+// selectors: the pseudo-classes and pseudo-elements are ignored (a:hover is
+// used if there is any link) and the selectors not supported, like the ones
+// with namespaces or escapes, are considered used. The classes added by
+// scripts are not known.
+func (e Epub) UnusedCSS() ([]UnusedCSSRule, error) {
+	usage, err := e.cssUsage()
+	if err != nil {
+		return nil, err
+	}
+	var unused []UnusedCSSRule
+	var walk func(href string, rules []cssRule)
+	walk = func(href string, rules []cssRule) {
+		for _, rule := range rules {
+			walk(href, rule.children)
+			var selectors []string
+			for _, selector := range rule.selectors {
+				if !usage.isUsed(selector) {
+					selectors = append(selectors, selector)
+				}
+			}
+			if len(selectors) > 0 {
+				unused = append(unused, UnusedCSSRule{href, strings.Join(rule.selectors, ", "), selectors})
+			}
+		}
+	}
+	for _, sheet := range usage.sheets {
+		walk(strings.TrimPrefix(sheet.name, e.rootPath), sheet.rules)
+	}
+	return unused, nil
+}
+
+// PruneCSS returns a transform that removes the unused selectors from the
+// stylesheets, see UnusedCSS
+//
+// The rules without used selectors are removed, and so are the @media and
+// @supports blocks left empty. The rest of the stylesheet (comments, at-rules,
+// formatting) is kept as it is. Don't use it on books that add classes with
+// scripts.
+func (e Epub) PruneCSS() (Transform, error) {
+	usage, err := e.cssUsage()
+	if err != nil {
+		return nil, err
+	}
+	pruned := make(map[string][]byte)
+	for _, sheet := range usage.sheets {
+		var edits []cssEdit
+		usage.pruneRules(sheet.data, sheet.rules, &edits)
+		if len(edits) > 0 {
+			pruned[sheet.name] = applyCSSEdits(sheet.data, edits)
+		}
+	}
+
+	return func(name, mediaType string, r io.Reader) (io.Reader, error) {
+		if data, ok := pruned[name]; ok {
+			return bytes.NewReader(data), nil
+		}
+		return r, nil
+	}, nil
+}
+
+type cssSheet struct {
+	name  string
+	data  []byte
+	rules []cssRule
+}
+
+// cssUsage are the elements of the documents of the book, indexed to match
+// the selectors of the stylesheets
+type cssUsage struct {
+	sheets   []cssSheet
+	elements []*cssElement
+	byID     map[string][]*cssElement
+	byClass  map[string][]*cssElement
+	byName   map[string][]*cssElement
+	used     map[string]bool
+}
+
+func (e Epub) cssUsage() (*cssUsage, error) {
+	usage := &cssUsage{
+		byID:    make(map[string][]*cssElement),
+		byClass: make(map[string][]*cssElement),
+		byName:  make(map[string][]*cssElement),
+		used:    make(map[string]bool),
+	}
+	for _, item := range e.opf.Manifest {
+		name := e.rootPath + item.Href
+		switch item.MediaType {
+		case "application/xhtml+xml", "text/html", svgMediaType:
+			data, err := e.readFile(name)
+			if err != nil {
+				return nil, err
+			}
+			for _, el := range cssElements(data) {
+				usage.elements = append(usage.elements, el)
+				usage.byName[el.name] = append(usage.byName[el.name], el)
+				if id := el.attrs["id"]; id != "" {
+					usage.byID[id] = append(usage.byID[id], el)
+				}
+				for _, class := range el.classes {
+					usage.byClass[class] = append(usage.byClass[class], el)
+				}
+			}
+		case "text/css":
+			data, err := e.readFile(name)
+			if err != nil {
+				return nil, err
+			}
+			rules, _ := parseCSS(data, 0, false)
+			usage.sheets = append(usage.sheets, cssSheet{name, data, rules})
+		}
+	}
+	return usage, nil
+}
+
+// isUsed returns whether the selector matches any element, or can't be
+// matched
+func (u *cssUsage) isUsed(selector string) bool {
+	if used, ok := u.used[selector]; ok {
+		return used
+	}
+	used := true
+	if parts, ok := parseSelector(selector); ok {
+		last := parts[len(parts)-1]
+		candidates := u.elements
+		switch {
+		case last.id != "":
+			candidates = u.byID[last.id]
+		case len(last.classes) > 0:
+			candidates = u.byClass[last.classes[0]]
+		case last.name != "":
+			candidates = u.byName[last.name]
+		}
+		used = false
+		for _, el := range candidates {
+			if matchSelector(parts, len(parts)-1, el) {
+				used = true
+				break
+			}
+		}
+	}
+	u.used[selector] = used
+	return used
+}
+
+// pruneRules adds to edits the removal of the unused selectors of the rules,
+// returns if all the rules are removed
+func (u *cssUsage) pruneRules(data []byte, rules []cssRule, edits *[]cssEdit) bool {
+	removedAll := true
+	for _, rule := range rules {
+		switch {
+		case rule.children != nil:
+			var children []cssEdit
+			if len(rule.children) > 0 && u.pruneRules(data, rule.children, &children) {
+				*edits = append(*edits, cssDeletion(data, rule.start, rule.end))
+				continue
+			}
+			*edits = append(*edits, children...)
+		case rule.selectors != nil:
+			var used []string
+			for _, selector := range rule.selectors {
+				if u.isUsed(selector) {
+					used = append(used, selector)
+				}
+			}
+			if len(used) == 0 {
+				*edits = append(*edits, cssDeletion(data, rule.start, rule.end))
+				continue
+			}
+			if len(used) < len(rule.selectors) {
+				prelude := data[rule.start:rule.preludeEnd]
+				space := prelude[len(bytes.TrimRight(prelude, " \t\r\n\f")):]
+				*edits = append(*edits, cssEdit{rule.start, rule.preludeEnd, strings.Join(used, ", ") + string(space)})
+			}
+		}
+		removedAll = false
+	}
+	return removedAll
+}
+
+// cssRule is a rule of a stylesheet, start and end are its position on the
+// stylesheet and preludeEnd the end of its selectors or at-rule prelude
+type cssRule struct {
+	start, end, preludeEnd int
+	// selectors is nil for the at-rules
+	selectors []string
+	// children are the rules inside a group at-rule (@media, @supports, ...),
+	// nil for the rest of the rules
+	children []cssRule
+}
+
+// parseCSS returns the rules of data from pos until the end of data or of
+// the block that contains them if nested, and the position where they end
+func parseCSS(data []byte, pos int, nested bool) ([]cssRule, int) {
+	var rules []cssRule
+	for {
+		pos = skipCSSSpace(data, pos)
+		if pos < len(data) && data[pos] == '}' && !nested {
+			pos++
+			continue
+		}
+		if pos >= len(data) || data[pos] == '}' {
+			return rules, pos
+		}
+
+		rule := cssRule{start: pos}
+		pos = scanCSS(data, pos, "{;}")
+		rule.preludeEnd = pos
+		prelude := strings.TrimSpace(cssCommentRegexp.ReplaceAllString(string(data[rule.start:pos]), ""))
+		switch {
+		case pos < len(data) && data[pos] == ';':
+			pos++
+		case pos < len(data) && data[pos] == '{':
+			if isCSSGroupRule(prelude) {
+				rule.children, pos = parseCSS(data, pos+1, true)
+				if rule.children == nil {
+					rule.children = []cssRule{}
+				}
+			} else {
+				pos = scanCSS(data, pos+1, "}")
+				if !strings.HasPrefix(prelude, "@") {
+					rule.selectors = splitSelectors(prelude)
+				}
+			}
+			if pos < len(data) {
+				pos++
+			}
+		}
+		rule.end = pos
+		rules = append(rules, rule)
+	}
+}
+
+func isCSSGroupRule(prelude string) bool {
+	prelude = strings.ToLower(prelude)
+	for _, name := range cssGroupRules {
+		if strings.HasPrefix(prelude, name) && (len(prelude) == len(name) || !isIdentByte(prelude[len(name)])) {
+			return true
+		}
+	}
+	return false
+}
+
+// skipCSSSpace returns the position of the first character after pos that is
+// not a space, a comment or an HTML comment delimiter
+func skipCSSSpace(data []byte, pos int) int {
+	for pos < len(data) {
+		switch {
+		case strings.IndexByte(" \t\r\n\f", data[pos]) != -1:
+			pos++
+		case bytes.HasPrefix(data[pos:], []byte("/*")):
+			end := bytes.Index(data[pos+2:], []byte("*/"))
+			if end == -1 {
+				return len(data)
+			}
+			pos += end + 4
+		case bytes.HasPrefix(data[pos:], []byte("<!--")):
+			pos += 4
+		case bytes.HasPrefix(data[pos:], []byte("-->")):
+			pos += 3
+		default:
+			return pos
+		}
+	}
+	return pos
+}
+
+// scanCSS returns the position of the first of the stop characters from pos
+// that is not inside a comment, a string or brackets, or the length of data
+func scanCSS(data []byte, pos int, stops string) int {
+	depth := 0
+	for pos < len(data) {
+		c := data[pos]
+		switch {
+		case c == '/' && bytes.HasPrefix(data[pos:], []byte("/*")):
+			end := bytes.Index(data[pos+2:], []byte("*/"))
+			if end == -1 {
+				return len(data)
+			}
+			pos += end + 4
+			continue
+		case c == '"' || c == '\'':
+			pos++
+			for pos < len(data) && data[pos] != c && data[pos] != '\n' {
+				if data[pos] == '\\' {
+					pos++
+				}
+				pos++
+			}
+		case c == '\\':
+			pos++
+		case depth == 0 && strings.IndexByte(stops, c) != -1:
+			return pos
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case (c == ')' || c == ']' || c == '}') && depth > 0:
+			depth--
+		}
+		pos++
+	}
+	return len(data)
+}
+
+// splitSelectors splits the selector list of a rule
+func splitSelectors(prelude string) []string {
+	data := []byte(prelude)
+	var selectors []string
+	for pos := 0; pos <= len(data); pos++ {
+		end := scanCSS(data, pos, ",")
+		selectors = append(selectors, strings.Join(strings.Fields(string(data[pos:end])), " "))
+		pos = end
+	}
+	return selectors
+}
+
+// cssEdit replaces the bytes from start to end of a stylesheet by text
+type cssEdit struct {
+	start, end int
+	text       string
+}
+
+// cssDeletion returns the edit removing the bytes from start to end, and the
+// line they are on if there is nothing else on it
+func cssDeletion(data []byte, start, end int) cssEdit {
+	lineStart := start
+	for lineStart > 0 && (data[lineStart-1] == ' ' || data[lineStart-1] == '\t') {
+		lineStart--
+	}
+	lineEnd := end
+	for lineEnd < len(data) && (data[lineEnd] == ' ' || data[lineEnd] == '\t' || data[lineEnd] == '\r') {
+		lineEnd++
+	}
+	if (lineStart == 0 || data[lineStart-1] == '\n') && (lineEnd == len(data) || data[lineEnd] == '\n') {
+		if lineEnd < len(data) {
+			lineEnd++
+		}
+		return cssEdit{lineStart, lineEnd, ""}
+	}
+	return cssEdit{start, end, ""}
+}
+
+// applyCSSEdits applies the edits, sorted by position, to data
+func applyCSSEdits(data []byte, edits []cssEdit) []byte {
+	var buff bytes.Buffer
+	last := 0
+	for _, edit := range edits {
+		buff.Write(data[last:edit.start])
+		buff.WriteString(edit.text)
+		last = edit.end
+	}
+	buff.Write(data[last:])
+	return buff.Bytes()
+}
+
+// cssCompound is a compound selector (like div.note#n1), the combinator
+// joins it with the compound before it
+type cssCompound struct {
+	combinator byte
+	name       string
+	id         string
+	classes    []string
+	attrs      []cssAttr
+	// approximate compounds have pseudo-classes, pseudo-elements or
+	// attributes with namespace, that are ignored when matching
+	approximate bool
+}
+
+// cssAttr is an attribute selector, an empty name matches any element
+type cssAttr struct {
+	name, op, value string
+	ignoreCase      bool
+}
+
+// parseSelector parses a complex selector into its compounds, ok is false if
+// the selector is not supported
+func parseSelector(selector string) (parts []cssCompound, ok bool) {
+	var (
+		current    cssCompound
+		empty      = true
+		combinator byte
+	)
+	for i := 0; i < len(selector); {
+		c := selector[i]
+		switch c {
+		case ' ', '\t', '\n', '\r', '\f', '>', '+', '~':
+			if !empty {
+				parts = append(parts, current)
+				current = cssCompound{}
+				empty = true
+				combinator = ' '
+			}
+			if c != ' ' && c != '\t' && c != '\n' && c != '\r' && c != '\f' {
+				if len(parts) == 0 {
+					return nil, false
+				}
+				combinator = c
+			}
+			i++
+			continue
+		}
+		if empty && len(parts) > 0 {
+			current.combinator = combinator
+		}
+
+		switch {
+		case c == '*' && empty:
+			i++
+		case c == '.' || c == '#':
+			name, n := cssIdent(selector[i+1:])
+			if n == 0 {
+				return nil, false
+			}
+			if c == '.' {
+				current.classes = append(current.classes, name)
+			} else {
+				current.id = name
+			}
+			i += n + 1
+		case c == '[':
+			end := strings.IndexByte(selector[i:], ']')
+			if end == -1 {
+				return nil, false
+			}
+			attr, ok := parseAttrSelector(selector[i+1 : i+end])
+			if !ok {
+				return nil, false
+			}
+			current.attrs = append(current.attrs, attr)
+			current.approximate = current.approximate || attr.name == ""
+			i += end + 1
+		case c == ':':
+			// the pseudo-classes and pseudo-elements are ignored, the
+			// selector matches more elements than it does
+			current.approximate = true
+			i++
+			if i < len(selector) && selector[i] == ':' {
+				i++
+			}
+			_, n := cssIdent(selector[i:])
+			if n == 0 {
+				return nil, false
+			}
+			i += n
+			if i < len(selector) && selector[i] == '(' {
+				end := scanCSS([]byte(selector), i+1, ")")
+				if end == len(selector) {
+					return nil, false
+				}
+				i = end + 1
+			}
+		case empty:
+			name, n := cssIdent(selector[i:])
+			if n == 0 {
+				return nil, false
+			}
+			current.name = strings.ToLower(name)
+			i += n
+		default:
+			return nil, false
+		}
+		empty = false
+	}
+	if empty {
+		return nil, false
+	}
+	return append(parts, current), true
+}
+
+// parseAttrSelector parses the content of the brackets of an attribute
+// selector, the attributes with namespace match any element
+func parseAttrSelector(s string) (cssAttr, bool) {
+	var attr cssAttr
+	name := s
+	if i := strings.IndexByte(s, '='); i != -1 {
+		name = s[:i]
+		attr.op = "="
+		if i > 0 && strings.IndexByte("~|^$*", s[i-1]) != -1 {
+			name = s[:i-1]
+			attr.op = s[i-1 : i+1]
+		}
+		value := strings.TrimSpace(s[i+1:])
+		if value != "" && (value[0] == '"' || value[0] == '\'') {
+			end := strings.IndexByte(value[1:], value[0])
+			if end == -1 {
+				return attr, false
+			}
+			attr.ignoreCase = strings.TrimSpace(value[end+2:]) == "i"
+			value = value[1 : end+1]
+		} else if fields := strings.Fields(value); len(fields) == 2 && fields[1] == "i" {
+			value = fields[0]
+			attr.ignoreCase = true
+		}
+		attr.value = value
+	}
+	name = strings.TrimSpace(name)
+	if strings.Contains(name, "|") {
+		return cssAttr{}, true
+	}
+	if _, n := cssIdent(name); n == 0 || n != len(name) {
+		return attr, false
+	}
+	attr.name = strings.ToLower(name)
+	return attr, true
+}
+
+// cssIdent returns the identifier at the beginning of s and its length, the
+// escapes are not supported
+func cssIdent(s string) (string, int) {
+	n := 0
+	for n < len(s) && isIdentByte(s[n]) {
+		n++
+	}
+	return s[:n], n
+}
+
+func isIdentByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '-' || c == '_' || c >= 0x80
+}
+
+// cssElement is an element of a document, with its parent and the sibling
+// before it. start and end are the position of its start tag.
+type cssElement struct {
+	start, end   int
+	name         string
+	attrs        map[string]string
+	classes      []string
+	parent, prev *cssElement
+}
+
+// cssElements returns the elements of the markup document
+func cssElements(data []byte) []*cssElement {
+	type level struct {
+		elem, last *cssElement
+	}
+	var elements []*cssElement
+	levels := []level{{}}
+	for _, loc := range htmlTagRegexp.FindAllSubmatchIndex(data, -1) {
+		closing := loc[3] > loc[2]
+		selfClosing := loc[7] > loc[6]
+		name := strings.ToLower(string(data[loc[4]:loc[5]]))
+		name = name[strings.Index(name, ":")+1:]
+		if closing {
+			for i := len(levels) - 1; i > 0; i-- {
+				if levels[i].elem.name == name {
+					levels = levels[:i]
+					break
+				}
+			}
+			continue
+		}
+
+		top := &levels[len(levels)-1]
+		el := &cssElement{start: loc[0], end: loc[1], name: name, attrs: make(map[string]string), parent: top.elem, prev: top.last}
+		for _, sub := range tagAttrRegexp.FindAllSubmatch(data[loc[0]:loc[1]], -1) {
+			el.attrs[strings.ToLower(string(sub[1]))] = string(sub[2][1 : len(sub[2])-1])
+		}
+		el.classes = strings.Fields(el.attrs["class"])
+		top.last = el
+		elements = append(elements, el)
+		if !selfClosing && !voidElements[name] {
+			levels = append(levels, level{elem: el})
+		}
+	}
+	return elements
+}
+
+// matchSelector returns whether the compounds of a selector up to i match
+// the element
+func matchSelector(parts []cssCompound, i int, el *cssElement) bool {
+	if !parts[i].matches(el) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	switch parts[i].combinator {
+	case '>':
+		return el.parent != nil && matchSelector(parts, i-1, el.parent)
+	case '+':
+		return el.prev != nil && matchSelector(parts, i-1, el.prev)
+	case '~':
+		for sibling := el.prev; sibling != nil; sibling = sibling.prev {
+			if matchSelector(parts, i-1, sibling) {
+				return true
+			}
+		}
+	default:
+		for ancestor := el.parent; ancestor != nil; ancestor = ancestor.parent {
+			if matchSelector(parts, i-1, ancestor) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c cssCompound) matches(el *cssElement) bool {
+	if c.name != "" && c.name != el.name {
+		return false
+	}
+	if c.id != "" && c.id != el.attrs["id"] {
+		return false
+	}
+	for _, class := range c.classes {
+		if !contains(el.classes, class) {
+			return false
+		}
+	}
+	for _, attr := range c.attrs {
+		if !attr.matches(el) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a cssAttr) matches(el *cssElement) bool {
+	if a.name == "" {
+		return true
+	}
+	value, ok := el.attrs[a.name]
+	if !ok || a.op == "" {
+		return ok
+	}
+	expected := a.value
+	if a.ignoreCase {
+		value, expected = strings.ToLower(value), strings.ToLower(expected)
+	}
+	switch a.op {
+	case "~=":
+		return contains(strings.Fields(value), expected)
+	case "|=":
+		return value == expected || strings.HasPrefix(value, expected+"-")
+	case "^=":
+		return expected != "" && strings.HasPrefix(value, expected)
+	case "$=":
+		return expected != "" && strings.HasSuffix(value, expected)
+	case "*=":
+		return expected != "" && strings.Contains(value, expected)
+	}
+	return value == expected
+}
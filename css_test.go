@@ -0,0 +1,102 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import "strings"
+
+const testCSS = `/* book styles */
+body { margin: 0 }
+p.calibre1, .calibre2 { text-indent: 1em }
+.calibre3 { font-weight: bold }
+div > p:first-child { margin-top: 0 }
+h1 + p { text-indent: 0 }
+a[href^="http"]:hover { color: blue }
+[epub|type~="footnote"] { display: none }
+@font-face { font-family: "Serif"; src: url(serif.otf) }
+@media amzn-kf8 {
+  .unused { color: red }
+}
+@media screen {
+  .note, .unused2 { color: gray }
+}
+`
+
+func cssEpub(t *testing.T) *Epub {
+	f := buildEpub(t, epub3OPF, map[string]string{
+		"nav.xhtml":      epub3Nav,
+		"text/ch1.xhtml": `<html><body><h1>One</h1><p class="calibre1">text</p><div><p class="note">note</p></div></body></html>`,
+		"text/ch2.xhtml": `<html><body><p>text</p></body></html>`,
+	})
+	if _, err := f.AddResource("style.css", "text/css", strings.NewReader(testCSS)); err != nil {
+		t.Fatalf("AddResource() return an error: %v", err)
+	}
+	return f
+}
+
+func TestUnusedCSS(t *testing.T) {
+	f := cssEpub(t)
+	rules, err := f.UnusedCSS()
+	if err != nil {
+		t.Fatalf("UnusedCSS() return an error: %v", err)
+	}
+	var unused []string
+	for _, rule := range rules {
+		if rule.Href != "style.css" {
+			t.Errorf("Wrong stylesheet: %v", rule.Href)
+		}
+		unused = append(unused, rule.Unused...)
+	}
+	expected := []string{".calibre2", ".calibre3", `a[href^="http"]:hover`, ".unused", ".unused2"}
+	if strings.Join(unused, " | ") != strings.Join(expected, " | ") {
+		t.Errorf("UnusedCSS() return: %v", unused)
+	}
+}
+
+func TestPruneCSS(t *testing.T) {
+	f := cssEpub(t)
+	prune, err := f.PruneCSS()
+	if err != nil {
+		t.Fatalf("PruneCSS() return an error: %v", err)
+	}
+	book := repackBook(t, f, prune)
+	css := readBookFile(t, book, "style.css")
+	expected := `/* book styles */
+body { margin: 0 }
+p.calibre1 { text-indent: 1em }
+div > p:first-child { margin-top: 0 }
+h1 + p { text-indent: 0 }
+[epub|type~="footnote"] { display: none }
+@font-face { font-family: "Serif"; src: url(serif.otf) }
+@media screen {
+  .note { color: gray }
+}
+`
+	if css != expected {
+		t.Errorf("The pruned stylesheet is:\n%v", css)
+	}
+}
+
+func TestParseSelector(t *testing.T) {
+	for _, selector := range []string{"", "> p", "p >", `.a\:b`, "svg|rect", "p:not("} {
+		if _, ok := parseSelector(selector); ok {
+			t.Errorf("parseSelector(%q) didn't fail", selector)
+		}
+	}
+	parts, ok := parseSelector(`div.a.b#c > p[lang|="en" i] ~ span`)
+	if !ok || len(parts) != 3 {
+		t.Fatalf("parseSelector() return: %v %v", parts, ok)
+	}
+	if parts[0].name != "div" || parts[0].id != "c" || len(parts[0].classes) != 2 {
+		t.Errorf("Wrong first compound: %v", parts[0])
+	}
+	if parts[1].combinator != '>' || parts[1].attrs[0] != (cssAttr{"lang", "|=", "en", true}) {
+		t.Errorf("Wrong second compound: %v", parts[1])
+	}
+	if parts[2].combinator != '~' || parts[2].name != "span" {
+		t.Errorf("Wrong third compound: %v", parts[2])
+	}
+}
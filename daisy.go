@@ -0,0 +1,157 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+const dtbookMediaType = "application/x-dtbook+xml"
+
+var smilTagRegexp = regexp.MustCompile(`<(?:[\w-]+:)?(\w+)\s[^>]*>`)
+
+// DAISYInfo is the metadata of the DAISY 3 talking books, from the dtb meta
+// elements of the package
+type DAISYInfo struct {
+	// MultimediaType is the kind of book: audioOnly, audioNCX, audioPartText,
+	// audioFullText, textPartAudio or textNCX
+	MultimediaType string
+	// MultimediaContent lists the media of the book: audio, text and image
+	MultimediaContent string
+	// TotalTime is the length of the audio, like "10:24:37"
+	TotalTime string
+	Narrator  string
+	Producer  string
+	Revision  string
+}
+
+// IsDAISY returns whether the book is a DAISY 3 (ANSI/NISO Z39.86) talking
+// book
+//
+// DAISY books are packaged like the epubs, with an OPF and an NCX, but their
+// spine lists SMIL files that synchronize the audio with the text of a
+// DTBook document. The Spine and the text methods (Text, Segments, ...) read
+// the part of the DTBook of each SMIL file and the URLs of the Navigation
+// point to the SMIL files, ResolveSMIL returns their place on the text.
+func (e Epub) IsDAISY() bool {
+	if e.metaContent("dtb:multimediaType") != "" {
+		return true
+	}
+	for _, item := range e.opf.Manifest {
+		if item.MediaType == dtbookMediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// DAISYInfo returns the DAISY metadata of the book, empty if it is not a
+// DAISY book
+func (e Epub) DAISYInfo() DAISYInfo {
+	return DAISYInfo{
+		MultimediaType:    e.metaContent("dtb:multimediaType"),
+		MultimediaContent: e.metaContent("dtb:multimediaContent"),
+		TotalTime:         e.metaContent("dtb:totalTime"),
+		Narrator:          e.metaContent("dtb:narrator"),
+		Producer:          e.metaContent("dtb:producer"),
+		Revision:          e.metaContent("dtb:revision"),
+	}
+}
+
+// ResolveSMIL returns the text reference of a reference to a SMIL file, like
+// the URLs of the navigation of DAISY books ("chapter1.smil#par3" may be
+// "book.xml#p3")
+//
+// Both references are relative to the OPF, as used by OpenFile. The text is
+// the first one synchronized on the SMIL at or after the element of the
+// fragment.
+func (e Epub) ResolveSMIL(href string) (string, error) {
+	name, fragment := splitFragment(href)
+	data, err := e.readFile(e.rootPath + name)
+	if err != nil {
+		return "", err
+	}
+
+	found := fragment == ""
+	for _, tag := range smilTagRegexp.FindAllStringSubmatch(string(data), -1) {
+		if !found && attrValue(tag[0], "id") == fragment {
+			found = true
+		}
+		if found && tag[1] == "text" {
+			src := attrValue(tag[0], "src")
+			doc, id := splitFragment(src)
+			ref := strings.TrimPrefix(resolveRef(e.rootPath+name, doc), e.rootPath)
+			if id != "" {
+				ref += "#" + id
+			}
+			return ref, nil
+		}
+	}
+	return "", errors.New("No text found for " + href)
+}
+
+// spineDocument returns the markup of the document at spineIndex, for the
+// SMIL files of the DAISY books the part of the DTBook they synchronize up to
+// the part of the next one
+func (e Epub) spineDocument(spineIndex int) ([]byte, error) {
+	href := e.opf.spineURL(spineIndex)
+	if href == "" {
+		return nil, errors.New("Spine item " + e.opf.Spine.Items[spineIndex].IDref + " is not in the manifest")
+	}
+	if !isSMIL(e.opf.mediaType(href)) {
+		return e.readFile(e.rootPath + href)
+	}
+
+	ref, err := e.ResolveSMIL(href)
+	if err != nil {
+		return nil, err
+	}
+	doc, id := splitFragment(ref)
+	data, err := e.readFile(e.rootPath + doc)
+	if err != nil {
+		return nil, err
+	}
+	start := idOffset(data, id)
+	if start == -1 {
+		start = 0
+	}
+	end := len(data)
+	if spineIndex+1 < e.opf.spineLength() {
+		next, err := e.ResolveSMIL(e.opf.spineURL(spineIndex + 1))
+		if nextDoc, nextID := splitFragment(next); err == nil && nextDoc == doc {
+			if offset := idOffset(data, nextID); offset > start {
+				end = offset
+			}
+		}
+	}
+	return data[start:end], nil
+}
+
+func isSMIL(mediaType string) bool {
+	return mediaType == "application/smil" || mediaType == "application/smil+xml"
+}
+
+func splitFragment(href string) (string, string) {
+	if i := strings.Index(href, "#"); i != -1 {
+		return href[:i], href[i+1:]
+	}
+	return href, ""
+}
+
+// idOffset returns the position of the tag with the id on the document, -1
+// if there is none
+func idOffset(data []byte, id string) int {
+	if id == "" {
+		return -1
+	}
+	re := regexp.MustCompile(`<[^>]*\sid\s*=\s*["']` + regexp.QuoteMeta(id) + `["']`)
+	loc := re.FindIndex(data)
+	if loc == nil {
+		return -1
+	}
+	return loc[0]
+}
@@ -0,0 +1,111 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"archive/zip"
+	"bytes"
+)
+
+var daisyFiles = map[string]string{
+	"book.opf": `<?xml version="1.0"?>
+<package xmlns="http://openebook.org/namespaces/oeb-package/1.0/" unique-identifier="uid">
+  <metadata>
+    <dc-metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+      <dc:Title>Talking Book</dc:Title>
+      <dc:Identifier id="uid">daisy-123</dc:Identifier>
+      <dc:Language>en</dc:Language>
+    </dc-metadata>
+    <x-metadata>
+      <meta name="dtb:multimediaType" content="audioFullText"/>
+      <meta name="dtb:totalTime" content="0:10:00"/>
+      <meta name="dtb:narrator" content="Some Voice"/>
+    </x-metadata>
+  </metadata>
+  <manifest>
+    <item id="opf" href="book.opf" media-type="text/xml"/>
+    <item id="navigation" href="book.ncx" media-type="application/x-dtbncx+xml"/>
+    <item id="text" href="book.xml" media-type="application/x-dtbook+xml"/>
+    <item id="smil1" href="part1.smil" media-type="application/smil"/>
+    <item id="smil2" href="part2.smil" media-type="application/smil"/>
+  </manifest>
+  <spine>
+    <itemref idref="smil1"/>
+    <itemref idref="smil2"/>
+  </spine>
+</package>`,
+	"book.ncx": `<?xml version="1.0"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1">
+  <head/>
+  <docTitle><text>Talking Book</text></docTitle>
+  <navMap>
+    <navPoint id="nav1" playOrder="1"><navLabel><text>First</text></navLabel><content src="part1.smil#par1"/></navPoint>
+    <navPoint id="nav2" playOrder="2"><navLabel><text>Second</text></navLabel><content src="part2.smil#par2"/></navPoint>
+  </navMap>
+</ncx>`,
+	"book.xml": `<?xml version="1.0"?>
+<dtbook xmlns="http://www.daisy.org/z3986/2005/dtbook/"><head/><book><bodymatter>
+<level1 id="l1"><h1 id="h1">First</h1><p id="p1">One fish.</p></level1>
+<level1 id="l2"><h1 id="h2">Second</h1><p id="p2">Two fish.</p></level1>
+</bodymatter></book></dtbook>`,
+	"part1.smil": `<?xml version="1.0"?>
+<smil xmlns="http://www.w3.org/2001/SMIL20/"><body><seq id="seq1">
+<par id="par1"><text src="book.xml#h1"/><audio src="1.mp3" clipBegin="0:00:00" clipEnd="0:00:02"/></par>
+<par id="par1b"><text src="book.xml#p1"/><audio src="1.mp3" clipBegin="0:00:02" clipEnd="0:00:04"/></par>
+</seq></body></smil>`,
+	"part2.smil": `<?xml version="1.0"?>
+<smil xmlns="http://www.w3.org/2001/SMIL20/"><body><seq id="seq2">
+<par id="par2"><text src="book.xml#h2"/><audio src="2.mp3" clipBegin="0:00:00" clipEnd="0:00:02"/></par>
+<par id="par2b"><text src="book.xml#p2"/><audio src="2.mp3" clipBegin="0:00:02" clipEnd="0:00:04"/></par>
+</seq></body></smil>`,
+}
+
+func TestDAISY(t *testing.T) {
+	var buff bytes.Buffer
+	w := zip.NewWriter(&buff)
+	for name, content := range daisyFiles {
+		f, _ := w.Create("dtb/" + name)
+		f.Write([]byte(content))
+	}
+	w.Close()
+	book, err := Load(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+	if err != nil {
+		t.Fatalf("Load() return an error: %v", err)
+	}
+
+	if !book.IsDAISY() {
+		t.Errorf("IsDAISY() return false")
+	}
+	info := book.DAISYInfo()
+	if info.MultimediaType != "audioFullText" || info.TotalTime != "0:10:00" || info.Narrator != "Some Voice" {
+		t.Errorf("DAISYInfo() return: %+v", info)
+	}
+	if title, _ := book.Metadata("title"); len(title) != 1 || title[0] != "Talking Book" {
+		t.Errorf("Metadata(title) return: %v", title)
+	}
+
+	for i, expected := range []string{"First\nOne fish.", "Second\nTwo fish."} {
+		if text, err := book.Text(i); err != nil || text != expected {
+			t.Errorf("Text(%d) return: %q, %v", i, text, err)
+		}
+	}
+
+	nav, err := book.Navigation()
+	if err != nil {
+		t.Fatalf("Navigation() return an error: %v", err)
+	}
+	nav.Next()
+	if nav.Title() != "Second" || nav.URL() != "part2.smil#par2" {
+		t.Errorf("Navigation() second point: %v %v", nav.Title(), nav.URL())
+	}
+	if ref, err := book.ResolveSMIL(nav.URL()); err != nil || ref != "book.xml#h2" {
+		t.Errorf("ResolveSMIL(%v) return: %v, %v", nav.URL(), ref, err)
+	}
+	if ref, err := book.ResolveSMIL("part1.smil#par1b"); err != nil || ref != "book.xml#p1" {
+		t.Errorf("ResolveSMIL() return: %v, %v", ref, err)
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// descriptionSchemes are the schemes of the links kept on the descriptions
+var descriptionSchemes = map[string]bool{"": true, "http": true, "https": true, "mailto": true}
+
+// descriptionTags are the tags kept on the descriptions, the ones the
+// retailers render on their book pages
+var descriptionTags = map[string]bool{
+	"p": true, "br": true, "b": true, "strong": true, "i": true, "em": true,
+	"u": true, "ul": true, "ol": true, "li": true, "blockquote": true, "a": true,
+}
+
+var (
+	descTagRegexp    = regexp.MustCompile(`</?([a-zA-Z][\w-]*)\b[^>]*>`)
+	descDropRegexp   = regexp.MustCompile(`(?is)<(script|style)\b[^>]*>.*?</(?:script|style)>|<!--.*?-->`)
+	descEntityRegexp = regexp.MustCompile(`&(?:[a-zA-Z]+|#[0-9]+|#[xX][0-9a-fA-F]+);`)
+	paragraphRegexp  = regexp.MustCompile(`\n\s*\n`)
+)
+
+// Description returns the description of the book
+//
+// The description can contain HTML markup, as set by SetDescription.
+func (e Epub) Description() string {
+	if elems := e.metadata["description"]; len(elems) > 0 {
+		return elems[0].Content
+	}
+	return ""
+}
+
+// SetDescription replaces the description of the book
+//
+// The html is cleaned up to the basic markup supported by the retailers
+// (paragraphs, line breaks, emphasis, lists and links), any other tag is
+// removed keeping its text. Only the relative, http, https and mailto links
+// keep their href, the others (like javascript:) are removed. Plain text is converted to paragraphs on the
+// blank lines. The description is stored escaped on the dc:description
+// element, the attributes of the previous description (like the language)
+// are preserved. An empty html removes the description.
+func (e *Epub) SetDescription(html string) error {
+	html = cleanDescription(html)
+	if html == "" {
+		return e.SetMetadata("description", nil)
+	}
+
+	elem := MdataElement{Content: html}
+	if elems := e.metadata["description"]; len(elems) > 0 {
+		elem.Attr = elems[0].Attr
+	}
+	return e.SetMetadata("description", []MdataElement{elem})
+}
+
+func cleanDescription(html string) string {
+	html = strings.TrimSpace(html)
+	if !descTagRegexp.MatchString(html) {
+		return textToHTML(html)
+	}
+
+	html = descDropRegexp.ReplaceAllString(html, "")
+	var buff strings.Builder
+	last := 0
+	for _, loc := range descTagRegexp.FindAllStringSubmatchIndex(html, -1) {
+		buff.WriteString(escapeText(html[last:loc[0]]))
+		last = loc[1]
+
+		tag := html[loc[0]:loc[1]]
+		name := strings.ToLower(html[loc[2]:loc[3]])
+		if !descriptionTags[name] {
+			continue
+		}
+		switch {
+		case name == "br":
+			buff.WriteString("<br/>")
+		case strings.HasPrefix(tag, "</"):
+			buff.WriteString("</" + name + ">")
+		case name == "a":
+			buff.WriteString("<a")
+			if href, ok := descriptionHref(attrValue(tag, "href")); ok {
+				buff.WriteString(` href="` + escapeXML(href) + `"`)
+			}
+			buff.WriteString(">")
+		default:
+			buff.WriteString("<" + name + ">")
+		}
+	}
+	buff.WriteString(escapeText(html[last:]))
+	return strings.TrimSpace(buff.String())
+}
+
+// descriptionHref returns the href of a link unescaped, or false if it is
+// empty or its scheme is not allowed on the descriptions
+func descriptionHref(href string) (string, bool) {
+	href = strings.TrimSpace(html.UnescapeString(href))
+	// the browsers ignore the control characters and spaces of the scheme,
+	// like in "java\tscript:"
+	stripped := strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, href)
+	if stripped == "" {
+		return "", false
+	}
+	u, err := url.Parse(stripped)
+	if err != nil || !descriptionSchemes[u.Scheme] {
+		return "", false
+	}
+	return href, true
+}
+
+// textToHTML converts plain text into paragraphs
+func textToHTML(text string) string {
+	if text == "" {
+		return ""
+	}
+	var paragraphs []string
+	for _, p := range paragraphRegexp.Split(text, -1) {
+		p = strings.Replace(escapeText(strings.TrimSpace(p)), "\n", "<br/>", -1)
+		paragraphs = append(paragraphs, "<p>"+p+"</p>")
+	}
+	return strings.Join(paragraphs, "")
+}
+
+// escapeText escapes the text of the html keeping the entities already
+// escaped
+func escapeText(text string) string {
+	var buff strings.Builder
+	last := 0
+	for _, loc := range descEntityRegexp.FindAllStringIndex(text, -1) {
+		buff.WriteString(escapeHTMLChars(text[last:loc[0]]))
+		buff.WriteString(text[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	buff.WriteString(escapeHTMLChars(text[last:]))
+	return buff.String()
+}
+
+func escapeHTMLChars(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
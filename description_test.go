@@ -0,0 +1,58 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import "strings"
+
+const (
+	descriptionHTML  = `<div class="blurb"><p>A <b>dog</b> &amp; her <i>master</i>.</p><script>alert(1)</script><p>Read <a href="http://example.com" onclick="x()">more</a><br>now</p></div>`
+	descriptionClean = `<p>A <b>dog</b> &amp; her <i>master</i>.</p><p>Read <a href="http://example.com">more</a><br/>now</p>`
+)
+
+func TestCleanDescription(t *testing.T) {
+	tests := []struct {
+		html, expected string
+	}{
+		{descriptionHTML, descriptionClean},
+		{"Tom & Jerry\n\n1 < 2", "<p>Tom &amp; Jerry</p><p>1 &lt; 2</p>"},
+		{"first line\nsecond line", "<p>first line<br/>second line</p>"},
+		{"  ", ""},
+		{`<p><a href="javascript:alert(1)">x</a> <a href=" java&#x09;script:alert(1)">y</a> <a href="data:text/html,x">z</a></p>`,
+			`<p><a>x</a> <a>y</a> <a>z</a></p>`},
+		{`<p><a href="mailto:a@example.com">a</a> <a href="../about.xhtml?a=1&amp;b=2">b</a></p>`,
+			`<p><a href="mailto:a@example.com">a</a> <a href="../about.xhtml?a=1&amp;b=2">b</a></p>`},
+	}
+	for _, test := range tests {
+		if html := cleanDescription(test.html); html != test.expected {
+			t.Errorf("cleanDescription(%q) return: %q when was expected: %q", test.html, html, test.expected)
+		}
+	}
+}
+
+func TestSetDescription(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	if err := f.SetDescription(descriptionHTML); err != nil {
+		t.Fatalf("SetDescription() return an error: %v", err)
+	}
+
+	book := repackBook(t, f)
+	if description := book.Description(); description != descriptionClean {
+		t.Errorf("Description() return: %v when was expected: %v", description, descriptionClean)
+	}
+
+	opf, _ := book.readFile(book.opfPath)
+	if !strings.Contains(string(opf), "&lt;b&gt;dog&lt;/b&gt; &amp;amp; her") {
+		t.Errorf("The description is not escaped on the OPF")
+	}
+
+	book.SetDescription("")
+	if description := book.Description(); description != "" {
+		t.Errorf("The description was not removed: %v", description)
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DuplicateResources returns the groups of files with identical content
+//
+// Each group is sorted putting first the files declared on the manifest.
+// Empty files are not reported.
+func (e Epub) DuplicateResources() ([][]string, error) {
+	m, err := e.IntegrityManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	byHash := make(map[string][]string)
+	for name, hash := range m {
+		if name == mimetypeName || e.size(name) == 0 {
+			continue
+		}
+		byHash[hash] = append(byHash[hash], name)
+	}
+
+	var groups [][]string
+	for _, names := range byHash {
+		if len(names) < 2 {
+			continue
+		}
+		sort.Slice(names, func(i, j int) bool {
+			inI := e.inManifest(names[i])
+			inJ := e.inManifest(names[j])
+			if inI != inJ {
+				return inI
+			}
+			return names[i] < names[j]
+		})
+		groups = append(groups, names)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i][0] < groups[j][0] })
+	return groups, nil
+}
+
+// Deduplicate returns a transform that drops the duplicated resources
+//
+// Only the first file of each group returned by DuplicateResources is kept,
+// the references to the others in the content documents, CSS, NCX and OPF
+// are rewritten to point to it and their manifest items are removed. The
+// spine and the cover meta point to the item kept, a document that ends up
+// twice on the spine is kept only on its first position.
+func (e Epub) Deduplicate() (Transform, error) {
+	groups, err := e.DuplicateResources()
+	if err != nil {
+		return nil, err
+	}
+
+	renames := make(map[string]string)
+	idRenames := make(map[string]string)
+	for _, group := range groups {
+		keepID := e.opf.fileID(strings.TrimPrefix(group[0], e.rootPath))
+		for _, name := range group[1:] {
+			renames[name] = group[0]
+			id := e.opf.fileID(strings.TrimPrefix(name, e.rootPath))
+			if id != "" && keepID != "" {
+				idRenames[id] = keepID
+			}
+		}
+	}
+
+	return func(name, mediaType string, r io.Reader) (io.Reader, error) {
+		if _, ok := renames[name]; ok {
+			return nil, nil
+		}
+		if name != e.opfPath && !isMarkup(mediaType) {
+			return r, nil
+		}
+
+		data, err := readAll(r)
+		if err != nil {
+			return nil, err
+		}
+		if name == e.opfPath {
+			data = removeManifestItems(data, name, renames)
+			data = renameIDRefs(data, idRenames)
+		}
+		return bytes.NewReader(rewriteRefs(data, name, renames)), nil
+	}, nil
+}
+
+// removeManifestItems removes from the OPF the items pointing to the files of
+// the removed map
+func removeManifestItems(opf []byte, opfPath string, removed map[string]string) []byte {
+	return itemTagRegexp.ReplaceAllFunc(opf, func(tag []byte) []byte {
+		href := attrValue(string(tag), "href")
+		if _, ok := removed[resolveRef(opfPath, href)]; ok {
+			return nil
+		}
+		return tag
+	})
+}
+
+var (
+	itemrefTagRegexp = regexp.MustCompile(`\s*<(?:[\w-]+:)?itemref\s[^>]*>(?:\s*</(?:[\w-]+:)?itemref>)?`)
+	metaTagRegexp    = regexp.MustCompile(`<(?:[\w-]+:)?meta\s[^>]*>`)
+)
+
+// renameIDRefs points to the new ids the itemrefs of the spine and the EPUB 2
+// cover meta, the itemrefs that end up pointing to an item already on the
+// spine are dropped
+func renameIDRefs(data []byte, renames map[string]string) []byte {
+	seen := make(map[string]bool)
+	data = itemrefTagRegexp.ReplaceAllFunc(data, func(tag []byte) []byte {
+		idref := attrValue(string(tag), "idref")
+		if newID, ok := renames[idref]; ok {
+			idref = newID
+			tag = replaceAttr(tag, "idref", newID)
+		}
+		if seen[idref] {
+			return nil
+		}
+		seen[idref] = true
+		return tag
+	})
+	return metaTagRegexp.ReplaceAllFunc(data, func(tag []byte) []byte {
+		if attrValue(string(tag), "name") != "cover" {
+			return tag
+		}
+		if newID, ok := renames[attrValue(string(tag), "content")]; ok {
+			tag = replaceAttr(tag, "content", newID)
+		}
+		return tag
+	})
+}
+
+// replaceAttr sets the value of the attribute name of tag
+func replaceAttr(tag []byte, name, value string) []byte {
+	re := regexp.MustCompile(`\s` + regexp.QuoteMeta(name) + `\s*=\s*("[^"]*"|'[^']*')`)
+	return re.ReplaceAllFunc(tag, func(attr []byte) []byte {
+		i := bytes.IndexAny(attr, `"'`)
+		return append(attr[:i:i], `"`+escapeXML(value)+`"`...)
+	})
+}
+
+func (e Epub) size(name string) int64 {
+	if data, ok := e.staged[name]; ok {
+		// removed files are staged as nil, so their size is 0
+		return int64(len(data))
+	}
+	for _, f := range e.zip.File {
+		if f.Name == name {
+			return int64(f.UncompressedSize64)
+		}
+	}
+	return 0
+}
+
+func (e Epub) inManifest(name string) bool {
+	return e.opf.fileID(strings.TrimPrefix(name, e.rootPath)) != ""
+}
@@ -0,0 +1,109 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"io/ioutil"
+	"strings"
+)
+
+const (
+	coverFile = "@public@vhost@g@gutenberg@html@files@3174@3174-h@images@cover.jpg"
+	coverCopy = "images/cover-copy.jpg"
+)
+
+func stageDuplicateCover(f *Epub) {
+	cover, _ := f.OpenFile(coverFile)
+	data, _ := ioutil.ReadAll(cover)
+	cover.Close()
+	f.stage("3174/"+coverCopy, data)
+
+	page, _ := f.OpenFile(spineURL)
+	html, _ := ioutil.ReadAll(page)
+	page.Close()
+	f.stage("3174/"+spineURL, []byte(strings.Replace(string(html), coverFile, coverCopy, 1)))
+}
+
+func TestDuplicateResources(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	groups, err := f.DuplicateResources()
+	if err != nil {
+		t.Fatalf("DuplicateResources() return an error: %v", err)
+	}
+	if len(groups) != 0 {
+		t.Errorf("DuplicateResources() return: %v", groups)
+	}
+
+	stageDuplicateCover(f)
+	groups, _ = f.DuplicateResources()
+	if len(groups) != 1 || len(groups[0]) != 2 || groups[0][0] != "3174/"+coverFile {
+		t.Errorf("DuplicateResources() return: %v", groups)
+	}
+}
+
+func TestDeduplicate(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	stageDuplicateCover(f)
+	dedup, err := f.Deduplicate()
+	if err != nil {
+		t.Fatalf("Deduplicate() return an error: %v", err)
+	}
+	book := repackBook(t, f, dedup)
+	if _, err := book.OpenFile(coverCopy); err == nil {
+		t.Errorf("%v was not dropped from the repacked epub", coverCopy)
+	}
+
+	page, _ := book.OpenFile(spineURL)
+	defer page.Close()
+	html, _ := ioutil.ReadAll(page)
+	if !strings.Contains(string(html), `src="`+coverFile+`"`) {
+		t.Errorf("The reference to %v was not rewritten", coverCopy)
+	}
+}
+
+func TestRewriteRefs(t *testing.T) {
+	html := `<a href="../text/ch1.xhtml#note">x</a><img src='img/a.png'/><style>p { background: url(img/a.png) }</style><a href="http://example.com/img/a.png">`
+	renames := map[string]string{
+		"OEBPS/text/ch1.xhtml": "OEBPS/ch1.xhtml",
+		"OEBPS/css/img/a.png":  "OEBPS/images/a.png",
+	}
+	result := string(rewriteRefs([]byte(html), "OEBPS/css/style.xhtml", renames))
+	expected := `<a href="../ch1.xhtml#note">x</a><img src='../images/a.png'/><style>p { background: url(../images/a.png) }</style><a href="http://example.com/img/a.png">`
+	if result != expected {
+		t.Errorf("rewriteRefs() return: %v when was expected: %v", result, expected)
+	}
+}
+
+func TestRenameIDRefs(t *testing.T) {
+	opf := `<metadata>
+    <meta name="cover" content="cover2"/>
+    <meta name="calibre:user_categories" content="ch2"/>
+    <meta property="dcterms:alternative" content="ch2"/>
+  </metadata>
+  <spine>
+    <itemref idref="ch1"/>
+    <itemref idref='ch2'/>
+    <itemref idref="ch3"></itemref>
+  </spine>`
+	expected := `<metadata>
+    <meta name="cover" content="cover1"/>
+    <meta name="calibre:user_categories" content="ch2"/>
+    <meta property="dcterms:alternative" content="ch2"/>
+  </metadata>
+  <spine>
+    <itemref idref="ch1"/>
+    <itemref idref="ch3"></itemref>
+  </spine>`
+	result := string(renameIDRefs([]byte(opf), map[string]string{"ch2": "ch1", "cover2": "cover1"}))
+	if result != expected {
+		t.Errorf("renameIDRefs() return: %v when was expected: %v", result, expected)
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"bytes"
+	"io"
+	"sort"
+)
+
+// Kinds of the FileChange of a Diff
+const (
+	FileAdded    = "added"
+	FileRemoved  = "removed"
+	FileModified = "modified"
+)
+
+// EditSession stages changes to a book in memory without modifying it, so
+// they can be reviewed with Diff before being written with Commit
+type EditSession struct {
+	base []byte
+	book *Epub
+}
+
+// EditDiff are the changes staged on an EditSession
+type EditDiff struct {
+	// Metadata are the values of the metadata modified, added (empty Old) or
+	// removed (empty New)
+	Metadata []MetadataChange
+	// Files are the files of the zip modified, sorted by name
+	Files []FileChange
+}
+
+// FileChange is a file of the zip added, removed or modified
+type FileChange struct {
+	// Name is the path of the file inside the zip
+	Name string
+	// Kind is FileAdded, FileRemoved or FileModified
+	Kind string
+}
+
+// Edit starts an EditSession on a copy of the book
+//
+// The changes pending of Repack on the book are part of the copy. The book
+// itself is never modified by the session.
+func (e Epub) Edit() (*EditSession, error) {
+	var buff bytes.Buffer
+	if err := e.Repack(&buff); err != nil {
+		return nil, err
+	}
+	s := &EditSession{base: buff.Bytes()}
+	return s, s.Rollback()
+}
+
+// Book returns the copy of the book where the changes are staged
+//
+// All the methods of Epub can be used on it to modify the book, the changes
+// are discarded by Rollback.
+func (s *EditSession) Book() *Epub {
+	return s.book
+}
+
+// SetMetadata stages the replacement of the values of a metadata field, like
+// Epub.SetMetadata
+func (s *EditSession) SetMetadata(field string, elems []MdataElement) error {
+	return s.book.SetMetadata(field, elems)
+}
+
+// SetFile stages the new content of a file, name is a path as used by
+// OpenFile. The file is added if it doesn't exist.
+func (s *EditSession) SetFile(name string, data []byte) {
+	s.book.stage(s.book.rootPath+name, data)
+}
+
+// Diff returns the changes staged on the session
+func (s *EditSession) Diff() (*EditDiff, error) {
+	orig, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	diff := &EditDiff{}
+	for _, field := range metadataFieldOrder {
+		before, after := orig.metadata[field], s.book.metadata[field]
+		for i := 0; i < len(before) || i < len(after); i++ {
+			var change MetadataChange
+			change.Field = field
+			if i < len(before) {
+				change.Old = before[i].Content
+			}
+			if i < len(after) {
+				change.New = after[i].Content
+			}
+			if change.Old != change.New {
+				diff.Metadata = append(diff.Metadata, change)
+			}
+		}
+	}
+
+	pending, err := s.book.pendingFiles()
+	if err != nil {
+		return nil, err
+	}
+	for name, data := range pending {
+		inZip := orig.inZip(name)
+		switch {
+		case data == nil && inZip:
+			diff.Files = append(diff.Files, FileChange{name, FileRemoved})
+		case data == nil:
+		case !inZip:
+			diff.Files = append(diff.Files, FileChange{name, FileAdded})
+		default:
+			origData, err := orig.readFile(name)
+			if err != nil {
+				return nil, err
+			}
+			if !bytes.Equal(origData, data) {
+				diff.Files = append(diff.Files, FileChange{name, FileModified})
+			}
+		}
+	}
+	sort.Slice(diff.Files, func(i, j int) bool {
+		return diff.Files[i].Name < diff.Files[j].Name
+	})
+	return diff, nil
+}
+
+// Commit writes into w the book with the changes staged, like Repack
+//
+// The session keeps the changes, more of them can be staged and committed
+// again.
+func (s *EditSession) Commit(w io.Writer, transforms ...Transform) error {
+	return s.book.Repack(w, transforms...)
+}
+
+// Rollback discards all the changes staged on the session
+func (s *EditSession) Rollback() error {
+	book, err := s.load()
+	if err != nil {
+		return err
+	}
+	s.book = book
+	return nil
+}
+
+// load returns a new copy of the book as it was when the session started
+func (s *EditSession) load() (*Epub, error) {
+	return Load(bytes.NewReader(s.base), int64(len(s.base)))
+}
@@ -0,0 +1,84 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"bytes"
+	"reflect"
+)
+
+func TestEditSession(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	s, err := f.Edit()
+	if err != nil {
+		t.Fatalf("Edit() return an error: %v", err)
+	}
+	if diff, err := s.Diff(); err != nil || len(diff.Metadata) != 0 || len(diff.Files) != 0 {
+		t.Errorf("Diff() of a new session return: %v, %v", diff, err)
+	}
+
+	chapter := f.opf.spineURL(0)
+	s.SetMetadata("title", []MdataElement{{Content: "A Cat's Tale"}})
+	s.SetFile(chapter, []byte("<html><body><p>Meow</p></body></html>"))
+	s.SetFile("notes.txt", []byte("notes"))
+	if title, _ := f.Metadata("title"); title[0] != "A Dog's Tale" {
+		t.Errorf("The session modified the book: %v", title)
+	}
+
+	diff, err := s.Diff()
+	if err != nil {
+		t.Fatalf("Diff() return an error: %v", err)
+	}
+	if !reflect.DeepEqual(diff.Metadata, []MetadataChange{{"title", "A Dog's Tale", "A Cat's Tale"}}) {
+		t.Errorf("Diff() metadata return: %v", diff.Metadata)
+	}
+	files := []FileChange{
+		{f.rootPath + chapter, FileModified},
+		{f.opfPath, FileModified},
+		{f.rootPath + "notes.txt", FileAdded},
+	}
+	for _, change := range files {
+		found := false
+		for _, c := range diff.Files {
+			found = found || c == change
+		}
+		if !found {
+			t.Errorf("Diff() files doesn't have %v: %v", change, diff.Files)
+		}
+	}
+	if len(diff.Files) != len(files) {
+		t.Errorf("Diff() files return: %v", diff.Files)
+	}
+
+	var buff bytes.Buffer
+	if err := s.Commit(&buff); err != nil {
+		t.Fatalf("Commit() return an error: %v", err)
+	}
+	book, err := Load(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+	if err != nil {
+		t.Fatalf("Load() return an error: %v", err)
+	}
+	if title, _ := book.Metadata("title"); title[0] != "A Cat's Tale" {
+		t.Errorf("The committed title is: %v", title)
+	}
+	if data := readBookFile(t, book, "notes.txt"); data != "notes" {
+		t.Errorf("The committed file is: %v", data)
+	}
+
+	s.Book().RemoveSpineItem(0)
+	if err := s.Rollback(); err != nil {
+		t.Fatalf("Rollback() return an error: %v", err)
+	}
+	if diff, err := s.Diff(); err != nil || len(diff.Metadata) != 0 || len(diff.Files) != 0 {
+		t.Errorf("Diff() after Rollback() return: %v, %v", diff, err)
+	}
+	if title, _ := s.Book().Metadata("title"); title[0] != "A Dog's Tale" {
+		t.Errorf("The title after Rollback() is: %v", title)
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"errors"
+	"sort"
+)
+
+// MetadataQuery is the information used to look up a book on a MetadataProvider
+type MetadataQuery struct {
+	ISBN   string
+	Title  string
+	Author string
+}
+
+// MetadataProvider looks up the metadata of a book on a remote service
+//
+// Lookup returns the metadata fields found (with the same names used by
+// Metadata()), or nil if the book was not found.
+type MetadataProvider interface {
+	Lookup(q MetadataQuery) (map[string][]MdataElement, error)
+}
+
+// MergePolicy decides how the fetched metadata is merged into the book
+type MergePolicy int
+
+const (
+	// FillMissing only sets the fields that the book doesn't have
+	FillMissing MergePolicy = iota
+	// PreferProvider replaces the fields of the book with the fetched ones
+	PreferProvider
+)
+
+// Query returns the MetadataQuery that identifies the book
+func (e Epub) Query() MetadataQuery {
+	var q MetadataQuery
+	for _, elem := range e.metadata["identifier"] {
+		id := ParseIdentifier(elem.Content, elem.Attr["scheme"])
+		if id.Scheme == SchemeISBN && id.Valid {
+			q.ISBN = id.Value
+			break
+		}
+	}
+	if titles := e.Titles(); len(titles) > 0 {
+		q.Title = titles[0].Content
+	}
+	if authors := e.Contributors(RoleAuthor); len(authors) > 0 {
+		q.Author = authors[0].Name
+	}
+	return q
+}
+
+// Enrich looks up the book on the providers and merges the fetched metadata
+//
+// The providers take precedence in the order they are given: a field is taken
+// from the first provider that returns it. The policy decides if the fetched
+// fields replace the ones of the book. The errors of the providers don't stop
+// the enrichment, they are returned together once all the providers were
+// queried.
+func Enrich(e *Epub, policy MergePolicy, providers ...MetadataProvider) error {
+	q := e.Query()
+	fetched := make(map[string][]MdataElement)
+	var errs []error
+	for _, provider := range providers {
+		result, err := provider.Lookup(q)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for field, elems := range result {
+			if _, ok := fetched[field]; !ok && len(elems) > 0 {
+				fetched[field] = elems
+			}
+		}
+	}
+
+	if err := e.ApplyMetadata(fetched, policy); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// ApplyMetadata sets the metadata fields of updates following the policy
+//
+// With FillMissing only the fields that the book doesn't have are set, with
+// PreferProvider all of them are replaced. Fields with no elements are
+// ignored. All the fields are applied even if some of them fail, the errors
+// are returned together.
+func (e *Epub) ApplyMetadata(updates map[string][]MdataElement, policy MergePolicy) error {
+	var errs []error
+	for _, field := range sortedFields(updates) {
+		elems := updates[field]
+		if len(elems) == 0 {
+			continue
+		}
+		if _, ok := e.metadata[field]; ok && policy == FillMissing {
+			continue
+		}
+		if err := e.SetMetadata(field, elems); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func sortedFields(m map[string][]MdataElement) []string {
+	fields := make([]string, 0, len(m))
+	for field := range m {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
@@ -0,0 +1,111 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"errors"
+)
+
+const (
+	enrichedDescription = "A dog tells the story of her life."
+	enrichedPublisher   = "Harper & Brothers"
+)
+
+type fakeProvider struct {
+	fields map[string][]MdataElement
+	err    error
+	query  MetadataQuery
+}
+
+func (p *fakeProvider) Lookup(q MetadataQuery) (map[string][]MdataElement, error) {
+	p.query = q
+	return p.fields, p.err
+}
+
+func TestEnrich(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	failing := &fakeProvider{err: errors.New("network down")}
+	first := &fakeProvider{fields: map[string][]MdataElement{
+		"title":       {{Content: "Other title"}},
+		"description": {{Content: enrichedDescription}},
+	}}
+	second := &fakeProvider{fields: map[string][]MdataElement{
+		"description": {{Content: "Ignored"}},
+		"publisher":   {{Content: enrichedPublisher}},
+	}}
+
+	if err := Enrich(f, FillMissing, failing, first, second); err == nil {
+		t.Errorf("Enrich() didn't return the error of the failing provider")
+	}
+	if first.query.Title != bookTitle || first.query.Author != bookCreator {
+		t.Errorf("Lookup() was called with: %v", first.query)
+	}
+
+	book := repackBook(t, f)
+	if title, _ := book.Metadata("title"); title[0] != bookTitle {
+		t.Errorf("Metadata title '%v', the expected was '%v'", title[0], bookTitle)
+	}
+	if description, _ := book.Metadata("description"); description[0] != enrichedDescription {
+		t.Errorf("Metadata description '%v', the expected was '%v'", description[0], enrichedDescription)
+	}
+	if publisher, _ := book.Metadata("publisher"); publisher[0] != enrichedPublisher {
+		t.Errorf("Metadata publisher '%v', the expected was '%v'", publisher[0], enrichedPublisher)
+	}
+	if creator, _ := book.MetadataAttr("creator"); creator[0]["file-as"] != creatorFileAs {
+		t.Errorf("Metadata creator attr file-as '%v', the expected was '%v'", creator[0]["file-as"], creatorFileAs)
+	}
+}
+
+func TestEnrichPreferProvider(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	provider := &fakeProvider{fields: map[string][]MdataElement{
+		"title": {{Content: "Other title"}},
+	}}
+	if err := Enrich(f, PreferProvider, provider); err != nil {
+		t.Errorf("Enrich() return an error: %v", err)
+	}
+	if title, _ := f.Metadata("title"); title[0] != "Other title" {
+		t.Errorf("Metadata title '%v', the expected was 'Other title'", title[0])
+	}
+}
+
+func TestApplyMetadata(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	updates := map[string][]MdataElement{
+		"title":     {{Content: newTitle}},
+		"publisher": {{Content: enrichedPublisher}},
+		"subject":   nil,
+	}
+	if err := f.ApplyMetadata(updates, FillMissing); err != nil {
+		t.Errorf("ApplyMetadata() return an error: %v", err)
+	}
+	if title, _ := f.Metadata("title"); title[0] != bookTitle {
+		t.Errorf("FillMissing replaced the title: %v", title[0])
+	}
+	if publisher, _ := f.Metadata("publisher"); publisher[0] != enrichedPublisher {
+		t.Errorf("Metadata publisher '%v', the expected was '%v'", publisher[0], enrichedPublisher)
+	}
+	if _, err := f.Metadata("subject"); err != nil {
+		t.Errorf("An empty update removed the subject")
+	}
+
+	if err := f.ApplyMetadata(updates, PreferProvider); err != nil {
+		t.Errorf("ApplyMetadata() return an error: %v", err)
+	}
+	if title, _ := f.Metadata("title"); title[0] != newTitle {
+		t.Errorf("Metadata title '%v', the expected was '%v'", title[0], newTitle)
+	}
+	if err := f.ApplyMetadata(map[string][]MdataElement{"foo": {{Content: "bar"}}}, PreferProvider); err == nil {
+		t.Errorf("ApplyMetadata(foo) didn't return an error")
+	}
+}
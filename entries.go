@@ -0,0 +1,67 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"archive/zip"
+	"strings"
+	"time"
+)
+
+// Entry is the information of a file of the epub zip
+type Entry struct {
+	// Name is the full path of the file on the zip
+	Name string
+	// Href is the path as used by OpenFile, empty for the files outside
+	// of the root directory like the ones on META-INF
+	Href             string
+	Method           uint16
+	CompressedSize   uint64
+	UncompressedSize uint64
+	CRC32            uint32
+	Modified         time.Time
+	Comment          string
+}
+
+// Entries returns the information of the files of the epub without opening
+// them
+//
+// They are the files of the zip as it was loaded, the changes pending to be
+// written by Repack are not included.
+func (e Epub) Entries() []Entry {
+	entries := make([]Entry, len(e.zip.File))
+	for i, f := range e.zip.File {
+		entries[i] = newEntry(f.FileHeader, e.rootPath)
+	}
+	return entries
+}
+
+// Entry returns the information of the file with the href, as used by
+// OpenFile
+func (e Epub) Entry(href string) (Entry, bool) {
+	name := e.rootPath + href
+	for _, f := range e.zip.File {
+		if f.Name == name {
+			return newEntry(f.FileHeader, e.rootPath), true
+		}
+	}
+	return Entry{}, false
+}
+
+func newEntry(header zip.FileHeader, rootPath string) Entry {
+	entry := Entry{
+		Name:             header.Name,
+		Method:           header.Method,
+		CompressedSize:   header.CompressedSize64,
+		UncompressedSize: header.UncompressedSize64,
+		CRC32:            header.CRC32,
+		Modified:         header.Modified,
+		Comment:          header.Comment,
+	}
+	if strings.HasPrefix(header.Name, rootPath) {
+		entry.Href = strings.TrimPrefix(header.Name, rootPath)
+	}
+	return entry
+}
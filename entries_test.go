@@ -0,0 +1,43 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"archive/zip"
+	"hash/crc32"
+	"io/ioutil"
+)
+
+func TestEntries(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	entries := f.Entries()
+	if len(entries) != len(f.zip.File) {
+		t.Fatalf("Entries() return %d entries instead of %d", len(entries), len(f.zip.File))
+	}
+	if entries[0].Name != "mimetype" || entries[0].Method != zip.Store {
+		t.Errorf("Wrong mimetype entry: %v", entries[0])
+	}
+
+	entry, ok := f.Entry(chapterFile)
+	if !ok {
+		t.Fatalf("Entry() didn't find %s", chapterFile)
+	}
+	if entry.Href != chapterFile || entry.Modified.IsZero() || entry.CompressedSize == 0 {
+		t.Errorf("Wrong entry: %v", entry)
+	}
+	file, _ := f.OpenFile(chapterFile)
+	data, _ := ioutil.ReadAll(file)
+	file.Close()
+	if entry.CRC32 != crc32.ChecksumIEEE(data) || entry.UncompressedSize != uint64(len(data)) {
+		t.Errorf("Wrong CRC or size: %v", entry)
+	}
+	if _, ok := f.Entry("nonexistent.html"); ok {
+		t.Errorf("Entry() found a nonexistent file")
+	}
+}
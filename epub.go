@@ -6,29 +6,73 @@ package epubgo
 
 import (
 	"archive/zip"
+	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	"os"
+	"strings"
+	"time"
 )
 
+// ErrMalformed is returned by Open and Load when the epub is so broken that
+// parsing it failed unexpectedly
+var ErrMalformed = errors.New("Malformed epub")
+
+// maxBufferedSize is the maximum size of the compressed files that are
+// buffered by OpenFile to be seekable
+const maxBufferedSize = 8 << 20
+
+// maxEntries is the maximum number of files of the zip, way over the 65535
+// entries of the classic zip format but low enough to not exhaust the memory
+// with a malicious central directory
+const maxEntries = 1 << 20
+
 // Epub holds all the data of the ebook
 type Epub struct {
 	file     *os.File
+	tmpPath  string
+	mapped   []byte
+	reader   io.ReaderAt
 	zip      *zip.Reader
+	index    zipIndex
 	rootPath string
+	opfPath  string
 	metadata mdata
 	opf      *xmlOPF
 	ncx      *xmlNCX
+	staged   map[string][]byte
+	progress ProgressFunc
+	logger   Logger
+	warnings []ParseWarning
 }
 
 // MdataElement contains the value and a map of attributes of any valid field
 type MdataElement struct {
 	Content string
 	Attr    map[string]string
+	// Index is the position of the element between all the metadata
+	// elements of the OPF, starting by 0. It is ignored when the metadata is
+	// written.
+	Index int
 }
 
 type mdata map[string][]MdataElement
 
+// refinement returns the value of the first EPUB 3 meta refining the element
+// with the given id, or an empty string if there is none
+func (m mdata) refinement(id, property string) string {
+	if id == "" {
+		return ""
+	}
+	for _, meta := range m["meta"] {
+		if meta.Attr["refines"] == "#"+id && meta.Attr["property"] == property {
+			return meta.Content
+		}
+	}
+	return ""
+}
+
 // Open an existing epub
 func Open(path string) (e *Epub, err error) {
 	e = new(Epub)
@@ -53,21 +97,56 @@ func Load(r io.ReaderAt, size int64) (e *Epub, err error) {
 }
 
 func (e *Epub) load(r io.ReaderAt, size int64) (err error) {
+	start := time.Now()
+	defer func() {
+		files := 0
+		if e.zip != nil {
+			files = len(e.zip.File)
+		}
+		recordOpen(start, size, files, err)
+	}()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: panic while parsing it: %v", ErrMalformed, r)
+		}
+	}()
+
+	e.staged = make(map[string][]byte)
+	e.reader = r
 	e.zip, err = zip.NewReader(r, size)
 	if err != nil {
 		return
 	}
+	if len(e.zip.File) > maxEntries {
+		return fmt.Errorf("%w: too many files (%d)", ErrMalformed, len(e.zip.File))
+	}
+	e.index = newZipIndex(e.zip)
 
-	e.rootPath, err = getRootPath(e.zip)
-	if err != nil {
-		return
+	e.warnings = nil
+	e.opfPath, err = getOpfPath(e.zip)
+	if err != nil || e.opfPath == "" || e.findFile(e.opfPath) == nil {
+		opfPath := discoverOPF(e.zip)
+		if opfPath == "" {
+			if err == nil {
+				err = errors.New("File " + e.opfPath + " not found")
+			}
+			return
+		}
+		msg := "The container doesn't point to an OPF file"
+		if err != nil {
+			msg = "Could not read the container: " + err.Error()
+		}
+		e.addWarning(WarnNoContainer, "META-INF/container.xml", msg+", using "+opfPath)
+		e.opfPath, err = opfPath, nil
 	}
+	e.rootPath = rootPath(e.opfPath)
 
 	return e.parseFiles()
 }
 
 func (e *Epub) parseFiles() (err error) {
-	opfFile, err := openOPF(e.zip)
+	e.checkCase(e.opfPath)
+	opfFile, err := openFile(e.zip, e.opfPath)
 	if err != nil {
 		return
 	}
@@ -78,14 +157,32 @@ func (e *Epub) parseFiles() (err error) {
 	}
 
 	e.metadata = e.opf.toMData()
+	if len(e.metadata["title"]) == 0 {
+		e.addWarning(WarnNoTitle, e.opfPath, "The epub has no title")
+	}
+	for i, item := range e.opf.Spine.Items {
+		if e.opf.spineURL(i) == "" {
+			e.addWarning(WarnSpineItem, e.opfPath, "Spine item "+item.IDref+" is not in the manifest")
+		}
+	}
+
+	if toc := e.opf.Spine.Toc; toc != "" && e.opf.filePath(toc) == "" {
+		e.addWarning(WarnTocFallback, e.opfPath, "The spine toc "+toc+" is not in the manifest, looking for the NCX by its media type")
+	}
 	ncxPath := e.opf.ncxPath()
 	if ncxPath != "" {
+		e.checkCase(e.rootPath + ncxPath)
 		ncx, err := e.OpenFile(ncxPath)
 		if err != nil {
 			return errors.New("Could not open the NCX file")
 		}
 		defer ncx.Close()
 		e.ncx, err = parseNCX(ncx)
+		if err != nil {
+			e.addWarning(WarnInvalidNCX, e.rootPath+ncxPath, "Could not parse the NCX file: "+err.Error())
+		}
+	} else {
+		e.addWarning(WarnNoNCX, e.opfPath, "The epub has no NCX file")
 	}
 	return
 }
@@ -95,11 +192,21 @@ func (e Epub) Close() {
 	if e.file != nil {
 		e.file.Close()
 	}
+	if e.tmpPath != "" {
+		os.Remove(e.tmpPath)
+	}
+	if e.mapped != nil {
+		unmap(e.mapped)
+	}
 }
 
 // OpenFile inside the epub
+//
+// The returned file implements io.ReadSeekCloser if it is stored
+// uncompressed on the zip or if it is compressed and small enough to be
+// buffered in memory.
 func (e Epub) OpenFile(name string) (io.ReadCloser, error) {
-	return openFile(e.zip, e.rootPath+name)
+	return e.openSeeker(e.rootPath + name)
 }
 
 // OpenFileId opens a file from its id
@@ -107,7 +214,92 @@ func (e Epub) OpenFile(name string) (io.ReadCloser, error) {
 // The id of the files often appears on metadata fields
 func (e Epub) OpenFileId(id string) (io.ReadCloser, error) {
 	path := e.opf.filePath(id)
-	return openFile(e.zip, e.rootPath+path)
+	return e.openSeeker(e.rootPath + path)
+}
+
+// open a file from the zip, the staged content takes precedence if any
+func (e Epub) open(name string) (io.ReadCloser, error) {
+	if data, ok := e.staged[name]; ok {
+		if data == nil {
+			return nil, errors.New("File " + name + " not found")
+		}
+		return nopSeekCloser{bytes.NewReader(data)}, nil
+	}
+	f := e.findFile(name)
+	if f == nil {
+		return nil, errors.New("File " + name + " not found")
+	}
+	if f.Name != name {
+		e.warn("File found with a different case", "name", name, "file", f.Name)
+	}
+	return openZipFile(f)
+}
+
+// openSeeker is like open but it returns an io.ReadSeekCloser when possible
+//
+// The uncompressed files are read directly from the zip, the compressed
+// ones are buffered if they are not bigger than maxBufferedSize.
+func (e Epub) openSeeker(name string) (io.ReadCloser, error) {
+	f := e.findFile(name)
+	if _, ok := e.staged[name]; ok || f == nil {
+		return e.open(name)
+	}
+	if f.Name != name {
+		e.warn("File found with a different case", "name", name, "file", f.Name)
+	}
+
+	if f.Method == zip.Store {
+		offset, err := f.DataOffset()
+		if err != nil {
+			return nil, err
+		}
+		section := io.NewSectionReader(e.reader, offset, int64(f.UncompressedSize64))
+		return nopSeekCloser{section}, nil
+	}
+	if f.UncompressedSize64 > maxBufferedSize {
+		return openZipFile(f)
+	}
+	r, err := openZipFile(f)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	data, err := readAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return nopSeekCloser{bytes.NewReader(data)}, nil
+}
+
+type nopSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (nopSeekCloser) Close() error { return nil }
+
+// readFile returns the content of a file from the zip
+func (e Epub) readFile(name string) ([]byte, error) {
+	f, err := e.open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readAll(f)
+}
+
+// stage replaces the content of a file or adds a new one to the epub
+//
+// The staged files are used by OpenFile and written by Repack.
+func (e Epub) stage(name string, data []byte) {
+	if data == nil {
+		data = []byte{}
+	}
+	e.staged[name] = data
+}
+
+// remove a file from the epub, it will not be written by Repack
+func (e Epub) remove(name string) {
+	e.staged[name] = nil
 }
 
 // Navigation returns a navigation iterator
@@ -118,6 +310,26 @@ func (e Epub) Navigation() (*NavigationIterator, error) {
 	return newNavigationIterator(e.ncx.navMap())
 }
 
+// FindNavPoint returns a navigation iterator on the first entry that points
+// to href, a path as used by OpenFile
+//
+// If href has no fragment (#...) any entry pointing to the file matches.
+func (e Epub) FindNavPoint(href string) (*NavigationIterator, error) {
+	nav, err := e.Navigation()
+	if err != nil {
+		return nil, err
+	}
+	ncxPath := e.opf.ncxPath()
+	err = nav.Find(func(title, url string) bool {
+		target := e.ResolveHref(ncxPath, url)
+		if !strings.Contains(href, "#") {
+			target = strings.SplitN(target, "#", 2)[0]
+		}
+		return target == href
+	})
+	return nav, err
+}
+
 // Spine returns a spine iterator
 func (e Epub) Spine() (*SpineIterator, error) {
 	return newSpineIterator(&e)
@@ -142,12 +354,14 @@ func (e Epub) Metadata(field string) ([]string, error) {
 }
 
 // MetadataFields returns the list of metadata fields present in the current epub
+//
+// The fields are in the order of the Dublin Core elements followed by meta.
 func (e Epub) MetadataFields() []string {
-	fields := make([]string, len(e.metadata))
-	i := 0
-	for k, _ := range e.metadata {
-		fields[i] = k
-		i++
+	var fields []string
+	for _, field := range metadataFieldOrder {
+		if _, ok := e.metadata[field]; ok {
+			fields = append(fields, field)
+		}
 	}
 	return fields
 }
@@ -156,6 +370,10 @@ func (e Epub) MetadataFields() []string {
 //
 // Returns: an array of maps of each attribute and its value.
 // The fields of this array are in the same order than in the Metadata method.
+//
+// The attributes are indexed by their local name, like "role". The ones with
+// a namespace are also indexed by their qualified name, like "opf:role" or
+// "xml:lang", so the EPUB 2 opf: attributes can be told apart.
 func (e Epub) MetadataAttr(field string) ([]map[string]string, error) {
 	elem, ok := e.metadata[field]
 	if ok {
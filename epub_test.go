@@ -9,6 +9,8 @@ import "testing"
 import (
 	"archive/zip"
 	"bytes"
+	"errors"
+	"io"
 	"io/ioutil"
 	"os"
 )
@@ -53,6 +55,19 @@ func TestLoad(t *testing.T) {
 	f.Close()
 }
 
+type panicReader struct{}
+
+func (panicReader) ReadAt(p []byte, off int64) (int, error) {
+	panic("broken reader")
+}
+
+func TestLoadPanic(t *testing.T) {
+	_, err := Load(panicReader{}, 1024)
+	if !errors.Is(err, ErrMalformed) {
+		t.Errorf("Load() with a panic return: %v", err)
+	}
+}
+
 func TestOpenFile(t *testing.T) {
 	f, _ := Open(bookPath)
 	defer f.Close()
@@ -119,6 +134,33 @@ func TestOpenFileId(t *testing.T) {
 	}
 }
 
+func TestOpenFileSeek(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	for _, name := range []string{coverFile, htmlFile} {
+		file, err := f.OpenFile(name)
+		if err != nil {
+			t.Fatalf("OpenFile(%v) return an error: %v", name, err)
+		}
+		defer file.Close()
+		data, _ := ioutil.ReadAll(file)
+
+		seeker, ok := file.(io.ReadSeekCloser)
+		if !ok {
+			t.Errorf("OpenFile(%v) is not seekable", name)
+			continue
+		}
+		if _, err := seeker.Seek(10, io.SeekStart); err != nil {
+			t.Errorf("Seek() return an error: %v", err)
+		}
+		rest, _ := ioutil.ReadAll(seeker)
+		if !bytes.Equal(rest, data[10:]) {
+			t.Errorf("The content after Seek() of %v is not equal", name)
+		}
+	}
+}
+
 func TestNoNCX(t *testing.T) {
 	f, err := Open(noNCXPath)
 	if err != nil {
@@ -176,14 +218,11 @@ func TestMetadataFields(t *testing.T) {
 		t.Errorf("len(MetadataFields()) should be %v, but was %v", lenMetadatafields, len(fields))
 	}
 
-	isTitle := false
-	for _, field := range fields {
-		if field == "title" {
-			isTitle = true
-		}
+	if fields[0] != "title" {
+		t.Errorf("title is not the first of the metadata fields: %v", fields)
 	}
-	if !isTitle {
-		t.Errorf("title is not in the metadata fields")
+	if fields[len(fields)-1] != "meta" {
+		t.Errorf("meta is not the last of the metadata fields: %v", fields)
 	}
 }
 
@@ -201,3 +240,32 @@ func TestMetadataAttr(t *testing.T) {
 		t.Errorf("Metadata meta attr name '%v', the expected was '%v'", meta[0]["name"], metaName)
 	}
 }
+
+// buildEpub creates in memory an epub with the OPF file as OEBPS/content.opf
+// and the files, with paths relative to OEBPS, as content
+func buildEpub(t *testing.T, opfPath string, files map[string]string) *Epub {
+	opf, err := ioutil.ReadFile(opfPath)
+	if err != nil {
+		t.Fatalf("ReadFile(%v) return an error: %v", opfPath, err)
+	}
+
+	var buff bytes.Buffer
+	w := zip.NewWriter(&buff)
+	mimetype, _ := w.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	mimetype.Write([]byte("application/epub+zip"))
+	container, _ := w.Create("META-INF/container.xml")
+	container.Write([]byte(`<?xml version="1.0"?><container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container"><rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles></container>`))
+	content, _ := w.Create("OEBPS/content.opf")
+	content.Write(opf)
+	for name, data := range files {
+		f, _ := w.Create("OEBPS/" + name)
+		f.Write([]byte(data))
+	}
+	w.Close()
+
+	book, err := Load(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+	if err != nil {
+		t.Fatalf("Load() of %v return an error: %v", opfPath, err)
+	}
+	return book
+}
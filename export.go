@@ -0,0 +1,275 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var dateRegexp = regexp.MustCompile(`^(\d{4})(?:-(\d{2})(?:-(\d{2}))?)?`)
+
+// reference is the metadata of the book mapped to the fields used by the
+// reference managers
+type reference struct {
+	authors      []Contributor
+	editors      []Contributor
+	translators  []Contributor
+	title        string
+	subtitle     string
+	edition      string
+	publisher    string
+	date         []int
+	isbn         string
+	doi          string
+	url          string
+	language     string
+	abstract     string
+	keywords     []string
+	series       string
+	seriesNumber string
+}
+
+// reference maps the Dublin Core metadata and its refinements to a reference
+func (e Epub) reference() reference {
+	ref := reference{
+		authors:     e.Contributors(RoleAuthor),
+		editors:     e.Contributors(RoleEditor),
+		translators: e.Contributors(RoleTranslator),
+		publisher:   e.first("publisher"),
+		isbn:        e.Query().ISBN,
+		language:    e.language(),
+		abstract:    strings.Replace(extractText([]byte(e.Description())), "\n", " ", -1),
+	}
+
+	for _, t := range e.Titles() {
+		switch t.Type {
+		case "", "main":
+			if ref.title == "" {
+				ref.title = t.Content
+			}
+		case "subtitle":
+			if ref.subtitle == "" {
+				ref.subtitle = t.Content
+			}
+		case "edition":
+			ref.edition = t.Content
+		}
+	}
+
+	if match := dateRegexp.FindStringSubmatch(e.first("date")); match != nil {
+		for _, part := range match[1:] {
+			n, err := strconv.Atoi(part)
+			if err != nil || n == 0 {
+				break
+			}
+			ref.date = append(ref.date, n)
+		}
+	}
+
+	for _, elem := range e.metadata["identifier"] {
+		id := ParseIdentifier(elem.Content, elem.Attr["scheme"])
+		switch {
+		case id.Scheme == SchemeDOI && ref.doi == "":
+			ref.doi = id.Value
+		case id.Scheme == SchemeURI && ref.url == "":
+			ref.url = id.Value
+		}
+	}
+	for _, elem := range e.metadata["subject"] {
+		if subject := strings.TrimSpace(elem.Content); subject != "" {
+			ref.keywords = append(ref.keywords, subject)
+		}
+	}
+	for _, c := range e.Collections() {
+		if c.Type == "" || c.Type == "series" {
+			ref.series, ref.seriesNumber = c.Name, c.Position
+			break
+		}
+	}
+	return ref
+}
+
+// BibTeX returns the metadata of the book as a BibTeX @book entry
+//
+// Unlike Citation it includes all the metadata known by BibTeX: editors,
+// translators, subtitle, edition, series, language, keywords, abstract, ...
+func (e Epub) BibTeX() string {
+	ref := e.reference()
+	year := ""
+	if len(ref.date) > 0 {
+		year = strconv.Itoa(ref.date[0])
+	}
+	names := ref.authors
+	if len(names) == 0 {
+		names = ref.editors
+	}
+	key := bibtexKey(names, year)
+
+	month := ""
+	if len(ref.date) > 1 {
+		month = strconv.Itoa(ref.date[1])
+	}
+	fields := [][2]string{
+		{"author", bibtexNames(ref.authors)},
+		{"editor", bibtexNames(ref.editors)},
+		{"translator", bibtexNames(ref.translators)},
+		{"title", ref.title},
+		{"subtitle", ref.subtitle},
+		{"edition", ref.edition},
+		{"series", ref.series},
+		{"number", ref.seriesNumber},
+		{"publisher", ref.publisher},
+		{"year", year},
+		{"month", month},
+		{"isbn", ref.isbn},
+		{"doi", ref.doi},
+		{"url", ref.url},
+		{"language", ref.language},
+		{"keywords", strings.Join(ref.keywords, ", ")},
+		{"abstract", ref.abstract},
+	}
+
+	var buff strings.Builder
+	buff.WriteString("@book{" + key)
+	for _, field := range fields {
+		if field[1] != "" {
+			buff.WriteString(",\n  " + field[0] + " = {" + bibtexEscape(field[1]) + "}")
+		}
+	}
+	buff.WriteString("\n}\n")
+	return buff.String()
+}
+
+// RIS returns the metadata of the book as a RIS record of type BOOK
+func (e Epub) RIS() string {
+	ref := e.reference()
+	var buff strings.Builder
+	tag := func(name, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			buff.WriteString(name + "  - " + value + "\r\n")
+		}
+	}
+
+	tag("TY", "BOOK")
+	for _, list := range []struct {
+		tag          string
+		contributors []Contributor
+	}{{"AU", ref.authors}, {"A2", ref.editors}, {"A4", ref.translators}} {
+		for _, c := range list.contributors {
+			tag(list.tag, invertedName(c))
+		}
+	}
+	title := ref.title
+	if ref.subtitle != "" {
+		title += ": " + ref.subtitle
+	}
+	tag("TI", title)
+	tag("T3", ref.series)
+	tag("ET", ref.edition)
+	if len(ref.date) > 0 {
+		tag("PY", strconv.Itoa(ref.date[0]))
+		date := make([]string, 3)
+		for i, n := range ref.date {
+			date[i] = strconv.Itoa(n)
+			if i > 0 {
+				date[i] = "0" + date[i]
+				date[i] = date[i][len(date[i])-2:]
+			}
+		}
+		tag("DA", strings.Join(date, "/")+"/")
+	}
+	tag("PB", ref.publisher)
+	tag("SN", ref.isbn)
+	tag("DO", ref.doi)
+	tag("UR", ref.url)
+	tag("LA", ref.language)
+	tag("AB", ref.abstract)
+	for _, keyword := range ref.keywords {
+		tag("KW", keyword)
+	}
+	buff.WriteString("ER  - \r\n")
+	return buff.String()
+}
+
+// cslName is a name variable of CSL-JSON
+type cslName struct {
+	Family  string `json:"family,omitempty"`
+	Given   string `json:"given,omitempty"`
+	Literal string `json:"literal,omitempty"`
+}
+
+type cslDate struct {
+	DateParts [][]int `json:"date-parts"`
+}
+
+// cslItem is an item of CSL-JSON, as read by Zotero and citeproc
+type cslItem struct {
+	ID               string    `json:"id"`
+	Type             string    `json:"type"`
+	Author           []cslName `json:"author,omitempty"`
+	Editor           []cslName `json:"editor,omitempty"`
+	Translator       []cslName `json:"translator,omitempty"`
+	Title            string    `json:"title,omitempty"`
+	Edition          string    `json:"edition,omitempty"`
+	CollectionTitle  string    `json:"collection-title,omitempty"`
+	CollectionNumber string    `json:"collection-number,omitempty"`
+	Publisher        string    `json:"publisher,omitempty"`
+	Issued           *cslDate  `json:"issued,omitempty"`
+	ISBN             string    `json:"ISBN,omitempty"`
+	DOI              string    `json:"DOI,omitempty"`
+	URL              string    `json:"URL,omitempty"`
+	Language         string    `json:"language,omitempty"`
+	Abstract         string    `json:"abstract,omitempty"`
+	Keyword          string    `json:"keyword,omitempty"`
+}
+
+// CSLJSON returns the metadata of the book as a CSL-JSON array with one item
+// of type book
+//
+// The id of the item is the first identifier of the book.
+func (e Epub) CSLJSON() ([]byte, error) {
+	ref := e.reference()
+	item := cslItem{
+		ID:               e.first("identifier"),
+		Type:             "book",
+		Author:           cslNames(ref.authors),
+		Editor:           cslNames(ref.editors),
+		Translator:       cslNames(ref.translators),
+		Title:            ref.title,
+		Edition:          ref.edition,
+		CollectionTitle:  ref.series,
+		CollectionNumber: ref.seriesNumber,
+		Publisher:        ref.publisher,
+		ISBN:             ref.isbn,
+		DOI:              ref.doi,
+		URL:              ref.url,
+		Language:         ref.language,
+		Abstract:         ref.abstract,
+		Keyword:          strings.Join(ref.keywords, ", "),
+	}
+	if ref.subtitle != "" {
+		item.Title += ": " + ref.subtitle
+	}
+	if len(ref.date) > 0 {
+		item.Issued = &cslDate{[][]int{ref.date}}
+	}
+	return json.MarshalIndent([]cslItem{item}, "", "  ")
+}
+
+func cslNames(contributors []Contributor) []cslName {
+	var names []cslName
+	for _, c := range contributors {
+		family, given := nameParts(c)
+		if given == "" {
+			names = append(names, cslName{Literal: c.Name})
+		} else {
+			names = append(names, cslName{Family: family, Given: given})
+		}
+	}
+	return names
+}
@@ -0,0 +1,79 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+func TestBibTeX(t *testing.T) {
+	f := buildEpub(t, epub3OPF, nil)
+
+	bibtex := f.BibTeX()
+	for _, field := range []string{
+		"@book{tolkien1954,",
+		"author = {Tolkien, J. R. R.}",
+		"editor = {Tolkien, Christopher}",
+		"title = {The Lord of the Rings}",
+		"subtitle = {The Fellowship of the Ring}",
+		"edition = {Collector's Edition}",
+		"series = {The Lord of the Rings}",
+		"number = {1}",
+		"publisher = {Allen \\& Unwin}",
+		"month = {7}",
+		"isbn = {9780306406157}",
+		"keywords = {Fantasy, Middle-earth}",
+	} {
+		if !strings.Contains(bibtex, field) {
+			t.Errorf("BibTeX() doesn't contain %q: %v", field, bibtex)
+		}
+	}
+}
+
+func TestRIS(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	expected := "TY  - BOOK\r\nAU  - Twain, Mark\r\nTI  - A Dog's Tale\r\nPY  - 2004\r\nDA  - 2004/06/01/\r\n"
+	ris := f.RIS()
+	if !strings.HasPrefix(ris, expected) {
+		t.Errorf("RIS() return: %q", ris)
+	}
+	if !strings.HasSuffix(ris, "ER  - \r\n") {
+		t.Errorf("RIS() doesn't end the record: %q", ris)
+	}
+}
+
+func TestCSLJSON(t *testing.T) {
+	f := buildEpub(t, epub3OPF, nil)
+
+	data, err := f.CSLJSON()
+	if err != nil {
+		t.Fatalf("CSLJSON() return an error: %v", err)
+	}
+	var items []map[string]interface{}
+	if err := json.Unmarshal(data, &items); err != nil || len(items) != 1 {
+		t.Fatalf("CSLJSON() return an invalid array: %s", data)
+	}
+	item := items[0]
+	if item["type"] != "book" || item["title"] != "The Lord of the Rings: The Fellowship of the Ring" {
+		t.Errorf("CSLJSON() return: %s", data)
+	}
+	authors := item["author"].([]interface{})
+	author := authors[0].(map[string]interface{})
+	if author["family"] != "Tolkien" || author["given"] != "J. R. R." {
+		t.Errorf("CSLJSON() author: %v", author)
+	}
+	issued := item["issued"].(map[string]interface{})["date-parts"].([]interface{})[0].([]interface{})
+	if len(issued) != 3 || issued[0] != 1954.0 || issued[2] != 29.0 {
+		t.Errorf("CSLJSON() issued: %v", issued)
+	}
+	if item["collection-number"] != "1" || item["ISBN"] != "9780306406157" {
+		t.Errorf("CSLJSON() return: %s", data)
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"bytes"
+	"encoding/xml"
+	"sort"
+	"strings"
+)
+
+const appleDisplayOptionsPath = "META-INF/com.apple.ibooks.display-options.xml"
+
+// Platforms and options of the Apple display options
+const (
+	// PlatformAll are the options for all the platforms
+	PlatformAll    = "*"
+	PlatformIPad   = "ipad"
+	PlatformIPhone = "iphone"
+
+	OptionFixedLayout     = "fixed-layout"
+	OptionOpenToSpread    = "open-to-spread"
+	OptionInteractive     = "interactive"
+	OptionSpecifiedFonts  = "specified-fonts"
+	OptionOrientationLock = "orientation-lock"
+)
+
+// DisplayOptions are the Apple display options by platform and option name
+type DisplayOptions map[string]map[string]string
+
+// Option returns the value of the option for the platform, or the one for
+// all the platforms if the platform doesn't set it
+func (o DisplayOptions) Option(platform, name string) string {
+	if value, ok := o[platform][name]; ok {
+		return value
+	}
+	return o[PlatformAll][name]
+}
+
+// Set sets the value of the option for the platform
+func (o DisplayOptions) Set(platform, name, value string) {
+	if o[platform] == nil {
+		o[platform] = make(map[string]string)
+	}
+	o[platform][name] = value
+}
+
+// FixedLayout returns whether the book is fixed layout on all the platforms
+func (o DisplayOptions) FixedLayout() bool {
+	return o.Option(PlatformAll, OptionFixedLayout) == "true"
+}
+
+// OpenToSpread returns whether the book opens showing two pages on all the
+// platforms
+func (o DisplayOptions) OpenToSpread() bool {
+	return o.Option(PlatformAll, OptionOpenToSpread) == "true"
+}
+
+// Interactive returns whether the book has interactive content on all the
+// platforms
+func (o DisplayOptions) Interactive() bool {
+	return o.Option(PlatformAll, OptionInteractive) == "true"
+}
+
+// ExtensionElement is an element of the OPF metadata that is not Dublin Core
+// or meta, like the ones added by publishers or reading systems
+type ExtensionElement struct {
+	// Namespace is the namespace URI of the element
+	Namespace string
+	Name      string
+	Content   string
+	// Attr are the attributes by their local name, the ones with a namespace
+	// are also indexed by their qualified name as in MetadataAttr
+	Attr map[string]string
+	// Index is the position of the element between all the metadata
+	// elements of the OPF, as in MdataElement
+	Index int
+}
+
+// ExtensionMetadata returns the elements of the OPF metadata that are not
+// Dublin Core or meta, like <ibooks:version>
+//
+// They are kept as they were loaded, the ones added by SetMetadata are not
+// included.
+func (e Epub) ExtensionMetadata() []ExtensionElement {
+	return e.opf.Metadata.extensions
+}
+
+type appleDisplayOptions struct {
+	Platforms []struct {
+		Name    string `xml:"name,attr"`
+		Options []struct {
+			Name  string `xml:"name,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"option"`
+	} `xml:"platform"`
+}
+
+// AppleDisplayOptions returns the options of the iBooks display options file
+// (META-INF/com.apple.ibooks.display-options.xml) by platform and name
+//
+// The platform "*" applies to all of them. Options like "fixed-layout" or
+// "specified-fonts" change how the book is rendered. It returns nil if the
+// epub has no display options file.
+func (e Epub) AppleDisplayOptions() (DisplayOptions, error) {
+	data, staged := e.staged[appleDisplayOptionsPath]
+	if (staged && data == nil) || (!staged && e.findFile(appleDisplayOptionsPath) == nil) {
+		return nil, nil
+	}
+	f, err := e.open(appleDisplayOptionsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var displayOptions appleDisplayOptions
+	if err := decodeXML(f, &displayOptions); err != nil {
+		return nil, err
+	}
+	options := make(DisplayOptions)
+	for _, platform := range displayOptions.Platforms {
+		if options[platform.Name] == nil {
+			options[platform.Name] = make(map[string]string)
+		}
+		for _, option := range platform.Options {
+			options.Set(platform.Name, option.Name, strings.TrimSpace(option.Value))
+		}
+	}
+	return options, nil
+}
+
+// SetAppleDisplayOptions replaces the Apple display options file, empty
+// options remove it
+//
+// The file is written with Repack.
+func (e *Epub) SetAppleDisplayOptions(options DisplayOptions) {
+	if len(options) == 0 {
+		e.remove(appleDisplayOptionsPath)
+		return
+	}
+
+	var buff bytes.Buffer
+	buff.WriteString(xml.Header + "<display_options>\n")
+	platforms := make([]string, 0, len(options))
+	for platform := range options {
+		platforms = append(platforms, platform)
+	}
+	sort.Strings(platforms)
+	for _, platform := range platforms {
+		buff.WriteString(`  <platform name="` + escapeXML(platform) + "\">\n")
+		for _, name := range sortedKeys(options[platform]) {
+			buff.WriteString(`    <option name="` + escapeXML(name) + `">` + escapeXML(options[platform][name]) + "</option>\n")
+		}
+		buff.WriteString("  </platform>\n")
+	}
+	buff.WriteString("</display_options>\n")
+	e.stage(appleDisplayOptionsPath, buff.Bytes())
+}
+
+// extensionAttrs returns the attributes by their local and qualified names
+func extensionAttrs(attrs []xml.Attr) map[string]string {
+	result := qualifiedAttrs(attrs)
+	for _, attr := range attrs {
+		if attr.Name.Space != "xmlns" && attr.Name.Local != "xmlns" {
+			result[attr.Name.Local] = attr.Value
+		}
+	}
+	return result
+}
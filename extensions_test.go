@@ -0,0 +1,103 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+const displayOptions = `<?xml version="1.0" encoding="UTF-8"?>
+<display_options>
+  <platform name="*">
+    <option name="fixed-layout">true</option>
+    <option name="specified-fonts">true</option>
+  </platform>
+  <platform name="iphone">
+    <option name="orientation-lock">landscape-only</option>
+  </platform>
+</display_options>`
+
+func TestExtensionMetadata(t *testing.T) {
+	opf, _ := ioutil.ReadFile("testdata/epub3.opf")
+	opf = []byte(strings.Replace(string(opf), `<metadata xmlns:dc="http://purl.org/dc/elements/1.1/">`,
+		`<metadata xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:ibooks="http://vocabulary.itunes.apple.com/rdf/ibooks/vocabulary-extensions-1.0/">
+    <ibooks:version>1.2.3</ibooks:version>
+    <ibooks:specified-fonts value="true"/>`, 1))
+	opfPath := filepath.Join(t.TempDir(), "content.opf")
+	ioutil.WriteFile(opfPath, opf, 0644)
+	f := buildEpub(t, opfPath, nil)
+
+	extensions := f.ExtensionMetadata()
+	if len(extensions) != 2 {
+		t.Fatalf("ExtensionMetadata() return: %v", extensions)
+	}
+	if extensions[0].Name != "version" || extensions[0].Content != "1.2.3" || extensions[0].Index != 0 ||
+		!strings.Contains(extensions[0].Namespace, "ibooks") {
+		t.Errorf("Wrong extension: %v", extensions[0])
+	}
+	if extensions[1].Attr["value"] != "true" {
+		t.Errorf("Wrong extension attributes: %v", extensions[1])
+	}
+	identifiers, _ := f.MetadataElement("identifier")
+	if identifiers[0].Index != 2 {
+		t.Errorf("Wrong identifier index: %d", identifiers[0].Index)
+	}
+
+	f.SetMetadata("publisher", []MdataElement{{Content: "Houghton Mifflin"}})
+	book := repackBook(t, f)
+	if extensions := book.ExtensionMetadata(); len(extensions) != 2 || extensions[0].Content != "1.2.3" {
+		t.Errorf("The extensions were not kept: %v", extensions)
+	}
+}
+
+func TestAppleDisplayOptions(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	if options, err := f.AppleDisplayOptions(); options != nil || err != nil {
+		t.Errorf("AppleDisplayOptions() return: %v, %v", options, err)
+	}
+	f.stage(appleDisplayOptionsPath, []byte(displayOptions))
+	options, err := f.AppleDisplayOptions()
+	if err != nil {
+		t.Fatalf("AppleDisplayOptions() return an error: %v", err)
+	}
+	if options["*"]["fixed-layout"] != "true" || options["iphone"]["orientation-lock"] != "landscape-only" {
+		t.Errorf("AppleDisplayOptions() return: %v", options)
+	}
+}
+
+func TestSetAppleDisplayOptions(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	options := make(DisplayOptions)
+	options.Set(PlatformAll, OptionFixedLayout, "true")
+	options.Set(PlatformAll, OptionOpenToSpread, "false")
+	options.Set(PlatformIPhone, OptionOpenToSpread, "true")
+	options.Set(PlatformAll, OptionInteractive, "a<b")
+	f.SetAppleDisplayOptions(options)
+
+	book := repackBook(t, f)
+	written, err := book.AppleDisplayOptions()
+	if err != nil {
+		t.Fatalf("AppleDisplayOptions() return an error: %v", err)
+	}
+	if !written.FixedLayout() || written.OpenToSpread() || written.Option(PlatformIPhone, OptionOpenToSpread) != "true" {
+		t.Errorf("Wrong display options: %v", written)
+	}
+	if written.Option(PlatformIPhone, OptionFixedLayout) != "true" || written.Option(PlatformAll, OptionInteractive) != "a<b" {
+		t.Errorf("Wrong display options: %v", written)
+	}
+
+	book.SetAppleDisplayOptions(nil)
+	if options, _ := book.AppleDisplayOptions(); options != nil {
+		t.Errorf("The display options were not removed: %v", options)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"context"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// ManifestItem is a resource listed on the manifest of the epub
+type ManifestItem struct {
+	ID string
+	// Href is the path of the resource, as used by OpenFile
+	Href         string
+	MediaType    string
+	Properties   string
+	Fallback     string
+	MediaOverlay string
+}
+
+// Manifest returns the resources listed on the manifest
+func (e Epub) Manifest() []ManifestItem {
+	items := make([]ManifestItem, len(e.opf.Manifest))
+	for i, item := range e.opf.Manifest {
+		items[i] = ManifestItem{
+			ID:           item.ID,
+			Href:         item.Href,
+			MediaType:    item.MediaType,
+			Properties:   item.Properties,
+			Fallback:     item.Fallback,
+			MediaOverlay: item.MediaOverlay,
+		}
+	}
+	return items
+}
+
+// ExtractAll calls fn with the content of every resource of the manifest
+//
+// The resources are decompressed concurrently by workers goroutines, the
+// number of CPUs if workers is 0, so fn must be safe for concurrent use.
+// The first error returned by fn, or the cancellation of ctx, stops the
+// extraction and is returned.
+func (e Epub) ExtractAll(ctx context.Context, workers int, fn func(item ManifestItem, r io.Reader) error) error {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		mu       sync.Mutex
+		done     int
+	)
+	manifest := e.Manifest()
+	e.report(StageExtract, 0, len(manifest))
+	items := make(chan ManifestItem)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range items {
+				if ctx.Err() != nil {
+					continue
+				}
+				if err := e.extract(item, fn); err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					continue
+				}
+				mu.Lock()
+				done++
+				e.report(StageExtract, done, len(manifest))
+				mu.Unlock()
+			}
+		}()
+	}
+
+loop:
+	for _, item := range manifest {
+		select {
+		case items <- item:
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	close(items)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+func (e Epub) extract(item ManifestItem, fn func(item ManifestItem, r io.Reader) error) error {
+	f, err := e.open(e.rootPath + item.Href)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return fn(item, f)
+}
@@ -0,0 +1,54 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+func TestExtractAll(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	var mu sync.Mutex
+	sizes := make(map[string]int)
+	err := f.ExtractAll(context.Background(), 4, func(item ManifestItem, r io.Reader) error {
+		data, err := ioutil.ReadAll(r)
+		mu.Lock()
+		sizes[item.Href] = len(data)
+		mu.Unlock()
+		return err
+	})
+	if err != nil {
+		t.Fatalf("ExtractAll() return an error: %v", err)
+	}
+	if len(sizes) != len(f.Manifest()) {
+		t.Errorf("ExtractAll() extracted %d of %d items", len(sizes), len(f.Manifest()))
+	}
+	if sizes[coverFile] == 0 {
+		t.Errorf("The cover was not extracted")
+	}
+
+	errStop := errors.New("stop")
+	err = f.ExtractAll(context.Background(), 2, func(item ManifestItem, r io.Reader) error {
+		return errStop
+	})
+	if err != errStop {
+		t.Errorf("ExtractAll() return: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = f.ExtractAll(ctx, 0, func(item ManifestItem, r io.Reader) error { return nil })
+	if err != context.Canceled {
+		t.Errorf("ExtractAll() with a canceled context return: %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"bytes"
+	"image"
+	_ "image/gif" // register the GIF decoder for the figure sizes
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var altAttrRegexp = regexp.MustCompile(`\salt\s*=`)
+
+// Figure is an image shown on a content document
+type Figure struct {
+	// Href is the path of the image, as used by OpenFile
+	Href string
+	// ID is the id attribute of the figure element, or of the image if it
+	// is not inside a figure
+	ID  string
+	Alt string
+	// HasAlt is whether the image has an alt attribute, an empty one marks
+	// a decorative image
+	HasAlt  bool
+	Caption string
+	// Width and Height are the size in pixels of the image, 0 if it can't
+	// be decoded
+	Width  int
+	Height int
+	// Location is where the image is on the text of the document
+	Location Location
+}
+
+type figureState struct {
+	id           string
+	first        int
+	captionStart int
+	caption      string
+}
+
+// Figures returns the images of the documents of the spine, with their
+// captions if they are inside a figure element
+func (e Epub) Figures() ([]Figure, error) {
+	var figures []Figure
+	sizes := make(map[string]image.Config)
+	for i := 0; i < e.opf.spineLength(); i++ {
+		href := e.opf.spineURL(i)
+		if href == "" {
+			continue
+		}
+		name := e.rootPath + href
+		data, err := e.readFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		var stack []figureState
+		for _, loc := range htmlTagRegexp.FindAllSubmatchIndex(data, -1) {
+			closing := loc[3] > loc[2]
+			tagname := strings.ToLower(string(data[loc[4]:loc[5]]))
+			tagname = tagname[strings.Index(tagname, ":")+1:]
+			tag := string(data[loc[0]:loc[1]])
+
+			switch {
+			case tagname == "figure" && !closing:
+				stack = append(stack, figureState{id: attrValue(tag, "id"), first: len(figures), captionStart: -1})
+			case tagname == "figure" && len(stack) > 0:
+				fig := stack[len(stack)-1]
+				for j := fig.first; j < len(figures); j++ {
+					if figures[j].Caption == "" {
+						figures[j].Caption = fig.caption
+					}
+				}
+				stack = stack[:len(stack)-1]
+			case tagname == "figcaption" && len(stack) > 0 && !closing:
+				stack[len(stack)-1].captionStart = loc[1]
+			case tagname == "figcaption" && len(stack) > 0:
+				fig := &stack[len(stack)-1]
+				if fig.captionStart != -1 {
+					fig.caption = cellText(data[fig.captionStart:loc[0]])
+				}
+			case (tagname == "img" || tagname == "image") && !closing:
+				figure := e.figure(tag, name, sizes)
+				if figure.Href == "" {
+					continue
+				}
+				figure.Location = Location{SpineIndex: i, Href: href, Offset: len(extractText(data[:loc[0]]))}
+				if len(stack) > 0 {
+					figure.ID = stack[len(stack)-1].id
+				}
+				figures = append(figures, figure)
+			}
+		}
+	}
+	return figures, nil
+}
+
+// figure returns the figure of the image tag of the document docPath
+func (e Epub) figure(tag, docPath string, sizes map[string]image.Config) Figure {
+	src := attrValue(tag, "src")
+	if src == "" {
+		src = attrValue(tag, "xlink:href")
+	}
+	if src == "" {
+		src = attrValue(tag, "href")
+	}
+	target := resolveRef(docPath, src)
+	if target == "" {
+		return Figure{}
+	}
+
+	figure := Figure{
+		Href:   strings.TrimPrefix(target, e.rootPath),
+		ID:     attrValue(tag, "id"),
+		Alt:    attrValue(tag, "alt"),
+		HasAlt: altAttrRegexp.MatchString(tag),
+	}
+	if !figure.HasAlt {
+		figure.Alt = attrValue(tag, "aria-label")
+	}
+	size, ok := sizes[target]
+	if !ok {
+		size = e.imageSize(target)
+		sizes[target] = size
+	}
+	figure.Width, figure.Height = size.Width, size.Height
+	return figure
+}
+
+// imageSize returns the size of the image name, from the width and height
+// attributes of the root element for SVG
+func (e Epub) imageSize(name string) image.Config {
+	data, err := e.readFile(name)
+	if err != nil {
+		return image.Config{}
+	}
+	if e.opf.mediaType(strings.TrimPrefix(name, e.rootPath)) == svgMediaType {
+		tag := svgTagRegexp.FindIndex(data)
+		if tag == nil {
+			return image.Config{}
+		}
+		end := bytes.IndexByte(data[tag[0]:], '>')
+		if end == -1 {
+			return image.Config{}
+		}
+		root := string(data[tag[0] : tag[0]+end+1])
+		width, _ := strconv.Atoi(strings.TrimSuffix(attrValue(root, "width"), "px"))
+		height, _ := strconv.Atoi(strings.TrimSuffix(attrValue(root, "height"), "px"))
+		return image.Config{Width: width, Height: height}
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return image.Config{}
+	}
+	return config
+}
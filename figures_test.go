@@ -0,0 +1,42 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+func TestFigures(t *testing.T) {
+	f := buildEpub(t, "testdata/epub3.opf", map[string]string{
+		"images/map.png":  pngImage(30, 20),
+		"images/line.png": pngImage(5, 1),
+		"text/ch1.xhtml":  `<html><body><p>Intro</p><figure id="fig1"><img src="../images/map.png" alt="A map"/><figcaption>The  map</figcaption></figure></body></html>`,
+		"text/ch2.xhtml":  `<html><body><img src="../images/line.png" alt=""/><img src="../images/missing.png"/></body></html>`,
+	})
+	defer f.Close()
+	f.opf.Manifest = append(f.opf.Manifest,
+		manifest{ID: "map", Href: "images/map.png", MediaType: "image/png"},
+		manifest{ID: "line", Href: "images/line.png", MediaType: "image/png"},
+	)
+
+	figures, err := f.Figures()
+	if err != nil {
+		t.Fatalf("Figures() return an error: %v", err)
+	}
+	if len(figures) != 3 {
+		t.Fatalf("Figures() return: %v", figures)
+	}
+	fig := figures[0]
+	if fig.Href != "images/map.png" || fig.ID != "fig1" || fig.Alt != "A map" || !fig.HasAlt || fig.Caption != "The map" {
+		t.Errorf("Wrong figure: %+v", fig)
+	}
+	if fig.Width != 30 || fig.Height != 20 || fig.Location.SpineIndex != 0 || fig.Location.Href != "text/ch1.xhtml" {
+		t.Errorf("Wrong figure: %+v", fig)
+	}
+	if !figures[1].HasAlt || figures[1].Alt != "" || figures[1].Caption != "" {
+		t.Errorf("Wrong decorative figure: %+v", figures[1])
+	}
+	if figures[2].HasAlt || figures[2].Width != 0 || figures[2].Location.SpineIndex != 1 {
+		t.Errorf("Wrong figure without alt: %+v", figures[2])
+	}
+}
@@ -22,25 +22,14 @@ type rootfile struct {
 	Path string `xml:"full-path,attr"`
 }
 
-func openOPF(file *zip.Reader) (io.ReadCloser, error) {
-	path, err := getOpfPath(file)
-	if err != nil {
-		return nil, err
-	}
-	return openFile(file, path)
-}
-
-func getRootPath(file *zip.Reader) (string, error) {
-	opfPath, err := getOpfPath(file)
-	if err != nil {
-		return "", err
-	}
+// rootPath returns the directory of the OPF file, where the paths of the
+// manifest are relative to
+func rootPath(opfPath string) string {
 	pathDir := path.Dir(opfPath)
 	if pathDir == "." {
-		return "", nil
-	} else {
-		return path.Dir(opfPath) + "/", nil
+		return ""
 	}
+	return pathDir + "/"
 }
 
 func getOpfPath(file *zip.Reader) (string, error) {
@@ -55,26 +44,117 @@ func getOpfPath(file *zip.Reader) (string, error) {
 	return c.Rootfile.Path, err
 }
 
+// discoverOPF looks for the OPF file of an epub without a usable container:
+// the .opf file closest to the root of the zip, the first by name if there
+// are several. It returns an empty string if there is none.
+func discoverOPF(file *zip.Reader) string {
+	found := ""
+	for _, f := range file.File {
+		name := f.Name
+		if strings.HasSuffix(name, "/") || !strings.EqualFold(path.Ext(name), ".opf") {
+			continue
+		}
+		if found == "" {
+			found = name
+			continue
+		}
+		depth, foundDepth := strings.Count(name, "/"), strings.Count(found, "/")
+		if depth < foundDepth || (depth == foundDepth && name < found) {
+			found = name
+		}
+	}
+	return found
+}
+
+// maxXMLSize is the maximum size of the XML files decoded (container, OPF,
+// NCX, ...), to not exhaust the memory with malicious files
+const maxXMLSize = 32 << 20
+
 func decodeXML(file io.Reader, v interface{}) error {
-	decoder := xml.NewDecoder(file)
+	br := getBufioReader(&limitedReader{file, maxXMLSize})
+	defer putBufioReader(br)
+	decoder := xml.NewDecoder(br)
 	decoder.Entity = xml.HTMLEntity
 	decoder.CharsetReader = charset.NewReaderLabel
 	return decoder.Decode(v)
 }
 
 func openFile(file *zip.Reader, path string) (io.ReadCloser, error) {
+	f := findFile(file, path)
+	if f == nil {
+		return nil, errors.New("File " + path + " not found")
+	}
+	return openZipFile(f)
+}
+
+// findFile returns the file of the zip with the path, matching it case
+// insensitive if there is no exact match
+func findFile(file *zip.Reader, path string) *zip.File {
 	for _, f := range file.File {
 		if f.Name == path {
-			return f.Open()
+			return f
 		}
 	}
 
 	pathLower := strings.ToLower(path)
 	for _, f := range file.File {
 		if strings.ToLower(f.Name) == pathLower {
-			return f.Open()
+			return f
 		}
 	}
+	return nil
+}
+
+// zipIndex maps the names of the files of a zip, so looking them up doesn't
+// scan all the entries of big archives
+type zipIndex struct {
+	exact map[string]*zip.File
+	lower map[string]*zip.File
+}
+
+func newZipIndex(file *zip.Reader) zipIndex {
+	index := zipIndex{
+		exact: make(map[string]*zip.File, len(file.File)),
+		lower: make(map[string]*zip.File, len(file.File)),
+	}
+	for _, f := range file.File {
+		if _, ok := index.exact[f.Name]; !ok {
+			index.exact[f.Name] = f
+		}
+		lower := strings.ToLower(f.Name)
+		if _, ok := index.lower[lower]; !ok {
+			index.lower[lower] = f
+		}
+	}
+	return index
+}
+
+// findFile looks up the file of the epub with the path like findFile does,
+// using the index
+func (e Epub) findFile(path string) *zip.File {
+	if e.index.exact == nil {
+		return findFile(e.zip, path)
+	}
+	if f, ok := e.index.exact[path]; ok {
+		return f
+	}
+	return e.index.lower[strings.ToLower(path)]
+}
 
-	return nil, errors.New("File " + path + " not found")
+// limitedReader is like io.LimitedReader but fails when the limit is reached
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		return 0, errors.New("XML file too big")
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
 }
@@ -0,0 +1,110 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"strings"
+)
+
+func FuzzContainer(f *testing.F) {
+	f.Add([]byte(`<?xml version="1.0"?><container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container"><rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles></container>`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var c containerXML
+		decodeXML(bytes.NewReader(data), &c)
+	})
+}
+
+func FuzzParseOPF(f *testing.F) {
+	for _, path := range []string{"testdata/epub3.opf", "testdata/encoding_err.opf"} {
+		data, _ := ioutil.ReadFile(path)
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		opf, err := parseOPF(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		opf.toMData()
+		opf.ncxPath()
+		for i := 0; i < opf.spineLength(); i++ {
+			opf.spineURL(i)
+		}
+	})
+}
+
+func FuzzParseNCX(f *testing.F) {
+	data, _ := ioutil.ReadFile("testdata/nbsp.ncx")
+	f.Add(data)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ncx, err := parseNCX(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		nav, err := newNavigationIterator(ncx.navMap())
+		for err == nil {
+			nav.Title()
+			nav.URL()
+			if nav.HasChildren() {
+				err = nav.In()
+			} else {
+				err = nav.Next()
+				for err != nil && nav.HasParents() {
+					nav.Out()
+					err = nav.Next()
+				}
+			}
+		}
+		tocEntries(ncx.NavMap, "toc.ncx", "page.html")
+		ncx.marshalNavMap("")
+	})
+}
+
+func FuzzLoad(f *testing.F) {
+	for _, path := range []string{bookPath, noNCXPath, invalidNCXPath, fileCapsPath} {
+		data, _ := ioutil.ReadFile(path)
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		book, err := Load(bytes.NewReader(data), int64(len(data)))
+		if errors.Is(err, ErrMalformed) {
+			t.Fatalf("Load() recovered from a panic: %v", err)
+		}
+		if err != nil {
+			return
+		}
+		book.Warnings()
+		book.MetadataFields()
+		book.Titles()
+		book.TOC()
+		if nav, err := book.Navigation(); err == nil {
+			for nav.Next() == nil {
+			}
+		}
+		for i := 0; i < book.opf.spineLength(); i++ {
+			book.Text(i)
+			book.Segments(i)
+		}
+	})
+}
+
+func FuzzText(f *testing.F) {
+	f.Add(segmentsPage)
+	f.Fuzz(func(t *testing.T, page string) {
+		if strings.Count(page, "<") > 10000 {
+			return
+		}
+		text := extractText([]byte(page))
+		segments([]byte(page))
+		splitDocument([]byte(page), map[string]bool{"h1": true, "h2": true}, 100)
+		findQuotes(text)
+		NameRecognizer{}.Recognize(text)
+		splitSentences(text)
+	})
+}
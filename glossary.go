@@ -0,0 +1,166 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"errors"
+	"strings"
+)
+
+// GlossaryEntry is a term of the glossary of the book with its definition
+type GlossaryEntry struct {
+	Term string
+	// Definition is the text of the definitions of the term, each one on its
+	// own line
+	Definition string
+	// Href is the location of the term, a path as used by OpenFile followed
+	// by the fragment of its id if it has one
+	Href string
+}
+
+// GlossaryOccurrence is a glossary term found on the text of a document
+type GlossaryOccurrence struct {
+	// Entry is the position of the term on the list returned by Glossary
+	Entry int
+	// Text is the term as it is written on the text
+	Text     string
+	Location Location
+}
+
+// Glossary returns the entries of the glossaries of the book
+//
+// They are the glossterm and glossdef elements of the documents of the spine
+// and the manifest items with the glossary property. The terms sharing
+// definitions get all of them and the entries are in the order of the
+// documents.
+func (e Epub) Glossary() ([]GlossaryEntry, error) {
+	var docs []string
+	for i := 0; i < e.opf.spineLength(); i++ {
+		if href := e.opf.spineURL(i); href != "" {
+			docs = append(docs, href)
+		}
+	}
+	for _, item := range e.opf.Manifest {
+		if hasProperty(item.Properties, "glossary") && !contains(docs, item.Href) {
+			docs = append(docs, item.Href)
+		}
+	}
+
+	var entries []GlossaryEntry
+	for _, href := range docs {
+		data, err := e.readFile(e.rootPath + href)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, parseGlossary(data, href)...)
+	}
+	return entries, nil
+}
+
+// GlossaryOccurrences returns the glossary terms found on the text, as
+// returned by Text, of the document at spineIndex
+//
+// The terms are matched as whole words without case, the longest term is
+// matched when several start on the same word.
+func (e Epub) GlossaryOccurrences(spineIndex int) ([]GlossaryOccurrence, error) {
+	if spineIndex < 0 || spineIndex >= e.opf.spineLength() {
+		return nil, errors.New("Spine index out of range")
+	}
+	glossary, err := e.Glossary()
+	if err != nil {
+		return nil, err
+	}
+	text, err := e.Text(spineIndex)
+	if err != nil {
+		return nil, err
+	}
+	href := e.opf.spineURL(spineIndex)
+
+	terms := make(map[string]int)
+	var words []string
+	for i, entry := range glossary {
+		key := glossaryKey(entry.Term)
+		if _, ok := terms[key]; key == "" || ok {
+			continue
+		}
+		terms[key] = i
+		words = append(words, entry.Term)
+	}
+
+	var occurrences []GlossaryOccurrence
+	for _, match := range NewWordListMatcher("glossary", words).Match(text) {
+		occurrences = append(occurrences, GlossaryOccurrence{
+			Entry:    terms[glossaryKey(match.Text)],
+			Text:     match.Text,
+			Location: Location{SpineIndex: spineIndex, Href: href, Offset: match.Offset},
+		})
+	}
+	return occurrences, nil
+}
+
+// parseGlossary returns the glossary entries of the document data
+func parseGlossary(data []byte, docPath string) []GlossaryEntry {
+	var entries []GlossaryEntry
+	// group is the first entry sharing the definitions being parsed
+	group := 0
+	inDefinitions := false
+	var stack []indexElement
+	for _, loc := range htmlTagRegexp.FindAllSubmatchIndex(data, -1) {
+		closing := loc[3] > loc[2]
+		selfClosing := loc[7] > loc[6]
+		name := strings.ToLower(string(data[loc[4]:loc[5]]))
+		name = name[strings.Index(name, ":")+1:]
+		tag := string(data[loc[0]:loc[1]])
+
+		if !closing {
+			if !selfClosing && !voidElements[name] && len(stack) <= maxNestingDepth {
+				types := strings.Fields(attrValue(tag, "epub:type"))
+				stack = append(stack, indexElement{name: name, tag: tag, types: types, start: loc[1]})
+			}
+			continue
+		}
+		for i := len(stack) - 1; i >= 0; i-- {
+			if stack[i].name != name {
+				continue
+			}
+			for _, el := range stack[i:] {
+				switch {
+				case contains(el.types, "glossterm"):
+					if inDefinitions {
+						group = len(entries)
+						inDefinitions = false
+					}
+					href := docPath
+					if id := attrValue(el.tag, "id"); id != "" {
+						href += "#" + id
+					}
+					entries = append(entries, GlossaryEntry{Term: cellText(data[el.start:loc[0]]), Href: href})
+				case contains(el.types, "glossdef"):
+					inDefinitions = true
+					definition := extractText(data[el.start:loc[0]])
+					for j := group; j < len(entries); j++ {
+						if entries[j].Definition != "" {
+							entries[j].Definition += "\n"
+						}
+						entries[j].Definition += definition
+					}
+				}
+			}
+			stack = stack[:i]
+			break
+		}
+	}
+	return entries
+}
+
+// glossaryKey returns the term normalized as the words matched by
+// WordListMatcher
+func glossaryKey(term string) string {
+	var words []string
+	for _, w := range wordTokens(term) {
+		words = append(words, w.text)
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,68 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+const glossaryDocument = `<html xmlns:epub="http://www.idpf.org/2007/ops"><body>
+<section epub:type="glossary"><h1>Glossary</h1>
+<dl>
+<dt epub:type="glossterm" id="g-hobbit"><dfn>Hobbit</dfn></dt>
+<dt epub:type="glossterm"><dfn>Halfling</dfn></dt>
+<dd epub:type="glossdef"><p>A small person.</p></dd>
+<dt epub:type="glossterm" id="g-ring"><dfn>One Ring</dfn></dt>
+<dd epub:type="glossdef">A ring of power.</dd>
+<dd epub:type="glossdef">The master ring.</dd>
+</dl>
+</section>
+</body></html>`
+
+func glossaryEpub(t *testing.T) *Epub {
+	return buildEpub(t, epub3OPF, map[string]string{
+		"nav.xhtml":      epub3Nav,
+		"text/ch1.xhtml": `<html><body><p>The hobbit found the one  ring. Hobbits are halflings.</p></body></html>`,
+		"text/ch2.xhtml": glossaryDocument,
+	})
+}
+
+func TestGlossary(t *testing.T) {
+	f := glossaryEpub(t)
+	entries, err := f.Glossary()
+	if err != nil {
+		t.Fatalf("Glossary() return an error: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Glossary() return: %v", entries)
+	}
+	if entries[0] != (GlossaryEntry{"Hobbit", "A small person.", "text/ch2.xhtml#g-hobbit"}) {
+		t.Errorf("Wrong entry: %v", entries[0])
+	}
+	if entries[1].Definition != "A small person." || entries[1].Href != "text/ch2.xhtml" {
+		t.Errorf("Wrong entry: %v", entries[1])
+	}
+	if entries[2].Term != "One Ring" || entries[2].Definition != "A ring of power.\nThe master ring." {
+		t.Errorf("Wrong entry: %v", entries[2])
+	}
+}
+
+func TestGlossaryOccurrences(t *testing.T) {
+	f := glossaryEpub(t)
+	occurrences, err := f.GlossaryOccurrences(0)
+	if err != nil {
+		t.Fatalf("GlossaryOccurrences() return an error: %v", err)
+	}
+	if len(occurrences) != 2 {
+		t.Fatalf("GlossaryOccurrences() return: %v", occurrences)
+	}
+	if occurrences[0].Entry != 0 || occurrences[0].Text != "hobbit" || occurrences[0].Location.Offset != 4 {
+		t.Errorf("Wrong occurrence: %v", occurrences[0])
+	}
+	if occurrences[1].Entry != 2 || occurrences[1].Text != "one ring" {
+		t.Errorf("Wrong occurrence: %v", occurrences[1])
+	}
+	if _, err := f.GlossaryOccurrences(5); err == nil {
+		t.Errorf("GlossaryOccurrences() out of range didn't return an error")
+	}
+}
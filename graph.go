@@ -0,0 +1,268 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Kinds of the nodes of the StructureGraph
+const (
+	// NodeDocument is a document of the spine
+	NodeDocument = "document"
+	// NodeResource is a file of the manifest that is not on the spine
+	NodeResource = "resource"
+	// NodeMissing is a file referenced by the book that doesn't exist
+	NodeMissing = "missing"
+	// NodeTOC is the root of the table of contents
+	NodeTOC = "toc"
+	// NodeTOCEntry is an entry of the table of contents
+	NodeTOCEntry = "toc-entry"
+)
+
+// Kinds of the edges of the StructureGraph
+const (
+	// EdgeSpine goes from each document of the spine to the next one
+	EdgeSpine = "spine"
+	// EdgeTOC goes from each entry of the table of contents to its children
+	EdgeTOC = "toc"
+	// EdgeTOCTarget goes from an entry of the table of contents to its file
+	EdgeTOCTarget = "toc-target"
+	// EdgeReference goes from a document to the files it references: links,
+	// images, stylesheets, ...
+	EdgeReference = "reference"
+)
+
+// StructureGraph is the spine, the table of contents and the references
+// between the files of the book as a graph
+//
+// It is encoded as JSON with encoding/json or as Graphviz with DOT.
+type StructureGraph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a node of the StructureGraph
+type GraphNode struct {
+	// ID is the path of the file, as used by OpenFile, "#toc" for the root of
+	// the table of contents or "#toc/" followed by the position of the entry,
+	// like "#toc/1.2" for the second child of the first entry. A path can't
+	// start with "#", the ids of the entries never collide with the files.
+	ID    string `json:"id"`
+	Kind  string `json:"kind"`
+	Label string `json:"label"`
+	// MediaType is the media type of the files of the manifest
+	MediaType string `json:"mediaType,omitempty"`
+	// SpineIndex is the position of the documents on the spine, -1 for the
+	// other nodes
+	SpineIndex int `json:"spineIndex"`
+}
+
+// GraphEdge is an edge of the StructureGraph between the nodes with the ids
+// From and To
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Kind string `json:"kind"`
+}
+
+// StructureGraph returns the graph of the structure of the book
+//
+// The files of the manifest are nodes joined by the spine order and by their
+// references, the references to files that don't exist point to nodes of
+// kind NodeMissing. The table of contents is a tree of nodes that point to
+// their files. It is the NCX or, if the book has none, the entries of the
+// navigation document nested like its lists.
+func (e Epub) StructureGraph() (*StructureGraph, error) {
+	g := &StructureGraph{}
+	nodes := make(map[string]bool)
+	edges := make(map[GraphEdge]bool)
+	addNode := func(node GraphNode) {
+		if !nodes[node.ID] {
+			nodes[node.ID] = true
+			g.Nodes = append(g.Nodes, node)
+		}
+	}
+	addEdge := func(from, to, kind string) {
+		edge := GraphEdge{from, to, kind}
+		if !edges[edge] {
+			edges[edge] = true
+			g.Edges = append(g.Edges, edge)
+		}
+	}
+	// addTarget adds the edge to the file of href, and a missing node if it
+	// doesn't exist
+	addTarget := func(from, href, kind string) {
+		href = strings.SplitN(href, "#", 2)[0]
+		if href == "" || href == from {
+			return
+		}
+		if !nodes[href] && !e.inZip(e.rootPath+href) {
+			addNode(GraphNode{ID: href, Kind: NodeMissing, Label: href, SpineIndex: -1})
+		}
+		addEdge(from, href, kind)
+	}
+
+	previous := ""
+	for i := 0; i < e.opf.spineLength(); i++ {
+		href := e.opf.spineURL(i)
+		if href == "" {
+			continue
+		}
+		addNode(GraphNode{href, NodeDocument, href, e.opf.mediaType(href), i})
+		if previous != "" {
+			addEdge(previous, href, EdgeSpine)
+		}
+		previous = href
+	}
+	for _, item := range e.opf.Manifest {
+		addNode(GraphNode{item.Href, NodeResource, item.Href, item.MediaType, -1})
+	}
+
+	for _, item := range e.opf.Manifest {
+		if !isMarkup(item.MediaType) || item.MediaType == "application/x-dtbncx+xml" {
+			continue
+		}
+		data, err := e.readFile(e.rootPath + item.Href)
+		if err != nil {
+			continue
+		}
+		for _, target := range references(data, e.rootPath+item.Href) {
+			if strings.HasPrefix(target, e.rootPath) {
+				addTarget(item.Href, strings.TrimPrefix(target, e.rootPath), EdgeReference)
+			}
+		}
+	}
+
+	toc := e.TOC()
+	if toc == nil {
+		if navPath := e.navDocPath(); navPath != "" {
+			if data, err := e.readFile(e.rootPath + navPath); err == nil {
+				toc = e.navTOC(data, navPath)
+			}
+		}
+	}
+	if len(toc) != 0 {
+		addNode(GraphNode{ID: "#toc", Kind: NodeTOC, Label: "Table of contents", SpineIndex: -1})
+	}
+	var addEntries func(parent string, entries []TOCEntry)
+	addEntries = func(parent string, entries []TOCEntry) {
+		for i, entry := range entries {
+			id := parent + "/" + strconv.Itoa(i+1)
+			if parent != "#toc" {
+				id = parent + "." + strconv.Itoa(i+1)
+			}
+			addNode(GraphNode{ID: id, Kind: NodeTOCEntry, Label: entry.Title, SpineIndex: -1})
+			addEdge(parent, id, EdgeTOC)
+			addTarget(id, entry.Href, EdgeTOCTarget)
+			addEntries(id, entry.Children)
+		}
+	}
+	addEntries("#toc", toc)
+	return g, nil
+}
+
+var navTOCTagRegexp = regexp.MustCompile(`<(/?)(?:[\w-]+:)?(ol|li|a|span)\b[^>]*>`)
+
+// navTOC returns the entries of the toc nav of the navigation document data,
+// nested like its ol and li elements
+func (e Epub) navTOC(data []byte, navPath string) []TOCEntry {
+	nav, ok := navContent(data, "toc")
+	if !ok {
+		return nil
+	}
+	tags := navTOCTagRegexp.FindAllSubmatchIndex(nav, -1)
+	for i, tag := range tags {
+		if string(nav[tag[4]:tag[5]]) == "ol" && tag[2] == tag[3] {
+			entries, _ := e.navTOCList(nav, tags, i+1, navPath)
+			return entries
+		}
+	}
+	return nil
+}
+
+// navTOCList returns the entries of the list of the toc nav that starts on
+// the tag i and the index of the tag that closes it
+func (e Epub) navTOCList(nav []byte, tags [][]int, i int, navPath string) ([]TOCEntry, int) {
+	var entries []TOCEntry
+	for ; i < len(tags); i++ {
+		tag := tags[i]
+		closing := tag[2] != tag[3]
+		name := string(nav[tag[4]:tag[5]])
+		switch {
+		case name == "ol" && closing:
+			return entries, i
+		case name == "li" && !closing:
+			entries = append(entries, TOCEntry{})
+		case len(entries) == 0 || closing:
+		case name == "ol":
+			var children []TOCEntry
+			children, i = e.navTOCList(nav, tags, i+1, navPath)
+			entry := &entries[len(entries)-1]
+			entry.Children = append(entry.Children, children...)
+		case entries[len(entries)-1].Title == "":
+			// the label ends on the closing tag of the a or span
+			end := len(nav)
+			for _, next := range tags[i+1:] {
+				if next[2] != next[3] && string(nav[next[4]:next[5]]) == name {
+					end = next[0]
+					break
+				}
+			}
+			entry := &entries[len(entries)-1]
+			entry.Title = cellText(nav[tag[1]:end])
+			if ref := attrValue(string(nav[tag[0]:tag[1]]), "href"); name == "a" && ref != "" {
+				entry.Href = e.ResolveHref(navPath, ref)
+			}
+		}
+	}
+	return entries, i
+}
+
+// DOT returns the graph in the Graphviz DOT language
+//
+// The documents of the spine are boxes, the other files ellipses and the
+// missing files red. The spine edges are bold, the table of contents gray
+// and its targets dashed.
+func (g StructureGraph) DOT() string {
+	var buff bytes.Buffer
+	buff.WriteString("digraph book {\n  rankdir=LR;\n")
+	for _, node := range g.Nodes {
+		attrs := "label=" + dotQuote(node.Label)
+		switch node.Kind {
+		case NodeDocument:
+			attrs += ", shape=box"
+		case NodeResource:
+			attrs += ", shape=ellipse"
+		case NodeMissing:
+			attrs += ", shape=ellipse, style=dashed, color=red"
+		case NodeTOC, NodeTOCEntry:
+			attrs += ", shape=note, color=gray40"
+		}
+		buff.WriteString("  " + dotQuote(node.ID) + " [" + attrs + "];\n")
+	}
+	for _, edge := range g.Edges {
+		attrs := ""
+		switch edge.Kind {
+		case EdgeSpine:
+			attrs = " [style=bold, color=blue]"
+		case EdgeTOC:
+			attrs = " [color=gray40]"
+		case EdgeTOCTarget:
+			attrs = " [style=dashed, color=gray40]"
+		}
+		buff.WriteString("  " + dotQuote(edge.From) + " -> " + dotQuote(edge.To) + attrs + ";\n")
+	}
+	buff.WriteString("}\n")
+	return buff.String()
+}
+
+// dotQuote returns s as a quoted DOT id
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
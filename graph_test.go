@@ -0,0 +1,156 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+func TestStructureGraph(t *testing.T) {
+	f, err := New("", "Title", "en")
+	if err != nil {
+		t.Fatalf("New() return an error: %v", err)
+	}
+	f.AddResource("style.css", "text/css", strings.NewReader("p { margin: 0; }"))
+	f.InsertDocument("text/ch1.xhtml", "Chapter 1", []byte(`<html><head><link rel="stylesheet" href="../style.css"/></head>
+<body><p><a href="ch2.xhtml#note">Note</a> <a href="#top">Top</a> <img src="../images/missing.png"/></p></body></html>`), -1)
+	f.InsertDocument("text/ch2.xhtml", "Chapter \"2\"", []byte(`<html><body><p id="note">Note</p></body></html>`), -1)
+	book := repackBook(t, f)
+
+	g, err := book.StructureGraph()
+	if err != nil {
+		t.Fatalf("StructureGraph() return an error: %v", err)
+	}
+	kinds := make(map[string]string)
+	for _, node := range g.Nodes {
+		kinds[node.ID] = node.Kind
+	}
+	expected := map[string]string{
+		"text/ch1.xhtml":     NodeDocument,
+		"text/ch2.xhtml":     NodeDocument,
+		"style.css":          NodeResource,
+		"nav.xhtml":          NodeResource,
+		"images/missing.png": NodeMissing,
+		"#toc":               NodeTOC,
+		"#toc/1":             NodeTOCEntry,
+		"#toc/2":             NodeTOCEntry,
+	}
+	for id, kind := range expected {
+		if kinds[id] != kind {
+			t.Errorf("The node %v is: %v", id, kinds[id])
+		}
+	}
+	if len(kinds) != len(expected) {
+		t.Errorf("StructureGraph() nodes: %v", g.Nodes)
+	}
+
+	edges := make(map[GraphEdge]bool)
+	for _, edge := range g.Edges {
+		edges[edge] = true
+	}
+	for _, edge := range []GraphEdge{
+		{"text/ch1.xhtml", "text/ch2.xhtml", EdgeSpine},
+		{"text/ch1.xhtml", "text/ch2.xhtml", EdgeReference},
+		{"text/ch1.xhtml", "style.css", EdgeReference},
+		{"text/ch1.xhtml", "images/missing.png", EdgeReference},
+		{"nav.xhtml", "text/ch1.xhtml", EdgeReference},
+		{"#toc", "#toc/2", EdgeTOC},
+		{"#toc/2", "text/ch2.xhtml", EdgeTOCTarget},
+	} {
+		if !edges[edge] {
+			t.Errorf("StructureGraph() doesn't have the edge %v: %v", edge, g.Edges)
+		}
+	}
+	if edges[GraphEdge{"text/ch1.xhtml", "text/ch1.xhtml", EdgeReference}] {
+		t.Errorf("StructureGraph() has a reference to itself")
+	}
+
+	dot := g.DOT()
+	for _, s := range []string{
+		"digraph book {",
+		`"#toc/2" [label="Chapter \"2\"", shape=note, color=gray40];`,
+		`"images/missing.png" [label="images/missing.png", shape=ellipse, style=dashed, color=red];`,
+		`"text/ch1.xhtml" -> "text/ch2.xhtml" [style=bold, color=blue];`,
+	} {
+		if !strings.Contains(dot, s) {
+			t.Errorf("DOT() doesn't contain %q:\n%s", s, dot)
+		}
+	}
+
+	data, err := json.Marshal(g)
+	if err != nil || !strings.Contains(string(data), `{"from":"#toc","to":"#toc/1","kind":"toc"}`) {
+		t.Errorf("The JSON of the graph is: %s, %v", data, err)
+	}
+}
+
+func TestStructureGraphNestedNav(t *testing.T) {
+	f, _ := New("", "Title", "en")
+	f.InsertDocument("toc", "Chapter 1", []byte(`<html><body><p>One</p></body></html>`), -1)
+	f.InsertDocument("text/ch2.xhtml", "Chapter 2", []byte(`<html><body><p id="s1">Two</p></body></html>`), -1)
+	f.stage(f.rootPath+f.navDocPath(), []byte(`<html xmlns:epub="http://www.idpf.org/2007/ops"><body>
+<nav epub:type="toc"><ol>
+  <li><a href="toc">Chapter <em>1</em></a></li>
+  <li><span>Part</span>
+    <ol>
+      <li><a href="text/ch2.xhtml">Chapter 2</a>
+        <ol><li><a href="text/ch2.xhtml#s1">Section</a></li></ol>
+      </li>
+    </ol>
+  </li>
+</ol></nav>
+<nav epub:type="landmarks"><ol><li><a href="toc">Start</a></li></ol></nav>
+</body></html>`))
+	book := repackBook(t, f)
+	if book.TOC() != nil {
+		t.Fatalf("The book has a NCX: %v", book.TOC())
+	}
+
+	g, err := book.StructureGraph()
+	if err != nil {
+		t.Fatalf("StructureGraph() return an error: %v", err)
+	}
+	labels := make(map[string]string)
+	for _, node := range g.Nodes {
+		labels[node.ID+" "+node.Kind] = node.Label
+	}
+	for id, label := range map[string]string{
+		"toc " + NodeDocument:        "toc",
+		"#toc " + NodeTOC:            "Table of contents",
+		"#toc/1 " + NodeTOCEntry:     "Chapter 1",
+		"#toc/2 " + NodeTOCEntry:     "Part",
+		"#toc/2.1 " + NodeTOCEntry:   "Chapter 2",
+		"#toc/2.1.1 " + NodeTOCEntry: "Section",
+	} {
+		if labels[id] != label {
+			t.Errorf("The node %v has the label: %q", id, labels[id])
+		}
+	}
+	if _, ok := labels["#toc/3 "+NodeTOCEntry]; ok {
+		t.Errorf("StructureGraph() has entries of the landmarks: %v", g.Nodes)
+	}
+
+	edges := make(map[GraphEdge]bool)
+	for _, edge := range g.Edges {
+		edges[edge] = true
+	}
+	for _, edge := range []GraphEdge{
+		{"#toc/1", "toc", EdgeTOCTarget},
+		{"#toc/2", "#toc/2.1", EdgeTOC},
+		{"#toc/2.1", "#toc/2.1.1", EdgeTOC},
+		{"#toc/2.1.1", "text/ch2.xhtml", EdgeTOCTarget},
+	} {
+		if !edges[edge] {
+			t.Errorf("StructureGraph() doesn't have the edge %v: %v", edge, g.Edges)
+		}
+	}
+	for _, edge := range g.Edges {
+		if edge.From == "#toc/2" && edge.Kind == EdgeTOCTarget {
+			t.Errorf("The entry without link has a target: %v", edge)
+		}
+	}
+}
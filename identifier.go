@@ -0,0 +1,274 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// Identifier schemes detected by ParseIdentifier
+const (
+	SchemeISBN      = "ISBN"
+	SchemeASIN      = "ASIN"
+	SchemeDOI       = "DOI"
+	SchemeUUID      = "UUID"
+	SchemeGoodreads = "GOODREADS"
+	SchemeURI       = "URI"
+)
+
+// Kinds of ISBN for PickIdentifier, the identifiers with SchemeISBN written
+// with 13 or 10 digits
+const (
+	PreferISBN13 = "ISBN13"
+	PreferISBN10 = "ISBN10"
+)
+
+// defaultIdentifierPreference is the priority of PickIdentifier without
+// preferences
+var defaultIdentifierPreference = []string{PreferISBN13, PreferISBN10, SchemeUUID}
+
+// onixIdentifierTypes are the schemes of the ONIX codelist 5 values of the
+// identifier-type refinements
+var onixIdentifierTypes = map[string]string{
+	"02": SchemeISBN,
+	"06": SchemeDOI,
+	"15": SchemeISBN,
+}
+
+var (
+	isbnCharsRegexp = regexp.MustCompile(`[^0-9Xx]`)
+	uuidRegexp      = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	doiRegexp       = regexp.MustCompile(`^10\.\d{4,9}/\S+$`)
+	asinRegexp      = regexp.MustCompile(`^B0[0-9A-Z]{8}$`)
+	digitsOnly      = regexp.MustCompile(`^\d+$`)
+)
+
+var identifierPrefixes = []struct {
+	prefix string
+	scheme string
+}{
+	{"urn:isbn:", SchemeISBN},
+	{"isbn:", SchemeISBN},
+	{"urn:uuid:", SchemeUUID},
+	{"uuid:", SchemeUUID},
+	{"urn:doi:", SchemeDOI},
+	{"doi:", SchemeDOI},
+	{"https://doi.org/", SchemeDOI},
+	{"http://dx.doi.org/", SchemeDOI},
+	{"urn:asin:", SchemeASIN},
+	{"asin:", SchemeASIN},
+	{"amazon:", SchemeASIN},
+	{"mobi-asin:", SchemeASIN},
+	{"goodreads:", SchemeGoodreads},
+}
+
+// Identifier is a book identifier classified by its scheme
+type Identifier struct {
+	// Scheme is one of the Scheme constants, the declared scheme uppercased if
+	// the value doesn't match any of them or empty if unknown
+	Scheme string
+	// Value is the identifier without prefixes, ISBNs are normalized to ISBN-13
+	Value string
+	// Valid reports whether the value is well formed, for ISBNs it checks
+	// the checksum
+	Valid bool
+}
+
+// ParseIdentifier classifies and normalizes an identifier
+//
+// scheme is the declared scheme (the opf:scheme attribute or similar), it can
+// be empty. Prefixes like urn:isbn: or urn:uuid: are stripped and ISBN-10 are
+// converted to ISBN-13.
+func ParseIdentifier(value, scheme string) Identifier {
+	value = strings.TrimSpace(value)
+	hint := strings.ToUpper(strings.TrimSpace(scheme))
+	if strings.HasPrefix(hint, "ISBN") {
+		hint = SchemeISBN
+	}
+	lower := strings.ToLower(value)
+	for _, p := range identifierPrefixes {
+		if strings.HasPrefix(lower, p.prefix) {
+			value = strings.TrimSpace(value[len(p.prefix):])
+			hint = p.scheme
+			break
+		}
+	}
+
+	if id, ok := parseISBN(value); ok || hint == SchemeISBN {
+		id.Valid = ok
+		return id
+	}
+	switch {
+	case uuidRegexp.MatchString(value):
+		return Identifier{SchemeUUID, strings.ToLower(value), true}
+	case doiRegexp.MatchString(value):
+		return Identifier{SchemeDOI, value, true}
+	case asinRegexp.MatchString(value):
+		return Identifier{SchemeASIN, value, true}
+	case hint == SchemeASIN:
+		return Identifier{SchemeASIN, value, false}
+	case hint == SchemeGoodreads:
+		return Identifier{SchemeGoodreads, value, digitsOnly.MatchString(value)}
+	case hint == SchemeUUID || hint == SchemeDOI:
+		return Identifier{hint, value, false}
+	case strings.Contains(value, "://"):
+		return Identifier{SchemeURI, value, true}
+	}
+	return Identifier{hint, value, value != ""}
+}
+
+// ISBN13 converts an ISBN-10 into an ISBN-13
+//
+// Returns an error if the ISBN is not valid. ISBN-13 are returned normalized.
+func ISBN13(isbn string) (string, error) {
+	id, ok := parseISBN(isbn)
+	if !ok {
+		return "", errors.New("Invalid ISBN " + isbn)
+	}
+	return id.Value, nil
+}
+
+// parseISBN returns the identifier normalized to ISBN-13 and whether it is a
+// valid ISBN
+func parseISBN(value string) (Identifier, bool) {
+	isbn := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(value))
+	id := Identifier{Scheme: SchemeISBN, Value: isbn}
+	switch len(isbn) {
+	case 10:
+		if !isbn10Valid(isbn) {
+			return id, false
+		}
+		isbn = "978" + isbn[:9]
+		id.Value = isbn + isbn13CheckDigit(isbn)
+		return id, true
+	case 13:
+		if !digitsOnly.MatchString(isbn) || (!strings.HasPrefix(isbn, "978") && !strings.HasPrefix(isbn, "979")) {
+			return id, false
+		}
+		return id, isbn13CheckDigit(isbn[:12]) == isbn[12:]
+	}
+	return id, false
+}
+
+func isbn10Valid(isbn string) bool {
+	sum := 0
+	for i, c := range isbn {
+		var d int
+		switch {
+		case c >= '0' && c <= '9':
+			d = int(c - '0')
+		case c == 'X' && i == 9:
+			d = 10
+		default:
+			return false
+		}
+		sum += (10 - i) * d
+	}
+	return sum%11 == 0
+}
+
+func isbn13CheckDigit(first12 string) string {
+	sum := 0
+	for i, c := range first12 {
+		d := int(c - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return string(rune('0' + (10-sum%10)%10))
+}
+
+// BookIdentifier is an identifier of the metadata of the book
+type BookIdentifier struct {
+	Identifier
+	// Raw is the value as written on the metadata
+	Raw string
+	// ID is the id of the dc:identifier element
+	ID string
+	// Unique reports whether it is the unique identifier of the package
+	Unique bool
+}
+
+// Identifiers returns the identifiers of the metadata classified by their
+// scheme, in the order of the OPF
+//
+// The declared scheme is the opf:scheme attribute or the EPUB 3
+// identifier-type refinement. A book can have several identifiers of the
+// same scheme, like the ISBNs of the print and the electronic editions.
+func (e Epub) Identifiers() []BookIdentifier {
+	var ids []BookIdentifier
+	for _, elem := range e.metadata["identifier"] {
+		scheme := elem.Attr["scheme"]
+		if scheme == "" {
+			scheme = e.metadata.refinement(elem.Attr["id"], "identifier-type")
+			if s, ok := onixIdentifierTypes[strings.TrimSpace(scheme)]; ok {
+				scheme = s
+			}
+		}
+		ids = append(ids, BookIdentifier{
+			Identifier: ParseIdentifier(elem.Content, scheme),
+			Raw:        elem.Content,
+			ID:         elem.Attr["id"],
+			Unique:     elem.Attr["id"] != "" && elem.Attr["id"] == e.opf.UniqueIdentifier,
+		})
+	}
+	return ids
+}
+
+// PickIdentifier returns the identifier of the book of the first scheme of
+// preferred found, and false if the book has no identifiers
+//
+// The preferences are Scheme constants, PreferISBN13 or PreferISBN10, by
+// default PreferISBN13, PreferISBN10 and SchemeUUID. For each preference the
+// valid identifiers go first. If none of the preferences is found it returns
+// the unique identifier of the package or, if it is missing, the first
+// identifier. The choice only depends on the metadata, so it is the same
+// each time.
+func (e Epub) PickIdentifier(preferred ...string) (BookIdentifier, bool) {
+	ids := e.Identifiers()
+	if len(ids) == 0 {
+		return BookIdentifier{}, false
+	}
+	if len(preferred) == 0 {
+		preferred = defaultIdentifierPreference
+	}
+	for _, preference := range preferred {
+		preference = strings.ToUpper(preference)
+		for _, valid := range []bool{true, false} {
+			for _, id := range ids {
+				if id.Valid == valid && id.matches(preference) {
+					return id, true
+				}
+			}
+		}
+	}
+	for _, id := range ids {
+		if id.Unique {
+			return id, true
+		}
+	}
+	return ids[0], true
+}
+
+// matches returns whether the identifier is of the scheme preference, as in
+// PickIdentifier
+func (id BookIdentifier) matches(preference string) bool {
+	switch preference {
+	case PreferISBN13, PreferISBN10:
+		if id.Scheme != SchemeISBN {
+			return false
+		}
+		raw := strings.ToLower(strings.TrimSpace(id.Raw))
+		for _, p := range identifierPrefixes {
+			raw = strings.TrimPrefix(raw, p.prefix)
+		}
+		digits := len(isbnCharsRegexp.ReplaceAllString(raw, ""))
+		return (preference == PreferISBN13 && digits == 13) || (preference == PreferISBN10 && digits == 10)
+	}
+	return id.Scheme == preference
+}
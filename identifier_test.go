@@ -0,0 +1,87 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+func TestParseIdentifier(t *testing.T) {
+	tests := []struct {
+		value, scheme string
+		expected      Identifier
+	}{
+		{"urn:isbn:978-0-306-40615-7", "", Identifier{SchemeISBN, "9780306406157", true}},
+		{"0-306-40615-2", "", Identifier{SchemeISBN, "9780306406157", true}},
+		{"0306406153", "ISBN", Identifier{SchemeISBN, "0306406153", false}},
+		{"urn:uuid:8D5C1A3E-3C2F-4D0B-9F3A-2B1E0C9D7A65", "", Identifier{SchemeUUID, "8d5c1a3e-3c2f-4d0b-9f3a-2b1e0c9d7a65", true}},
+		{"doi:10.1000/182", "", Identifier{SchemeDOI, "10.1000/182", true}},
+		{"B00ABCDEFG", "", Identifier{SchemeASIN, "B00ABCDEFG", true}},
+		{"goodreads:12345", "", Identifier{SchemeGoodreads, "12345", true}},
+		{bookIdentifier, identifierScheme, Identifier{SchemeURI, bookIdentifier, true}},
+		{"1234567", "calibre", Identifier{"CALIBRE", "1234567", true}},
+	}
+	for _, test := range tests {
+		result := ParseIdentifier(test.value, test.scheme)
+		if result != test.expected {
+			t.Errorf("ParseIdentifier(%v, %v) return: %v when was expected: %v", test.value, test.scheme, result, test.expected)
+		}
+	}
+}
+
+func TestISBN13(t *testing.T) {
+	if isbn, err := ISBN13("080442957X"); err != nil || isbn != "9780804429573" {
+		t.Errorf("ISBN13(080442957X) return: %v, %v", isbn, err)
+	}
+	if _, err := ISBN13("0804429571"); err == nil {
+		t.Errorf("ISBN13() didn't return an error for an invalid ISBN")
+	}
+}
+
+func TestPickIdentifier(t *testing.T) {
+	f, err := New("urn:uuid:4fdd43d0-0a65-4e40-9c17-1e0a3b3e3e4c", "Title", "en")
+	if err != nil {
+		t.Fatalf("New() return an error: %v", err)
+	}
+	if id, ok := f.PickIdentifier(); !ok || id.Scheme != SchemeUUID || !id.Unique || id.ID != "uid" {
+		t.Errorf("PickIdentifier() with only the UUID return: %v, %v", id, ok)
+	}
+
+	f.SetMetadata("identifier", []MdataElement{
+		{Content: "urn:uuid:4fdd43d0-0a65-4e40-9c17-1e0a3b3e3e4c", Attr: map[string]string{"id": "uid"}},
+		{Content: "0-306-40615-2", Attr: map[string]string{"scheme": "ISBN"}},
+		{Content: "9780306406158", Attr: map[string]string{"scheme": "ISBN"}},
+		{Content: "978-0-306-40615-7", Attr: map[string]string{"id": "isbn"}},
+		{Content: "10.1000/182", Attr: map[string]string{}},
+	})
+	f.metadata["meta"] = append(f.metadata["meta"], MdataElement{
+		Content: "15",
+		Attr:    map[string]string{"refines": "#isbn", "property": "identifier-type", "scheme": "onix:codelist5"},
+	})
+
+	ids := f.Identifiers()
+	if len(ids) != 5 || ids[1].Value != "9780306406157" || ids[1].Raw != "0-306-40615-2" || ids[2].Valid || ids[3].Scheme != SchemeISBN || ids[4].Scheme != SchemeDOI {
+		t.Errorf("Identifiers() return: %v", ids)
+	}
+
+	tests := []struct {
+		preferred []string
+		raw       string
+	}{
+		{nil, "978-0-306-40615-7"},
+		{[]string{PreferISBN10}, "0-306-40615-2"},
+		{[]string{"doi", PreferISBN13}, "10.1000/182"},
+		{[]string{SchemeUUID}, "urn:uuid:4fdd43d0-0a65-4e40-9c17-1e0a3b3e3e4c"},
+		{[]string{SchemeASIN}, "urn:uuid:4fdd43d0-0a65-4e40-9c17-1e0a3b3e3e4c"},
+	}
+	for _, test := range tests {
+		if id, ok := f.PickIdentifier(test.preferred...); !ok || id.Raw != test.raw {
+			t.Errorf("PickIdentifier(%v) return: %v, %v", test.preferred, id, ok)
+		}
+	}
+
+	f.SetMetadata("identifier", nil)
+	if _, ok := f.PickIdentifier(); ok {
+		t.Errorf("PickIdentifier() without identifiers return true")
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"path"
+	"strings"
+)
+
+const convertedJPEGQuality = 90
+
+// nextGenImages are the image formats that older reading systems can't show
+// and the risk of using them
+var nextGenImages = map[string]string{
+	"image/webp": "WebP is only a core media type since EPUB 3.3, older reading systems can't show it",
+	"image/avif": "AVIF is not an EPUB core media type, most reading systems can't show it",
+	"image/jxl":  "JPEG XL is not an EPUB core media type, most reading systems can't show it",
+	"image/heic": "HEIC is not an EPUB core media type, most reading systems can't show it",
+}
+
+// ImageCompatibility is an image of the book that might not be shown by the
+// reading systems
+type ImageCompatibility struct {
+	// Href is the path of the image, as used by OpenFile
+	Href      string
+	MediaType string
+	Risk      string
+}
+
+// IncompatibleImages returns the images of the manifest in formats not
+// supported by older reading systems, like WebP or AVIF
+//
+// The format is taken from the content of the image when it can be detected,
+// if not from the media type declared on the manifest.
+func (e Epub) IncompatibleImages() ([]ImageCompatibility, error) {
+	var images []ImageCompatibility
+	for _, item := range e.opf.Manifest {
+		mediaType, err := e.imageType(item.Href, item.MediaType)
+		if err != nil {
+			return nil, err
+		}
+		if risk, ok := nextGenImages[mediaType]; ok {
+			images = append(images, ImageCompatibility{item.Href, mediaType, risk})
+		}
+	}
+	return images, nil
+}
+
+// ConvertImages converts the images reported by IncompatibleImages to JPEG,
+// or to PNG if they have transparency
+//
+// The images are decoded with the image package, so the decoders of their
+// formats must be registered, for example importing golang.org/x/image/webp.
+// The converted files are renamed with the new extension and the manifest and
+// the references to them are updated. The changes are written with Repack.
+func (e *Epub) ConvertImages() error {
+	images, err := e.IncompatibleImages()
+	if err != nil {
+		return err
+	}
+	for _, img := range images {
+		if err := e.convertImage(img.Href, img.MediaType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Epub) convertImage(href, mediaType string) error {
+	data, err := e.readFile(e.rootPath + href)
+	if err != nil {
+		return err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err == image.ErrFormat {
+		return errors.New("No decoder registered for " + mediaType + " to convert " + href)
+	}
+	if err != nil {
+		return err
+	}
+
+	var buff bytes.Buffer
+	newType := "image/jpeg"
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && !opaque.Opaque() {
+		newType = "image/png"
+		err = png.Encode(&buff, img)
+	} else {
+		err = jpeg.Encode(&buff, img, &jpeg.Options{Quality: convertedJPEGQuality})
+	}
+	if err != nil {
+		return err
+	}
+
+	newHref := strings.TrimSuffix(href, path.Ext(href)) + imageExtensions[newType]
+	if newHref != href {
+		newHref = e.opf.uniqueHref(newHref)
+		err = e.renameFile(e.rootPath+href, e.rootPath+newHref)
+		if err != nil {
+			return err
+		}
+	}
+	e.opf.manifestItem(e.opf.fileID(newHref)).MediaType = newType
+	e.stage(e.rootPath+newHref, buff.Bytes())
+	return nil
+}
+
+// imageType returns the media type detected from the content of the file
+// href, or declared if it can't be detected
+func (e Epub) imageType(href, declared string) (string, error) {
+	if !strings.HasPrefix(declared, "image/") {
+		return declared, nil
+	}
+	f, err := e.open(e.rootPath + href)
+	if err != nil {
+		return declared, nil
+	}
+	defer f.Close()
+	data, err := sniff(f)
+	if err != nil {
+		return "", err
+	}
+	if detected := DetectMediaType(data); detected != "" {
+		return detected, nil
+	}
+	return declared, nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"image"
+	"image/color"
+	"io"
+	"strings"
+)
+
+const (
+	fakeWebP       = "RIFF\x00\x00\x00\x00WEBPVP8 fake"
+	fakeAVIF       = "\x00\x00\x00\x1cftypavif\x00\x00\x00\x00"
+	frontpieceJPG  = "@public@vhost@g@gutenberg@html@files@3174@3174-h@images@Frontpiece.jpg"
+	frontpieceWebP = "@public@vhost@g@gutenberg@html@files@3174@3174-h@images@Frontpiece.webp"
+	frontpiecePNG  = "@public@vhost@g@gutenberg@html@files@3174@3174-h@images@Frontpiece.png"
+)
+
+func init() {
+	decode := func(r io.Reader) (image.Image, error) {
+		img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+		img.Set(0, 0, color.NRGBA{255, 0, 0, 128})
+		return img, nil
+	}
+	config := func(r io.Reader) (image.Config, error) {
+		return image.Config{ColorModel: color.NRGBAModel, Width: 2, Height: 2}, nil
+	}
+	image.RegisterFormat("webp", "RIFF????WEBPVP8", decode, config)
+}
+
+func webpBook(t *testing.T) *Epub {
+	f, _ := Open(bookPath)
+	if err := f.renameFile(f.rootPath+frontpieceJPG, f.rootPath+frontpieceWebP); err != nil {
+		t.Fatalf("renameFile() return an error: %v", err)
+	}
+	f.opf.manifestItem(f.opf.fileID(frontpieceWebP)).MediaType = "image/webp"
+	f.stage(f.rootPath+frontpieceWebP, []byte(fakeWebP))
+	return f
+}
+
+func TestIncompatibleImages(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	images, err := f.IncompatibleImages()
+	if err != nil {
+		t.Fatalf("IncompatibleImages() return an error: %v", err)
+	}
+	if len(images) != 0 {
+		t.Errorf("IncompatibleImages() return: %v", images)
+	}
+
+	f.stage(f.rootPath+coverJPG, []byte(fakeAVIF))
+	images, _ = f.IncompatibleImages()
+	if len(images) != 1 || images[0].Href != coverJPG || images[0].MediaType != "image/avif" {
+		t.Errorf("IncompatibleImages() return: %v", images)
+	}
+	if err := f.ConvertImages(); err == nil {
+		t.Errorf("ConvertImages() didn't return an error without an AVIF decoder")
+	}
+}
+
+func TestConvertImages(t *testing.T) {
+	f := webpBook(t)
+	defer f.Close()
+
+	images, _ := f.IncompatibleImages()
+	if len(images) != 1 || images[0].Href != frontpieceWebP {
+		t.Errorf("IncompatibleImages() return: %v", images)
+	}
+	if err := f.ConvertImages(); err != nil {
+		t.Fatalf("ConvertImages() return an error: %v", err)
+	}
+
+	book := repackBook(t, f)
+	if mediaType := book.opf.mediaType(frontpiecePNG); mediaType != "image/png" {
+		t.Errorf("mediaType(%v) return: %v", frontpiecePNG, mediaType)
+	}
+	if _, err := book.OpenFile(frontpieceWebP); err == nil {
+		t.Errorf("%v is still on the epub", frontpieceWebP)
+	}
+	html, _ := book.readFile(book.rootPath + htmlFile)
+	if !strings.Contains(string(html), frontpiecePNG) || strings.Contains(string(html), frontpieceWebP) {
+		t.Errorf("The references to %v were not updated", frontpiecePNG)
+	}
+	if images, _ := book.IncompatibleImages(); len(images) != 0 {
+		t.Errorf("IncompatibleImages() return: %v", images)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// ignorableChars are the invisible characters that can appear inside the words
+var ignorableChars = map[rune]bool{
+	'\u00ad': true, // soft hyphen
+	'\u200b': true, // zero width space
+	'\u200c': true, // zero width non-joiner
+	'\u200d': true, // zero width joiner
+	'\u2060': true, // word joiner
+	'\ufeff': true, // zero width no-break space
+}
+
+var ligatureReplacer = strings.NewReplacer(
+	"ﬀ", "ff", "ﬁ", "fi", "ﬂ", "fl", "ﬃ", "ffi", "ﬄ", "ffl", "ﬅ", "st", "ﬆ", "st",
+	"’", "'",
+)
+
+// IndexToken is a normalized word of the text of a document, ready to be
+// indexed by a search engine
+type IndexToken struct {
+	Term string
+	// Offset is the position in bytes of the word on the text returned by Text
+	Offset int
+}
+
+// IndexTokens returns the words of the document at spineIndex normalized for
+// a search index with NormalizeTerm
+//
+// The language of the book is used to lowercase the words.
+func (e Epub) IndexTokens(spineIndex int) ([]IndexToken, error) {
+	text, err := e.Text(spineIndex)
+	if err != nil {
+		return nil, err
+	}
+	return Tokenize(text, e.language()), nil
+}
+
+// Tokenize splits the text in words normalized with NormalizeTerm, like
+// IndexTokens does with the text of the documents
+func Tokenize(text, lang string) []IndexToken {
+	var tokens []IndexToken
+	start := -1
+	for i, r := range text + " " {
+		if isWordRune(r) {
+			if start == -1 {
+				start = i
+			}
+			continue
+		}
+		if start != -1 {
+			word := text[start:i]
+			if term := NormalizeTerm(word, lang); term != "" {
+				offset := start + len(word) - len(strings.TrimLeft(word, "'’"))
+				tokens = append(tokens, IndexToken{term, offset})
+			}
+			start = -1
+		}
+	}
+	return tokens
+}
+
+// NormalizeTerm normalizes a word for a search index, the same way IndexTokens
+// does, so it can be used on the search queries
+//
+// The word is normalized to NFC, the soft hyphens and zero width characters
+// are removed, the typographic ligatures are expanded and it is lowercased
+// following the rules of lang (for example the dotted i of Turkish).
+func NormalizeTerm(word, lang string) string {
+	word = strings.Map(func(r rune) rune {
+		if ignorableChars[r] {
+			return -1
+		}
+		return r
+	}, word)
+	word = ligatureReplacer.Replace(norm.NFC.String(word))
+	word = strings.Trim(word, "'")
+
+	switch baseLang(lang) {
+	case "tr", "az":
+		return strings.ToLowerSpecial(unicode.TurkishCase, word)
+	}
+	return strings.ToLower(word)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r) ||
+		ignorableChars[r] || r == '\'' || r == '’'
+}
@@ -0,0 +1,45 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+func TestIndexTokens(t *testing.T) {
+	f := buildEpub(t, "testdata/epub3.opf", map[string]string{
+		"text/ch1.xhtml": "<html><body><p>Ef\u00ad\ufb01cient cafe\u0301, ‘DON’T’ zero\u200bwidth</p></body></html>",
+		"text/ch2.xhtml": `<html><body><p>Text</p></body></html>`,
+	})
+	defer f.Close()
+
+	tokens, err := f.IndexTokens(0)
+	if err != nil {
+		t.Fatalf("IndexTokens() return an error: %v", err)
+	}
+	text, _ := f.Text(0)
+	expected := []string{"efficient", "café", "don't", "zerowidth"}
+	if len(tokens) != len(expected) {
+		t.Fatalf("IndexTokens() return: %v", tokens)
+	}
+	for i, token := range tokens {
+		if token.Term != expected[i] {
+			t.Errorf("IndexTokens()[%d] return: %v when was expected: %v", i, token.Term, expected[i])
+		}
+	}
+	if text[tokens[2].Offset:tokens[2].Offset+3] != "DON" {
+		t.Errorf("IndexTokens()[2] has the wrong offset: %v", tokens[2].Offset)
+	}
+}
+
+func TestNormalizeTerm(t *testing.T) {
+	if term := NormalizeTerm("İSTANBUL", "tr-TR"); term != "istanbul" {
+		t.Errorf("NormalizeTerm(tr) return: %v", term)
+	}
+	if term := NormalizeTerm("ISTANBUL", "tr"); term != "ıstanbul" {
+		t.Errorf("NormalizeTerm(tr) return: %v", term)
+	}
+	if term := NormalizeTerm("ISTANBUL", "en"); term != "istanbul" {
+		t.Errorf("NormalizeTerm(en) return: %v", term)
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"encoding/hex"
+	"sort"
+)
+
+// IntegrityManifest maps the path of each file inside the epub to the hex
+// encoded SHA-256 hash of its content
+type IntegrityManifest map[string]string
+
+// IntegrityManifest computes the SHA-256 hash of every file of the epub
+func (e Epub) IntegrityManifest() (IntegrityManifest, error) {
+	m := make(IntegrityManifest)
+	names := e.fileNames()
+	e.report(StageIntegrity, 0, len(names))
+	for i, name := range names {
+		digest, err := e.digest(name, sha256Digest)
+		if err != nil {
+			return nil, err
+		}
+		m[name] = hex.EncodeToString(digest)
+		e.report(StageIntegrity, i+1, len(names))
+	}
+	return m, nil
+}
+
+// Verify compares the epub against an integrity manifest
+//
+// Returns the sorted list of paths that are missing, unexpected or modified.
+// An empty list means the epub matches the manifest.
+func (e Epub) Verify(m IntegrityManifest) ([]string, error) {
+	current, err := e.IntegrityManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	var mismatches []string
+	for name, hash := range m {
+		if current[name] != hash {
+			mismatches = append(mismatches, name)
+		}
+	}
+	for name := range current {
+		if _, ok := m[name]; !ok {
+			mismatches = append(mismatches, name)
+		}
+	}
+	sort.Strings(mismatches)
+	return mismatches, nil
+}
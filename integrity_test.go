@@ -0,0 +1,49 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+const (
+	lenBookFiles = 14
+)
+
+func TestIntegrityManifest(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	m, err := f.IntegrityManifest()
+	if err != nil {
+		t.Fatalf("IntegrityManifest() return an error: %v", err)
+	}
+	if len(m) != lenBookFiles {
+		t.Errorf("len(IntegrityManifest()) should be %v, but was %v", lenBookFiles, len(m))
+	}
+
+	mismatches, err := f.Verify(m)
+	if err != nil {
+		t.Fatalf("Verify() return an error: %v", err)
+	}
+	if len(mismatches) != 0 {
+		t.Errorf("Verify() found mismatches on an unmodified epub: %v", mismatches)
+	}
+}
+
+func TestVerifyModified(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	m, _ := f.IntegrityManifest()
+	f.stage("3174/"+cssFile, []byte("body {}"))
+	f.stage("3174/new.css", []byte("p {}"))
+
+	mismatches, err := f.Verify(m)
+	if err != nil {
+		t.Fatalf("Verify() return an error: %v", err)
+	}
+	if len(mismatches) != 2 || mismatches[0] != "3174/new.css" || mismatches[1] != "3174/"+cssFile {
+		t.Errorf("Verify() return: %v", mismatches)
+	}
+}
@@ -0,0 +1,358 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+/*
+Package sqltest is an in-memory database/sql driver for the tests of the
+packages that store books on SQL databases.
+
+It only understands the statements those packages use, with ? placeholders:
+
+	CREATE [VIRTUAL] TABLE/INDEX ...
+	INSERT [OR IGNORE] INTO table (columns) VALUES (?, ...)
+	SELECT columns|COUNT(*) FROM table [WHERE column = ?|column MATCH ?] [ORDER BY ...] [LIMIT ?]
+	UPDATE table SET column = ?, ... WHERE column = ?
+	DELETE FROM table WHERE column = ?
+
+A table declaring "id INTEGER PRIMARY KEY" gets the ids assigned on insert,
+INSERT OR IGNORE ignores the rows equal to an existing one and MATCH is true
+when all the quoted terms of the query are words of the column, like a FTS5
+query without operators. The transactions are rolled back to the state of
+their beginning.
+*/
+package sqltest
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Row is a row of a table, indexed by the column names
+type Row map[string]driver.Value
+
+// DB is a database of the driver
+type DB struct {
+	mu     sync.Mutex
+	tables map[string]*table
+}
+
+type table struct {
+	autoID bool
+	lastID int64
+	rows   []Row
+}
+
+var (
+	registerOnce sync.Once
+	databases    = make(map[string]*DB)
+	databasesMu  sync.Mutex
+)
+
+type sqlDriver struct{}
+
+// Open returns a new empty database and its handle to inspect the rows
+func Open() (*sql.DB, *DB, error) {
+	registerOnce.Do(func() { sql.Register("sqltest", sqlDriver{}) })
+	db := &DB{tables: make(map[string]*table)}
+	databasesMu.Lock()
+	name := strconv.Itoa(len(databases))
+	databases[name] = db
+	databasesMu.Unlock()
+	sqlDB, err := sql.Open("sqltest", name)
+	return sqlDB, db, err
+}
+
+// Rows returns a copy of the rows of the table
+func (db *DB) Rows(name string) []Row {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	t, ok := db.tables[name]
+	if !ok {
+		return nil
+	}
+	rows := make([]Row, len(t.rows))
+	for i, row := range t.rows {
+		rows[i] = copyRow(row)
+	}
+	return rows
+}
+
+func (sqlDriver) Open(name string) (driver.Conn, error) {
+	databasesMu.Lock()
+	defer databasesMu.Unlock()
+	db, ok := databases[name]
+	if !ok {
+		return nil, errors.New("Unknown database " + name)
+	}
+	return &conn{db: db}, nil
+}
+
+type conn struct {
+	db       *DB
+	snapshot map[string]*table
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{c, strings.Join(strings.Fields(query), " ")}, nil
+}
+
+func (c *conn) Close() error { return nil }
+
+func (c *conn) Begin() (driver.Tx, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.snapshot = make(map[string]*table)
+	for name, t := range c.db.tables {
+		copied := *t
+		copied.rows = make([]Row, len(t.rows))
+		for i, row := range t.rows {
+			copied.rows[i] = copyRow(row)
+		}
+		c.snapshot[name] = &copied
+	}
+	return c, nil
+}
+
+func (c *conn) Commit() error {
+	c.snapshot = nil
+	return nil
+}
+
+func (c *conn) Rollback() error {
+	if c.snapshot == nil {
+		return nil
+	}
+	c.db.mu.Lock()
+	c.db.tables = c.snapshot
+	c.db.mu.Unlock()
+	c.snapshot = nil
+	return nil
+}
+
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+func (s *stmt) Close() error  { return nil }
+func (s *stmt) NumInput() int { return -1 }
+
+var (
+	createTableRegexp = regexp.MustCompile(`(?i)^CREATE (?:VIRTUAL )?TABLE (?:IF NOT EXISTS )?(\w+)`)
+	createIndexRegexp = regexp.MustCompile(`(?i)^CREATE INDEX`)
+	insertRegexp      = regexp.MustCompile(`(?i)^INSERT (OR IGNORE )?INTO (\w+) \(([^)]*)\) VALUES \(([^)]*)\)$`)
+	selectRegexp      = regexp.MustCompile(`(?i)^SELECT (.+?) FROM (\w+)(?: WHERE (\w+) (=|MATCH) \?)?(?: ORDER BY \w+)?(?: LIMIT \?)?$`)
+	updateRegexp      = regexp.MustCompile(`(?i)^UPDATE (\w+) SET (.+) WHERE (\w+) = \?$`)
+	deleteRegexp      = regexp.MustCompile(`(?i)^DELETE FROM (\w+) WHERE (\w+) = \?$`)
+)
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	db := s.conn.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if m := createTableRegexp.FindStringSubmatch(s.query); m != nil {
+		if _, ok := db.tables[m[1]]; !ok {
+			db.tables[m[1]] = &table{autoID: strings.Contains(s.query, "id INTEGER PRIMARY KEY")}
+		}
+		return driver.RowsAffected(0), nil
+	}
+	if createIndexRegexp.MatchString(s.query) {
+		return driver.RowsAffected(0), nil
+	}
+	if m := insertRegexp.FindStringSubmatch(s.query); m != nil {
+		t, err := db.table(m[2])
+		if err != nil {
+			return nil, err
+		}
+		columns := splitList(m[3])
+		if len(columns) != len(args) {
+			return nil, fmt.Errorf("%d values for %d columns", len(args), len(columns))
+		}
+		row := make(Row)
+		for i, column := range columns {
+			row[column] = args[i]
+		}
+		if m[1] != "" {
+			for _, existing := range t.rows {
+				if rowContains(existing, row) {
+					return result{t.lastID, 0}, nil
+				}
+			}
+		}
+		t.lastID++
+		if t.autoID {
+			row["id"] = t.lastID
+		}
+		t.rows = append(t.rows, row)
+		return result{t.lastID, 1}, nil
+	}
+	if m := updateRegexp.FindStringSubmatch(s.query); m != nil {
+		t, err := db.table(m[1])
+		if err != nil {
+			return nil, err
+		}
+		var columns []string
+		for _, assignment := range splitList(m[2]) {
+			columns = append(columns, strings.TrimSpace(strings.TrimSuffix(assignment, "= ?")))
+		}
+		if len(columns)+1 != len(args) {
+			return nil, fmt.Errorf("%d values for %d columns", len(args), len(columns)+1)
+		}
+		var affected int64
+		for _, row := range t.rows {
+			if equal(row[m[3]], args[len(args)-1]) {
+				for i, column := range columns {
+					row[column] = args[i]
+				}
+				affected++
+			}
+		}
+		return result{0, affected}, nil
+	}
+	if m := deleteRegexp.FindStringSubmatch(s.query); m != nil {
+		t, err := db.table(m[1])
+		if err != nil {
+			return nil, err
+		}
+		var kept []Row
+		for _, row := range t.rows {
+			if !equal(row[m[2]], args[0]) {
+				kept = append(kept, row)
+			}
+		}
+		affected := int64(len(t.rows) - len(kept))
+		t.rows = kept
+		return result{0, affected}, nil
+	}
+	return nil, errors.New("Unsupported statement: " + s.query)
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	db := s.conn.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	m := selectRegexp.FindStringSubmatch(s.query)
+	if m == nil {
+		return nil, errors.New("Unsupported query: " + s.query)
+	}
+	t, err := db.table(m[2])
+	if err != nil {
+		return nil, err
+	}
+	var selected []Row
+	for _, row := range t.rows {
+		switch {
+		case m[3] == "":
+		case strings.EqualFold(m[4], "MATCH") && !matches(row[m[3]], args[0]):
+			continue
+		case m[4] == "=" && !equal(row[m[3]], args[0]):
+			continue
+		}
+		selected = append(selected, row)
+	}
+	if strings.HasSuffix(s.query, "LIMIT ?") {
+		limit, ok := args[len(args)-1].(int64)
+		if ok && int(limit) < len(selected) {
+			selected = selected[:limit]
+		}
+	}
+
+	columns := splitList(m[1])
+	values := make([][]driver.Value, len(selected))
+	if len(columns) == 1 && strings.EqualFold(columns[0], "COUNT(*)") {
+		values = [][]driver.Value{{int64(len(selected))}}
+	} else {
+		for i, row := range selected {
+			for _, column := range columns {
+				values[i] = append(values[i], row[column])
+			}
+		}
+	}
+	return &rows{columns, values}, nil
+}
+
+func (db *DB) table(name string) (*table, error) {
+	t, ok := db.tables[name]
+	if !ok {
+		return nil, errors.New("No such table: " + name)
+	}
+	return t, nil
+}
+
+type result struct {
+	lastID   int64
+	affected int64
+}
+
+func (r result) LastInsertId() (int64, error) { return r.lastID, nil }
+func (r result) RowsAffected() (int64, error) { return r.affected, nil }
+
+type rows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *rows) Columns() []string { return r.columns }
+func (r *rows) Close() error      { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		items = append(items, strings.TrimSpace(item))
+	}
+	return items
+}
+
+func copyRow(row Row) Row {
+	copied := make(Row, len(row))
+	for k, v := range row {
+		copied[k] = v
+	}
+	return copied
+}
+
+func rowContains(row, values Row) bool {
+	for column, value := range values {
+		if !equal(row[column], value) {
+			return false
+		}
+	}
+	return true
+}
+
+func equal(a, b driver.Value) bool {
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+// matches returns whether all the quoted terms of query are words of value
+func matches(value, query driver.Value) bool {
+	words := make(map[string]bool)
+	for _, word := range strings.Fields(fmt.Sprint(value)) {
+		words[word] = true
+	}
+	terms := strings.Split(fmt.Sprint(query), `"`)
+	for i := 1; i < len(terms); i += 2 {
+		if !words[terms[i]] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,136 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+type ldThing struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+}
+
+type ldSeries struct {
+	Type     string `json:"@type"`
+	Name     string `json:"name"`
+	Position string `json:"position,omitempty"`
+}
+
+// ldBook is a schema.org Book
+type ldBook struct {
+	Context              string     `json:"@context"`
+	Type                 string     `json:"@type"`
+	ID                   string     `json:"@id,omitempty"`
+	Name                 string     `json:"name,omitempty"`
+	AlternativeHeadline  string     `json:"alternativeHeadline,omitempty"`
+	BookEdition          string     `json:"bookEdition,omitempty"`
+	BookFormat           string     `json:"bookFormat"`
+	Author               []ldThing  `json:"author,omitempty"`
+	Editor               []ldThing  `json:"editor,omitempty"`
+	Translator           []ldThing  `json:"translator,omitempty"`
+	Illustrator          []ldThing  `json:"illustrator,omitempty"`
+	Publisher            *ldThing   `json:"publisher,omitempty"`
+	DatePublished        string     `json:"datePublished,omitempty"`
+	ISBN                 []string   `json:"isbn,omitempty"`
+	Identifier           []string   `json:"identifier,omitempty"`
+	InLanguage           string     `json:"inLanguage,omitempty"`
+	Description          string     `json:"description,omitempty"`
+	Keywords             string     `json:"keywords,omitempty"`
+	IsPartOf             []ldSeries `json:"isPartOf,omitempty"`
+	AccessMode           []string   `json:"accessMode,omitempty"`
+	AccessModeSufficient []string   `json:"accessModeSufficient,omitempty"`
+	AccessibilityFeature []string   `json:"accessibilityFeature,omitempty"`
+	AccessibilityHazard  []string   `json:"accessibilityHazard,omitempty"`
+	AccessibilitySummary string     `json:"accessibilitySummary,omitempty"`
+	AccessibilityAPI     []string   `json:"accessibilityAPI,omitempty"`
+	AccessibilityControl []string   `json:"accessibilityControl,omitempty"`
+	ConformsTo           []string   `json:"conformsTo,omitempty"`
+	DateModified         string     `json:"dateModified,omitempty"`
+}
+
+// ToJSONLD returns the metadata of the book as a schema.org Book in JSON-LD
+//
+// The contributors are Person (authors, editors, translators and
+// illustrators), the publisher an Organization and the collections BookSeries
+// on isPartOf. The accessibility properties are taken from the schema: meta
+// properties of the package (schema:accessMode, schema:accessibilityFeature,
+// ...), the accessModeSufficient values keep their comma separated form.
+func (e Epub) ToJSONLD() ([]byte, error) {
+	ref := e.reference()
+	book := ldBook{
+		Context:              "https://schema.org",
+		Type:                 "Book",
+		Name:                 ref.title,
+		AlternativeHeadline:  ref.subtitle,
+		BookEdition:          ref.edition,
+		BookFormat:           "https://schema.org/EBook",
+		Author:               ldPersons(ref.authors),
+		Editor:               ldPersons(ref.editors),
+		Translator:           ldPersons(ref.translators),
+		Illustrator:          ldPersons(e.Contributors(RoleIllustrator)),
+		DatePublished:        e.first("date"),
+		InLanguage:           ref.language,
+		Description:          ref.abstract,
+		Keywords:             strings.Join(ref.keywords, ", "),
+		AccessMode:           e.metaProperties("schema:accessMode"),
+		AccessModeSufficient: e.metaProperties("schema:accessModeSufficient"),
+		AccessibilityFeature: e.metaProperties("schema:accessibilityFeature"),
+		AccessibilityHazard:  e.metaProperties("schema:accessibilityHazard"),
+		AccessibilityAPI:     e.metaProperties("schema:accessibilityAPI"),
+		AccessibilityControl: e.metaProperties("schema:accessibilityControl"),
+		ConformsTo:           e.metaProperties("dcterms:conformsTo"),
+	}
+	if summary := e.metaProperties("schema:accessibilitySummary"); len(summary) > 0 {
+		book.AccessibilitySummary = summary[0]
+	}
+	if modified := e.metaProperties("dcterms:modified"); len(modified) > 0 {
+		book.DateModified = modified[0]
+	}
+	if ref.publisher != "" {
+		book.Publisher = &ldThing{"Organization", ref.publisher}
+	}
+
+	seen := make(map[string]bool)
+	for _, elem := range e.metadata["identifier"] {
+		id := ParseIdentifier(elem.Content, elem.Attr["scheme"])
+		switch {
+		case id.Scheme == SchemeISBN && id.Valid:
+			if !seen[id.Value] {
+				book.ISBN = append(book.ISBN, id.Value)
+				seen[id.Value] = true
+			}
+		case id.Scheme == SchemeURI && book.ID == "":
+			book.ID = id.Value
+		default:
+			book.Identifier = append(book.Identifier, strings.TrimSpace(elem.Content))
+		}
+	}
+	for _, c := range e.Collections() {
+		book.IsPartOf = append(book.IsPartOf, ldSeries{"BookSeries", c.Name, c.Position})
+	}
+	return json.MarshalIndent(book, "", "  ")
+}
+
+func ldPersons(contributors []Contributor) []ldThing {
+	var persons []ldThing
+	for _, c := range contributors {
+		persons = append(persons, ldThing{"Person", c.Name})
+	}
+	return persons
+}
+
+// metaProperties returns the content of the metas of the package with the
+// given property, the refinements are not included
+func (e Epub) metaProperties(property string) []string {
+	var values []string
+	for _, meta := range e.metadata["meta"] {
+		if meta.Attr["property"] == property && meta.Attr["refines"] == "" {
+			values = append(values, strings.TrimSpace(meta.Content))
+		}
+	}
+	return values
+}
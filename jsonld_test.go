@@ -0,0 +1,63 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import "encoding/json"
+
+func TestToJSONLD(t *testing.T) {
+	f := buildEpub(t, epub3OPF, nil)
+
+	data, err := f.ToJSONLD()
+	if err != nil {
+		t.Fatalf("ToJSONLD() return an error: %v", err)
+	}
+	var book map[string]interface{}
+	if err := json.Unmarshal(data, &book); err != nil {
+		t.Fatalf("ToJSONLD() return invalid JSON: %v", err)
+	}
+	if book["@context"] != "https://schema.org" || book["@type"] != "Book" {
+		t.Errorf("ToJSONLD() return: %s", data)
+	}
+	if book["name"] != "The Lord of the Rings" || book["alternativeHeadline"] != "The Fellowship of the Ring" {
+		t.Errorf("ToJSONLD() return: %s", data)
+	}
+	author := book["author"].([]interface{})[0].(map[string]interface{})
+	if author["@type"] != "Person" || author["name"] != "J. R. R. Tolkien" {
+		t.Errorf("ToJSONLD() author: %v", author)
+	}
+	publisher := book["publisher"].(map[string]interface{})
+	if publisher["@type"] != "Organization" || publisher["name"] != "Allen & Unwin" {
+		t.Errorf("ToJSONLD() publisher: %v", publisher)
+	}
+	isbn := book["isbn"].([]interface{})
+	if len(isbn) != 1 || isbn[0] != "9780306406157" {
+		t.Errorf("ToJSONLD() isbn: %v", isbn)
+	}
+	if series := book["isPartOf"].([]interface{}); len(series) != 2 {
+		t.Errorf("ToJSONLD() isPartOf: %v", series)
+	}
+}
+
+func TestToJSONLDAccessibility(t *testing.T) {
+	f := buildEpub(t, epub3OPF, nil)
+	f.metadata["meta"] = append(f.metadata["meta"],
+		MdataElement{Content: "textual", Attr: map[string]string{"property": "schema:accessMode"}},
+		MdataElement{Content: "visual", Attr: map[string]string{"property": "schema:accessMode"}},
+		MdataElement{Content: "none", Attr: map[string]string{"property": "schema:accessibilityHazard"}},
+		MdataElement{Content: "Fully accessible.", Attr: map[string]string{"property": "schema:accessibilitySummary"}},
+	)
+
+	data, _ := f.ToJSONLD()
+	var book map[string]interface{}
+	json.Unmarshal(data, &book)
+	if modes := book["accessMode"].([]interface{}); len(modes) != 2 || modes[1] != "visual" {
+		t.Errorf("ToJSONLD() accessMode: %v", modes)
+	}
+	if book["accessibilitySummary"] != "Fully accessible." {
+		t.Errorf("ToJSONLD() accessibilitySummary: %v", book["accessibilitySummary"])
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"strings"
+)
+
+// guideLandmarkTypes are the epub:type of the EPUB 2 guide types that are
+// named differently
+var guideLandmarkTypes = map[string]string{
+	"text":             "bodymatter",
+	"title-page":       "titlepage",
+	"acknowledgements": "acknowledgments",
+	"notes":            "endnotes",
+}
+
+// Landmark is an entry of the landmarks of the book
+type Landmark struct {
+	// Type is the epub:type of the landmark, like "bodymatter" or "toc".
+	// The EPUB 2 guide types are translated to their epub:type.
+	Type  string
+	Title string
+	// Href is the location of the landmark, a path as used by OpenFile
+	// maybe followed by a fragment
+	Href string
+}
+
+// Landmarks returns the landmarks of the navigation document followed by
+// the references of the guide of other types
+func (e Epub) Landmarks() ([]Landmark, error) {
+	var landmarks []Landmark
+	seen := make(map[string]bool)
+	if navPath := e.navDocPath(); navPath != "" {
+		data, err := e.readFile(e.rootPath + navPath)
+		if err != nil {
+			return nil, err
+		}
+		nav, _ := navContent(data, "landmarks")
+		for _, a := range navAnchorRegexp.FindAllSubmatchIndex(nav, -1) {
+			tag := string(nav[a[0]:a[2]])
+			ref := attrValue(tag, "href")
+			if ref == "" {
+				continue
+			}
+			href := e.ResolveHref(navPath, ref)
+			for _, t := range strings.Fields(attrValue(tag, "epub:type")) {
+				landmarks = append(landmarks, Landmark{t, cellText(nav[a[2]:a[3]]), href})
+				seen[t] = true
+			}
+		}
+	}
+
+	for _, ref := range e.opf.Guide {
+		t := strings.TrimSpace(ref.Type)
+		if landmarkType, ok := guideLandmarkTypes[t]; ok {
+			t = landmarkType
+		}
+		if t == "" || seen[t] {
+			continue
+		}
+		landmarks = append(landmarks, Landmark{t, strings.TrimSpace(ref.Title), ref.Href})
+	}
+	return landmarks, nil
+}
+
+// StartOfContent returns the spine index and the href of the beginning of
+// the body matter, where a book is opened the first time
+//
+// It is the bodymatter landmark or, if there is none, the first document of
+// the spine that is not front matter. It returns -1 if the spine is empty.
+func (e Epub) StartOfContent() (int, string) {
+	if i, href := e.landmark("bodymatter"); i != -1 {
+		return i, href
+	}
+	if e.opf.spineLength() == 0 {
+		return -1, ""
+	}
+	i := e.bodyMatterStart()
+	return i, e.opf.spineURL(i)
+}
+
+// TOCDocument returns the spine index and the href of the table of contents
+// of the book
+//
+// It is the toc landmark, the navigation document if it is on the spine or
+// the document with a toc epub:type. It returns -1 if the table of contents
+// is not a document of the spine.
+func (e Epub) TOCDocument() (int, string) {
+	if i, href := e.landmark("toc"); i != -1 {
+		return i, href
+	}
+	if navPath := e.navDocPath(); e.opf.spineIndex(navPath) != -1 {
+		return e.opf.spineIndex(navPath), navPath
+	}
+	return e.typedDocument([]string{"toc"}, nil)
+}
+
+// Bibliography returns the spine index and the href of the bibliography of
+// the book, or -1 if it is not found
+//
+// It is the bibliography landmark, the document with a bibliography
+// epub:type or the entry of the table of contents titled "Bibliography",
+// "References" or "Works Cited".
+func (e Epub) Bibliography() (int, string) {
+	if i, href := e.landmark("bibliography"); i != -1 {
+		return i, href
+	}
+	return e.typedDocument([]string{"bibliography"}, []string{"bibliography", "references", "works cited"})
+}
+
+// Acknowledgements returns the spine index and the href of the
+// acknowledgements of the book, or -1 if they are not found
+//
+// They are the acknowledgments landmark, the document with an
+// acknowledgments epub:type or the entry of the table of contents titled
+// "Acknowledgements" or "Acknowledgments".
+func (e Epub) Acknowledgements() (int, string) {
+	if i, href := e.landmark("acknowledgments"); i != -1 {
+		return i, href
+	}
+	return e.typedDocument([]string{"acknowledgments", "acknowledgements"}, []string{"acknowledgements", "acknowledgments"})
+}
+
+// landmark returns the spine index and the href of the first landmark of
+// type landmarkType on the spine, or -1 if there is none
+func (e Epub) landmark(landmarkType string) (int, string) {
+	landmarks, _ := e.Landmarks()
+	for _, l := range landmarks {
+		if l.Type != landmarkType {
+			continue
+		}
+		if i := e.opf.spineIndex(strings.SplitN(l.Href, "#", 2)[0]); i != -1 {
+			return i, l.Href
+		}
+	}
+	return -1, ""
+}
+
+// typedDocument returns the spine index and the href of the first element
+// with any of the epub:types on the documents of the spine or, if there is
+// none, of the entry of the table of contents with any of the titles,
+// compared without case. It returns -1 if none is found.
+func (e Epub) typedDocument(types, titles []string) (int, string) {
+	for i := 0; i < e.opf.spineLength(); i++ {
+		href := e.opf.spineURL(i)
+		if href == "" {
+			continue
+		}
+		data, err := e.readFile(e.rootPath + href)
+		if err != nil {
+			continue
+		}
+		for _, tag := range htmlTagRegexp.FindAll(data, -1) {
+			for _, t := range strings.Fields(attrValue(string(tag), "epub:type")) {
+				if !contains(types, t) {
+					continue
+				}
+				if id := attrValue(string(tag), "id"); id != "" {
+					return i, href + "#" + id
+				}
+				return i, href
+			}
+		}
+	}
+
+	if len(titles) == 0 {
+		return -1, ""
+	}
+	var entries []NavListEntry
+	if navPath := e.navDocPath(); navPath != "" {
+		if data, err := e.readFile(e.rootPath + navPath); err == nil {
+			entries, _ = e.navDocList(data, navPath, "toc")
+		}
+	}
+	if entries == nil && e.ncx != nil {
+		ncxPath := e.opf.ncxPath()
+		var walk func(points []navpoint)
+		walk = func(points []navpoint) {
+			for _, point := range points {
+				entries = append(entries, e.newNavListEntry(point.Title(), e.ResolveHref(ncxPath, point.URL())))
+				walk(point.Children())
+			}
+		}
+		walk(e.ncx.navMap())
+	}
+	for _, entry := range entries {
+		if entry.SpineIndex != -1 && contains(titles, strings.ToLower(strings.Join(strings.Fields(entry.Label), " "))) {
+			return entry.SpineIndex, entry.Href
+		}
+	}
+	return -1, ""
+}
@@ -0,0 +1,71 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+func TestLandmarks(t *testing.T) {
+	opf, _ := ioutil.ReadFile(epub3OPF)
+	opfStr := strings.Replace(string(opf), "</spine>", `</spine>
+  <guide>
+    <reference type="text" title="Start" href="text/ch2.xhtml"/>
+    <reference type="acknowledgements" title="Thanks" href="text/ch2.xhtml#thanks"/>
+  </guide>`, 1)
+	opfPath := filepath.Join(t.TempDir(), "content.opf")
+	ioutil.WriteFile(opfPath, []byte(opfStr), 0644)
+
+	nav := strings.Replace(epub3Nav, "</body>", `<nav epub:type="landmarks"><ol>
+<li><a epub:type="bodymatter" href="text/ch1.xhtml#start">Start of Content</a></li>
+</ol></nav>
+</body>`, 1)
+	f := buildEpub(t, opfPath, map[string]string{
+		"nav.xhtml":      nav,
+		"text/ch1.xhtml": `<html><body><p id="start">Chapter 1</p></body></html>`,
+		"text/ch2.xhtml": `<html><body><section epub:type="bibliography" id="biblio"><p>Books</p></section></body></html>`,
+	})
+	landmarks, err := f.Landmarks()
+	if err != nil {
+		t.Fatalf("Landmarks() return an error: %v", err)
+	}
+	if len(landmarks) != 2 || landmarks[0] != (Landmark{"bodymatter", "Start of Content", "text/ch1.xhtml#start"}) ||
+		landmarks[1] != (Landmark{"acknowledgments", "Thanks", "text/ch2.xhtml#thanks"}) {
+		t.Errorf("Landmarks() return: %v", landmarks)
+	}
+
+	if i, href := f.StartOfContent(); i != 0 || href != "text/ch1.xhtml#start" {
+		t.Errorf("StartOfContent() return: %v, %v", i, href)
+	}
+	if i, href := f.Acknowledgements(); i != 1 || href != "text/ch2.xhtml#thanks" {
+		t.Errorf("Acknowledgements() return: %v, %v", i, href)
+	}
+	if i, href := f.Bibliography(); i != 1 || href != "text/ch2.xhtml#biblio" {
+		t.Errorf("Bibliography() return: %v, %v", i, href)
+	}
+	if i, href := f.TOCDocument(); i != -1 || href != "" {
+		t.Errorf("TOCDocument() return: %v, %v", i, href)
+	}
+}
+
+func TestLandmarksFallback(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	if i, href := f.StartOfContent(); i == -1 || href != f.opf.spineURL(i) {
+		t.Errorf("StartOfContent() return: %v, %v", i, href)
+	}
+	f.ncx.NavMap[0].Text = " Works  Cited "
+	if i, href := f.Bibliography(); i == -1 || !strings.HasPrefix(href, f.opf.spineURL(i)) {
+		t.Errorf("Bibliography() return: %v, %v", i, href)
+	}
+	if i, _ := f.Acknowledgements(); i != -1 {
+		t.Errorf("Acknowledgements() return: %v", i)
+	}
+}
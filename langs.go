@@ -0,0 +1,119 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"errors"
+	"strings"
+)
+
+// langValue is a metadata value with its language
+type langValue struct {
+	lang, value string
+}
+
+// MetadataByLang returns the values of the metadata field grouped by their
+// language
+//
+// The language of a value is its xml:lang attribute, or the one of the
+// package element if it has none. The values without language are under "".
+// The EPUB 3 alternate-script refinements of an element are values of the
+// field in their language, so a title with its transliteration is returned
+// in both languages.
+func (e Epub) MetadataByLang(field string) (map[string][]string, error) {
+	values, err := e.langValues(field)
+	if err != nil {
+		return nil, err
+	}
+	byLang := make(map[string][]string)
+	for _, v := range values {
+		byLang[v.lang] = append(byLang[v.lang], v.value)
+	}
+	return byLang, nil
+}
+
+// MetadataForLang returns the values of the metadata field in the language
+// lang, a BCP 47 tag like "pt-BR"
+//
+// The languages are compared without case and the first step of the
+// fallback chain with values is returned: the values in lang, in its
+// prefixes ("pt"), in other variants of its base language ("pt-PT"), the
+// values without language, the values in the language of the book and
+// finally all the values.
+func (e Epub) MetadataForLang(field, lang string) ([]string, error) {
+	values, err := e.langValues(field)
+	if err != nil {
+		return nil, err
+	}
+	filter := func(match func(l string) bool) []string {
+		var result []string
+		for _, v := range values {
+			if match(strings.ToLower(v.lang)) {
+				result = append(result, v.value)
+			}
+		}
+		return result
+	}
+
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	var chain []func(l string) bool
+	for prefix := lang; prefix != ""; {
+		p := prefix
+		chain = append(chain, func(l string) bool { return l == p })
+		i := strings.LastIndex(prefix, "-")
+		if i == -1 {
+			break
+		}
+		prefix = prefix[:i]
+	}
+	if lang != "" {
+		base := baseLang(lang)
+		chain = append(chain, func(l string) bool { return l != "" && baseLang(l) == base })
+	}
+	chain = append(chain, func(l string) bool { return l == "" })
+	if bookLang := strings.ToLower(e.language()); bookLang != "" {
+		chain = append(chain, func(l string) bool { return l != "" && baseLang(l) == baseLang(bookLang) })
+	}
+	for _, match := range chain {
+		if result := filter(match); len(result) > 0 {
+			return result, nil
+		}
+	}
+	return filter(func(string) bool { return true }), nil
+}
+
+// langValues returns the values of the field and their alternate scripts
+// with their languages
+func (e Epub) langValues(field string) ([]langValue, error) {
+	elems, ok := e.metadata[field]
+	if !ok {
+		return nil, errors.New("Metadata field " + field + " does not exist")
+	}
+	var values []langValue
+	for _, elem := range elems {
+		values = append(values, langValue{e.elementLang(elem), elem.Content})
+		id := elem.Attr["id"]
+		if id == "" {
+			continue
+		}
+		for _, meta := range e.metadata["meta"] {
+			if meta.Attr["refines"] == "#"+id && meta.Attr["property"] == "alternate-script" {
+				values = append(values, langValue{e.elementLang(meta), meta.Content})
+			}
+		}
+	}
+	return values, nil
+}
+
+// elementLang returns the language of the metadata element
+func (e Epub) elementLang(elem MdataElement) string {
+	if lang := elem.Attr["xml:lang"]; lang != "" {
+		return strings.TrimSpace(lang)
+	}
+	if lang := elem.Attr["lang"]; lang != "" {
+		return strings.TrimSpace(lang)
+	}
+	return strings.TrimSpace(e.opf.Lang)
+}
@@ -0,0 +1,63 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+func bilingualEpub(t *testing.T) *Epub {
+	opf, _ := ioutil.ReadFile(epub3OPF)
+	opf = []byte(strings.Replace(string(opf), `<dc:title id="t3">Collector's Edition</dc:title>`,
+		`<dc:title id="t3" xml:lang="pt-BR">O Senhor dos Anéis</dc:title>
+    <dc:title xml:lang="fr">Le Seigneur des anneaux</dc:title>
+    <meta refines="#t1" property="alternate-script" xml:lang="ja">指輪物語</meta>`, 1))
+	opfPath := filepath.Join(t.TempDir(), "content.opf")
+	ioutil.WriteFile(opfPath, opf, 0644)
+	return buildEpub(t, opfPath, nil)
+}
+
+func TestMetadataByLang(t *testing.T) {
+	f := bilingualEpub(t)
+	titles, err := f.MetadataByLang("title")
+	if err != nil {
+		t.Fatalf("MetadataByLang() return an error: %v", err)
+	}
+	if len(titles["en"]) != 2 || titles["en"][0] != "The Lord of the Rings" {
+		t.Errorf("The english titles are: %v", titles["en"])
+	}
+	if len(titles["ja"]) != 1 || len(titles["pt-BR"]) != 1 || len(titles["fr"]) != 1 {
+		t.Errorf("MetadataByLang() return: %v", titles)
+	}
+	if _, err := f.MetadataByLang("foo"); err == nil {
+		t.Errorf("MetadataByLang() of an unknown field didn't return an error")
+	}
+}
+
+func TestMetadataForLang(t *testing.T) {
+	f := bilingualEpub(t)
+	tests := map[string]string{
+		"pt-BR": "O Senhor dos Anéis",
+		"PT":    "O Senhor dos Anéis",
+		"pt-PT": "O Senhor dos Anéis",
+		"fr-CA": "Le Seigneur des anneaux",
+		"ja":    "指輪物語",
+		"de":    "The Lord of the Rings",
+		"":      "The Lord of the Rings",
+	}
+	for lang, expected := range tests {
+		titles, err := f.MetadataForLang("title", lang)
+		if err != nil {
+			t.Fatalf("MetadataForLang() return an error: %v", err)
+		}
+		if len(titles) == 0 || titles[0] != expected {
+			t.Errorf("MetadataForLang(title, %v) return: %v", lang, titles)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+var (
+	verticalModeRegexp = regexp.MustCompile(`(?i)writing-mode\s*:\s*(?:vertical-rl|vertical-lr|tb-rl|tb\b)`)
+	rubyTagRegexp      = regexp.MustCompile(`<(?:[\w-]+:)?ruby\b`)
+)
+
+// rtlLanguages are the languages written right to left
+var rtlLanguages = map[string]bool{
+	"ar": true, "he": true, "iw": true, "fa": true, "ur": true, "yi": true,
+	"ps": true, "sd": true, "ug": true, "dv": true, "ckb": true, "syr": true,
+}
+
+// cjkLanguages are the Chinese, Japanese and Korean languages
+var cjkLanguages = map[string]bool{
+	"zh": true, "ja": true, "ko": true, "yue": true, "cmn": true,
+}
+
+// LayoutHints are the settings a renderer needs to lay out the book
+type LayoutHints struct {
+	// PageProgression is the direction of the pages, "ltr" or "rtl", as
+	// declared on the spine or guessed from the language and the writing
+	// mode if the spine doesn't declare it
+	PageProgression string
+	// RightToLeft is whether the language of the book is written right to
+	// left, like Arabic or Hebrew
+	RightToLeft bool
+	// CJK is whether the language of the book is Chinese, Japanese or Korean
+	CJK bool
+	// VerticalWriting is whether the book uses the vertical writing modes,
+	// on the stylesheets, the documents or the primary-writing-mode meta
+	VerticalWriting bool
+	// Ruby is whether the documents of the spine have ruby annotations
+	Ruby bool
+	// FixedLayout is whether the book is pre-paginated
+	FixedLayout bool
+	// CJKFonts are the embedded fonts covering CJK scripts, as used by
+	// OpenFile. The obfuscated and WOFF2 fonts can't be inspected.
+	CJKFonts []string
+}
+
+// LayoutHints inspects the book to find the direction, the writing mode,
+// the annotations and the fonts it needs
+func (e Epub) LayoutHints() (*LayoutHints, error) {
+	lang := baseLang(e.language())
+	hints := LayoutHints{
+		RightToLeft: rtlLanguages[lang],
+		CJK:         cjkLanguages[lang],
+		FixedLayout: e.renditionLayout() == "pre-paginated",
+	}
+	if mode := e.metaContent("primary-writing-mode"); strings.HasPrefix(mode, "vertical") {
+		hints.VerticalWriting = true
+	}
+
+	for _, item := range e.opf.Manifest {
+		name := e.rootPath + item.Href
+		switch {
+		case item.MediaType == "text/css" || item.MediaType == "application/xhtml+xml":
+			data, err := e.readFile(name)
+			if err != nil {
+				return nil, err
+			}
+			if verticalModeRegexp.Match(data) {
+				hints.VerticalWriting = true
+			}
+			if item.MediaType != "text/css" && e.opf.spineIndex(item.Href) != -1 && rubyTagRegexp.Match(data) {
+				hints.Ruby = true
+			}
+		case isFont(item.MediaType, item.Href):
+			data, err := e.readFile(name)
+			if err != nil {
+				return nil, err
+			}
+			if coversCJK(data) {
+				hints.CJKFonts = append(hints.CJKFonts, item.Href)
+			}
+		}
+	}
+
+	switch direction := e.opf.Spine.PageProgression; {
+	case direction == "ltr" || direction == "rtl":
+		hints.PageProgression = direction
+	case hints.RightToLeft || hints.CJK && hints.VerticalWriting:
+		hints.PageProgression = "rtl"
+	default:
+		hints.PageProgression = "ltr"
+	}
+	return &hints, nil
+}
+
+// renditionLayout returns the rendition:layout property of the book
+func (e Epub) renditionLayout() string {
+	for _, meta := range e.metadata["meta"] {
+		if meta.Attr["property"] == "rendition:layout" && meta.Attr["refines"] == "" {
+			return strings.TrimSpace(meta.Content)
+		}
+	}
+	return ""
+}
+
+// coversCJK returns whether the TrueType, OpenType or WOFF font declares
+// support for CJK scripts on its OS/2 table
+func coversCJK(data []byte) bool {
+	os2 := fontTable(data, "OS/2")
+	if len(os2) < 58 {
+		return false
+	}
+	// Hiragana, Katakana, Hangul syllables and CJK unified ideographs
+	const cjkRanges = 1<<(49-32) | 1<<(50-32) | 1<<(56-32) | 1<<(59-32)
+	if binary.BigEndian.Uint32(os2[46:])&cjkRanges != 0 {
+		return true
+	}
+	// Japanese, simplified Chinese, Korean and traditional Chinese code
+	// pages, from the version 1 of the table
+	const cjkCodePages = 1<<17 | 1<<18 | 1<<19 | 1<<20 | 1<<21
+	return binary.BigEndian.Uint16(os2) >= 1 && len(os2) >= 82 &&
+		binary.BigEndian.Uint32(os2[78:])&cjkCodePages != 0
+}
+
+// fontTable returns the table tag of the font, or nil if it is not found
+func fontTable(data []byte, tag string) []byte {
+	if bytes.HasPrefix(data, []byte("wOFF")) {
+		if len(data) < 44 {
+			return nil
+		}
+		numTables := int(binary.BigEndian.Uint16(data[12:]))
+		for i := 0; i < numTables; i++ {
+			entry := 44 + i*20
+			if entry+20 > len(data) {
+				return nil
+			}
+			if string(data[entry:entry+4]) != tag {
+				continue
+			}
+			offset := int(binary.BigEndian.Uint32(data[entry+4:]))
+			compLength := int(binary.BigEndian.Uint32(data[entry+8:]))
+			origLength := int(binary.BigEndian.Uint32(data[entry+12:]))
+			if offset < 0 || compLength < 0 || offset+compLength > len(data) {
+				return nil
+			}
+			table := data[offset : offset+compLength]
+			if compLength >= origLength {
+				return table
+			}
+			r, err := zlib.NewReader(bytes.NewReader(table))
+			if err != nil {
+				return nil
+			}
+			defer r.Close()
+			table, _ = ioutil.ReadAll(io.LimitReader(r, int64(origLength)))
+			return table
+		}
+		return nil
+	}
+
+	if len(data) < 12 {
+		return nil
+	}
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < numTables; i++ {
+		entry := 12 + i*16
+		if entry+16 > len(data) {
+			return nil
+		}
+		if string(data[entry:entry+4]) != tag {
+			continue
+		}
+		offset := int(binary.BigEndian.Uint32(data[entry+8:]))
+		length := int(binary.BigEndian.Uint32(data[entry+12:]))
+		if offset < 0 || length < 0 || offset+length > len(data) {
+			return nil
+		}
+		return data[offset : offset+length]
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+)
+
+// testFont returns a font with only an OS/2 table declaring the code pages
+func testFont(codePages uint32, woff bool) []byte {
+	os2 := make([]byte, 86)
+	binary.BigEndian.PutUint16(os2, 1)
+	binary.BigEndian.PutUint32(os2[78:], codePages)
+
+	var font bytes.Buffer
+	if !woff {
+		binary.Write(&font, binary.BigEndian, []uint32{0x00010000, 1 << 16, 0})
+		font.WriteString("OS/2")
+		binary.Write(&font, binary.BigEndian, []uint32{0, 28, uint32(len(os2))})
+		font.Write(os2)
+		return font.Bytes()
+	}
+
+	var compressed bytes.Buffer
+	w := zlib.NewWriter(&compressed)
+	w.Write(os2)
+	w.Close()
+	font.WriteString("wOFF")
+	binary.Write(&font, binary.BigEndian, []uint32{0x00010000, 0})
+	binary.Write(&font, binary.BigEndian, []uint16{1, 0})
+	font.Write(make([]byte, 28))
+	font.WriteString("OS/2")
+	binary.Write(&font, binary.BigEndian, []uint32{64, uint32(compressed.Len()), uint32(len(os2)), 0})
+	font.Write(compressed.Bytes())
+	return font.Bytes()
+}
+
+func TestCoversCJK(t *testing.T) {
+	if !coversCJK(testFont(1<<17, false)) || !coversCJK(testFont(1<<20, true)) {
+		t.Errorf("coversCJK() didn't detect a CJK font")
+	}
+	if coversCJK(testFont(1, false)) || coversCJK(testFont(1, true)) || coversCJK([]byte("font")) {
+		t.Errorf("coversCJK() detected a latin font")
+	}
+}
+
+func TestLayoutHints(t *testing.T) {
+	f := buildEpub(t, epub3OPF, map[string]string{
+		"nav.xhtml":      epub3Nav,
+		"text/ch1.xhtml": `<html><body><p><ruby>漢<rt>かん</rt></ruby></p></body></html>`,
+		"text/ch2.xhtml": `<html><body><p>text</p></body></html>`,
+	})
+	hints, err := f.LayoutHints()
+	if err != nil {
+		t.Fatalf("LayoutHints() return an error: %v", err)
+	}
+	if hints.PageProgression != "ltr" || hints.CJK || hints.VerticalWriting || !hints.Ruby || hints.FixedLayout {
+		t.Errorf("LayoutHints() return: %+v", hints)
+	}
+
+	f.SetMetadata("language", []MdataElement{{Content: "ja"}})
+	f.opf.Spine.PageProgression = ""
+	f.AddResource("style.css", "text/css", bytes.NewReader([]byte("html { -epub-writing-mode: vertical-rl }")))
+	f.AddResource("fonts/mincho.otf", "font/otf", bytes.NewReader(testFont(1<<17, false)))
+	f.AddResource("fonts/serif.woff", "font/woff", bytes.NewReader(testFont(1, true)))
+	hints, err = f.LayoutHints()
+	if err != nil {
+		t.Fatalf("LayoutHints() return an error: %v", err)
+	}
+	if hints.PageProgression != "rtl" || !hints.CJK || !hints.VerticalWriting || hints.RightToLeft {
+		t.Errorf("LayoutHints() return: %+v", hints)
+	}
+	if len(hints.CJKFonts) != 1 || hints.CJKFonts[0] != "fonts/mincho.otf" {
+		t.Errorf("The CJK fonts are: %v", hints.CJKFonts)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "strings"
+
+// dcLegacyNamespace is the Dublin Core namespace of the OEB 1.x packages,
+// the predecessors of the OPF
+const dcLegacyNamespace = "http://purl.org/dc/elements/1.0/"
+
+// legacyMediaTypes are the media types of the OEB 1.x manifests and their
+// current equivalent
+var legacyMediaTypes = map[string]string{
+	"text/x-oeb1-document": "application/xhtml+xml",
+	"text/x-oeb-document":  "application/xhtml+xml",
+	"text/x-oeb1-css":      "text/css",
+	"text/x-oeb1-html":     "text/html",
+	"text/x-oeb1-package":  "application/oebps-package+xml",
+}
+
+// Tour is a sequence of reading stops of an OEB 1.x or EPUB 2 package, as
+// an alternative reading path of the book
+type Tour struct {
+	ID    string
+	Title string
+	Sites []TourSite
+}
+
+// TourSite is a stop of a Tour
+type TourSite struct {
+	Title string
+	Href  string
+}
+
+type xmlTour struct {
+	ID    string    `xml:"id,attr"`
+	Title string    `xml:"title,attr"`
+	Sites []xmlSite `xml:"site"`
+}
+type xmlSite struct {
+	Title string `xml:"title,attr"`
+	Href  string `xml:"href,attr"`
+}
+
+// Tours returns the tours of the package, most books don't have any
+func (e Epub) Tours() []Tour {
+	var tours []Tour
+	for _, t := range e.opf.Tours {
+		tour := Tour{ID: t.ID, Title: t.Title}
+		for _, s := range t.Sites {
+			tour.Sites = append(tour.Sites, TourSite(s))
+		}
+		tours = append(tours, tour)
+	}
+	return tours
+}
+
+// isMetadataWrapper returns whether the element of the OEB 1.x metadata
+// groups the Dublin Core or the extra metadata elements
+func isMetadataWrapper(local string) bool {
+	return local == "dc-metadata" || local == "x-metadata"
+}
+
+// legacyFieldName returns the name of the Dublin Core field of the
+// capitalized OEB 1.x elements (dc:Title, dc:Creator, ...)
+func legacyFieldName(space, local string) string {
+	if space != dcLegacyNamespace && space != dcNamespace {
+		return local
+	}
+	return strings.ToLower(local)
+}
+
+// upgradeMediaTypes replaces the OEB 1.x media types of the manifest
+func (opf *xmlOPF) upgradeMediaTypes() {
+	for i, item := range opf.Manifest {
+		if mediaType, ok := legacyMediaTypes[strings.ToLower(item.MediaType)]; ok {
+			opf.Manifest[i].MediaType = mediaType
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"archive/zip"
+	"bytes"
+)
+
+const oebPackage = `<?xml version="1.0"?>
+<!DOCTYPE package PUBLIC "+//ISBN 0-9673008-1-9//DTD OEB 1.0.1 Package//EN" "http://openebook.org/dtds/oeb-1.0.1/oebpkg101.dtd">
+<package unique-identifier="bookid">
+  <metadata>
+    <dc-metadata xmlns:dc="http://purl.org/dc/elements/1.0/" xmlns:oebpackage="http://openebook.org/namespaces/oeb-package/1.0/">
+      <dc:Title>The Old Book</dc:Title>
+      <dc:Creator role="aut" file-as="Writer, Some">Some Writer</dc:Creator>
+      <dc:Identifier id="bookid" scheme="ISBN">0-306-40615-2</dc:Identifier>
+      <dc:Language>en</dc:Language>
+    </dc-metadata>
+    <x-metadata>
+      <meta name="Converter" content="OldTool 1.0"/>
+    </x-metadata>
+  </metadata>
+  <manifest>
+    <item id="ch1" href="ch1.html" media-type="text/x-oeb1-document"/>
+    <item id="css" href="style.css" media-type="text/x-oeb1-css"/>
+  </manifest>
+  <spine>
+    <itemref idref="ch1"/>
+  </spine>
+  <tours>
+    <tour id="t1" title="Highlights">
+      <site title="Chapter 1" href="ch1.html#start"/>
+    </tour>
+  </tours>
+</package>`
+
+func TestOEBPackage(t *testing.T) {
+	var buff bytes.Buffer
+	w := zip.NewWriter(&buff)
+	f, _ := w.Create("book.opf")
+	f.Write([]byte(oebPackage))
+	f, _ = w.Create("ch1.html")
+	f.Write([]byte("<html><body><p>Once upon a time</p></body></html>"))
+	w.Close()
+
+	book, err := Load(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+	if err != nil {
+		t.Fatalf("Load() return an error: %v", err)
+	}
+	if title, _ := book.Metadata("title"); len(title) != 1 || title[0] != "The Old Book" {
+		t.Errorf("Metadata(title) return: %v", title)
+	}
+	creators, _ := book.MetadataElement("creator")
+	if len(creators) != 1 || creators[0].Content != "Some Writer" || creators[0].Attr["file-as"] != "Writer, Some" {
+		t.Errorf("MetadataElement(creator) return: %v", creators)
+	}
+	if id, _ := book.Metadata("identifier"); len(id) != 1 || id[0] != "0-306-40615-2" {
+		t.Errorf("Metadata(identifier) return: %v", id)
+	}
+	meta, _ := book.MetadataElement("meta")
+	if len(meta) != 1 || meta[0].Attr["name"] != "Converter" || meta[0].Content != "OldTool 1.0" {
+		t.Errorf("MetadataElement(meta) return: %v", meta)
+	}
+	if mediaType := book.opf.mediaType("ch1.html"); mediaType != "application/xhtml+xml" {
+		t.Errorf("The media type of the document is: %v", mediaType)
+	}
+
+	tours := book.Tours()
+	if len(tours) != 1 || tours[0].Title != "Highlights" || len(tours[0].Sites) != 1 || tours[0].Sites[0].Href != "ch1.html#start" {
+		t.Errorf("Tours() return: %v", tours)
+	}
+
+	spine, err := book.Spine()
+	if err != nil {
+		t.Fatalf("Spine() return an error: %v", err)
+	}
+	if spine.URL() != "ch1.html" {
+		t.Errorf("Spine() URL: %v", spine.URL())
+	}
+}
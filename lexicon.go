@@ -0,0 +1,99 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"strings"
+)
+
+const plsMediaType = "application/pls+xml"
+
+// Lexicon is a PLS pronunciation lexicon of the epub
+type Lexicon struct {
+	// Href is the path of the lexicon, as used by OpenFile
+	Href     string   `xml:"-"`
+	Lang     string   `xml:"lang,attr"`
+	Alphabet string   `xml:"alphabet,attr"`
+	Lexemes  []Lexeme `xml:"lexeme"`
+}
+
+// Lexeme is the pronunciation of one or more graphemes (spellings of a word)
+type Lexeme struct {
+	Graphemes []string  `xml:"grapheme"`
+	Phonemes  []Phoneme `xml:"phoneme"`
+	// Aliases are texts to be read instead of the graphemes
+	Aliases []string `xml:"alias"`
+}
+
+// Phoneme is a pronunciation on a phonetic alphabet
+type Phoneme struct {
+	// Alphabet is empty if it is the one of the lexicon
+	Alphabet string `xml:"alphabet,attr"`
+	Text     string `xml:",chardata"`
+}
+
+// Lexicons returns the PLS lexicons listed on the manifest
+func (e Epub) Lexicons() ([]Lexicon, error) {
+	var lexicons []Lexicon
+	for _, item := range e.opf.Manifest {
+		if item.MediaType != plsMediaType {
+			continue
+		}
+		f, err := e.open(e.rootPath + item.Href)
+		if err != nil {
+			return nil, err
+		}
+		var lexicon Lexicon
+		err = decodeXML(f, &lexicon)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		lexicon.Href = item.Href
+		for i := range lexicon.Lexemes {
+			lexeme := &lexicon.Lexemes[i]
+			for j := range lexeme.Phonemes {
+				lexeme.Phonemes[j].Text = strings.TrimSpace(lexeme.Phonemes[j].Text)
+			}
+			for j := range lexeme.Graphemes {
+				lexeme.Graphemes[j] = strings.TrimSpace(lexeme.Graphemes[j])
+			}
+		}
+		lexicons = append(lexicons, lexicon)
+	}
+	return lexicons, nil
+}
+
+// Pronunciations returns the lexemes for the language indexed by grapheme
+//
+// The lexicons without language apply to all of them. If a grapheme is on
+// several lexicons the first one in the manifest is used, as PLS processors
+// do.
+func (e Epub) Pronunciations(lang string) (map[string]Lexeme, error) {
+	lexicons, err := e.Lexicons()
+	if err != nil {
+		return nil, err
+	}
+
+	pronunciations := make(map[string]Lexeme)
+	for _, lexicon := range lexicons {
+		if lexicon.Lang != "" && baseLang(lexicon.Lang) != baseLang(lang) {
+			continue
+		}
+		for _, lexeme := range lexicon.Lexemes {
+			for i, phoneme := range lexeme.Phonemes {
+				if phoneme.Alphabet == "" {
+					lexeme.Phonemes[i].Alphabet = lexicon.Alphabet
+				}
+			}
+			for _, grapheme := range lexeme.Graphemes {
+				if _, ok := pronunciations[grapheme]; !ok {
+					pronunciations[grapheme] = lexeme
+				}
+			}
+		}
+	}
+	return pronunciations, nil
+}
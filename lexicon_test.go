@@ -0,0 +1,54 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+const lexiconPLS = `<?xml version="1.0" encoding="UTF-8"?>
+<lexicon version="1.0" xmlns="http://www.w3.org/2005/01/pronunciation-lexicon"
+      alphabet="ipa" xml:lang="en">
+  <lexeme>
+    <grapheme>Aileen</grapheme>
+    <phoneme>ˈeɪliːn</phoneme>
+  </lexeme>
+  <lexeme>
+    <grapheme>W3C</grapheme>
+    <alias>World Wide Web Consortium</alias>
+  </lexeme>
+</lexicon>`
+
+func TestPronunciations(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	if lexicons, err := f.Lexicons(); err != nil || len(lexicons) != 0 {
+		t.Errorf("Lexicons() return: %v, %v", lexicons, err)
+	}
+
+	f.stage(f.rootPath+"lexicon.pls", []byte(lexiconPLS))
+	f.opf.Manifest = append(f.opf.Manifest, manifest{ID: "pls", Href: "lexicon.pls", MediaType: plsMediaType})
+	lexicons, err := f.Lexicons()
+	if err != nil {
+		t.Fatalf("Lexicons() return an error: %v", err)
+	}
+	if len(lexicons) != 1 || lexicons[0].Lang != "en" || lexicons[0].Href != "lexicon.pls" || len(lexicons[0].Lexemes) != 2 {
+		t.Fatalf("Lexicons() return: %v", lexicons)
+	}
+
+	pronunciations, err := f.Pronunciations("en-US")
+	if err != nil {
+		t.Fatalf("Pronunciations() return an error: %v", err)
+	}
+	phonemes := pronunciations["Aileen"].Phonemes
+	if len(phonemes) != 1 || phonemes[0].Text != "ˈeɪliːn" || phonemes[0].Alphabet != "ipa" {
+		t.Errorf("Wrong pronunciation: %v", phonemes)
+	}
+	if aliases := pronunciations["W3C"].Aliases; len(aliases) != 1 || aliases[0] != "World Wide Web Consortium" {
+		t.Errorf("Wrong alias: %v", aliases)
+	}
+	if pronunciations, _ := f.Pronunciations("fr"); len(pronunciations) != 0 {
+		t.Errorf("Pronunciations(fr) return: %v", pronunciations)
+	}
+}
@@ -0,0 +1,367 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package library
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/meskio/epubgo"
+)
+
+const (
+	shingleSize = 3
+	// maxFingerprintDistance is the number of different bits of the content
+	// fingerprints of two copies of the same text
+	maxFingerprintDistance = 3
+	minNameSimilarity      = 0.85
+)
+
+// The duplicate signals found between two books
+const (
+	SignalIdentifier = "identifier"
+	SignalMetadata   = "title and author"
+	SignalContent    = "content"
+)
+
+// Candidate is a book of the library checked for duplicates
+type Candidate struct {
+	Path string
+	Book Book
+	// Fingerprint is the content fingerprint of the book, 0 if unknown
+	Fingerprint uint64
+}
+
+// NewCandidate reads the metadata and computes the content fingerprint of
+// the epub e, read from the file path
+func NewCandidate(path string, e *epubgo.Epub) (Candidate, error) {
+	fingerprint, err := Fingerprint(e)
+	if err != nil {
+		return Candidate{}, err
+	}
+	return Candidate{path, ReadBook(e), fingerprint}, nil
+}
+
+// DuplicateGroup is a set of books that are likely the same
+type DuplicateGroup struct {
+	Paths []string
+	// Confidence is between 0 and 1, the confidence of the weakest match
+	// that joins the group
+	Confidence float64
+	// Signals are the kinds of match found between the books of the group
+	Signals []string
+}
+
+// Fingerprint returns the simhash of the text of the book
+//
+// The words are normalized with epubgo.IndexTokens, so the fingerprints of
+// copies of the same text with different markup, typography or small edits
+// are only a few bits apart.
+func Fingerprint(e *epubgo.Epub) (uint64, error) {
+	spine, err := e.Spine()
+	if err != nil {
+		return 0, err
+	}
+	var (
+		weights [64]int
+		window  []string
+	)
+	for {
+		tokens, err := e.IndexTokens(spine.Index())
+		if err != nil {
+			return 0, err
+		}
+		for _, token := range tokens {
+			window = append(window, token.Term)
+			if len(window) > shingleSize {
+				window = window[1:]
+			}
+			if len(window) < shingleSize {
+				continue
+			}
+			h := fnv.New64a()
+			h.Write([]byte(strings.Join(window, " ")))
+			sum := h.Sum64()
+			for b := range weights {
+				if sum&(1<<uint(b)) != 0 {
+					weights[b]++
+				} else {
+					weights[b]--
+				}
+			}
+		}
+		if spine.Next() != nil {
+			break
+		}
+	}
+
+	var fingerprint uint64
+	for b, w := range weights {
+		if w > 0 {
+			fingerprint |= 1 << uint(b)
+		}
+	}
+	return fingerprint, nil
+}
+
+// FindDuplicates clusters the candidates that are likely the same book
+//
+// Two books match if they share an identifier (ISBN, UUID, ...), if their
+// normalized titles and authors are similar or if their content fingerprints
+// are close. The confidences of the signals found between two books are
+// combined, the pairs below minConfidence are ignored and the rest are
+// clustered transitively. The groups are sorted by confidence.
+func FindDuplicates(candidates []Candidate, minConfidence float64) []DuplicateGroup {
+	keys := make([]dupKeys, len(candidates))
+	for i, c := range candidates {
+		keys[i] = newDupKeys(c)
+	}
+
+	parent := make([]int, len(candidates))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	confidence := make(map[int]float64)
+	signals := make(map[int]map[string]bool)
+	for _, pair := range candidatePairs(keys) {
+		i, j := pair[0], pair[1]
+		conf, found := matchConfidence(candidates[i], candidates[j], keys[i], keys[j])
+		if conf < minConfidence || conf == 0 {
+			continue
+		}
+		ri, rj := find(i), find(j)
+		if signals[ri] == nil {
+			signals[ri] = make(map[string]bool)
+		}
+		for _, s := range found {
+			signals[ri][s] = true
+		}
+		if ri == rj {
+			continue
+		}
+
+		parent[rj] = ri
+		for _, root := range []int{ri, rj} {
+			if c, ok := confidence[root]; ok && c < conf {
+				conf = c
+			}
+		}
+		confidence[ri] = conf
+		for s := range signals[rj] {
+			signals[ri][s] = true
+		}
+		delete(confidence, rj)
+		delete(signals, rj)
+	}
+
+	members := make(map[int][]string)
+	for i, c := range candidates {
+		root := find(i)
+		if _, ok := confidence[root]; ok {
+			members[root] = append(members[root], c.Path)
+		}
+	}
+	var groups []DuplicateGroup
+	for root, paths := range members {
+		sort.Strings(paths)
+		group := DuplicateGroup{Paths: paths, Confidence: confidence[root]}
+		for _, s := range []string{SignalIdentifier, SignalMetadata, SignalContent} {
+			if signals[root][s] {
+				group.Signals = append(group.Signals, s)
+			}
+		}
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Confidence != groups[j].Confidence {
+			return groups[i].Confidence > groups[j].Confidence
+		}
+		return groups[i].Paths[0] < groups[j].Paths[0]
+	})
+	return groups
+}
+
+// dupKeys are the normalized values compared between the candidates
+type dupKeys struct {
+	identifiers []string
+	strong      map[string]bool
+	title       string
+	author      string
+	fingerprint uint64
+}
+
+func newDupKeys(c Candidate) dupKeys {
+	keys := dupKeys{strong: make(map[string]bool), fingerprint: c.Fingerprint}
+	for _, id := range c.Book.Identifiers {
+		key := id.Scheme + ":" + strings.ToLower(id.Value)
+		keys.identifiers = append(keys.identifiers, key)
+		keys.strong[key] = id.Scheme == epubgo.SchemeISBN && id.Valid
+	}
+	lang := c.Book.Language
+	keys.title = normalizeName(epubgo.SortTitle(c.Book.Title, lang))
+	var authors []string
+	for _, a := range c.Book.Authors {
+		if a.Role == "aut" {
+			authors = append(authors, normalizeName(a.Sort))
+		}
+	}
+	sort.Strings(authors)
+	keys.author = strings.Join(authors, " ")
+	return keys
+}
+
+// normalizeName returns the words of s normalized with epubgo.NormalizeTerm,
+// without punctuation
+func normalizeName(s string) string {
+	var words []string
+	for _, word := range strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && !unicode.Is(unicode.Mn, r)
+	}) {
+		if word = epubgo.NormalizeTerm(word, ""); word != "" {
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// candidatePairs returns the pairs of candidates that share a blocking key:
+// an identifier, the first word of the title or author or a band of the
+// fingerprint. Two fingerprints with at most maxFingerprintDistance
+// different bits have at least one of their maxFingerprintDistance+1 bands
+// equal.
+func candidatePairs(keys []dupKeys) [][2]int {
+	const bands = maxFingerprintDistance + 1
+	const bandSize = 64 / bands
+	buckets := make(map[string][]int)
+	for i, k := range keys {
+		if k.fingerprint != 0 {
+			for b := uint(0); b < bands; b++ {
+				band := k.fingerprint >> (b * bandSize) & (1<<bandSize - 1)
+				key := "band " + strconv.Itoa(int(b)) + " " + strconv.FormatUint(band, 16)
+				buckets[key] = append(buckets[key], i)
+			}
+		}
+		for _, id := range k.identifiers {
+			buckets["id "+id] = append(buckets["id "+id], i)
+		}
+		if title := strings.Fields(k.title); len(title) > 0 {
+			buckets["title "+title[0]] = append(buckets["title "+title[0]], i)
+		}
+		if author := strings.Fields(k.author); len(author) > 0 {
+			buckets["author "+author[0]] = append(buckets["author "+author[0]], i)
+		}
+	}
+
+	seen := make(map[[2]int]bool)
+	var pairs [][2]int
+	for _, bucket := range buckets {
+		for a := 0; a < len(bucket); a++ {
+			for b := a + 1; b < len(bucket); b++ {
+				pair := [2]int{bucket[a], bucket[b]}
+				if !seen[pair] {
+					seen[pair] = true
+					pairs = append(pairs, pair)
+				}
+			}
+		}
+	}
+	return pairs
+}
+
+// matchConfidence returns the combined confidence of the signals found
+// between the candidates a and b
+func matchConfidence(a, b Candidate, ka, kb dupKeys) (float64, []string) {
+	var (
+		confidences []float64
+		signals     []string
+	)
+	for _, id := range ka.identifiers {
+		for _, other := range kb.identifiers {
+			if id != other {
+				continue
+			}
+			conf := 0.8
+			if ka.strong[id] {
+				conf = 0.95
+			}
+			confidences = append(confidences, conf)
+			signals = append(signals, SignalIdentifier)
+			break
+		}
+		if len(signals) > 0 {
+			break
+		}
+	}
+
+	if ka.title != "" && ka.author != "" {
+		titleSim := similarity(ka.title, kb.title)
+		authorSim := similarity(ka.author, kb.author)
+		if titleSim >= minNameSimilarity && authorSim >= minNameSimilarity {
+			confidences = append(confidences, 0.8*titleSim*authorSim)
+			signals = append(signals, SignalMetadata)
+		}
+	}
+
+	if a.Fingerprint != 0 && b.Fingerprint != 0 {
+		distance := bits.OnesCount64(a.Fingerprint ^ b.Fingerprint)
+		if distance <= maxFingerprintDistance {
+			confidences = append(confidences, 0.95-0.05*float64(distance))
+			signals = append(signals, SignalContent)
+		}
+	}
+
+	miss := 1.0
+	for _, conf := range confidences {
+		miss *= 1 - conf
+	}
+	return 1 - miss, signals
+}
+
+// similarity returns 1 minus the normalized Levenshtein distance of the strings
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = prev[j-1] + cost
+			if prev[j]+1 < curr[j] {
+				curr[j] = prev[j] + 1
+			}
+			if curr[j-1]+1 < curr[j] {
+				curr[j] = curr[j-1] + 1
+			}
+		}
+		prev, curr = curr, prev
+	}
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	return 1 - float64(prev[len(rb)])/float64(longest)
+}
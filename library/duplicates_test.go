@@ -0,0 +1,60 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package library
+
+import "testing"
+
+import "github.com/meskio/epubgo"
+
+func TestFingerprint(t *testing.T) {
+	e, _ := epubgo.Open(bookPath)
+	defer e.Close()
+
+	candidate, err := NewCandidate(bookPath, e)
+	if err != nil {
+		t.Fatalf("NewCandidate() return an error: %v", err)
+	}
+	if candidate.Fingerprint == 0 {
+		t.Errorf("NewCandidate() return an empty fingerprint")
+	}
+	again, _ := Fingerprint(e)
+	if again != candidate.Fingerprint {
+		t.Errorf("Fingerprint() is not stable: %x %x", again, candidate.Fingerprint)
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	isbn := epubgo.ParseIdentifier("9780306406157", "ISBN")
+	tolkien := []Author{{"J. R. R. Tolkien", "Tolkien, J. R. R.", "aut"}}
+	candidates := []Candidate{
+		{Path: "a.epub", Book: Book{Title: "The Hobbit", Authors: tolkien, Identifiers: []epubgo.Identifier{isbn}}},
+		{Path: "b.epub", Book: Book{Title: "Hobbit", Authors: tolkien, Identifiers: []epubgo.Identifier{isbn}}},
+		{Path: "c.epub", Book: Book{Title: "The Hobit", Authors: []Author{{"JRR Tolkien", "Tolkien, J.R.R.", "aut"}}}},
+		{Path: "d.epub", Book: Book{Title: "Silmarillion", Authors: tolkien}, Fingerprint: 0xf0f0f0f0f0f0f0f0},
+		{Path: "e.epub", Book: Book{Title: "Untitled"}, Fingerprint: 0xf0f0f0f0f0f0f0f1},
+		{Path: "f.epub", Book: Book{Title: "Dune", Authors: []Author{{"Frank Herbert", "Herbert, Frank", "aut"}}}},
+	}
+
+	groups := FindDuplicates(candidates, 0.5)
+	if len(groups) != 2 {
+		t.Fatalf("FindDuplicates() return %v groups: %v", len(groups), groups)
+	}
+	if len(groups[0].Paths) != 2 || groups[0].Paths[0] != "d.epub" || groups[0].Signals[0] != SignalContent {
+		t.Errorf("FindDuplicates()[0] return: %v", groups[0])
+	}
+	if len(groups[1].Paths) != 3 || groups[1].Paths[2] != "c.epub" {
+		t.Errorf("FindDuplicates()[1] return: %v", groups[1])
+	}
+	if len(groups[1].Signals) != 2 || groups[1].Signals[0] != SignalIdentifier {
+		t.Errorf("FindDuplicates()[1] signals: %v", groups[1].Signals)
+	}
+	if groups[0].Confidence < groups[1].Confidence {
+		t.Errorf("FindDuplicates() groups are not sorted: %v", groups)
+	}
+
+	if groups := FindDuplicates(candidates, 0.995); len(groups) != 0 {
+		t.Errorf("FindDuplicates() with high confidence return: %v", groups)
+	}
+}
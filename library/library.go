@@ -0,0 +1,379 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+/*
+Package library catalogs collections of epubs on a SQL database.
+
+The metadata of each book is stored on a normalized schema: books, authors,
+identifiers, subjects and the files of the books on disk. Building a catalog
+over a directory is:
+
+	db, _ := sql.Open("sqlite3", "library.db")
+	library.CreateSchema(db)
+	library.IngestDir(db, "/home/user/books")
+
+The schema and the queries are written for SQLite, they use ? placeholders
+and LastInsertId. The SQL driver is not imported, the caller chooses it.
+
+The package also has the other pieces of a library manager: a Watcher of the
+changes of a directory tree, the detection of duplicate books and a Renamer
+to organize the files from their metadata.
+*/
+package library
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/meskio/epubgo"
+)
+
+// Schema are the statements that create the tables of the catalog
+const Schema = `
+CREATE TABLE IF NOT EXISTS books (
+	id INTEGER PRIMARY KEY,
+	title TEXT NOT NULL,
+	title_sort TEXT NOT NULL,
+	subtitle TEXT NOT NULL,
+	author_sort TEXT NOT NULL,
+	publisher TEXT NOT NULL,
+	date TEXT NOT NULL,
+	language TEXT NOT NULL,
+	description TEXT NOT NULL,
+	series TEXT NOT NULL,
+	series_index TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS authors (
+	id INTEGER PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	sort TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS book_authors (
+	book_id INTEGER NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+	author_id INTEGER NOT NULL REFERENCES authors(id),
+	role TEXT NOT NULL,
+	position INTEGER NOT NULL,
+	PRIMARY KEY (book_id, author_id, role)
+);
+CREATE TABLE IF NOT EXISTS identifiers (
+	book_id INTEGER NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+	scheme TEXT NOT NULL,
+	value TEXT NOT NULL,
+	PRIMARY KEY (book_id, scheme, value)
+);
+CREATE INDEX IF NOT EXISTS identifiers_value ON identifiers(scheme, value);
+CREATE TABLE IF NOT EXISTS subjects (
+	id INTEGER PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE
+);
+CREATE TABLE IF NOT EXISTS book_subjects (
+	book_id INTEGER NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+	subject_id INTEGER NOT NULL REFERENCES subjects(id),
+	PRIMARY KEY (book_id, subject_id)
+);
+CREATE TABLE IF NOT EXISTS files (
+	id INTEGER PRIMARY KEY,
+	book_id INTEGER NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+	path TEXT NOT NULL UNIQUE,
+	size INTEGER NOT NULL,
+	modified INTEGER NOT NULL
+);
+`
+
+// Author is a contributor of a book
+type Author struct {
+	Name string
+	Sort string
+	// Role is the MARC relator code of the contributor
+	Role string
+}
+
+// Book is the metadata of an epub as stored on the catalog
+type Book struct {
+	Title       string
+	TitleSort   string
+	Subtitle    string
+	AuthorSort  string
+	Authors     []Author
+	Publisher   string
+	Date        string
+	Language    string
+	Description string
+	Series      string
+	SeriesIndex string
+	Identifiers []epubgo.Identifier
+	Subjects    []string
+}
+
+// ReadBook extracts the metadata of the catalog from the epub
+func ReadBook(e *epubgo.Epub) Book {
+	book := Book{
+		TitleSort:   e.TitleSort(),
+		AuthorSort:  e.AuthorSort(),
+		Publisher:   first(e, "publisher"),
+		Date:        first(e, "date"),
+		Language:    first(e, "language"),
+		Description: e.Description(),
+	}
+	for _, t := range e.Titles() {
+		switch {
+		case (t.Type == "" || t.Type == "main") && book.Title == "":
+			book.Title = t.Content
+		case t.Type == "subtitle" && book.Subtitle == "":
+			book.Subtitle = t.Content
+		}
+	}
+	for _, c := range e.Contributors("") {
+		sort := c.FileAs
+		if sort == "" {
+			sort = epubgo.SortName(c.Name, book.Language)
+		}
+		book.Authors = append(book.Authors, Author{c.Name, sort, c.Role})
+	}
+	if collections := e.Collections(); len(collections) > 0 {
+		book.Series = collections[0].Name
+		book.SeriesIndex = collections[0].Position
+	}
+
+	elems, _ := e.MetadataElement("identifier")
+	for _, elem := range elems {
+		id := epubgo.ParseIdentifier(elem.Content, elem.Attr["scheme"])
+		if id.Value != "" {
+			book.Identifiers = append(book.Identifiers, id)
+		}
+	}
+	subjects, _ := e.Metadata("subject")
+	for _, subject := range subjects {
+		if subject = strings.TrimSpace(subject); subject != "" {
+			book.Subjects = append(book.Subjects, subject)
+		}
+	}
+	return book
+}
+
+func first(e *epubgo.Epub, field string) string {
+	values, err := e.Metadata(field)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(values[0])
+}
+
+// CreateSchema creates the tables of the catalog on db if they don't exist
+func CreateSchema(db *sql.DB) error {
+	for _, stmt := range strings.Split(Schema, ";") {
+		if strings.TrimSpace(stmt) == "" {
+			continue
+		}
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Ingest stores the metadata of the epub e, read from the file path, on the
+// catalog and returns the id of its book
+//
+// If the file is already on the catalog the metadata of its book is replaced.
+func Ingest(db *sql.DB, path string, e *epubgo.Epub) (int64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	book := ReadBook(e)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRow(`SELECT book_id FROM files WHERE path = ?`, path).Scan(&id)
+	switch {
+	case err == sql.ErrNoRows:
+		id, err = insertBook(tx, book)
+		if err != nil {
+			return 0, err
+		}
+		_, err = tx.Exec(`INSERT INTO files (book_id, path, size, modified) VALUES (?, ?, ?, ?)`,
+			id, path, info.Size(), info.ModTime().Unix())
+	case err == nil:
+		err = updateBook(tx, id, book)
+		if err != nil {
+			return 0, err
+		}
+		_, err = tx.Exec(`UPDATE files SET size = ?, modified = ? WHERE path = ?`,
+			info.Size(), info.ModTime().Unix(), path)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err := insertRelations(tx, id, book); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// IngestDir ingests all the files with the .epub extension on the directory
+// tree of dir
+//
+// The files not modified since they were ingested are skipped. A file that
+// fails doesn't stop the ingestion, the errors are returned together once
+// all the files were processed.
+func IngestDir(db *sql.DB, dir string) error {
+	var errs []error
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		if info.IsDir() || !strings.EqualFold(filepath.Ext(path), ".epub") {
+			return nil
+		}
+		var modified int64
+		err = db.QueryRow(`SELECT modified FROM files WHERE path = ?`, path).Scan(&modified)
+		if err == nil && modified == info.ModTime().Unix() {
+			return nil
+		}
+
+		e, err := epubgo.Open(path)
+		if err != nil {
+			errs = append(errs, errors.New(path+": "+err.Error()))
+			return nil
+		}
+		defer e.Close()
+		if _, err := Ingest(db, path, e); err != nil {
+			errs = append(errs, errors.New(path+": "+err.Error()))
+		}
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Remove deletes the file path from the catalog, and its book if it has no
+// other files
+func Remove(db *sql.DB, path string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRow(`SELECT book_id FROM files WHERE path = ?`, path).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM files WHERE path = ?`, path); err != nil {
+		return err
+	}
+	var files int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM files WHERE book_id = ?`, id).Scan(&files); err != nil {
+		return err
+	}
+	if files == 0 {
+		if err := deleteRelations(tx, id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM books WHERE id = ?`, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func bookValues(book Book) []interface{} {
+	return []interface{}{book.Title, book.TitleSort, book.Subtitle, book.AuthorSort,
+		book.Publisher, book.Date, book.Language, book.Description, book.Series,
+		book.SeriesIndex}
+}
+
+func insertBook(tx *sql.Tx, book Book) (int64, error) {
+	res, err := tx.Exec(`INSERT INTO books (title, title_sort, subtitle, author_sort,
+		publisher, date, language, description, series, series_index)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, bookValues(book)...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func updateBook(tx *sql.Tx, id int64, book Book) error {
+	_, err := tx.Exec(`UPDATE books SET title = ?, title_sort = ?, subtitle = ?,
+		author_sort = ?, publisher = ?, date = ?, language = ?, description = ?,
+		series = ?, series_index = ? WHERE id = ?`, append(bookValues(book), id)...)
+	if err != nil {
+		return err
+	}
+	return deleteRelations(tx, id)
+}
+
+func deleteRelations(tx *sql.Tx, id int64) error {
+	for _, table := range []string{"book_authors", "identifiers", "book_subjects"} {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE book_id = ?`, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertRelations(tx *sql.Tx, id int64, book Book) error {
+	for i, author := range book.Authors {
+		authorID, err := upsert(tx, `authors`, `INSERT INTO authors (name, sort) VALUES (?, ?)`,
+			author.Name, author.Sort)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT OR IGNORE INTO book_authors (book_id, author_id, role, position)
+			VALUES (?, ?, ?, ?)`, id, authorID, author.Role, i)
+		if err != nil {
+			return err
+		}
+	}
+	for _, identifier := range book.Identifiers {
+		_, err := tx.Exec(`INSERT OR IGNORE INTO identifiers (book_id, scheme, value) VALUES (?, ?, ?)`,
+			id, identifier.Scheme, identifier.Value)
+		if err != nil {
+			return err
+		}
+	}
+	for _, subject := range book.Subjects {
+		subjectID, err := upsert(tx, `subjects`, `INSERT INTO subjects (name) VALUES (?)`, subject)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT OR IGNORE INTO book_subjects (book_id, subject_id) VALUES (?, ?)`,
+			id, subjectID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// upsert returns the id of the row of table with the name of args[0],
+// inserting it if it doesn't exist
+func upsert(tx *sql.Tx, table, insert string, args ...interface{}) (int64, error) {
+	var id int64
+	err := tx.QueryRow(`SELECT id FROM `+table+` WHERE name = ?`, args[0]).Scan(&id)
+	if err != sql.ErrNoRows {
+		return id, err
+	}
+	res, err := tx.Exec(insert, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
@@ -0,0 +1,200 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package library
+
+import "testing"
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/meskio/epubgo"
+	"github.com/meskio/epubgo/internal/sqltest"
+)
+
+const bookPath = "../testdata/a_dogs_tale.epub"
+
+func TestReadBook(t *testing.T) {
+	e, err := epubgo.Open(bookPath)
+	if err != nil {
+		t.Fatalf("Open(%v) return an error: %v", bookPath, err)
+	}
+	defer e.Close()
+
+	book := ReadBook(e)
+	if book.Title != "A Dog's Tale" {
+		t.Errorf("ReadBook() title: %v", book.Title)
+	}
+	if book.Date != "2004-06-01" || book.Language != "en" {
+		t.Errorf("ReadBook() date and language: %v %v", book.Date, book.Language)
+	}
+	expected := Author{"Mark Twain", "Twain, Mark", "aut"}
+	if len(book.Authors) != 1 || book.Authors[0] != expected {
+		t.Errorf("ReadBook() authors: %v when was expected: %v", book.Authors, expected)
+	}
+	if book.AuthorSort != "Twain, Mark" {
+		t.Errorf("ReadBook() author sort: %v", book.AuthorSort)
+	}
+	if len(book.Identifiers) == 0 {
+		t.Errorf("ReadBook() return no identifiers")
+	}
+}
+
+func newTestCatalog(t *testing.T) (*sql.DB, *sqltest.DB) {
+	db, store, err := sqltest.Open()
+	if err != nil {
+		t.Fatalf("sqltest.Open() return an error: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	for i := 0; i < 2; i++ {
+		if err := CreateSchema(db); err != nil {
+			t.Fatalf("CreateSchema() return an error: %v", err)
+		}
+	}
+	return db, store
+}
+
+func ingest(t *testing.T, db *sql.DB, path string) int64 {
+	e, err := epubgo.Open(path)
+	if err != nil {
+		t.Fatalf("Open(%v) return an error: %v", path, err)
+	}
+	defer e.Close()
+	id, err := Ingest(db, path, e)
+	if err != nil {
+		t.Fatalf("Ingest(%v) return an error: %v", path, err)
+	}
+	return id
+}
+
+func copyBook(t *testing.T, path string) {
+	data, err := ioutil.ReadFile(bookPath)
+	if err != nil {
+		t.Fatalf("ReadFile(%v) return an error: %v", bookPath, err)
+	}
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("WriteFile(%v) return an error: %v", path, err)
+	}
+}
+
+// checkRows checks the number of rows of each table
+func checkRows(t *testing.T, store *sqltest.DB, counts map[string]int) {
+	for table, count := range counts {
+		if rows := store.Rows(table); len(rows) != count {
+			t.Errorf("The table %v has %v rows when were expected %v: %v", table, len(rows), count, rows)
+		}
+	}
+}
+
+func TestCreateSchema(t *testing.T) {
+	_, store := newTestCatalog(t)
+	for _, table := range []string{"books", "authors", "book_authors", "identifiers", "subjects", "book_subjects", "files"} {
+		if rows := store.Rows(table); rows == nil || len(rows) != 0 {
+			t.Errorf("CreateSchema() table %v: %v", table, rows)
+		}
+	}
+}
+
+func TestIngest(t *testing.T) {
+	db, store := newTestCatalog(t)
+	path := filepath.Join(t.TempDir(), "tale.epub")
+	copyBook(t, path)
+
+	id := ingest(t, db, path)
+	checkRows(t, store, map[string]int{"books": 1, "authors": 1, "book_authors": 1,
+		"identifiers": 1, "subjects": 1, "book_subjects": 1, "files": 1})
+	book := store.Rows("books")[0]
+	if book["id"] != id || book["title"] != "A Dog's Tale" || book["language"] != "en" {
+		t.Errorf("Ingest() book: %v", book)
+	}
+	author := store.Rows("authors")[0]
+	if author["name"] != "Mark Twain" || author["sort"] != "Twain, Mark" {
+		t.Errorf("Ingest() author: %v", author)
+	}
+	bookAuthor := store.Rows("book_authors")[0]
+	if bookAuthor["book_id"] != id || bookAuthor["author_id"] != author["id"] || bookAuthor["role"] != "aut" {
+		t.Errorf("Ingest() book author: %v", bookAuthor)
+	}
+	identifier := store.Rows("identifiers")[0]
+	if identifier["book_id"] != id || identifier["value"] != "http://www.gutenberg.org/ebooks/3174" {
+		t.Errorf("Ingest() identifier: %v", identifier)
+	}
+	subject := store.Rows("subjects")[0]
+	bookSubject := store.Rows("book_subjects")[0]
+	if subject["name"] != "Dogs -- Fiction" || bookSubject["book_id"] != id || bookSubject["subject_id"] != subject["id"] {
+		t.Errorf("Ingest() subject: %v %v", subject, bookSubject)
+	}
+	info, _ := os.Stat(path)
+	file := store.Rows("files")[0]
+	if file["book_id"] != id || file["path"] != path || file["size"] != info.Size() {
+		t.Errorf("Ingest() file: %v", file)
+	}
+
+	if newID := ingest(t, db, path); newID != id {
+		t.Errorf("Ingest() again return the id %v when was %v", newID, id)
+	}
+	checkRows(t, store, map[string]int{"books": 1, "authors": 1, "book_authors": 1,
+		"identifiers": 1, "subjects": 1, "book_subjects": 1, "files": 1})
+
+	other := filepath.Join(filepath.Dir(path), "other.epub")
+	copyBook(t, other)
+	if otherID := ingest(t, db, other); otherID == id {
+		t.Errorf("Ingest() of another file return the same id %v", id)
+	}
+	checkRows(t, store, map[string]int{"books": 2, "authors": 1, "book_authors": 2,
+		"identifiers": 2, "subjects": 1, "book_subjects": 2, "files": 2})
+}
+
+func TestIngestDir(t *testing.T) {
+	db, store := newTestCatalog(t)
+	dir := t.TempDir()
+	copyBook(t, filepath.Join(dir, "tale.epub"))
+	copyBook(t, filepath.Join(dir, "mark twain", "tale.EPUB"))
+	ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte("notes"), 0644)
+
+	if err := IngestDir(db, dir); err != nil {
+		t.Fatalf("IngestDir() return an error: %v", err)
+	}
+	checkRows(t, store, map[string]int{"books": 2, "files": 2})
+
+	ioutil.WriteFile(filepath.Join(dir, "broken.epub"), []byte("broken"), 0644)
+	if err := IngestDir(db, dir); err == nil {
+		t.Errorf("IngestDir() with a broken epub didn't return an error")
+	}
+	checkRows(t, store, map[string]int{"books": 2, "authors": 1, "files": 2})
+}
+
+func TestRemove(t *testing.T) {
+	db, store := newTestCatalog(t)
+	dir := t.TempDir()
+	path, other := filepath.Join(dir, "tale.epub"), filepath.Join(dir, "other.epub")
+	copyBook(t, path)
+	copyBook(t, other)
+	ingest(t, db, path)
+	otherID := ingest(t, db, other)
+
+	if err := Remove(db, path); err != nil {
+		t.Fatalf("Remove() return an error: %v", err)
+	}
+	checkRows(t, store, map[string]int{"books": 1, "authors": 1, "book_authors": 1,
+		"identifiers": 1, "subjects": 1, "book_subjects": 1, "files": 1})
+	for _, table := range []string{"book_authors", "identifiers", "book_subjects", "files"} {
+		if rows := store.Rows(table); rows[0]["book_id"] != otherID {
+			t.Errorf("Remove() left on %v the rows: %v", table, rows)
+		}
+	}
+	if err := Remove(db, path); err != nil {
+		t.Errorf("Remove() of a file not on the catalog return an error: %v", err)
+	}
+
+	if err := Remove(db, other); err != nil {
+		t.Fatalf("Remove() return an error: %v", err)
+	}
+	checkRows(t, store, map[string]int{"books": 0, "book_authors": 0,
+		"identifiers": 0, "book_subjects": 0, "files": 0})
+}
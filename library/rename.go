@@ -0,0 +1,233 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package library
+
+import (
+	"errors"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const maxSegmentLength = 200
+
+var placeholderRegexp = regexp.MustCompile(`\{([a-z_]*)\}`)
+
+// placeholders are the values of a Book available on the templates
+var placeholders = map[string]func(b Book) string{
+	"title":      func(b Book) string { return b.Title },
+	"title_sort": func(b Book) string { return b.TitleSort },
+	"subtitle":   func(b Book) string { return b.Subtitle },
+	"author": func(b Book) string {
+		if authors := b.authors(); len(authors) > 0 {
+			return authors[0].Name
+		}
+		return ""
+	},
+	"author_sort": func(b Book) string {
+		if authors := b.authors(); len(authors) > 0 {
+			return authors[0].Sort
+		}
+		return ""
+	},
+	"authors": func(b Book) string {
+		var names []string
+		for _, a := range b.authors() {
+			names = append(names, a.Name)
+		}
+		return strings.Join(names, " & ")
+	},
+	"series":       func(b Book) string { return b.Series },
+	"series_index": func(b Book) string { return seriesIndex(b.SeriesIndex) },
+	"publisher":    func(b Book) string { return b.Publisher },
+	"year":         func(b Book) string { return yearRegexp.FindString(b.Date) },
+	"language":     func(b Book) string { return b.Language },
+	"isbn": func(b Book) string {
+		for _, id := range b.Identifiers {
+			if id.Scheme == "ISBN" && id.Valid {
+				return id.Value
+			}
+		}
+		return ""
+	},
+}
+
+var (
+	yearRegexp        = regexp.MustCompile(`^\d{4}`)
+	separatorsRegexp  = regexp.MustCompile(`\s+`)
+	unsafeFileChars   = strings.NewReplacer("/", "-", `\`, "-", ":", " -", "*", "", "?", "", `"`, "'", "<", "", ">", "", "|", "-")
+	reservedFileNames = map[string]bool{
+		"con": true, "prn": true, "aux": true, "nul": true,
+		"com1": true, "com2": true, "com3": true, "com4": true, "com5": true,
+		"com6": true, "com7": true, "com8": true, "com9": true,
+		"lpt1": true, "lpt2": true, "lpt3": true, "lpt4": true, "lpt5": true,
+		"lpt6": true, "lpt7": true, "lpt8": true, "lpt9": true,
+	}
+)
+
+// transliterations are the ASCII forms of the lowercase letters that don't
+// decompose into a base letter and marks
+var transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'ł': "l", 'đ': "d", 'ð': "d",
+	'þ': "th", 'ı': "i", 'ĸ': "k", 'ŀ': "l", 'ŋ': "ng",
+	'‘': "'", '’': "'", '“': `"`, '”': `"`, '–': "-", '—': "-", '…': "...",
+	'«': `"`, '»': `"`, '¿': "", '¡': "",
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "i", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "",
+	'ы': "y", 'ь': "", 'э': "e", 'ю': "iu", 'я': "ia",
+	'α': "a", 'β': "b", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "e",
+	'θ': "th", 'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x",
+	'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y",
+	'φ': "ph", 'χ': "ch", 'ψ': "ps", 'ω': "o",
+}
+
+// Renamer builds the paths of the books of a library from a template
+type Renamer struct {
+	template string
+	// ASCII transliterates the non-ASCII characters of the paths
+	ASCII bool
+}
+
+// NewRenamer parses a template like
+// "{author_sort}/{series}/{series_index} - {title}.epub"
+//
+// The placeholders are title, title_sort, subtitle, author, author_sort,
+// authors, series, series_index, publisher, year, language and isbn. The
+// slashes of the template separate directories, the values are sanitized so
+// they can't add new ones.
+func NewRenamer(template string) (*Renamer, error) {
+	if strings.TrimSpace(template) == "" {
+		return nil, errors.New("Empty template")
+	}
+	for _, match := range placeholderRegexp.FindAllStringSubmatch(template, -1) {
+		if _, ok := placeholders[match[1]]; !ok {
+			return nil, errors.New("Unknown placeholder " + match[0])
+		}
+	}
+	return &Renamer{template: template}, nil
+}
+
+// Path returns the relative path of the book, with slashes as separators
+//
+// Each directory or file name is cleaned up: the characters not allowed on
+// the file systems are replaced, the separators left by the empty
+// placeholders are trimmed ("{series_index} - {title}" without series index
+// is just the title), the empty directories are dropped and the names are
+// cut to 200 bytes keeping the extension.
+func (r Renamer) Path(book Book) string {
+	var segments []string
+	for _, segment := range strings.Split(r.template, "/") {
+		name := placeholderRegexp.ReplaceAllStringFunc(segment, func(p string) string {
+			value := placeholders[p[1:len(p)-1]](book)
+			return unsafeFileChars.Replace(value)
+		})
+		if name = r.cleanName(name); name != "" {
+			segments = append(segments, name)
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// UniquePath returns the Path of the book, with a number added before the
+// extension ("Title (2).epub") if taken returns true for it
+func (r Renamer) UniquePath(book Book, taken func(path string) bool) string {
+	p := r.Path(book)
+	if !taken(p) {
+		return p
+	}
+	ext := path.Ext(p)
+	base := strings.TrimSuffix(p, ext)
+	for n := 2; ; n++ {
+		candidate := base + " (" + strconv.Itoa(n) + ")" + ext
+		if !taken(candidate) {
+			return candidate
+		}
+	}
+}
+
+func (r Renamer) cleanName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	if r.ASCII {
+		name = transliterate(name)
+	}
+	name = separatorsRegexp.ReplaceAllString(name, " ")
+
+	ext := path.Ext(name)
+	if strings.ContainsAny(ext, " ") || len(ext) < 2 || len(ext) > 10 {
+		ext = ""
+	}
+	base := strings.TrimSuffix(name, ext)
+	base = strings.Trim(base, " -_.,;")
+	if base == "" {
+		return ""
+	}
+	if reservedFileNames[strings.ToLower(base)] {
+		base += "_"
+	}
+	for len(base)+len(ext) > maxSegmentLength {
+		_, size := utf8.DecodeLastRuneInString(base)
+		base = base[:len(base)-size]
+	}
+	return strings.TrimRight(base, " .") + ext
+}
+
+// transliterate returns the ASCII form of s, the characters that can't be
+// transliterated are dropped
+func transliterate(s string) string {
+	var buff strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		switch {
+		case r < utf8.RuneSelf:
+			buff.WriteRune(r)
+		case unicode.Is(unicode.Mn, r):
+		default:
+			if t, ok := transliterations[unicode.ToLower(r)]; ok && unicode.IsUpper(r) {
+				first, size := utf8.DecodeRuneInString(t)
+				buff.WriteString(string(unicode.ToUpper(first)) + t[size:])
+			} else if t, ok := transliterations[r]; ok {
+				buff.WriteString(t)
+			} else if unicode.IsSpace(r) {
+				buff.WriteRune(' ')
+			}
+		}
+	}
+	return buff.String()
+}
+
+// seriesIndex drops the decimals of whole series indexes ("2.0" -> "2")
+func seriesIndex(index string) string {
+	index = strings.TrimSpace(index)
+	if f, err := strconv.ParseFloat(index, 64); err == nil && f == float64(int(f)) {
+		return strconv.Itoa(int(f))
+	}
+	return index
+}
+
+// authors returns the authors of the book, or all the contributors if none
+// of them has the author role
+func (b Book) authors() []Author {
+	var authors []Author
+	for _, a := range b.Authors {
+		if a.Role == "aut" {
+			authors = append(authors, a)
+		}
+	}
+	if len(authors) == 0 {
+		return b.Authors
+	}
+	return authors
+}
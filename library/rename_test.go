@@ -0,0 +1,56 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package library
+
+import "testing"
+
+const testTemplate = "{author_sort}/{series}/{series_index} - {title}.epub"
+
+func TestRenamerPath(t *testing.T) {
+	r, err := NewRenamer(testTemplate)
+	if err != nil {
+		t.Fatalf("NewRenamer() return an error: %v", err)
+	}
+	book := Book{
+		Title:       "Harry Potter: The Philosopher's Stone?",
+		Authors:     []Author{{"J. K. Rowling", "Rowling, J. K.", "aut"}},
+		Series:      "Harry Potter",
+		SeriesIndex: "1.0",
+	}
+	tests := []struct {
+		book     Book
+		ascii    bool
+		expected string
+	}{
+		{book, false, "Rowling, J. K/Harry Potter/1 - Harry Potter - The Philosopher's Stone.epub"},
+		{Book{Title: "Dune", Authors: []Author{{"Frank Herbert", "Herbert, Frank", "aut"}}}, false, "Herbert, Frank/Dune.epub"},
+		{Book{Title: "Ærø ../Ðåß", Authors: []Author{{"Лев Толстой", "Толстой, Лев", "aut"}}}, true, "Tolstoi, Lev/Aero ..-Dass.epub"},
+		{Book{Title: "Ærø"}, false, "Ærø.epub"},
+		{Book{Title: "con"}, false, "con_.epub"},
+	}
+	for _, test := range tests {
+		r.ASCII = test.ascii
+		if p := r.Path(test.book); p != test.expected {
+			t.Errorf("Path(%v) return: %q when was expected: %q", test.book.Title, p, test.expected)
+		}
+	}
+}
+
+func TestRenamerUniquePath(t *testing.T) {
+	r, _ := NewRenamer("{title}.epub")
+	taken := map[string]bool{"Dune.epub": true, "Dune (2).epub": true}
+	p := r.UniquePath(Book{Title: "Dune"}, func(p string) bool { return taken[p] })
+	if p != "Dune (3).epub" {
+		t.Errorf("UniquePath() return: %v", p)
+	}
+}
+
+func TestNewRenamerErrors(t *testing.T) {
+	for _, template := range []string{"", "{unknown}.epub"} {
+		if _, err := NewRenamer(template); err == nil {
+			t.Errorf("NewRenamer(%q) didn't return an error", template)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package library
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/meskio/epubgo"
+)
+
+// EventType is the kind of change reported by a Watcher
+type EventType int
+
+// The changes reported by a Watcher
+const (
+	Added EventType = iota
+	Updated
+	Removed
+	Renamed
+)
+
+// Event is a change of an epub of the directory tree watched
+type Event struct {
+	Type EventType
+	Path string
+	// OldPath is the previous path of a Renamed epub
+	OldPath string
+	// Book is the metadata of the epub, empty for Removed
+	Book Book
+	// Err is the error opening the epub, if any. The events with errors are
+	// still reported, a broken file added to the library is a change too.
+	Err error
+}
+
+// Watcher monitors the epubs of a directory tree
+//
+// The tree is scanned every interval, a file is only reported once its size
+// and modification time didn't change between two scans, so the files being
+// written are not parsed half copied. A file that disappears while another
+// one appears being the same file (os.SameFile) is reported as Renamed. The
+// atomic renames over an existing path are reported as Updated.
+//
+// The scans are needed anyway: fsnotify doesn't watch the subdirectories of
+// a tree, it misses the changes on network filesystems and it can't tell when
+// a file is completely written. So fsnotify is not imported, like the SQL
+// drivers, and the package has no dependencies. To react sooner to the
+// changes the caller can call Notify on its events:
+//
+//	fw, _ := fsnotify.NewWatcher()
+//	fw.Add(dir)
+//	go func() {
+//		for range fw.Events {
+//			w.Notify()
+//		}
+//	}()
+type Watcher struct {
+	// Events receives the changes, it is closed by Close
+	Events <-chan Event
+
+	events   chan Event
+	dir      string
+	interval time.Duration
+	files    map[string]os.FileInfo
+	pending  map[string]os.FileInfo
+	notify   chan struct{}
+	done     chan struct{}
+	wg       sync.WaitGroup
+	closed   sync.Once
+}
+
+// NewWatcher starts watching the directory tree of dir
+//
+// The epubs already on the tree are reported as Added after the first two
+// scans.
+func NewWatcher(dir string, interval time.Duration) (*Watcher, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, err
+	}
+	events := make(chan Event)
+	w := &Watcher{
+		Events:   events,
+		events:   events,
+		dir:      dir,
+		interval: interval,
+		files:    make(map[string]os.FileInfo),
+		pending:  make(map[string]os.FileInfo),
+		notify:   make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	w.wg.Add(1)
+	go w.run()
+	return w, nil
+}
+
+// Notify asks the watcher to scan the tree now
+func (w *Watcher) Notify() {
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Close stops watching and closes the Events channel, it can be called more
+// than once
+func (w *Watcher) Close() {
+	w.closed.Do(func() {
+		close(w.done)
+		w.wg.Wait()
+		close(w.events)
+	})
+}
+
+func (w *Watcher) run() {
+	defer w.wg.Done()
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		for _, event := range w.scan() {
+			select {
+			case w.events <- event:
+			case <-w.done:
+				return
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-w.notify:
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// scan walks the tree and returns the changes since the previous scan
+func (w *Watcher) scan() []Event {
+	if _, err := os.Stat(w.dir); err != nil {
+		// the directory is not reachable, keep the state until it is back
+		return nil
+	}
+	current := make(map[string]os.FileInfo)
+	filepath.Walk(w.dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && strings.EqualFold(filepath.Ext(path), ".epub") {
+			current[path] = info
+		}
+		return nil
+	})
+
+	var removed []string
+	for path := range w.files {
+		if _, ok := current[path]; !ok {
+			removed = append(removed, path)
+		}
+	}
+	for path := range w.pending {
+		if _, ok := current[path]; !ok {
+			delete(w.pending, path)
+		}
+	}
+	paths := make([]string, 0, len(current))
+	for path := range current {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	sort.Strings(removed)
+
+	var events []Event
+	renamed := make(map[string]bool)
+	for _, path := range paths {
+		info := current[path]
+		known, ok := w.files[path]
+		if ok && sameState(known, info) {
+			continue
+		}
+
+		event := Event{Type: Updated, Path: path}
+		if !ok {
+			event.Type = Added
+			for _, old := range removed {
+				if !renamed[old] && sameState(w.files[old], info) && os.SameFile(w.files[old], info) {
+					event.Type = Renamed
+					event.OldPath = old
+					renamed[old] = true
+					break
+				}
+			}
+		}
+		if event.Type != Renamed {
+			if p, ok := w.pending[path]; !ok || !sameState(p, info) {
+				w.pending[path] = info
+				continue
+			}
+		}
+
+		delete(w.pending, path)
+		delete(w.files, event.OldPath)
+		w.files[path] = info
+		event.Book, event.Err = readFile(path)
+		events = append(events, event)
+	}
+	for _, path := range removed {
+		if renamed[path] {
+			continue
+		}
+		delete(w.files, path)
+		events = append(events, Event{Type: Removed, Path: path})
+	}
+	return events
+}
+
+func sameState(a, b os.FileInfo) bool {
+	return a.Size() == b.Size() && a.ModTime().Equal(b.ModTime())
+}
+
+func readFile(path string) (Book, error) {
+	e, err := epubgo.Open(path)
+	if err != nil {
+		return Book{}, err
+	}
+	defer e.Close()
+	return ReadBook(e), nil
+}
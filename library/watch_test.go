@@ -0,0 +1,100 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package library
+
+import "testing"
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func newTestWatcher(t *testing.T) (*Watcher, string) {
+	dir := t.TempDir()
+	w := &Watcher{
+		dir:     dir,
+		files:   make(map[string]os.FileInfo),
+		pending: make(map[string]os.FileInfo),
+	}
+	return w, dir
+}
+
+func TestWatcherScan(t *testing.T) {
+	w, dir := newTestWatcher(t)
+	data, err := ioutil.ReadFile(bookPath)
+	if err != nil {
+		t.Fatalf("ReadFile(%v) return an error: %v", bookPath, err)
+	}
+
+	path := filepath.Join(dir, "tale.epub")
+	ioutil.WriteFile(path, data[:len(data)/2], 0644)
+	ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte("notes"), 0644)
+	if events := w.scan(); len(events) != 0 {
+		t.Errorf("scan() reported a file being written: %v", events)
+	}
+	ioutil.WriteFile(path, data, 0644)
+	if events := w.scan(); len(events) != 0 {
+		t.Errorf("scan() reported a file that changed: %v", events)
+	}
+	events := w.scan()
+	if len(events) != 1 || events[0].Type != Added || events[0].Path != path {
+		t.Fatalf("scan() return: %v", events)
+	}
+	if events[0].Err != nil || events[0].Book.Title != "A Dog's Tale" {
+		t.Errorf("scan() return the book: %v %v", events[0].Book, events[0].Err)
+	}
+	if events := w.scan(); len(events) != 0 {
+		t.Errorf("scan() reported an unchanged file: %v", events)
+	}
+
+	newPath := filepath.Join(dir, "sub", "tale.epub")
+	os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	os.Rename(path, newPath)
+	events = w.scan()
+	if len(events) != 1 || events[0].Type != Renamed || events[0].Path != newPath || events[0].OldPath != path {
+		t.Fatalf("scan() after rename return: %v", events)
+	}
+
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(newPath, later, later)
+	w.scan()
+	events = w.scan()
+	if len(events) != 1 || events[0].Type != Updated {
+		t.Errorf("scan() after update return: %v", events)
+	}
+
+	os.Remove(newPath)
+	events = w.scan()
+	if len(events) != 1 || events[0].Type != Removed || events[0].Path != newPath {
+		t.Errorf("scan() after remove return: %v", events)
+	}
+}
+
+func TestWatcherEvents(t *testing.T) {
+	dir := t.TempDir()
+	data, _ := ioutil.ReadFile(bookPath)
+	ioutil.WriteFile(filepath.Join(dir, "tale.epub"), data, 0644)
+
+	w, err := NewWatcher(dir, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewWatcher() return an error: %v", err)
+	}
+	select {
+	case event := <-w.Events:
+		if event.Type != Added {
+			t.Errorf("Events return: %v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("Events didn't report the book")
+	}
+
+	w.Close()
+	w.Close()
+	if event, ok := <-w.Events; ok {
+		t.Errorf("Events is not closed after Close(): %v", event)
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	navEndRegexp    = regexp.MustCompile(`</(?:[\w-]+:)?nav\s*>`)
+	navAnchorRegexp = regexp.MustCompile(`(?s)<(?:[\w-]+:)?a\b[^>]*>(.*?)</(?:[\w-]+:)?a\s*>`)
+)
+
+// navListClasses are the classes of the NCX navList elements of each nav type
+var navListClasses = map[string][]string{
+	"loi": {"loi", "lof", "figure", "figures", "illustration", "illustrations"},
+	"lot": {"lot", "table", "tables"},
+}
+
+// NavListEntry is an entry of a list of illustrations or tables
+type NavListEntry struct {
+	Label string
+	// Href is the location of the entry, a path as used by OpenFile
+	// followed by a fragment
+	Href string
+	// SpineIndex is the position on the spine of the document of the
+	// entry, -1 if it is not on the spine
+	SpineIndex int
+}
+
+// ListOfIllustrations returns the list of illustrations of the book
+//
+// It is taken from the loi nav of the navigation document, from an NCX
+// navList of figures or, if the book has none, from the figures with a
+// caption or an alternative text of the documents of the spine.
+func (e Epub) ListOfIllustrations() ([]NavListEntry, error) {
+	return e.navList("loi", func() ([]NavListEntry, error) {
+		figures, err := e.Figures()
+		if err != nil {
+			return nil, err
+		}
+		var entries []NavListEntry
+		for _, figure := range figures {
+			label := figure.Caption
+			if label == "" {
+				label = strings.Join(strings.Fields(figure.Alt), " ")
+			}
+			if label != "" {
+				entries = append(entries, navListEntry(label, figure.Location, figure.ID))
+			}
+		}
+		return entries, nil
+	})
+}
+
+// ListOfTables returns the list of tables of the book
+//
+// It is taken from the lot nav of the navigation document, from an NCX
+// navList of tables or, if the book has none, from the tables with a caption
+// of the documents of the spine.
+func (e Epub) ListOfTables() ([]NavListEntry, error) {
+	return e.navList("lot", func() ([]NavListEntry, error) {
+		tables, err := e.Tables()
+		if err != nil {
+			return nil, err
+		}
+		var entries []NavListEntry
+		for _, table := range tables {
+			if table.Caption != "" {
+				entries = append(entries, navListEntry(table.Caption, table.Location, table.ID))
+			}
+		}
+		return entries, nil
+	})
+}
+
+// navList returns the entries of the nav of type navType of the navigation
+// document or of the NCX, or the ones returned by scan if there is none
+func (e Epub) navList(navType string, scan func() ([]NavListEntry, error)) ([]NavListEntry, error) {
+	if navPath := e.navDocPath(); navPath != "" {
+		data, err := e.readFile(e.rootPath + navPath)
+		if err != nil {
+			return nil, err
+		}
+		if entries, ok := e.navDocList(data, navPath, navType); ok {
+			return entries, nil
+		}
+	}
+
+	if e.ncx != nil {
+		ncxPath := e.opf.ncxPath()
+		for _, list := range e.ncx.NavLists {
+			if !isNavListOf(list, navType) {
+				continue
+			}
+			var entries []NavListEntry
+			for _, target := range list.Targets {
+				href := e.ResolveHref(ncxPath, target.Content.Src)
+				entries = append(entries, e.newNavListEntry(strings.TrimSpace(target.Text), href))
+			}
+			return entries, nil
+		}
+	}
+	return scan()
+}
+
+// navDocList returns the links of the nav of type navType of the navigation
+// document data, or false if the document has no such nav
+func (e Epub) navDocList(data []byte, navPath, navType string) ([]NavListEntry, bool) {
+	nav, ok := navContent(data, navType)
+	if !ok {
+		return nil, false
+	}
+
+	var entries []NavListEntry
+	for _, a := range navAnchorRegexp.FindAllSubmatchIndex(nav, -1) {
+		tag := string(nav[a[0]:a[2]])
+		ref := attrValue(tag, "href")
+		if ref == "" {
+			continue
+		}
+		entries = append(entries, e.newNavListEntry(cellText(nav[a[2]:a[3]]), e.ResolveHref(navPath, ref)))
+	}
+	return entries, true
+}
+
+// navContent returns the content of the nav of type navType of the
+// navigation document data, or false if it has no such nav
+func navContent(data []byte, navType string) ([]byte, bool) {
+	re := regexp.MustCompile(`<(?:[\w-]+:)?nav\b[^>]*\btype\s*=\s*["'](?:[^"']*\s)?` + regexp.QuoteMeta(navType) + `[\s"']`)
+	loc := re.FindIndex(data)
+	if loc == nil {
+		return nil, false
+	}
+	nav := data[loc[1]:]
+	if end := navEndRegexp.FindIndex(nav); end != nil {
+		nav = nav[:end[0]]
+	}
+	return nav, true
+}
+
+// newNavListEntry returns the entry of the list pointing to href
+func (e Epub) newNavListEntry(label, href string) NavListEntry {
+	path := href
+	if i := strings.Index(path, "#"); i != -1 {
+		path = path[:i]
+	}
+	return NavListEntry{Label: label, Href: href, SpineIndex: e.opf.spineIndex(path)}
+}
+
+// navListEntry returns the entry of the list for the element with id at
+// location
+func navListEntry(label string, location Location, id string) NavListEntry {
+	href := location.Href
+	if id != "" {
+		href += "#" + id
+	}
+	return NavListEntry{Label: label, Href: href, SpineIndex: location.SpineIndex}
+}
+
+// isNavListOf returns whether the NCX navList is a list of navType
+func isNavListOf(list ncxNavList, navType string) bool {
+	for _, class := range append(strings.Fields(list.Class), list.ID) {
+		if contains(navListClasses[navType], strings.ToLower(class)) {
+			return true
+		}
+	}
+	return false
+}
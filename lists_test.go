@@ -0,0 +1,72 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import "strings"
+
+const listsDocument = `<html><body>
+<figure id="f1"><img src="../images/cover.jpg" alt=""/><figcaption>Figure 1. The cover</figcaption></figure>
+<p><img src="../images/cover.jpg" alt=""/></p>
+<table id="t1"><caption>Table 1. Prices</caption><tr><td>1</td></tr></table>
+<table><tr><td>layout</td></tr></table>
+</body></html>`
+
+func TestListOfIllustrationsNav(t *testing.T) {
+	nav := strings.Replace(epub3Nav, "</body>", `<nav epub:type="loi" hidden=""><h2>Illustrations</h2><ol>
+<li><a href="text/ch2.xhtml#map">The <em>map</em></a></li>
+</ol></nav>
+</body>`, 1)
+	f := buildEpub(t, epub3OPF, map[string]string{
+		"nav.xhtml":      nav,
+		"text/ch1.xhtml": listsDocument,
+		"text/ch2.xhtml": `<html><body><p>Chapter 2</p></body></html>`,
+	})
+	entries, err := f.ListOfIllustrations()
+	if err != nil {
+		t.Fatalf("ListOfIllustrations() return an error: %v", err)
+	}
+	if len(entries) != 1 || entries[0] != (NavListEntry{"The map", "text/ch2.xhtml#map", 1}) {
+		t.Errorf("ListOfIllustrations() return: %v", entries)
+	}
+}
+
+func TestListsScan(t *testing.T) {
+	f := buildEpub(t, epub3OPF, map[string]string{
+		"nav.xhtml":      epub3Nav,
+		"text/ch1.xhtml": listsDocument,
+		"text/ch2.xhtml": `<html><body><p>Chapter 2</p></body></html>`,
+	})
+	entries, err := f.ListOfIllustrations()
+	if err != nil {
+		t.Fatalf("ListOfIllustrations() return an error: %v", err)
+	}
+	if len(entries) != 1 || entries[0] != (NavListEntry{"Figure 1. The cover", "text/ch1.xhtml#f1", 0}) {
+		t.Errorf("ListOfIllustrations() return: %v", entries)
+	}
+
+	entries, err = f.ListOfTables()
+	if err != nil {
+		t.Fatalf("ListOfTables() return an error: %v", err)
+	}
+	if len(entries) != 1 || entries[0] != (NavListEntry{"Table 1. Prices", "text/ch1.xhtml#t1", 0}) {
+		t.Errorf("ListOfTables() return: %v", entries)
+	}
+}
+
+func TestListOfTablesNCX(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	f.ncx.NavLists = []ncxNavList{{Class: "lot", Targets: []pageTarget{{Text: " Prices ", Content: content{Src: chapterFile + "#prices"}}}}}
+	entries, err := f.ListOfTables()
+	if err != nil {
+		t.Fatalf("ListOfTables() return an error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Label != "Prices" || entries[0].Href != chapterFile+"#prices" {
+		t.Errorf("ListOfTables() return: %v", entries)
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+// Logger receives the warnings about the problems found on the epub that
+// don't prevent reading it: malformed files that are ignored, missing
+// metadata or the fallbacks used
+//
+// The args are key value pairs as in log/slog, so a *slog.Logger can be
+// used as Logger.
+type Logger interface {
+	Warn(msg string, args ...interface{})
+}
+
+// SetLogger sets the logger of the warnings, nil disables them
+//
+// The warnings found while loading the epub, the ones returned by Warnings,
+// are logged when the logger is set.
+func (e *Epub) SetLogger(logger Logger) {
+	e.logger = logger
+	if logger == nil {
+		return
+	}
+	for _, w := range e.warnings {
+		logger.Warn(w.Message, "code", w.Code, "path", w.Path)
+	}
+}
+
+func (e Epub) warn(msg string, args ...interface{}) {
+	if e.logger != nil {
+		e.logger.Warn(msg, args...)
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import "strings"
+
+type testLogger []string
+
+func (l *testLogger) Warn(msg string, args ...interface{}) {
+	*l = append(*l, msg)
+}
+
+func TestLogger(t *testing.T) {
+	f, _ := Open(invalidNCXPath)
+	defer f.Close()
+
+	var logger testLogger
+	f.SetLogger(&logger)
+	if len(logger) != 1 || logger[0] != "The spine toc notvalid is not in the manifest, looking for the NCX by its media type" {
+		t.Errorf("Wrong load warnings: %v", logger)
+	}
+
+	f, _ = Open(bookPath)
+	defer f.Close()
+	logger = nil
+	f.SetLogger(&logger)
+	if len(logger) != 0 {
+		t.Errorf("Wrong load warnings: %v", logger)
+	}
+	if _, err := f.OpenFile(strings.ToUpper(coverFile)); err != nil {
+		t.Fatalf("OpenFile() return an error: %v", err)
+	}
+	if len(logger) != 1 || logger[0] != "File found with a different case" {
+		t.Errorf("Wrong warnings: %v", logger)
+	}
+}
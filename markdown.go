@@ -0,0 +1,460 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var markdownChapterTemplate = template.Must(template.New("chapter").Parse(`<html{{with .Language}} lang="{{.}}"{{end}}>
+<head>
+<title>{{.Title}}</title>
+{{if .Stylesheet}}<link rel="stylesheet" type="text/css" href="style.css"/>{{end}}
+</head>
+<body{{with .Class}} class="{{.}}"{{end}}>
+{{.Body}}
+</body>
+</html>
+`))
+
+var (
+	mdATXRegexp       = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))??(?:[ \t]+#+)?[ \t]*$`)
+	mdSetextRegexp    = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	mdRuleRegexp      = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	mdFenceRegexp     = regexp.MustCompile("^( {0,3})(```+|~~~+)[ \t]*([^`\\s]*)[^`]*$")
+	mdQuoteRegexp     = regexp.MustCompile(`^ {0,3}> ?`)
+	mdListItemRegexp  = regexp.MustCompile(`^( {0,3})([-*+]|\d{1,9}[.)])(?:[ \t]+|$)`)
+	mdIndentRegexp    = regexp.MustCompile(`^(?: {4}|\t)`)
+	mdHTMLBlockRegexp = regexp.MustCompile(`^ {0,3}(?:<!--|</?(?:address|article|aside|blockquote|details|div|dl|fieldset|figcaption|figure|footer|form|h[1-6]|header|hr|main|nav|ol|p|pre|section|table|ul|svg|math)(?:[\s/>]|$))`)
+
+	mdBackticksRegexp = regexp.MustCompile("`+")
+	mdAutolinkRegexp  = regexp.MustCompile(`<((?:https?|ftp|mailto):[^\s<>]+)>`)
+	mdEscapeRegexp    = regexp.MustCompile(`\\([\\` + "`" + `*_{}\[\]()#+\-.!<>|~])`)
+	mdImageRegexp     = regexp.MustCompile(`!\[([^\]]*)\]\(\s*<?([^)\s>]*)>?(?:\s+"([^"]*)")?\s*\)`)
+	mdLinkRegexp      = regexp.MustCompile(`\[([^\]]+)\]\(\s*<?([^)\s>]*)>?(?:\s+"([^"]*)")?\s*\)`)
+	mdBreakRegexp     = regexp.MustCompile(`(?: {2,}|\\)\n`)
+	mdStrongRegexp    = regexp.MustCompile(`\*\*(\S(?:.*?\S)?)\*\*|__(\S(?:.*?\S)?)__`)
+	mdEmRegexp        = regexp.MustCompile(`\*(\S(?:.*?\S)?)\*`)
+	mdUnderEmRegexp   = regexp.MustCompile(`(^|[^\w])_(\S(?:.*?\S)?)_([^\w]|$)`)
+	mdPlaceRegexp     = regexp.MustCompile("\x00([0-9]+)\x00")
+)
+
+// MarkdownChapter is a chapter of the book for Build
+type MarkdownChapter struct {
+	// Title is the entry of the chapter on the table of contents, by default
+	// the text of its first heading
+	Title    string
+	Markdown []byte
+	// Class is added to the body of the chapter, to style it with the CSS
+	// of the book
+	Class string
+}
+
+// BookMetadata is the metadata of the book for Build
+type BookMetadata struct {
+	// Identifier, Title and Language are the metadata of the book, as in New
+	Identifier string
+	Title      string
+	Language   string
+	// Metadata are other metadata fields, like "creator" or "publisher"
+	Metadata map[string][]MdataElement
+	// CSS is the stylesheet of the book, linked from all the chapters
+	CSS []byte
+}
+
+type markdownChapterData struct {
+	Title      string
+	Language   string
+	Stylesheet bool
+	Class      string
+	Body       template.HTML
+}
+
+// Build returns an EPUB 3 book with a document for each chapter, converted
+// from Markdown
+//
+// The chapters are added to the table of contents and their headings get an
+// id made from their text, to link them. The Markdown supported is the
+// CommonMark blocks (headings, paragraphs, block quotes, lists, code blocks,
+// thematic breaks and HTML) and inlines (code spans, emphasis, links, images,
+// autolinks and hard line breaks), without reference links. The chapters
+// are written next to the OPF, as chapter-001.xhtml, chapter-002.xhtml, ...
+// so the images and other files linked by them are added with AddResource
+// on the path they use. The book is written with Repack.
+func Build(metadata BookMetadata, chapters []MarkdownChapter) (*Epub, error) {
+	if len(chapters) == 0 {
+		return nil, errors.New("The book has no chapters")
+	}
+	e, err := New(metadata.Identifier, metadata.Title, metadata.Language)
+	if err != nil {
+		return nil, err
+	}
+	for field, elems := range metadata.Metadata {
+		if err := e.SetMetadata(field, elems); err != nil {
+			return nil, err
+		}
+	}
+	if len(metadata.CSS) != 0 {
+		if _, err := e.AddResource("style.css", "text/css", bytes.NewReader(metadata.CSS)); err != nil {
+			return nil, err
+		}
+	}
+
+	digits := len(strconv.Itoa(len(chapters)))
+	if digits < 3 {
+		digits = 3
+	}
+	for i, chapter := range chapters {
+		r := markdownRenderer{ids: make(map[string]bool)}
+		r.render(chapter.Markdown)
+		data := markdownChapterData{
+			Title:      chapter.Title,
+			Language:   metadata.Language,
+			Stylesheet: len(metadata.CSS) != 0,
+			Class:      chapter.Class,
+			Body:       template.HTML(r.buff.String()),
+		}
+		if data.Title == "" {
+			data.Title = r.firstHeading
+		}
+		if data.Title == "" {
+			data.Title = "Chapter " + strconv.Itoa(i+1)
+		}
+
+		var buff bytes.Buffer
+		if err := markdownChapterTemplate.Execute(&buff, data); err != nil {
+			return nil, err
+		}
+		href := fmt.Sprintf("chapter-%0*d.xhtml", digits, i+1)
+		if err := e.InsertDocument(href, data.Title, ToXHTML(buff.Bytes(), data.Title, metadata.Language), -1); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// markdownRenderer converts Markdown into HTML
+type markdownRenderer struct {
+	buff strings.Builder
+	// ids are the ids given to the headings
+	ids          map[string]bool
+	firstHeading string
+}
+
+func (r *markdownRenderer) render(markdown []byte) {
+	text := strings.NewReplacer("\r\n", "\n", "\r", "\n", "\x00", "").Replace(string(markdown))
+	r.blocks(strings.Split(text, "\n"), false)
+}
+
+// blocks renders the lines as blocks, tight renders the paragraphs without
+// the p element as on the items of tight lists
+func (r *markdownRenderer) blocks(lines []string, tight bool) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			i++
+
+		case mdFenceRegexp.MatchString(line):
+			sub := mdFenceRegexp.FindStringSubmatch(line)
+			indent, fence := len(sub[1]), sub[2]
+			end := regexp.MustCompile(`^ {0,3}` + regexp.QuoteMeta(fence[:1]) + `{` + strconv.Itoa(len(fence)) + `,}[ \t]*$`)
+			var code []string
+			for i++; i < len(lines) && !end.MatchString(lines[i]); i++ {
+				l := lines[i]
+				for j := 0; j < indent && strings.HasPrefix(l, " "); j++ {
+					l = l[1:]
+				}
+				code = append(code, l)
+			}
+			i++
+			r.code(code, sub[3])
+
+		case mdATXRegexp.MatchString(line):
+			sub := mdATXRegexp.FindStringSubmatch(line)
+			r.heading(len(sub[1]), sub[2])
+			i++
+
+		case mdRuleRegexp.MatchString(line):
+			r.buff.WriteString("<hr/>\n")
+			i++
+
+		case mdQuoteRegexp.MatchString(line):
+			var quote []string
+			for ; i < len(lines) && strings.TrimSpace(lines[i]) != ""; i++ {
+				quote = append(quote, mdQuoteRegexp.ReplaceAllString(lines[i], ""))
+			}
+			r.buff.WriteString("<blockquote>\n")
+			r.blocks(quote, false)
+			r.buff.WriteString("</blockquote>\n")
+
+		case mdListItemRegexp.MatchString(line):
+			i = r.list(lines, i)
+
+		case mdIndentRegexp.MatchString(line):
+			var code []string
+			for ; i < len(lines) && (mdIndentRegexp.MatchString(lines[i]) || strings.TrimSpace(lines[i]) == ""); i++ {
+				code = append(code, mdIndentRegexp.ReplaceAllString(lines[i], ""))
+			}
+			for len(code) > 0 && strings.TrimSpace(code[len(code)-1]) == "" {
+				code = code[:len(code)-1]
+			}
+			r.code(code, "")
+
+		case mdHTMLBlockRegexp.MatchString(line):
+			for ; i < len(lines) && strings.TrimSpace(lines[i]) != ""; i++ {
+				r.buff.WriteString(lines[i] + "\n")
+			}
+
+		default:
+			var para []string
+			level := 0
+			for ; i < len(lines) && strings.TrimSpace(lines[i]) != ""; i++ {
+				if sub := mdSetextRegexp.FindStringSubmatch(lines[i]); sub != nil && len(para) > 0 {
+					level = 2
+					if sub[1][0] == '=' {
+						level = 1
+					}
+					i++
+					break
+				}
+				if len(para) > 0 && interruptsParagraph(lines[i]) {
+					break
+				}
+				para = append(para, strings.TrimLeft(lines[i], " \t"))
+			}
+			text := strings.TrimSpace(strings.Join(para, "\n"))
+			switch {
+			case level != 0:
+				r.heading(level, text)
+			case tight:
+				r.buff.WriteString(r.inline(text) + "\n")
+			default:
+				r.buff.WriteString("<p>" + r.inline(text) + "</p>\n")
+			}
+		}
+	}
+}
+
+// interruptsParagraph returns whether the line starts a block that ends the
+// paragraph before it
+func interruptsParagraph(line string) bool {
+	return mdFenceRegexp.MatchString(line) || mdATXRegexp.MatchString(line) ||
+		mdRuleRegexp.MatchString(line) || mdQuoteRegexp.MatchString(line) ||
+		mdHTMLBlockRegexp.MatchString(line) || mdListItemRegexp.MatchString(line)
+}
+
+// list renders the list starting on the line start, returns the line after
+// its end
+//
+// The list is loose, with its items rendered as paragraphs, if there are
+// blank lines between its items or inside them.
+func (r *markdownRenderer) list(lines []string, start int) int {
+	sub := mdListItemRegexp.FindStringSubmatch(lines[start])
+	marker := sub[2]
+	ordered := unicode.IsDigit(rune(marker[0]))
+	sameList := func(m string) bool {
+		if ordered {
+			return unicode.IsDigit(rune(m[0])) && m[len(m)-1] == marker[len(marker)-1]
+		}
+		return m == marker
+	}
+
+	var items [][]string
+	loose := false
+	blank := false
+	indent := 0
+	i := start
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" {
+			blank = true
+			if len(items) > 0 {
+				items[len(items)-1] = append(items[len(items)-1], "")
+			}
+			continue
+		}
+		if sub := mdListItemRegexp.FindStringSubmatch(line); sub != nil && (len(items) == 0 || len(sub[1]) < indent) && !mdRuleRegexp.MatchString(line) {
+			if !sameList(sub[2]) {
+				break
+			}
+			if blank && len(items) > 0 {
+				loose = true
+			}
+			blank = false
+			indent = len(sub[0])
+			if strings.TrimSpace(line[indent:]) == "" {
+				indent = len(sub[1]) + len(sub[2]) + 1
+			}
+			items = append(items, []string{strings.TrimSpace(line[len(sub[0]):])})
+			continue
+		}
+		leading := len(line) - len(strings.TrimLeft(line, " "))
+		switch {
+		case leading >= indent:
+			loose = loose || blank
+			items[len(items)-1] = append(items[len(items)-1], line[indent:])
+		case !blank && !interruptsParagraph(line):
+			items[len(items)-1] = append(items[len(items)-1], strings.TrimSpace(line))
+		default:
+			r.writeList(items, ordered, marker, loose)
+			return i
+		}
+		blank = false
+	}
+	r.writeList(items, ordered, marker, loose)
+	return i
+}
+
+func (r *markdownRenderer) writeList(items [][]string, ordered bool, marker string, loose bool) {
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+	}
+	r.buff.WriteString("<" + tag)
+	if n, _ := strconv.Atoi(marker[:len(marker)-1]); ordered && n != 1 {
+		r.buff.WriteString(` start="` + strconv.Itoa(n) + `"`)
+	}
+	r.buff.WriteString(">\n")
+	for _, item := range items {
+		r.buff.WriteString("<li>")
+		r.blocks(item, !loose)
+		r.buff.WriteString("</li>\n")
+	}
+	r.buff.WriteString("</" + tag + ">\n")
+}
+
+func (r *markdownRenderer) code(lines []string, lang string) {
+	r.buff.WriteString("<pre><code")
+	if lang != "" {
+		r.buff.WriteString(` class="language-` + escapeAttr(lang) + `"`)
+	}
+	r.buff.WriteString(">")
+	for _, line := range lines {
+		r.buff.WriteString(escapeHTMLChars(line) + "\n")
+	}
+	r.buff.WriteString("</code></pre>\n")
+}
+
+func (r *markdownRenderer) heading(level int, text string) {
+	html := r.inline(strings.TrimSpace(text))
+	plain := extractText([]byte(html))
+	if r.firstHeading == "" {
+		r.firstHeading = plain
+	}
+	id := headingID(plain)
+	for n := 2; r.ids[id]; n++ {
+		id = headingID(plain) + "-" + strconv.Itoa(n)
+	}
+	r.ids[id] = true
+	tag := "h" + strconv.Itoa(level)
+	r.buff.WriteString("<" + tag + ` id="` + id + `">` + html + "</" + tag + ">\n")
+}
+
+// headingID returns the id of the heading, its text lowercased with the
+// spaces replaced by hyphens and without punctuation
+func headingID(text string) string {
+	var id strings.Builder
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '-' && r != '_'
+	}) {
+		if id.Len() > 0 {
+			id.WriteString("-")
+		}
+		id.WriteString(word)
+	}
+	if id.Len() == 0 || !unicode.IsLetter([]rune(id.String())[0]) {
+		return "h-" + id.String()
+	}
+	return id.String()
+}
+
+// inline renders the inline Markdown of the text
+//
+// The code spans, links and HTML tags are replaced by placeholders while the
+// emphasis is parsed, so their content is not modified.
+func (r *markdownRenderer) inline(text string) string {
+	var places []string
+	place := func(html string) string {
+		places = append(places, html)
+		return "\x00" + strconv.Itoa(len(places)-1) + "\x00"
+	}
+
+	text = codeSpans(text, func(code string) string {
+		return place("<code>" + escapeHTMLChars(code) + "</code>")
+	})
+	text = mdEscapeRegexp.ReplaceAllStringFunc(text, func(s string) string {
+		return place(escapeHTMLChars(s[1:]))
+	})
+	text = mdAutolinkRegexp.ReplaceAllStringFunc(text, func(s string) string {
+		url := s[1 : len(s)-1]
+		return place(`<a href="` + escapeAttr(url) + `">` + escapeHTMLChars(strings.TrimPrefix(url, "mailto:")) + `</a>`)
+	})
+	text = htmlTagRegexp.ReplaceAllStringFunc(text, place)
+	text = mdImageRegexp.ReplaceAllStringFunc(text, func(s string) string {
+		sub := mdImageRegexp.FindStringSubmatch(s)
+		alt := mdPlaceRegexp.ReplaceAllString(sub[1], "")
+		img := `<img src="` + escapeAttr(sub[2]) + `" alt="` + escapeAttr(alt) + `"`
+		if sub[3] != "" {
+			img += ` title="` + escapeAttr(sub[3]) + `"`
+		}
+		return place(img + "/>")
+	})
+	text = mdLinkRegexp.ReplaceAllStringFunc(text, func(s string) string {
+		sub := mdLinkRegexp.FindStringSubmatch(s)
+		a := `<a href="` + escapeAttr(sub[2]) + `"`
+		if sub[3] != "" {
+			a += ` title="` + escapeAttr(sub[3]) + `"`
+		}
+		return place(a+">") + sub[1] + place("</a>")
+	})
+	text = mdBreakRegexp.ReplaceAllString(text, "<br/>\n")
+	text = mdStrongRegexp.ReplaceAllString(text, "<strong>$1$2</strong>")
+	text = mdEmRegexp.ReplaceAllString(text, "<em>$1</em>")
+	for i := 0; i < 2; i++ {
+		text = mdUnderEmRegexp.ReplaceAllString(text, "$1<em>$2</em>$3")
+	}
+
+	for mdPlaceRegexp.MatchString(text) {
+		text = mdPlaceRegexp.ReplaceAllStringFunc(text, func(s string) string {
+			n, _ := strconv.Atoi(s[1 : len(s)-1])
+			return places[n]
+		})
+	}
+	return text
+}
+
+// codeSpans replaces the code spans of the text, the text between two runs
+// of backticks of the same length, by the result of replace on their code
+func codeSpans(text string, replace func(code string) string) string {
+	var buff strings.Builder
+	last := 0
+	runs := mdBackticksRegexp.FindAllStringIndex(text, -1)
+	for i := 0; i < len(runs); i++ {
+		if runs[i][0] < last {
+			continue
+		}
+		length := runs[i][1] - runs[i][0]
+		for j := i + 1; j < len(runs); j++ {
+			if runs[j][1]-runs[j][0] != length {
+				continue
+			}
+			buff.WriteString(text[last:runs[i][0]])
+			buff.WriteString(replace(strings.TrimSpace(text[runs[i][1]:runs[j][0]])))
+			last = runs[j][1]
+			i = j
+			break
+		}
+	}
+	buff.WriteString(text[last:])
+	return buff.String()
+}
@@ -0,0 +1,103 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import "strings"
+
+func TestRenderMarkdown(t *testing.T) {
+	markdown := "# The *first* chapter #\n" +
+		"\n" +
+		"Some **bold** and _emphasis_ with `<code>` & a [link](http://example.com \"Example\").  \n" +
+		"snake_case_name \\*not emphasis\\*\n" +
+		"\n" +
+		"Setext\n" +
+		"------\n" +
+		"\n" +
+		"- one\n" +
+		"- two\n" +
+		"  - nested\n" +
+		"\n" +
+		"3. three\n" +
+		"4. four\n" +
+		"\n" +
+		"> quoted\n" +
+		"> text\n" +
+		"\n" +
+		"```go\n" +
+		"if a < b {\n" +
+		"```\n" +
+		"\n" +
+		"![Alt](images/a.png)\n" +
+		"\n" +
+		"***\n" +
+		"\n" +
+		"# The first chapter\n"
+	r := markdownRenderer{ids: make(map[string]bool)}
+	r.render([]byte(markdown))
+	html := r.buff.String()
+
+	for _, expected := range []string{
+		`<h1 id="the-first-chapter">The <em>first</em> chapter</h1>`,
+		`<p>Some <strong>bold</strong> and <em>emphasis</em> with <code>&lt;code&gt;</code> & a <a href="http://example.com" title="Example">link</a>.<br/>` + "\n" +
+			`snake_case_name *not emphasis*</p>`,
+		`<h2 id="setext">Setext</h2>`,
+		"<ul>\n<li>one\n</li>\n<li>two\n<ul>\n<li>nested\n</li>\n</ul>\n</li>\n</ul>",
+		"<ol start=\"3\">\n<li>three\n</li>\n<li>four\n</li>\n</ol>",
+		"<blockquote>\n<p>quoted\ntext</p>\n</blockquote>",
+		"<pre><code class=\"language-go\">if a &lt; b {\n</code></pre>",
+		`<p><img src="images/a.png" alt="Alt"/></p>`,
+		`<hr/>`,
+		`<h1 id="the-first-chapter-2">`,
+	} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("render() doesn't contain %q:\n%s", expected, html)
+		}
+	}
+	if r.firstHeading != "The first chapter" {
+		t.Errorf("The first heading is: %q", r.firstHeading)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	f, err := Build(BookMetadata{
+		Title:    "Markdown",
+		Language: "en",
+		CSS:      []byte("p { margin: 0; }"),
+	}, []MarkdownChapter{
+		{Markdown: []byte("# Start\n\nFish & chips\n\n1. one\n\n2. two\n")},
+		{Title: "Second", Markdown: []byte("Text"), Class: "second"},
+	})
+	if err != nil {
+		t.Fatalf("Build() return an error: %v", err)
+	}
+
+	book := repackBook(t, f)
+	if text, err := book.Text(0); err != nil || !strings.Contains(text, "Fish & chips") {
+		t.Errorf("Text() return: %v, %v", text, err)
+	}
+	chapter := readBookFile(t, book, "chapter-001.xhtml")
+	for _, expected := range []string{
+		`<link rel="stylesheet" type="text/css" href="style.css"/>`,
+		`<h1 id="start">Start</h1>`,
+		"<ol>\n<li><p>one</p>\n</li>\n<li><p>two</p>\n</li>\n</ol>",
+	} {
+		if !strings.Contains(chapter, expected) {
+			t.Errorf("The chapter doesn't contain %q:\n%s", expected, chapter)
+		}
+	}
+	if chapter := readBookFile(t, book, "chapter-002.xhtml"); !strings.Contains(chapter, `<body class="second">`) {
+		t.Errorf("The second chapter is: %s", chapter)
+	}
+	nav := readBookFile(t, book, "nav.xhtml")
+	if !strings.Contains(nav, `<a href="chapter-001.xhtml">Start</a>`) || !strings.Contains(nav, `<a href="chapter-002.xhtml">Second</a>`) {
+		t.Errorf("The navigation document is: %v", nav)
+	}
+
+	if _, err := Build(BookMetadata{Title: "Empty"}, nil); err == nil {
+		t.Errorf("Build() without chapters didn't return an error")
+	}
+}
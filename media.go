@@ -0,0 +1,101 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	videoRegexp    = regexp.MustCompile(`(?is)<(?:[\w-]+:)?video\b[^>]*/>|<(?:[\w-]+:)?video\b(?:[^>]*[^/>])?>.*?</(?:[\w-]+:)?video>`)
+	videoTagRegexp = regexp.MustCompile(`(?i)<(?:[\w-]+:)?video\b[^>]*>`)
+	sourceRegexp   = regexp.MustCompile(`(?i)<(?:[\w-]+:)?source\b[^>]*>`)
+)
+
+// MediaResource is an audio or video file of the epub
+type MediaResource struct {
+	// Href is the path of the file, as used by OpenFile
+	Href      string
+	MediaType string
+	Size      int64
+	// ReferencedBy are the hrefs of the documents that use the file
+	ReferencedBy []string
+	// Poster is the href of the image shown before a video is played, taken
+	// from the poster attribute of the video element, empty if there is none
+	Poster string
+}
+
+// MediaResources returns the audio and video files of the manifest with the
+// documents that reference them
+func (e Epub) MediaResources() ([]MediaResource, error) {
+	var resources []MediaResource
+	index := make(map[string]int)
+	for _, item := range e.opf.Manifest {
+		if !strings.HasPrefix(item.MediaType, "audio/") && !strings.HasPrefix(item.MediaType, "video/") {
+			continue
+		}
+		index[e.rootPath+item.Href] = len(resources)
+		resources = append(resources, MediaResource{
+			Href:      item.Href,
+			MediaType: item.MediaType,
+			Size:      e.size(e.rootPath + item.Href),
+		})
+	}
+	if len(resources) == 0 {
+		return nil, nil
+	}
+
+	for _, item := range e.opf.Manifest {
+		if !isMarkup(item.MediaType) || item.MediaType == "application/oebps-package+xml" {
+			continue
+		}
+		name := e.rootPath + item.Href
+		data, err := e.readFile(name)
+		if err != nil {
+			continue
+		}
+		referenced := make(map[int]bool)
+		for _, ref := range references(data, name) {
+			if i, ok := index[ref]; ok && !referenced[i] {
+				referenced[i] = true
+				resources[i].ReferencedBy = append(resources[i].ReferencedBy, item.Href)
+			}
+		}
+		for _, video := range videoRegexp.FindAll(data, -1) {
+			e.setPoster(resources, index, string(video), name)
+		}
+	}
+	for _, r := range resources {
+		sort.Strings(r.ReferencedBy)
+	}
+	return resources, nil
+}
+
+// setPoster sets the poster of the video element to the resources it plays
+func (e Epub) setPoster(resources []MediaResource, index map[string]int, video, docPath string) {
+	tag := videoTagRegexp.FindString(video)
+	poster := attrValue(tag, "poster")
+	if poster == "" {
+		return
+	}
+	poster = e.ResolveHref(strings.TrimPrefix(docPath, e.rootPath), poster)
+	if poster == "" {
+		return
+	}
+	srcs := []string{attrValue(tag, "src")}
+	for _, source := range sourceRegexp.FindAllString(video, -1) {
+		srcs = append(srcs, attrValue(source, "src"))
+	}
+	for _, src := range srcs {
+		if src == "" {
+			continue
+		}
+		if i, ok := index[resolveRef(docPath, src)]; ok {
+			resources[i].Poster = strings.SplitN(poster, "#", 2)[0]
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+func TestMediaResources(t *testing.T) {
+	f := buildEpub(t, "testdata/epub3.opf", map[string]string{
+		"media/clip.mp4":   "video",
+		"media/song.mp3":   "audio",
+		"images/cover.jpg": "jpeg",
+		"text/ch1.xhtml":   `<html><body><video poster="../images/cover.jpg" controls="controls"><source src="../media/clip.mp4" type="video/mp4"/></video></body></html>`,
+		"text/ch2.xhtml":   `<html><body><audio src="../media/song.mp3"/><video src="../media/clip.mp4"/></body></html>`,
+	})
+	defer f.Close()
+	f.opf.Manifest = append(f.opf.Manifest,
+		manifest{ID: "clip", Href: "media/clip.mp4", MediaType: "video/mp4"},
+		manifest{ID: "song", Href: "media/song.mp3", MediaType: "audio/mpeg"},
+	)
+
+	resources, err := f.MediaResources()
+	if err != nil {
+		t.Fatalf("MediaResources() return an error: %v", err)
+	}
+	if len(resources) != 2 {
+		t.Fatalf("MediaResources() return: %v", resources)
+	}
+	clip := resources[0]
+	if clip.Href != "media/clip.mp4" || clip.Size != 5 || clip.Poster != "images/cover.jpg" {
+		t.Errorf("MediaResources() return: %+v", clip)
+	}
+	if len(clip.ReferencedBy) != 2 || clip.ReferencedBy[0] != "text/ch1.xhtml" || clip.ReferencedBy[1] != "text/ch2.xhtml" {
+		t.Errorf("MediaResources() ReferencedBy: %v", clip.ReferencedBy)
+	}
+	song := resources[1]
+	if song.MediaType != "audio/mpeg" || song.Poster != "" || len(song.ReferencedBy) != 1 || song.ReferencedBy[0] != "text/ch2.xhtml" {
+		t.Errorf("MediaResources() return: %+v", song)
+	}
+}
+
+func TestMediaResourcesNone(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	resources, err := f.MediaResources()
+	if err != nil || len(resources) != 0 {
+		t.Errorf("MediaResources() return: %v, %v", resources, err)
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// sniffLen is the number of bytes needed to detect the media type
+const sniffLen = 512
+
+// mediaTypeAliases are the media types that are accepted on the manifest for
+// the content detected as the key
+var mediaTypeAliases = map[string][]string{
+	"font/ttf":  {"application/x-font-ttf", "application/x-font-truetype", "application/font-sfnt"},
+	"font/otf":  {"application/vnd.ms-opentype", "application/x-font-otf", "application/font-sfnt"},
+	"font/woff": {"application/font-woff"},
+	"video/mp4": {"audio/mp4"},
+}
+
+var mediaTypeAttrRegexp = regexp.MustCompile(`(\smedia-type\s*=\s*)("[^"]*"|'[^']*')`)
+
+// MediaTypeMismatch is a resource which content doesn't match the media type
+// declared on the manifest
+type MediaTypeMismatch struct {
+	// Href is the path of the resource, as used by OpenFile
+	Href     string
+	Declared string
+	Detected string
+}
+
+// MediaTypeMismatches returns the resources of the manifest which content
+// doesn't match their declared media type
+//
+// Only images, fonts and audio and video files are checked, the text formats
+// can't be told apart reliably from their content.
+func (e Epub) MediaTypeMismatches() ([]MediaTypeMismatch, error) {
+	var mismatches []MediaTypeMismatch
+	for _, item := range e.opf.Manifest {
+		f, err := e.open(e.rootPath + item.Href)
+		if err != nil {
+			continue
+		}
+		data, err := sniff(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		detected := DetectMediaType(data)
+		if detected == "" || matchesMediaType(detected, item.MediaType) {
+			continue
+		}
+		mismatches = append(mismatches, MediaTypeMismatch{item.Href, item.MediaType, detected})
+	}
+	return mismatches, nil
+}
+
+// FixMediaTypes returns a transform that corrects on the manifest the media
+// types reported by MediaTypeMismatches
+func (e Epub) FixMediaTypes() (Transform, error) {
+	mismatches, err := e.MediaTypeMismatches()
+	if err != nil {
+		return nil, err
+	}
+	fixes := make(map[string]string)
+	for _, m := range mismatches {
+		fixes[e.rootPath+m.Href] = m.Detected
+	}
+
+	return func(name, mediaType string, r io.Reader) (io.Reader, error) {
+		if name != e.opfPath || len(fixes) == 0 {
+			return r, nil
+		}
+		data, err := readAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(fixManifestMediaTypes(data, name, fixes)), nil
+	}, nil
+}
+
+// DetectMediaType returns the media type of a resource from the first bytes
+// of its content, or an empty string if it is not an image, font, audio or
+// video format
+func DetectMediaType(data []byte) string {
+	if len(data) >= 12 && string(data[4:8]) == "ftyp" {
+		switch string(data[8:12]) {
+		case "avif", "avis":
+			return "image/avif"
+		case "heic", "heix":
+			return "image/heic"
+		}
+	}
+	if bytes.HasPrefix(data, []byte("OggS")) && bytes.Contains(data, []byte("OpusHead")) {
+		return "audio/ogg"
+	}
+	if bytes.HasPrefix(data, []byte("\xff\x0a")) || bytes.HasPrefix(data, []byte("\x00\x00\x00\x0cJXL \r\n\x87\n")) {
+		return "image/jxl"
+	}
+
+	mediaType := http.DetectContentType(data)
+	switch {
+	case strings.HasPrefix(mediaType, "image/"),
+		strings.HasPrefix(mediaType, "font/"),
+		strings.HasPrefix(mediaType, "audio/"),
+		strings.HasPrefix(mediaType, "video/"):
+		return mediaType
+	}
+	return ""
+}
+
+func matchesMediaType(detected, declared string) bool {
+	declared = strings.ToLower(strings.TrimSpace(strings.SplitN(declared, ";", 2)[0]))
+	if declared == detected {
+		return true
+	}
+	for _, alias := range mediaTypeAliases[detected] {
+		if declared == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// sniff returns the first bytes of r
+func sniff(r io.Reader) ([]byte, error) {
+	data := make([]byte, sniffLen)
+	n, err := io.ReadFull(r, data)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+// fixManifestMediaTypes replaces on the OPF the media type of the items
+// pointing to the files of the fixes map
+func fixManifestMediaTypes(opf []byte, opfPath string, fixes map[string]string) []byte {
+	return itemTagRegexp.ReplaceAllFunc(opf, func(tag []byte) []byte {
+		mediaType, ok := fixes[resolveRef(opfPath, attrValue(string(tag), "href"))]
+		if !ok {
+			return tag
+		}
+		return mediaTypeAttrRegexp.ReplaceAll(tag, []byte(`${1}"`+mediaType+`"`))
+	})
+}
@@ -0,0 +1,70 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import "strings"
+
+const coverJPG = "@public@vhost@g@gutenberg@html@files@3174@3174-h@images@cover.jpg"
+
+func TestMediaTypeMismatches(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	mismatches, err := f.MediaTypeMismatches()
+	if err != nil {
+		t.Fatalf("MediaTypeMismatches() return an error: %v", err)
+	}
+	if len(mismatches) != 0 {
+		t.Errorf("MediaTypeMismatches() return: %v", mismatches)
+	}
+
+	f.stage(f.rootPath+coverJPG, []byte(coverPNG))
+	mismatches, _ = f.MediaTypeMismatches()
+	expected := MediaTypeMismatch{coverJPG, "image/jpeg", "image/png"}
+	if len(mismatches) != 1 || mismatches[0] != expected {
+		t.Errorf("MediaTypeMismatches() return: %v when was expected: %v", mismatches, expected)
+	}
+}
+
+func TestFixMediaTypes(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	f.stage(f.rootPath+coverJPG, []byte(coverPNG))
+	fix, err := f.FixMediaTypes()
+	if err != nil {
+		t.Fatalf("FixMediaTypes() return an error: %v", err)
+	}
+	book := repackBook(t, f, fix)
+	if mediaType := book.opf.mediaType(coverJPG); mediaType != "image/png" {
+		t.Errorf("mediaType(%v) return: %v", coverJPG, mediaType)
+	}
+	if mismatches, _ := book.MediaTypeMismatches(); len(mismatches) != 0 {
+		t.Errorf("MediaTypeMismatches() return: %v", mismatches)
+	}
+}
+
+func TestDetectMediaType(t *testing.T) {
+	tests := map[string]string{
+		coverPNG:           "image/png",
+		"wOFF\x00\x01":     "font/woff",
+		"<html></html>":    "",
+		"\xff\xd8\xff\xe0": "image/jpeg",
+		"OggS\x00\x02" + strings.Repeat("\x00", 22) + "\x01\x13OpusHead": "audio/ogg",
+	}
+	for data, expected := range tests {
+		if mediaType := DetectMediaType([]byte(data)); mediaType != expected {
+			t.Errorf("DetectMediaType(%q) return: %v when was expected: %v", data, mediaType, expected)
+		}
+	}
+	if !matchesMediaType("font/woff", "application/font-woff") {
+		t.Errorf("matchesMediaType() didn't accept the font alias")
+	}
+	if !matchesMediaType("audio/ogg", "audio/ogg; codecs=opus") {
+		t.Errorf("matchesMediaType() didn't accept the media type parameters")
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"archive/zip"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// Stats are the counters of all the epubs opened by the program
+//
+// They can be published with expvar:
+//
+//	expvar.Publish("epubgo", expvar.Func(func() interface{} {
+//		return epubgo.ReadStats()
+//	}))
+type Stats struct {
+	// Opened is the number of epubs opened or loaded, Failed the number of
+	// them that couldn't be parsed
+	Opened int64
+	Failed int64
+	// OpenTime is the total time spent parsing the epubs when opening them
+	OpenTime time.Duration
+	// BytesDecompressed is the number of bytes read from the files of the
+	// epubs
+	BytesDecompressed int64
+	// PoolHits is the number of buffers reused from the pool, PoolMisses the
+	// number of them allocated (see SetBufferPooling)
+	PoolHits   int64
+	PoolMisses int64
+}
+
+// OpenStats describes the opening of an epub
+type OpenStats struct {
+	// Duration is the time spent parsing the epub
+	Duration time.Duration
+	// Size is the size of the epub and Files the number of files of the zip
+	Size  int64
+	Files int
+	// Err is the error returned by the open, nil if it succeeded
+	Err error
+}
+
+var (
+	statOpened            int64
+	statFailed            int64
+	statOpenTime          int64
+	statBytesDecompressed int64
+	statPoolGets          int64
+	statPoolMisses        int64
+	statsFunc             atomic.Value
+)
+
+// ReadStats returns the current value of the counters
+func ReadStats() Stats {
+	gets := atomic.LoadInt64(&statPoolGets)
+	misses := atomic.LoadInt64(&statPoolMisses)
+	hits := gets - misses
+	if hits < 0 {
+		hits = 0
+	}
+	return Stats{
+		Opened:            atomic.LoadInt64(&statOpened),
+		Failed:            atomic.LoadInt64(&statFailed),
+		OpenTime:          time.Duration(atomic.LoadInt64(&statOpenTime)),
+		BytesDecompressed: atomic.LoadInt64(&statBytesDecompressed),
+		PoolHits:          hits,
+		PoolMisses:        misses,
+	}
+}
+
+// SetStatsFunc sets a function called after opening or loading each epub,
+// to feed metrics like the latency histograms of Prometheus. nil disables it.
+//
+// The function is called from the goroutine opening the epub, it should not
+// block.
+func SetStatsFunc(f func(OpenStats)) {
+	statsFunc.Store(&f)
+}
+
+// recordOpen updates the counters with the open of an epub started at start
+func recordOpen(start time.Time, size int64, files int, err error) {
+	duration := time.Since(start)
+	atomic.AddInt64(&statOpened, 1)
+	atomic.AddInt64(&statOpenTime, int64(duration))
+	if err != nil {
+		atomic.AddInt64(&statFailed, 1)
+	}
+	if f, ok := statsFunc.Load().(*func(OpenStats)); ok && *f != nil {
+		(*f)(OpenStats{duration, size, files, err})
+	}
+}
+
+// openZipFile opens a file of the zip counting the bytes decompressed
+func openZipFile(f *zip.File) (io.ReadCloser, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	return countingReadCloser{rc}, nil
+}
+
+type countingReadCloser struct {
+	io.ReadCloser
+}
+
+func (c countingReadCloser) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	atomic.AddInt64(&statBytesDecompressed, int64(n))
+	return n, err
+}
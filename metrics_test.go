@@ -0,0 +1,40 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import "bytes"
+
+func TestReadStats(t *testing.T) {
+	var opens []OpenStats
+	SetStatsFunc(func(s OpenStats) { opens = append(opens, s) })
+	defer SetStatsFunc(nil)
+
+	before := ReadStats()
+	f, err := Open(bookPath)
+	if err != nil {
+		t.Fatalf("Open() return an error: %v", err)
+	}
+	f.Close()
+	Load(bytes.NewReader([]byte("not a zip")), 9)
+
+	stats := ReadStats()
+	if stats.Opened-before.Opened != 2 || stats.Failed-before.Failed != 1 {
+		t.Errorf("ReadStats() return: %+v, before: %+v", stats, before)
+	}
+	if stats.BytesDecompressed <= before.BytesDecompressed || stats.OpenTime <= before.OpenTime {
+		t.Errorf("ReadStats() didn't count the open: %+v, before: %+v", stats, before)
+	}
+	if len(opens) != 2 {
+		t.Fatalf("The stats func was called %d times", len(opens))
+	}
+	if opens[0].Err != nil || opens[0].Files == 0 || opens[0].Size == 0 || opens[0].Duration <= 0 {
+		t.Errorf("OpenStats of the book: %+v", opens[0])
+	}
+	if opens[1].Err == nil || opens[1].Size != 9 {
+		t.Errorf("OpenStats of the invalid zip: %+v", opens[1])
+	}
+}
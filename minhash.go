@@ -0,0 +1,135 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash/fnv"
+	"strings"
+)
+
+const (
+	// minHashSize is the number of hashes of a TextFingerprint
+	minHashSize = 128
+	// shingleWords is the number of words of each shingle
+	shingleWords = 5
+)
+
+// TextFingerprint is the MinHash signature of the text of a book
+//
+// It is computed over the shingles of the words normalized with
+// NormalizeTerm, so it doesn't depend on the markup, the files or the
+// typography of the book. Two fingerprints estimate how much text their
+// books share.
+type TextFingerprint []uint64
+
+// TextFingerprint returns the fingerprint of the text of the documents of the
+// spine
+func (e Epub) TextFingerprint() (TextFingerprint, error) {
+	var tokens []IndexToken
+	for i := 0; i < e.opf.spineLength(); i++ {
+		if e.opf.spineURL(i) == "" {
+			continue
+		}
+		t, err := e.IndexTokens(i)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t...)
+	}
+	return newTextFingerprint(tokens), nil
+}
+
+// FingerprintText returns the fingerprint of the text in the language lang,
+// like TextFingerprint does with the text of a book
+func FingerprintText(text, lang string) TextFingerprint {
+	return newTextFingerprint(Tokenize(text, lang))
+}
+
+// ParseTextFingerprint parses a fingerprint formatted with String
+func ParseTextFingerprint(s string) (TextFingerprint, error) {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != minHashSize*8 {
+		return nil, errors.New("Invalid text fingerprint length")
+	}
+	f := make(TextFingerprint, minHashSize)
+	for i := range f {
+		f[i] = binary.BigEndian.Uint64(data[i*8:])
+	}
+	return f, nil
+}
+
+// String returns the fingerprint in hexadecimal, to be stored
+func (f TextFingerprint) String() string {
+	data := make([]byte, len(f)*8)
+	for i, h := range f {
+		binary.BigEndian.PutUint64(data[i*8:], h)
+	}
+	return hex.EncodeToString(data)
+}
+
+// Similarity returns the estimated proportion of shingles shared by the
+// texts of both fingerprints, their Jaccard similarity, between 0 and 1
+//
+// Copies of the same text are close to 1 even with small edits. The
+// fingerprints of empty texts are not similar to any.
+func (f TextFingerprint) Similarity(other TextFingerprint) float64 {
+	if len(f) == 0 || len(f) != len(other) {
+		return 0
+	}
+	equal := 0
+	for i := range f {
+		if f[i] == other[i] {
+			equal++
+		}
+	}
+	return float64(equal) / float64(len(f))
+}
+
+// newTextFingerprint returns the MinHash signature of the shingles of the
+// tokens, nil if there are no tokens
+//
+// Each of the minHashSize hash functions is the hash of the shingle mixed
+// with a different seed.
+func newTextFingerprint(tokens []IndexToken) TextFingerprint {
+	if len(tokens) == 0 {
+		return nil
+	}
+	f := make(TextFingerprint, minHashSize)
+	for i := range f {
+		f[i] = ^uint64(0)
+	}
+	window := shingleWords
+	if len(tokens) < window {
+		window = len(tokens)
+	}
+	terms := make([]string, window)
+	for start := 0; start+window <= len(tokens); start++ {
+		for i := range terms {
+			terms[i] = tokens[start+i].Term
+		}
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(terms, " ")))
+		sum := h.Sum64()
+		for i := range f {
+			if v := mix64(sum ^ uint64(i+1)*0x9e3779b97f4a7c15); v < f[i] {
+				f[i] = v
+			}
+		}
+	}
+	return f
+}
+
+// mix64 is the finalizer of splitmix64, it spreads the bits of x
+func mix64(x uint64) uint64 {
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+	return x ^ x>>31
+}
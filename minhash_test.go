@@ -0,0 +1,53 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import "strings"
+
+const fingerprintText = `It was a dark and stormy night; the rain fell in torrents, except at
+occasional intervals, when it was checked by a violent gust of wind which swept
+up the streets, rattling along the housetops, and fiercely agitating the scanty
+flame of the lamps that struggled against the darkness. Through one of the
+obscurest quarters of London, and among haunts little loved by the gentlemen of
+the police, a man, evidently of the lowest orders, was wending his solitary way.`
+
+func TestTextFingerprint(t *testing.T) {
+	f := buildEpub(t, "testdata/epub3.opf", map[string]string{
+		"text/ch1.xhtml": "<html><body><p>" + strings.Replace(fingerprintText, "night;", "<em>night</em>;", 1) + "</p>",
+		"text/ch2.xhtml": `<html><body><p>THE END</p></body></html>`,
+	})
+	defer f.Close()
+
+	fingerprint, err := f.TextFingerprint()
+	if err != nil {
+		t.Fatalf("TextFingerprint() return an error: %v", err)
+	}
+	if len(fingerprint) != minHashSize {
+		t.Fatalf("TextFingerprint() return: %v", fingerprint)
+	}
+	if s := fingerprint.Similarity(FingerprintText(fingerprintText+"\nThe end.", "en")); s != 1 {
+		t.Errorf("Similarity() of the same text return: %v", s)
+	}
+	edited := strings.Replace(fingerprintText, "violent", "strong", 1)
+	if s := fingerprint.Similarity(FingerprintText(edited, "en")); s < 0.6 || s == 1 {
+		t.Errorf("Similarity() of the edited text return: %v", s)
+	}
+	if s := fingerprint.Similarity(FingerprintText("A completely different text about other things, that shares nothing.", "en")); s > 0.1 {
+		t.Errorf("Similarity() of a different text return: %v", s)
+	}
+
+	parsed, err := ParseTextFingerprint(fingerprint.String())
+	if err != nil || parsed.Similarity(fingerprint) != 1 {
+		t.Errorf("ParseTextFingerprint() return: %v, %v", parsed, err)
+	}
+	if _, err := ParseTextFingerprint("abcd"); err == nil {
+		t.Errorf("ParseTextFingerprint() of a short string didn't return an error")
+	}
+	if s := FingerprintText("", "en").Similarity(FingerprintText("", "en")); s != 0 {
+		t.Errorf("Similarity() of empty texts return: %v", s)
+	}
+}
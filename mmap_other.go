@@ -0,0 +1,15 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+//go:build !unix
+
+package epubgo
+
+// OpenMmap opens an existing epub, memory mapping is only supported on unix
+// systems so it is the same as Open
+func OpenMmap(path string) (*Epub, error) {
+	return Open(path)
+}
+
+func unmap(data []byte) {}
@@ -0,0 +1,34 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import "io/ioutil"
+
+func TestOpenMmap(t *testing.T) {
+	f, err := OpenMmap(bookPath)
+	if err != nil {
+		t.Fatalf("OpenMmap(%v) return an error: %v", bookPath, err)
+	}
+	defer f.Close()
+
+	if title, _ := f.Metadata("title"); title[0] != "A Dog's Tale" {
+		t.Errorf("OpenMmap() title: %v", title)
+	}
+	file, err := f.OpenFile(htmlFile)
+	if err != nil {
+		t.Fatalf("OpenFile(%v) return an error: %v", htmlFile, err)
+	}
+	data, _ := ioutil.ReadAll(file)
+	file.Close()
+	if len(data) == 0 {
+		t.Errorf("OpenFile(%v) return an empty file", htmlFile)
+	}
+
+	if _, err := OpenMmap("testdata/missing.epub"); err == nil {
+		t.Errorf("OpenMmap() of a missing file didn't return an error")
+	}
+}
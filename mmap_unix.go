@@ -0,0 +1,52 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+//go:build unix
+
+package epubgo
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+)
+
+// OpenMmap opens an existing epub memory-mapping the file
+//
+// The files of the epub are read from the mapping without syscalls, which is
+// cheaper when scanning big libraries or reading the epub concurrently. The
+// mapping is released by Close. The file must not be truncated while it is
+// open, reading it would crash the program. If the file can't be mapped it
+// is opened like with Open.
+func OpenMmap(path string) (*Epub, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+	if size <= 0 || int64(int(size)) != size {
+		return Open(path)
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return Open(path)
+	}
+
+	e := new(Epub)
+	e.mapped = data
+	if err := e.load(bytes.NewReader(data), size); err != nil {
+		unmap(data)
+		return nil, err
+	}
+	return e, nil
+}
+
+func unmap(data []byte) {
+	syscall.Munmap(data)
+}
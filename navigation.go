@@ -51,7 +51,7 @@ func (nav NavigationIterator) HasChildren() bool {
 
 // HasParents returns whether the item has any parent sections
 func (nav NavigationIterator) HasParents() bool {
-	return nav.parents != nil
+	return len(nav.parents) > 0
 }
 
 // IsFirst returns whether the item is the first of the sections on the same depth level
@@ -111,6 +111,43 @@ func (nav *NavigationIterator) Out() error {
 	return nil
 }
 
+// Find moves the iterator to the first item of the whole navigation tree,
+// in reading order, for which match returns true
+//
+// Returns an error if no item matches, the iterator is not moved then.
+func (nav *NavigationIterator) Find(match func(title, url string) bool) error {
+	root := nav.curr.navMap
+	if len(nav.parents) > 0 {
+		root = nav.parents[0].navMap
+	}
+	path := findNavPoint(root, match)
+	if path == nil {
+		return errors.New("Navigation entry not found")
+	}
+
+	nav.parents = nil
+	nav.curr = navCursor{root, path[0]}
+	for _, index := range path[1:] {
+		nav.In()
+		nav.curr.index = index
+	}
+	return nil
+}
+
+// findNavPoint returns the indexes on each level of the first navpoint that
+// matches, or nil if none does
+func findNavPoint(navMap []navpoint, match func(title, url string) bool) []int {
+	for i, point := range navMap {
+		if match(point.Title(), point.URL()) {
+			return []int{i}
+		}
+		if path := findNavPoint(point.Children(), match); path != nil {
+			return append([]int{i}, path...)
+		}
+	}
+	return nil
+}
+
 func (nav NavigationIterator) item() *navpoint {
 	return &nav.curr.navMap[nav.curr.index]
 }
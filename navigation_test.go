@@ -99,3 +99,59 @@ func TestDepth(t *testing.T) {
 		t.Errorf("it.Title() return: %v when was expected: %v", it.Title(), firstTitle)
 	}
 }
+
+func TestFind(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	it, _ := f.Navigation()
+	err := it.Find(func(title, url string) bool { return title == childTitle })
+	if err != nil {
+		t.Errorf("it.Find() return an error: %v", err)
+	}
+	if it.Title() != childTitle {
+		t.Errorf("it.Title() return: %v when was expected: %v", it.Title(), childTitle)
+	}
+	if !it.HasParents() {
+		t.Errorf("it.HasParents() not behaving as expected")
+	}
+	it.Out()
+	if it.HasParents() {
+		t.Errorf("it.HasParents() not behaving as expected after Out")
+	}
+	title := it.Title()
+
+	err = it.Find(func(title, url string) bool { return title == "none" })
+	if err == nil {
+		t.Errorf("it.Find() didn't return an error")
+	}
+	if it.Title() != title {
+		t.Errorf("it.Find() moved the iterator to: %v", it.Title())
+	}
+}
+
+func TestFindNavPoint(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	it, err := f.FindNavPoint(firstURL)
+	if err != nil {
+		t.Fatalf("f.FindNavPoint() return an error: %v", err)
+	}
+	if it.Title() != firstTitle {
+		t.Errorf("it.Title() return: %v when was expected: %v", it.Title(), firstTitle)
+	}
+
+	path := firstURL[:len(firstURL)-len("#pgepubid00000")]
+	it, err = f.FindNavPoint(path)
+	if err != nil {
+		t.Fatalf("f.FindNavPoint() return an error: %v", err)
+	}
+	if it.Title() != firstTitle {
+		t.Errorf("it.Title() return: %v when was expected: %v", it.Title(), firstTitle)
+	}
+
+	if _, err := f.FindNavPoint("missing.html"); err == nil {
+		t.Errorf("f.FindNavPoint() didn't return an error")
+	}
+}
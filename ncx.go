@@ -9,9 +9,29 @@ import (
 )
 
 type xmlNCX struct {
-	NavMap []navpoint `xml:"navMap>navPoint"`
+	Meta      []ncxMeta    `xml:"head>meta"`
+	DocTitle  string       `xml:"docTitle>text"`
+	DocAuthor []string     `xml:"docAuthor>text"`
+	NavMap    []navpoint   `xml:"navMap>navPoint"`
+	PageList  []pageTarget `xml:"pageList>pageTarget"`
+	NavLists  []ncxNavList `xml:"navList"`
+}
+type ncxMeta struct {
+	Name    string `xml:"name,attr"`
+	Content string `xml:"content,attr"`
+}
+type ncxNavList struct {
+	ID      string       `xml:"id,attr"`
+	Class   string       `xml:"class,attr"`
+	Label   string       `xml:"navLabel>text"`
+	Targets []pageTarget `xml:"navTarget"`
+}
+type pageTarget struct {
+	Text    string  `xml:"navLabel>text"`
+	Content content `xml:"content"`
 }
 type navpoint struct {
+	ID       string     `xml:"id,attr"`
 	Text     string     `xml:"navLabel>text"`
 	Content  content    `xml:"content"`
 	NavPoint []navpoint `xml:"navPoint"`
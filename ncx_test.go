@@ -8,6 +8,7 @@ import "testing"
 
 import (
 	"os"
+	"strings"
 )
 
 const (
@@ -23,3 +24,32 @@ func TestNbspNcx(t *testing.T) {
 		t.Errorf("parseNCX(%v) with encoding problems return an error: %v", nbspNCX, err)
 	}
 }
+
+func TestDeepNcx(t *testing.T) {
+	const depth = 5000
+	ncx := `<ncx><navMap>` +
+		strings.Repeat(`<navPoint><navLabel><text>x</text></navLabel><content src="a.html"/>`, depth) +
+		strings.Repeat(`</navPoint>`, depth) + `</navMap></ncx>`
+	n, err := parseNCX(strings.NewReader(ncx))
+	if err != nil {
+		t.Fatalf("parseNCX() return an error: %v", err)
+	}
+	if entries := tocEntries(n.NavMap, "toc.ncx", "page.html"); len(entries) != 1 {
+		t.Errorf("tocEntries() return %d entries", len(entries))
+	}
+	if navMap := n.marshalNavMap(""); strings.Count(navMap, "<navPoint") != depth {
+		t.Errorf("marshalNavMap() lost navPoints")
+	}
+
+	tooDeep := strings.Repeat("<navPoint>", 100000)
+	if _, err := parseNCX(strings.NewReader("<ncx><navMap>" + tooDeep)); err == nil {
+		t.Errorf("parseNCX() didn't return an error with a too deep NCX")
+	}
+}
+
+func TestBigNcx(t *testing.T) {
+	big := `<ncx><navMap><navPoint id="` + strings.Repeat("x", maxXMLSize) + `"/></navMap></ncx>`
+	if _, err := parseNCX(strings.NewReader(big)); err == nil {
+		t.Errorf("parseNCX() didn't return an error with a too big NCX")
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// The ways the NCX is found, on NCXInfo.Source
+const (
+	// NCXFromSpineToc is the item of the manifest referenced by the toc
+	// attribute of the spine
+	NCXFromSpineToc = "spine-toc"
+	// NCXFromMediaType is the item of the manifest with the NCX media type,
+	// used if the spine has no toc attribute or it is not on the manifest
+	NCXFromMediaType = "media-type"
+	// NCXFromID is the item of the manifest with the id "ncx", used if no
+	// item has the NCX media type
+	NCXFromID = "id"
+)
+
+// NCXInfo is the metadata of the NCX
+type NCXInfo struct {
+	// Path is the path of the NCX, as used by OpenFile
+	Path string
+	// Source is how the NCX was found on the manifest, one of the NCXFrom
+	// constants
+	Source string
+	// UID is the dtb:uid, that should match the unique identifier of the OPF
+	UID string
+	// Depth is the dtb:depth, the number of levels of the navMap
+	Depth          int
+	TotalPageCount int
+	MaxPageNumber  int
+	Title          string
+	Authors        []string
+	// Meta are all the meta elements of the head by name
+	Meta map[string]string
+}
+
+// NCXInfo returns the metadata of the head of the NCX and its docTitle and
+// docAuthor
+//
+// Returns an error if the epub has no NCX.
+func (e Epub) NCXInfo() (NCXInfo, error) {
+	var info NCXInfo
+	if e.ncx == nil {
+		return info, errors.New("The epub has no NCX")
+	}
+
+	info.Path, info.Source = e.opf.ncxSource()
+	info.Meta = make(map[string]string)
+	for _, meta := range e.ncx.Meta {
+		info.Meta[meta.Name] = meta.Content
+	}
+	info.UID = info.Meta["dtb:uid"]
+	info.Depth, _ = strconv.Atoi(strings.TrimSpace(info.Meta["dtb:depth"]))
+	info.TotalPageCount, _ = strconv.Atoi(strings.TrimSpace(info.Meta["dtb:totalPageCount"]))
+	info.MaxPageNumber, _ = strconv.Atoi(strings.TrimSpace(info.Meta["dtb:maxPageNumber"]))
+	info.Title = strings.TrimSpace(e.ncx.DocTitle)
+	for _, author := range e.ncx.DocAuthor {
+		if author = strings.TrimSpace(author); author != "" {
+			info.Authors = append(info.Authors, author)
+		}
+	}
+	return info, nil
+}
+
+// SpineToc returns the toc attribute of the spine, the id of the NCX on the
+// manifest
+//
+// It is empty on most EPUB 3 books. NCXInfo tells how the NCX was found if
+// the attribute is missing or wrong.
+func (e Epub) SpineToc() string {
+	return e.opf.Spine.Toc
+}
@@ -0,0 +1,63 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+func TestNCXInfo(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	info, err := f.NCXInfo()
+	if err != nil {
+		t.Fatalf("NCXInfo() return an error: %v", err)
+	}
+	if info.UID != "http://www.gutenberg.org/ebooks/3174" || info.Depth != 3 || info.TotalPageCount != 0 {
+		t.Errorf("Wrong NCX head: %v", info)
+	}
+	if info.Title != "A Dog's Tale" || len(info.Authors) != 0 {
+		t.Errorf("Wrong NCX title or authors: %v", info)
+	}
+	if info.Meta["dtb:generator"] == "" {
+		t.Errorf("The NCX meta are missing: %v", info.Meta)
+	}
+	if info.Path != "toc.ncx" || info.Source != NCXFromSpineToc || f.SpineToc() != "ncx" {
+		t.Errorf("Wrong NCX source: %v %v %v", info.Path, info.Source, f.SpineToc())
+	}
+
+	invalid, _ := Open(invalidNCXPath)
+	defer invalid.Close()
+	if info, _ := invalid.NCXInfo(); info.Source != NCXFromMediaType || invalid.SpineToc() != "notvalid" {
+		t.Errorf("Wrong NCX source with an invalid spine toc: %v", info.Source)
+	}
+
+	book := buildEpub(t, "testdata/epub3.opf", nil)
+	if _, err := book.NCXInfo(); err == nil {
+		t.Errorf("NCXInfo() didn't return an error without NCX")
+	}
+}
+
+func TestNCXSource(t *testing.T) {
+	ncx := manifest{ID: "navigation-control", Href: "nav.ncx", MediaType: "application/x-dtbncx+xml"}
+	other := manifest{ID: "toc", Href: "other.ncx", MediaType: "application/x-dtbncx+xml"}
+	legacy := manifest{ID: "ncx", Href: "legacy.ncx", MediaType: "text/xml"}
+	tests := []struct {
+		opf    xmlOPF
+		path   string
+		source string
+	}{
+		{xmlOPF{Manifest: []manifest{other, ncx}, Spine: spine{Toc: "navigation-control"}}, "nav.ncx", NCXFromSpineToc},
+		{xmlOPF{Manifest: []manifest{legacy, ncx}, Spine: spine{Toc: " navigation-control "}}, "nav.ncx", NCXFromSpineToc},
+		{xmlOPF{Manifest: []manifest{legacy, ncx}, Spine: spine{Toc: "missing"}}, "nav.ncx", NCXFromMediaType},
+		{xmlOPF{Manifest: []manifest{legacy, ncx}}, "nav.ncx", NCXFromMediaType},
+		{xmlOPF{Manifest: []manifest{legacy}}, "legacy.ncx", NCXFromID},
+		{xmlOPF{}, "", ""},
+	}
+	for i, test := range tests {
+		if path, source := test.opf.ncxSource(); path != test.path || source != test.source {
+			t.Errorf("ncxSource() of %d return: %v, %v", i, path, source)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	romanNumeralRegexp = regexp.MustCompile(`^X{0,3}(?:IX|IV|V?I{0,3})$`)
+	titleAuthorRegexp  = regexp.MustCompile(`(?i)\s+(?:by|[-–—:])\s+`)
+)
+
+// englishSmallWords are the words kept lowercase inside the English titles
+var englishSmallWords = []string{
+	"a", "an", "the", "and", "but", "or", "nor", "for", "so", "yet", "as",
+	"at", "by", "in", "of", "off", "on", "per", "to", "up", "via", "vs",
+}
+
+// NormalizeOptions configures NormalizeMetadata
+type NormalizeOptions struct {
+	// CollapseWhitespace trims the values and collapses their whitespace,
+	// the lines of the descriptions are kept
+	CollapseWhitespace bool
+	// FixCapitals converts the titles written in all capitals to title case
+	FixCapitals bool
+	// StripTitleAuthor removes the authors appended to the titles, like
+	// "Title / Author Name"
+	StripTitleAuthor bool
+	// StripDescriptionHTML converts the description to plain text, a line
+	// per paragraph
+	StripDescriptionHTML bool
+	// SmallWords are the words kept lowercase by FixCapitals, as in
+	// TitleCase. By default the English ones if the book is in English.
+	SmallWords []string
+}
+
+// DefaultNormalizeOptions enables all the normalizers
+var DefaultNormalizeOptions = NormalizeOptions{
+	CollapseWhitespace:   true,
+	FixCapitals:          true,
+	StripTitleAuthor:     true,
+	StripDescriptionHTML: true,
+}
+
+// MetadataChange is a metadata value modified by NormalizeMetadata
+type MetadataChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// NormalizeMetadata cleans up the metadata values with the normalizers
+// enabled on opts
+//
+// It returns the values modified. The changes are written with Repack.
+func (e *Epub) NormalizeMetadata(opts NormalizeOptions) []MetadataChange {
+	smallWords := opts.SmallWords
+	if smallWords == nil && baseLang(e.language()) != "en" {
+		smallWords = []string{}
+	}
+	var authors []string
+	for _, field := range []string{"creator", "contributor"} {
+		for _, elem := range e.metadata[field] {
+			authors = append(authors, elem.Content, elem.Attr["file-as"], e.metadata.refinement(elem.Attr["id"], "file-as"))
+		}
+	}
+
+	var changes []MetadataChange
+	for _, field := range metadataFieldOrder {
+		for i, elem := range e.metadata[field] {
+			value := elem.Content
+			switch field {
+			case "title":
+				if opts.StripTitleAuthor {
+					value = StripTitleAuthor(value, authors)
+				}
+				if opts.FixCapitals && isAllCaps(value) {
+					value = TitleCase(value, smallWords)
+				}
+			case "description":
+				if opts.StripDescriptionHTML {
+					value = StripHTML(value)
+				}
+			}
+			if opts.CollapseWhitespace {
+				if field == "description" {
+					value = collapseLines(value)
+				} else {
+					value = CollapseWhitespace(value)
+				}
+			}
+			if value != elem.Content {
+				changes = append(changes, MetadataChange{field, elem.Content, value})
+				e.metadata[field][i].Content = value
+			}
+		}
+	}
+	return changes
+}
+
+// CollapseWhitespace trims the text and replaces each run of whitespace by a
+// single space
+func CollapseWhitespace(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// StripHTML returns the text of the HTML markup, with the entities decoded
+// and each paragraph on its own line
+//
+// A text without tags is returned as it is.
+func StripHTML(markup string) string {
+	if !descTagRegexp.MatchString(markup) {
+		return markup
+	}
+	return extractText([]byte(markup))
+}
+
+// TitleCase capitalizes the first letter of each word of the title and
+// lowercases the rest
+//
+// The smallWords are kept lowercase unless they start the title or follow a
+// colon, nil means the English ones. The roman numerals written in capitals
+// are kept, so "WORLD WAR II" becomes "World War II".
+func TitleCase(title string, smallWords []string) string {
+	if smallWords == nil {
+		smallWords = englishSmallWords
+	}
+	words := strings.Fields(title)
+	for i, word := range words {
+		lower := strings.ToLower(word)
+		bare := strings.TrimFunc(lower, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsNumber(r) })
+		startsPhrase := i == 0 || strings.HasSuffix(words[i-1], ":")
+		switch {
+		case romanNumeralRegexp.MatchString(strings.TrimFunc(word, unicode.IsPunct)) && bare != "":
+			continue
+		case !startsPhrase && i != len(words)-1 && contains(smallWords, bare):
+			words[i] = lower
+		default:
+			words[i] = capitalizeWord(lower)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// StripTitleAuthor removes from the title a trailing statement of
+// responsibility, like "Title / Author Name"
+//
+// Everything after " / " is removed. The endings " by Author", " - Author"
+// and ": Author" are only removed if Author is one of the authors, compared
+// without case.
+func StripTitleAuthor(title string, authors []string) string {
+	if i := strings.LastIndex(title, " / "); i > 0 {
+		return strings.TrimSpace(title[:i])
+	}
+	locs := titleAuthorRegexp.FindAllStringIndex(title, -1)
+	for i := len(locs) - 1; i >= 0; i-- {
+		suffix := strings.ToLower(CollapseWhitespace(title[locs[i][1]:]))
+		for _, author := range authors {
+			if author = strings.ToLower(CollapseWhitespace(author)); author != "" && author == suffix {
+				return strings.TrimSpace(title[:locs[i][0]])
+			}
+		}
+	}
+	return title
+}
+
+// isAllCaps returns whether the text has letters and all of them are
+// capitals
+func isAllCaps(text string) bool {
+	hasUpper := false
+	for _, r := range text {
+		if unicode.IsLower(r) {
+			return false
+		}
+		if unicode.IsUpper(r) {
+			hasUpper = true
+		}
+	}
+	return hasUpper
+}
+
+// capitalizeWord capitalizes the first letter of the word and of each part
+// of it after a hyphen
+func capitalizeWord(word string) string {
+	parts := strings.Split(word, "-")
+	for i, part := range parts {
+		for j, r := range part {
+			if unicode.IsLetter(r) {
+				parts[i] = part[:j] + string(unicode.ToTitle(r)) + part[j+utf8.RuneLen(r):]
+				break
+			}
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// collapseLines collapses the whitespace of each line of the text and drops
+// the empty lines
+func collapseLines(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = CollapseWhitespace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,66 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+func TestTitleCase(t *testing.T) {
+	tests := map[string]string{
+		"THE LORD OF THE RINGS":         "The Lord of the Rings",
+		"WORLD WAR II: THE LAST DAYS":   "World War II: The Last Days",
+		"SELF-HELP FOR THE ANXIOUS":     "Self-Help for the Anxious",
+		"DON'T LOOK UP":                 "Don't Look Up",
+		"WHAT THE CAT SAT ON":           "What the Cat Sat On",
+		"\"ÉLAN\" AND THE (LONG) NIGHT": "\"Élan\" and the (Long) Night",
+	}
+	for title, expected := range tests {
+		if result := TitleCase(title, nil); result != expected {
+			t.Errorf("TitleCase(%q) return: %q", title, result)
+		}
+	}
+	if result := TitleCase("LE TEMPS DES CERISES", []string{"des"}); result != "Le Temps des Cerises" {
+		t.Errorf("TitleCase() return: %q", result)
+	}
+}
+
+func TestStripTitleAuthor(t *testing.T) {
+	authors := []string{"J. R. R. Tolkien", "Tolkien, J. R. R."}
+	tests := map[string]string{
+		"The Hobbit / J.R.R. Tolkien ; illustrated": "The Hobbit",
+		"The Hobbit by J. R. R.  Tolkien":           "The Hobbit",
+		"The Hobbit - Tolkien, J. R. R.":            "The Hobbit",
+		"The Hobbit: There and Back Again":          "The Hobbit: There and Back Again",
+		"Stand by Me":                               "Stand by Me",
+	}
+	for title, expected := range tests {
+		if result := StripTitleAuthor(title, authors); result != expected {
+			t.Errorf("StripTitleAuthor(%q) return: %q", title, result)
+		}
+	}
+}
+
+func TestNormalizeMetadata(t *testing.T) {
+	f := buildEpub(t, epub3OPF, nil)
+	f.metadata["title"][0].Content = "THE LORD  OF THE RINGS by J. R. R. Tolkien"
+	f.metadata["publisher"][0].Content = "  Allen &\n Unwin "
+	f.SetMetadata("description", []MdataElement{{Content: "<p>A <b>great</b>\n  book.</p><p>Really &amp; truly.</p>"}})
+
+	changes := f.NormalizeMetadata(DefaultNormalizeOptions)
+	if len(changes) != 3 {
+		t.Errorf("NormalizeMetadata() return: %v", changes)
+	}
+	if title := f.metadata["title"][0].Content; title != "The Lord of the Rings" {
+		t.Errorf("The title is: %q", title)
+	}
+	if publisher := f.metadata["publisher"][0].Content; publisher != "Allen & Unwin" {
+		t.Errorf("The publisher is: %q", publisher)
+	}
+	if description := f.Description(); description != "A great book.\nReally & truly." {
+		t.Errorf("The description is: %q", description)
+	}
+	if changes := f.NormalizeMetadata(DefaultNormalizeOptions); len(changes) != 0 {
+		t.Errorf("NormalizeMetadata() is not idempotent: %v", changes)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package objectstore
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// HTTPObject is a RangeReader of a file served by HTTP, the server must
+// support range requests
+type HTTPObject struct {
+	// Client is the HTTP client used, http.DefaultClient if nil
+	Client *http.Client
+	URL    string
+}
+
+// Size returns the size of the file from the Content-Length of a HEAD request
+func (o HTTPObject) Size() (int64, error) {
+	resp, err := o.client().Head(o.URL)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, errors.New("HEAD " + o.URL + ": " + resp.Status)
+	}
+	if resp.ContentLength < 0 {
+		return 0, errors.New("HEAD " + o.URL + ": unknown size")
+	}
+	return resp.ContentLength, nil
+}
+
+// ReadRange implements RangeReader
+func (o HTTPObject) ReadRange(offset, length int64) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, o.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-"+strconv.FormatInt(offset+length-1, 10))
+	resp, err := o.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			return nil, errors.New("GET " + o.URL + ": the server doesn't support range requests")
+		}
+		return nil, errors.New("GET " + o.URL + ": " + resp.Status)
+	}
+	return resp.Body, nil
+}
+
+func (o HTTPObject) client() *http.Client {
+	if o.Client == nil {
+		return http.DefaultClient
+	}
+	return o.Client
+}
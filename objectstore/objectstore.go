@@ -0,0 +1,217 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+/*
+Package objectstore reads epubs from object stores (S3, GCS, HTTP servers,
+...) without downloading them.
+
+A ReaderAt fetches the byte ranges read by epubgo.Load in blocks and caches
+them, so opening an epub only downloads the end of the zip (the central
+directory) and the files read. The object store is accessed through the
+RangeReader interface, for S3 with the AWS SDK it can be:
+
+	type s3Object struct {
+		client *s3.Client
+		bucket, key string
+	}
+
+	func (o s3Object) ReadRange(offset, length int64) (io.ReadCloser, error) {
+		out, err := o.client.GetObject(context.TODO(), &s3.GetObjectInput{
+			Bucket: &o.bucket,
+			Key:    &o.key,
+			Range:  aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return out.Body, nil
+	}
+
+	r := objectstore.NewReaderAt(s3Object{client, bucket, key}, size, objectstore.Options{})
+	book, err := epubgo.Load(r, size)
+
+HTTPObject implements RangeReader for HTTP servers supporting range requests.
+*/
+package objectstore
+
+import (
+	"container/list"
+	"errors"
+	"io"
+	"sync"
+)
+
+const (
+	defaultBlockSize = 256 << 10
+	defaultMaxBlocks = 64
+)
+
+// RangeReader reads byte ranges of an object
+type RangeReader interface {
+	// ReadRange returns the length bytes of the object from offset
+	ReadRange(offset, length int64) (io.ReadCloser, error)
+}
+
+// Options configures a ReaderAt
+type Options struct {
+	// BlockSize is the size of the ranges fetched and cached, 256 KB by
+	// default
+	BlockSize int64
+	// MaxBlocks is the number of blocks cached, 64 by default
+	MaxBlocks int
+}
+
+// ReaderAt is an io.ReaderAt over an object of an object store
+//
+// The reads are aligned to blocks and the contiguous blocks missing from the
+// cache are fetched in a single request. The zip files are read from the end
+// (the end of central directory record and the central directory) and then
+// file by file, the least recently used blocks are evicted first so the
+// central directory stays cached. It is safe for concurrent use.
+type ReaderAt struct {
+	obj       RangeReader
+	size      int64
+	blockSize int64
+	maxBlocks int
+
+	mu     sync.Mutex
+	blocks map[int64]*list.Element
+	lru    *list.List
+	stats  Stats
+}
+
+// Stats are the counters of the cache of a ReaderAt
+type Stats struct {
+	// Hits and Misses are the number of blocks read found or not on the
+	// cache
+	Hits   int64
+	Misses int64
+	// Requests is the number of ranges fetched and BytesFetched their size
+	Requests     int64
+	BytesFetched int64
+}
+
+type block struct {
+	index int64
+	data  []byte
+}
+
+// NewReaderAt returns a ReaderAt of the object of size bytes
+func NewReaderAt(obj RangeReader, size int64, opts Options) *ReaderAt {
+	if opts.BlockSize <= 0 {
+		opts.BlockSize = defaultBlockSize
+	}
+	if opts.MaxBlocks <= 0 {
+		opts.MaxBlocks = defaultMaxBlocks
+	}
+	return &ReaderAt{
+		obj:       obj,
+		size:      size,
+		blockSize: opts.BlockSize,
+		maxBlocks: opts.MaxBlocks,
+		blocks:    make(map[int64]*list.Element),
+		lru:       list.New(),
+	}
+}
+
+// Size returns the size of the object
+func (r *ReaderAt) Size() int64 {
+	return r.size
+}
+
+// Stats returns the counters of the cache
+func (r *ReaderAt) Stats() Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stats
+}
+
+// ReadAt implements io.ReaderAt
+func (r *ReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("Negative offset")
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	end := off + int64(len(p))
+	if end > r.size {
+		end = r.size
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	first, last := off/r.blockSize, (end-1)/r.blockSize
+	fetched := first - 1
+	for index := first; index <= last; {
+		if _, ok := r.blocks[index]; !ok {
+			missing := index
+			for missing+1 <= last {
+				if _, ok := r.blocks[missing+1]; ok {
+					break
+				}
+				missing++
+			}
+			if err := r.fetch(index, missing); err != nil {
+				return n, err
+			}
+			r.stats.Misses += missing - index + 1
+			fetched = missing
+		}
+		for ; index <= last; index++ {
+			elem, ok := r.blocks[index]
+			if !ok {
+				break
+			}
+			if index > fetched {
+				r.stats.Hits++
+			}
+			r.lru.MoveToFront(elem)
+			data := elem.Value.(*block).data
+			start := off + int64(n) - index*r.blockSize
+			n += copy(p[n:], data[start:])
+		}
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// fetch reads the blocks from first to last in one request
+func (r *ReaderAt) fetch(first, last int64) error {
+	offset := first * r.blockSize
+	length := (last+1)*r.blockSize - offset
+	if offset+length > r.size {
+		length = r.size - offset
+	}
+	r.stats.Requests++
+	r.stats.BytesFetched += length
+	rc, err := r.obj.ReadRange(offset, length)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	data := make([]byte, length)
+	if _, err := io.ReadFull(rc, data); err != nil {
+		return err
+	}
+
+	for index := first; index <= last; index++ {
+		start := (index - first) * r.blockSize
+		stop := start + r.blockSize
+		if stop > length {
+			stop = length
+		}
+		r.blocks[index] = r.lru.PushFront(&block{index, data[start:stop:stop]})
+	}
+	// keep at least the blocks just fetched
+	for r.lru.Len() > r.maxBlocks && r.lru.Len() > int(last-first+1) {
+		oldest := r.lru.Back()
+		r.lru.Remove(oldest)
+		delete(r.blocks, oldest.Value.(*block).index)
+	}
+	return nil
+}
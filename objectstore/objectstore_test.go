@@ -0,0 +1,102 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package objectstore
+
+import "testing"
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/meskio/epubgo"
+)
+
+const bookPath = "../testdata/a_dogs_tale.epub"
+
+type countingObject struct {
+	data     []byte
+	requests int
+	read     int64
+}
+
+func (o *countingObject) ReadRange(offset, length int64) (io.ReadCloser, error) {
+	o.requests++
+	o.read += length
+	return ioutil.NopCloser(bytes.NewReader(o.data[offset : offset+length])), nil
+}
+
+func TestReaderAt(t *testing.T) {
+	data, _ := ioutil.ReadFile(bookPath)
+	obj := &countingObject{data: data}
+	r := NewReaderAt(obj, int64(len(data)), Options{BlockSize: 1024, MaxBlocks: 8})
+
+	for _, test := range []struct{ off, length int64 }{
+		{0, 10}, {1000, 100}, {int64(len(data)) - 50, 50}, {5000, 4000}, {0, int64(len(data))},
+	} {
+		p := make([]byte, test.length)
+		n, err := r.ReadAt(p, test.off)
+		if err != nil || int64(n) != test.length {
+			t.Fatalf("ReadAt(%v, %v) return: %v, %v", test.length, test.off, n, err)
+		}
+		if !bytes.Equal(p, data[test.off:test.off+test.length]) {
+			t.Errorf("ReadAt(%v, %v) return wrong data", test.length, test.off)
+		}
+	}
+
+	requests := obj.requests
+	r.ReadAt(make([]byte, 10), int64(len(data))-10)
+	if obj.requests != requests {
+		t.Errorf("ReadAt() didn't use the cache")
+	}
+	stats := r.Stats()
+	if stats.Requests != int64(obj.requests) || stats.BytesFetched != obj.read || stats.Hits == 0 || stats.Misses == 0 {
+		t.Errorf("Stats() return: %+v", stats)
+	}
+	if n, err := r.ReadAt(make([]byte, 20), int64(len(data))-10); n != 10 || err != io.EOF {
+		t.Errorf("ReadAt() past the end return: %v, %v", n, err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	data, _ := ioutil.ReadFile(bookPath)
+	obj := &countingObject{data: data}
+	r := NewReaderAt(obj, int64(len(data)), Options{BlockSize: 4096})
+
+	book, err := epubgo.Load(r, r.Size())
+	if err != nil {
+		t.Fatalf("Load() return an error: %v", err)
+	}
+	if title, _ := book.Metadata("title"); title[0] != "A Dog's Tale" {
+		t.Errorf("Load() title: %v", title)
+	}
+	if obj.read >= int64(len(data)) {
+		t.Errorf("Load() read the whole object: %v bytes", obj.read)
+	}
+}
+
+func TestHTTPObject(t *testing.T) {
+	data, _ := ioutil.ReadFile(bookPath)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.ServeContent(w, req, "book.epub", time.Time{}, bytes.NewReader(data))
+	}))
+	defer server.Close()
+
+	obj := HTTPObject{URL: server.URL}
+	size, err := obj.Size()
+	if err != nil || size != int64(len(data)) {
+		t.Fatalf("Size() return: %v, %v", size, err)
+	}
+	book, err := epubgo.Load(NewReaderAt(obj, size, Options{}), size)
+	if err != nil {
+		t.Fatalf("Load() return an error: %v", err)
+	}
+	if title, _ := book.Metadata("title"); title[0] != "A Dog's Tale" {
+		t.Errorf("Load() title: %v", title)
+	}
+}
@@ -5,6 +5,7 @@
 package epubgo
 
 import (
+	"encoding/xml"
 	"errors"
 	"io"
 	"reflect"
@@ -12,52 +13,165 @@ import (
 )
 
 type xmlOPF struct {
+	Version  string     `xml:"version,attr"`
+	Prefix   string     `xml:"prefix,attr"`
+	Lang     string     `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
 	Metadata meta       `xml:"metadata"`
 	Manifest []manifest `xml:"manifest>item"`
 	Spine    spine      `xml:"spine"`
+	Guide    []guideRef `xml:"guide>reference"`
+	Tours    []xmlTour  `xml:"tours>tour"`
+
+	// UniqueIdentifier is the id of the dc:identifier of the book
+	UniqueIdentifier string `xml:"unique-identifier,attr"`
 }
 type meta struct {
-	Title       []string     `xml:"title"`
-	Language    []string     `xml:"language"`
+	Title       []dcElement  `xml:"title"`
+	Language    []dcElement  `xml:"language"`
 	Identifier  []identifier `xml:"identifier"`
 	Creator     []author     `xml:"creator"`
-	Subject     []string     `xml:"subject"`
-	Description []string     `xml:"description"`
-	Publisher   []string     `xml:"publisher"`
+	Subject     []dcElement  `xml:"subject"`
+	Description []dcElement  `xml:"description"`
+	Publisher   []dcElement  `xml:"publisher"`
 	Contributor []author     `xml:"contributor"`
 	Date        []date       `xml:"date"`
-	Type        []string     `xml:"type"`
-	Format      []string     `xml:"format"`
-	Source      []string     `xml:"source"`
-	Relation    []string     `xml:"relation"`
-	Coverage    []string     `xml:"coverage"`
-	Rights      []string     `xml:"rights"`
+	Type        []dcElement  `xml:"type"`
+	Format      []dcElement  `xml:"format"`
+	Source      []dcElement  `xml:"source"`
+	Relation    []dcElement  `xml:"relation"`
+	Coverage    []dcElement  `xml:"coverage"`
+	Rights      []dcElement  `xml:"rights"`
 	Meta        []metafield  `xml:"meta"`
+	// extensions are the elements that are not Dublin Core or meta
+	extensions []ExtensionElement
+}
+type dcElement struct {
+	Data      string            `xml:",chardata"`
+	ID        string            `xml:"id,attr"`
+	Lang      string            `xml:"lang,attr"`
+	Index     int               `xml:"-"`
+	Qualified map[string]string `xml:"-"`
 }
 type identifier struct {
-	Data   string `xml:",chardata"`
-	ID     string `xml:"id,attr"`
-	Scheme string `xml:"scheme,attr"`
+	Data      string            `xml:",chardata"`
+	ID        string            `xml:"id,attr"`
+	Scheme    string            `xml:"scheme,attr"`
+	Index     int               `xml:"-"`
+	Qualified map[string]string `xml:"-"`
 }
 type author struct {
-	Data   string `xml:",chardata"`
-	FileAs string `xml:"file-as,attr"`
-	Role   string `xml:"role,attr"`
+	Data      string            `xml:",chardata"`
+	ID        string            `xml:"id,attr"`
+	FileAs    string            `xml:"file-as,attr"`
+	Role      string            `xml:"role,attr"`
+	Index     int               `xml:"-"`
+	Qualified map[string]string `xml:"-"`
 }
 type date struct {
 	// TODO: convert date to date type?
-	Data  string `xml:",chardata"`
-	Event string `xml:"event,attr"`
+	Data      string            `xml:",chardata"`
+	Event     string            `xml:"event,attr"`
+	Index     int               `xml:"-"`
+	Qualified map[string]string `xml:"-"`
 }
 type metafield struct {
-	Name    string `xml:"name,attr"`
-	Content string `xml:"content,attr"`
+	Data      string            `xml:",chardata"`
+	Name      string            `xml:"name,attr"`
+	Content   string            `xml:"content,attr"`
+	ID        string            `xml:"id,attr"`
+	Property  string            `xml:"property,attr"`
+	Refines   string            `xml:"refines,attr"`
+	Scheme    string            `xml:"scheme,attr"`
+	Index     int               `xml:"-"`
+	Qualified map[string]string `xml:"-"`
+}
+
+// UnmarshalXML decodes the metadata keeping the position of each element
+// on the Index field
+//
+// The dc-metadata and x-metadata groups and the capitalized elements of the
+// OEB 1.x packages are read as the EPUB ones.
+func (m *meta) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	v := reflect.ValueOf(m).Elem()
+	fields := make(map[string]reflect.Value)
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).PkgPath == "" {
+			fields[v.Type().Field(i).Tag.Get("xml")] = v.Field(i)
+		}
+	}
+
+	index := 0
+	wrappers := 0
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if isMetadataWrapper(t.Name.Local) {
+				wrappers++
+				break
+			}
+			field, ok := fields[legacyFieldName(t.Name.Space, t.Name.Local)]
+			if !ok {
+				var ext struct {
+					Data string `xml:",chardata"`
+				}
+				if err := d.DecodeElement(&ext, &t); err != nil {
+					return err
+				}
+				m.extensions = append(m.extensions, ExtensionElement{
+					Namespace: t.Name.Space,
+					Name:      t.Name.Local,
+					Content:   strings.TrimSpace(ext.Data),
+					Attr:      extensionAttrs(t.Attr),
+					Index:     index,
+				})
+				index++
+				break
+			}
+			element := reflect.New(field.Type().Elem())
+			if err := d.DecodeElement(element.Interface(), &t); err != nil {
+				return err
+			}
+			element.Elem().FieldByName("Index").SetInt(int64(index))
+			element.Elem().FieldByName("Qualified").Set(reflect.ValueOf(qualifiedAttrs(t.Attr)))
+			field.Set(reflect.Append(field, element.Elem()))
+			index++
+		case xml.EndElement:
+			if wrappers > 0 {
+				wrappers--
+				break
+			}
+			return nil
+		}
+	}
+}
+
+// qualifiedAttrs returns the attributes with namespace by their qualified
+// name, like "opf:role" or "xml:lang"
+func qualifiedAttrs(attrs []xml.Attr) map[string]string {
+	qualified := make(map[string]string)
+	for _, attr := range attrs {
+		switch attr.Name.Space {
+		case "", "xmlns":
+		case opfNamespace:
+			qualified["opf:"+attr.Name.Local] = attr.Value
+		case xmlNamespace:
+			qualified["xml:"+attr.Name.Local] = attr.Value
+		default:
+			qualified[attr.Name.Space+":"+attr.Name.Local] = attr.Value
+		}
+	}
+	return qualified
 }
+
 type manifest struct {
 	ID           string `xml:"id,attr"`
 	Href         string `xml:"href,attr"`
 	MediaType    string `xml:"media-type,attr"`
-	Fallback     string `xml:"media-fallback,attr"`
+	Fallback     string `xml:"fallback,attr"`
 	Properties   string `xml:"properties,attr"`
 	MediaOverlay string `xml:"media-overlay,attr"`
 }
@@ -65,6 +179,7 @@ type spine struct {
 	ID              string      `xml:"id,attr"`
 	Toc             string      `xml:"toc,attr"`
 	PageProgression string      `xml:"page-progression-direction,attr"`
+	PageMap         string      `xml:"page-map,attr"`
 	Items           []spineItem `xml:"itemref"`
 }
 type spineItem struct {
@@ -74,6 +189,12 @@ type spineItem struct {
 	Properties string `xml:"properties,attr"`
 }
 
+type guideRef struct {
+	Type  string `xml:"type,attr"`
+	Title string `xml:"title,attr"`
+	Href  string `xml:"href,attr"`
+}
+
 func parseOPF(opf io.Reader) (*xmlOPF, error) {
 	var o xmlOPF
 	err := decodeXML(opf, &o)
@@ -81,20 +202,37 @@ func parseOPF(opf io.Reader) (*xmlOPF, error) {
 		return nil, err
 	}
 
+	o.upgradeMediaTypes()
 	return &o, nil
 }
 
 func (opf xmlOPF) ncxPath() string {
-	if opf.Spine.Toc != "" {
-		fileID := opf.Spine.Toc
-		path := opf.filePath(fileID)
-		if path != "" {
-			return path
+	path, _ := opf.ncxSource()
+	return path
+}
+
+// ncxSource returns the path of the NCX and how it was found, one of the
+// NCXFrom constants
+//
+// The NCX is the item referenced by the toc attribute of the spine. If the
+// spine has none or it is not on the manifest, it is the item with the NCX
+// media type or, as some books declare it with other media type, the item
+// with the id "ncx".
+func (opf xmlOPF) ncxSource() (string, string) {
+	if toc := strings.TrimSpace(opf.Spine.Toc); toc != "" {
+		if path := opf.filePath(toc); path != "" {
+			return path, NCXFromSpineToc
 		}
 	}
-
-	fileID := "ncx"
-	return opf.filePath(fileID)
+	for _, item := range opf.Manifest {
+		if item.MediaType == "application/x-dtbncx+xml" {
+			return item.Href, NCXFromMediaType
+		}
+	}
+	if path := opf.filePath("ncx"); path != "" {
+		return path, NCXFromID
+	}
+	return "", ""
 }
 
 func (opf xmlOPF) filePath(id string) string {
@@ -106,6 +244,35 @@ func (opf xmlOPF) filePath(id string) string {
 	return ""
 }
 
+// hasProperty returns whether the space separated list of properties
+// contains property
+func hasProperty(properties, property string) bool {
+	for _, p := range strings.Fields(properties) {
+		if p == property {
+			return true
+		}
+	}
+	return false
+}
+
+func (opf xmlOPF) fileID(href string) string {
+	for _, item := range opf.Manifest {
+		if item.Href == href {
+			return item.ID
+		}
+	}
+	return ""
+}
+
+func (opf xmlOPF) mediaType(href string) string {
+	for _, item := range opf.Manifest {
+		if item.Href == href {
+			return item.MediaType
+		}
+	}
+	return ""
+}
+
 func (opf xmlOPF) toMData() mdata {
 	m := opf.Metadata
 	metadata := make(mdata)
@@ -113,7 +280,7 @@ func (opf xmlOPF) toMData() mdata {
 	typeOf := v.Type()
 	for i := 0; i < v.NumField(); i++ {
 		field := v.Field(i)
-		if field.Len() == 0 {
+		if typeOf.Field(i).PkgPath != "" || field.Len() == 0 {
 			continue
 		}
 
@@ -130,28 +297,47 @@ func (opf xmlOPF) toMData() mdata {
 
 func elementToMData(element interface{}) (result MdataElement) {
 	result.Attr = make(map[string]string)
+	for k, v := range reflect.ValueOf(element).FieldByName("Qualified").Interface().(map[string]string) {
+		result.Attr[k] = v
+	}
 	switch element.(type) {
-	case string:
-		result.Content, _ = element.(string)
+	case dcElement:
+		elem, _ := element.(dcElement)
+		result.Content = elem.Data
+		result.Index = elem.Index
+		result.Attr["id"] = elem.ID
+		result.Attr["lang"] = elem.Lang
 	case identifier:
 		ident, _ := element.(identifier)
 		result.Content = ident.Data
+		result.Index = ident.Index
 		result.Attr["id"] = ident.ID
 		result.Attr["scheme"] = ident.Scheme
 	case author:
 		auth, _ := element.(author)
 		result.Content = auth.Data
+		result.Index = auth.Index
+		result.Attr["id"] = auth.ID
 		result.Attr["file-as"] = auth.FileAs
 		result.Attr["role"] = auth.Role
 	case date:
 		d, _ := element.(date)
 		result.Content = d.Data
+		result.Index = d.Index
 		result.Attr["event"] = d.Event
 	case metafield:
 		m, _ := element.(metafield)
 		result.Content = m.Content
+		result.Index = m.Index
+		if m.Content == "" {
+			result.Content = strings.TrimSpace(m.Data)
+		}
 		result.Attr["name"] = m.Name
 		result.Attr["content"] = m.Content
+		result.Attr["id"] = m.ID
+		result.Attr["property"] = m.Property
+		result.Attr["refines"] = m.Refines
+		result.Attr["scheme"] = m.Scheme
 	}
 	return
 }
@@ -160,12 +346,26 @@ func (opf xmlOPF) spineLength() int {
 	return len(opf.Spine.Items)
 }
 
+// spineURL returns the href of the item of the spine at index, an empty
+// string if it is not on the manifest
 func (opf xmlOPF) spineURL(index int) string {
 	idref := opf.Spine.Items[index].IDref
 	url, _ := opf.getURL(idref)
 	return url
 }
 
+// nextSpineItem returns the index of the next item of the spine after index,
+// going backwards if step is -1, skipping the items that are not on the
+// manifest. Returns -1 if there is none.
+func (opf xmlOPF) nextSpineItem(index, step int) int {
+	for index += step; index >= 0 && index < opf.spineLength(); index += step {
+		if opf.spineURL(index) != "" {
+			return index
+		}
+	}
+	return -1
+}
+
 func (opf xmlOPF) getURL(id string) (string, error) {
 	for _, item := range opf.Manifest {
 		if item.ID == id {
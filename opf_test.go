@@ -8,6 +8,7 @@ import "testing"
 
 import (
 	"os"
+	"strings"
 )
 
 const (
@@ -23,3 +24,54 @@ func TestEncodingError(t *testing.T) {
 		t.Errorf("parseOpf(%v) with encoding problems return an error: %v", encodingOpf, err)
 	}
 }
+
+func TestMetadataOrder(t *testing.T) {
+	f := buildEpub(t, "testdata/epub3.opf", nil)
+
+	creators, _ := f.MetadataElement("creator")
+	if len(creators) != 2 || creators[0].Content != "J. R. R. Tolkien" || creators[1].Content != "Christopher Tolkien" {
+		t.Fatalf("Wrong creators order: %v", creators)
+	}
+	if creators[0].Index != 13 || creators[1].Index != 16 {
+		t.Errorf("Wrong creators index: %d, %d", creators[0].Index, creators[1].Index)
+	}
+	subjects, _ := f.MetadataElement("subject")
+	if len(subjects) != 2 || subjects[0].Content != "Fantasy" || subjects[0].Index != 21 || subjects[1].Index != 22 {
+		t.Errorf("Wrong subjects: %v", subjects)
+	}
+	identifiers, _ := f.MetadataElement("identifier")
+	if identifiers[0].Index != 0 {
+		t.Errorf("Wrong identifier index: %d", identifiers[0].Index)
+	}
+
+	f.SetMetadata("creator", []MdataElement{creators[1], creators[0]})
+	book := repackBook(t, f)
+	authors, _ := book.Metadata("creator")
+	if len(authors) != 2 || authors[0] != "Christopher Tolkien" || authors[1] != "J. R. R. Tolkien" {
+		t.Errorf("The creators order was not written: %v", authors)
+	}
+}
+
+func TestQualifiedAttrs(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	creators, _ := f.MetadataAttr("creator")
+	if creators[0]["file-as"] == "" || creators[0]["opf:file-as"] != creators[0]["file-as"] {
+		t.Errorf("Wrong creator attributes: %v", creators[0])
+	}
+
+	f = buildEpub(t, "testdata/epub3.opf", nil)
+	metas, _ := f.MetadataAttr("meta")
+	if _, ok := metas[0]["opf:refines"]; ok || metas[0]["refines"] != "#isbn" {
+		t.Errorf("Wrong meta attributes: %v", metas[0])
+	}
+
+	elems, _ := f.MetadataElement("creator")
+	f.SetMetadata("creator", []MdataElement{{Content: elems[0].Content, Attr: map[string]string{"id": "c1", "role": "aut"}}})
+	book := repackBook(t, f)
+	data := readBookFile(t, book, "content.opf")
+	if !strings.Contains(data, `<dc:creator id="c1" opf:role="aut">`) {
+		t.Errorf("Wrong creator written: %s", data)
+	}
+}
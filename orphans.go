@@ -0,0 +1,128 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+)
+
+// OrphanedResources returns the files never referenced by the book
+//
+// A file is referenced if it is reachable from the spine, the NCX, the
+// navigation document, the cover, the guide or the links of the metadata,
+// following the links, images (with their srcset candidates),
+// stylesheets, ... of the documents and the fallbacks and media overlays of
+// the manifest items. The zip entries that are not on the
+// manifest are reported as well, except the ones in META-INF.
+func (e Epub) OrphanedResources() ([]string, error) {
+	reachable, err := e.reachableFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var orphans []string
+	for _, name := range e.fileNames() {
+		if name == mimetypeName || name == e.opfPath || strings.HasPrefix(name, "META-INF/") {
+			continue
+		}
+		if !reachable[name] {
+			orphans = append(orphans, name)
+		}
+	}
+	sort.Strings(orphans)
+	return orphans, nil
+}
+
+// PruneOrphans returns a transform that drops the orphaned resources
+//
+// The manifest items of the dropped files are removed from the OPF.
+func (e Epub) PruneOrphans() (Transform, error) {
+	orphans, err := e.OrphanedResources()
+	if err != nil {
+		return nil, err
+	}
+	removed := make(map[string]string)
+	for _, name := range orphans {
+		removed[name] = ""
+	}
+
+	return func(name, mediaType string, r io.Reader) (io.Reader, error) {
+		if _, ok := removed[name]; ok {
+			return nil, nil
+		}
+		if name != e.opfPath {
+			return r, nil
+		}
+		data, err := readAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(removeManifestItems(data, name, removed)), nil
+	}, nil
+}
+
+func (e Epub) reachableFiles() (map[string]bool, error) {
+	var pending []string
+	for i := 0; i < e.opf.spineLength(); i++ {
+		if href := e.opf.spineURL(i); href != "" {
+			pending = append(pending, href)
+		}
+	}
+	pending = append(pending, e.opf.ncxPath())
+	for _, item := range e.opf.Manifest {
+		if hasProperty(item.Properties, "nav") {
+			pending = append(pending, item.Href)
+		}
+	}
+	pending = append(pending, e.coverHref())
+	for _, ref := range e.opf.Guide {
+		pending = append(pending, ref.Href)
+	}
+	for _, ext := range e.opf.Metadata.extensions {
+		if ext.Namespace == opfNamespace && ext.Name == "link" {
+			pending = append(pending, ext.Attr["href"])
+		}
+	}
+	for i, href := range pending {
+		pending[i] = resolveRef(e.opfPath, href)
+	}
+
+	reachable := make(map[string]bool)
+	for len(pending) > 0 {
+		name := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if name == "" || reachable[name] {
+			continue
+		}
+		reachable[name] = true
+
+		href := strings.TrimPrefix(name, e.rootPath)
+		if item := e.opf.manifestItem(e.opf.fileID(href)); item != nil {
+			for _, id := range []string{item.Fallback, item.MediaOverlay} {
+				if target := e.opf.filePath(id); id != "" && target != "" {
+					pending = append(pending, resolveRef(e.opfPath, target))
+				}
+			}
+		}
+		mediaType := e.opf.mediaType(href)
+		if !isMarkup(mediaType) {
+			continue
+		}
+		f, err := e.open(name)
+		if err != nil {
+			continue
+		}
+		data, err := readAll(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, references(data, name)...)
+	}
+	return reachable, nil
+}
@@ -0,0 +1,171 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	orphanFile = "3174/unused.css"
+)
+
+func TestOrphanedResources(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	orphans, err := f.OrphanedResources()
+	if err != nil {
+		t.Fatalf("OrphanedResources() return an error: %v", err)
+	}
+	if len(orphans) != 0 {
+		t.Errorf("OrphanedResources() return: %v", orphans)
+	}
+
+	f.stage(orphanFile, []byte("p {}"))
+	orphans, _ = f.OrphanedResources()
+	if len(orphans) != 1 || orphans[0] != orphanFile {
+		t.Errorf("OrphanedResources() return: %v", orphans)
+	}
+}
+
+func TestPruneOrphans(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	f.stage(orphanFile, []byte("p {}"))
+	prune, err := f.PruneOrphans()
+	if err != nil {
+		t.Fatalf("PruneOrphans() return an error: %v", err)
+	}
+	book := repackBook(t, f, prune)
+	if _, err := book.OpenFile("unused.css"); err == nil {
+		t.Errorf("%v was not dropped from the repacked epub", orphanFile)
+	}
+	if _, err := book.OpenFile(cssFile); err != nil {
+		t.Errorf("%v was dropped from the repacked epub", cssFile)
+	}
+}
+
+func TestOrphanedOverlaysAndFallbacks(t *testing.T) {
+	f := buildEpub(t, "testdata/epub3.opf", map[string]string{
+		"nav.xhtml":         `<html><body><nav epub:type="toc"><ol><li><a href="text/ch1.xhtml">1</a></li></ol></nav></body></html>`,
+		"images/cover.jpg":  "jpeg",
+		"text/ch1.xhtml":    `<html><body><p id="p1">One</p></body></html>`,
+		"text/ch2.xhtml":    `<html><body><p>Two</p></body></html>`,
+		"smil/ch1.smil":     `<smil><body><par><text src="../text/ch1.xhtml#p1"/><audio src="../audio/ch1.mp3"/></par></body></smil>`,
+		"audio/ch1.mp3":     "mp3",
+		"images/ch2.png":    "png",
+		"images/ch2.gif":    "gif",
+		"images/unused.gif": "gif",
+	})
+	defer f.Close()
+	f.opf.Manifest[2].MediaOverlay = "ch1-overlay"
+	f.opf.Manifest[3].Fallback = "ch2-png"
+	f.opf.Manifest = append(f.opf.Manifest,
+		manifest{ID: "ch1-overlay", Href: "smil/ch1.smil", MediaType: "application/smil+xml"},
+		manifest{ID: "ch1-audio", Href: "audio/ch1.mp3", MediaType: "audio/mpeg"},
+		manifest{ID: "ch2-png", Href: "images/ch2.png", MediaType: "image/png", Fallback: "ch2-gif"},
+		manifest{ID: "ch2-gif", Href: "images/ch2.gif", MediaType: "image/gif"},
+		manifest{ID: "unused", Href: "images/unused.gif", MediaType: "image/gif"},
+	)
+
+	orphans, err := f.OrphanedResources()
+	if err != nil {
+		t.Fatalf("OrphanedResources() return an error: %v", err)
+	}
+	if len(orphans) != 1 || orphans[0] != "OEBPS/images/unused.gif" {
+		t.Errorf("OrphanedResources() return: %v", orphans)
+	}
+}
+
+func TestFallbackChain(t *testing.T) {
+	opf, _ := ioutil.ReadFile(epub3OPF)
+	opfPath := filepath.Join(t.TempDir(), "content.opf")
+	ioutil.WriteFile(opfPath, []byte(strings.Replace(string(opf),
+		`<item id="ch2" href="text/ch2.xhtml" media-type="application/xhtml+xml" properties="scripted svg"/>`,
+		`<item id="ch2" href="text/ch2.xhtml" media-type="application/xhtml+xml" properties="scripted svg" fallback="ch2-png"/>
+    <item id="ch2-png" href="images/ch2.png" media-type="image/png" fallback="ch2-gif"/>
+    <item id="ch2-gif" href="images/ch2.gif" media-type="image/gif"/>
+    <item id="unused" href="images/unused.gif" media-type="image/gif"/>`, 1)), 0644)
+	f := buildEpub(t, opfPath, map[string]string{
+		"nav.xhtml":         `<html><body><nav epub:type="toc"><ol><li><a href="text/ch1.xhtml">1</a></li></ol></nav></body></html>`,
+		"images/cover.jpg":  "jpeg",
+		"text/ch1.xhtml":    `<html><body><p>One</p></body></html>`,
+		"text/ch2.xhtml":    `<html><body><p>Two</p></body></html>`,
+		"images/ch2.png":    "png",
+		"images/ch2.gif":    "gif",
+		"images/unused.gif": "gif",
+	})
+	defer f.Close()
+
+	for _, book := range []*Epub{f, repackBook(t, f)} {
+		if ch2, png := book.opf.manifestItem("ch2"), book.opf.manifestItem("ch2-png"); ch2.Fallback != "ch2-png" || png.Fallback != "ch2-gif" {
+			t.Errorf("The fallbacks of the manifest are: %v %v", ch2, png)
+		}
+		orphans, err := book.OrphanedResources()
+		if err != nil {
+			t.Fatalf("OrphanedResources() return an error: %v", err)
+		}
+		if len(orphans) != 1 || orphans[0] != "OEBPS/images/unused.gif" {
+			t.Errorf("OrphanedResources() return: %v", orphans)
+		}
+	}
+}
+
+func TestPruneOrphansSrcsetAndLinks(t *testing.T) {
+	opf, _ := ioutil.ReadFile(epub3OPF)
+	opfPath := filepath.Join(t.TempDir(), "content.opf")
+	opfData := strings.Replace(string(opf), "</metadata>",
+		`  <link rel="record" href="record.xml" media-type="application/marcxml+xml"/>
+    <link rel="record" href="https://example.org/record.xml"/>
+  </metadata>`, 1)
+	opfData = strings.Replace(opfData, `<item id="nav"`,
+		`<item id="small" href="images/small.jpg" media-type="image/jpeg"/>
+    <item id="large" href="images/large%20one.jpg" media-type="image/jpeg"/>
+    <item id="wide" href="images/wide.jpg" media-type="image/jpeg"/>
+    <item id="unused" href="images/unused.gif" media-type="image/gif"/>
+    <item id="nav"`, 1)
+	ioutil.WriteFile(opfPath, []byte(opfData), 0644)
+	f := buildEpub(t, opfPath, map[string]string{
+		"nav.xhtml":            `<html><body><nav epub:type="toc"><ol><li><a href="text/ch1.xhtml">1</a></li></ol></nav></body></html>`,
+		"images/cover.jpg":     "jpeg",
+		"text/ch1.xhtml":       `<html><body><img src="../images/small.jpg" srcset="../images/large%20one.jpg 2x,../images/wide.jpg 900w"/></body></html>`,
+		"text/ch2.xhtml":       `<html><body><p>Two</p></body></html>`,
+		"images/small.jpg":     "jpeg",
+		"images/large one.jpg": "jpeg",
+		"images/wide.jpg":      "jpeg",
+		"images/unused.gif":    "gif",
+		"record.xml":           "<record/>",
+	})
+	defer f.Close()
+
+	orphans, err := f.OrphanedResources()
+	if err != nil {
+		t.Fatalf("OrphanedResources() return an error: %v", err)
+	}
+	if len(orphans) != 1 || orphans[0] != "OEBPS/images/unused.gif" {
+		t.Errorf("OrphanedResources() return: %v", orphans)
+	}
+
+	prune, err := f.PruneOrphans()
+	if err != nil {
+		t.Fatalf("PruneOrphans() return an error: %v", err)
+	}
+	book := repackBook(t, f, prune)
+	defer book.Close()
+	for _, name := range []string{"images/large one.jpg", "images/wide.jpg", "record.xml"} {
+		if _, err := book.OpenFile(name); err != nil {
+			t.Errorf("%v was dropped from the repacked epub", name)
+		}
+	}
+	if _, err := book.OpenFile("images/unused.gif"); err == nil {
+		t.Errorf("images/unused.gif was not dropped from the repacked epub")
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"strings"
+)
+
+// PageTarget is a page of the print edition of the book
+type PageTarget struct {
+	// Label is the page number, like "12" or "xii"
+	Label string
+	// Href is the location of the beginning of the page, a path as used by
+	// OpenFile followed by a fragment
+	Href string
+}
+
+type xmlPageMap struct {
+	Pages []struct {
+		Name string `xml:"name,attr"`
+		Href string `xml:"href,attr"`
+	} `xml:"page"`
+}
+
+// PageList returns the pages of the print edition of the book
+//
+// They are taken from the pageList of the NCX or, if it has none, from the
+// Adobe page-map referenced by the spine. It returns nil if the book has no
+// page information.
+func (e Epub) PageList() ([]PageTarget, error) {
+	if e.ncx != nil && len(e.ncx.PageList) > 0 {
+		ncxPath := e.opf.ncxPath()
+		pages := make([]PageTarget, len(e.ncx.PageList))
+		for i, target := range e.ncx.PageList {
+			pages[i] = PageTarget{strings.TrimSpace(target.Text), e.ResolveHref(ncxPath, target.Content.Src)}
+		}
+		return pages, nil
+	}
+
+	if e.opf.Spine.PageMap == "" {
+		return nil, nil
+	}
+	href := e.opf.filePath(e.opf.Spine.PageMap)
+	if href == "" {
+		return nil, nil
+	}
+	f, err := e.open(e.rootPath + href)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var pageMap xmlPageMap
+	if err := decodeXML(f, &pageMap); err != nil {
+		return nil, err
+	}
+	pages := make([]PageTarget, len(pageMap.Pages))
+	for i, page := range pageMap.Pages {
+		pages[i] = PageTarget{page.Name, e.ResolveHref(href, page.Href)}
+	}
+	return pages, nil
+}
@@ -0,0 +1,49 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+func TestPageList(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	if pages, err := f.PageList(); pages != nil || err != nil {
+		t.Errorf("PageList() return: %v, %v", pages, err)
+	}
+	f.ncx.PageList = []pageTarget{{Text: " 12 ", Content: content{Src: chapterFile + "#page12"}}}
+	pages, _ := f.PageList()
+	if len(pages) != 1 || pages[0].Label != "12" || pages[0].Href != chapterFile+"#page12" {
+		t.Errorf("PageList() return: %v", pages)
+	}
+}
+
+func TestPageMap(t *testing.T) {
+	opf, _ := ioutil.ReadFile("testdata/epub3.opf")
+	opfStr := strings.Replace(string(opf), "</manifest>", `<item id="map" href="xml/page-map.xml" media-type="application/oebps-page-map+xml"/></manifest>`, 1)
+	opfStr = strings.Replace(opfStr, "<spine", `<spine page-map="map"`, 1)
+	opfPath := filepath.Join(t.TempDir(), "content.opf")
+	ioutil.WriteFile(opfPath, []byte(opfStr), 0644)
+
+	f := buildEpub(t, opfPath, map[string]string{
+		"xml/page-map.xml": `<page-map xmlns="http://www.idpf.org/2007/opf">
+  <page name="i" href="../text/ch1.xhtml"/>
+  <page name="1" href="../text/ch1.xhtml#page1"/>
+</page-map>`,
+	})
+	pages, err := f.PageList()
+	if err != nil {
+		t.Fatalf("PageList() return an error: %v", err)
+	}
+	if len(pages) != 2 || pages[0].Label != "i" || pages[0].Href != "text/ch1.xhtml" || pages[1].Href != "text/ch1.xhtml#page1" {
+		t.Errorf("PageList() return: %v", pages)
+	}
+}
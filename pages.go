@@ -0,0 +1,342 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"bytes"
+	"encoding/xml"
+	"html/template"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// PageData is the data passed to the templates of the generated pages
+type PageData struct {
+	Title     string
+	Subtitle  string
+	Authors   []string
+	Publisher string
+	Date      string
+	Rights    string
+	ISBN      string
+	Language  string
+	// TOC is the table of contents with the hrefs relative to the page
+	TOC []TOCEntry
+	// Text is the free content of the page, like the biography of the
+	// about the author page
+	Text template.HTML
+}
+
+// TOCEntry is an entry of the table of contents of PageData
+type TOCEntry struct {
+	Title    string
+	Href     string
+	Children []TOCEntry
+}
+
+const pageLayout = `<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops"{{with .Language}} xml:lang="{{.}}" lang="{{.}}"{{end}}>
+  <head>
+    <title>{{block "title" .}}{{.Title}}{{end}}</title>
+  </head>
+  <body>
+{{template "body" .}}
+  </body>
+</html>
+`
+
+// The templates of the standard pages, they are executed with a PageData
+var (
+	TitlePageTemplate = newPageTemplate(`
+    <section epub:type="titlepage">
+      <h1>{{.Title}}</h1>
+      {{with .Subtitle}}<h2>{{.}}</h2>{{end}}
+      {{range .Authors}}<p class="author">{{.}}</p>{{end}}
+      {{with .Publisher}}<p class="publisher">{{.}}</p>{{end}}
+    </section>`)
+	CopyrightPageTemplate = newPageTemplate(`
+    <section epub:type="copyright-page">
+      <p>{{.Title}}{{range .Authors}}<br/>{{.}}{{end}}</p>
+      {{with .Rights}}<p>{{.}}</p>{{else}}{{with .Date}}<p>Copyright © {{.}}{{with $.Publisher}} {{.}}{{end}}</p>{{end}}{{end}}
+      {{with .Publisher}}<p>Published by {{.}}</p>{{end}}
+      {{with .ISBN}}<p>ISBN {{.}}</p>{{end}}
+    </section>`)
+	TOCPageTemplate = newPageTemplate(`{{define "title"}}Contents{{end}}
+    <nav epub:type="toc">
+      <h1>Contents</h1>
+      {{template "entries" .TOC}}
+    </nav>
+    {{define "entries"}}<ol>{{range .}}
+      <li><a href="{{.Href}}">{{.Title}}</a>{{with .Children}}{{template "entries" .}}{{end}}</li>{{end}}
+    </ol>{{end}}`)
+	AboutAuthorTemplate = newPageTemplate(`{{define "title"}}About the Author{{end}}
+    <section epub:type="appendix">
+      <h1>About the Author</h1>
+      {{.Text}}
+    </section>`)
+)
+
+func newPageTemplate(body string) *template.Template {
+	t := template.Must(template.New("page").Parse(pageLayout))
+	template.Must(t.New("body").Parse(body))
+	return t
+}
+
+// AddPage renders the template with data and inserts the result on the spine
+//
+// The page is written on href (relative to the OPF) and added to the manifest
+// and to the spine at index, a negative index adds it at the end. If title is
+// not empty the page is added also to the NCX and the EPUB 3 navigation
+// document. html/template can't produce the XML declaration, it is added to
+// the page. The changes are written with Repack.
+func (e *Epub) AddPage(href, title string, tmpl *template.Template, data interface{}, index int) error {
+	var buff bytes.Buffer
+	buff.WriteString(xml.Header)
+	if err := tmpl.Execute(&buff, data); err != nil {
+		return err
+	}
+	return e.InsertDocument(href, title, buff.Bytes(), index)
+}
+
+// AddTitlePage adds a title page after the cover
+func (e *Epub) AddTitlePage() error {
+	href := e.pageHref("titlepage.xhtml")
+	err := e.AddPage(href, "Title Page", TitlePageTemplate, e.PageData(href), e.frontMatterEnd())
+	if err != nil {
+		return err
+	}
+	e.setGuide("title-page", "Title Page", href)
+	return nil
+}
+
+// AddCopyrightPage adds a copyright page after the title page
+func (e *Epub) AddCopyrightPage() error {
+	href := e.pageHref("copyright.xhtml")
+	err := e.AddPage(href, "Copyright", CopyrightPageTemplate, e.PageData(href), e.frontMatterEnd("title-page"))
+	if err != nil {
+		return err
+	}
+	e.setGuide("copyright-page", "Copyright", href)
+	return nil
+}
+
+// AddTOCPage adds a table of contents page, generated from the navigation of
+// the book, after the title and copyright pages
+func (e *Epub) AddTOCPage() error {
+	href := e.pageHref("toc.xhtml")
+	err := e.AddPage(href, "Contents", TOCPageTemplate, e.PageData(href), e.frontMatterEnd("title-page", "copyright-page"))
+	if err != nil {
+		return err
+	}
+	e.setGuide("toc", "Contents", href)
+	return nil
+}
+
+// AddAboutAuthorPage adds an about the author page at the end of the book
+//
+// The bio is cleaned up as the description of SetDescription.
+func (e *Epub) AddAboutAuthorPage(bio string) error {
+	href := e.pageHref("about.xhtml")
+	data := e.PageData(href)
+	data.Text = template.HTML(cleanDescription(bio))
+	return e.AddPage(href, "About the Author", AboutAuthorTemplate, data, -1)
+}
+
+// PageData returns the data of the book for the page templates, the hrefs
+// of the TOC are relative to the page href
+func (e Epub) PageData(href string) PageData {
+	var data PageData
+	for _, title := range e.Titles() {
+		switch {
+		case data.Title == "" && (title.Type == "" || title.Type == "main"):
+			data.Title = title.Content
+		case data.Subtitle == "" && title.Type == "subtitle":
+			data.Subtitle = title.Content
+		}
+	}
+	for _, author := range e.Contributors(RoleAuthor) {
+		data.Authors = append(data.Authors, author.Name)
+	}
+	data.Publisher = e.first("publisher")
+	data.Date = e.first("date")
+	data.Rights = e.first("rights")
+	data.ISBN = e.Query().ISBN
+	data.Language = e.language()
+
+	if e.ncx != nil {
+		ncxPath := e.rootPath + e.opf.ncxPath()
+		data.TOC = tocEntries(e.ncx.NavMap, ncxPath, e.rootPath+href)
+	}
+	return data
+}
+
+func tocEntries(points []navpoint, ncxPath, pagePath string) []TOCEntry {
+	entries := make([]TOCEntry, len(points))
+	for i, point := range points {
+		entries[i].Title = point.Text
+		entries[i].Href = point.URL()
+		if target := resolveRef(ncxPath, point.URL()); target != "" {
+			entries[i].Href = relativeRef(pagePath, target)
+			if j := strings.Index(point.URL(), "#"); j != -1 {
+				entries[i].Href += point.URL()[j:]
+			}
+		}
+		entries[i].Children = tocEntries(point.NavPoint, ncxPath, pagePath)
+	}
+	return entries
+}
+
+func (e Epub) first(field string) string {
+	if elems := e.metadata[field]; len(elems) > 0 {
+		return elems[0].Content
+	}
+	return ""
+}
+
+// pageHref returns an unused href for a new page on the directory of the
+// content documents
+func (e Epub) pageHref(name string) string {
+	if e.opf.spineLength() > 0 {
+		if dir := path.Dir(e.opf.spineURL(e.opf.spineLength() - 1)); dir != "." {
+			name = dir + "/" + name
+		}
+	}
+	return e.opf.uniqueHref(name)
+}
+
+// frontMatterEnd returns the spine position after the cover and the pages of
+// the guide types
+func (e Epub) frontMatterEnd(types ...string) int {
+	end := 0
+	if i := e.opf.spineIndex(e.coverPage()); i >= end {
+		end = i + 1
+	}
+	for _, ref := range e.opf.Guide {
+		for _, t := range types {
+			if ref.Type != t {
+				continue
+			}
+			href := strings.SplitN(ref.Href, "#", 2)[0]
+			if i := e.opf.spineIndex(href); i >= end {
+				end = i + 1
+			}
+		}
+	}
+	return end
+}
+
+// spineIndex returns the position on the spine of href, -1 if it is not on it
+func (opf xmlOPF) spineIndex(href string) int {
+	if href == "" {
+		return -1
+	}
+	for i := range opf.Spine.Items {
+		if opf.spineURL(i) == href {
+			return i
+		}
+	}
+	return -1
+}
+
+// insertSpine inserts item on the spine at index, at the end if index is
+// negative or out of range
+func (opf *xmlOPF) insertSpine(item spineItem, index int) {
+	items := opf.Spine.Items
+	if index < 0 || index > len(items) {
+		index = len(items)
+	}
+	items = append(items, spineItem{})
+	copy(items[index+1:], items[index:])
+	items[index] = item
+	opf.Spine.Items = items
+}
+
+// addNavEntry adds a top level entry pointing to href on the NCX and the EPUB
+// 3 navigation document, placed following the order of the spine
+func (e *Epub) addNavEntry(title, href string) error {
+	name := e.rootPath + href
+	index := e.opf.spineIndex(href)
+	if e.ncx != nil {
+		ncxPath := e.rootPath + e.opf.ncxPath()
+		targets := make([]string, len(e.ncx.NavMap))
+		for i, point := range e.ncx.NavMap {
+			targets[i] = resolveRef(ncxPath, point.URL())
+		}
+		point := navpoint{Text: title, Content: content{Src: relativeRef(ncxPath, name)}}
+		pos := e.navPosition(targets, index)
+		points := append(e.ncx.NavMap, navpoint{})
+		copy(points[pos+1:], points[pos:])
+		points[pos] = point
+		e.ncx.NavMap = points
+	}
+
+	navPath := e.navDocPath()
+	if navPath == "" {
+		return nil
+	}
+	navPath = e.rootPath + navPath
+	data, err := e.readFile(navPath)
+	if err != nil {
+		return err
+	}
+	li := `<li><a href="` + escapeXML(relativeRef(navPath, name)) + `">` + escapeXML(title) + "</a></li>\n"
+	if newData := e.insertNavItem(data, navPath, li, index); newData != nil {
+		e.stage(navPath, newData)
+	}
+	return nil
+}
+
+// navPosition returns where to insert on a list of navigation entries pointing
+// to targets a new one for the spine position index
+func (e Epub) navPosition(targets []string, index int) int {
+	if index < 0 {
+		return len(targets)
+	}
+	for i, target := range targets {
+		if e.opf.spineIndex(strings.TrimPrefix(target, e.rootPath)) > index {
+			return i
+		}
+	}
+	return len(targets)
+}
+
+// navDocPath returns the href of the EPUB 3 navigation document
+func (e Epub) navDocPath() string {
+	for _, item := range e.opf.Manifest {
+		if hasProperty(item.Properties, "nav") {
+			return item.Href
+		}
+	}
+	return ""
+}
+
+var (
+	tocNavRegexp     = regexp.MustCompile(`<(?:[\w-]+:)?nav\b[^>]*\btype\s*=\s*["'](?:[^"']*\s)?toc[\s"']`)
+	navListTagRegexp = regexp.MustCompile(`</?(?:[\w-]+:)?(ol|li)\b[^>]*>`)
+)
+
+// insertNavItem inserts li on the top level list of the toc nav, returns nil
+// if the toc nav is not found
+func (e Epub) insertNavItem(data []byte, navPath, li string, index int) []byte {
+	items, end, ok := tocNavItems(data, navPath)
+	if !ok {
+		return nil
+	}
+	targets := make([]string, len(items))
+	for i, item := range items {
+		targets[i] = item.target
+	}
+
+	pos := end
+	if i := e.navPosition(targets, index); i < len(items) {
+		pos = items[i].start
+	}
+	var buff bytes.Buffer
+	buff.Write(data[:pos])
+	buff.WriteString(li)
+	buff.Write(data[pos:])
+	return buff.Bytes()
+}
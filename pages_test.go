@@ -0,0 +1,119 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"html/template"
+	"io/ioutil"
+	"strings"
+)
+
+const (
+	chapterFile = "@public@vhost@g@gutenberg@html@files@3174@3174-h@3174-h-0.htm.html"
+	epub3Nav    = `<?xml version="1.0" encoding="UTF-8"?>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<body>
+<nav epub:type="toc"><ol>
+<li><a href="text/ch1.xhtml">Chapter 1</a><ol><li><a href="text/ch1.xhtml#s1">Section</a></li></ol></li>
+<li><a href="text/ch2.xhtml">Chapter 2</a></li>
+</ol></nav>
+</body>
+</html>`
+)
+
+func readBookFile(t *testing.T, book *Epub, name string) string {
+	f, err := book.OpenFile(name)
+	if err != nil {
+		t.Fatalf("OpenFile(%v) return an error: %v", name, err)
+	}
+	defer f.Close()
+	data, _ := ioutil.ReadAll(f)
+	return string(data)
+}
+
+func TestAddPages(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	if err := f.AddTOCPage(); err != nil {
+		t.Fatalf("AddTOCPage() return an error: %v", err)
+	}
+	if err := f.AddTitlePage(); err != nil {
+		t.Fatalf("AddTitlePage() return an error: %v", err)
+	}
+	if err := f.AddCopyrightPage(); err != nil {
+		t.Fatalf("AddCopyrightPage() return an error: %v", err)
+	}
+	if err := f.AddAboutAuthorPage("Mark Twain was <b>an American writer</b>."); err != nil {
+		t.Fatalf("AddAboutAuthorPage() return an error: %v", err)
+	}
+
+	book := repackBook(t, f)
+	spine := []string{spineURL, "titlepage.xhtml", "copyright.xhtml", "toc.xhtml", chapterFile, "about.xhtml"}
+	if book.opf.spineLength() != len(spine) {
+		t.Fatalf("The spine has %v items", book.opf.spineLength())
+	}
+	for i, href := range spine {
+		if url := book.opf.spineURL(i); url != href {
+			t.Errorf("Spine item %v is %v when was expected %v", i, url, href)
+		}
+	}
+
+	titlePage := readBookFile(t, book, "titlepage.xhtml")
+	if !strings.HasPrefix(titlePage, "<?xml") || !strings.Contains(titlePage, "<html") {
+		t.Errorf("The title page is not a complete XHTML document: %v", titlePage)
+	}
+	if !strings.Contains(titlePage, "<h1>"+template.HTMLEscapeString(bookTitle)+"</h1>") {
+		t.Errorf("The title page doesn't have the title: %v", titlePage)
+	}
+	toc := readBookFile(t, book, "toc.xhtml")
+	if !strings.Contains(toc, chapterFile+"#pgepubid00000") {
+		t.Errorf("The TOC page doesn't link the first chapter: %v", toc)
+	}
+	if about := readBookFile(t, book, "about.xhtml"); !strings.Contains(about, "<b>an American writer</b>") {
+		t.Errorf("The about the author page doesn't have the bio: %v", about)
+	}
+
+	nav, _ := book.Navigation()
+	if nav.Title() != "Title Page" {
+		t.Errorf("The first navigation entry is: %v", nav.Title())
+	}
+	last := ""
+	for nav.Next() == nil {
+		last = nav.Title()
+	}
+	if last != "About the Author" {
+		t.Errorf("The last navigation entry is: %v", last)
+	}
+}
+
+func TestAddPageNavDoc(t *testing.T) {
+	f := buildEpub(t, epub3OPF, map[string]string{
+		"nav.xhtml":      epub3Nav,
+		"text/ch1.xhtml": "<html/>",
+		"text/ch2.xhtml": "<html/>",
+	})
+	if err := f.AddTitlePage(); err != nil {
+		t.Fatalf("AddTitlePage() return an error: %v", err)
+	}
+	if err := f.AddAboutAuthorPage("bio"); err != nil {
+		t.Fatalf("AddAboutAuthorPage() return an error: %v", err)
+	}
+
+	book := repackBook(t, f)
+	if url := book.opf.spineURL(0); url != "text/titlepage.xhtml" {
+		t.Errorf("The first spine item is: %v", url)
+	}
+	nav := readBookFile(t, book, "nav.xhtml")
+	title := strings.Index(nav, `<li><a href="text/titlepage.xhtml">Title Page</a></li>`)
+	about := strings.Index(nav, `<li><a href="text/about.xhtml">About the Author</a></li>`)
+	ch1 := strings.Index(nav, `<li><a href="text/ch1.xhtml">`)
+	ch2 := strings.Index(nav, `<li><a href="text/ch2.xhtml">`)
+	if title == -1 || about == -1 || title > ch1 || about < ch2 {
+		t.Errorf("The pages are not correctly placed on the nav: %v", nav)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+const defaultMaxPooledBuffer = 1 << 20
+
+var (
+	maxPooledBuffer int64 = defaultMaxPooledBuffer
+	bufferPool            = sync.Pool{New: func() interface{} {
+		atomic.AddInt64(&statPoolMisses, 1)
+		return new(bytes.Buffer)
+	}}
+	bufioPool = sync.Pool{New: func() interface{} {
+		atomic.AddInt64(&statPoolMisses, 1)
+		return bufio.NewReader(nil)
+	}}
+)
+
+// SetBufferPooling sets the size of the biggest buffer reused between reads
+//
+// The files decompressed from the zip and the XML decoded are read through
+// buffers kept on a pool, so servers opening many epubs generate less
+// garbage. The buffers that grew bigger than maxSize are dropped, so a huge
+// file doesn't keep the memory allocated. 1 MB by default, 0 disables the
+// pooling. It is safe to call it concurrently with the use of the epubs.
+func SetBufferPooling(maxSize int) {
+	if maxSize < 0 {
+		maxSize = 0
+	}
+	atomic.StoreInt64(&maxPooledBuffer, int64(maxSize))
+}
+
+// readAll is like ioutil.ReadAll but it reads through a pooled buffer, only
+// the returned slice is allocated
+func readAll(r io.Reader) ([]byte, error) {
+	max := atomic.LoadInt64(&maxPooledBuffer)
+	if max == 0 {
+		var buff bytes.Buffer
+		_, err := buff.ReadFrom(r)
+		return buff.Bytes(), err
+	}
+
+	atomic.AddInt64(&statPoolGets, 1)
+	buff := bufferPool.Get().(*bytes.Buffer)
+	buff.Reset()
+	_, err := buff.ReadFrom(r)
+	data := make([]byte, buff.Len())
+	copy(data, buff.Bytes())
+	if int64(buff.Cap()) <= max {
+		bufferPool.Put(buff)
+	}
+	return data, err
+}
+
+// getBufioReader returns a pooled bufio.Reader of r, it must be returned
+// with putBufioReader
+func getBufioReader(r io.Reader) *bufio.Reader {
+	if atomic.LoadInt64(&maxPooledBuffer) == 0 {
+		return bufio.NewReader(r)
+	}
+	atomic.AddInt64(&statPoolGets, 1)
+	br := bufioPool.Get().(*bufio.Reader)
+	br.Reset(r)
+	return br
+}
+
+func putBufioReader(br *bufio.Reader) {
+	br.Reset(nil)
+	if atomic.LoadInt64(&maxPooledBuffer) != 0 {
+		bufioPool.Put(br)
+	}
+}
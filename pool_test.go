@@ -0,0 +1,37 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import "strings"
+
+func TestReadAll(t *testing.T) {
+	defer SetBufferPooling(defaultMaxPooledBuffer)
+
+	for _, max := range []int{0, 16, defaultMaxPooledBuffer} {
+		SetBufferPooling(max)
+		for _, text := range []string{"", "short", strings.Repeat("long text ", 1000)} {
+			data, err := readAll(strings.NewReader(text))
+			if err != nil || string(data) != text {
+				t.Errorf("readAll() with pooling %v return: %q, %v", max, data, err)
+			}
+		}
+		first, _ := readAll(strings.NewReader("first"))
+		readAll(strings.NewReader("second"))
+		if string(first) != "first" {
+			t.Errorf("readAll() with pooling %v reused the returned data: %q", max, first)
+		}
+
+		f, err := Open(bookPath)
+		if err != nil {
+			t.Fatalf("Open() with pooling %v return an error: %v", max, err)
+		}
+		if title, _ := f.Metadata("title"); title[0] != "A Dog's Tale" {
+			t.Errorf("Open() with pooling %v title: %v", max, title)
+		}
+		f.Close()
+	}
+}
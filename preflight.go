@@ -0,0 +1,265 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Retailer profiles of Preflight
+const (
+	ProfileApple  = "apple"
+	ProfileKobo   = "kobo"
+	ProfileGoogle = "google"
+	ProfileAmazon = "amazon"
+)
+
+// Severities of the PreflightIssue
+const (
+	// SeverityError is a requirement of the retailer, the book is rejected
+	SeverityError = "error"
+	// SeverityWarning is a recommendation of the retailer
+	SeverityWarning = "warning"
+)
+
+// Checks of the PreflightIssue
+const (
+	CheckCover      = "cover"
+	CheckCoverSize  = "cover-size"
+	CheckISBN       = "isbn"
+	CheckFileSize   = "file-size"
+	CheckImageSize  = "image-size"
+	CheckFontFormat = "font-format"
+	// The checks of ScreenContent
+	CheckEmptyFile   = "empty-file"
+	CheckBlankPage   = "blank-page"
+	CheckImageOnly   = "image-only"
+	CheckPlaceholder = "placeholder"
+	CheckBaitFile    = "bait-file"
+)
+
+// PreflightIssue is a requirement of a retailer that the book doesn't meet
+type PreflightIssue struct {
+	Check    string
+	Severity string
+	// Href is the file with the issue, as used by OpenFile, empty if it is
+	// about the whole book
+	Href    string
+	Message string
+}
+
+// String formats the issue as "severity: href: message (check)"
+func (i PreflightIssue) String() string {
+	s := i.Severity + ": "
+	if i.Href != "" {
+		s += i.Href + ": "
+	}
+	return s + i.Message + " (" + i.Check + ")"
+}
+
+// preflightProfile are the requirements of a retailer
+type preflightProfile struct {
+	// minCoverWidth and minCoverHeight are the minimum size of the cover in
+	// pixels
+	minCoverWidth, minCoverHeight int
+	// maxSize is the maximum size of the epub
+	maxSize int64
+	// maxImagePixels is the maximum width by height of the images, 0 if
+	// there is no limit
+	maxImagePixels int
+	// isbn is the severity of a book without ISBN, empty if it is not checked
+	isbn string
+	// fonts are the accepted font formats
+	fonts []string
+}
+
+// preflightProfiles are the requirements published by the retailers on
+// their asset guides
+var preflightProfiles = map[string]preflightProfile{
+	ProfileApple: {
+		minCoverWidth:  1400,
+		minCoverHeight: 1400,
+		maxSize:        2 << 30,
+		maxImagePixels: 4000000,
+		isbn:           SeverityWarning,
+		fonts:          []string{"font/otf", "font/ttf", "font/woff"},
+	},
+	ProfileKobo: {
+		minCoverWidth:  1400,
+		minCoverHeight: 1400,
+		maxSize:        650 << 20,
+		isbn:           SeverityWarning,
+		fonts:          []string{"font/otf", "font/ttf", "font/woff"},
+	},
+	ProfileGoogle: {
+		minCoverWidth:  640,
+		minCoverHeight: 640,
+		maxSize:        2 << 30,
+		maxImagePixels: 3200 * 3200,
+		isbn:           SeverityWarning,
+		fonts:          []string{"font/otf", "font/ttf", "font/woff", "font/woff2"},
+	},
+	ProfileAmazon: {
+		minCoverWidth:  625,
+		minCoverHeight: 1000,
+		maxSize:        650 << 20,
+		maxImagePixels: 5000000,
+		fonts:          []string{"font/otf", "font/ttf"},
+	},
+}
+
+// Preflight checks the book against the requirements of a retailer before
+// uploading it: "apple", "kobo", "google" or "amazon" (KDP epub input)
+//
+// The cover must exist and be big enough, the size of the epub and of its
+// images under the limits and the fonts in accepted formats. The pixels of
+// the images are checked against the limits of Apple, Google and Amazon,
+// Kobo doesn't publish one. A book without ISBN gets a warning, except for
+// Amazon that assigns its own ASIN. The size of the epub is estimated from
+// the compressed size of its files. The retailers update their requirements,
+// the profiles encode the ones published on their guides. The content is
+// screened as well, see ScreenContent.
+func (e Epub) Preflight(profile string) ([]PreflightIssue, error) {
+	p, ok := preflightProfiles[profile]
+	if !ok {
+		return nil, errors.New("Unknown preflight profile " + profile)
+	}
+	var issues []PreflightIssue
+	add := func(check, severity, href, message string) {
+		issues = append(issues, PreflightIssue{check, severity, href, message})
+	}
+
+	cover := e.coverHref()
+	if cover == "" {
+		add(CheckCover, SeverityError, "", "The book has no cover image")
+	} else if config, err := e.imageConfig(cover); err == nil {
+		if config.Width < p.minCoverWidth || config.Height < p.minCoverHeight {
+			add(CheckCoverSize, SeverityError, cover, "The cover is "+dimensions(config.Width, config.Height)+
+				", at least "+dimensions(p.minCoverWidth, p.minCoverHeight)+" are required")
+		}
+	}
+
+	if p.isbn != "" && !e.hasISBN() {
+		add(CheckISBN, p.isbn, "", "The book has no valid ISBN")
+	}
+
+	if size := e.packedSize(); size > p.maxSize {
+		add(CheckFileSize, SeverityError, "", "The epub is "+strconv.FormatInt(size>>20, 10)+
+			" MB, the limit is "+strconv.FormatInt(p.maxSize>>20, 10)+" MB")
+	}
+
+	for _, item := range e.opf.Manifest {
+		switch {
+		case strings.HasPrefix(item.MediaType, "image/") && p.maxImagePixels > 0:
+			config, err := e.imageConfig(item.Href)
+			if err == nil && config.Width*config.Height > p.maxImagePixels {
+				add(CheckImageSize, SeverityError, item.Href, "The image is "+dimensions(config.Width, config.Height)+
+					", over the limit of "+strconv.Itoa(p.maxImagePixels)+" pixels")
+			}
+		case isFont(item.MediaType, item.Href):
+			format, err := e.fontFormat(item.Href, item.MediaType)
+			if err != nil {
+				return nil, err
+			}
+			if !contains(p.fonts, format) {
+				add(CheckFontFormat, SeverityError, item.Href, "The font format "+format+" is not accepted")
+			}
+		}
+	}
+
+	screened, err := e.ScreenContent()
+	if err != nil {
+		return nil, err
+	}
+	return append(issues, screened...), nil
+}
+
+// imageConfig returns the size of the image, the formats without a
+// registered decoder return an error
+func (e Epub) imageConfig(href string) (image.Config, error) {
+	data, err := e.readFile(e.rootPath + href)
+	if err != nil {
+		return image.Config{}, err
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	return config, err
+}
+
+// fontFormat returns the media type of the font detected from its content,
+// or the declared one if it can't be detected
+func (e Epub) fontFormat(href, declared string) (string, error) {
+	f, err := e.open(e.rootPath + href)
+	if err != nil {
+		return declared, nil
+	}
+	data, err := sniff(f)
+	f.Close()
+	if err != nil {
+		return "", err
+	}
+	if detected := DetectMediaType(data); strings.HasPrefix(detected, "font/") {
+		return detected, nil
+	}
+	for format, aliases := range mediaTypeAliases {
+		if contains(aliases, declared) && strings.HasPrefix(format, "font/") {
+			return format, nil
+		}
+	}
+	return declared, nil
+}
+
+func (e Epub) hasISBN() bool {
+	for _, elem := range e.metadata["identifier"] {
+		id := ParseIdentifier(elem.Content, elem.Attr["scheme"])
+		if id.Scheme == SchemeISBN && id.Valid {
+			return true
+		}
+	}
+	return false
+}
+
+// packedSize estimates the size of the epub from the compressed size of its
+// files, the staged ones are counted uncompressed
+func (e Epub) packedSize() int64 {
+	var size int64
+	for _, f := range e.zip.File {
+		if _, ok := e.staged[f.Name]; !ok {
+			size += int64(f.CompressedSize64)
+		}
+	}
+	for _, data := range e.staged {
+		size += int64(len(data))
+	}
+	return size
+}
+
+func isFont(mediaType, href string) bool {
+	if strings.Contains(mediaType, "font") || mediaType == "application/vnd.ms-opentype" {
+		return true
+	}
+	switch strings.ToLower(path.Ext(href)) {
+	case ".otf", ".ttf", ".woff", ".woff2", ".pfb", ".pfa":
+		return true
+	}
+	return false
+}
+
+func dimensions(width, height int) string {
+	return strconv.Itoa(width) + "x" + strconv.Itoa(height)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+)
+
+func TestPreflight(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	issues, err := f.Preflight(ProfileApple)
+	if err != nil {
+		t.Fatalf("Preflight() return an error: %v", err)
+	}
+	if len(issues) != 2 || issues[0].Check != CheckCoverSize || issues[0].Severity != SeverityError ||
+		issues[1].Check != CheckISBN || issues[1].Severity != SeverityWarning {
+		t.Errorf("Preflight(apple) return: %v", issues)
+	}
+	if issues, _ := f.Preflight(ProfileAmazon); len(issues) != 0 {
+		t.Errorf("Preflight(amazon) return: %v", issues)
+	}
+	if _, err := f.Preflight("foo"); err == nil {
+		t.Errorf("Preflight(foo) didn't return an error")
+	}
+
+	var img bytes.Buffer
+	png.Encode(&img, image.NewGray(image.Rect(0, 0, 2100, 2000)))
+	f.opf.Manifest = append(f.opf.Manifest,
+		manifest{ID: "big", Href: "big.png", MediaType: "image/png"},
+		manifest{ID: "font", Href: "font.pfb", MediaType: "application/x-font-type1"},
+	)
+	f.stage(f.rootPath+"big.png", img.Bytes())
+	f.stage(f.rootPath+"font.pfb", []byte("%!PS-AdobeFont-1.0: Test"))
+
+	issues, _ = f.Preflight(ProfileApple)
+	if len(issues) != 4 || issues[2].Check != CheckImageSize || issues[2].Href != "big.png" ||
+		issues[3].Check != CheckFontFormat || issues[3].Href != "font.pfb" {
+		t.Errorf("Preflight(apple) return: %v", issues)
+	}
+	issues, _ = f.Preflight(ProfileAmazon)
+	if len(issues) != 1 || issues[0].Check != CheckFontFormat {
+		t.Errorf("Preflight(amazon) return: %v", issues)
+	}
+
+	img.Reset()
+	png.Encode(&img, image.NewGray(image.Rect(0, 0, 2500, 2100)))
+	f.stage(f.rootPath+"big.png", img.Bytes())
+	issues, _ = f.Preflight(ProfileAmazon)
+	if len(issues) != 2 || issues[0].Check != CheckImageSize || issues[0].Href != "big.png" {
+		t.Errorf("Preflight(amazon) of a big image return: %v", issues)
+	}
+	if issues, _ := f.Preflight(ProfileKobo); len(issues) != 3 || issues[2].Check != CheckFontFormat {
+		t.Errorf("Preflight(kobo) return: %v", issues)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// frontMatterTypes are the epub:type and guide types of the documents that
+// are not part of the body matter of the book
+var frontMatterTypes = map[string]bool{
+	"frontmatter": true, "cover": true, "titlepage": true, "title-page": true,
+	"halftitlepage": true, "toc": true, "copyright-page": true, "dedication": true,
+	"epigraph": true, "acknowledgments": true, "acknowledgements": true,
+	"loi": true, "lot": true, "colophon": true, "imprint": true,
+}
+
+// Preview returns the opening of the body matter of the book, at most
+// nChars characters long and ending on a sentence boundary
+//
+// The body matter starts on the document of the guide reference of type
+// "text", if there is none the documents of the front matter (cover, title
+// page, table of contents, ...) are skipped using their epub:type and the
+// guide. If the first sentence is longer than nChars it is cut on a word
+// boundary and finished with an ellipsis.
+func (e Epub) Preview(nChars int) (string, error) {
+	var paragraphs []string
+	length := 0
+	for i := e.bodyMatterStart(); i < e.opf.spineLength(); i++ {
+		if e.opf.spineURL(i) == "" {
+			continue
+		}
+		data, err := e.readFile(e.rootPath + e.opf.spineURL(i))
+		if err != nil {
+			return "", err
+		}
+		for _, line := range strings.Split(extractText(data), "\n") {
+			var sentences []string
+			for _, sentence := range splitSentences(line) {
+				sentenceLen := utf8.RuneCountInString(sentence)
+				if length > 0 {
+					sentenceLen++
+				}
+				if length+sentenceLen > nChars {
+					if length == 0 {
+						return cutWords(sentence, nChars), nil
+					}
+					if len(sentences) > 0 {
+						paragraphs = append(paragraphs, strings.Join(sentences, " "))
+					}
+					return strings.Join(paragraphs, "\n"), nil
+				}
+				sentences = append(sentences, sentence)
+				length += sentenceLen
+			}
+			if len(sentences) > 0 {
+				paragraphs = append(paragraphs, strings.Join(sentences, " "))
+			}
+		}
+	}
+	return strings.Join(paragraphs, "\n"), nil
+}
+
+// bodyMatterStart returns the spine index of the first document of the body
+// matter
+func (e Epub) bodyMatterStart() int {
+	guideTypes := make(map[string]string)
+	for _, ref := range e.opf.Guide {
+		href := strings.SplitN(ref.Href, "#", 2)[0]
+		if ref.Type == "text" || ref.Type == "bodymatter" {
+			if i := e.opf.spineIndex(href); i != -1 {
+				return i
+			}
+		}
+		guideTypes[href] = ref.Type
+	}
+
+	for i, item := range e.opf.Spine.Items {
+		href := e.opf.spineURL(i)
+		if href == "" || item.Linear == "no" || frontMatterTypes[guideTypes[href]] {
+			continue
+		}
+		data, err := e.readFile(e.rootPath + href)
+		if err != nil || isFrontMatter(data) || strings.TrimSpace(extractText(data)) == "" {
+			continue
+		}
+		return i
+	}
+	if first := e.opf.nextSpineItem(-1, 1); first != -1 {
+		return first
+	}
+	return 0
+}
+
+// isFrontMatter returns whether the epub:type of the body of the document, or
+// of its first element, is front matter
+func isFrontMatter(data []byte) bool {
+	loc := bodyStartRegexp.FindIndex(data)
+	if loc == nil {
+		return false
+	}
+	tags := []string{string(data[loc[0]:loc[1]])}
+	if tag := htmlTagRegexp.Find(data[loc[1]:]); tag != nil {
+		tags = append(tags, string(tag))
+	}
+	for _, tag := range tags {
+		for _, t := range strings.Fields(attrValue(tag, "epub:type")) {
+			if frontMatterTypes[t] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cutWords returns the text cut on the last word that fits in nChars
+// characters, ellipsis included
+func cutWords(text string, nChars int) string {
+	runes := []rune(text)
+	if len(runes) <= nChars {
+		return text
+	}
+	if nChars < 1 {
+		return ""
+	}
+	cut := string(runes[:nChars-1])
+	if i := strings.LastIndexAny(cut, " \t"); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,;:") + "…"
+}
@@ -0,0 +1,48 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+func TestPreview(t *testing.T) {
+	f := buildEpub(t, "testdata/epub3.opf", map[string]string{
+		"text/ch1.xhtml": `<html><body epub:type="frontmatter"><section epub:type="titlepage"><h1>The Book</h1></section></body></html>`,
+		"text/ch2.xhtml": `<html><body><section epub:type="bodymatter chapter"><p>It was a dark night. The rain fell. Nobody came.</p><p>Then the sun rose.</p></section></body></html>`,
+	})
+	defer f.Close()
+
+	tests := map[int]string{
+		20: "It was a dark night.",
+		36: "It was a dark night. The rain fell.",
+		70: "It was a dark night. The rain fell. Nobody came.\nThen the sun rose.",
+		10: "It was a…",
+	}
+	for n, expected := range tests {
+		preview, err := f.Preview(n)
+		if err != nil {
+			t.Fatalf("Preview(%d) return an error: %v", n, err)
+		}
+		if preview != expected {
+			t.Errorf("Preview(%d) return: %q when was expected: %q", n, preview, expected)
+		}
+	}
+}
+
+func TestPreviewGuide(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	start := f.bodyMatterStart()
+	if start < 0 || start >= f.opf.spineLength() {
+		t.Errorf("bodyMatterStart() return: %v", start)
+	}
+	preview, err := f.Preview(200)
+	if err != nil {
+		t.Fatalf("Preview() return an error: %v", err)
+	}
+	if preview == "" || len([]rune(preview)) > 200 {
+		t.Errorf("Preview(200) return: %q", preview)
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"strings"
+	"unicode"
+)
+
+// richnessWindow is the number of words of each segment used to measure the
+// vocabulary richness
+const richnessWindow = 1000
+
+// ContentSignal computes a custom feature from the text of the book
+type ContentSignal func(text string) float64
+
+// ContentProfile are simple signals of the style of the book, useful to
+// classify or recommend it
+type ContentProfile struct {
+	Words     int
+	Sentences int
+	// DialogueRatio is the fraction of the words inside quotation marks or
+	// on lines starting with a dash
+	DialogueRatio float64
+	// AverageSentenceLength is measured in words
+	AverageSentenceLength float64
+	// VocabularyRichness is the average ratio of distinct words on each
+	// segment of 1000 words of the text, so it doesn't depend on its length
+	VocabularyRichness float64
+	// Signals are the values of the custom signals by name
+	Signals map[string]float64
+}
+
+// ContentProfile computes the profile of the text of the spine, as returned
+// by Text
+//
+// Each of the signals is called with the whole text of the book and its
+// value stored on Signals with the same name.
+func (e Epub) ContentProfile(signals map[string]ContentSignal) (*ContentProfile, error) {
+	var (
+		profile  ContentProfile
+		dialogue int
+		texts    []string
+		richness richnessCounter
+	)
+	for i := 0; i < e.opf.spineLength(); i++ {
+		if e.opf.spineURL(i) == "" {
+			continue
+		}
+		text, err := e.Text(i)
+		if err != nil {
+			return nil, err
+		}
+		words := profileWords(text)
+		profile.Words += len(words)
+		profile.Sentences += len(splitSentences(text))
+		dialogue += dialogueWords(text)
+		richness.add(words)
+		if len(signals) > 0 {
+			texts = append(texts, text)
+		}
+	}
+
+	if profile.Words > 0 {
+		profile.DialogueRatio = float64(dialogue) / float64(profile.Words)
+		profile.VocabularyRichness = richness.value()
+	}
+	if profile.Sentences > 0 {
+		profile.AverageSentenceLength = float64(profile.Words) / float64(profile.Sentences)
+	}
+	if len(signals) > 0 {
+		text := strings.Join(texts, "\n")
+		profile.Signals = make(map[string]float64, len(signals))
+		for name, signal := range signals {
+			profile.Signals[name] = signal(text)
+		}
+	}
+	return &profile, nil
+}
+
+// profileWords returns the lowercase words of the text
+func profileWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '\'' && r != '’'
+	})
+}
+
+// dialogueWords returns the number of words of the text that are dialogue
+func dialogueWords(text string) int {
+	count := 0
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "—") || strings.HasPrefix(trimmed, "–") {
+			count += len(profileWords(trimmed))
+			continue
+		}
+		for _, quote := range quoteRegexp.FindAllString(line, -1) {
+			count += len(profileWords(quote))
+		}
+	}
+	return count
+}
+
+// richnessCounter computes the mean type-token ratio of the consecutive
+// segments of richnessWindow words
+type richnessCounter struct {
+	segment  map[string]bool
+	words    int
+	ratios   float64
+	segments int
+}
+
+func (c *richnessCounter) add(words []string) {
+	for _, word := range words {
+		if c.segment == nil {
+			c.segment = make(map[string]bool)
+		}
+		c.segment[word] = true
+		c.words++
+		if c.words == richnessWindow {
+			c.ratios += float64(len(c.segment)) / richnessWindow
+			c.segments++
+			c.segment = nil
+			c.words = 0
+		}
+	}
+}
+
+func (c richnessCounter) value() float64 {
+	if c.segments == 0 {
+		if c.words == 0 {
+			return 0
+		}
+		return float64(len(c.segment)) / float64(c.words)
+	}
+	return c.ratios / float64(c.segments)
+}
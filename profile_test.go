@@ -0,0 +1,48 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import "strings"
+
+func TestContentProfile(t *testing.T) {
+	f := buildEpub(t, "testdata/epub3.opf", map[string]string{
+		"text/ch1.xhtml": `<html><body><p>The dog ran home. “Stop the dog,” she said.</p></body></html>`,
+		"text/ch2.xhtml": `<html><body><p>— Who is there?</p><p>Nobody answered.</p></body></html>`,
+	})
+	defer f.Close()
+
+	signals := map[string]ContentSignal{
+		"questions": func(text string) float64 { return float64(strings.Count(text, "?")) },
+	}
+	profile, err := f.ContentProfile(signals)
+	if err != nil {
+		t.Fatalf("ContentProfile() return an error: %v", err)
+	}
+	if profile.Words != 14 || profile.Sentences != 4 {
+		t.Errorf("ContentProfile() return %v words and %v sentences", profile.Words, profile.Sentences)
+	}
+	if profile.DialogueRatio != 6.0/14 {
+		t.Errorf("DialogueRatio: %v", profile.DialogueRatio)
+	}
+	if profile.AverageSentenceLength != 3.5 {
+		t.Errorf("AverageSentenceLength: %v", profile.AverageSentenceLength)
+	}
+	if profile.VocabularyRichness != 12.0/14 {
+		t.Errorf("VocabularyRichness: %v", profile.VocabularyRichness)
+	}
+	if profile.Signals["questions"] != 1 {
+		t.Errorf("Signals: %v", profile.Signals)
+	}
+}
+
+func TestVocabularyRichness(t *testing.T) {
+	var c richnessCounter
+	c.add(strings.Fields(strings.Repeat("a b ", 1000)))
+	if v := c.value(); v != 0.002 {
+		t.Errorf("richnessCounter.value() return: %v", v)
+	}
+}
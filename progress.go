@@ -0,0 +1,35 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+// Stages of the long operations reported to the ProgressFunc
+const (
+	// StageRepack is reported by Repack for each entry written
+	StageRepack = "repack"
+	// StageExtract is reported by ExtractAll for each resource extracted
+	StageExtract = "extract"
+	// StageIntegrity is reported by IntegrityManifest and Verify for each
+	// file hashed
+	StageIntegrity = "integrity"
+)
+
+// ProgressFunc is called by the long operations after processing each
+// file, with the number of files processed and the total on the stage
+//
+// It is called with current 0 when the stage starts. The calls are never
+// concurrent, even if the operation processes the files in parallel.
+type ProgressFunc func(stage string, current, total int)
+
+// SetProgress sets the function that reports the progress of the long
+// operations, nil disables the reporting
+func (e *Epub) SetProgress(fn ProgressFunc) {
+	e.progress = fn
+}
+
+func (e Epub) report(stage string, current, total int) {
+	if e.progress != nil {
+		e.progress(stage, current, total)
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+)
+
+func TestProgress(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	last := make(map[string]int)
+	totals := make(map[string]int)
+	f.SetProgress(func(stage string, current, total int) {
+		if current != last[stage]+1 && current != 0 {
+			t.Errorf("Progress of %s jumped from %d to %d", stage, last[stage], current)
+		}
+		last[stage] = current
+		totals[stage] = total
+	})
+	err := f.ExtractAll(context.Background(), 4, func(item ManifestItem, r io.Reader) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExtractAll() return an error: %v", err)
+	}
+	f.remove(f.rootPath + coverFile)
+	if err := f.Repack(ioutil.Discard); err != nil {
+		t.Fatalf("Repack() return an error: %v", err)
+	}
+	if _, err := f.IntegrityManifest(); err != nil {
+		t.Fatalf("IntegrityManifest() return an error: %v", err)
+	}
+
+	for _, stage := range []string{StageExtract, StageRepack, StageIntegrity} {
+		if totals[stage] == 0 || last[stage] != totals[stage] {
+			t.Errorf("Progress of %s ended on %d of %d", stage, last[stage], totals[stage])
+		}
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+// HasProperty returns whether property is on the space separated list of
+// properties of the item, like "nav", "cover-image" or "scripted"
+//
+// The properties are compared whole, so "svg" doesn't match "scripted-svg".
+func (item ManifestItem) HasProperty(property string) bool {
+	return hasProperty(item.Properties, property)
+}
+
+// ItemsWithProperty returns the items of the manifest with the property,
+// like "remote-resources" or "mathml", in the order of the manifest
+func (e Epub) ItemsWithProperty(property string) []ManifestItem {
+	var items []ManifestItem
+	for _, item := range e.Manifest() {
+		if item.HasProperty(property) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// SpineItemsWithProperty returns the indexes of the spine of the itemrefs
+// with the property, like "page-spread-left" or "rendition:layout-pre-paginated"
+func (e Epub) SpineItemsWithProperty(property string) []int {
+	var indexes []int
+	for i, item := range e.opf.Spine.Items {
+		if hasProperty(item.Properties, property) {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
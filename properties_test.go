@@ -0,0 +1,36 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+func TestItemsWithProperty(t *testing.T) {
+	f, err := New("", "Title", "en")
+	if err != nil {
+		t.Fatalf("New() return an error: %v", err)
+	}
+	f.InsertDocument("ch1.xhtml", "", []byte(`<html><body><p>Text</p></body></html>`), -1)
+	f.InsertDocument("ch2.xhtml", "", []byte(`<html><body><p>Text</p></body></html>`), -1)
+	f.opf.manifestItem("ch1").Properties = "scripted-svg  remote-resources"
+	f.opf.manifestItem("ch2").Properties = "scripted svg"
+	f.opf.Spine.Items[1].Properties = "page-spread-left"
+
+	items := f.ItemsWithProperty("svg")
+	if len(items) != 1 || items[0].Href != "ch2.xhtml" {
+		t.Errorf("ItemsWithProperty(svg) return: %v", items)
+	}
+	if items := f.ItemsWithProperty("remote-resources"); len(items) != 1 || items[0].Href != "ch1.xhtml" {
+		t.Errorf("ItemsWithProperty(remote-resources) return: %v", items)
+	}
+	if items := f.ItemsWithProperty("nav"); len(items) != 1 || !items[0].HasProperty("nav") || items[0].HasProperty("na") {
+		t.Errorf("ItemsWithProperty(nav) return: %v", items)
+	}
+	if items := f.ItemsWithProperty("cover-image"); len(items) != 0 {
+		t.Errorf("ItemsWithProperty(cover-image) return: %v", items)
+	}
+	if indexes := f.SpineItemsWithProperty("page-spread-left"); len(indexes) != 1 || indexes[0] != 1 {
+		t.Errorf("SpineItemsWithProperty() return: %v", indexes)
+	}
+}
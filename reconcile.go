@@ -0,0 +1,164 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"io"
+	"strings"
+)
+
+// MetadataConflict is a metadata field with different values on the book and
+// on another source
+type MetadataConflict struct {
+	Field string
+	// Book and Other are the values of the field, empty if the source doesn't
+	// have it
+	Book  []string
+	Other []string
+}
+
+// ReadSidecar parses the metadata of an OPF file kept next to the epub, like
+// the metadata.opf of calibre libraries
+//
+// The metadata is returned with the same field names used by Metadata(), to
+// be used with Reconcile or ApplyMetadata.
+func ReadSidecar(r io.Reader) (map[string][]MdataElement, error) {
+	opf, err := parseOPF(r)
+	if err != nil {
+		return nil, err
+	}
+	return opf.toMData(), nil
+}
+
+// Reconcile compares the metadata of the book with other, returns the fields
+// that differ
+//
+// Only the Dublin Core fields are compared, the values are compared after
+// collapsing the whitespace. The publication dates are compared by their day,
+// the descriptions by their text, the identifiers by their normalized values
+// and the languages without case. The identifiers are only reported if other
+// has some that the book doesn't, those are the Other values of the conflict.
+// The calibre identifiers of other (calibre and uuid schemes) are ignored,
+// calibre adds them to every book.
+func (e Epub) Reconcile(other map[string][]MdataElement) []MetadataConflict {
+	var conflicts []MetadataConflict
+	for _, field := range metadataFieldOrder {
+		if field == "meta" {
+			continue
+		}
+		book := reconcileValues(field, e.metadata[field])
+		theirs := reconcileValues(field, other[field])
+		if field == "identifier" {
+			theirs = missingValues(theirs, book)
+			book = nil
+			if len(theirs) == 0 {
+				continue
+			}
+		}
+		if !equalValues(book, theirs) {
+			conflicts = append(conflicts, MetadataConflict{field, book, theirs})
+		}
+	}
+	return conflicts
+}
+
+// ReconcileWith reports the conflicts of the metadata of the book with other,
+// like Reconcile, and applies other to the book following the policy
+//
+// With FillMissing only the fields that the book doesn't have are taken from
+// other, with PreferProvider the fields of other replace the ones of the
+// book. The identifiers are never replaced, the ones of other not present on
+// the book are added after them, so the unique identifier is preserved. The
+// changes are written with Repack.
+func (e *Epub) ReconcileWith(other map[string][]MdataElement, policy MergePolicy) ([]MetadataConflict, error) {
+	conflicts := e.Reconcile(other)
+	updates := make(map[string][]MdataElement)
+	for _, c := range conflicts {
+		if c.Field != "identifier" {
+			updates[c.Field] = other[c.Field]
+			continue
+		}
+
+		missing := make(map[string]bool)
+		for _, value := range c.Other {
+			missing[value] = true
+		}
+		identifiers := append([]MdataElement(nil), e.metadata["identifier"]...)
+		for _, elem := range other["identifier"] {
+			value := identifierKey(elem)
+			if missing[value] {
+				identifiers = append(identifiers, elem)
+				missing[value] = false
+			}
+		}
+		if err := e.SetMetadata("identifier", identifiers); err != nil {
+			return conflicts, err
+		}
+	}
+	return conflicts, e.ApplyMetadata(updates, policy)
+}
+
+// reconcileValues returns the normalized values of the elements of the field
+func reconcileValues(field string, elems []MdataElement) []string {
+	var values []string
+	for _, elem := range elems {
+		value := strings.Join(strings.Fields(elem.Content), " ")
+		switch field {
+		case "date":
+			if event := elem.Attr["event"]; event != "" && event != "publication" {
+				continue
+			}
+			if len(value) > 10 {
+				value = value[:10]
+			}
+		case "description":
+			value = strings.Join(strings.Fields(extractText([]byte(elem.Content))), " ")
+		case "language":
+			value = strings.ToLower(value)
+		case "identifier":
+			scheme := strings.ToLower(elem.Attr["scheme"])
+			if scheme == "calibre" || scheme == "uuid" {
+				continue
+			}
+			value = identifierKey(elem)
+		}
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func identifierKey(elem MdataElement) string {
+	id := ParseIdentifier(elem.Content, elem.Attr["scheme"])
+	return strings.ToLower(id.Value)
+}
+
+// missingValues returns the values of a not present on b
+func missingValues(a, b []string) []string {
+	present := make(map[string]bool)
+	for _, value := range b {
+		present[value] = true
+	}
+	var missing []string
+	for _, value := range a {
+		if !present[value] {
+			missing = append(missing, value)
+		}
+	}
+	return missing
+}
+
+func equalValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
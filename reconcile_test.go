@@ -0,0 +1,80 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import "strings"
+
+const sidecar = `<?xml version='1.0' encoding='utf-8'?>
+<package xmlns="http://www.idpf.org/2007/opf" unique-identifier="uuid_id" version="2.0">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:opf="http://www.idpf.org/2007/opf">
+    <dc:identifier opf:scheme="calibre" id="calibre_id">42</dc:identifier>
+    <dc:identifier opf:scheme="uuid" id="uuid_id">0b4e3f3c-5f8a-4c3e-9d8b-6c1f2e3d4a5b</dc:identifier>
+    <dc:identifier opf:scheme="ISBN">9780306406157</dc:identifier>
+    <dc:title>A Dog's Tale</dc:title>
+    <dc:creator opf:file-as="Twain, Mark" opf:role="aut">Mark Twain</dc:creator>
+    <dc:date>2004-06-01T00:00:00+00:00</dc:date>
+    <dc:publisher>Harper</dc:publisher>
+    <dc:language>EN</dc:language>
+    <meta name="calibre:series" content="Stories"/>
+  </metadata>
+</package>`
+
+func TestReconcile(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	other, err := ReadSidecar(strings.NewReader(sidecar))
+	if err != nil {
+		t.Fatalf("ReadSidecar() return an error: %v", err)
+	}
+	conflicts := f.Reconcile(other)
+	fields := make(map[string]MetadataConflict)
+	for _, c := range conflicts {
+		fields[c.Field] = c
+	}
+	for _, field := range []string{"title", "creator", "date", "language"} {
+		if _, ok := fields[field]; ok {
+			t.Errorf("Reconcile() reported a conflict on %v: %v", field, fields[field])
+		}
+	}
+	if c, ok := fields["publisher"]; !ok || len(c.Other) != 1 || c.Other[0] != "Harper" {
+		t.Errorf("Reconcile() publisher conflict: %v", c)
+	}
+	if c, ok := fields["identifier"]; !ok || len(c.Other) != 1 || c.Other[0] != "9780306406157" {
+		t.Errorf("Reconcile() identifier conflict: %v", c)
+	}
+}
+
+func TestReconcileWith(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+	other, _ := ReadSidecar(strings.NewReader(sidecar))
+	other["title"] = []MdataElement{{Content: "A Dog's Tale: A Story"}}
+	uid, _ := f.Metadata("identifier")
+
+	if _, err := f.ReconcileWith(other, FillMissing); err != nil {
+		t.Fatalf("ReconcileWith() return an error: %v", err)
+	}
+	if title, _ := f.Metadata("title"); title[0] != "A Dog's Tale" {
+		t.Errorf("ReconcileWith(FillMissing) replaced the title: %v", title)
+	}
+	if publisher, _ := f.Metadata("publisher"); len(publisher) != 1 || publisher[0] != "Harper" {
+		t.Errorf("ReconcileWith(FillMissing) publisher: %v", publisher)
+	}
+	identifiers, _ := f.Metadata("identifier")
+	if len(identifiers) != len(uid)+1 || identifiers[0] != uid[0] {
+		t.Errorf("ReconcileWith() identifiers: %v", identifiers)
+	}
+
+	f.ReconcileWith(other, PreferProvider)
+	if title, _ := f.Metadata("title"); title[0] != "A Dog's Tale: A Story" {
+		t.Errorf("ReconcileWith(PreferProvider) title: %v", title)
+	}
+	if identifiers, _ := f.Metadata("identifier"); len(identifiers) != len(uid)+1 {
+		t.Errorf("ReconcileWith() added the identifiers twice: %v", identifiers)
+	}
+}
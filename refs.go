@@ -0,0 +1,221 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	refAttrRegexp = regexp.MustCompile(`(?i)(\s(?:href|src|xlink:href|poster|data)\s*=\s*)("[^"]*"|'[^']*')`)
+	srcsetRegexp  = regexp.MustCompile(`(?i)(\s(?:srcset|imagesrcset)\s*=\s*)("[^"]*"|'[^']*')`)
+	cssURLRegexp  = regexp.MustCompile(`(url\(\s*)("[^"]*"|'[^']*'|[^'")\s]+)(\s*\))`)
+	cssImpRegexp  = regexp.MustCompile(`(@import\s+)("[^"]*"|'[^']*')`)
+	itemTagRegexp = regexp.MustCompile(`<([\w-]+:)?item\s[^>]*>`)
+)
+
+// ResolveHref returns the path, as used by OpenFile, of the reference ref
+// found on the document baseDoc (also a path as used by OpenFile)
+//
+// The reference is percent decoded, its ./ and ../ are resolved and its
+// fragment (#...) is kept. It returns an empty string for external
+// references and for the ones pointing outside of the root directory.
+func (e Epub) ResolveHref(baseDoc, ref string) string {
+	fragment := ""
+	if i := strings.Index(ref, "#"); i != -1 {
+		fragment = ref[i:]
+	}
+	if ref == "" || strings.IndexAny(ref, "#?") == 0 {
+		return baseDoc + fragment
+	}
+
+	target := resolveRef(e.rootPath+baseDoc, ref)
+	if target == "" || !strings.HasPrefix(target, e.rootPath) || strings.HasPrefix(target, "../") {
+		return ""
+	}
+	return strings.TrimPrefix(target, e.rootPath) + fragment
+}
+
+// RelativizeHref returns the reference to use on the document fromDoc to
+// point to target, both paths as used by OpenFile
+//
+// The fragment (#...) of target is kept and the path is percent encoded.
+func (e Epub) RelativizeHref(fromDoc, target string) string {
+	fragment := ""
+	if i := strings.Index(target, "#"); i != -1 {
+		target, fragment = target[:i], target[i:]
+	}
+	if target == fromDoc && fragment != "" {
+		return fragment
+	}
+	return relativeRef(e.rootPath+fromDoc, e.rootPath+target) + fragment
+}
+
+// references returns the paths inside the zip of the files referenced by the
+// document docPath
+func references(data []byte, docPath string) []string {
+	var refs []string
+	eachRef(data, func(ref string) string {
+		if target := resolveRef(docPath, ref); target != "" {
+			refs = append(refs, target)
+		}
+		return ref
+	})
+	return refs
+}
+
+// rewriteRefs rewrites the references of the document docPath following the
+// renames map, that maps paths inside the zip to their new paths
+func rewriteRefs(data []byte, docPath string, renames map[string]string) []byte {
+	return eachRef(data, func(ref string) string {
+		return moveRef(docPath, docPath, ref, renames)
+	})
+}
+
+// moveRef returns the reference ref of the document docPath fixed for the
+// document moved to newDocPath and the files moved following renames
+func moveRef(docPath, newDocPath, ref string, renames map[string]string) string {
+	target := resolveRef(docPath, ref)
+	if target == "" {
+		return ref
+	}
+	newTarget, ok := renames[target]
+	if !ok {
+		if docPath == newDocPath {
+			return ref
+		}
+		newTarget = target
+	}
+	newRef := relativeRef(newDocPath, newTarget)
+	if i := strings.IndexAny(ref, "#?"); i != -1 {
+		newRef += ref[i:]
+	}
+	return newRef
+}
+
+// eachRef calls fn with every reference (href, src, srcset candidates, css
+// url(), ...) of the document and replaces the reference with its return value
+func eachRef(data []byte, fn func(ref string) string) []byte {
+	replace := func(re *regexp.Regexp, data []byte, fn func(ref string) string) []byte {
+		return re.ReplaceAllFunc(data, func(match []byte) []byte {
+			sub := re.FindSubmatch(match)
+			value := string(sub[2])
+			quote := ""
+			if value != "" && (value[0] == '"' || value[0] == '\'') {
+				quote = value[:1]
+				value = value[1 : len(value)-1]
+			}
+			tail := ""
+			if len(sub) > 3 {
+				tail = string(sub[3])
+			}
+			return []byte(string(sub[1]) + quote + fn(value) + quote + tail)
+		})
+	}
+	data = replace(refAttrRegexp, data, fn)
+	data = replace(srcsetRegexp, data, func(srcset string) string { return eachSrcsetURL(srcset, fn) })
+	data = replace(cssURLRegexp, data, fn)
+	return replace(cssImpRegexp, data, fn)
+}
+
+// eachSrcsetURL calls fn with the URL of every candidate of the srcset and
+// replaces it with its return value, the descriptors (2x, 300w, ...) are kept
+func eachSrcsetURL(srcset string, fn func(ref string) string) string {
+	var buff strings.Builder
+	for srcset != "" {
+		i := strings.IndexFunc(srcset, func(r rune) bool { return !isSrcsetSpace(r) && r != ',' })
+		if i == -1 {
+			buff.WriteString(srcset)
+			break
+		}
+		buff.WriteString(srcset[:i])
+		srcset = srcset[i:]
+
+		end := strings.IndexFunc(srcset, isSrcsetSpace)
+		if end == -1 {
+			end = len(srcset)
+		}
+		ref := strings.TrimRight(srcset[:end], ",")
+		buff.WriteString(fn(ref))
+		srcset = srcset[len(ref):]
+		if len(ref) < end {
+			continue
+		}
+
+		end = strings.Index(srcset, ",")
+		if end == -1 {
+			end = len(srcset)
+		}
+		buff.WriteString(srcset[:end])
+		srcset = srcset[end:]
+	}
+	return buff.String()
+}
+
+func isSrcsetSpace(r rune) bool {
+	return strings.ContainsRune(" \t\n\f\r", r)
+}
+
+// resolveRef returns the path inside the zip of ref relative to docPath, or
+// an empty string if it is an external reference
+func resolveRef(docPath, ref string) string {
+	if i := strings.IndexAny(ref, "#?"); i != -1 {
+		ref = ref[:i]
+	}
+	if ref == "" || strings.Contains(ref, ":") {
+		return ""
+	}
+	if unescaped, err := url.PathUnescape(ref); err == nil {
+		ref = unescaped
+	}
+	if strings.HasPrefix(ref, "/") {
+		return strings.TrimPrefix(path.Clean(ref), "/")
+	}
+	return path.Join(path.Dir(docPath), ref)
+}
+
+// relativeRef returns the reference to target from the document docPath
+func relativeRef(docPath, target string) string {
+	from := strings.Split(path.Dir(docPath), "/")
+	if from[0] == "." {
+		from = nil
+	}
+	to := strings.Split(target, "/")
+	i := 0
+	for i < len(from) && i < len(to)-1 && from[i] == to[i] {
+		i++
+	}
+	parts := make([]string, 0, len(from)-i+len(to)-i)
+	for j := i; j < len(from); j++ {
+		parts = append(parts, "..")
+	}
+	parts = append(parts, to[i:]...)
+	return (&url.URL{Path: strings.Join(parts, "/")}).EscapedPath()
+}
+
+// attrValue returns the value of the attribute name on an XML tag
+func attrValue(tag, name string) string {
+	re := regexp.MustCompile(`\s` + regexp.QuoteMeta(name) + `\s*=\s*("[^"]*"|'[^']*')`)
+	sub := re.FindStringSubmatch(tag)
+	if sub == nil {
+		return ""
+	}
+	return sub[1][1 : len(sub[1])-1]
+}
+
+// isMarkup returns whether the media type is a document that can contain
+// references to other files of the epub
+func isMarkup(mediaType string) bool {
+	switch mediaType {
+	case "application/xhtml+xml", "text/html", "text/css", "image/svg+xml",
+		"application/x-dtbncx+xml", "application/oebps-package+xml", "application/smil+xml",
+		"application/smil", dtbookMediaType:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+func TestResolveHref(t *testing.T) {
+	e := Epub{rootPath: "OEBPS/"}
+	tests := []struct{ base, ref, href string }{
+		{"text/ch1.xhtml", "ch2.xhtml", "text/ch2.xhtml"},
+		{"text/ch1.xhtml", "./ch2.xhtml#p1", "text/ch2.xhtml#p1"},
+		{"text/ch1.xhtml", "../images/a%20b.png", "images/a b.png"},
+		{"text/ch1.xhtml", "#note", "text/ch1.xhtml#note"},
+		{"text/ch1.xhtml", "/OEBPS/style.css", "style.css"},
+		{"text/ch1.xhtml", "../../META-INF/container.xml", ""},
+		{"text/ch1.xhtml", "http://example.com/", ""},
+	}
+	for _, test := range tests {
+		if href := e.ResolveHref(test.base, test.ref); href != test.href {
+			t.Errorf("ResolveHref(%q, %q) return: %q when was expected: %q", test.base, test.ref, href, test.href)
+		}
+	}
+}
+
+func TestRelativizeHref(t *testing.T) {
+	e := Epub{rootPath: "OEBPS/"}
+	tests := []struct{ from, target, ref string }{
+		{"text/ch1.xhtml", "text/ch2.xhtml", "ch2.xhtml"},
+		{"text/ch1.xhtml", "images/a b.png", "../images/a%20b.png"},
+		{"text/ch1.xhtml", "text/ch1.xhtml#note", "#note"},
+		{"toc.ncx", "text/ch2.xhtml#p1", "text/ch2.xhtml#p1"},
+	}
+	for _, test := range tests {
+		ref := e.RelativizeHref(test.from, test.target)
+		if ref != test.ref {
+			t.Errorf("RelativizeHref(%q, %q) return: %q when was expected: %q", test.from, test.target, ref, test.ref)
+		}
+		if href := e.ResolveHref(test.from, ref); href != test.target {
+			t.Errorf("ResolveHref(%q, %q) return: %q when was expected: %q", test.from, ref, href, test.target)
+		}
+	}
+}
+
+func TestRewriteSrcset(t *testing.T) {
+	data := `<img src="a.jpg" srcset="a.jpg, b.jpg 2x,c.jpg  900w , http://example.com/d.jpg 3x"/>`
+	renames := map[string]string{"OEBPS/text/a.jpg": "OEBPS/images/a.jpg", "OEBPS/text/c.jpg": "OEBPS/images/c.jpg"}
+	expected := `<img src="../images/a.jpg" srcset="../images/a.jpg, b.jpg 2x,../images/c.jpg  900w , http://example.com/d.jpg 3x"/>`
+	if result := string(rewriteRefs([]byte(data), "OEBPS/text/ch1.xhtml", renames)); result != expected {
+		t.Errorf("rewriteRefs() return: %q", result)
+	}
+}
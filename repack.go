@@ -0,0 +1,195 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+const mimetypeName = "mimetype"
+
+// Transform rewrites the content of an entry while repacking the epub
+//
+// name is the path of the entry inside the zip file and mediaType the media
+// type declared for it on the manifest (empty if it is not on the manifest).
+// Returning a nil reader drops the entry from the repacked epub.
+type Transform func(name, mediaType string, r io.Reader) (io.Reader, error)
+
+// Repack writes the epub into w, applying the transforms in order to each entry
+//
+// The mimetype entry is always written first and uncompressed, as required by
+// the OCF spec, and it is never passed to the transforms. Staged files replace
+// the original entries, new ones are appended at the end and removed ones are
+// skipped. If the metadata
+// was modified the OPF is updated accordingly. The epubs over the limits of
+// the classic zip format (4 GB files or 65535 entries) are written as Zip64.
+//
+// The entries that are not modified, including the ones epubgo doesn't know
+// about, are copied byte by byte with their compressed data and headers.
+// When the OPF is rewritten only the modified sections change, and the
+// elements of the metadata that epubgo doesn't manage (extension elements,
+// EPUB 3 links, comments and processing instructions) are kept verbatim.
+func (e Epub) Repack(w io.Writer, transforms ...Transform) error {
+	pending, err := e.pendingFiles()
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(w)
+
+	files := make([]*zip.File, 0, len(e.zip.File))
+	for _, f := range e.zip.File {
+		if f.Name == mimetypeName {
+			files = append([]*zip.File{f}, files...)
+		} else {
+			files = append(files, f)
+		}
+	}
+
+	newFiles := e.newFiles()
+	total := len(files) + len(newFiles)
+	e.report(StageRepack, 0, total)
+	for i, f := range files {
+		if data, ok := pending[f.Name]; ok {
+			if data == nil {
+				e.report(StageRepack, i+1, total)
+				continue
+			}
+			err = e.repackEntry(zw, f.FileHeader, bytes.NewReader(data), transforms)
+		} else if len(transforms) == 0 || f.Name == mimetypeName || strings.HasSuffix(f.Name, "/") {
+			err = zw.Copy(f)
+		} else {
+			err = e.repackFile(zw, f, transforms)
+		}
+		if err != nil {
+			return err
+		}
+		e.report(StageRepack, i+1, total)
+	}
+
+	for i, name := range newFiles {
+		header := zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()}
+		err := e.repackEntry(zw, header, bytes.NewReader(pending[name]), transforms)
+		if err != nil {
+			return err
+		}
+		e.report(StageRepack, len(files)+i+1, total)
+	}
+	return zw.Close()
+}
+
+// repackFile writes the file applying the transforms, the files small
+// enough to be buffered that are left unchanged by the transforms are copied
+// byte by byte
+func (e Epub) repackFile(zw *zip.Writer, f *zip.File, transforms []Transform) error {
+	rc, err := openZipFile(f)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if f.UncompressedSize64 > maxBufferedSize {
+		return e.repackEntry(zw, f.FileHeader, rc, transforms)
+	}
+
+	data, err := readAll(rc)
+	if err != nil {
+		return err
+	}
+	r, err := e.transform(f.Name, bytes.NewReader(data), transforms)
+	if err != nil || r == nil {
+		return err
+	}
+	transformed, err := readAll(r)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(transformed, data) {
+		return zw.Copy(f)
+	}
+	return writeEntry(zw, f.FileHeader, bytes.NewReader(transformed))
+}
+
+func (e Epub) repackEntry(zw *zip.Writer, fh zip.FileHeader, r io.Reader, transforms []Transform) error {
+	r, err := e.transform(fh.Name, r, transforms)
+	if err != nil || r == nil {
+		return err
+	}
+	return writeEntry(zw, fh, r)
+}
+
+// transform applies the transforms to the entry name, it returns a nil
+// reader if the entry is dropped
+func (e Epub) transform(name string, r io.Reader, transforms []Transform) (io.Reader, error) {
+	if name == mimetypeName {
+		return r, nil
+	}
+	var err error
+	mediaType := e.opf.mediaType(strings.TrimPrefix(name, e.rootPath))
+	for _, transform := range transforms {
+		r, err = transform(name, mediaType, r)
+		if err != nil || r == nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// writeEntry writes the content of r as a new entry, keeping the comment and
+// the attributes of the original header
+func writeEntry(zw *zip.Writer, fh zip.FileHeader, r io.Reader) error {
+	header := &zip.FileHeader{
+		Name:          fh.Name,
+		Comment:       fh.Comment,
+		NonUTF8:       fh.NonUTF8,
+		Method:        fh.Method,
+		Modified:      fh.Modified,
+		ExternalAttrs: fh.ExternalAttrs,
+	}
+	fw, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(fw, r)
+	return err
+}
+
+// fileNames returns the names of all the files of the epub, including the staged ones
+func (e Epub) fileNames() []string {
+	var names []string
+	for _, f := range e.zip.File {
+		if data, ok := e.staged[f.Name]; ok && data == nil {
+			continue
+		}
+		if !strings.HasSuffix(f.Name, "/") {
+			names = append(names, f.Name)
+		}
+	}
+	return append(names, e.newFiles()...)
+}
+
+// newFiles returns the sorted names of the staged files not present on the zip
+func (e Epub) newFiles() []string {
+	var names []string
+	for name, data := range e.staged {
+		if data != nil && !e.inZip(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (e Epub) inZip(name string) bool {
+	for _, f := range e.zip.File {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
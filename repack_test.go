@@ -0,0 +1,169 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+const (
+	cssFile = "pgepub.css"
+)
+
+func repackBook(t *testing.T, f *Epub, transforms ...Transform) *Epub {
+	var buff bytes.Buffer
+	if err := f.Repack(&buff, transforms...); err != nil {
+		t.Fatalf("Repack() return an error: %v", err)
+	}
+	book, err := Load(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+	if err != nil {
+		t.Fatalf("Load() of the repacked epub return an error: %v", err)
+	}
+	return book
+}
+
+func TestRepack(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	book := repackBook(t, f)
+	if title, _ := book.Metadata("title"); title[0] != bookTitle {
+		t.Errorf("Metadata title '%v', the expected was '%v'", title[0], bookTitle)
+	}
+	if book.zip.File[0].Name != mimetypeName {
+		t.Errorf("The first file of the repacked epub is %v", book.zip.File[0].Name)
+	}
+}
+
+func TestRepackTransform(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	upperCSS := func(name, mediaType string, r io.Reader) (io.Reader, error) {
+		if mediaType != "text/css" {
+			return r, nil
+		}
+		data, err := ioutil.ReadAll(r)
+		return strings.NewReader(strings.ToUpper(string(data))), err
+	}
+	book := repackBook(t, f, upperCSS)
+
+	css, err := book.OpenFile(cssFile)
+	if err != nil {
+		t.Fatalf("OpenFile(%v) return an error: %v", cssFile, err)
+	}
+	defer css.Close()
+	data, _ := ioutil.ReadAll(css)
+	if string(data) != strings.ToUpper(string(data)) {
+		t.Errorf("The transform was not applied to %v", cssFile)
+	}
+}
+
+func TestRepackDrop(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	dropCSS := func(name, mediaType string, r io.Reader) (io.Reader, error) {
+		if strings.HasSuffix(name, cssFile) {
+			return nil, nil
+		}
+		return r, nil
+	}
+	book := repackBook(t, f, dropCSS)
+	if _, err := book.OpenFile(cssFile); err == nil {
+		t.Errorf("%v was not dropped from the repacked epub", cssFile)
+	}
+}
+
+const roundtripOPF = `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="uid">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:calibre="http://calibre.kovidgoyal.net/2009/metadata">
+    <dc:title>Old title</dc:title>
+    <!-- keep this comment -->
+    <dc:identifier id="uid">urn:uuid:1234</dc:identifier>
+    <?tool generated="yes"?>
+    <link rel="record" href="record.xml" media-type="application/marc"/>
+    <calibre:custom name="x">Some <b>mixed</b> value</calibre:custom>
+    <meta property="dcterms:modified">2020-01-01T00:00:00Z</meta>
+  </metadata>
+  <manifest>
+    <item id="ch1" href="ch1.xhtml" media-type="application/xhtml+xml"/>
+  </manifest>
+  <spine>
+    <itemref idref="ch1"/>
+  </spine>
+</package>`
+
+func TestRepackPassthrough(t *testing.T) {
+	var buff bytes.Buffer
+	w := zip.NewWriter(&buff)
+	files := []struct {
+		name, content string
+		method        uint16
+	}{
+		{"mimetype", "application/epub+zip", zip.Store},
+		{"META-INF/container.xml", `<?xml version="1.0"?><container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container"><rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles></container>`, zip.Deflate},
+		{"META-INF/com.apple.ibooks.display-options.xml", "<display_options/>", zip.Deflate},
+		{"META-INF/vendor.bin", "\x00\x01 unknown data", zip.Store},
+		{"OEBPS/content.opf", roundtripOPF, zip.Deflate},
+		{"OEBPS/ch1.xhtml", "<html><body><p>Text</p></body></html>", zip.Deflate},
+	}
+	for _, file := range files {
+		fw, _ := w.CreateHeader(&zip.FileHeader{Name: file.name, Method: file.method, Comment: "comment of " + file.name})
+		fw.Write([]byte(file.content))
+	}
+	w.Close()
+	f, err := Load(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+	if err != nil {
+		t.Fatalf("Load() return an error: %v", err)
+	}
+
+	f.SetMetadata("title", []MdataElement{{Content: "New title"}})
+	identity := func(name, mediaType string, r io.Reader) (io.Reader, error) { return r, nil }
+	book := repackBook(t, f, identity)
+
+	opf, _ := book.readFile("OEBPS/content.opf")
+	for _, fragment := range []string{
+		"<!-- keep this comment -->",
+		`<?tool generated="yes"?>`,
+		`<link rel="record" href="record.xml" media-type="application/marc"/>`,
+		`<calibre:custom name="x">Some <b>mixed</b> value</calibre:custom>`,
+		"<dc:title>New title</dc:title>",
+	} {
+		if !strings.Contains(string(opf), fragment) {
+			t.Errorf("The repacked OPF doesn't contain %q:\n%s", fragment, opf)
+		}
+	}
+	if strings.Contains(string(opf), "Old title") {
+		t.Errorf("The repacked OPF has the old title:\n%s", opf)
+	}
+
+	for _, orig := range f.zip.File {
+		if orig.Name == "OEBPS/content.opf" {
+			continue
+		}
+		repacked := book.findFile(orig.Name)
+		if repacked == nil {
+			t.Errorf("%v is not on the repacked epub", orig.Name)
+			continue
+		}
+		if repacked.Comment != orig.Comment || repacked.Method != orig.Method || repacked.CRC32 != orig.CRC32 {
+			t.Errorf("The header of %v changed: %+v", orig.Name, repacked.FileHeader)
+		}
+		origRaw, _ := orig.OpenRaw()
+		newRaw, _ := repacked.OpenRaw()
+		origData, _ := ioutil.ReadAll(origRaw)
+		newData, _ := ioutil.ReadAll(newRaw)
+		if !bytes.Equal(origData, newData) {
+			t.Errorf("The compressed data of %v changed", orig.Name)
+		}
+	}
+}
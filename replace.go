@@ -0,0 +1,125 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// ReplaceOptions configures ReplaceAll
+type ReplaceOptions struct {
+	// DryRun reports the changes without modifying the epub
+	DryRun bool
+	// Literal matches the pattern as plain text instead of as a regexp
+	Literal bool
+	// IgnoreCase makes the match case insensitive
+	IgnoreCase bool
+}
+
+// Change is a line of a content document modified by ReplaceAll
+type Change struct {
+	// Href is the path of the document, as used by OpenFile
+	Href string
+	// Line is the line number on the original document, starting by 1
+	Line int
+	Old  string
+	New  string
+}
+
+// String formats the change as a diff
+func (c Change) String() string {
+	return fmt.Sprintf("%s:%d\n-%s\n+%s\n", c.Href, c.Line, c.Old, c.New)
+}
+
+// replaceSkip are the elements which text is not searched
+var replaceSkip = map[string]bool{
+	"head": true, "script": true, "style": true,
+}
+
+// ReplaceAll replaces the matches of pattern on the text of the documents of
+// the spine
+//
+// Only the text is searched, never the tags or attributes. The entities are
+// decoded before matching, and the replacement is expanded as in
+// regexp.ReplaceAllString. It returns the changed lines, with DryRun they are
+// not applied. The changes are written with Repack.
+func (e *Epub) ReplaceAll(pattern, replacement string, opts ReplaceOptions) ([]Change, error) {
+	if opts.Literal {
+		pattern = regexp.QuoteMeta(pattern)
+		replacement = strings.Replace(replacement, "$", "$$", -1)
+	}
+	if opts.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for i := 0; i < e.opf.spineLength(); i++ {
+		href := e.opf.spineURL(i)
+		if href == "" {
+			continue
+		}
+		mediaType := e.opf.mediaType(href)
+		if mediaType != "application/xhtml+xml" && mediaType != "text/html" {
+			continue
+		}
+		data, err := e.readFile(e.rootPath + href)
+		if err != nil {
+			return nil, err
+		}
+
+		var buff bytes.Buffer
+		last := 0
+		line := 1
+		for _, node := range textNodes(data, replaceSkip) {
+			line += bytes.Count(data[last:node.start], []byte("\n"))
+			buff.Write(data[last:node.start])
+			last = node.end
+
+			text := string(data[node.start:node.end])
+			unescaped := html.UnescapeString(text)
+			if !re.MatchString(unescaped) {
+				buff.WriteString(text)
+				line += strings.Count(text, "\n")
+				continue
+			}
+			newText := escapeHTMLChars(re.ReplaceAllString(unescaped, replacement))
+			changes = append(changes, diffLines(href, line, text, newText)...)
+			buff.WriteString(newText)
+			line += strings.Count(text, "\n")
+		}
+		buff.Write(data[last:])
+
+		if !opts.DryRun && !bytes.Equal(buff.Bytes(), data) {
+			e.stage(e.rootPath+href, buff.Bytes())
+		}
+	}
+	return changes, nil
+}
+
+// diffLines returns the lines changed between the old and new text, that
+// starts at line
+func diffLines(href string, line int, old, new string) []Change {
+	oldLines := strings.Split(old, "\n")
+	newLines := strings.Split(new, "\n")
+	if len(oldLines) != len(newLines) {
+		return []Change{{href, line, strings.TrimSpace(old), strings.TrimSpace(new)}}
+	}
+
+	var changes []Change
+	for i := range oldLines {
+		if oldLines[i] != newLines[i] {
+			changes = append(changes, Change{href, line + i, strings.TrimSpace(oldLines[i]), strings.TrimSpace(newLines[i])})
+		}
+	}
+	return changes
+}
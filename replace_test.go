@@ -0,0 +1,44 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import "strings"
+
+func TestReplaceAll(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	changes, err := f.ReplaceAll(`Aileen (\w+)`, "Eileen $1", ReplaceOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("ReplaceAll() return an error: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Href != chapterFile {
+		t.Fatalf("ReplaceAll() return: %v", changes)
+	}
+	if !strings.Contains(changes[0].New, "Eileen Mavourneen") || strings.Contains(changes[0].Old, "Eileen") {
+		t.Errorf("Wrong change: %v", changes[0])
+	}
+	if len(f.staged) != 0 {
+		t.Errorf("DryRun modified the epub")
+	}
+
+	if changes, _ := f.ReplaceAll("pgepubid", "x", ReplaceOptions{}); len(changes) != 0 {
+		t.Errorf("ReplaceAll() modified the attributes: %v", changes)
+	}
+	if _, err := f.ReplaceAll("(", "", ReplaceOptions{}); err == nil {
+		t.Errorf("ReplaceAll() didn't return an error with an invalid pattern")
+	}
+	if _, err := f.ReplaceAll("aileen mavourneen.", "Eileen $1.", ReplaceOptions{Literal: true, IgnoreCase: true}); err != nil {
+		t.Fatalf("ReplaceAll() return an error: %v", err)
+	}
+
+	book := repackBook(t, f)
+	page := readBookFile(t, book, chapterFile)
+	if !strings.Contains(page, "Eileen $1. She") {
+		t.Errorf("The text was not replaced")
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"errors"
+	"io"
+	"path"
+	"strings"
+)
+
+// AddResource adds the file read from r on href (relative to the OPF) to the
+// manifest with the media type, returns its id
+//
+// The resource is not added to the spine, use InsertDocument for the XHTML
+// documents to be read. The changes are written with Repack.
+func (e *Epub) AddResource(href, mediaType string, r io.Reader) (string, error) {
+	href = path.Clean(href)
+	if href == "." || strings.HasPrefix(href, "/") || strings.HasPrefix(href, "../") {
+		return "", errors.New("Invalid resource path " + href)
+	}
+	if e.opf.fileID(href) != "" || e.inZip(e.rootPath+href) {
+		return "", errors.New("File " + href + " already exists")
+	}
+	data, err := readAll(r)
+	if err != nil {
+		return "", err
+	}
+
+	id := e.opf.uniqueID(strings.TrimSuffix(path.Base(href), path.Ext(href)))
+	e.opf.Manifest = append(e.opf.Manifest, manifest{
+		ID:        id,
+		Href:      href,
+		MediaType: mediaType,
+	})
+	e.stage(e.rootPath+href, data)
+	return id, nil
+}
+
+// RemoveResource removes the file with the manifest id from the epub
+//
+// The documents of the spine are removed like with RemoveSpineItem, their
+// links are redirected to the document after them. For the other resources
+// the entries of the NCX pointing to them, their guide references, the cover
+// meta and the fallbacks to them are removed, the references to them on the
+// documents are kept. The NCX can't be removed. The changes are written with
+// Repack.
+func (e *Epub) RemoveResource(id string) error {
+	href := e.opf.filePath(id)
+	if href == "" {
+		return errors.New("Unknown resource " + id)
+	}
+	if e.ncx != nil && href == e.opf.ncxPath() {
+		return errors.New("Can't remove the NCX")
+	}
+	if index := e.opf.spineIndex(href); index != -1 {
+		return e.RemoveSpineItem(index)
+	}
+
+	name := e.rootPath + href
+	if e.ncx != nil {
+		e.ncx.NavMap = removeNavPoints(e.ncx.NavMap, e.rootPath+e.opf.ncxPath(), name)
+	}
+	for i, item := range e.opf.Manifest {
+		if item.Fallback == id {
+			e.opf.Manifest[i].Fallback = ""
+		}
+		if item.MediaOverlay == id {
+			e.opf.Manifest[i].MediaOverlay = ""
+		}
+	}
+	var metas []MdataElement
+	for _, m := range e.metadata["meta"] {
+		if m.Attr["name"] != "cover" || m.Attr["content"] != id {
+			metas = append(metas, m)
+		}
+	}
+	if len(metas) != len(e.metadata["meta"]) {
+		e.metadata["meta"] = metas
+	}
+
+	var guide []guideRef
+	for _, ref := range e.opf.Guide {
+		if resolveRef(e.opfPath, ref.Href) != name {
+			guide = append(guide, ref)
+		}
+	}
+	e.opf.Guide = guide
+	var items []manifest
+	for _, item := range e.opf.Manifest {
+		if item.ID != id {
+			items = append(items, item)
+		}
+	}
+	e.opf.Manifest = items
+	e.remove(name)
+	return nil
+}
+
+// RenameResource moves the file on oldHref to newHref, both relative to the
+// OPF
+//
+// The manifest, the guide and the NCX are updated and the references to the
+// file on the XHTML, CSS, SVG and navigation documents are rewritten, so are
+// the relative references of the file itself if it is moved to another
+// directory. The changes are written with Repack.
+func (e *Epub) RenameResource(oldHref, newHref string) error {
+	newHref = path.Clean(newHref)
+	if newHref == "." || strings.HasPrefix(newHref, "/") || strings.HasPrefix(newHref, "../") {
+		return errors.New("Invalid resource path " + newHref)
+	}
+	if e.opf.fileID(oldHref) == "" {
+		return errors.New("Unknown resource " + oldHref)
+	}
+	if newHref == oldHref {
+		return nil
+	}
+	if e.opf.fileID(newHref) != "" || e.inZip(e.rootPath+newHref) {
+		return errors.New("File " + newHref + " already exists")
+	}
+	return e.renameFile(e.rootPath+oldHref, e.rootPath+newHref)
+}
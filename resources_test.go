@@ -0,0 +1,115 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import "strings"
+
+func resourcesEpub(t *testing.T) *Epub {
+	return buildEpub(t, epub3OPF, map[string]string{
+		"nav.xhtml":        epub3Nav,
+		"images/cover.jpg": "jpeg",
+		"text/ch1.xhtml":   `<html><body><a href="ch2.xhtml">next</a><img src="../images/cover.jpg"/></body></html>`,
+		"text/ch2.xhtml":   `<html><body><a href="ch1.xhtml#s1">back</a></body></html>`,
+	})
+}
+
+func TestAddResource(t *testing.T) {
+	f := resourcesEpub(t)
+	id, err := f.AddResource("styles/main.css", "text/css", strings.NewReader("body {}"))
+	if err != nil {
+		t.Fatalf("AddResource() return an error: %v", err)
+	}
+	if _, err := f.AddResource("images/cover.jpg", "image/jpeg", strings.NewReader("")); err == nil {
+		t.Errorf("AddResource() of an existing file didn't return an error")
+	}
+	if _, err := f.AddResource("../outside.css", "text/css", strings.NewReader("")); err == nil {
+		t.Errorf("AddResource() outside of the epub didn't return an error")
+	}
+
+	book := repackBook(t, f)
+	if href := book.opf.filePath(id); href != "styles/main.css" {
+		t.Errorf("The resource %v has the href: %v", id, href)
+	}
+	if css := readBookFile(t, book, "styles/main.css"); css != "body {}" {
+		t.Errorf("The resource content is: %v", css)
+	}
+}
+
+func TestRemoveResource(t *testing.T) {
+	f := resourcesEpub(t)
+	if err := f.RemoveResource("missing"); err == nil {
+		t.Errorf("RemoveResource() of an unknown id didn't return an error")
+	}
+	if err := f.RemoveResource("cover-img"); err != nil {
+		t.Fatalf("RemoveResource() return an error: %v", err)
+	}
+	if err := f.RemoveResource("ch1"); err != nil {
+		t.Fatalf("RemoveResource() of a spine document return an error: %v", err)
+	}
+
+	book := repackBook(t, f)
+	if book.opf.filePath("cover-img") != "" || book.inZip(book.rootPath+"images/cover.jpg") {
+		t.Errorf("The cover image was not removed")
+	}
+	if cover := book.metaContent("cover"); cover != "" {
+		t.Errorf("The cover meta was not removed: %v", cover)
+	}
+	if book.opf.spineLength() != 1 || book.opf.spineURL(0) != "text/ch2.xhtml" {
+		t.Errorf("The spine is: %v", book.opf.Spine.Items)
+	}
+	if ch2 := readBookFile(t, book, "text/ch2.xhtml"); !strings.Contains(ch2, `href="ch2.xhtml"`) {
+		t.Errorf("The link to the removed document was not redirected: %v", ch2)
+	}
+}
+
+func TestRenameResource(t *testing.T) {
+	f := resourcesEpub(t)
+	if err := f.RenameResource("text/ch1.xhtml", "text/ch2.xhtml"); err == nil {
+		t.Errorf("RenameResource() over an existing file didn't return an error")
+	}
+	if err := f.RenameResource("text/ch1.xhtml", "ch1.xhtml"); err != nil {
+		t.Fatalf("RenameResource() return an error: %v", err)
+	}
+	if err := f.RenameResource("images/cover.jpg", "text/cover.jpg"); err != nil {
+		t.Fatalf("RenameResource() return an error: %v", err)
+	}
+
+	book := repackBook(t, f)
+	if url := book.opf.spineURL(0); url != "ch1.xhtml" {
+		t.Errorf("The first spine item is: %v", url)
+	}
+	ch1 := readBookFile(t, book, "ch1.xhtml")
+	if !strings.Contains(ch1, `href="text/ch2.xhtml"`) || !strings.Contains(ch1, `src="text/cover.jpg"`) {
+		t.Errorf("The references of the moved document were not fixed: %v", ch1)
+	}
+	if ch2 := readBookFile(t, book, "text/ch2.xhtml"); !strings.Contains(ch2, `href="../ch1.xhtml#s1"`) {
+		t.Errorf("The link to the moved document was not rewritten: %v", ch2)
+	}
+	if nav := readBookFile(t, book, "nav.xhtml"); !strings.Contains(nav, `href="ch1.xhtml#s1"`) {
+		t.Errorf("The nav was not rewritten: %v", nav)
+	}
+}
+
+func TestRenameResourceNCX(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	href := f.opf.spineURL(1)
+	newHref := "moved/" + href
+	if err := f.RenameResource(href, newHref); err != nil {
+		t.Fatalf("RenameResource() return an error: %v", err)
+	}
+
+	book := repackBook(t, f)
+	it, err := book.FindNavPoint(newHref)
+	if err != nil {
+		t.Fatalf("FindNavPoint(%v) return an error: %v", newHref, err)
+	}
+	if it.URL() != newHref && !strings.HasPrefix(it.URL(), newHref+"#") {
+		t.Errorf("The nav point points to: %v", it.URL())
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"bytes"
+	"errors"
+	"html"
+	"regexp"
+	"strings"
+)
+
+var rubyRegexp = regexp.MustCompile(`(?is)<(?:[\w-]+:)?ruby\b[^>]*>(.*?)</(?:[\w-]+:)?ruby\s*>`)
+
+// RubyMode is how the ruby annotations are extracted with the text
+type RubyMode int
+
+const (
+	// RubyKeep keeps the text of the annotations as it is on the document,
+	// the base text followed by its reading and the fallback parentheses
+	RubyKeep RubyMode = iota
+	// RubyBase keeps only the base text ("漢字"), for search indexing
+	RubyBase
+	// RubyReading replaces the base text by its reading ("かんじ"), for text
+	// to speech
+	RubyReading
+	// RubyAnnotated writes the reading in parentheses after the base text
+	// ("漢字(かんじ)"), with or without fallback parentheses on the document
+	RubyAnnotated
+)
+
+// RubyAnnotation is a base text with its reading
+type RubyAnnotation struct {
+	Base    string
+	Reading string
+	// Location is the position of the ruby element on the text returned by
+	// Text
+	Location Location
+}
+
+// TextWithRuby is like Text but extracts the ruby annotations as mode
+//
+// The offsets of the text returned are only the ones of Text with RubyKeep.
+func (e Epub) TextWithRuby(spineIndex int, mode RubyMode) (string, error) {
+	if spineIndex < 0 || spineIndex >= e.opf.spineLength() {
+		return "", errors.New("Spine index out of range")
+	}
+	data, err := e.spineDocument(spineIndex)
+	if err != nil {
+		return "", err
+	}
+	return extractText(flattenRuby(data, mode)), nil
+}
+
+// RubyAnnotations returns the ruby annotations of the document at
+// spineIndex, empty if it has none
+//
+// Each base text is paired with its reading, on the interleaved
+// (<rb>base<rt>reading) and the tabular (<rb>base<rb>base<rt>reading<rt>reading)
+// markups.
+func (e Epub) RubyAnnotations(spineIndex int) ([]RubyAnnotation, error) {
+	if spineIndex < 0 || spineIndex >= e.opf.spineLength() {
+		return nil, errors.New("Spine index out of range")
+	}
+	data, err := e.spineDocument(spineIndex)
+	if err != nil {
+		return nil, err
+	}
+	href := e.opf.spineURL(spineIndex)
+
+	var annotations []RubyAnnotation
+	for _, loc := range rubyRegexp.FindAllSubmatchIndex(data, -1) {
+		location := Location{SpineIndex: spineIndex, Href: href, Offset: len(extractText(data[:loc[0]]))}
+		for _, pair := range rubyPairs(data[loc[2]:loc[3]]) {
+			annotations = append(annotations, RubyAnnotation{
+				Base:     rubyText(pair[0]),
+				Reading:  rubyText(pair[1]),
+				Location: location,
+			})
+		}
+	}
+	return annotations, nil
+}
+
+// flattenRuby replaces the ruby elements of the XHTML data by their text as
+// mode
+func flattenRuby(data []byte, mode RubyMode) []byte {
+	if mode == RubyKeep {
+		return data
+	}
+	return rubyRegexp.ReplaceAllFunc(data, func(ruby []byte) []byte {
+		loc := rubyRegexp.FindSubmatchIndex(ruby)
+		var buff bytes.Buffer
+		for _, pair := range rubyPairs(ruby[loc[2]:loc[3]]) {
+			base, reading := strings.TrimSpace(pair[0]), strings.TrimSpace(pair[1])
+			switch {
+			case mode == RubyReading && reading != "":
+				buff.WriteString(reading)
+			case mode == RubyAnnotated && reading != "":
+				buff.WriteString(base + "(" + reading + ")")
+			default:
+				buff.WriteString(base)
+			}
+		}
+		return buff.Bytes()
+	})
+}
+
+// rubyPairs returns the base texts of the content of a ruby element paired
+// with their readings, as they are on the document with the entities escaped
+//
+// The fallback parentheses (<rp>) are dropped. The readings of a ruby text
+// container (<rtc>) are joined as a single one.
+func rubyPairs(content []byte) [][2]string {
+	var pairs [][2]string
+	var bases, readings []string
+	flush := func() {
+		for i, base := range bases {
+			reading := ""
+			if i < len(readings) {
+				reading = readings[i]
+			}
+			if i == len(bases)-1 && len(readings) > len(bases) {
+				reading = strings.Join(readings[i:], " ")
+			}
+			pairs = append(pairs, [2]string{base, reading})
+		}
+		bases, readings = nil, nil
+	}
+	addBase := func(text string) {
+		if strings.TrimSpace(text) == "" && (len(bases) == 0 || len(readings) > 0) {
+			return
+		}
+		if len(readings) > 0 {
+			flush()
+		}
+		if len(bases) == 0 {
+			bases = append(bases, "")
+		}
+		bases[len(bases)-1] += text
+	}
+
+	inReading, inFallback, inContainer := false, 0, false
+	last := 0
+	for _, loc := range markupRegexp.FindAllIndex(content, -1) {
+		if text := string(content[last:loc[0]]); inFallback == 0 && text != "" {
+			switch {
+			case inReading || inContainer:
+				if len(readings) == 0 {
+					readings = append(readings, "")
+				}
+				readings[len(readings)-1] += text
+			default:
+				addBase(text)
+			}
+		}
+		last = loc[1]
+
+		tag := string(content[loc[0]:loc[1]])
+		closing := strings.HasPrefix(tag, "</")
+		if strings.HasSuffix(tag, "/>") {
+			continue
+		}
+		name := tagName(strings.TrimPrefix(tag, "</"))
+		switch name[strings.Index(name, ":")+1:] {
+		case "rb":
+			if !closing {
+				if len(readings) > 0 {
+					flush()
+				}
+				bases = append(bases, "")
+			}
+		case "rt":
+			inReading = !closing
+			if !closing && !inContainer {
+				readings = append(readings, "")
+			}
+		case "rtc":
+			inContainer = !closing
+			if !closing {
+				readings = append(readings, "")
+			}
+		case "rp":
+			if closing && inFallback > 0 {
+				inFallback--
+			} else if !closing {
+				inFallback++
+			}
+		}
+	}
+	if inFallback == 0 && !inReading && !inContainer {
+		addBase(string(content[last:]))
+	}
+	flush()
+	return pairs
+}
+
+// rubyText returns the escaped text of a ruby pair unescaped and with the
+// whitespace collapsed
+func rubyText(text string) string {
+	return strings.TrimSpace(whitespaceRegexp.ReplaceAllString(html.UnescapeString(text), " "))
+}
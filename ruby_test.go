@@ -0,0 +1,75 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import "strings"
+
+const rubyDocument = `<html><body>
+<p><ruby>漢<rp>(</rp><rt>かん</rt><rp>)</rp>字<rp>(</rp><rt>じ</rt><rp>)</rp></ruby>を読む</p>
+<p><ruby><rb>東</rb><rb>京</rb><rt>とう</rt><rt>きょう</rt></ruby>へ</p>
+</body></html>`
+
+func rubyEpub(t *testing.T) *Epub {
+	return buildEpub(t, epub3OPF, map[string]string{
+		"nav.xhtml":      epub3Nav,
+		"text/ch1.xhtml": rubyDocument,
+		"text/ch2.xhtml": `<html><body><p>No ruby</p></body></html>`,
+	})
+}
+
+func TestTextWithRuby(t *testing.T) {
+	f := rubyEpub(t)
+	tests := map[RubyMode]string{
+		RubyKeep:      "漢(かん)字(じ)を読む\n東京とうきょうへ",
+		RubyBase:      "漢字を読む\n東京へ",
+		RubyReading:   "かんじを読む\nとうきょうへ",
+		RubyAnnotated: "漢(かん)字(じ)を読む\n東(とう)京(きょう)へ",
+	}
+	for mode, expected := range tests {
+		text, err := f.TextWithRuby(0, mode)
+		if err != nil {
+			t.Fatalf("TextWithRuby() return an error: %v", err)
+		}
+		if text != expected {
+			t.Errorf("TextWithRuby(%v) return: %q", mode, text)
+		}
+	}
+}
+
+func TestRubyAnnotations(t *testing.T) {
+	f := rubyEpub(t)
+	annotations, err := f.RubyAnnotations(0)
+	if err != nil {
+		t.Fatalf("RubyAnnotations() return an error: %v", err)
+	}
+	var pairs []string
+	for _, a := range annotations {
+		pairs = append(pairs, a.Base+":"+a.Reading)
+	}
+	if strings.Join(pairs, " ") != "漢:かん 字:じ 東:とう 京:きょう" {
+		t.Errorf("RubyAnnotations() return: %v", annotations)
+	}
+	if annotations[2].Location.Offset != len("漢(かん)字(じ)を読む") {
+		t.Errorf("Wrong location: %v", annotations[2].Location)
+	}
+
+	annotations, err = f.RubyAnnotations(1)
+	if err != nil || len(annotations) != 0 {
+		t.Errorf("RubyAnnotations() return: %v, %v", annotations, err)
+	}
+}
+
+func TestToSSMLRuby(t *testing.T) {
+	f := rubyEpub(t)
+	ssml, err := f.ToSSML(0, SSMLOptions{Ruby: RubyReading})
+	if err != nil {
+		t.Fatalf("ToSSML() return an error: %v", err)
+	}
+	if !strings.Contains(ssml, "かんじを読む") || strings.Contains(ssml, "漢") {
+		t.Errorf("ToSSML() return: %v", ssml)
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+const defaultSamplePercent = 10
+
+// EndOfSampleTemplate is the page added at the end of the samples, it is
+// executed with a PageData
+var EndOfSampleTemplate = newPageTemplate(`{{define "title"}}End of Sample{{end}}
+    <section epub:type="backmatter">
+      <h1>End of Sample</h1>
+      <p>You have reached the end of this sample of <em>{{.Title}}</em>{{with .Authors}} by {{range $i, $a := .}}{{if $i}}, {{end}}{{$a}}{{end}}{{end}}.</p>
+    </section>`)
+
+// SampleOptions configures Sample
+type SampleOptions struct {
+	// Percent is the part of the text of the body matter included on the
+	// sample, 10 by default
+	Percent float64
+	// EndTemplate is the page added at the end of the sample, it is executed
+	// with a PageData. EndOfSampleTemplate if nil.
+	EndTemplate *template.Template
+}
+
+// Sample writes into w a sample of the book with the cover, the front matter
+// and the beginning of the body matter
+//
+// The body matter starts where Preview does, the document where the sample
+// reaches Percent of its text is cut between blocks and the next ones are
+// dropped. An end of sample page is added after it, the links to the dropped
+// documents on the content, the NCX and the navigation document point to it
+// and the resources only used by the dropped documents are removed. The book
+// itself is not modified.
+func (e Epub) Sample(w io.Writer, opts SampleOptions) error {
+	if opts.Percent == 0 {
+		opts.Percent = defaultSamplePercent
+	}
+	if opts.Percent < 0 || opts.Percent > 100 {
+		return errors.New("Invalid sample percent")
+	}
+	if opts.EndTemplate == nil {
+		opts.EndTemplate = EndOfSampleTemplate
+	}
+
+	var buff bytes.Buffer
+	if err := e.Repack(&buff); err != nil {
+		return err
+	}
+	sample, err := Load(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+	if err != nil {
+		return err
+	}
+	if err := sample.cutSample(opts); err != nil {
+		return err
+	}
+	prune, err := sample.PruneOrphans()
+	if err != nil {
+		return err
+	}
+	return sample.Repack(w, prune)
+}
+
+func (e *Epub) cutSample(opts SampleOptions) error {
+	if e.opf.nextSpineItem(-1, 1) == -1 {
+		return errors.New("Spine is empty")
+	}
+	start := e.bodyMatterStart()
+	var lengths []int
+	total := 0
+	for i := start; i < e.opf.spineLength(); i++ {
+		href := e.opf.spineURL(i)
+		if href == "" {
+			lengths = append(lengths, 0)
+			continue
+		}
+		data, err := e.readFile(e.rootPath + href)
+		if err != nil {
+			return err
+		}
+		length := utf8.RuneCountInString(extractText(data))
+		lengths = append(lengths, length)
+		total += length
+	}
+
+	budget := int(float64(total) * opts.Percent / 100)
+	last := e.opf.nextSpineItem(e.opf.spineLength(), -1)
+	for i, length := range lengths {
+		if length >= budget && e.opf.spineURL(start+i) != "" {
+			last = start + i
+			break
+		}
+		budget -= length
+	}
+	name := e.rootPath + e.opf.spineURL(last)
+	data, err := e.readFile(name)
+	if err != nil {
+		return err
+	}
+	if truncated := truncateDocument(data, budget); !bytes.Equal(truncated, data) {
+		e.stage(name, truncated)
+	}
+
+	removed := make(map[string]bool)
+	for i := last + 1; i < e.opf.spineLength(); i++ {
+		if href := e.opf.spineURL(i); href != "" {
+			removed[e.rootPath+href] = true
+		}
+	}
+	e.opf.Spine.Items = e.opf.Spine.Items[:last+1]
+
+	endHref := e.pageHref("endofsample.xhtml")
+	err = e.AddPage(endHref, "End of Sample", opts.EndTemplate, e.PageData(endHref), -1)
+	if err != nil {
+		return err
+	}
+	return e.dropDocuments(removed, e.rootPath+endHref)
+}
+
+// dropDocuments removes the documents of the removed map, the links to them
+// are redirected to the document target
+func (e *Epub) dropDocuments(removed map[string]bool, target string) error {
+	redirect := func(docPath, ref string) string {
+		if !removed[resolveRef(docPath, ref)] {
+			return ref
+		}
+		return relativeRef(docPath, target)
+	}
+
+	for _, name := range e.fileNames() {
+		mediaType := e.opf.mediaType(strings.TrimPrefix(name, e.rootPath))
+		if removed[name] || name == e.opfPath || !isMarkup(mediaType) {
+			continue
+		}
+		data, err := e.readFile(name)
+		if err != nil {
+			return err
+		}
+		docPath := name
+		redirected := eachRef(data, func(ref string) string { return redirect(docPath, ref) })
+		if !bytes.Equal(redirected, data) {
+			e.stage(name, redirected)
+		}
+	}
+	if e.ncx != nil {
+		ncxPath := e.rootPath + e.opf.ncxPath()
+		fixNavPoints(e.ncx.NavMap, func(ref string) string { return redirect(ncxPath, ref) })
+	}
+
+	var guide []guideRef
+	for _, ref := range e.opf.Guide {
+		if !removed[resolveRef(e.opfPath, ref.Href)] {
+			guide = append(guide, ref)
+		}
+	}
+	e.opf.Guide = guide
+	var items []manifest
+	for _, item := range e.opf.Manifest {
+		if !removed[e.rootPath+item.Href] {
+			items = append(items, item)
+		}
+	}
+	e.opf.Manifest = items
+	for name := range removed {
+		e.remove(name)
+	}
+	return nil
+}
+
+// truncateDocument cuts the body of the XHTML data on the first block after
+// chars characters of text, closing the elements open there
+func truncateDocument(data []byte, chars int) []byte {
+	startLoc := bodyStartRegexp.FindIndex(data)
+	endLocs := bodyEndRegexp.FindAllIndex(data, -1)
+	if startLoc == nil || endLocs == nil {
+		return data
+	}
+	bodyStart := startLoc[1]
+	bodyEnd := endLocs[len(endLocs)-1][0]
+	if bodyEnd < bodyStart {
+		return data
+	}
+
+	var open []string
+	length := 0
+	last := bodyStart
+	for _, loc := range htmlTagRegexp.FindAllSubmatchIndex(data[bodyStart:bodyEnd], -1) {
+		pos := loc[0] + bodyStart
+		tag := string(data[pos : loc[1]+bodyStart])
+		closing := loc[3] > loc[2]
+		selfClosing := loc[7] > loc[6]
+		qname := strings.ToLower(string(data[loc[4]+bodyStart : loc[5]+bodyStart]))
+		name := qname[strings.Index(qname, ":")+1:]
+
+		if closing {
+			for i := len(open) - 1; i >= 0; i-- {
+				if tagName(open[i]) == qname {
+					open = open[:i]
+					break
+				}
+			}
+			continue
+		}
+		if splitBlocks[name] {
+			length += utf8.RuneCountInString(extractText(data[last:pos]))
+			last = pos
+			if length >= chars && length > 0 {
+				var buff bytes.Buffer
+				buff.Write(data[:pos])
+				for i := len(open) - 1; i >= 0; i-- {
+					buff.WriteString("</" + tagName(open[i]) + ">")
+				}
+				buff.WriteString("\n")
+				buff.Write(data[bodyEnd:])
+				return buff.Bytes()
+			}
+		}
+		if !selfClosing && !voidElements[name] {
+			open = append(open, tag)
+			if len(open) > maxNestingDepth {
+				return data
+			}
+		}
+	}
+	return data
+}
@@ -0,0 +1,81 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"bytes"
+	"strings"
+)
+
+func loadSample(t *testing.T, f *Epub, opts SampleOptions) *Epub {
+	var buff bytes.Buffer
+	if err := f.Sample(&buff, opts); err != nil {
+		t.Fatalf("Sample() return an error: %v", err)
+	}
+	sample, err := Load(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+	if err != nil {
+		t.Fatalf("Load() of the sample return an error: %v", err)
+	}
+	return sample
+}
+
+func TestSample(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	sample := loadSample(t, f, SampleOptions{})
+	if f.opf.spineLength() != 2 {
+		t.Errorf("Sample() modified the book spine")
+	}
+	if sample.opf.spineLength() != 3 {
+		t.Fatalf("The sample spine has %v items", sample.opf.spineLength())
+	}
+	text, _ := sample.Text(1)
+	fullText, _ := f.Text(1)
+	if len(text) == 0 || len(text) > len(fullText)/5 {
+		t.Errorf("The sample text has %v bytes of %v", len(text), len(fullText))
+	}
+	end, _ := sample.Text(2)
+	if !strings.Contains(end, "End of Sample") || !strings.Contains(end, "A Dog's Tale") {
+		t.Errorf("Wrong end of sample page: %q", end)
+	}
+	if _, err := sample.OpenFile(coverJPG); err != nil {
+		t.Errorf("The cover was dropped from the sample")
+	}
+	if _, err := sample.OpenFile("@public@vhost@g@gutenberg@html@files@3174@3174-h@images@p34.jpg"); err == nil {
+		t.Errorf("The images of the dropped text are still on the sample")
+	}
+
+	if err := f.Sample(&bytes.Buffer{}, SampleOptions{Percent: 101}); err == nil {
+		t.Errorf("Sample() didn't return an error with an invalid percent")
+	}
+}
+
+func TestSampleDropDocuments(t *testing.T) {
+	f := buildEpub(t, "testdata/epub3.opf", map[string]string{
+		"nav.xhtml":      `<html><body><nav epub:type="toc"><ol><li><a href="text/ch1.xhtml">One</a></li><li><a href="text/ch2.xhtml#c2">Two</a></li></ol></nav></body></html>`,
+		"text/ch1.xhtml": `<html><body><p>` + strings.Repeat("word ", 200) + `</p><p>More text.</p><p>See <a href="ch2.xhtml">two</a>.</p></body></html>`,
+		"text/ch2.xhtml": `<html><body><p id="c2">` + strings.Repeat("word ", 200) + `</p></body></html>`,
+	})
+	defer f.Close()
+
+	sample := loadSample(t, f, SampleOptions{Percent: 40})
+	if sample.opf.spineLength() != 2 || sample.opf.spineURL(1) != "text/endofsample.xhtml" {
+		t.Fatalf("Wrong sample spine: %v", sample.opf.Spine.Items)
+	}
+	if _, err := sample.OpenFile("text/ch2.xhtml"); err == nil {
+		t.Errorf("text/ch2.xhtml was not dropped")
+	}
+	ch1, _ := sample.readFile(sample.rootPath + "text/ch1.xhtml")
+	if strings.Contains(string(ch1), "More text") || !strings.HasSuffix(strings.TrimSpace(string(ch1)), "</html>") {
+		t.Errorf("text/ch1.xhtml was not cut: %s", ch1)
+	}
+	nav, _ := sample.readFile(sample.rootPath + "nav.xhtml")
+	if !strings.Contains(string(nav), `href="text/endofsample.xhtml"`) || strings.Contains(string(nav), "ch2.xhtml") {
+		t.Errorf("The navigation document was not redirected: %s", nav)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// SaveOptions configures Save
+type SaveOptions struct {
+	// Backup keeps the previous file as path + ".bak", replacing any older
+	// backup
+	Backup bool
+	// Transforms are applied while repacking, see Repack
+	Transforms []Transform
+}
+
+// Save writes the epub into path atomically
+//
+// The epub is repacked into a temporary file in the same directory, synced to
+// disk and renamed over path, so path always has either the old or the new
+// complete epub. The permissions of the previous file are preserved. path can
+// be the file the epub was opened from, the epub keeps reading the previous
+// content until it is closed (on Windows the file can't be replaced while it
+// is open).
+func (e Epub) Save(path string, opts SaveOptions) (err error) {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := ioutil.TempFile(dir, "."+base+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err = e.Repack(tmp, opts.Transforms...); err != nil {
+		return err
+	}
+	if info, statErr := os.Stat(path); statErr == nil {
+		if err = tmp.Chmod(info.Mode().Perm()); err != nil {
+			return err
+		}
+		if opts.Backup {
+			if err = backupFile(path, path+".bak"); err != nil {
+				return err
+			}
+		}
+	} else if err = tmp.Chmod(0644); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	syncDir(dir)
+	return nil
+}
+
+// backupFile makes bak a copy of path, a hard link if the file system
+// supports them
+func backupFile(path, bak string) error {
+	if err := os.Remove(bak); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if os.Link(path, bak) == nil {
+		return nil
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.Create(bak)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Sync(); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// syncDir flushes the directory entries, so the rename survives a power cut.
+// Not all the platforms support it, the errors are ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
@@ -0,0 +1,73 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+)
+
+func TestSave(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tale.epub")
+	orig, _ := ioutil.ReadFile(bookPath)
+	ioutil.WriteFile(path, orig, 0600)
+
+	f, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open(%v) return an error: %v", path, err)
+	}
+	f.SetMetadata("title", []MdataElement{{Content: "Saved"}})
+	if err := f.Save(path, SaveOptions{Backup: true}); err != nil {
+		t.Fatalf("Save() return an error: %v", err)
+	}
+	f.Close()
+
+	saved, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() the saved epub return an error: %v", err)
+	}
+	defer saved.Close()
+	if title, _ := saved.Metadata("title"); title[0] != "Saved" {
+		t.Errorf("Save() title: %v", title)
+	}
+	if bak, _ := ioutil.ReadFile(path + ".bak"); !bytes.Equal(bak, orig) {
+		t.Errorf("Save() didn't keep the original as backup")
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 2 {
+		t.Errorf("Save() left %v files on the directory", len(files))
+	}
+	if files[0].Mode().Perm() != 0600 {
+		t.Errorf("Save() changed the permissions: %v", files[0].Mode())
+	}
+}
+
+func TestSaveError(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tale.epub")
+	orig, _ := ioutil.ReadFile(bookPath)
+	ioutil.WriteFile(path, orig, 0644)
+
+	f, _ := Open(bookPath)
+	defer f.Close()
+	fail := func(name, mediaType string, r io.Reader) (io.Reader, error) {
+		return nil, errors.New("Transform failed")
+	}
+	if err := f.Save(path, SaveOptions{Transforms: []Transform{fail}}); err == nil {
+		t.Errorf("Save() didn't return the error of the transform")
+	}
+	if data, _ := ioutil.ReadFile(path); !bytes.Equal(data, orig) {
+		t.Errorf("Save() modified the file after an error")
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Errorf("Save() left %v files on the directory", len(files))
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ContentMatch is a match of a ContentMatcher on a text
+type ContentMatch struct {
+	// Category is the kind of content matched, like "profanity" or
+	// "violence", defined by the matcher
+	Category string
+	Text     string
+	// Offset is the position in bytes of the match on the text
+	Offset int
+}
+
+// ContentMatcher finds the content to flag on a text
+//
+// It is called with the text of each document of the spine, as returned by
+// Text. WordListMatcher matches lists of words, classifiers or moderation
+// services can be plugged implementing this interface.
+type ContentMatcher interface {
+	Match(text string) []ContentMatch
+}
+
+// ContentMatcherFunc is a function used as a ContentMatcher
+type ContentMatcherFunc func(text string) []ContentMatch
+
+// Match calls f(text)
+func (f ContentMatcherFunc) Match(text string) []ContentMatch {
+	return f(text)
+}
+
+// ContentFinding is a match of ScanContent on the book
+type ContentFinding struct {
+	Category string
+	Text     string
+	Location Location
+	// Context is the text around the match, on the same line
+	Context string
+}
+
+// ScanContent runs the matchers over the text of the documents of the spine
+//
+// The findings are returned on the reading order, the ones of the same
+// position in the order of the matchers.
+func (e Epub) ScanContent(matchers ...ContentMatcher) ([]ContentFinding, error) {
+	var findings []ContentFinding
+	for i := 0; i < e.opf.spineLength(); i++ {
+		href := e.opf.spineURL(i)
+		if href == "" {
+			continue
+		}
+		text, err := e.Text(i)
+		if err != nil {
+			return nil, err
+		}
+
+		var matches []ContentMatch
+		for _, matcher := range matchers {
+			matches = append(matches, matcher.Match(text)...)
+		}
+		sort.SliceStable(matches, func(i, j int) bool { return matches[i].Offset < matches[j].Offset })
+		for _, m := range matches {
+			findings = append(findings, ContentFinding{
+				Category: m.Category,
+				Text:     m.Text,
+				Location: Location{SpineIndex: i, Href: href, Offset: m.Offset},
+				Context:  matchContext(text, m.Offset, len(m.Text)),
+			})
+		}
+	}
+	return findings, nil
+}
+
+// matchContext returns the text around the match, up to contextLength bytes
+// on each side without crossing lines or cutting characters
+func matchContext(text string, offset, length int) string {
+	start := offset - contextLength
+	if start < 0 {
+		start = 0
+	}
+	for start < offset && !utf8.RuneStart(text[start]) {
+		start++
+	}
+	if nl := strings.LastIndexByte(text[start:offset], '\n'); nl != -1 {
+		start += nl + 1
+	}
+	end := offset + length + contextLength
+	if end > len(text) {
+		end = len(text)
+	}
+	for end > offset+length && end < len(text) && !utf8.RuneStart(text[end]) {
+		end--
+	}
+	if nl := strings.IndexByte(text[offset+length:end], '\n'); nl != -1 {
+		end = offset + length + nl
+	}
+	return strings.TrimSpace(text[start:end])
+}
+
+// WordListMatcher is a ContentMatcher of the words or phrases of a list
+//
+// The words are matched whole and without case, so "ass" doesn't match
+// "class". A word ending with * matches any word starting with it
+// ("damn*" matches "damned"). The phrases match the words separated by any
+// whitespace or punctuation.
+type WordListMatcher struct {
+	Category string
+	// words are the phrases by their first word, prefixes the ones which
+	// first word ends with *
+	words    map[string][][]string
+	prefixes map[string][][]string
+}
+
+// NewWordListMatcher returns a WordListMatcher of the words of category
+func NewWordListMatcher(category string, words []string) *WordListMatcher {
+	m := &WordListMatcher{
+		Category: category,
+		words:    make(map[string][][]string),
+		prefixes: make(map[string][][]string),
+	}
+	for _, word := range words {
+		var phrase []string
+		for _, w := range wordTokens(word) {
+			phrase = append(phrase, w.text)
+		}
+		if len(phrase) == 0 {
+			continue
+		}
+		if strings.HasSuffix(strings.TrimSpace(word), "*") {
+			phrase[len(phrase)-1] += "*"
+		}
+		if prefix := strings.TrimSuffix(phrase[0], "*"); prefix != phrase[0] {
+			m.prefixes[prefix] = append(m.prefixes[prefix], phrase)
+		} else {
+			m.words[prefix] = append(m.words[prefix], phrase)
+		}
+	}
+	return m
+}
+
+// Match implements ContentMatcher, the longest phrase is matched
+func (m *WordListMatcher) Match(text string) []ContentMatch {
+	var matches []ContentMatch
+	tokens := wordTokens(text)
+	for i, token := range tokens {
+		longest := 0
+		for _, phrase := range m.candidates(token.text) {
+			if n := matchPhrase(tokens[i:], phrase); n > longest {
+				longest = n
+			}
+		}
+		if longest > 0 {
+			end := tokens[i+longest-1].offset + len(tokens[i+longest-1].raw)
+			matches = append(matches, ContentMatch{m.Category, text[token.offset:end], token.offset})
+		}
+	}
+	return matches
+}
+
+// candidates returns the phrases that can start with the word
+func (m *WordListMatcher) candidates(word string) [][]string {
+	phrases := m.words[word]
+	for prefix, list := range m.prefixes {
+		if strings.HasPrefix(word, prefix) {
+			phrases = append(phrases, list...)
+		}
+	}
+	return phrases
+}
+
+// matchPhrase returns the number of tokens matched by the phrase, 0 if it
+// doesn't match
+func matchPhrase(tokens []wordToken, phrase []string) int {
+	if len(tokens) < len(phrase) {
+		return 0
+	}
+	for i, word := range phrase {
+		if prefix := strings.TrimSuffix(word, "*"); prefix != word {
+			if !strings.HasPrefix(tokens[i].text, prefix) {
+				return 0
+			}
+		} else if tokens[i].text != word {
+			return 0
+		}
+	}
+	return len(phrase)
+}
+
+// wordToken is a word of a text, raw as it is on the text and lowercased
+type wordToken struct {
+	text, raw string
+	offset    int
+}
+
+// wordTokens splits the text in words, sequences of letters, numbers and
+// marks joined by apostrophes
+func wordTokens(text string) []wordToken {
+	var tokens []wordToken
+	start := -1
+	for i, r := range text + " " {
+		inWord := unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.Is(unicode.Mn, r)
+		if (r == '\'' || r == '’') && start != -1 && i+1 < len(text) {
+			next, _ := utf8.DecodeRuneInString(text[i+utf8.RuneLen(r):])
+			inWord = unicode.IsLetter(next)
+		}
+		switch {
+		case inWord && start == -1:
+			start = i
+		case !inWord && start != -1:
+			raw := text[start:i]
+			tokens = append(tokens, wordToken{strings.ToLower(raw), raw, start})
+			start = -1
+		}
+	}
+	return tokens
+}
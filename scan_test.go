@@ -0,0 +1,54 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import "strings"
+
+func TestWordListMatcher(t *testing.T) {
+	m := NewWordListMatcher("profanity", []string{"damn*", "Hell", "son of a gun", "ass"})
+	matches := m.Match("Damned class! Go to hell, you son of a gun. Hello ass’s.")
+	var texts []string
+	for _, match := range matches {
+		if match.Category != "profanity" {
+			t.Errorf("Wrong category: %v", match.Category)
+		}
+		texts = append(texts, match.Text)
+	}
+	if strings.Join(texts, "|") != "Damned|hell|son of a gun" {
+		t.Errorf("Match() return: %v", matches)
+	}
+	if matches[1].Offset != 20 {
+		t.Errorf("Wrong offset: %v", matches[1].Offset)
+	}
+}
+
+func TestScanContent(t *testing.T) {
+	f := buildEpub(t, epub3OPF, map[string]string{
+		"text/ch1.xhtml": `<html><body><p>A quiet start.</p><p>Then the gun fired, and the duel began.</p></body></html>`,
+		"text/ch2.xhtml": `<html><body><p>No violence here.</p></body></html>`,
+	})
+	violence := NewWordListMatcher("violence", []string{"gun", "duel*"})
+	weapons := ContentMatcherFunc(func(text string) []ContentMatch {
+		if i := strings.Index(text, "gun"); i != -1 {
+			return []ContentMatch{{"weapons", "gun", i}}
+		}
+		return nil
+	})
+	findings, err := f.ScanContent(violence, weapons)
+	if err != nil {
+		t.Fatalf("ScanContent() return an error: %v", err)
+	}
+	if len(findings) != 3 {
+		t.Fatalf("ScanContent() return: %v", findings)
+	}
+	if findings[0].Category != "violence" || findings[1].Category != "weapons" || findings[2].Text != "duel" {
+		t.Errorf("ScanContent() return: %v", findings)
+	}
+	if findings[0].Location.Href != "text/ch1.xhtml" || findings[0].Context != "Then the gun fired, and the duel began." {
+		t.Errorf("Wrong location: %v", findings[0])
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	minDescriptionLength = 100
+	minCoverSize         = 10 * 1024
+)
+
+var yearRegexp = regexp.MustCompile(`^\d{4}`)
+
+var placeholderValues = map[string]bool{
+	"": true, "unknown": true, "none": true, "n/a": true, "null": true,
+	"untitled": true, "unknown author": true, "anonymous": true,
+	"calibre": true, "lorem ipsum": true, "publisher": true, "press": true,
+}
+
+// MetadataReport is the result of the metadata completeness scoring
+type MetadataReport struct {
+	// Score goes from 0 (no metadata at all) to 100 (complete metadata)
+	Score int
+	// Missing lists the fields not present on the book
+	Missing []string
+	// Weak lists the fields present but with poor values, with the reason
+	// like "description: too short"
+	Weak []string
+}
+
+type scoreRule struct {
+	field  string
+	weight int
+	check  func(e Epub) (present bool, weakness string)
+}
+
+var scoreRules = []scoreRule{
+	{"title", 15, checkPlaceholder("title")},
+	{"creator", 15, checkPlaceholder("creator")},
+	{"language", 10, checkPlaceholder("language")},
+	{"identifier", 10, checkIdentifier},
+	{"description", 15, checkDescription},
+	{"subject", 10, checkPlaceholder("subject")},
+	{"publisher", 10, checkPlaceholder("publisher")},
+	{"date", 5, checkDate},
+	{"cover", 10, checkCover},
+}
+
+// MetadataScore grades how complete the metadata of the book is
+//
+// Each field has a weight on the score, the weak fields score half of it.
+func (e Epub) MetadataScore() MetadataReport {
+	var report MetadataReport
+	for _, rule := range scoreRules {
+		present, weakness := rule.check(e)
+		switch {
+		case !present:
+			report.Missing = append(report.Missing, rule.field)
+		case weakness != "":
+			report.Weak = append(report.Weak, rule.field+": "+weakness)
+			report.Score += rule.weight / 2
+		default:
+			report.Score += rule.weight
+		}
+	}
+	return report
+}
+
+// values returns the non empty values of a metadata field
+func (e Epub) values(field string) []string {
+	var values []string
+	for _, elem := range e.metadata[field] {
+		if v := strings.TrimSpace(elem.Content); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func checkPlaceholder(field string) func(e Epub) (bool, string) {
+	return func(e Epub) (bool, string) {
+		values := e.values(field)
+		if len(values) == 0 {
+			return false, ""
+		}
+		for _, v := range values {
+			if !placeholderValues[strings.ToLower(v)] {
+				return true, ""
+			}
+		}
+		return true, "placeholder value"
+	}
+}
+
+func checkIdentifier(e Epub) (bool, string) {
+	elems := e.metadata["identifier"]
+	if len(elems) == 0 {
+		return false, ""
+	}
+	for _, elem := range elems {
+		id := ParseIdentifier(elem.Content, elem.Attr["scheme"])
+		if id.Valid && id.Scheme != "" && id.Scheme != SchemeUUID {
+			return true, ""
+		}
+	}
+	return true, "no standard identifier (ISBN, DOI, ...)"
+}
+
+func checkDescription(e Epub) (bool, string) {
+	values := e.values("description")
+	if len(values) == 0 {
+		return false, ""
+	}
+	if placeholderValues[strings.ToLower(values[0])] {
+		return true, "placeholder value"
+	}
+	if len(values[0]) < minDescriptionLength {
+		return true, "too short"
+	}
+	return true, ""
+}
+
+func checkDate(e Epub) (bool, string) {
+	values := e.values("date")
+	if len(values) == 0 {
+		return false, ""
+	}
+	if !yearRegexp.MatchString(values[0]) {
+		return true, "not a valid date"
+	}
+	return true, ""
+}
+
+func checkCover(e Epub) (bool, string) {
+	href := e.coverHref()
+	if href == "" {
+		return false, ""
+	}
+	size := e.size(e.rootPath + href)
+	if size == 0 {
+		return false, ""
+	}
+	if size < minCoverSize {
+		return true, "cover image too small"
+	}
+	return true, ""
+}
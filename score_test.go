@@ -0,0 +1,37 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+func TestMetadataScore(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	report := f.MetadataScore()
+	if report.Score != 75 {
+		t.Errorf("MetadataScore().Score should be 75, but was %v", report.Score)
+	}
+	if len(report.Missing) != 2 || report.Missing[0] != "description" || report.Missing[1] != "publisher" {
+		t.Errorf("MetadataScore().Missing return: %v", report.Missing)
+	}
+	if len(report.Weak) != 0 {
+		t.Errorf("MetadataScore().Weak return: %v", report.Weak)
+	}
+}
+
+func TestMetadataScorePlaceholders(t *testing.T) {
+	f, _ := Open(noNCXPath)
+	defer f.Close()
+
+	report := f.MetadataScore()
+	weak := map[string]bool{}
+	for _, w := range report.Weak {
+		weak[w] = true
+	}
+	if !weak["publisher: placeholder value"] || !weak["date: not a valid date"] {
+		t.Errorf("MetadataScore().Weak return: %v", report.Weak)
+	}
+}
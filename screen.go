@@ -0,0 +1,158 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"bytes"
+	"path"
+	"strings"
+	"unicode"
+)
+
+// minDocumentText is the number of letters under which a document with
+// images is considered to be only images
+const minDocumentText = 20
+
+// placeholderPhrases are the filler texts left by templates and layout tools
+var placeholderPhrases = []string{
+	"lorem ipsum", "dolor sit amet", "insert text here", "your text here",
+	"text goes here", "chapter text goes here",
+}
+
+// baitSignatures are the first bytes of the executable and archive formats
+var baitSignatures = []struct {
+	prefix, kind string
+}{
+	{"MZ", "a Windows executable"},
+	{"\x7fELF", "an ELF executable"},
+	{"\xcf\xfa\xed\xfe", "a Mach-O executable"},
+	{"\xce\xfa\xed\xfe", "a Mach-O executable"},
+	{"\xca\xfe\xba\xbe", "a Mach-O executable"},
+	{"#!", "a script"},
+	{"PK\x03\x04", "a zip archive"},
+	{"Rar!\x1a\x07", "a RAR archive"},
+	{"7z\xbc\xaf\x27\x1c", "a 7-Zip archive"},
+	{"\x1f\x8b", "a gzip archive"},
+}
+
+// baitExtensions are the extensions of the files that run code
+var baitExtensions = map[string]bool{
+	".exe": true, ".dll": true, ".com": true, ".scr": true, ".bat": true,
+	".cmd": true, ".vbs": true, ".ps1": true, ".jar": true, ".apk": true,
+	".msi": true, ".sh": true, ".app": true, ".lnk": true,
+}
+
+// ScreenContent looks for suspicious content, that the retailers screen
+// before accepting a book
+//
+// The issues reported are empty files (errors), documents of the spine
+// without text nor images, documents that are only images (which may be
+// images of text, the cover page is not reported), placeholder text like
+// "lorem ipsum" on the documents, the title or the description (warnings)
+// and bait files: executables and archives, by their content or extension
+// (errors).
+func (e Epub) ScreenContent() ([]PreflightIssue, error) {
+	var issues []PreflightIssue
+	add := func(check, severity, href, message string) {
+		issues = append(issues, PreflightIssue{check, severity, href, message})
+	}
+
+	for _, name := range e.fileNames() {
+		if name == mimetypeName {
+			continue
+		}
+		href := strings.TrimPrefix(name, e.rootPath)
+		if e.fileSize(name) == 0 {
+			add(CheckEmptyFile, SeverityError, href, "The file is empty")
+			continue
+		}
+		if kind := e.baitKind(name); kind != "" {
+			add(CheckBaitFile, SeverityError, href, "The file is "+kind)
+		}
+	}
+
+	coverPage := e.coverPage()
+	for i := 0; i < e.opf.spineLength(); i++ {
+		href := e.opf.spineURL(i)
+		if href == "" {
+			continue
+		}
+		data, err := e.readFile(e.rootPath + href)
+		if err != nil || len(data) == 0 {
+			continue
+		}
+		text := extractText(data)
+		letters := 0
+		for _, r := range text {
+			if unicode.IsLetter(r) || unicode.IsNumber(r) {
+				letters++
+			}
+		}
+		images := contentRegexp.Match(data)
+		switch {
+		case letters == 0 && !images:
+			add(CheckBlankPage, SeverityWarning, href, "The document has no text nor images")
+		case letters < minDocumentText && images && href != coverPage:
+			add(CheckImageOnly, SeverityWarning, href, "The document is only images, they may be images of text")
+		}
+		if phrase := placeholderPhrase(text); phrase != "" {
+			add(CheckPlaceholder, SeverityWarning, href, `The document has placeholder text "`+phrase+`"`)
+		}
+	}
+	for _, field := range []string{"title", "description"} {
+		for _, elem := range e.metadata[field] {
+			if phrase := placeholderPhrase(extractText([]byte(elem.Content))); phrase != "" {
+				add(CheckPlaceholder, SeverityWarning, "", "The "+field+` has placeholder text "`+phrase+`"`)
+			}
+		}
+	}
+	return issues, nil
+}
+
+// fileSize returns the uncompressed size of the file name of the epub
+func (e Epub) fileSize(name string) int64 {
+	if data, ok := e.staged[name]; ok {
+		return int64(len(data))
+	}
+	if f := e.findFile(name); f != nil {
+		return int64(f.UncompressedSize64)
+	}
+	return 0
+}
+
+// baitKind returns the kind of executable or archive of the file name, or
+// an empty string if it is not one
+func (e Epub) baitKind(name string) string {
+	if baitExtensions[strings.ToLower(path.Ext(name))] {
+		return "an executable (" + path.Ext(name) + ")"
+	}
+	f, err := e.open(name)
+	if err != nil {
+		return ""
+	}
+	data, err := sniff(f)
+	f.Close()
+	if err != nil {
+		return ""
+	}
+	for _, sig := range baitSignatures {
+		if bytes.HasPrefix(data, []byte(sig.prefix)) {
+			return sig.kind
+		}
+	}
+	return ""
+}
+
+// placeholderPhrase returns the placeholder phrase found on text, or an
+// empty string
+func placeholderPhrase(text string) string {
+	text = strings.ToLower(strings.Join(strings.Fields(text), " "))
+	for _, phrase := range placeholderPhrases {
+		if strings.Contains(text, phrase) {
+			return phrase
+		}
+	}
+	return ""
+}
@@ -0,0 +1,47 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+func TestScreenContent(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+	issues, err := f.ScreenContent()
+	if err != nil {
+		t.Fatalf("ScreenContent() return an error: %v", err)
+	}
+	if len(issues) != 0 {
+		t.Errorf("ScreenContent() return: %v", issues)
+	}
+
+	f = buildEpub(t, epub3OPF, map[string]string{
+		"nav.xhtml":        epub3Nav,
+		"images/cover.jpg": "",
+		"text/ch1.xhtml":   `<html><body><p>Lorem  ipsum dolor sit amet, consectetur adipiscing elit.</p></body></html>`,
+		"text/ch2.xhtml":   `<html><body><div><img src="../images/page.png"/></div></body></html>`,
+		"extra/setup.exe":  "text",
+		"extra/data.bin":   "MZ\x90\x00",
+	})
+	issues, err = f.ScreenContent()
+	if err != nil {
+		t.Fatalf("ScreenContent() return an error: %v", err)
+	}
+	expected := map[string]string{
+		"images/cover.jpg": CheckEmptyFile,
+		"extra/setup.exe":  CheckBaitFile,
+		"extra/data.bin":   CheckBaitFile,
+		"text/ch1.xhtml":   CheckPlaceholder,
+		"text/ch2.xhtml":   CheckImageOnly,
+	}
+	if len(issues) != len(expected) {
+		t.Errorf("ScreenContent() return: %v", issues)
+	}
+	for _, issue := range issues {
+		if expected[issue.Href] != issue.Check {
+			t.Errorf("Unexpected issue: %v", issue)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package search
+
+import (
+	"strings"
+
+	"github.com/meskio/epubgo"
+)
+
+// BleveIndex is the method of bleve.Index used to add the chunks
+type BleveIndex interface {
+	Index(id string, data interface{}) error
+}
+
+// IndexBleve adds the chunks of the book to a Bleve index
+//
+// The chunks are indexed as Chunk documents with their ID. The queries
+// should search on the Terms field with the words normalized by
+// epubgo.Tokenize, and the ids of the hits can be decoded with ParseID.
+func IndexBleve(index BleveIndex, book *epubgo.Epub, bookID string) error {
+	chunks, err := Chunks(book, bookID)
+	if err != nil {
+		return err
+	}
+	for _, chunk := range chunks {
+		if err := index.Index(chunk.ID, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BleveHit is a hit of a Bleve search: the ID and the stored Fields of a
+// search.DocumentMatch
+type BleveHit struct {
+	ID     string
+	Fields map[string]interface{}
+}
+
+// BleveSearcher searches on a Bleve index the chunks with all the terms,
+// separated by spaces, on the Terms field and returns at most limit hits,
+// the best matches first
+//
+// With bleve it can be written as:
+//
+//	func(terms string, limit int) ([]search.BleveHit, error) {
+//		q := bleve.NewMatchQuery(terms)
+//		q.SetField("Terms")
+//		q.SetOperator(query.MatchQueryOperatorAnd)
+//		req := bleve.NewSearchRequestOptions(q, limit, 0, false)
+//		req.Fields = []string{"Href", "Text"}
+//		res, err := index.Search(req)
+//		if err != nil {
+//			return nil, err
+//		}
+//		hits := make([]search.BleveHit, len(res.Hits))
+//		for i, hit := range res.Hits {
+//			hits[i] = search.BleveHit{ID: hit.ID, Fields: hit.Fields}
+//		}
+//		return hits, nil
+//	}
+type BleveSearcher func(terms string, limit int) ([]BleveHit, error)
+
+// SearchBleve returns the chunks indexed with IndexBleve that contain all
+// the words of the query, the best matches first
+//
+// The words are normalized for the language lang as the indexed text. The
+// locations are decoded from the ids of the hits, the Href and the Text of
+// the results are the stored fields of the hits if the searcher returns
+// them.
+func SearchBleve(searcher BleveSearcher, query, lang string, limit int) ([]Result, error) {
+	var terms []string
+	for _, token := range epubgo.Tokenize(query, lang) {
+		terms = append(terms, token.Term)
+	}
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	hits, err := searcher(strings.Join(terms, " "), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, len(hits))
+	for i, hit := range hits {
+		bookID, spineIndex, offset, err := ParseID(hit.ID)
+		if err != nil {
+			return nil, err
+		}
+		results[i].BookID = bookID
+		results[i].Location = epubgo.Location{SpineIndex: spineIndex, Offset: offset}
+		results[i].Location.Href, _ = hit.Fields["Href"].(string)
+		results[i].Text, _ = hit.Fields["Text"].(string)
+	}
+	return results, nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+/*
+Package search indexes the text of epubs on full text search engines.
+
+The text of each document of the spine is split in chunks, one per paragraph,
+with the normalized words of epubgo.IndexTokens, so the same normalization can
+be applied to the queries with epubgo.NormalizeTerm. Each chunk keeps the
+location of the paragraph on the book.
+
+There are adapters for Bleve, through the BleveIndex interface implemented by
+bleve.Index, and for SQLite FTS5 through database/sql. The packages of Bleve
+and the SQLite driver are not imported, the caller chooses them.
+*/
+package search
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/meskio/epubgo"
+)
+
+// Chunk is a paragraph of the text of a book
+type Chunk struct {
+	// ID identifies the chunk on the index, see ParseID
+	ID         string
+	BookID     string
+	SpineIndex int
+	Href       string
+	// Offset is the position in bytes of the paragraph on the text of the
+	// document as returned by epubgo.Text
+	Offset int
+	Text   string
+	// Terms are the normalized words of the paragraph separated by spaces
+	Terms string
+}
+
+// Location returns the location of the chunk on the book
+func (c Chunk) Location() epubgo.Location {
+	return epubgo.Location{SpineIndex: c.SpineIndex, Href: c.Href, Offset: c.Offset}
+}
+
+// Chunks splits the text of the spine of the book in paragraphs
+//
+// bookID identifies the book on the index, it can't contain '/'.
+func Chunks(book *epubgo.Epub, bookID string) ([]Chunk, error) {
+	if bookID == "" || strings.Contains(bookID, "/") {
+		return nil, errors.New("Invalid book id " + bookID)
+	}
+
+	spine, err := book.Spine()
+	if err != nil {
+		return nil, err
+	}
+	var chunks []Chunk
+	for {
+		i := spine.Index()
+		text, err := book.Text(i)
+		if err != nil {
+			return nil, err
+		}
+		tokens, err := book.IndexTokens(i)
+		if err != nil {
+			return nil, err
+		}
+		href := spine.URL()
+
+		offset := 0
+		for _, line := range strings.SplitAfter(text, "\n") {
+			var terms []string
+			for len(tokens) > 0 && tokens[0].Offset < offset+len(line) {
+				terms = append(terms, tokens[0].Term)
+				tokens = tokens[1:]
+			}
+			if len(terms) > 0 {
+				chunks = append(chunks, Chunk{
+					ID:         chunkID(bookID, i, offset),
+					BookID:     bookID,
+					SpineIndex: i,
+					Href:       href,
+					Offset:     offset,
+					Text:       strings.TrimSpace(line),
+					Terms:      strings.Join(terms, " "),
+				})
+			}
+			offset += len(line)
+		}
+
+		if spine.Next() != nil {
+			return chunks, nil
+		}
+	}
+}
+
+func chunkID(bookID string, spineIndex, offset int) string {
+	return bookID + "/" + strconv.Itoa(spineIndex) + "/" + strconv.Itoa(offset)
+}
+
+// ParseID returns the book id, the spine index and the offset encoded on the
+// id of a chunk
+func ParseID(id string) (bookID string, spineIndex, offset int, err error) {
+	parts := strings.Split(id, "/")
+	if len(parts) != 3 {
+		return "", 0, 0, errors.New("Invalid chunk id " + id)
+	}
+	spineIndex, err = strconv.Atoi(parts[1])
+	if err != nil {
+		return "", 0, 0, err
+	}
+	offset, err = strconv.Atoi(parts[2])
+	if err != nil {
+		return "", 0, 0, err
+	}
+	return parts[0], spineIndex, offset, nil
+}
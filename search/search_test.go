@@ -0,0 +1,186 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package search
+
+import "testing"
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/meskio/epubgo"
+	"github.com/meskio/epubgo/internal/sqltest"
+)
+
+const bookPath = "../testdata/a_dogs_tale.epub"
+
+type fakeBleve map[string]interface{}
+
+func (f fakeBleve) Index(id string, data interface{}) error {
+	f[id] = data
+	return nil
+}
+
+// search returns the chunks with all the terms, sorted by id
+func (f fakeBleve) search(terms string, limit int) ([]BleveHit, error) {
+	var hits []BleveHit
+	for id, data := range f {
+		chunk := data.(Chunk)
+		words := " " + chunk.Terms + " "
+		found := true
+		for _, term := range strings.Fields(terms) {
+			found = found && strings.Contains(words, " "+term+" ")
+		}
+		if found {
+			hits = append(hits, BleveHit{id, map[string]interface{}{"Href": chunk.Href, "Text": chunk.Text}})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].ID < hits[j].ID })
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// checkResults checks that the results are locations of the book with text
+// containing the word
+func checkResults(t *testing.T, book *epubgo.Epub, results []Result, word string) {
+	if len(results) == 0 {
+		t.Fatalf("The search of %v return no results", word)
+	}
+	for _, r := range results {
+		text, _ := book.Text(r.Location.SpineIndex)
+		if r.BookID != "tale" || r.Location.Href == "" || !strings.HasPrefix(text[r.Location.Offset:], r.Text) {
+			t.Errorf("The result %v doesn't point to its text", r)
+		}
+		if !strings.Contains(strings.ToLower(r.Text), word) {
+			t.Errorf("The result %v doesn't contain %v", r, word)
+		}
+	}
+}
+
+func TestChunks(t *testing.T) {
+	book, err := epubgo.Open(bookPath)
+	if err != nil {
+		t.Fatalf("Open(%v) return an error: %v", bookPath, err)
+	}
+	defer book.Close()
+
+	chunks, err := Chunks(book, "tale")
+	if err != nil {
+		t.Fatalf("Chunks() return an error: %v", err)
+	}
+	if len(chunks) == 0 {
+		t.Fatalf("Chunks() return no chunks")
+	}
+	for _, c := range chunks[:10] {
+		text, _ := book.Text(c.SpineIndex)
+		if !strings.HasPrefix(text[c.Offset:], c.Text) {
+			t.Errorf("Chunk %v doesn't point to its text: %q", c.ID, c.Text)
+		}
+		bookID, spineIndex, offset, err := ParseID(c.ID)
+		if err != nil || bookID != "tale" || spineIndex != c.SpineIndex || offset != c.Offset {
+			t.Errorf("ParseID(%v) return: %v %v %v %v", c.ID, bookID, spineIndex, offset, err)
+		}
+		if c.Terms != strings.ToLower(c.Terms) {
+			t.Errorf("Chunk %v terms are not normalized: %v", c.ID, c.Terms)
+		}
+	}
+
+	if _, err := Chunks(book, "a/b"); err == nil {
+		t.Errorf("Chunks() didn't return an error with an invalid id")
+	}
+}
+
+func TestIndexBleve(t *testing.T) {
+	book, _ := epubgo.Open(bookPath)
+	defer book.Close()
+
+	index := make(fakeBleve)
+	if err := IndexBleve(index, book, "tale"); err != nil {
+		t.Fatalf("IndexBleve() return an error: %v", err)
+	}
+	chunks, _ := Chunks(book, "tale")
+	if len(index) != len(chunks) {
+		t.Errorf("IndexBleve() indexed %v chunks of %v", len(index), len(chunks))
+	}
+}
+
+func TestSearchBleve(t *testing.T) {
+	book, _ := epubgo.Open(bookPath)
+	defer book.Close()
+
+	index := make(fakeBleve)
+	IndexBleve(index, book, "tale")
+	results, err := SearchBleve(index.search, "PUPPY", "en", 5)
+	if err != nil {
+		t.Fatalf("SearchBleve() return an error: %v", err)
+	}
+	if len(results) > 5 {
+		t.Errorf("SearchBleve() return %v results", len(results))
+	}
+	checkResults(t, book, results, "puppy")
+
+	if results, err := SearchBleve(index.search, "...", "en", 5); err != nil || results != nil {
+		t.Errorf("SearchBleve() of an empty query return: %v, %v", results, err)
+	}
+	index["bad"] = Chunk{Terms: "puppy"}
+	if _, err := SearchBleve(index.search, "puppy", "en", 100); err == nil {
+		t.Errorf("SearchBleve() didn't return an error with an invalid id")
+	}
+}
+
+func TestSQLiteIndex(t *testing.T) {
+	book, _ := epubgo.Open(bookPath)
+	defer book.Close()
+	db, store, err := sqltest.Open()
+	if err != nil {
+		t.Fatalf("sqltest.Open() return an error: %v", err)
+	}
+	defer db.Close()
+
+	index, err := NewSQLiteIndex(db, "chunks")
+	if err != nil {
+		t.Fatalf("NewSQLiteIndex() return an error: %v", err)
+	}
+	chunks, _ := Chunks(book, "tale")
+	for _, bookID := range []string{"tale", "tale", "other"} {
+		if err := index.Add(book, bookID); err != nil {
+			t.Fatalf("Add() return an error: %v", err)
+		}
+	}
+	if rows := store.Rows("chunks"); len(rows) != 2*len(chunks) {
+		t.Errorf("Add() twice of the same book left %v rows for %v chunks", len(rows), len(chunks))
+	}
+
+	if err := index.Remove("other"); err != nil {
+		t.Fatalf("Remove() return an error: %v", err)
+	}
+	results, err := index.Search("Puppy", "en", 5)
+	if err != nil {
+		t.Fatalf("Search() return an error: %v", err)
+	}
+	if len(results) > 5 {
+		t.Errorf("Search() return %v results", len(results))
+	}
+	checkResults(t, book, results, "puppy")
+
+	if err := index.Remove("tale"); err != nil {
+		t.Fatalf("Remove() return an error: %v", err)
+	}
+	if results, err := index.Search("puppy", "en", 5); err != nil || len(results) != 0 {
+		t.Errorf("Search() after Remove() return: %v, %v", results, err)
+	}
+}
+
+func TestFTSQuery(t *testing.T) {
+	query := ftsQuery(`The DOG's "tale" OR`, "en")
+	if query != `"the" "dog's" "tale" "or"` {
+		t.Errorf("ftsQuery() return: %v", query)
+	}
+	if _, err := NewSQLiteIndex(nil, "books; DROP TABLE x"); err == nil {
+		t.Errorf("NewSQLiteIndex() didn't return an error with an invalid table")
+	}
+}
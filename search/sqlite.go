@@ -0,0 +1,122 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package search
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/meskio/epubgo"
+)
+
+var tableNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Result is a chunk found by a search
+type Result struct {
+	BookID   string
+	Location epubgo.Location
+	Text     string
+}
+
+// SQLiteIndex is a full text index on a SQLite FTS5 table
+type SQLiteIndex struct {
+	db    *sql.DB
+	table string
+}
+
+// NewSQLiteIndex creates, if it doesn't exist, the FTS5 table to index the
+// books on db
+//
+// db must be opened with a SQLite driver compiled with FTS5.
+func NewSQLiteIndex(db *sql.DB, table string) (*SQLiteIndex, error) {
+	if !tableNameRegexp.MatchString(table) {
+		return nil, errors.New("Invalid table name " + table)
+	}
+	_, err := db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS ` + table + ` USING fts5(
+		terms, text UNINDEXED, book_id UNINDEXED, spine_index UNINDEXED,
+		href UNINDEXED, text_offset UNINDEXED)`)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteIndex{db, table}, nil
+}
+
+// Add indexes the chunks of the book, replacing the ones already indexed
+// with the same bookID
+func (s SQLiteIndex) Add(book *epubgo.Epub, bookID string) error {
+	chunks, err := Chunks(book, bookID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec(`DELETE FROM `+s.table+` WHERE book_id = ?`, bookID)
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT INTO ` + s.table + `
+		(terms, text, book_id, spine_index, href, text_offset) VALUES (?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, c := range chunks {
+		_, err = stmt.Exec(c.Terms, c.Text, c.BookID, c.SpineIndex, c.Href, c.Offset)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Remove deletes the chunks of the book from the index
+func (s SQLiteIndex) Remove(bookID string) error {
+	_, err := s.db.Exec(`DELETE FROM `+s.table+` WHERE book_id = ?`, bookID)
+	return err
+}
+
+// Search returns the chunks that contain all the words of the query, the
+// best matches first
+//
+// The words are normalized for the language lang as the indexed text.
+func (s SQLiteIndex) Search(query, lang string, limit int) ([]Result, error) {
+	match := ftsQuery(query, lang)
+	if match == "" {
+		return nil, nil
+	}
+	rows, err := s.db.Query(`SELECT book_id, spine_index, href, text_offset, text FROM `+s.table+`
+		WHERE terms MATCH ? ORDER BY rank LIMIT ?`, match, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []Result
+	for rows.Next() {
+		var r Result
+		err := rows.Scan(&r.BookID, &r.Location.SpineIndex, &r.Location.Href, &r.Location.Offset, &r.Text)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// ftsQuery returns the FTS5 query for the words of query, every word quoted
+// so they are not parsed as FTS5 operators
+func ftsQuery(query, lang string) string {
+	var terms []string
+	for _, token := range epubgo.Tokenize(query, lang) {
+		terms = append(terms, `"`+strings.Replace(token.Term, `"`, `""`, -1)+`"`)
+	}
+	return strings.Join(terms, " ")
+}
@@ -0,0 +1,228 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"errors"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Segment is a paragraph or a sentence of a content document
+type Segment struct {
+	// ID identifies the segment on the document. For paragraphs it is the
+	// path of its element, like "/body/div[1]/p[3]", with the position of
+	// the element between its siblings of the same name. For sentences it
+	// is the ID of the paragraph followed by the position of the sentence,
+	// like "/body/div[1]/p[3]:2". The positions start by 1.
+	ID   string
+	Text string
+	// Sentences of a paragraph, nil for the sentences
+	Sentences []Segment
+}
+
+// segmentBlocks are the elements that contain paragraphs of text
+var segmentBlocks = map[string]bool{
+	"body": true, "p": true, "div": true, "section": true, "article": true,
+	"aside": true, "blockquote": true, "pre": true, "li": true, "dt": true,
+	"dd": true, "td": true, "th": true, "caption": true, "figcaption": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+var (
+	sentenceEndRegexp = regexp.MustCompile(`[.!?…]+["”’»)\]]*\s+`)
+	abbreviations     = map[string]bool{
+		"mr": true, "mrs": true, "ms": true, "dr": true, "st": true, "sr": true,
+		"jr": true, "vs": true, "etc": true, "e.g": true, "i.e": true, "no": true,
+		"mt": true, "prof": true, "capt": true, "col": true, "gen": true,
+	}
+)
+
+type segmentElement struct {
+	name     string
+	path     string
+	lang     string
+	skip     bool
+	children map[string]int
+}
+
+// paragraph is a segment with the information of its element
+type paragraph struct {
+	Segment
+	name string
+	lang string
+}
+
+// Segments returns the paragraphs of the document at spineIndex split in
+// sentences
+//
+// The IDs of the segments only depend on the structure of the document, so
+// they are stable between different readings and can be used to anchor
+// annotations or to highlight the text read aloud.
+func (e Epub) Segments(spineIndex int) ([]Segment, error) {
+	if spineIndex < 0 || spineIndex >= e.opf.spineLength() {
+		return nil, errors.New("Spine index out of range")
+	}
+	data, err := e.spineDocument(spineIndex)
+	if err != nil {
+		return nil, err
+	}
+	return segments(data)
+}
+
+func segments(data []byte) ([]Segment, error) {
+	paragraphs, err := paragraphs(data, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	var result []Segment
+	for _, p := range paragraphs {
+		result = append(result, p.Segment)
+	}
+	return result, nil
+}
+
+// paragraphs returns the paragraphs of the XHTML data, skipping the elements
+// with any of the skipTypes on their epub:type. lang is the language of the
+// document if it doesn't declare one.
+func paragraphs(data []byte, lang string, skipTypes map[string]bool) ([]paragraph, error) {
+	var result []paragraph
+	index := make(map[string]int)
+	stack := []segmentElement{{lang: lang, children: make(map[string]int)}}
+	addText := func(text string) {
+		if strings.TrimSpace(text) == "" {
+			return
+		}
+		block := -1
+		for i := len(stack) - 1; i > 0; i-- {
+			if stack[i].skip {
+				return
+			}
+			if block == -1 && segmentBlocks[stack[i].name] {
+				block = i
+			}
+		}
+		if block == -1 {
+			return
+		}
+		element := stack[block]
+		i, ok := index[element.path]
+		if !ok {
+			i = len(result)
+			index[element.path] = i
+			result = append(result, paragraph{Segment{ID: element.path}, element.name, element.lang})
+		}
+		result[i].Text += text
+	}
+
+	last := 0
+	for _, loc := range markupRegexp.FindAllIndex(data, -1) {
+		addText(string(data[last:loc[0]]))
+		last = loc[1]
+
+		tag := string(data[loc[0]:loc[1]])
+		if strings.HasPrefix(tag, "<!") || strings.HasPrefix(tag, "<?") {
+			continue
+		}
+		name := tagName(strings.TrimPrefix(tag, "</"))
+		name = name[strings.Index(name, ":")+1:]
+		switch {
+		case strings.HasPrefix(tag, "</"):
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].name == name {
+					stack = stack[:i]
+					break
+				}
+			}
+		case strings.HasSuffix(tag, "/>") || voidElements[name]:
+			if name == "br" {
+				addText(" ")
+			}
+		default:
+			parent := stack[len(stack)-1]
+			parent.children[name]++
+			path := ""
+			switch name {
+			case "html":
+			case "body":
+				path = parent.path + "/body"
+			default:
+				path = parent.path + "/" + name + "[" + strconv.Itoa(parent.children[name]) + "]"
+			}
+			if len(stack) > maxNestingDepth {
+				return nil, errors.New("Document too deeply nested")
+			}
+			element := segmentElement{name, path, parent.lang, textSkip[name], make(map[string]int)}
+			if l := attrValue(tag, "xml:lang"); l != "" {
+				element.lang = l
+			} else if l := attrValue(tag, "lang"); l != "" {
+				element.lang = l
+			}
+			for _, t := range strings.Fields(attrValue(tag, "epub:type")) {
+				if skipTypes[t] {
+					element.skip = true
+				}
+			}
+			stack = append(stack, element)
+		}
+	}
+	addText(string(data[last:]))
+
+	var paragraphs []paragraph
+	for _, p := range result {
+		p.Text = strings.TrimSpace(whitespaceRegexp.ReplaceAllString(html.UnescapeString(p.Text), " "))
+		if p.Text == "" {
+			continue
+		}
+		for i, sentence := range splitSentences(p.Text) {
+			p.Sentences = append(p.Sentences, Segment{
+				ID:   p.ID + ":" + strconv.Itoa(i+1),
+				Text: sentence,
+			})
+		}
+		paragraphs = append(paragraphs, p)
+	}
+	return paragraphs, nil
+}
+
+// splitSentences splits the text on the sentence endings, skipping the
+// common abbreviations and the endings not followed by a capital letter
+func splitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	for _, loc := range sentenceEndRegexp.FindAllStringIndex(text, -1) {
+		if loc[1] == len(text) || !startsSentence(text[loc[1]:]) || isAbbreviation(text[start:loc[0]]) {
+			continue
+		}
+		sentences = append(sentences, strings.TrimSpace(text[start:loc[1]]))
+		start = loc[1]
+	}
+	if rest := strings.TrimSpace(text[start:]); rest != "" {
+		sentences = append(sentences, rest)
+	}
+	return sentences
+}
+
+func startsSentence(text string) bool {
+	text = strings.TrimLeft(text, `"“‘'«(¿¡—`)
+	for _, r := range text {
+		return strings.ToUpper(string(r)) == string(r)
+	}
+	return false
+}
+
+func isAbbreviation(text string) bool {
+	word := text
+	if i := strings.LastIndexAny(text, " \t\n(\"“"); i != -1 {
+		word = text[i+1:]
+	}
+	if len([]rune(word)) == 1 {
+		// initials like "J. R. R. Tolkien"
+		return true
+	}
+	return abbreviations[strings.ToLower(word)]
+}
@@ -0,0 +1,85 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import "strings"
+
+const segmentsPage = `<?xml version="1.0" encoding="utf-8"?>
+<html xmlns="http://www.w3.org/1999/xhtml">
+<head><title>Chapter</title><style>p { margin: 0 }</style></head>
+<body>
+<h1>Chapter I</h1>
+<div>
+<p>Mr. Holmes came in. He was <i>late</i> again! &ldquo;Why?&rdquo; he asked.</p>
+<p>It was J. R. R. Tolkien's book... and it was good.</p>
+</div>
+<ul><li>one</li><li><p>two</p></li></ul>
+</body>
+</html>`
+
+func TestSegments(t *testing.T) {
+	segments, err := segments([]byte(segmentsPage))
+	if err != nil {
+		t.Fatalf("segments() return an error: %v", err)
+	}
+	ids := []string{"/body/h1[1]", "/body/div[1]/p[1]", "/body/div[1]/p[2]", "/body/ul[1]/li[1]", "/body/ul[1]/li[2]/p[1]"}
+	if len(segments) != len(ids) {
+		t.Fatalf("segments() return: %v", segments)
+	}
+	for i, id := range ids {
+		if segments[i].ID != id {
+			t.Errorf("Segment %d has id %s instead of %s", i, segments[i].ID, id)
+		}
+	}
+
+	sentences := segments[1].Sentences
+	texts := []string{"Mr. Holmes came in.", "He was late again!", "“Why?” he asked."}
+	if len(sentences) != len(texts) {
+		t.Fatalf("Wrong sentences: %v", sentences)
+	}
+	for i, text := range texts {
+		if sentences[i].Text != text {
+			t.Errorf("Sentence %d is '%s' instead of '%s'", i, sentences[i].Text, text)
+		}
+	}
+	if sentences[2].ID != "/body/div[1]/p[1]:3" {
+		t.Errorf("Wrong sentence id: %s", sentences[2].ID)
+	}
+	if len(segments[2].Sentences) != 1 {
+		t.Errorf("Wrong sentences: %v", segments[2].Sentences)
+	}
+}
+
+func TestSegmentsBook(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	segments, err := f.Segments(1)
+	if err != nil {
+		t.Fatalf("Segments() return an error: %v", err)
+	}
+	if len(segments) == 0 || len(segments[0].Sentences) == 0 {
+		t.Errorf("Segments() return: %v", segments)
+	}
+	again, _ := f.Segments(1)
+	if again[len(again)-1].ID != segments[len(segments)-1].ID {
+		t.Errorf("The ids are not stable")
+	}
+	if _, err := f.Segments(-1); err == nil {
+		t.Errorf("Segments() didn't return an error with an invalid index")
+	}
+}
+
+func TestSegmentsDeep(t *testing.T) {
+	page := "<html><body>" + strings.Repeat("<div><p>text</p>", 20000) + strings.Repeat("</div>", 20000) + "</body></html>"
+	if _, err := segments([]byte(page)); err == nil {
+		t.Errorf("segments() didn't return an error with a too deep document")
+	}
+	if parts := splitDocument([]byte(page), map[string]bool{"h1": true}, 100); parts != nil {
+		t.Errorf("splitDocument() split a too deep document")
+	}
+}
@@ -0,0 +1,307 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const defaultPageSize = 2000
+
+// ReadingSession keeps the state of a reader on the book: the position on
+// the text, its pagination and the bookmarks
+//
+// The pages are chunks of the text of the documents of the spine, as
+// returned by Epub.Text, of at most PageSize bytes broken on whitespace.
+type ReadingSession struct {
+	Bookmarks []Bookmark
+
+	epub     *Epub
+	pageSize int
+	location Location
+	// pages are the offsets where the pages of the current document start
+	pages []int
+	text  string
+}
+
+// Bookmark is a named location of the book
+type Bookmark struct {
+	Title    string
+	Location Location
+}
+
+// sessionState is the JSON representation of the ReadingSession
+type sessionState struct {
+	Identifier string
+	PageSize   int
+	Location   Location
+	// CFI is the location for other reading systems
+	CFI       string
+	Bookmarks []Bookmark
+}
+
+// ReadingSession starts reading the book from the beginning with pages of
+// pageSize bytes of text, 2000 if pageSize is 0
+func (e *Epub) ReadingSession(pageSize int) (*ReadingSession, error) {
+	first := e.opf.nextSpineItem(-1, 1)
+	if first == -1 {
+		return nil, errors.New("Spine is empty")
+	}
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	session := &ReadingSession{epub: e, pageSize: pageSize}
+	return session, session.GoTo(Location{SpineIndex: first, Href: e.opf.spineURL(first)})
+}
+
+// RestoreReadingSession restores a session saved with
+// ReadingSession.MarshalJSON
+//
+// It returns an error if the session is from another book. If the spine
+// changed since the session was saved the document is searched by its href.
+// A session without Location, like the ones saved by other applications, is
+// restored from its CFI.
+func (e *Epub) RestoreReadingSession(data []byte) (*ReadingSession, error) {
+	var state sessionState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	if state.Identifier != e.first("identifier") {
+		return nil, errors.New("The reading session is from another book")
+	}
+
+	session, err := e.ReadingSession(state.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	session.Bookmarks = state.Bookmarks
+	if state.Location.Href == "" && state.CFI != "" {
+		return session, session.GoToCFI(state.CFI)
+	}
+	return session, session.GoTo(state.Location)
+}
+
+// MarshalJSON implements json.Marshaler
+func (s *ReadingSession) MarshalJSON() ([]byte, error) {
+	cfi, err := s.CFI()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(sessionState{
+		Identifier: s.epub.first("identifier"),
+		PageSize:   s.pageSize,
+		Location:   s.location,
+		CFI:        cfi,
+		Bookmarks:  s.Bookmarks,
+	})
+}
+
+// Location returns the current position on the book
+func (s ReadingSession) Location() Location {
+	return s.location
+}
+
+// Page returns the current page on the document, starting by 0
+func (s ReadingSession) Page() int {
+	page := 0
+	for i, start := range s.pages {
+		if start <= s.location.Offset {
+			page = i
+		}
+	}
+	return page
+}
+
+// PageCount returns the number of pages of the current document
+func (s ReadingSession) PageCount() int {
+	return len(s.pages)
+}
+
+// PageText returns the text of the current page
+func (s ReadingSession) PageText() string {
+	page := s.Page()
+	end := len(s.text)
+	if page+1 < len(s.pages) {
+		end = s.pages[page+1]
+	}
+	return strings.TrimSpace(s.text[s.pages[page]:end])
+}
+
+// NextPage advances to the next page, moving to the next document of the
+// spine after the last page
+//
+// Returns an error if it is the last page of the book
+func (s *ReadingSession) NextPage() error {
+	page := s.Page()
+	if page+1 < len(s.pages) {
+		s.location.Offset = s.pages[page+1]
+		return nil
+	}
+	next := s.epub.opf.nextSpineItem(s.location.SpineIndex, 1)
+	if next == -1 {
+		return errors.New("It is the last page")
+	}
+	return s.GoTo(Location{SpineIndex: next})
+}
+
+// PrevPage steps back to the previous page, moving to the last page of the
+// previous document of the spine before the first page
+//
+// Returns an error if it is the first page of the book
+func (s *ReadingSession) PrevPage() error {
+	page := s.Page()
+	if page > 0 {
+		s.location.Offset = s.pages[page-1]
+		return nil
+	}
+	previous := s.epub.opf.nextSpineItem(s.location.SpineIndex, -1)
+	if previous == -1 {
+		return errors.New("It is the first page")
+	}
+	if err := s.GoTo(Location{SpineIndex: previous}); err != nil {
+		return err
+	}
+	s.location.Offset = s.pages[len(s.pages)-1]
+	return nil
+}
+
+// GoTo moves to the location
+//
+// If the location has an Href it is used to find the document on the spine,
+// if not the SpineIndex is used.
+func (s *ReadingSession) GoTo(location Location) error {
+	if location.Href != "" {
+		index := s.epub.spineIndexOf(location.Href)
+		if index == -1 {
+			return errors.New("The document " + location.Href + " is not on the spine")
+		}
+		location.SpineIndex = index
+	}
+	text, err := s.epub.Text(location.SpineIndex)
+	if err != nil {
+		return err
+	}
+	location.Href = s.epub.opf.spineURL(location.SpineIndex)
+	if location.Offset < 0 || location.Offset > len(text) {
+		location.Offset = 0
+	}
+
+	s.text = text
+	s.pages = paginate(text, s.pageSize)
+	s.location = location
+	return nil
+}
+
+// GoToTOCEntry moves to the position of the entry of the table of contents
+// returned by Epub.TOC
+func (s *ReadingSession) GoToTOCEntry(entry TOCEntry) error {
+	href := entry.Href
+	fragment := ""
+	if i := strings.Index(href, "#"); i != -1 {
+		href, fragment = href[:i], href[i+1:]
+	}
+	index := s.epub.spineIndexOf(href)
+	if index == -1 {
+		return errors.New("The document " + href + " is not on the spine")
+	}
+
+	location := Location{SpineIndex: index}
+	if fragment != "" {
+		data, err := s.epub.readFile(s.epub.rootPath + s.epub.opf.spineURL(index))
+		if err != nil {
+			return err
+		}
+		location.Offset = fragmentOffset(data, fragment)
+	}
+	return s.GoTo(location)
+}
+
+// AddBookmark bookmarks the current location
+func (s *ReadingSession) AddBookmark(title string) Bookmark {
+	bookmark := Bookmark{Title: title, Location: s.location}
+	s.Bookmarks = append(s.Bookmarks, bookmark)
+	return bookmark
+}
+
+// CFI returns the EPUB canonical fragment identifier of the current
+// location, see Epub.LocationCFI
+func (s ReadingSession) CFI() (string, error) {
+	return s.epub.LocationCFI(s.location)
+}
+
+// GoToCFI moves to the position of the EPUB canonical fragment identifier,
+// see Epub.CFILocation
+func (s *ReadingSession) GoToCFI(cfi string) error {
+	location, err := s.epub.CFILocation(cfi)
+	if err != nil {
+		return err
+	}
+	return s.GoTo(location)
+}
+
+// TOC returns the table of contents of the book
+//
+// The hrefs are the paths as used by OpenFile.
+func (e Epub) TOC() []TOCEntry {
+	if e.ncx == nil {
+		return nil
+	}
+	return tocEntries(e.ncx.NavMap, e.rootPath+e.opf.ncxPath(), e.rootPath)
+}
+
+// spineIndexOf returns the position on the spine of the document href, as
+// used by OpenFile, or -1 if it is not on the spine
+func (e Epub) spineIndexOf(href string) int {
+	target := resolveRef(e.rootPath, href)
+	for i := 0; i < e.opf.spineLength(); i++ {
+		if href := e.opf.spineURL(i); href != "" && resolveRef(e.rootPath, href) == target {
+			return i
+		}
+	}
+	return -1
+}
+
+// fragmentOffset returns the offset on the text of the document of the
+// element with id, 0 if it is not found
+func fragmentOffset(data []byte, id string) int {
+	re := regexp.MustCompile(`<[^>]*\sid\s*=\s*["']` + regexp.QuoteMeta(id) + `["']`)
+	loc := re.FindIndex(data)
+	if loc == nil {
+		return 0
+	}
+	offset := len(extractText(data[:loc[0]]))
+	if offset > 0 {
+		// the element starts a new line
+		offset++
+	}
+	return offset
+}
+
+// paginate returns the offsets where the pages of the text start
+func paginate(text string, pageSize int) []int {
+	pages := []int{0}
+	start := 0
+	for len(text)-start > pageSize {
+		end := start + pageSize
+		brk := strings.LastIndexFunc(text[start:end], unicode.IsSpace)
+		if brk <= 0 {
+			brk = pageSize
+			for brk > 1 && !utf8.RuneStart(text[start+brk]) {
+				brk--
+			}
+		} else {
+			_, size := utf8.DecodeRuneInString(text[start+brk:])
+			brk += size
+		}
+		start += brk
+		pages = append(pages, start)
+	}
+	return pages
+}
@@ -0,0 +1,92 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+func TestReadingSession(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	session, err := f.ReadingSession(500)
+	if err != nil {
+		t.Fatalf("ReadingSession() return an error: %v", err)
+	}
+	if err := session.PrevPage(); err == nil {
+		t.Errorf("PrevPage() didn't return an error on the first page")
+	}
+	for session.Location().SpineIndex == 0 {
+		if err := session.NextPage(); err != nil {
+			t.Fatalf("NextPage() return an error: %v", err)
+		}
+	}
+	if session.Page() != 0 || session.PageCount() < 2 || session.PageText() == "" {
+		t.Errorf("Wrong page %d of %d: %s", session.Page(), session.PageCount(), session.PageText())
+	}
+	if len(session.PageText()) > 500 {
+		t.Errorf("The page is too long: %d", len(session.PageText()))
+	}
+	session.NextPage()
+	session.AddBookmark("bookmark")
+	page := session.PageText()
+	cfi, err := session.CFI()
+	if err != nil || !strings.HasPrefix(cfi, "epubcfi(/6/4["+f.opf.Spine.Items[1].IDref+"]!/4/") {
+		t.Errorf("CFI() return: %s, %v", cfi, err)
+	}
+	other, _ := f.ReadingSession(500)
+	if err := other.GoToCFI(cfi); err != nil || other.Location() != session.Location() {
+		t.Errorf("GoToCFI(%s) moved to: %v, %v", cfi, other.Location(), err)
+	}
+
+	data, err := session.MarshalJSON()
+	if err != nil {
+		t.Fatalf("MarshalJSON() return an error: %v", err)
+	}
+	restored, err := f.RestoreReadingSession(data)
+	if err != nil {
+		t.Fatalf("RestoreReadingSession() return an error: %v", err)
+	}
+	if restored.PageText() != page || len(restored.Bookmarks) != 1 || restored.Bookmarks[0].Location != session.Location() {
+		t.Errorf("Wrong restored session: %v", restored)
+	}
+	restored.PrevPage()
+	restored.PrevPage()
+	if restored.Location().SpineIndex != 0 || restored.Page() != restored.PageCount()-1 {
+		t.Errorf("PrevPage() didn't go to the last page of the previous document: %v", restored.Location())
+	}
+
+	toc := f.TOC()
+	if len(toc) < 2 {
+		t.Fatalf("TOC() return: %v", toc)
+	}
+	if err := session.GoToTOCEntry(toc[1]); err != nil {
+		t.Fatalf("GoToTOCEntry() return an error: %v", err)
+	}
+	href := strings.SplitN(toc[1].Href, "#", 2)[0]
+	if session.Location().SpineIndex != f.spineIndexOf(href) {
+		t.Errorf("GoToTOCEntry() moved to: %v", session.Location())
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	text := strings.Repeat("aaa\u3000bb\u00a0c ", 20)
+	for size := 4; size < 20; size++ {
+		pages := paginate(text, size)
+		for i, start := range pages {
+			end := len(text)
+			if i+1 < len(pages) {
+				end = pages[i+1]
+			}
+			if page := text[start:end]; !utf8.ValidString(page) || len(page) > size {
+				t.Fatalf("paginate() with size %d return the page %q", size, page)
+			}
+		}
+	}
+}
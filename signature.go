@@ -0,0 +1,524 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"hash"
+	"io"
+	"math/big"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	signaturesName = "META-INF/signatures.xml"
+
+	dsigNamespace  = "http://www.w3.org/2000/09/xmldsig#"
+	c14nAlgorithm  = "http://www.w3.org/TR/2001/REC-xml-c14n-20010315"
+	sha1Digest     = "http://www.w3.org/2000/09/xmldsig#sha1"
+	sha256Digest   = "http://www.w3.org/2001/04/xmlenc#sha256"
+	sha512Digest   = "http://www.w3.org/2001/04/xmlenc#sha512"
+	rsaSHA256      = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	ecdsaSHA256    = "http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha256"
+	rsaSHA1        = "http://www.w3.org/2000/09/xmldsig#rsa-sha1"
+	containerNSURI = "urn:oasis:names:tc:opendocument:xmlns:container"
+)
+
+var (
+	elementPrefixRegexp = regexp.MustCompile(`^<(?:([\w-]+):)?[\w-]+`)
+	selfClosingRegexp   = regexp.MustCompile(`<([\w:-]+)([^<>]*?)\s*/>`)
+)
+
+// Signature is an XML-DSig signature of the container (META-INF/signatures.xml)
+type Signature struct {
+	ID              string
+	SignatureMethod string
+	References      []SignatureReference
+	Value           []byte
+	// Certificates contains the DER encoded X509 certificates of the KeyInfo
+	Certificates [][]byte
+	// signedInfo is the raw SignedInfo element canonicalized
+	signedInfo []byte
+	// elements are the elements of signatures.xml with an Id canonicalized,
+	// the targets of the same document references
+	elements map[string][]byte
+}
+
+// SignatureReference is a file of the epub covered by a signature
+type SignatureReference struct {
+	URI          string
+	DigestMethod string
+	Digest       []byte
+}
+
+type xmlSignature struct {
+	ID         string
+	SignedInfo xmlSignedInfo
+	Value      string
+	X509       []string
+}
+type xmlKeyInfo struct {
+	X509 []string `xml:"X509Data>X509Certificate"`
+}
+type xmlSignedInfo struct {
+	Canonicalization xmlAlgorithm   `xml:"CanonicalizationMethod"`
+	SignatureMethod  xmlAlgorithm   `xml:"SignatureMethod"`
+	Reference        []xmlReference `xml:"Reference"`
+}
+type xmlAlgorithm struct {
+	Algorithm string `xml:"Algorithm,attr"`
+}
+type xmlReference struct {
+	URI          string       `xml:"URI,attr"`
+	DigestMethod xmlAlgorithm `xml:"DigestMethod"`
+	DigestValue  string       `xml:"DigestValue"`
+}
+
+// Signatures returns the signatures of the container
+//
+// Returns an error if the epub has no META-INF/signatures.xml or if it is
+// ambiguous: a Signature with more than one SignedInfo, a SignedInfo inside a
+// comment or two elements with the same Id.
+func (e Epub) Signatures() ([]Signature, error) {
+	f, err := e.open(signaturesName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := readAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	elements, err := signatureElements(data)
+	if err != nil {
+		return nil, err
+	}
+	var signatures []Signature
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name != (xml.Name{Space: dsigNamespace, Local: "Signature"}) {
+			continue
+		}
+		s, err := decodeSignature(d, start, data)
+		if err != nil {
+			return nil, err
+		}
+		s.elements = elements
+		signatures = append(signatures, s)
+	}
+	return signatures, nil
+}
+
+// decodeSignature decodes the Signature element that starts with start, data
+// is the signatures.xml read by d
+//
+// The SignedInfo is taken from the same bytes its references are decoded
+// from, using the offsets of the decoder.
+func decodeSignature(d *xml.Decoder, start xml.StartElement, data []byte) (Signature, error) {
+	var sig xmlSignature
+	var signedInfo []byte
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "Id" {
+			sig.ID = attr.Value
+		}
+	}
+	for {
+		offset := d.InputOffset()
+		tok, err := d.Token()
+		if err != nil {
+			return Signature{}, err
+		}
+		switch t := tok.(type) {
+		case xml.EndElement:
+			s, err := sig.toSignature()
+			s.signedInfo = signedInfo
+			return s, err
+		case xml.StartElement:
+			if t.Name.Space != dsigNamespace {
+				err = d.Skip()
+				break
+			}
+			switch t.Name.Local {
+			case "SignedInfo":
+				if signedInfo != nil {
+					return Signature{}, errors.New("The signature has more than one SignedInfo")
+				}
+				err = d.DecodeElement(&sig.SignedInfo, &t)
+				raw := data[offset:d.InputOffset()]
+				if bytes.Contains(raw, []byte("<!--")) {
+					return Signature{}, errors.New("The SignedInfo has comments")
+				}
+				signedInfo = canonicalElement(raw, dsigNamespace)
+			case "SignatureValue":
+				err = d.DecodeElement(&sig.Value, &t)
+			case "KeyInfo":
+				var keyInfo xmlKeyInfo
+				err = d.DecodeElement(&keyInfo, &t)
+				sig.X509 = append(sig.X509, keyInfo.X509...)
+			default:
+				err = d.Skip()
+			}
+		}
+		if err != nil {
+			return Signature{}, err
+		}
+	}
+}
+
+// signatureElements returns the elements of signatures.xml with an Id
+// canonicalized, by their Id
+//
+// Returns an error if two elements have the same Id or a comment contains a
+// SignedInfo, both are ways to show to the verifier other content than the
+// signed one.
+func signatureElements(data []byte) (map[string][]byte, error) {
+	type openElement struct {
+		offset int64
+		id     string
+		space  string
+	}
+	elements := make(map[string][]byte)
+	var stack []openElement
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		offset := d.InputOffset()
+		tok, err := d.Token()
+		if err == io.EOF {
+			return elements, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.Comment:
+			if bytes.Contains(t, []byte("SignedInfo")) {
+				return nil, errors.New("signatures.xml has a SignedInfo inside a comment")
+			}
+		case xml.StartElement:
+			element := openElement{offset: offset, space: t.Name.Space}
+			for _, attr := range t.Attr {
+				if attr.Name.Local == "Id" {
+					element.id = attr.Value
+				}
+			}
+			stack = append(stack, element)
+		case xml.EndElement:
+			element := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if element.id == "" {
+				continue
+			}
+			if _, ok := elements[element.id]; ok {
+				return nil, errors.New("Duplicated Id " + element.id + " on signatures.xml")
+			}
+			elements[element.id] = canonicalElement(data[element.offset:d.InputOffset()], element.space)
+		}
+	}
+}
+
+func (sig xmlSignature) toSignature() (s Signature, err error) {
+	s.ID = sig.ID
+	s.SignatureMethod = sig.SignedInfo.SignatureMethod.Algorithm
+	s.Value, err = decodeBase64(sig.Value)
+	if err != nil {
+		return
+	}
+	for _, cert := range sig.X509 {
+		der, err := decodeBase64(cert)
+		if err != nil {
+			return s, err
+		}
+		s.Certificates = append(s.Certificates, der)
+	}
+	for _, ref := range sig.SignedInfo.Reference {
+		digest, err := decodeBase64(ref.DigestValue)
+		if err != nil {
+			return s, err
+		}
+		s.References = append(s.References, SignatureReference{
+			URI:          ref.URI,
+			DigestMethod: ref.DigestMethod.Algorithm,
+			Digest:       digest,
+		})
+	}
+	return
+}
+
+// VerifySignature checks the digests of the referenced files and the signature value
+//
+// The key is the trusted public key of the signer, the certificates of the
+// signature are not used as anybody can add one. Use VerifySignatureChain to
+// trust the signers with a certificate of a certification authority. The
+// signature value is checked against the SignedInfo element of
+// signatures.xml canonicalized, which supports the SignedInfo written by Sign
+// and documents without unusual formatting. The same document references,
+// like "#props", are checked against the element with that Id canonicalized
+// the same way.
+func (e Epub) VerifySignature(s Signature, key crypto.PublicKey) error {
+	if key == nil {
+		return errors.New("No key to verify the signature")
+	}
+	if s.signedInfo == nil {
+		return errors.New("The signature has no SignedInfo")
+	}
+	for _, ref := range s.References {
+		var name string
+		var digest []byte
+		var err error
+		if strings.HasPrefix(ref.URI, "#") {
+			name = ref.URI
+			element, ok := s.elements[strings.TrimPrefix(ref.URI, "#")]
+			if !ok {
+				return errors.New("Reference to an unknown element " + ref.URI)
+			}
+			digest, err = digestReader(bytes.NewReader(element), ref.DigestMethod)
+		} else {
+			name, err = url.PathUnescape(ref.URI)
+			if err != nil {
+				return err
+			}
+			digest, err = e.digest(name, ref.DigestMethod)
+		}
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(digest, ref.Digest) {
+			return errors.New("Digest of " + name + " does not match")
+		}
+	}
+
+	return verifySignatureValue(s.SignatureMethod, s.signedInfo, s.Value, key)
+}
+
+// VerifySignatureChain checks the signature with the key of its first
+// certificate, after validating the certificate against the roots
+//
+// The other certificates of the signature are used as intermediates. Returns
+// the validated certificate of the signer.
+func (e Epub) VerifySignatureChain(s Signature, roots *x509.CertPool) (*x509.Certificate, error) {
+	if roots == nil {
+		return nil, errors.New("No roots to verify the certificate")
+	}
+	if len(s.Certificates) == 0 {
+		return nil, errors.New("The signature has no certificate")
+	}
+	certs := make([]*x509.Certificate, len(s.Certificates))
+	for i, der := range s.Certificates {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+		certs[i] = cert
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	if _, err := certs[0].Verify(opts); err != nil {
+		return nil, err
+	}
+	return certs[0], e.VerifySignature(s, certs[0].PublicKey)
+}
+
+// Sign adds a META-INF/signatures.xml signing all the files of the epub
+//
+// The signature covers the content as it is when Sign is called, so it should
+// be the last modification done before Repack and no transforms should be
+// passed to Repack. Only RSA and ECDSA keys are supported. The certificates,
+// the one of the key first, are added to the KeyInfo of the signature.
+func (e Epub) Sign(key crypto.Signer, certs ...*x509.Certificate) error {
+	method := ""
+	switch key.Public().(type) {
+	case *rsa.PublicKey:
+		method = rsaSHA256
+	case *ecdsa.PublicKey:
+		method = ecdsaSHA256
+	default:
+		return errors.New("Unsupported key type")
+	}
+
+	var refs []SignatureReference
+	for _, name := range e.fileNames() {
+		if name == mimetypeName || name == signaturesName {
+			continue
+		}
+		digest, err := e.digest(name, sha256Digest)
+		if err != nil {
+			return err
+		}
+		uri := (&url.URL{Path: name}).EscapedPath()
+		refs = append(refs, SignatureReference{uri, sha256Digest, digest})
+	}
+
+	signedInfo := writeSignedInfo(c14nAlgorithm, method, refs)
+	h := sha256.Sum256(signedInfo)
+	value, err := key.Sign(rand.Reader, h[:], crypto.SHA256)
+	if err != nil {
+		return err
+	}
+	if method == ecdsaSHA256 {
+		value, err = ecdsaRawSignature(value, key.Public().(*ecdsa.PublicKey))
+		if err != nil {
+			return err
+		}
+	}
+
+	var buff bytes.Buffer
+	buff.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	buff.WriteString(`<signatures xmlns="` + containerNSURI + `">`)
+	buff.WriteString(`<Signature Id="sig" xmlns="` + dsigNamespace + `">`)
+	buff.Write(signedInfo)
+	buff.WriteString(`<SignatureValue>` + base64.StdEncoding.EncodeToString(value) + `</SignatureValue>`)
+	if len(certs) != 0 {
+		buff.WriteString(`<KeyInfo><X509Data>`)
+		for _, cert := range certs {
+			buff.WriteString(`<X509Certificate>` + base64.StdEncoding.EncodeToString(cert.Raw) + `</X509Certificate>`)
+		}
+		buff.WriteString(`</X509Data></KeyInfo>`)
+	}
+	buff.WriteString(`</Signature></signatures>`)
+	e.stage(signaturesName, buff.Bytes())
+	return nil
+}
+
+func (e Epub) digest(name, method string) ([]byte, error) {
+	f, err := e.open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return digestReader(f, method)
+}
+
+func digestReader(r io.Reader, method string) ([]byte, error) {
+	var h hash.Hash
+	switch method {
+	case sha1Digest:
+		h = sha1.New()
+	case sha256Digest:
+		h = sha256.New()
+	case sha512Digest:
+		h = sha512.New()
+	default:
+		return nil, errors.New("Unsupported digest method " + method)
+	}
+	_, err := io.Copy(h, r)
+	return h.Sum(nil), err
+}
+
+// writeSignedInfo serializes the SignedInfo element in its canonical form
+func writeSignedInfo(canonicalization, method string, refs []SignatureReference) []byte {
+	var buff bytes.Buffer
+	buff.WriteString(`<SignedInfo xmlns="` + dsigNamespace + `">`)
+	buff.WriteString(`<CanonicalizationMethod Algorithm="` + escapeXML(canonicalization) + `"></CanonicalizationMethod>`)
+	buff.WriteString(`<SignatureMethod Algorithm="` + escapeXML(method) + `"></SignatureMethod>`)
+	for _, ref := range refs {
+		buff.WriteString(`<Reference URI="` + escapeXML(ref.URI) + `">`)
+		buff.WriteString(`<DigestMethod Algorithm="` + escapeXML(ref.DigestMethod) + `"></DigestMethod>`)
+		buff.WriteString(`<DigestValue>` + base64.StdEncoding.EncodeToString(ref.Digest) + `</DigestValue>`)
+		buff.WriteString(`</Reference>`)
+	}
+	buff.WriteString(`</SignedInfo>`)
+	return buff.Bytes()
+}
+
+// canonicalElement approximates the canonical form of a raw element of the
+// namespace space, declaring its namespace and expanding the empty elements
+func canonicalElement(raw []byte, space string) []byte {
+	canonical := selfClosingRegexp.ReplaceAll(raw, []byte("<$1$2></$1>"))
+	start := elementPrefixRegexp.FindSubmatch(canonical)
+	if start == nil || space == "" {
+		return canonical
+	}
+	nsAttr := "xmlns"
+	if len(start[1]) != 0 {
+		nsAttr += ":" + string(start[1])
+	}
+	if bytes.Contains(canonical[:bytes.IndexByte(canonical, '>')], []byte(nsAttr+"=")) {
+		return canonical
+	}
+	var buff bytes.Buffer
+	buff.Write(start[0])
+	buff.WriteString(" " + nsAttr + `="` + escapeXML(space) + `"`)
+	buff.Write(canonical[len(start[0]):])
+	return buff.Bytes()
+}
+
+func verifySignatureValue(method string, signedInfo, value []byte, key crypto.PublicKey) error {
+	var hashType crypto.Hash
+	var hashed []byte
+	switch method {
+	case rsaSHA1:
+		hashType = crypto.SHA1
+		h := sha1.Sum(signedInfo)
+		hashed = h[:]
+	case rsaSHA256, ecdsaSHA256:
+		hashType = crypto.SHA256
+		h := sha256.Sum256(signedInfo)
+		hashed = h[:]
+	default:
+		return errors.New("Unsupported signature method " + method)
+	}
+
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(pub, hashType, hashed, value)
+	case *ecdsa.PublicKey:
+		r := new(big.Int).SetBytes(value[:len(value)/2])
+		s := new(big.Int).SetBytes(value[len(value)/2:])
+		if !ecdsa.Verify(pub, hashed, r, s) {
+			return errors.New("Invalid signature")
+		}
+		return nil
+	}
+	return errors.New("Unsupported key type")
+}
+
+// ecdsaRawSignature converts an ASN.1 ECDSA signature into the r||s form
+// used by XML-DSig
+func ecdsaRawSignature(der []byte, pub *ecdsa.PublicKey) ([]byte, error) {
+	var sig struct{ R, S *big.Int }
+	if _, err := asn1.Unmarshal(der, &sig); err != nil {
+		return nil, err
+	}
+	size := (pub.Curve.Params().BitSize + 7) / 8
+	raw := make([]byte, 2*size)
+	sig.R.FillBytes(raw[:size])
+	sig.S.FillBytes(raw[size:])
+	return raw, nil
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.Join(strings.Fields(s), "")
+	return base64.StdEncoding.DecodeString(s)
+}
@@ -0,0 +1,225 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"io"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+)
+
+func signAndVerify(t *testing.T, key crypto.Signer) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	if err := f.Sign(key); err != nil {
+		t.Fatalf("Sign() return an error: %v", err)
+	}
+	book := repackBook(t, f)
+	signatures, err := book.Signatures()
+	if err != nil {
+		t.Fatalf("Signatures() return an error: %v", err)
+	}
+	if len(signatures) != 1 {
+		t.Fatalf("Signatures() return %v signatures, expected 1", len(signatures))
+	}
+	if err := book.VerifySignature(signatures[0], key.Public()); err != nil {
+		t.Errorf("VerifySignature() return an error: %v", err)
+	}
+	if err := book.VerifySignature(signatures[0], nil); err == nil {
+		t.Errorf("VerifySignature() didn't return an error without key")
+	}
+
+	tamper := func(name, mediaType string, r io.Reader) (io.Reader, error) {
+		if mediaType == "text/css" {
+			return strings.NewReader("body {}"), nil
+		}
+		return r, nil
+	}
+	tampered := repackBook(t, book, tamper)
+	if err := tampered.VerifySignature(signatures[0], key.Public()); err == nil {
+		t.Errorf("VerifySignature() didn't return an error on a tampered epub")
+	}
+}
+
+func TestSignRSA(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	signAndVerify(t, key)
+}
+
+func TestSignECDSA(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	signAndVerify(t, key)
+}
+
+// newCertificate returns a certificate of key signed by parent, or self
+// signed if parent is nil
+func newCertificate(t *testing.T, key crypto.Signer, parent *x509.Certificate, parentKey crypto.Signer) *x509.Certificate {
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: "epubgo test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	if err != nil {
+		t.Fatalf("CreateCertificate() return an error: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return cert
+}
+
+func TestVerifySignatureChain(t *testing.T) {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ca := newCertificate(t, caKey, nil, nil)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	forgerKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tests := []struct {
+		key   crypto.Signer
+		cert  *x509.Certificate
+		valid bool
+	}{
+		{key, newCertificate(t, key, ca, caKey), true},
+		{forgerKey, newCertificate(t, forgerKey, nil, nil), false},
+	}
+	for i, test := range tests {
+		f, _ := Open(bookPath)
+		defer f.Close()
+		if err := f.Sign(test.key, test.cert); err != nil {
+			t.Fatalf("Sign() return an error: %v", err)
+		}
+		book := repackBook(t, f)
+		signatures, err := book.Signatures()
+		if err != nil || len(signatures) != 1 || len(signatures[0].Certificates) != 1 {
+			t.Fatalf("Signatures() return: %v, %v", signatures, err)
+		}
+		cert, err := book.VerifySignatureChain(signatures[0], roots)
+		if test.valid && (err != nil || !cert.Equal(test.cert)) {
+			t.Errorf("VerifySignatureChain() of %d return: %v, %v", i, cert, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("VerifySignatureChain() of %d didn't return an error", i)
+		}
+		if _, err := book.VerifySignatureChain(signatures[0], nil); err == nil {
+			t.Errorf("VerifySignatureChain() of %d didn't return an error without roots", i)
+		}
+	}
+}
+
+func TestSignatureWrapping(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	f, _ := Open(bookPath)
+	defer f.Close()
+	if err := f.Sign(key); err != nil {
+		t.Fatalf("Sign() return an error: %v", err)
+	}
+	book := repackBook(t, f)
+	data, _ := book.readFile(signaturesName)
+	genuine := regexp.MustCompile(`(?s)<SignedInfo.*</SignedInfo>`).Find(data)
+
+	// the SignedInfo of the attacker covers a tampered stylesheet
+	css := ""
+	for _, item := range book.opf.Manifest {
+		if item.MediaType == "text/css" {
+			css = book.rootPath + item.Href
+		}
+	}
+	tampered := []byte("body {}")
+	digest := sha256.Sum256(tampered)
+	attack := writeSignedInfo(c14nAlgorithm, ecdsaSHA256, []SignatureReference{{css, sha256Digest, digest[:]}})
+	for _, forged := range []string{
+		"<!--" + string(genuine) + "-->" + string(attack),
+		string(genuine) + string(attack),
+		string(attack) + "<Object><!--" + string(genuine) + "--></Object>",
+	} {
+		book.stage(signaturesName, []byte(strings.Replace(string(data), string(genuine), forged, 1)))
+		book.stage(css, tampered)
+		forgedBook := repackBook(t, book)
+		signatures, err := forgedBook.Signatures()
+		if err == nil {
+			t.Errorf("Signatures() didn't return an error on: %s", forged)
+			if err := forgedBook.VerifySignature(signatures[0], key.Public()); err == nil {
+				t.Errorf("VerifySignature() verified the tampered epub")
+			}
+		}
+	}
+}
+
+func TestSignatureSameDocumentReference(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	object := `<Object Id="props"><SignatureProperties><SignatureProperty Target="#sig"/></SignatureProperties></Object>`
+	digest := sha256.Sum256(canonicalElement([]byte(object), dsigNamespace))
+	signedInfo := writeSignedInfo(c14nAlgorithm, rsaSHA256, []SignatureReference{{"#props", sha256Digest, digest[:]}})
+	hashed := sha256.Sum256(signedInfo)
+	value, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	signatures := func(object string) []byte {
+		return []byte(`<signatures xmlns="` + containerNSURI + `"><Signature Id="sig" xmlns="` + dsigNamespace + `">` +
+			string(signedInfo) + `<SignatureValue>` + base64.StdEncoding.EncodeToString(value) + `</SignatureValue>` +
+			object + `</Signature></signatures>`)
+	}
+
+	tests := []struct {
+		object string
+		valid  bool
+	}{
+		{object, true},
+		{strings.Replace(object, "#sig", "#other", 1), false},
+		{strings.Replace(object, "props", "other", 1), false},
+	}
+	for _, test := range tests {
+		f.stage(signaturesName, signatures(test.object))
+		book := repackBook(t, f)
+		sigs, err := book.Signatures()
+		if err != nil || len(sigs) != 1 {
+			t.Fatalf("Signatures() return: %v, %v", sigs, err)
+		}
+		err = book.VerifySignature(sigs[0], key.Public())
+		if test.valid && err != nil {
+			t.Errorf("VerifySignature() of %s return an error: %v", test.object, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("VerifySignature() of %s didn't return an error", test.object)
+		}
+	}
+
+	f.stage(signaturesName, signatures(object+object))
+	if _, err := repackBook(t, f).Signatures(); err == nil {
+		t.Errorf("Signatures() with a duplicated Id didn't return an error")
+	}
+}
+
+func TestNoSignatures(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	if _, err := f.Signatures(); err == nil {
+		t.Errorf("Signatures() didn't return an error on an unsigned epub")
+	}
+}
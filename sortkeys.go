@@ -0,0 +1,149 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"strings"
+)
+
+// leading articles moved to the end of the titles by SortTitle
+var articles = map[string][]string{
+	"en": {"the", "a", "an"},
+	"fr": {"le", "la", "les", "l'", "un", "une"},
+	"es": {"el", "la", "los", "las", "un", "una"},
+	"it": {"il", "lo", "la", "i", "gli", "le", "l'", "un", "una"},
+	"pt": {"o", "a", "os", "as", "um", "uma"},
+	"de": {"der", "die", "das", "ein", "eine"},
+	"nl": {"de", "het", "een"},
+}
+
+// surname particles kept with the last name by SortName
+var particles = map[string]bool{
+	"da": true, "das": true, "de": true, "del": true, "della": true,
+	"den": true, "der": true, "di": true, "do": true, "dos": true,
+	"du": true, "la": true, "le": true, "st.": true, "ten": true,
+	"ter": true, "van": true, "von": true, "y": true,
+}
+
+var nameSuffixes = map[string]bool{
+	"jr": true, "jr.": true, "sr": true, "sr.": true,
+	"ii": true, "iii": true, "iv": true,
+}
+
+// languages that write the family name first
+var familyFirstLangs = map[string]bool{
+	"zh": true, "ja": true, "ko": true, "hu": true, "vi": true,
+}
+
+// SortTitle computes the sorting key of a title moving the leading article
+// of the language to the end ("The Name of the Wind" -> "Name of the Wind, The")
+//
+// lang is a language tag like "en" or "fr-CA", English is used if empty.
+func SortTitle(title, lang string) string {
+	title = strings.TrimSpace(title)
+	lower := strings.ToLower(title)
+	for _, article := range articles[baseLang(lang)] {
+		if strings.HasSuffix(article, "'") {
+			if strings.HasPrefix(lower, article) && len(title) > len(article) {
+				return strings.TrimSpace(title[len(article):]) + ", " + title[:len(article)]
+			}
+			continue
+		}
+		if strings.HasPrefix(lower, article+" ") {
+			return strings.TrimSpace(title[len(article)+1:]) + ", " + title[:len(article)]
+		}
+	}
+	return title
+}
+
+// SortName computes the sorting key of a personal name putting the last name
+// first ("Ursula K. Le Guin" -> "Le Guin, Ursula K.")
+//
+// Names already containing a comma and names in languages that write the
+// family name first (Chinese, Japanese, Korean, Hungarian, ...) are returned
+// unchanged.
+func SortName(name, lang string) string {
+	name = strings.Join(strings.Fields(name), " ")
+	if strings.Contains(name, ",") || familyFirstLangs[baseLang(lang)] {
+		return name
+	}
+
+	words := strings.Fields(name)
+	suffix := ""
+	if len(words) > 2 && nameSuffixes[strings.ToLower(words[len(words)-1])] {
+		suffix = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+	if len(words) < 2 {
+		return name
+	}
+
+	last := len(words) - 1
+	for last > 1 && particles[strings.ToLower(words[last-1])] {
+		last--
+	}
+	sortName := strings.Join(words[last:], " ") + ", " + strings.Join(words[:last], " ")
+	if suffix != "" {
+		sortName += ", " + suffix
+	}
+	return sortName
+}
+
+// TitleSort returns the sorting key of the book title
+//
+// It is the file-as of the main title if present, if not it is computed
+// with SortTitle.
+func (e Epub) TitleSort() string {
+	titles := e.Titles()
+	if len(titles) == 0 {
+		return ""
+	}
+	title := titles[0]
+	for _, t := range titles {
+		if t.Type == "main" {
+			title = t
+			break
+		}
+	}
+	if title.FileAs != "" {
+		return title.FileAs
+	}
+	return SortTitle(title.Content, e.language())
+}
+
+// AuthorSort returns the sorting key of the authors joined by " & "
+//
+// The file-as of each author is used if present, if not it is computed with
+// SortName.
+func (e Epub) AuthorSort() string {
+	var names []string
+	for _, author := range e.Contributors(RoleAuthor) {
+		if author.FileAs != "" {
+			names = append(names, author.FileAs)
+		} else {
+			names = append(names, SortName(author.Name, e.language()))
+		}
+	}
+	return strings.Join(names, " & ")
+}
+
+// language returns the first language of the book
+func (e Epub) language() string {
+	if langs := e.metadata["language"]; len(langs) > 0 {
+		return langs[0].Content
+	}
+	return ""
+}
+
+func baseLang(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "-_"); i != -1 {
+		lang = lang[:i]
+	}
+	if lang == "" {
+		return "en"
+	}
+	return lang
+}
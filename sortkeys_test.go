@@ -0,0 +1,57 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+func TestSortTitle(t *testing.T) {
+	tests := []struct{ title, lang, expected string }{
+		{"The Name of the Wind", "en", "Name of the Wind, The"},
+		{"A Dog's Tale", "", "Dog's Tale, A"},
+		{"Theory of Everything", "en-US", "Theory of Everything"},
+		{"L'Étranger", "fr", "Étranger, L'"},
+		{"Les Misérables", "fr", "Misérables, Les"},
+		{"The Road", "es", "The Road"},
+	}
+	for _, test := range tests {
+		if result := SortTitle(test.title, test.lang); result != test.expected {
+			t.Errorf("SortTitle(%v, %v) return: %v when was expected: %v", test.title, test.lang, result, test.expected)
+		}
+	}
+}
+
+func TestSortName(t *testing.T) {
+	tests := []struct{ name, lang, expected string }{
+		{"Ursula K. Le Guin", "en", "Le Guin, Ursula K."},
+		{"Mark Twain", "", "Twain, Mark"},
+		{"Ludwig van Beethoven", "de", "van Beethoven, Ludwig"},
+		{"Martin Luther King Jr.", "en", "King, Martin Luther, Jr."},
+		{"Twain, Mark", "en", "Twain, Mark"},
+		{"Homer", "en", "Homer"},
+		{"Murakami Haruki", "ja", "Murakami Haruki"},
+	}
+	for _, test := range tests {
+		if result := SortName(test.name, test.lang); result != test.expected {
+			t.Errorf("SortName(%v, %v) return: %v when was expected: %v", test.name, test.lang, result, test.expected)
+		}
+	}
+}
+
+func TestSortKeys(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	if title := f.TitleSort(); title != "Dog's Tale, A" {
+		t.Errorf("TitleSort() return: %v", title)
+	}
+	if author := f.AuthorSort(); author != creatorFileAs {
+		t.Errorf("AuthorSort() return: %v when was expected: %v", author, creatorFileAs)
+	}
+
+	book := buildEpub(t, epub3OPF, nil)
+	if title := book.TitleSort(); title != "Lord of the Rings, The" {
+		t.Errorf("TitleSort() return: %v", title)
+	}
+}
@@ -11,7 +11,8 @@ import (
 
 // SpineIterator is an iterator on the epub pages spine
 //
-// With it is possible to navigate throw the pages of the epub.
+// With it is possible to navigate throw the pages of the epub. The items of
+// the spine that are not on the manifest are skipped.
 type SpineIterator struct {
 	opf   *xmlOPF
 	index int
@@ -19,24 +20,25 @@ type SpineIterator struct {
 }
 
 func newSpineIterator(epub *Epub) (*SpineIterator, error) {
-	if epub.opf.spineLength() == 0 {
+	index := epub.opf.nextSpineItem(-1, 1)
+	if index == -1 {
 		return nil, errors.New("Spine is empty")
 	}
 	var spine SpineIterator
 	spine.epub = epub
 	spine.opf = epub.opf
-	spine.index = 0
+	spine.index = index
 	return &spine, nil
 }
 
 // IsFirst returns whether the element is the first of the book
 func (spine SpineIterator) IsFirst() bool {
-	return spine.index == 0
+	return spine.opf.nextSpineItem(spine.index, -1) == -1
 }
 
 // IsLast returns whether the element is the last of the book
 func (spine SpineIterator) IsLast() bool {
-	return spine.index == spine.opf.spineLength()-1
+	return spine.opf.nextSpineItem(spine.index, 1) == -1
 }
 
 // Next advances the iterator to the next element on the spine
@@ -46,7 +48,7 @@ func (spine *SpineIterator) Next() error {
 	if spine.IsLast() {
 		return errors.New("It is the last entry")
 	}
-	spine.index++
+	spine.index = spine.opf.nextSpineItem(spine.index, 1)
 	return nil
 }
 
@@ -57,7 +59,7 @@ func (spine *SpineIterator) Previous() error {
 	if spine.IsFirst() {
 		return errors.New("It is the first entry")
 	}
-	spine.index--
+	spine.index = spine.opf.nextSpineItem(spine.index, -1)
 	return nil
 }
 
@@ -71,3 +73,9 @@ func (spine SpineIterator) Open() (io.ReadCloser, error) {
 func (spine SpineIterator) URL() string {
 	return spine.opf.spineURL(spine.index)
 }
+
+// Index returns the position of the item of the iterator on the spine, as
+// used by Epub.Text and the other methods that take a spine index
+func (spine SpineIterator) Index() int {
+	return spine.index
+}
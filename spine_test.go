@@ -125,3 +125,47 @@ func TestSpineOpen(t *testing.T) {
 		return
 	}
 }
+
+func TestSpineMissingItem(t *testing.T) {
+	f := buildEpub(t, "testdata/epub3.opf", map[string]string{
+		"nav.xhtml":      `<html><body><nav epub:type="toc"><ol><li><a href="text/ch1.xhtml">1</a></li></ol></nav></body></html>`,
+		"text/ch1.xhtml": `<html><body><p>One</p></body></html>`,
+		"text/ch2.xhtml": `<html><body><p>Two</p></body></html>`,
+	})
+	defer f.Close()
+	items := f.opf.Spine.Items
+	f.opf.Spine.Items = []spineItem{{IDref: "missing"}, items[0], {IDref: "missing"}, items[1], {IDref: "missing"}}
+
+	it, err := f.Spine()
+	if err != nil {
+		t.Fatalf("Spine() return an error: %v", err)
+	}
+	var urls []string
+	for {
+		if href := f.opf.spineURL(it.Index()); href != it.URL() {
+			t.Errorf("Index() %v points to %v instead of %v", it.Index(), href, it.URL())
+		}
+		urls = append(urls, it.URL())
+		if it.IsLast() {
+			break
+		}
+		it.Next()
+	}
+	if len(urls) != 2 || urls[0] != "text/ch1.xhtml" || urls[1] != "text/ch2.xhtml" || !it.IsLast() {
+		t.Errorf("The iterator return: %v", urls)
+	}
+	if it.Previous(); !it.IsFirst() || it.Previous() == nil {
+		t.Errorf("The iterator didn't stop on the first document: %v", it.URL())
+	}
+
+	if _, err := f.Text(0); err == nil {
+		t.Errorf("Text() of a missing item didn't return an error")
+	}
+	stats, err := f.ChapterStats()
+	if err != nil || len(stats) != 2 || stats[1].SpineIndex != 3 {
+		t.Errorf("ChapterStats() return: %v, %v", stats, err)
+	}
+	if err := f.RemoveSpineItem(0); err != nil || f.opf.spineLength() != 4 {
+		t.Errorf("RemoveSpineItem() of a missing item return: %v", err)
+	}
+}
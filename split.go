@@ -0,0 +1,319 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"bytes"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SplitOptions configures how SplitChapters splits the content documents
+type SplitOptions struct {
+	// MaxSize is the size in bytes over which a document is split, the
+	// parts bigger than it are split also between paragraphs. With 0 all
+	// the documents are split on their headings.
+	MaxSize int64
+	// Headings are the elements where the documents are split, h1 and h2
+	// if empty
+	Headings []string
+}
+
+// splitBlocks are the elements where a document can be split when a part
+// is bigger than the MaxSize
+var splitBlocks = map[string]bool{
+	"p": true, "div": true, "section": true, "blockquote": true, "ul": true,
+	"ol": true, "table": true, "pre": true, "figure": true, "hr": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+// voidElements are the HTML elements without end tag
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"source": true, "track": true, "wbr": true,
+}
+
+var (
+	bodyStartRegexp = regexp.MustCompile(`<(?:[\w-]+:)?body\b[^>]*>`)
+	htmlTagRegexp   = regexp.MustCompile(`<(/?)((?:[\w-]+:)?[\w-]+)\b[^>]*?(/?)>`)
+	idAttrRegexp    = regexp.MustCompile(`\sid\s*=\s*("[^"]*"|'[^']*')`)
+	contentRegexp   = regexp.MustCompile(`(?i)<(?:img|svg|image|object|video|audio)\b`)
+)
+
+// SplitChapters splits the oversized content documents of the spine
+//
+// The documents are split on the headings, and the parts still bigger than
+// MaxSize between paragraphs. The elements open at a split point are closed
+// at the end of the part and open again at the beginning of the next one.
+// The first part keeps the name of the document and the next ones are added
+// after it on the manifest and the spine. The links to the elements moved to
+// other parts are fixed on the content documents, the NCX, the navigation
+// document and the guide. The changes are written with Repack.
+func (e *Epub) SplitChapters(opts SplitOptions) error {
+	headings := make(map[string]bool)
+	for _, h := range opts.Headings {
+		headings[strings.ToLower(h)] = true
+	}
+	if len(headings) == 0 {
+		headings["h1"] = true
+		headings["h2"] = true
+	}
+
+	for i := 0; i < e.opf.spineLength(); i++ {
+		href := e.opf.spineURL(i)
+		if href == "" {
+			continue
+		}
+		name := e.rootPath + href
+		mediaType := e.opf.mediaType(href)
+		if mediaType != "application/xhtml+xml" && mediaType != "text/html" {
+			continue
+		}
+		if opts.MaxSize > 0 && e.size(name) <= opts.MaxSize {
+			continue
+		}
+
+		data, err := e.readFile(name)
+		if err != nil {
+			return err
+		}
+		parts := splitDocument(data, headings, opts.MaxSize)
+		if len(parts) < 2 {
+			continue
+		}
+
+		item := e.opf.Spine.Items[i]
+		ext := path.Ext(href)
+		fragments := make(map[string]string)
+		e.stage(name, parts[0])
+		for j, part := range parts[1:] {
+			partHref := e.opf.uniqueHref(strings.TrimSuffix(href, ext) + "-" + strconv.Itoa(j+1) + ext)
+			id := e.opf.uniqueID(item.IDref + "-" + strconv.Itoa(j+1))
+			e.opf.Manifest = append(e.opf.Manifest, manifest{ID: id, Href: partHref, MediaType: mediaType})
+			e.opf.insertSpine(spineItem{IDref: id, Linear: item.Linear}, i+j+1)
+			e.stage(e.rootPath+partHref, part)
+			for _, id := range elementIDs(part) {
+				fragments[id] = e.rootPath + partHref
+			}
+		}
+		if err := e.moveFragments(name, fragments); err != nil {
+			return err
+		}
+		i += len(parts) - 1
+	}
+	return nil
+}
+
+// maxNestingDepth is the maximum depth of the elements of the documents
+// processed by SplitChapters and Segments, deeper documents are not
+// supported to not consume quadratic time or memory
+const maxNestingDepth = 512
+
+type splitPoint struct {
+	pos     int
+	open    []string
+	heading bool
+}
+
+// splitDocument splits the body of the XHTML data on the headings and on
+// the blocks if the parts are bigger than maxSize
+func splitDocument(data []byte, headings map[string]bool, maxSize int64) [][]byte {
+	startLoc := bodyStartRegexp.FindIndex(data)
+	endLocs := bodyEndRegexp.FindAllIndex(data, -1)
+	if startLoc == nil || endLocs == nil {
+		return nil
+	}
+	bodyStart := startLoc[1]
+	bodyEnd := endLocs[len(endLocs)-1][0]
+	if bodyEnd < bodyStart {
+		return nil
+	}
+
+	var candidates []splitPoint
+	var open []string
+	for _, loc := range htmlTagRegexp.FindAllSubmatchIndex(data[bodyStart:bodyEnd], -1) {
+		pos := loc[0] + bodyStart
+		tag := string(data[pos : loc[1]+bodyStart])
+		closing := loc[3] > loc[2]
+		selfClosing := loc[7] > loc[6]
+		qname := strings.ToLower(string(data[loc[4]+bodyStart : loc[5]+bodyStart]))
+		name := qname[strings.Index(qname, ":")+1:]
+
+		switch {
+		case closing:
+			for i := len(open) - 1; i >= 0; i-- {
+				if tagName(open[i]) == qname {
+					open = open[:i]
+					break
+				}
+			}
+		default:
+			if headings[name] || splitBlocks[name] {
+				candidates = append(candidates, splitPoint{
+					pos:     pos,
+					open:    append([]string(nil), open...),
+					heading: headings[name],
+				})
+			}
+			if !selfClosing && !voidElements[name] {
+				open = append(open, tag)
+				if len(open) > maxNestingDepth {
+					return nil
+				}
+			}
+		}
+	}
+
+	splits := []splitPoint{{pos: bodyStart}}
+	var prev *splitPoint
+	for i := range candidates {
+		c := candidates[i]
+		last := splits[len(splits)-1]
+		if c.heading && hasContent(data[last.pos:c.pos]) {
+			splits = append(splits, c)
+			prev = nil
+			continue
+		}
+		if maxSize > 0 && int64(c.pos-last.pos) > maxSize {
+			if prev != nil && prev.pos > last.pos && hasContent(data[last.pos:prev.pos]) {
+				splits = append(splits, *prev)
+			} else if hasContent(data[last.pos:c.pos]) {
+				splits = append(splits, c)
+				prev = nil
+				continue
+			}
+		}
+		prev = &candidates[i]
+	}
+	last := splits[len(splits)-1]
+	if maxSize > 0 && int64(bodyEnd-last.pos) > maxSize && prev != nil && prev.pos > last.pos && hasContent(data[last.pos:prev.pos]) {
+		splits = append(splits, *prev)
+	}
+	if len(splits) < 2 {
+		return nil
+	}
+
+	parts := make([][]byte, len(splits))
+	for i, split := range splits {
+		var buff bytes.Buffer
+		buff.Write(data[:bodyStart])
+		for _, tag := range split.open {
+			buff.WriteString(idAttrRegexp.ReplaceAllString(tag, ""))
+		}
+		if i+1 < len(splits) {
+			next := splits[i+1]
+			buff.Write(data[split.pos:next.pos])
+			for j := len(next.open) - 1; j >= 0; j-- {
+				buff.WriteString("</" + tagName(next.open[j]) + ">")
+			}
+			buff.WriteString("\n")
+		} else {
+			buff.Write(data[split.pos:bodyEnd])
+		}
+		buff.Write(data[bodyEnd:])
+		parts[i] = buff.Bytes()
+	}
+	return parts
+}
+
+// tagName returns the name of the element of a start tag
+func tagName(tag string) string {
+	name := strings.TrimLeft(tag, "<")
+	if i := strings.IndexAny(name, " \t\r\n/>"); i != -1 {
+		name = name[:i]
+	}
+	return strings.ToLower(name)
+}
+
+// hasContent returns whether the markup has any text or image
+func hasContent(markup []byte) bool {
+	if contentRegexp.Match(markup) {
+		return true
+	}
+	return len(bytes.TrimSpace(htmlTagRegexp.ReplaceAll(markup, nil))) > 0
+}
+
+// elementIDs returns the ids of the elements of the body
+func elementIDs(data []byte) []string {
+	if loc := bodyStartRegexp.FindIndex(data); loc != nil {
+		data = data[loc[1]:]
+	}
+	var ids []string
+	for _, sub := range idAttrRegexp.FindAllSubmatch(data, -1) {
+		ids = append(ids, string(sub[1][1:len(sub[1])-1]))
+	}
+	return ids
+}
+
+// moveFragments fixes the links to the elements of the document name that
+// were moved to other files, fragments maps the ids of the moved elements
+// to their new files
+func (e *Epub) moveFragments(name string, fragments map[string]string) error {
+	parts := map[string]bool{name: true}
+	for _, part := range fragments {
+		parts[part] = true
+	}
+	fix := func(docPath, ref string) string {
+		i := strings.Index(ref, "#")
+		if i == -1 {
+			return ref
+		}
+		frag := ref[i+1:]
+		target := docPath
+		if i > 0 {
+			target = resolveRef(docPath, ref)
+		}
+		if target != name && !(target == docPath && parts[docPath]) {
+			return ref
+		}
+
+		newTarget, ok := fragments[frag]
+		if !ok {
+			newTarget = name
+		}
+		switch newTarget {
+		case target:
+			return ref
+		case docPath:
+			return "#" + frag
+		}
+		return relativeRef(docPath, newTarget) + "#" + frag
+	}
+
+	ncxPath := ""
+	if e.ncx != nil {
+		ncxPath = e.rootPath + e.opf.ncxPath()
+		fixNavPoints(e.ncx.NavMap, func(ref string) string { return fix(ncxPath, ref) })
+	}
+	for i, ref := range e.opf.Guide {
+		e.opf.Guide[i].Href = fix(e.opfPath, ref.Href)
+	}
+
+	for _, doc := range e.fileNames() {
+		mediaType := e.opf.mediaType(strings.TrimPrefix(doc, e.rootPath))
+		if doc == ncxPath || doc == e.opfPath || !isMarkup(mediaType) {
+			continue
+		}
+		data, err := e.readFile(doc)
+		if err != nil {
+			return err
+		}
+		fixed := eachRef(data, func(ref string) string { return fix(doc, ref) })
+		if !bytes.Equal(fixed, data) {
+			e.stage(doc, fixed)
+		}
+	}
+	return nil
+}
+
+func fixNavPoints(points []navpoint, fix func(ref string) string) {
+	for i := range points {
+		points[i].Content.Src = fix(points[i].Content.Src)
+		fixNavPoints(points[i].NavPoint, fix)
+	}
+}
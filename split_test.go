@@ -0,0 +1,71 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import "strings"
+
+const (
+	splitChapter = `<?xml version="1.0" encoding="UTF-8"?>
+<html xmlns="http://www.w3.org/1999/xhtml"><head><title>Ch 2</title></head>
+<body id="body"><section id="s"><h1 id="a">One</h1><p>Text one</p>
+<h1 id="b">Two</h1><p>Text two <a href="#a">back</a></p></section></body></html>`
+	splitNav = `<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops"><body>
+<nav epub:type="toc"><ol><li><a href="text/ch2.xhtml#b">Two</a></li></ol></nav></body></html>`
+)
+
+func TestSplitChapters(t *testing.T) {
+	f := buildEpub(t, epub3OPF, map[string]string{
+		"nav.xhtml":      splitNav,
+		"text/ch1.xhtml": `<html><body><p><a href="ch2.xhtml#b">Two</a></p></body></html>`,
+		"text/ch2.xhtml": splitChapter,
+	})
+	if err := f.SplitChapters(SplitOptions{}); err != nil {
+		t.Fatalf("SplitChapters() return an error: %v", err)
+	}
+
+	book := repackBook(t, f)
+	spine := []string{"text/ch1.xhtml", "text/ch2.xhtml", "text/ch2-1.xhtml"}
+	if book.opf.spineLength() != len(spine) {
+		t.Fatalf("The spine has %v items", book.opf.spineLength())
+	}
+	for i, href := range spine {
+		if url := book.opf.spineURL(i); url != href {
+			t.Errorf("Spine item %v is %v when was expected %v", i, url, href)
+		}
+	}
+
+	first := readBookFile(t, book, "text/ch2.xhtml")
+	if !strings.Contains(first, `<p>Text one</p>`+"\n</section>\n</body>") || strings.Contains(first, "Two") {
+		t.Errorf("Wrong first part: %v", first)
+	}
+	second := readBookFile(t, book, "text/ch2-1.xhtml")
+	if !strings.Contains(second, `<body id="body"><section><h1 id="b">Two</h1>`) {
+		t.Errorf("Wrong second part: %v", second)
+	}
+	if !strings.Contains(second, `<a href="ch2.xhtml#a">`) {
+		t.Errorf("The link to the first part was not fixed: %v", second)
+	}
+	if ch1 := readBookFile(t, book, "text/ch1.xhtml"); !strings.Contains(ch1, `href="ch2-1.xhtml#b"`) {
+		t.Errorf("The link from ch1 was not fixed: %v", ch1)
+	}
+	if nav := readBookFile(t, book, "nav.xhtml"); !strings.Contains(nav, `href="text/ch2-1.xhtml#b"`) {
+		t.Errorf("The nav was not fixed: %v", nav)
+	}
+}
+
+func TestSplitDocumentSize(t *testing.T) {
+	doc := "<html><body><div>" + strings.Repeat("<p>Some text of the chapter.</p>", 10) + "</div></body></html>"
+	parts := splitDocument([]byte(doc), nil, 100)
+	if len(parts) != 4 {
+		t.Fatalf("splitDocument() return %v parts", len(parts))
+	}
+	for _, part := range parts {
+		if strings.Count(string(part), "<div>") != 1 || strings.Count(string(part), "</div>") != 1 {
+			t.Errorf("Unbalanced part: %s", part)
+		}
+	}
+}
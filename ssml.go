@@ -0,0 +1,104 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+)
+
+const (
+	defaultHeadingBreak = time.Second
+	ssmlNamespace       = "http://www.w3.org/2001/10/synthesis"
+)
+
+// defaultSSMLSkip are the epub:type of the elements not read by default
+var defaultSSMLSkip = []string{"footnote", "endnote", "rearnote", "noteref", "pagebreak"}
+
+// SSMLOptions configures ToSSML
+type SSMLOptions struct {
+	// Lang is the language of the text, by default the language of the book
+	Lang string
+	// HeadingBreak is the pause after the headings, one second by default
+	HeadingBreak time.Duration
+	// ParagraphBreak is the pause after the paragraphs, none by default
+	ParagraphBreak time.Duration
+	// SkipTypes are the epub:type of the elements that are not read, by
+	// default footnotes and page numbers
+	SkipTypes []string
+	// Ruby is how the ruby annotations are read, RubyReading reads the
+	// readings instead of the base texts. By default both are read.
+	Ruby RubyMode
+}
+
+// ToSSML returns the document at spineIndex as SSML for a text to speech
+// engine
+//
+// Each paragraph is split in sentences and the parts of the text in other
+// language than the document are tagged with their language.
+func (e Epub) ToSSML(spineIndex int, opts SSMLOptions) (string, error) {
+	if spineIndex < 0 || spineIndex >= e.opf.spineLength() {
+		return "", errors.New("Spine index out of range")
+	}
+	data, err := e.spineDocument(spineIndex)
+	if err != nil {
+		return "", err
+	}
+
+	if opts.Lang == "" {
+		opts.Lang = e.language()
+	}
+	if opts.HeadingBreak == 0 {
+		opts.HeadingBreak = defaultHeadingBreak
+	}
+	if opts.SkipTypes == nil {
+		opts.SkipTypes = defaultSSMLSkip
+	}
+	skip := make(map[string]bool)
+	for _, t := range opts.SkipTypes {
+		skip[t] = true
+	}
+
+	paragraphs, err := paragraphs(flattenRuby(data, opts.Ruby), opts.Lang, skip)
+	if err != nil {
+		return "", err
+	}
+
+	var buff strings.Builder
+	buff.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	buff.WriteString(`<speak version="1.1" xmlns="` + ssmlNamespace + `"`)
+	if opts.Lang != "" {
+		buff.WriteString(` xml:lang="` + html.EscapeString(opts.Lang) + `"`)
+	}
+	buff.WriteString(">\n")
+	for _, p := range paragraphs {
+		buff.WriteString("<p")
+		if p.lang != "" && p.lang != opts.Lang {
+			buff.WriteString(` xml:lang="` + html.EscapeString(p.lang) + `"`)
+		}
+		buff.WriteString(">")
+		for _, s := range p.Sentences {
+			buff.WriteString("<s>" + escapeHTMLChars(s.Text) + "</s>")
+		}
+		buff.WriteString("</p>\n")
+
+		pause := opts.ParagraphBreak
+		if isHeading(p.name) {
+			pause = opts.HeadingBreak
+		}
+		if pause > 0 {
+			fmt.Fprintf(&buff, "<break time=\"%dms\"/>\n", pause.Milliseconds())
+		}
+	}
+	buff.WriteString("</speak>\n")
+	return buff.String(), nil
+}
+
+func isHeading(name string) bool {
+	return len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6'
+}
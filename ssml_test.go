@@ -0,0 +1,56 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"encoding/xml"
+	"strings"
+	"time"
+)
+
+func TestToSSML(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	page := `<?xml version="1.0" encoding="utf-8"?>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<body>
+<h1>Chapter I</h1>
+<p>It was late. The <em>train</em> &amp; the bus had gone.<a epub:type="noteref" href="#n1">1</a></p>
+<span epub:type="pagebreak" id="p2">2</span>
+<p xml:lang="fr">Bonjour tout le monde.</p>
+<aside epub:type="footnote" id="n1"><p>A note.</p></aside>
+</body>
+</html>`
+	f.stage(f.rootPath+f.opf.spineURL(1), []byte(page))
+
+	ssml, err := f.ToSSML(1, SSMLOptions{})
+	if err != nil {
+		t.Fatalf("ToSSML() return an error: %v", err)
+	}
+	if err := xml.Unmarshal([]byte(ssml), new(struct{})); err != nil {
+		t.Errorf("ToSSML() return invalid xml: %v", err)
+	}
+	for _, s := range []string{
+		`xml:lang="en"`,
+		"<p><s>Chapter I</s></p>\n<break time=\"1000ms\"/>",
+		"<s>It was late.</s><s>The train &amp; the bus had gone.</s></p>",
+		`<p xml:lang="fr"><s>Bonjour tout le monde.</s></p>`,
+	} {
+		if !strings.Contains(ssml, s) {
+			t.Errorf("ToSSML() doesn't contain %s: %s", s, ssml)
+		}
+	}
+	if strings.Contains(ssml, "note") || strings.Contains(ssml, "<s>2</s>") {
+		t.Errorf("ToSSML() didn't skip the notes: %s", ssml)
+	}
+
+	ssml, _ = f.ToSSML(1, SSMLOptions{SkipTypes: []string{}, ParagraphBreak: 300 * time.Millisecond})
+	if !strings.Contains(ssml, "A note.") || !strings.Contains(ssml, `<break time="300ms"/>`) {
+		t.Errorf("ToSSML() didn't use the options: %s", ssml)
+	}
+}
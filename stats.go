@@ -0,0 +1,58 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// wordsPerMinute is the reading speed used to estimate the reading time
+const wordsPerMinute = 250
+
+var imageTagRegexp = regexp.MustCompile(`(?i)<(?:[\w-]+:)?(?:img|image)\b`)
+
+// ChapterStats are the statistics of a document of the spine
+type ChapterStats struct {
+	SpineIndex int
+	Href       string
+	// Size is the size in bytes of the document
+	Size int64
+	// TextLength is the number of characters of the text, as returned by Text
+	TextLength int
+	Words      int
+	Images     int
+	// ReadingTime is estimated at 250 words per minute
+	ReadingTime time.Duration
+}
+
+// ChapterStats returns the statistics of each document of the spine
+func (e Epub) ChapterStats() ([]ChapterStats, error) {
+	var stats []ChapterStats
+	for i := 0; i < e.opf.spineLength(); i++ {
+		href := e.opf.spineURL(i)
+		if href == "" {
+			continue
+		}
+		data, err := e.readFile(e.rootPath + href)
+		if err != nil {
+			return nil, err
+		}
+		text := extractText(data)
+		words := len(strings.Fields(text))
+		stats = append(stats, ChapterStats{
+			SpineIndex:  i,
+			Href:        href,
+			Size:        int64(len(data)),
+			TextLength:  utf8.RuneCountInString(text),
+			Words:       words,
+			Images:      len(imageTagRegexp.FindAllIndex(data, -1)),
+			ReadingTime: time.Duration(words) * time.Minute / wordsPerMinute,
+		})
+	}
+	return stats, nil
+}
@@ -0,0 +1,37 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"strings"
+	"time"
+)
+
+func TestChapterStats(t *testing.T) {
+	f := buildEpub(t, "testdata/epub3.opf", map[string]string{
+		"text/ch1.xhtml": `<html><body><p>Ñandú and friends</p><img src="a.png"/><svg><image href="b.png"/></svg></body></html>`,
+		"text/ch2.xhtml": `<html><body><p>` + strings.Repeat("word ", 500) + `</p></body></html>`,
+	})
+	defer f.Close()
+
+	stats, err := f.ChapterStats()
+	if err != nil {
+		t.Fatalf("ChapterStats() return an error: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("ChapterStats() return: %v", stats)
+	}
+	if stats[0].Href != "text/ch1.xhtml" || stats[0].TextLength != 17 || stats[0].Words != 3 || stats[0].Images != 2 {
+		t.Errorf("ChapterStats()[0] return: %+v", stats[0])
+	}
+	if stats[1].SpineIndex != 1 || stats[1].Words != 500 || stats[1].ReadingTime != 2*time.Minute {
+		t.Errorf("ChapterStats()[1] return: %+v", stats[1])
+	}
+	if stats[1].Size == 0 {
+		t.Errorf("ChapterStats()[1] has no size")
+	}
+}
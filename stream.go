@@ -0,0 +1,71 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+const defaultStreamMemory = 32 << 20
+
+// StreamOptions configures LoadStream
+type StreamOptions struct {
+	// MaxMemory is the biggest epub kept in memory, 32 MB by default. The
+	// bigger ones are spooled to a temporary file.
+	MaxMemory int64
+	// TempDir is the directory of the temporary files, the default of the
+	// system if empty
+	TempDir string
+}
+
+// LoadStream loads an epub from a stream that can't seek, like the body of an
+// HTTP response
+//
+// The zip format needs random access, so the stream is read until its end.
+// The epubs not bigger than MaxMemory are kept in memory and the rest are
+// spooled to a temporary file that is removed by Close.
+func LoadStream(r io.Reader, opts StreamOptions) (*Epub, error) {
+	if opts.MaxMemory <= 0 {
+		opts.MaxMemory = defaultStreamMemory
+	}
+
+	var buff bytes.Buffer
+	n, err := io.CopyN(&buff, r, opts.MaxMemory+1)
+	if err == io.EOF || (err == nil && n <= opts.MaxMemory) {
+		return Load(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := ioutil.TempFile(opts.TempDir, "epubgo-*.epub")
+	if err != nil {
+		return nil, err
+	}
+	e, err := loadSpool(tmp, io.MultiReader(&buff, r))
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	return e, nil
+}
+
+func loadSpool(tmp *os.File, r io.Reader) (*Epub, error) {
+	size, err := io.Copy(tmp, r)
+	if err != nil {
+		return nil, err
+	}
+	e := new(Epub)
+	e.file = tmp
+	e.tmpPath = tmp.Name()
+	if err := e.load(tmp, size); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
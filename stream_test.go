@@ -0,0 +1,47 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+)
+
+func TestLoadStream(t *testing.T) {
+	data, _ := ioutil.ReadFile(bookPath)
+	dir := t.TempDir()
+
+	for _, maxMemory := range []int64{0, 1024} {
+		f, err := LoadStream(bytes.NewBuffer(data), StreamOptions{MaxMemory: maxMemory, TempDir: dir})
+		if err != nil {
+			t.Fatalf("LoadStream(%v) return an error: %v", maxMemory, err)
+		}
+		if title, _ := f.Metadata("title"); title[0] != "A Dog's Tale" {
+			t.Errorf("LoadStream(%v) title: %v", maxMemory, title)
+		}
+		spooled, _ := ioutil.ReadDir(dir)
+		if (maxMemory != 0) != (len(spooled) == 1) {
+			t.Errorf("LoadStream(%v) spooled %v files", maxMemory, len(spooled))
+		}
+		f.Close()
+		if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+			t.Errorf("Close() didn't remove the spooled file")
+		}
+	}
+}
+
+func TestLoadStreamError(t *testing.T) {
+	dir := t.TempDir()
+	_, err := LoadStream(strings.NewReader(strings.Repeat("no epub", 1000)), StreamOptions{MaxMemory: 10, TempDir: dir})
+	if err == nil {
+		t.Errorf("LoadStream() didn't return an error")
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Errorf("LoadStream() left the spooled file after an error")
+	}
+}
@@ -0,0 +1,258 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"bytes"
+	"errors"
+	"path"
+	"sort"
+	"strings"
+)
+
+// InsertDocument adds the XHTML document data on href (relative to the OPF)
+// to the manifest and to the spine at index, a negative index adds it at the
+// end
+//
+// If title is not empty the document is added also to the NCX and the EPUB 3
+// navigation document, next to the entries of the documents around it on the
+// spine. The changes are written with Repack.
+func (e *Epub) InsertDocument(href, title string, data []byte, index int) error {
+	if e.opf.fileID(href) != "" || e.inZip(e.rootPath+href) {
+		return errors.New("File " + href + " already exists")
+	}
+	id := e.opf.uniqueID(strings.TrimSuffix(path.Base(href), path.Ext(href)))
+	e.opf.Manifest = append(e.opf.Manifest, manifest{
+		ID:        id,
+		Href:      href,
+		MediaType: "application/xhtml+xml",
+	})
+	e.stage(e.rootPath+href, data)
+	e.opf.insertSpine(spineItem{IDref: id}, index)
+	if title != "" {
+		return e.addNavEntry(title, href)
+	}
+	return nil
+}
+
+// MoveSpineItem moves the document at the position from of the spine to the
+// position to
+//
+// The top level entries of the NCX and of the EPUB 3 navigation document are
+// sorted to follow the new order of the spine, the entries pointing outside
+// of the spine stay after the entry before them. The playOrder of the NCX is
+// renumbered when it is written with Repack.
+func (e *Epub) MoveSpineItem(from, to int) error {
+	items := e.opf.Spine.Items
+	if from < 0 || from >= len(items) || to < 0 || to >= len(items) {
+		return errors.New("Spine index out of range")
+	}
+	item := items[from]
+	items = append(items[:from], items[from+1:]...)
+	items = append(items[:to], append([]spineItem{item}, items[to:]...)...)
+	e.opf.Spine.Items = items
+	return e.sortNavigation()
+}
+
+// RemoveSpineItem removes the document at index of the spine from the epub
+//
+// Its entries on the NCX are removed, their children take their place, and so
+// are its top level entries of the EPUB 3 navigation document. The links to the document are redirected
+// to the next document of the spine, or to the previous one if it was the
+// last. The items of the spine that are not on the manifest are just removed
+// from the spine. The changes are written with Repack.
+func (e *Epub) RemoveSpineItem(index int) error {
+	length := e.opf.spineLength()
+	if index < 0 || index >= length {
+		return errors.New("Spine index out of range")
+	}
+	if e.opf.spineURL(index) == "" {
+		e.opf.Spine.Items = append(e.opf.Spine.Items[:index], e.opf.Spine.Items[index+1:]...)
+		return nil
+	}
+	target := e.opf.nextSpineItem(index, 1)
+	if target == -1 {
+		target = e.opf.nextSpineItem(index, -1)
+	}
+	if target == -1 {
+		return errors.New("Can't remove the only document of the spine")
+	}
+	name := e.rootPath + e.opf.spineURL(index)
+	targetName := e.rootPath + e.opf.spineURL(target)
+
+	if e.ncx != nil {
+		ncxPath := e.rootPath + e.opf.ncxPath()
+		e.ncx.NavMap = removeNavPoints(e.ncx.NavMap, ncxPath, name)
+	}
+	if navPath := e.navDocPath(); navPath != "" {
+		navPath = e.rootPath + navPath
+		data, err := e.readFile(navPath)
+		if err != nil {
+			return err
+		}
+		if items, _, ok := tocNavItems(data, navPath); ok {
+			var buff bytes.Buffer
+			last := 0
+			for i, item := range items {
+				if item.target != name {
+					continue
+				}
+				buff.Write(data[last:item.start])
+				last = item.end
+				if i+1 < len(items) {
+					last = items[i+1].start
+				}
+			}
+			buff.Write(data[last:])
+			if !bytes.Equal(buff.Bytes(), data) {
+				e.stage(navPath, buff.Bytes())
+			}
+		}
+	}
+
+	e.opf.Spine.Items = append(e.opf.Spine.Items[:index], e.opf.Spine.Items[index+1:]...)
+	return e.dropDocuments(map[string]bool{name: true}, targetName)
+}
+
+// removeNavPoints removes the points pointing to name, their children take
+// their place
+func removeNavPoints(points []navpoint, ncxPath, name string) []navpoint {
+	var result []navpoint
+	for _, point := range points {
+		children := removeNavPoints(point.NavPoint, ncxPath, name)
+		if resolveRef(ncxPath, point.URL()) == name {
+			result = append(result, children...)
+			continue
+		}
+		point.NavPoint = children
+		result = append(result, point)
+	}
+	return result
+}
+
+// sortNavigation sorts the top level entries of the NCX and the navigation
+// document by the position on the spine of their targets
+func (e *Epub) sortNavigation() error {
+	if e.ncx != nil {
+		ncxPath := e.rootPath + e.opf.ncxPath()
+		targets := make([]string, len(e.ncx.NavMap))
+		for i, point := range e.ncx.NavMap {
+			targets[i] = resolveRef(ncxPath, point.URL())
+		}
+		order := e.spineOrder(targets)
+		points := make([]navpoint, len(order))
+		for i, j := range order {
+			points[i] = e.ncx.NavMap[j]
+		}
+		e.ncx.NavMap = points
+	}
+
+	navPath := e.navDocPath()
+	if navPath == "" {
+		return nil
+	}
+	navPath = e.rootPath + navPath
+	data, err := e.readFile(navPath)
+	if err != nil {
+		return err
+	}
+	items, _, ok := tocNavItems(data, navPath)
+	if !ok || len(items) == 0 {
+		return nil
+	}
+	targets := make([]string, len(items))
+	for i, item := range items {
+		targets[i] = item.target
+	}
+	var buff bytes.Buffer
+	buff.Write(data[:items[0].start])
+	for i, j := range e.spineOrder(targets) {
+		buff.Write(data[items[j].start:items[j].end])
+		if i+1 < len(items) {
+			buff.Write(data[items[i].end:items[i+1].start])
+		}
+	}
+	buff.Write(data[items[len(items)-1].end:])
+	if !bytes.Equal(buff.Bytes(), data) {
+		e.stage(navPath, buff.Bytes())
+	}
+	return nil
+}
+
+// spineOrder returns the indexes of the targets (paths inside the zip)
+// sorted by their position on the spine, the targets that are not on the
+// spine keep the position of the target before them
+func (e Epub) spineOrder(targets []string) []int {
+	keys := make([]int, len(targets))
+	key := -1
+	for i, target := range targets {
+		if index := e.opf.spineIndex(strings.TrimPrefix(target, e.rootPath)); index != -1 && strings.HasPrefix(target, e.rootPath) {
+			key = index
+		}
+		keys[i] = key
+	}
+	order := make([]int, len(targets))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return keys[order[i]] < keys[order[j]] })
+	return order
+}
+
+// navItem is a top level entry of the toc nav of the navigation document
+type navItem struct {
+	// start and end are the position of the li element on the document
+	start, end int
+	// target is the path inside the zip of its first link
+	target string
+}
+
+// tocNavItems returns the top level entries of the toc nav and the position
+// where its list ends, or false if the toc nav is not found
+func tocNavItems(data []byte, navPath string) ([]navItem, int, bool) {
+	loc := tocNavRegexp.FindIndex(data)
+	if loc == nil {
+		return nil, 0, false
+	}
+
+	var items []navItem
+	depth := 0
+	tags := navListTagRegexp.FindAllSubmatchIndex(data[loc[1]:], -1)
+	for i, tag := range tags {
+		start, end := tag[0]+loc[1], tag[1]+loc[1]
+		closing := data[start+1] == '/'
+		switch string(data[tag[2]+loc[1] : tag[3]+loc[1]]) {
+		case "ol":
+			if closing {
+				depth--
+			} else {
+				depth++
+			}
+		case "li":
+			if depth != 1 {
+				break
+			}
+			if closing {
+				if len(items) > 0 {
+					items[len(items)-1].end = end
+				}
+				break
+			}
+			stop := len(data)
+			if i+1 < len(tags) {
+				stop = tags[i+1][0] + loc[1]
+			}
+			target := ""
+			if refs := references(data[start:stop], navPath); len(refs) > 0 {
+				target = refs[0]
+			}
+			items = append(items, navItem{start, stop, target})
+		}
+		if depth == 0 {
+			return items, start, true
+		}
+	}
+	return nil, 0, false
+}
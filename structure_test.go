@@ -0,0 +1,113 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import "strings"
+
+func structureEpub(t *testing.T) *Epub {
+	return buildEpub(t, epub3OPF, map[string]string{
+		"nav.xhtml":      epub3Nav,
+		"text/ch1.xhtml": `<html><body><a href="ch2.xhtml">next</a></body></html>`,
+		"text/ch2.xhtml": `<html><body><a href="ch1.xhtml#s1">back</a></body></html>`,
+	})
+}
+
+func TestInsertDocument(t *testing.T) {
+	f := structureEpub(t)
+	if err := f.InsertDocument("text/foreword.xhtml", "Foreword", []byte("<html/>"), 0); err != nil {
+		t.Fatalf("InsertDocument() return an error: %v", err)
+	}
+	if err := f.InsertDocument("text/ch1.xhtml", "", []byte("<html/>"), 0); err == nil {
+		t.Errorf("InsertDocument() of an existing file didn't return an error")
+	}
+
+	book := repackBook(t, f)
+	if url := book.opf.spineURL(0); url != "text/foreword.xhtml" {
+		t.Errorf("The first spine item is: %v", url)
+	}
+	nav := readBookFile(t, book, "nav.xhtml")
+	foreword := strings.Index(nav, `<li><a href="text/foreword.xhtml">Foreword</a></li>`)
+	if foreword == -1 || foreword > strings.Index(nav, "Chapter 1") {
+		t.Errorf("The document is not correctly placed on the nav: %v", nav)
+	}
+}
+
+func TestMoveSpineItem(t *testing.T) {
+	f := structureEpub(t)
+	if err := f.MoveSpineItem(0, 2); err == nil {
+		t.Errorf("MoveSpineItem() out of range didn't return an error")
+	}
+	if err := f.MoveSpineItem(0, 1); err != nil {
+		t.Fatalf("MoveSpineItem() return an error: %v", err)
+	}
+
+	book := repackBook(t, f)
+	if book.opf.spineURL(0) != "text/ch2.xhtml" || book.opf.spineURL(1) != "text/ch1.xhtml" {
+		t.Errorf("The spine is: %v", book.opf.Spine.Items)
+	}
+	nav := readBookFile(t, book, "nav.xhtml")
+	expected := `<ol>
+<li><a href="text/ch2.xhtml">Chapter 2</a></li>
+<li><a href="text/ch1.xhtml">Chapter 1</a><ol><li><a href="text/ch1.xhtml#s1">Section</a></li></ol></li>
+</ol>`
+	if !strings.Contains(nav, expected) {
+		t.Errorf("The nav was not reordered: %v", nav)
+	}
+}
+
+func TestMoveSpineItemNCX(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+	last := f.opf.spineLength() - 1
+	first := f.opf.spineURL(0)
+	if err := f.MoveSpineItem(0, last); err != nil {
+		t.Fatalf("MoveSpineItem() return an error: %v", err)
+	}
+
+	book := repackBook(t, f)
+	if book.opf.spineURL(last) != first {
+		t.Errorf("The last spine item is: %v", book.opf.spineURL(last))
+	}
+	points := book.ncx.NavMap
+	ncxPath := book.rootPath + book.opf.ncxPath()
+	previous := -1
+	for _, point := range points {
+		index := book.opf.spineIndex(strings.TrimPrefix(resolveRef(ncxPath, point.URL()), book.rootPath))
+		if index < previous {
+			t.Errorf("The NCX is not sorted by the spine: %v", point.URL())
+		}
+		previous = index
+	}
+}
+
+func TestRemoveSpineItem(t *testing.T) {
+	f := structureEpub(t)
+	if err := f.RemoveSpineItem(2); err == nil {
+		t.Errorf("RemoveSpineItem() out of range didn't return an error")
+	}
+	if err := f.RemoveSpineItem(0); err != nil {
+		t.Fatalf("RemoveSpineItem() return an error: %v", err)
+	}
+	if err := f.RemoveSpineItem(0); err == nil {
+		t.Errorf("RemoveSpineItem() of the only document didn't return an error")
+	}
+
+	book := repackBook(t, f)
+	if book.opf.spineLength() != 1 || book.opf.spineURL(0) != "text/ch2.xhtml" {
+		t.Errorf("The spine is: %v", book.opf.Spine.Items)
+	}
+	if _, err := book.OpenFile("text/ch1.xhtml"); err == nil {
+		t.Errorf("The document was not removed")
+	}
+	nav := readBookFile(t, book, "nav.xhtml")
+	if strings.Contains(nav, "Chapter 1") || !strings.Contains(nav, "Chapter 2") {
+		t.Errorf("The nav entry was not removed: %v", nav)
+	}
+	if ch2 := readBookFile(t, book, "text/ch2.xhtml"); !strings.Contains(ch2, `href="ch2.xhtml"`) {
+		t.Errorf("The link to the removed document was not redirected: %v", ch2)
+	}
+}
@@ -0,0 +1,412 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"bytes"
+	"errors"
+	"html"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const hoistedClassPrefix = "inline-"
+
+var (
+	styleAttrRegexp = regexp.MustCompile(`\sstyle\s*=\s*("[^"]*"|'[^']*')`)
+	classAttrRegexp = regexp.MustCompile(`\sclass\s*=\s*("[^"]*"|'[^']*')`)
+	headEndRegexp   = regexp.MustCompile(`</([\w-]+:)?head>`)
+)
+
+// cssDeclaration is a property of a CSS rule or style attribute
+type cssDeclaration struct {
+	property, value string
+	important       bool
+}
+
+func (d cssDeclaration) String() string {
+	s := d.property + ": " + d.value
+	if d.important {
+		s += " !important"
+	}
+	return s
+}
+
+// HoistInlineStyles moves the style attributes repeated on at least
+// minCount elements of the content documents to classes of a new stylesheet
+// on href (relative to the OPF)
+//
+// The style attributes are compared after normalizing their declarations.
+// The elements get the class (inline-1, inline-2, ...) instead of the style
+// attribute and the documents link the new stylesheet after the ones they
+// already use. The declarations are marked !important on the stylesheet, so
+// they still override the rules of the other stylesheets as the style
+// attributes did. The changes are written with Repack.
+func (e *Epub) HoistInlineStyles(href string, minCount int) error {
+	href = path.Clean(href)
+	if e.opf.fileID(href) != "" || e.inZip(e.rootPath+href) {
+		return errors.New("File " + href + " already exists")
+	}
+	cssName := e.rootPath + href
+	usage, err := e.cssUsage()
+	if err != nil {
+		return err
+	}
+
+	var docs []string
+	counts := make(map[string]int)
+	var styles []string
+	for _, item := range e.opf.Manifest {
+		if item.MediaType != "application/xhtml+xml" && item.MediaType != "text/html" {
+			continue
+		}
+		name := e.rootPath + item.Href
+		data, err := e.readFile(name)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, name)
+		for _, sub := range styleAttrRegexp.FindAllSubmatch(data, -1) {
+			style := hoistedStyle(sub[1], name, cssName)
+			if style == "" {
+				continue
+			}
+			if counts[style] == 0 {
+				styles = append(styles, style)
+			}
+			counts[style]++
+		}
+	}
+
+	classes := make(map[string]string)
+	var sheet bytes.Buffer
+	n := 0
+	for _, style := range styles {
+		if counts[style] < minCount {
+			continue
+		}
+		class := ""
+		for class == "" || usage.byClass[class] != nil {
+			n++
+			class = hoistedClassPrefix + strconv.Itoa(n)
+		}
+		classes[style] = class
+		sheet.WriteString("." + class + " { ")
+		for i, decl := range parseDeclarations(style) {
+			if i > 0 {
+				sheet.WriteString("; ")
+			}
+			decl.important = true
+			sheet.WriteString(decl.String())
+		}
+		sheet.WriteString(" }\n")
+	}
+	if len(classes) == 0 {
+		return nil
+	}
+
+	for _, name := range docs {
+		data, err := e.readFile(name)
+		if err != nil {
+			return err
+		}
+		head := headEndRegexp.FindIndex(data)
+		if head == nil {
+			continue
+		}
+		var edits []cssEdit
+		for _, loc := range htmlTagRegexp.FindAllIndex(data, -1) {
+			tag := string(data[loc[0]:loc[1]])
+			sub := styleAttrRegexp.FindStringSubmatch(tag)
+			if sub == nil {
+				continue
+			}
+			class, ok := classes[hoistedStyle([]byte(sub[1]), name, cssName)]
+			if !ok {
+				continue
+			}
+			edits = append(edits, cssEdit{loc[0], loc[1], addClass(tag, class)})
+		}
+		if len(edits) == 0 {
+			continue
+		}
+		indent := data[bytes.LastIndexByte(data[:head[0]], '\n')+1 : head[0]]
+		if len(bytes.TrimSpace(indent)) != 0 {
+			indent = nil
+		}
+		link := `<link rel="stylesheet" type="text/css" href="` + escapeXML(relativeRef(name, cssName)) + `"/>`
+		edits = append(edits, cssEdit{head[0], head[0], link + "\n" + string(indent)})
+		sort.SliceStable(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+		e.stage(name, applyCSSEdits(data, edits))
+	}
+	_, err = e.AddResource(href, "text/css", &sheet)
+	return err
+}
+
+// hoistedStyle returns the normalized declarations of the style attribute
+// value of the document docPath, with the urls relative to cssPath
+func hoistedStyle(value []byte, docPath, cssPath string) string {
+	style := html.UnescapeString(string(value[1 : len(value)-1]))
+	var decls []string
+	for _, decl := range parseDeclarations(style) {
+		decl.value = string(eachRef([]byte(decl.value), func(ref string) string {
+			return moveRef(docPath, cssPath, ref, nil)
+		}))
+		decls = append(decls, decl.String())
+	}
+	return strings.Join(decls, "; ")
+}
+
+// addClass returns the start tag with class instead of its style attribute
+func addClass(tag, class string) string {
+	if loc := classAttrRegexp.FindStringSubmatchIndex(tag); loc != nil {
+		tag = tag[:loc[3]-1] + " " + class + tag[loc[3]-1:]
+		return styleAttrRegexp.ReplaceAllLiteralString(tag, "")
+	}
+	return styleAttrRegexp.ReplaceAllLiteralString(tag, ` class="`+class+`"`)
+}
+
+// cssInlineRule is a selector of a style rule of the stylesheets
+type cssInlineRule struct {
+	sheet        string
+	parts        []cssCompound
+	specificity  [3]int
+	declarations []cssDeclaration
+	// approximate rules can't be inlined, the element may not match them
+	// (pseudo-classes and pseudo-elements) or only on some media
+	approximate bool
+}
+
+// InlineCSS returns a transform that copies the rules of the stylesheets
+// linked by the content documents to the style attributes of their
+// elements, for the reading systems that ignore the stylesheets
+//
+// The declarations of the rules matching an element are merged on its style
+// attribute following the cascade: sorted by specificity and position, with
+// the previous style attribute at the end, and only the winning declaration
+// of each property is kept. The urls are made relative to the document. The
+// rules that depend on the state or the position of the elements
+// (pseudo-classes and pseudo-elements), on media queries or on namespaces
+// can't be inlined, they stay on the stylesheets, that are still linked. As
+// the style attributes win over the stylesheets, the properties (or their
+// shorthands and longhands) that those rules could set on an element are
+// not inlined on it: with "a { color: black } a:hover { color: red }" the
+// color of the links stays on the stylesheet. The elements of the head are
+// not modified.
+func (e Epub) InlineCSS() (Transform, error) {
+	sheets := make(map[string][]cssInlineRule)
+	for _, item := range e.opf.Manifest {
+		if item.MediaType != "text/css" {
+			continue
+		}
+		name := e.rootPath + item.Href
+		data, err := e.readFile(name)
+		if err != nil {
+			return nil, err
+		}
+		rules, _ := parseCSS(data, 0, false)
+		sheets[name] = inlineRules(data, name, rules, false)
+	}
+
+	return func(name, mediaType string, r io.Reader) (io.Reader, error) {
+		if mediaType != "application/xhtml+xml" && mediaType != "text/html" {
+			return r, nil
+		}
+		data, err := readAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(inlineStyles(data, name, sheets)), nil
+	}, nil
+}
+
+// inlineRules returns the selectors of the style rules, the ones of group
+// rules like @media are approximate
+func inlineRules(data []byte, sheet string, rules []cssRule, approximate bool) []cssInlineRule {
+	var inline []cssInlineRule
+	for _, rule := range rules {
+		if rule.children != nil {
+			inline = append(inline, inlineRules(data, sheet, rule.children, true)...)
+			continue
+		}
+		if rule.selectors == nil {
+			continue
+		}
+		block := data[rule.preludeEnd+1 : rule.end]
+		block = bytes.TrimSuffix(block, []byte("}"))
+		decls := parseDeclarations(string(block))
+		for _, selector := range rule.selectors {
+			parts, ok := parseSelector(selector)
+			if !ok {
+				continue
+			}
+			var specificity [3]int
+			approximate := approximate
+			for _, part := range parts {
+				if part.approximate {
+					approximate = true
+				}
+				if part.id != "" {
+					specificity[0]++
+				}
+				specificity[1] += len(part.classes) + len(part.attrs)
+				if part.name != "" {
+					specificity[2]++
+				}
+			}
+			inline = append(inline, cssInlineRule{sheet, parts, specificity, decls, approximate})
+		}
+	}
+	return inline
+}
+
+// inlineStyles adds the declarations of the rules of the stylesheets linked
+// by the document docPath to the style attributes of its elements
+func inlineStyles(data []byte, docPath string, sheets map[string][]cssInlineRule) []byte {
+	var rules []cssInlineRule
+	for _, loc := range htmlTagRegexp.FindAllSubmatchIndex(data, -1) {
+		name := strings.ToLower(string(data[loc[4]:loc[5]]))
+		if loc[3] > loc[2] || name[strings.Index(name, ":")+1:] != "link" {
+			continue
+		}
+		tag := string(data[loc[0]:loc[1]])
+		rel := strings.Fields(strings.ToLower(attrValue(tag, "rel")))
+		if contains(rel, "stylesheet") && !contains(rel, "alternate") {
+			rules = append(rules, sheets[resolveRef(docPath, attrValue(tag, "href"))]...)
+		}
+	}
+	if len(rules) == 0 {
+		return data
+	}
+
+	var edits []cssEdit
+	for _, el := range cssElements(data) {
+		if inHead(el) {
+			continue
+		}
+		var matched []cssInlineRule
+		blocked := make(map[string]bool)
+		for _, rule := range rules {
+			if !matchSelector(rule.parts, len(rule.parts)-1, el) {
+				continue
+			}
+			if rule.approximate {
+				for _, decl := range rule.declarations {
+					blocked[decl.property] = true
+				}
+				continue
+			}
+			matched = append(matched, rule)
+		}
+		if len(matched) == 0 {
+			continue
+		}
+		sort.SliceStable(matched, func(i, j int) bool {
+			a, b := matched[i].specificity, matched[j].specificity
+			for k := range a {
+				if a[k] != b[k] {
+					return a[k] < b[k]
+				}
+			}
+			return false
+		})
+
+		var decls []cssDeclaration
+		for _, rule := range matched {
+			for _, decl := range rule.declarations {
+				if isBlockedProperty(blocked, decl.property) {
+					continue
+				}
+				sheet := rule.sheet
+				decl.value = string(eachRef([]byte(decl.value), func(ref string) string {
+					return moveRef(sheet, docPath, ref, nil)
+				}))
+				decls = append(decls, decl)
+			}
+		}
+		decls = append(decls, parseDeclarations(html.UnescapeString(el.attrs["style"]))...)
+		winners := make(map[string]int)
+		for i, decl := range decls {
+			if j, ok := winners[decl.property]; !ok || decl.important || !decls[j].important {
+				winners[decl.property] = i
+			}
+		}
+		var style []string
+		for i, decl := range decls {
+			if winners[decl.property] == i {
+				style = append(style, decl.String())
+			}
+		}
+		if len(style) == 0 {
+			continue
+		}
+
+		tag := string(data[el.start:el.end])
+		attr := ` style="` + escapeXML(strings.Join(style, "; ")) + `"`
+		if styleAttrRegexp.MatchString(tag) {
+			tag = styleAttrRegexp.ReplaceAllLiteralString(tag, attr)
+		} else {
+			end := len(tag) - 1
+			if strings.HasSuffix(tag, "/>") {
+				end--
+			}
+			tag = tag[:end] + attr + tag[end:]
+		}
+		edits = append(edits, cssEdit{el.start, el.end, tag})
+	}
+	return applyCSSEdits(data, edits)
+}
+
+// isBlockedProperty returns whether property, one of its shorthands or of
+// its longhands is on blocked
+func isBlockedProperty(blocked map[string]bool, property string) bool {
+	for p := range blocked {
+		if p == property || strings.HasPrefix(p, property+"-") || strings.HasPrefix(property, p+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+func inHead(el *cssElement) bool {
+	for ; el != nil; el = el.parent {
+		if el.name == "head" {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDeclarations returns the declarations of a CSS block or style
+// attribute
+func parseDeclarations(block string) []cssDeclaration {
+	data := []byte(cssCommentRegexp.ReplaceAllString(block, ""))
+	var decls []cssDeclaration
+	for pos := 0; pos < len(data); pos++ {
+		end := scanCSS(data, pos, ";")
+		decl := string(data[pos:end])
+		pos = end
+		i := strings.IndexByte(decl, ':')
+		if i == -1 {
+			continue
+		}
+		d := cssDeclaration{
+			property: strings.ToLower(strings.TrimSpace(decl[:i])),
+			value:    strings.TrimSpace(decl[i+1:]),
+		}
+		if j := strings.LastIndexByte(d.value, '!'); j != -1 && strings.ToLower(strings.TrimSpace(d.value[j+1:])) == "important" {
+			d.value = strings.TrimSpace(d.value[:j])
+			d.important = true
+		}
+		if d.property != "" && d.value != "" {
+			decls = append(decls, d)
+		}
+	}
+	return decls
+}
@@ -0,0 +1,86 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import "strings"
+
+func TestHoistInlineStyles(t *testing.T) {
+	f := buildEpub(t, epub3OPF, map[string]string{
+		"nav.xhtml": epub3Nav,
+		"text/ch1.xhtml": `<html><head>
+  <title>One</title>
+  </head><body><p style="text-indent:0;margin: 0">a</p><p class="x" style="text-indent: 0; margin: 0;">b</p><p style="color: red">c</p></body></html>`,
+		"text/ch2.xhtml": `<html><head></head><body><div style="background: url(../images/bg.png)"/><p style="background: url(../images/bg.png)">d</p></body></html>`,
+	})
+	if err := f.HoistInlineStyles("text/ch1.xhtml", 2); err == nil {
+		t.Errorf("HoistInlineStyles() over an existing file didn't return an error")
+	}
+	if err := f.HoistInlineStyles("styles/inline.css", 2); err != nil {
+		t.Fatalf("HoistInlineStyles() return an error: %v", err)
+	}
+
+	book := repackBook(t, f)
+	css := readBookFile(t, book, "styles/inline.css")
+	expected := `.inline-1 { text-indent: 0 !important; margin: 0 !important }
+.inline-2 { background: url(../images/bg.png) !important }
+`
+	if css != expected {
+		t.Errorf("The stylesheet is:\n%v", css)
+	}
+	ch1 := readBookFile(t, book, "text/ch1.xhtml")
+	expected = `<html><head>
+  <title>One</title>
+  <link rel="stylesheet" type="text/css" href="../styles/inline.css"/>
+  </head><body><p class="inline-1">a</p><p class="x inline-1">b</p><p style="color: red">c</p></body></html>`
+	if ch1 != expected {
+		t.Errorf("The document is:\n%v", ch1)
+	}
+	ch2 := readBookFile(t, book, "text/ch2.xhtml")
+	if !strings.Contains(ch2, `<div class="inline-2"/>`) || !strings.Contains(ch2, `href="../styles/inline.css"/>`) {
+		t.Errorf("The document is:\n%v", ch2)
+	}
+}
+
+func TestInlineCSS(t *testing.T) {
+	f := buildEpub(t, epub3OPF, map[string]string{
+		"nav.xhtml": epub3Nav,
+		"text/ch1.xhtml": `<html><head><link rel="stylesheet" href="../style.css"/></head>` +
+			`<body><p class="first" style="color: green">a</p><p>b</p><div class="box"/><a href="#">c</a></body></html>`,
+		"text/ch2.xhtml": `<html><body><p>c</p></body></html>`,
+	})
+	css := `p { color: black; margin: 1em }
+.first { color: red; margin-top: 0 }
+p { color: blue !important }
+p:first-child { font-weight: bold }
+.box { background: url(images/bg.png); border: 1px solid }
+@media print { p { color: gray } }
+@media (min-width: 40em) { .box { border-color: red } }
+a { color: black; font-size: 1em }
+a:hover { color: red }
+`
+	if _, err := f.AddResource("style.css", "text/css", strings.NewReader(css)); err != nil {
+		t.Fatalf("AddResource() return an error: %v", err)
+	}
+	inline, err := f.InlineCSS()
+	if err != nil {
+		t.Fatalf("InlineCSS() return an error: %v", err)
+	}
+
+	book := repackBook(t, f, inline)
+	ch1 := readBookFile(t, book, "text/ch1.xhtml")
+	expected := `<html><head><link rel="stylesheet" href="../style.css"/></head>` +
+		`<body><p class="first" style="margin: 1em; margin-top: 0; color: green">a</p>` +
+		`<p style="margin: 1em">b</p>` +
+		`<div class="box" style="background: url(../images/bg.png)"/>` +
+		`<a href="#" style="font-size: 1em">c</a></body></html>`
+	if ch1 != expected {
+		t.Errorf("The document is:\n%v", ch1)
+	}
+	if ch2 := readBookFile(t, book, "text/ch2.xhtml"); strings.Contains(ch2, "style") {
+		t.Errorf("A document without stylesheets was modified: %v", ch2)
+	}
+}
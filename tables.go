@@ -0,0 +1,158 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Table is a table of the content documents
+type Table struct {
+	// ID is the id attribute of the table element, if any
+	ID      string
+	Caption string
+	// Location is where the table starts on the text of its document
+	Location Location
+	// Rows are the text of the cells, the header rows included. A cell
+	// spanning several columns is followed by empty cells.
+	Rows [][]string
+}
+
+// WriteCSV writes the rows of the table as CSV
+func (t Table) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.WriteAll(t.Rows)
+	return cw.Error()
+}
+
+// Tables returns the tables of the documents of the spine in reading order
+//
+// The tables nested in a cell are returned after the table containing them
+// and their text is also part of the cell.
+func (e Epub) Tables() ([]Table, error) {
+	var tables []Table
+	for i := 0; i < e.opf.spineLength(); i++ {
+		href := e.opf.spineURL(i)
+		if href == "" {
+			continue
+		}
+		data, err := e.readFile(e.rootPath + href)
+		if err != nil {
+			return nil, err
+		}
+		for _, table := range findTables(data) {
+			table.Location.SpineIndex = i
+			table.Location.Href = href
+			tables = append(tables, table)
+		}
+	}
+	return tables, nil
+}
+
+type tableState struct {
+	table        Table
+	start        int
+	row          []string
+	inRow        bool
+	cellStart    int
+	colspan      int
+	captionStart int
+}
+
+func (s *tableState) endCell(data []byte, pos int) {
+	if s.cellStart == -1 {
+		return
+	}
+	s.row = append(s.row, cellText(data[s.cellStart:pos]))
+	for i := 1; i < s.colspan; i++ {
+		s.row = append(s.row, "")
+	}
+	s.cellStart = -1
+}
+
+func (s *tableState) endRow(data []byte, pos int) {
+	s.endCell(data, pos)
+	if s.inRow {
+		s.table.Rows = append(s.table.Rows, s.row)
+	}
+	s.row = nil
+	s.inRow = false
+}
+
+// findTables returns the tables of the XHTML data, with the offset of their
+// location on the text of the document
+func findTables(data []byte) []Table {
+	var (
+		tables []Table
+		starts []int
+		stack  []*tableState
+	)
+	for _, loc := range htmlTagRegexp.FindAllSubmatchIndex(data, -1) {
+		closing := loc[3] > loc[2]
+		name := strings.ToLower(string(data[loc[4]:loc[5]]))
+		name = name[strings.Index(name, ":")+1:]
+		tag := string(data[loc[0]:loc[1]])
+
+		if name == "table" && !closing {
+			state := &tableState{start: loc[0], cellStart: -1, captionStart: -1}
+			state.table.ID = attrValue(tag, "id")
+			state.table.Location.Offset = len(extractText(data[:loc[0]]))
+			stack = append(stack, state)
+			continue
+		}
+		if len(stack) == 0 {
+			continue
+		}
+		s := stack[len(stack)-1]
+		switch {
+		case name == "table":
+			s.endRow(data, loc[0])
+			stack = stack[:len(stack)-1]
+			tables = append(tables, s.table)
+			starts = append(starts, s.start)
+		case name == "caption" && !closing:
+			s.captionStart = loc[1]
+		case name == "caption" && s.captionStart != -1:
+			s.table.Caption = cellText(data[s.captionStart:loc[0]])
+			s.captionStart = -1
+		case name == "tr" && !closing:
+			s.endRow(data, loc[0])
+			s.inRow = true
+		case name == "tr":
+			s.endRow(data, loc[0])
+		case (name == "td" || name == "th") && !closing:
+			s.endCell(data, loc[0])
+			s.inRow = true
+			s.cellStart = loc[1]
+			s.colspan, _ = strconv.Atoi(attrValue(tag, "colspan"))
+		case name == "td" || name == "th":
+			s.endCell(data, loc[0])
+		}
+	}
+
+	// the nested tables are closed before the ones containing them
+	sort.Sort(byStart{tables, starts})
+	return tables
+}
+
+type byStart struct {
+	tables []Table
+	starts []int
+}
+
+func (b byStart) Len() int           { return len(b.tables) }
+func (b byStart) Less(i, j int) bool { return b.starts[i] < b.starts[j] }
+func (b byStart) Swap(i, j int) {
+	b.tables[i], b.tables[j] = b.tables[j], b.tables[i]
+	b.starts[i], b.starts[j] = b.starts[j], b.starts[i]
+}
+
+func cellText(markup []byte) string {
+	return strings.Join(strings.Fields(extractText(markup)), " ")
+}
@@ -0,0 +1,55 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import "strings"
+
+const tablesDoc = `<html><body><p>Prices</p>
+<table id="prices"><caption>Fruit  prices</caption>
+<thead><tr><th>Fruit</th><th>Price</th></tr></thead>
+<tbody><tr><td>Apple, red</td><td>1 €</td></tr>
+<tr><td colspan="2"><table><tr><td>nested</td></tr></table></td></tr></tbody>
+</table></body></html>`
+
+func TestTables(t *testing.T) {
+	f := buildEpub(t, "testdata/epub3.opf", map[string]string{
+		"text/ch1.xhtml": `<html><body><p>No tables</p></body></html>`,
+		"text/ch2.xhtml": tablesDoc,
+	})
+	defer f.Close()
+
+	tables, err := f.Tables()
+	if err != nil {
+		t.Fatalf("Tables() return an error: %v", err)
+	}
+	if len(tables) != 2 {
+		t.Fatalf("Tables() return: %v", tables)
+	}
+	table := tables[0]
+	if table.ID != "prices" || table.Caption != "Fruit prices" || table.Location.SpineIndex != 1 || table.Location.Href != "text/ch2.xhtml" {
+		t.Errorf("Wrong table: %+v", table)
+	}
+	text, _ := f.Text(1)
+	if !strings.HasPrefix(strings.TrimSpace(text[table.Location.Offset:]), "Fruit") {
+		t.Errorf("Wrong table offset: %v", table.Location.Offset)
+	}
+	if len(table.Rows) != 3 || table.Rows[1][0] != "Apple, red" || table.Rows[2][0] != "nested" || len(table.Rows[2]) != 2 {
+		t.Errorf("Wrong table rows: %q", table.Rows)
+	}
+	if len(tables[1].Rows) != 1 || tables[1].Rows[0][0] != "nested" {
+		t.Errorf("Wrong nested table rows: %q", tables[1].Rows)
+	}
+
+	var buff strings.Builder
+	if err := table.WriteCSV(&buff); err != nil {
+		t.Fatalf("WriteCSV() return an error: %v", err)
+	}
+	expected := "Fruit,Price\n\"Apple, red\",1 €\nnested,\n"
+	if buff.String() != expected {
+		t.Errorf("WriteCSV() wrote: %q when was expected: %q", buff.String(), expected)
+	}
+}
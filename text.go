@@ -0,0 +1,176 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"bytes"
+	"errors"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	markupRegexp     = regexp.MustCompile(`(?s)<!--.*?-->|<!\[CDATA\[.*?\]\]>|<[^>]*>`)
+	whitespaceRegexp = regexp.MustCompile(`\s+`)
+)
+
+// textSkip are the elements which text is not extracted
+var textSkip = map[string]bool{
+	"head": true, "script": true, "style": true,
+}
+
+// Location is a position on the text of the book
+type Location struct {
+	// SpineIndex is the position on the spine of the document
+	SpineIndex int
+	// Href is the path of the document, as used by OpenFile
+	Href string
+	// Offset is the position in bytes on the text of the document as
+	// returned by Text
+	Offset int
+}
+
+// Text returns the plain text of the document at spineIndex
+//
+// The entities are decoded and the whitespace collapsed, each block of text
+// (paragraph, heading, list item, ...) is on its own line.
+func (e Epub) Text(spineIndex int) (string, error) {
+	if spineIndex < 0 || spineIndex >= e.opf.spineLength() {
+		return "", errors.New("Spine index out of range")
+	}
+	data, err := e.spineDocument(spineIndex)
+	if err != nil {
+		return "", err
+	}
+	return extractText(data), nil
+}
+
+func extractText(data []byte) string {
+	text, _ := layoutText(data)
+	return text
+}
+
+// textSpan is a text node placed on the text of its document
+type textSpan struct {
+	textNode
+	// offset is the position of the node on the text of the document
+	offset int
+	// trimmed is whether the leading whitespace of the node was removed
+	trimmed bool
+}
+
+// layoutText returns the text of the document, as extractText, and the
+// position on it of its text nodes
+func layoutText(data []byte) (string, []textSpan) {
+	var buff strings.Builder
+	var spans []textSpan
+	// starts are the positions of the spans on buff
+	var starts []int
+	for _, node := range textNodes(data, textSkip) {
+		text := whitespaceRegexp.ReplaceAllString(html.UnescapeString(string(data[node.start:node.end])), " ")
+		atLineStart := buff.Len() == 0 || strings.HasSuffix(buff.String(), "\n")
+		if isBlockTag(node.tag) && !atLineStart {
+			buff.WriteString("\n")
+			atLineStart = true
+		}
+		if atLineStart {
+			text = strings.TrimLeft(text, " ")
+		}
+		spans = append(spans, textSpan{textNode: node, trimmed: atLineStart})
+		starts = append(starts, buff.Len())
+		buff.WriteString(text)
+	}
+
+	var paragraphs []string
+	length, pos, i := 0, 0, 0
+	for _, line := range strings.Split(buff.String(), "\n") {
+		trimmed := strings.TrimSpace(line)
+		lead := len(line) - len(strings.TrimLeftFunc(line, unicode.IsSpace))
+		start := length
+		if trimmed != "" && len(paragraphs) > 0 {
+			start++
+		}
+		for ; i < len(spans) && starts[i] <= pos+len(line); i++ {
+			offset := starts[i] - pos - lead
+			if offset < 0 {
+				offset = 0
+			}
+			if offset > len(trimmed) {
+				offset = len(trimmed)
+			}
+			spans[i].offset = start + offset
+		}
+		if trimmed != "" {
+			paragraphs = append(paragraphs, trimmed)
+			length = start + len(trimmed)
+		}
+		pos += len(line) + 1
+	}
+	return strings.Join(paragraphs, "\n"), spans
+}
+
+// textNode is the location of a text node on a document
+type textNode struct {
+	start, end int
+	// tag is the markup (tag, comment, ...) just before the text, empty at
+	// the beginning of the document
+	tag string
+}
+
+// textNodes returns the text nodes of the XHTML data that are not inside the
+// elements of skip
+func textNodes(data []byte, skip map[string]bool) []textNode {
+	var nodes []textNode
+	skipping := 0
+	last := 0
+	tag := ""
+	for _, loc := range markupRegexp.FindAllIndex(data, -1) {
+		if skipping == 0 && loc[0] > last {
+			nodes = append(nodes, textNode{last, loc[0], tag})
+		}
+		last = loc[1]
+
+		tag = string(data[loc[0]:loc[1]])
+		if !skip[tagName(strings.TrimPrefix(tag, "</"))] || strings.HasSuffix(tag, "/>") {
+			continue
+		}
+		if strings.HasPrefix(tag, "</") {
+			if skipping > 0 {
+				skipping--
+			}
+		} else {
+			skipping++
+		}
+	}
+	if skipping == 0 && len(data) > last {
+		nodes = append(nodes, textNode{last, len(data), tag})
+	}
+	return nodes
+}
+
+// eachText calls fn with every text node of the XHTML data and replaces the
+// text with its return value
+//
+// The text nodes inside the elements of skip are left untouched. The text is
+// passed as it is on the document, with its entities escaped.
+func eachText(data []byte, skip map[string]bool, fn func(text string) string) []byte {
+	return eachTextAfter(data, skip, func(tag, text string) string { return fn(text) })
+}
+
+// eachTextAfter is like eachText but fn receives also the markup (tag,
+// comment, ...) just before the text, empty at the beginning of the document
+func eachTextAfter(data []byte, skip map[string]bool, fn func(tag, text string) string) []byte {
+	var buff bytes.Buffer
+	last := 0
+	for _, node := range textNodes(data, skip) {
+		buff.Write(data[last:node.start])
+		buff.WriteString(fn(node.tag, string(data[node.start:node.end])))
+		last = node.end
+	}
+	buff.Write(data[last:])
+	return buff.Bytes()
+}
@@ -0,0 +1,181 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"regexp"
+	"strings"
+)
+
+const svgMediaType = "image/svg+xml"
+
+var (
+	svgImageRegexp = regexp.MustCompile(`<(?:[\w-]+:)?image\b[^>]*>`)
+	svgShapeRegexp = regexp.MustCompile(`<(?:[\w-]+:)?(?:path|rect|circle|ellipse|line|polyline|polygon|text|use)\b`)
+	svgTagRegexp   = regexp.MustCompile(`(?i)<(?:[\w-]+:)?svg\b`)
+	svgRefRegexp   = regexp.MustCompile(`(?i)\.svgz?$`)
+)
+
+// Rasterizer draws an SVG image of the given size
+//
+// The epub package doesn't include an SVG renderer, a Rasterizer can be
+// written on top of any library that provides one.
+type Rasterizer func(svg []byte, width, height int) (image.Image, error)
+
+// CoverThumbnail returns the cover image encoded as PNG and scaled to fit in
+// width x height, keeping its aspect ratio
+//
+// If width or height is 0 it is computed from the other one. An SVG cover
+// that only wraps a bitmap image, as most of them do, is drawn from that
+// image, any other one needs rasterize, that can be nil for bitmap covers.
+func (e Epub) CoverThumbnail(width, height int, rasterize Rasterizer) ([]byte, error) {
+	if width <= 0 && height <= 0 {
+		return nil, errors.New("Invalid thumbnail size")
+	}
+	href := e.coverHref()
+	if href == "" {
+		return nil, errors.New("The epub has no cover")
+	}
+	data, err := e.readFile(e.rootPath + href)
+	if err != nil {
+		return nil, err
+	}
+
+	var img image.Image
+	if e.opf.mediaType(href) == svgMediaType {
+		img, err = e.rasterizeSVG(href, data, width, height, rasterize)
+	} else {
+		img, _, err = image.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var buff bytes.Buffer
+	err = png.Encode(&buff, scaleImage(img, width, height))
+	return buff.Bytes(), err
+}
+
+// SVGDocuments returns the hrefs of the documents of the spine that use SVG,
+// because they are SVG documents, they have inline SVG or they include SVG
+// images
+func (e Epub) SVGDocuments() ([]string, error) {
+	var docs []string
+	for i := 0; i < e.opf.spineLength(); i++ {
+		href := e.opf.spineURL(i)
+		if href == "" {
+			continue
+		}
+		item := e.opf.manifestItem(e.opf.fileID(href))
+		if item != nil && (item.MediaType == svgMediaType || hasProperty(item.Properties, "svg")) {
+			docs = append(docs, href)
+			continue
+		}
+		data, err := e.readFile(e.rootPath + href)
+		if err != nil {
+			return nil, err
+		}
+		if usesSVG(data, e.rootPath+href) {
+			docs = append(docs, href)
+		}
+	}
+	return docs, nil
+}
+
+func usesSVG(data []byte, docPath string) bool {
+	if svgTagRegexp.Match(data) {
+		return true
+	}
+	for _, ref := range references(data, docPath) {
+		if svgRefRegexp.MatchString(ref) {
+			return true
+		}
+	}
+	return false
+}
+
+// rasterizeSVG draws the SVG href, using its bitmap image if it only wraps
+// one or rasterize if not
+func (e Epub) rasterizeSVG(href string, data []byte, width, height int, rasterize Rasterizer) (image.Image, error) {
+	images := svgImageRegexp.FindAll(data, -1)
+	if len(images) == 1 && !svgShapeRegexp.Match(data) {
+		tag := string(images[0])
+		ref := attrValue(tag, "xlink:href")
+		if ref == "" {
+			ref = attrValue(tag, "href")
+		}
+		if target := e.ResolveHref(href, ref); target != "" {
+			bitmap, err := e.readFile(e.rootPath + strings.SplitN(target, "#", 2)[0])
+			if err != nil {
+				return nil, err
+			}
+			img, _, err := image.Decode(bytes.NewReader(bitmap))
+			return img, err
+		}
+	}
+
+	if rasterize == nil {
+		return nil, errors.New("The SVG cover needs a Rasterizer")
+	}
+	return rasterize(data, width, height)
+}
+
+// scaleImage returns img scaled to fit in width x height, averaging the
+// pixels of the original image that fall on each pixel of the new one
+func scaleImage(img image.Image, width, height int) image.Image {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW == 0 || srcH == 0 {
+		return img
+	}
+	switch {
+	case width <= 0:
+		width = srcW * height / srcH
+	case height <= 0, srcW*height > srcH*width:
+		height = srcH * width / srcW
+	default:
+		width = srcW * height / srcH
+	}
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*srcH/height
+		y1 := bounds.Min.Y + (y+1)*srcH/height
+		if y1 == y0 {
+			y1++
+		}
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*srcW/width
+			x1 := bounds.Min.X + (x+1)*srcW/width
+			if x1 == x0 {
+				x1++
+			}
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := color.NRGBA64Model.Convert(img.At(sx, sy)).(color.NRGBA64)
+					r += uint64(c.R)
+					g += uint64(c.G)
+					b += uint64(c.B)
+					a += uint64(c.A)
+					n++
+				}
+			}
+			dst.Set(x, y, color.NRGBA64{uint16(r / n), uint16(g / n), uint16(b / n), uint16(a / n)})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,99 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+)
+
+const svgCover = `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" viewBox="0 0 600 800"><image width="600" height="800" xlink:href="cover.png"/></svg>`
+
+func pngImage(width, height int) string {
+	var buff bytes.Buffer
+	png.Encode(&buff, image.NewNRGBA(image.Rect(0, 0, width, height)))
+	return buff.String()
+}
+
+func decodeThumbnail(t *testing.T, data []byte) image.Rectangle {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("png.Decode() return an error: %v", err)
+	}
+	return img.Bounds()
+}
+
+func TestCoverThumbnail(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	thumb, err := f.CoverThumbnail(100, 100, nil)
+	if err != nil {
+		t.Fatalf("CoverThumbnail() return an error: %v", err)
+	}
+	bounds := decodeThumbnail(t, thumb)
+	if bounds.Dx() > 100 || bounds.Dy() != 100 {
+		t.Errorf("CoverThumbnail(100, 100) size: %v", bounds)
+	}
+}
+
+func TestSVGCoverThumbnail(t *testing.T) {
+	f := buildEpub(t, "testdata/epub3.opf", map[string]string{
+		"images/cover.jpg": svgCover,
+		"images/cover.png": pngImage(60, 80),
+		"text/ch1.xhtml":   `<html><body><p>Text</p></body></html>`,
+		"text/ch2.xhtml":   `<html><body><svg/></body></html>`,
+	})
+	defer f.Close()
+	f.opf.manifestItem("cover-img").MediaType = svgMediaType
+
+	thumb, err := f.CoverThumbnail(30, 0, nil)
+	if err != nil {
+		t.Fatalf("CoverThumbnail() return an error: %v", err)
+	}
+	if bounds := decodeThumbnail(t, thumb); bounds.Dx() != 30 || bounds.Dy() != 40 {
+		t.Errorf("CoverThumbnail(30, 0) size: %v", bounds)
+	}
+
+	f.stage(f.rootPath+"images/cover.jpg", []byte(`<svg><rect width="10" height="10"/></svg>`))
+	if _, err := f.CoverThumbnail(30, 0, nil); err == nil {
+		t.Errorf("CoverThumbnail() didn't return an error without a Rasterizer")
+	}
+	rasterize := func(svg []byte, width, height int) (image.Image, error) {
+		return image.NewNRGBA(image.Rect(0, 0, 10, 10)), nil
+	}
+	thumb, err = f.CoverThumbnail(0, 20, rasterize)
+	if err != nil {
+		t.Fatalf("CoverThumbnail() return an error: %v", err)
+	}
+	if bounds := decodeThumbnail(t, thumb); bounds.Dx() != 20 || bounds.Dy() != 20 {
+		t.Errorf("CoverThumbnail(0, 20) size: %v", bounds)
+	}
+}
+
+func TestSVGDocuments(t *testing.T) {
+	f := buildEpub(t, "testdata/epub3.opf", map[string]string{
+		"text/ch1.xhtml": `<html><body><img src="../images/map.svg"/></body></html>`,
+		"text/ch2.xhtml": `<html><body><p>Text</p></body></html>`,
+	})
+	defer f.Close()
+
+	docs, err := f.SVGDocuments()
+	if err != nil {
+		t.Fatalf("SVGDocuments() return an error: %v", err)
+	}
+	if len(docs) != 2 || docs[0] != "text/ch1.xhtml" || docs[1] != "text/ch2.xhtml" {
+		t.Errorf("SVGDocuments() return: %v", docs)
+	}
+
+	f.opf.Manifest[3].Properties = ""
+	docs, _ = f.SVGDocuments()
+	if len(docs) != 1 || docs[0] != "text/ch1.xhtml" {
+		t.Errorf("SVGDocuments() return: %v", docs)
+	}
+}
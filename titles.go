@@ -0,0 +1,90 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"sort"
+	"strconv"
+)
+
+// Title is a title of the book with its EPUB 3 refinements
+type Title struct {
+	Content string
+	// Type is the title-type: main, subtitle, short, collection, edition or
+	// expanded. It is empty if the book doesn't specify it.
+	Type   string
+	FileAs string
+	// Sequence is the display-seq of the title, 0 if not specified
+	Sequence int
+}
+
+// Titles returns the titles of the book with their types
+//
+// The titles are sorted by their display-seq, the ones without it keep the
+// document order after the sorted ones.
+func (e Epub) Titles() []Title {
+	elems := e.metadata["title"]
+	titles := make([]Title, len(elems))
+	for i, elem := range elems {
+		id := elem.Attr["id"]
+		titles[i].Content = elem.Content
+		titles[i].Type = e.metadata.refinement(id, "title-type")
+		titles[i].FileAs = e.metadata.refinement(id, "file-as")
+		titles[i].Sequence, _ = strconv.Atoi(e.metadata.refinement(id, "display-seq"))
+	}
+
+	sort.SliceStable(titles, func(i, j int) bool {
+		if titles[j].Sequence == 0 {
+			return titles[i].Sequence != 0
+		}
+		return titles[i].Sequence != 0 && titles[i].Sequence < titles[j].Sequence
+	})
+	return titles
+}
+
+// FullTitle assembles the title of the book from its typed titles
+//
+// The expanded title is used if present, if not the main title is combined
+// with the subtitles and the edition like "Main: Subtitle (Edition)".
+func (e Epub) FullTitle() string {
+	titles := e.Titles()
+	if len(titles) == 0 {
+		return ""
+	}
+
+	main := ""
+	for _, t := range titles {
+		switch t.Type {
+		case "expanded":
+			return t.Content
+		case "main":
+			if main == "" {
+				main = t.Content
+			}
+		}
+	}
+	if main == "" {
+		main = titles[0].Content
+		for _, t := range titles {
+			if t.Type == "" {
+				main = t.Content
+				break
+			}
+		}
+	}
+
+	full := main
+	for _, t := range titles {
+		if t.Type == "subtitle" {
+			full += ": " + t.Content
+		}
+	}
+	for _, t := range titles {
+		if t.Type == "edition" {
+			full += " (" + t.Content + ")"
+		}
+	}
+	return full
+}
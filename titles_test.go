@@ -0,0 +1,42 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+const (
+	epub3OPF      = "testdata/epub3.opf"
+	epub3Title    = "The Lord of the Rings"
+	epub3Subtitle = "The Fellowship of the Ring"
+	epub3Full     = "The Lord of the Rings: The Fellowship of the Ring (Collector's Edition)"
+)
+
+func TestTitles(t *testing.T) {
+	f := buildEpub(t, epub3OPF, nil)
+
+	titles := f.Titles()
+	if len(titles) != 3 {
+		t.Fatalf("len(Titles()) should be 3, but was %v", len(titles))
+	}
+	if titles[0].Content != epub3Title || titles[0].Type != "main" || titles[0].FileAs != "Lord of the Rings, The" {
+		t.Errorf("Titles()[0] return: %v", titles[0])
+	}
+	if titles[1].Content != epub3Subtitle || titles[1].Type != "subtitle" || titles[1].Sequence != 2 {
+		t.Errorf("Titles()[1] return: %v", titles[1])
+	}
+}
+
+func TestFullTitle(t *testing.T) {
+	f := buildEpub(t, epub3OPF, nil)
+	if title := f.FullTitle(); title != epub3Full {
+		t.Errorf("FullTitle() return: %v when was expected: %v", title, epub3Full)
+	}
+
+	book, _ := Open(bookPath)
+	defer book.Close()
+	if title := book.FullTitle(); title != bookTitle {
+		t.Errorf("FullTitle() return: %v when was expected: %v", title, bookTitle)
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TypographyOptions configures the SmartPunctuation transform
+type TypographyOptions struct {
+	// Lang selects the quotation marks, if empty the language of the book
+	// is used
+	Lang string
+	// FixMojibake repairs the text that was encoded as UTF-8 and decoded as
+	// Windows-1252, like "â€™" instead of "’"
+	FixMojibake bool
+}
+
+type quoteMarks struct {
+	open, close             string
+	openSingle, closeSingle string
+	// spaced languages separate the quotation marks from the text with a
+	// no-break space
+	spaced bool
+}
+
+var languageQuotes = map[string]quoteMarks{
+	"en": {"“", "”", "‘", "’", false},
+	"fr": {"«", "»", "‹", "›", true},
+	"de": {"„", "“", "‚", "‘", false},
+	"es": {"«", "»", "“", "”", false},
+	"it": {"«", "»", "“", "”", false},
+	"pt": {"«", "»", "“", "”", false},
+	"ru": {"«", "»", "„", "“", false},
+	"sv": {"”", "”", "’", "’", false},
+	"fi": {"”", "”", "’", "’", false},
+	"da": {"»", "«", "›", "‹", false},
+}
+
+// typographySkip are the elements which text is not modified
+var typographySkip = map[string]bool{
+	"pre": true, "code": true, "kbd": true, "samp": true, "script": true, "style": true,
+}
+
+// typographyBlocks are the elements that separate the text, besides the
+// splitBlocks
+var typographyBlocks = map[string]bool{
+	"body": true, "br": true, "li": true, "td": true, "th": true, "dt": true,
+	"dd": true, "caption": true, "figcaption": true, "title": true,
+}
+
+var (
+	quoteEntities = strings.NewReplacer("&quot;", `"`, "&#34;", `"`, "&apos;", "'", "&#39;", "'", "&#x27;", "'")
+	dashes        = strings.NewReplacer("---", "—", "--", "—", "...", "…", ". . .", "…")
+	mojibake      = newMojibakeReplacer()
+)
+
+const noBreakSpace = '\u00a0'
+
+// cp1252 are the characters of Windows-1252 on the range 0x80-0x9F, the
+// bytes not defined there are decoded as the C1 control characters
+var cp1252 = []rune{
+	'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', '\u008d', 'Ž', '\u008f',
+	'\u0090', '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', '\u009d', 'ž', 'Ÿ',
+}
+
+// SmartPunctuation returns a transform that normalizes the typography of the
+// content documents
+//
+// The straight quotes are converted to the quotation marks of the language,
+// "--" to an em dash and "..." to an ellipsis. Only the text is modified,
+// never the tags or attributes, and the text of the pre, code and similar
+// elements is kept as it is.
+func (e Epub) SmartPunctuation(opts TypographyOptions) Transform {
+	lang := opts.Lang
+	if lang == "" {
+		lang = e.language()
+	}
+	quotes, ok := languageQuotes[baseLang(lang)]
+	if !ok {
+		quotes = languageQuotes["en"]
+	}
+
+	return func(name, mediaType string, r io.Reader) (io.Reader, error) {
+		if mediaType != "application/xhtml+xml" && mediaType != "text/html" {
+			return r, nil
+		}
+		data, err := readAll(r)
+		if err != nil {
+			return nil, err
+		}
+		state := quoteState{prev: ' '}
+		data = eachTextAfter(data, typographySkip, func(tag, text string) string {
+			if isBlockTag(tag) {
+				state.prev = ' '
+			}
+			if opts.FixMojibake {
+				text = mojibake.Replace(text)
+			}
+			text = dashes.Replace(quoteEntities.Replace(text))
+			return quotes.replace(text, &state)
+		})
+		return bytes.NewReader(data), nil
+	}
+}
+
+// quoteState is the state of the quotes at the end of the previous text
+type quoteState struct {
+	prev rune
+	// open is true if there is a double quote not closed yet
+	open bool
+}
+
+// opens returns whether a double quote followed by next opens a quotation,
+// the open state is only used if the surrounding characters are ambiguous
+func (state quoteState) opens(next rune) bool {
+	ambiguous := next == utf8.RuneError || unicode.IsSpace(next)
+	switch {
+	case !opensQuote(state.prev):
+		return false
+	case state.open && (ambiguous || unicode.IsPunct(next)):
+		return false
+	case ambiguous:
+		return !state.open
+	}
+	return true
+}
+
+// replace the straight quotes of text, state is updated at the end of text
+func (q quoteMarks) replace(text string, state *quoteState) string {
+	var buff bytes.Buffer
+	afterOpen := false
+	for i, c := range text {
+		next, _ := utf8.DecodeRuneInString(text[i+utf8.RuneLen(c):])
+		switch {
+		case c == '"' && state.opens(next):
+			state.open = true
+			buff.WriteString(q.open)
+			if q.spaced {
+				buff.WriteRune(noBreakSpace)
+				afterOpen = true
+			}
+		case c == '"':
+			state.open = false
+			if q.spaced {
+				buff.Truncate(len(bytes.TrimRight(buff.Bytes(), " ")))
+				buff.WriteRune(noBreakSpace)
+			}
+			buff.WriteString(q.close)
+		case c == '\'' && (unicode.IsLetter(state.prev) || unicode.IsDigit(state.prev) || unicode.IsDigit(next)):
+			buff.WriteString("’")
+		case c == '\'' && opensQuote(state.prev):
+			buff.WriteString(q.openSingle)
+		case c == '\'':
+			buff.WriteString(q.closeSingle)
+		case c == ' ' && afterOpen:
+			continue
+		default:
+			buff.WriteRune(c)
+		}
+		afterOpen = afterOpen && (c == '"' || c == ' ')
+		state.prev = c
+	}
+	return buff.String()
+}
+
+// isBlockTag returns whether the tag starts or ends a block of text
+func isBlockTag(tag string) bool {
+	name := tagName(strings.TrimPrefix(tag, "</"))
+	name = name[strings.Index(name, ":")+1:]
+	return splitBlocks[name] || typographyBlocks[name]
+}
+
+func opensQuote(prev rune) bool {
+	return unicode.IsSpace(prev) || strings.ContainsRune("([{—–-/«„“‘", prev)
+}
+
+// newMojibakeReplacer builds a replacer from the Windows-1252 decoding of the
+// UTF-8 encoding of the non ASCII characters to the characters
+func newMojibakeReplacer() *strings.Replacer {
+	var runes []rune
+	for r := rune(0xA0); r <= 0xFF; r++ {
+		runes = append(runes, r)
+	}
+	runes = append(runes, '–', '—', '‘', '’', '‚', '“', '”', '„', '†', '•', '…', '€', '™')
+
+	var oldnew []string
+	for _, r := range runes {
+		var decoded strings.Builder
+		for _, b := range []byte(string(r)) {
+			if b >= 0x80 && b < 0xA0 {
+				decoded.WriteRune(cp1252[b-0x80])
+			} else {
+				decoded.WriteRune(rune(b))
+			}
+		}
+		oldnew = append(oldnew, decoded.String(), string(r))
+	}
+	return strings.NewReplacer(oldnew...)
+}
@@ -0,0 +1,68 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"io/ioutil"
+	"strings"
+)
+
+func smartPunctuation(t *testing.T, opts TypographyOptions, html string) string {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	r, err := f.SmartPunctuation(opts)("page.xhtml", "application/xhtml+xml", strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("SmartPunctuation() return an error: %v", err)
+	}
+	data, _ := ioutil.ReadAll(r)
+	return string(data)
+}
+
+func TestSmartPunctuation(t *testing.T) {
+	tests := []struct {
+		opts     TypographyOptions
+		html     string
+		expected string
+	}{
+		{
+			TypographyOptions{},
+			`<p title="it's">"Don't," she said -- 'it's the '90s...'</p>`,
+			`<p title="it's">“Don’t,” she said — ‘it’s the ’90s…’</p>`,
+		},
+		{
+			TypographyOptions{},
+			`<p>&quot;Hello <i>world</i>&quot;</p><pre>"raw" -- text</pre>`,
+			`<p>“Hello <i>world</i>”</p><pre>"raw" -- text</pre>`,
+		},
+		{
+			TypographyOptions{},
+			`<p>"One paragraph</p><p>"Another one."</p>`,
+			`<p>“One paragraph</p><p>“Another one.”</p>`,
+		},
+		{
+			TypographyOptions{Lang: "fr"},
+			`<p>Il a dit " bonjour ".</p>`,
+			"<p>Il a dit « bonjour ».</p>",
+		},
+		{
+			TypographyOptions{Lang: "de-AT"},
+			`<p>"Ja"</p>`,
+			`<p>„Ja“</p>`,
+		},
+		{
+			TypographyOptions{FixMojibake: true},
+			`<p>Itâ€™s a cafÃ© â€” fine</p>`,
+			`<p>It’s a café — fine</p>`,
+		},
+	}
+	for _, test := range tests {
+		if html := smartPunctuation(t, test.opts, test.html); html != test.expected {
+			t.Errorf("SmartPunctuation(%v) return: %q when was expected: %q", test.html, html, test.expected)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Vocabulary is a metadata vocabulary, declared on the prefix attribute of
+// the package so its properties (like "rendition:layout") can be used on the
+// EPUB 3 meta elements
+type Vocabulary struct {
+	Prefix string
+	URI    string
+}
+
+// Vocabularies often required by the retailers
+var (
+	VocabRendition = Vocabulary{"rendition", "http://www.idpf.org/vocab/rendition/#"}
+	VocabIBooks    = Vocabulary{"ibooks", "http://vocabulary.itunes.apple.com/rdf/ibooks/vocabulary-extensions-1.0/"}
+	VocabSchema    = Vocabulary{"schema", "http://schema.org/"}
+	VocabA11y      = Vocabulary{"a11y", "http://www.idpf.org/epub/vocab/package/a11y/#"}
+	VocabCalibre   = Vocabulary{"calibre", "http://calibre.kovidgoyal.net/2009/metadata"}
+)
+
+var prefixAttrRegexp = regexp.MustCompile(`(\sprefix\s*=\s*)("[^"]*"|'[^']*')`)
+
+// Vocabularies returns the vocabularies declared on the prefix attribute of
+// the package
+func (e Epub) Vocabularies() []Vocabulary {
+	return parseVocabularies(e.opf.Prefix)
+}
+
+// AddVocabulary declares the vocabularies on the prefix attribute of the
+// package, replacing the URI of the prefixes already declared
+//
+// The OPF is updated when the epub is written with Repack.
+func (e *Epub) AddVocabulary(vocabs ...Vocabulary) {
+	declared := parseVocabularies(e.opf.Prefix)
+	for _, v := range vocabs {
+		replaced := false
+		for i := range declared {
+			if declared[i].Prefix == v.Prefix {
+				declared[i].URI = v.URI
+				replaced = true
+			}
+		}
+		if !replaced {
+			declared = append(declared, v)
+		}
+	}
+	var parts []string
+	for _, v := range declared {
+		parts = append(parts, v.Prefix+": "+v.URI)
+	}
+	e.opf.Prefix = strings.Join(parts, " ")
+}
+
+// SetExtensionMetadata replaces the elements of the OPF metadata that are
+// not Dublin Core or meta, the ones returned by ExtensionMetadata
+//
+// The elements are written with the prefix of their vocabulary, declared on
+// the package or with AddVocabulary, the elements on the OPF namespace (like
+// the EPUB 3 link) without prefix. The comments and processing instructions
+// of the metadata are kept. The OPF is updated when the epub is written with
+// Repack.
+func (e *Epub) SetExtensionMetadata(elems []ExtensionElement) {
+	extensions := make([]ExtensionElement, len(elems))
+	for i, elem := range elems {
+		extensions[i] = elem
+		if extensions[i].Attr == nil {
+			extensions[i].Attr = make(map[string]string)
+		}
+	}
+	e.opf.Metadata.extensions = extensions
+}
+
+// marshalExtensions serializes the extension elements, prefix is the prefix
+// of the OPF namespace on the metadata
+func (e Epub) marshalExtensions(prefix string) []string {
+	prefixes := make(map[string]string)
+	for _, v := range append(knownVocabularies(), e.Vocabularies()...) {
+		prefixes[v.URI] = v.Prefix
+	}
+
+	var elements []string
+	generated := 0
+	for _, elem := range e.opf.Metadata.extensions {
+		var buff bytes.Buffer
+		name := prefix + elem.Name
+		declaration := ""
+		if elem.Namespace != "" && elem.Namespace != opfNamespace {
+			p, ok := prefixes[elem.Namespace]
+			if !ok {
+				generated++
+				p = "ns" + strconv.Itoa(generated)
+				prefixes[elem.Namespace] = p
+			}
+			name = p + ":" + elem.Name
+			declaration = ` xmlns:` + p + `="` + escapeXML(elem.Namespace) + `"`
+		}
+
+		buff.WriteString("<" + name + declaration)
+		for _, k := range sortedKeys(elem.Attr) {
+			v := elem.Attr[k]
+			attr, ok := attrName(k, elem.Attr)
+			if v == "" || !ok {
+				continue
+			}
+			buff.WriteString(" " + attr + `="` + escapeXML(v) + `"`)
+		}
+		if elem.Content == "" {
+			buff.WriteString("/>")
+		} else {
+			buff.WriteString(">" + escapeXML(elem.Content) + "</" + name + ">")
+		}
+		elements = append(elements, buff.String())
+	}
+	return elements
+}
+
+// setPrefixAttr sets the prefix attribute of the package tag of the OPF
+func setPrefixAttr(opf []byte, prefix string) []byte {
+	loc := packageTagRegexp.FindIndex(opf)
+	if loc == nil {
+		return opf
+	}
+	tag := string(opf[loc[0]:loc[1]])
+	value := `"` + escapeXML(prefix) + `"`
+	if sub := prefixAttrRegexp.FindStringSubmatchIndex(tag); sub != nil {
+		tag = tag[:sub[4]] + value + tag[sub[5]:]
+	} else {
+		tag = tag[:len(tag)-1] + " prefix=" + value + ">"
+	}
+
+	var buff bytes.Buffer
+	buff.Write(opf[:loc[0]])
+	buff.WriteString(tag)
+	buff.Write(opf[loc[1]:])
+	return buff.Bytes()
+}
+
+// parseVocabularies parses the value of a prefix attribute, pairs of
+// "prefix: URI" separated by whitespace
+func parseVocabularies(prefix string) []Vocabulary {
+	var vocabs []Vocabulary
+	fields := strings.Fields(prefix)
+	for i := 0; i+1 < len(fields); i++ {
+		if !strings.HasSuffix(fields[i], ":") {
+			continue
+		}
+		vocabs = append(vocabs, Vocabulary{strings.TrimSuffix(fields[i], ":"), fields[i+1]})
+		i++
+	}
+	return vocabs
+}
+
+func knownVocabularies() []Vocabulary {
+	return []Vocabulary{VocabRendition, VocabIBooks, VocabSchema, VocabA11y, VocabCalibre}
+}
@@ -0,0 +1,75 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import "strings"
+
+func TestAddVocabulary(t *testing.T) {
+	f := buildEpub(t, epub3OPF, nil)
+	if vocabs := f.Vocabularies(); len(vocabs) != 0 {
+		t.Errorf("Vocabularies() return: %v", vocabs)
+	}
+	f.AddVocabulary(VocabRendition, VocabIBooks)
+	f.AddVocabulary(Vocabulary{"ibooks", "http://example.com/ibooks/"})
+	meta, _ := f.MetadataElement("meta")
+	meta = append(meta, MdataElement{Content: "1.0", Attr: map[string]string{"property": "ibooks:version"}})
+	f.SetMetadata("meta", meta)
+
+	book := repackBook(t, f)
+	vocabs := book.Vocabularies()
+	if len(vocabs) != 2 || vocabs[0] != VocabRendition || vocabs[1].URI != "http://example.com/ibooks/" {
+		t.Errorf("Vocabularies() return: %v", vocabs)
+	}
+	opf, _ := book.readFile(book.opfPath)
+	if !strings.Contains(string(opf), `prefix="rendition: http://www.idpf.org/vocab/rendition/# ibooks: http://example.com/ibooks/"`) {
+		t.Errorf("The OPF has no prefix attribute:\n%s", opf)
+	}
+	if !strings.Contains(string(opf), `<meta property="ibooks:version">1.0</meta>`) {
+		t.Errorf("The OPF has no ibooks:version:\n%s", opf)
+	}
+
+	book.AddVocabulary(VocabSchema)
+	opf, _ = repackBook(t, book).readFile(book.opfPath)
+	if strings.Count(string(opf), "prefix=") != 1 || !strings.Contains(string(opf), "schema: http://schema.org/\"") {
+		t.Errorf("The prefix attribute was not updated:\n%s", opf)
+	}
+}
+
+func TestSetExtensionMetadata(t *testing.T) {
+	f := buildEpub(t, epub3OPF, nil)
+	extensions := append(f.ExtensionMetadata(),
+		ExtensionElement{Namespace: VocabCalibre.URI, Name: "custom", Content: "A & B", Attr: map[string]string{"name": "x"}},
+		ExtensionElement{Namespace: "http://example.com/ns", Name: "flag"},
+		ExtensionElement{Namespace: opfNamespace, Name: "link", Attr: map[string]string{"rel": "record", "href": "record.xml"}},
+	)
+	f.SetExtensionMetadata(extensions)
+
+	book := repackBook(t, f)
+	got := book.ExtensionMetadata()
+	if len(got) != len(extensions) {
+		t.Fatalf("ExtensionMetadata() return: %v", got)
+	}
+	for i, ext := range got[len(got)-3:] {
+		expected := extensions[len(extensions)-3+i]
+		if ext.Namespace != expected.Namespace || ext.Name != expected.Name || ext.Content != expected.Content {
+			t.Errorf("ExtensionMetadata() element %d: %+v", i, ext)
+		}
+	}
+	opf, _ := book.readFile(book.opfPath)
+	for _, fragment := range []string{
+		`<calibre:custom xmlns:calibre="http://calibre.kovidgoyal.net/2009/metadata" name="x">A &amp; B</calibre:custom>`,
+		`<ns1:flag xmlns:ns1="http://example.com/ns"/>`,
+		`<link href="record.xml" rel="record"/>`,
+	} {
+		if !strings.Contains(string(opf), fragment) {
+			t.Errorf("The OPF doesn't contain %q:\n%s", fragment, opf)
+		}
+	}
+	if title := book.FullTitle(); title != epub3Full {
+		t.Errorf("FullTitle() return: %v", title)
+	}
+}
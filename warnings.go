@@ -0,0 +1,59 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+// Codes of the ParseWarning
+const (
+	// WarnNoTitle is an epub without dc:title
+	WarnNoTitle = "no-title"
+	// WarnSpineItem is an item of the spine that is not on the manifest, it
+	// is skipped by the iterators and by the methods that go through the
+	// spine, the methods that take its spine index return an error
+	WarnSpineItem = "spine-item-missing"
+	// WarnTocFallback is a toc attribute on the spine that is not on the
+	// manifest, the NCX is looked for by its media type or by the id "ncx"
+	WarnTocFallback = "toc-fallback"
+	// WarnNoNCX is an epub without NCX, Navigation is not available
+	WarnNoNCX = "no-ncx"
+	// WarnInvalidNCX is a NCX that could not be parsed, Navigation is not
+	// available
+	WarnInvalidNCX = "invalid-ncx"
+	// WarnNoContainer is a missing or broken META-INF/container.xml, the OPF
+	// file is looked for on the zip
+	WarnNoContainer = "container-fallback"
+	// WarnFileCase is a file referenced with a different case than on the
+	// zip
+	WarnFileCase = "file-case"
+)
+
+// ParseWarning is a non fatal issue found while loading the epub
+type ParseWarning struct {
+	Code string
+	// Path is the file of the zip where the issue was found
+	Path    string
+	Message string
+}
+
+// String formats the warning as "path: message (code)"
+func (w ParseWarning) String() string {
+	return w.Path + ": " + w.Message + " (" + w.Code + ")"
+}
+
+// Warnings returns the non fatal issues tolerated while loading the epub
+func (e Epub) Warnings() []ParseWarning {
+	return e.warnings
+}
+
+func (e *Epub) addWarning(code, path, message string) {
+	e.warnings = append(e.warnings, ParseWarning{code, path, message})
+}
+
+// checkCase adds a WarnFileCase if the file name is on the zip with a
+// different case
+func (e *Epub) checkCase(name string) {
+	if f := e.findFile(name); f != nil && f.Name != name {
+		e.addWarning(WarnFileCase, f.Name, "File referenced as "+name)
+	}
+}
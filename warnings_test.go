@@ -0,0 +1,78 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+)
+
+func TestWarnings(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+	if warnings := f.Warnings(); len(warnings) != 0 {
+		t.Errorf("Warnings() return: %v", warnings)
+	}
+
+	f, _ = Open(invalidNCXPath)
+	defer f.Close()
+	warnings := f.Warnings()
+	if len(warnings) != 1 || warnings[0].Code != WarnTocFallback || warnings[0].Path != f.opfPath {
+		t.Errorf("Warnings() return: %v", warnings)
+	}
+
+	f, _ = Open(fileCapsPath)
+	defer f.Close()
+	warnings = f.Warnings()
+	if len(warnings) == 0 || warnings[0].Code != WarnFileCase || warnings[0].Path != "OPS/content.opf" {
+		t.Errorf("Warnings() return: %v", warnings)
+	}
+
+	book := buildEpub(t, "testdata/epub3.opf", nil)
+	warnings = book.Warnings()
+	if len(warnings) != 1 || warnings[0].Code != WarnNoNCX {
+		t.Errorf("Warnings() return: %v", warnings)
+	}
+}
+
+func TestMissingContainer(t *testing.T) {
+	opf, _ := ioutil.ReadFile("testdata/epub3.opf")
+	for _, container := range []string{"", "<container>", `<container><rootfiles><rootfile full-path="missing.opf"/></rootfiles></container>`} {
+		var buff bytes.Buffer
+		w := zip.NewWriter(&buff)
+		if container != "" {
+			f, _ := w.Create("META-INF/container.xml")
+			f.Write([]byte(container))
+		}
+		f, _ := w.Create("OEBPS/extra/other.opf")
+		f.Write(opf)
+		f, _ = w.Create("OEBPS/content.opf")
+		f.Write(opf)
+		w.Close()
+
+		book, err := Load(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+		if err != nil {
+			t.Fatalf("Load() with container %q return an error: %v", container, err)
+		}
+		if book.opfPath != "OEBPS/content.opf" || book.rootPath != "OEBPS/" {
+			t.Errorf("Load() with container %q found the OPF: %v", container, book.opfPath)
+		}
+		warnings := book.Warnings()
+		if len(warnings) == 0 || warnings[0].Code != WarnNoContainer {
+			t.Errorf("Warnings() with container %q return: %v", container, warnings)
+		}
+	}
+
+	var buff bytes.Buffer
+	w := zip.NewWriter(&buff)
+	w.Create("mimetype")
+	w.Close()
+	if _, err := Load(bytes.NewReader(buff.Bytes()), int64(buff.Len())); err == nil {
+		t.Errorf("Load() without OPF didn't return an error")
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"html/template"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"regexp"
+	"strings"
+)
+
+const defaultColophon = "This copy belongs to %s"
+
+var (
+	metadataEndRegexp = regexp.MustCompile(`</([\w-]+:)?metadata>`)
+	bodyEndRegexp     = regexp.MustCompile(`</([\w-]+:)?body>`)
+)
+
+// ColophonTemplate is the page added at the end of the book by
+// WriteWatermarked, it is executed with a PageData with the colophon on Text
+var ColophonTemplate = newPageTemplate(`{{define "title"}}Colophon{{end}}
+    <section epub:type="colophon" class="watermark">
+      <p>{{.Text}}</p>
+    </section>`)
+
+// WatermarkOptions configures the Watermark transform
+type WatermarkOptions struct {
+	// Colophon is the text of the colophon page of WriteWatermarked, the
+	// first %s is replaced by the purchaser identifier. If empty a default
+	// text is used.
+	Colophon string
+	// Images enables hiding the identifier on the least significant bits of
+	// the PNG images. Lossy formats like JPEG are left untouched.
+	Images bool
+}
+
+// Watermark returns a transform that stamps the purchaser identifier into the epub
+//
+// The identifier is added as a meta name="watermark" field on the OPF
+// metadata. A transform can't add files to the epub, WriteWatermarked adds
+// also a colophon page.
+func (e Epub) Watermark(id string, opts WatermarkOptions) Transform {
+	return func(name, mediaType string, r io.Reader) (io.Reader, error) {
+		switch {
+		case name == e.opfPath:
+			meta := `<meta name="watermark" content="` + escapeXML(id) + `"/>`
+			return insertBefore(r, metadataEndRegexp, meta)
+		case opts.Images && mediaType == "image/png":
+			return watermarkPNG(r, id)
+		}
+		return r, nil
+	}
+}
+
+// WriteWatermarked writes into w a copy of the book stamped with the
+// purchaser identifier
+//
+// The copy has the Watermark of the identifier and a colophon page with it
+// at the end of the spine, made with ColophonTemplate. The book itself is not
+// modified.
+func (e Epub) WriteWatermarked(w io.Writer, id string, opts WatermarkOptions) error {
+	var buff bytes.Buffer
+	if err := e.Repack(&buff); err != nil {
+		return err
+	}
+	book, err := Load(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+	if err != nil {
+		return err
+	}
+	colophon := opts.Colophon
+	if colophon == "" {
+		colophon = defaultColophon
+	}
+	href := book.pageHref("colophon.xhtml")
+	data := book.PageData(href)
+	data.Text = template.HTML(escapeXML(strings.Replace(colophon, "%s", id, 1)))
+	if err := book.AddPage(href, "", ColophonTemplate, data, -1); err != nil {
+		return err
+	}
+	return book.Repack(w, book.Watermark(id, opts))
+}
+
+// ImageWatermark returns the identifier hidden on a PNG image by Watermark
+func ImageWatermark(r io.Reader) (string, error) {
+	img, err := png.Decode(r)
+	if err != nil {
+		return "", err
+	}
+	bits := pixelBits(img)
+	length := int(readBits(bits, 0, 16)) + 2
+	if length*8 > len(bits) {
+		return "", errors.New("The image has no watermark")
+	}
+	data := make([]byte, length-2)
+	for i := range data {
+		data[i] = byte(readBits(bits, (i+2)*8, 8))
+	}
+	return string(data), nil
+}
+
+func insertBefore(r io.Reader, re *regexp.Regexp, text string) (io.Reader, error) {
+	data, err := readAll(r)
+	if err != nil {
+		return nil, err
+	}
+	loc := re.FindAllIndex(data, -1)
+	if loc == nil {
+		return bytes.NewReader(data), nil
+	}
+	pos := loc[len(loc)-1][0]
+	var buff bytes.Buffer
+	buff.Write(data[:pos])
+	buff.WriteString(text)
+	buff.Write(data[pos:])
+	return &buff, nil
+}
+
+func escapeXML(s string) string {
+	var buff bytes.Buffer
+	xml.EscapeText(&buff, []byte(s))
+	return buff.String()
+}
+
+func watermarkPNG(r io.Reader, id string) (io.Reader, error) {
+	data, err := readAll(r)
+	if err != nil {
+		return nil, err
+	}
+	src, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return bytes.NewReader(data), nil
+	}
+
+	payload := append([]byte{byte(len(id) >> 8), byte(len(id))}, id...)
+	bounds := src.Bounds()
+	if len(payload)*8 > bounds.Dx()*bounds.Dy()*3 || len(id) > 0xffff {
+		return bytes.NewReader(data), nil
+	}
+
+	img := image.NewNRGBA(bounds)
+	draw.Draw(img, bounds, src, bounds.Min, draw.Src)
+	bit := 0
+	for i := 0; i < len(img.Pix) && bit < len(payload)*8; i++ {
+		if i%4 == 3 {
+			continue // skip alpha
+		}
+		b := (payload[bit/8] >> uint(7-bit%8)) & 1
+		img.Pix[i] = img.Pix[i]&0xfe | b
+		bit++
+	}
+
+	var buff bytes.Buffer
+	err = png.Encode(&buff, img)
+	return &buff, err
+}
+
+func pixelBits(img image.Image) []byte {
+	bounds := img.Bounds()
+	bits := make([]byte, 0, bounds.Dx()*bounds.Dy()*3)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			bits = append(bits, c.R&1, c.G&1, c.B&1)
+		}
+	}
+	return bits
+}
+
+func readBits(bits []byte, start, n int) uint {
+	var v uint
+	for i := start; i < start+n && i < len(bits); i++ {
+		v = v<<1 | uint(bits[i])
+	}
+	return v
+}
@@ -0,0 +1,98 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+)
+
+const (
+	purchaserID = "order-1234 <buyer@example.com>"
+)
+
+func TestWatermark(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	book := repackBook(t, f, f.Watermark(purchaserID, WatermarkOptions{}))
+	meta, _ := book.MetadataAttr("meta")
+	found := false
+	for _, m := range meta {
+		if m["name"] == "watermark" && m["content"] == purchaserID {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("The watermark was not found on the metadata: %v", meta)
+	}
+
+}
+
+func TestWriteWatermarked(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+	length := f.opf.spineLength()
+
+	tests := []struct {
+		colophon string
+		text     string
+	}{
+		{"", "This copy belongs to order-1234 &lt;buyer@example.com&gt;"},
+		{"Licensed copy", "Licensed copy"},
+		{"100% yours, %s %d", "100% yours, order-1234 &lt;buyer@example.com&gt; %d"},
+	}
+	for _, test := range tests {
+		var buff bytes.Buffer
+		if err := f.WriteWatermarked(&buff, purchaserID, WatermarkOptions{Colophon: test.colophon}); err != nil {
+			t.Fatalf("WriteWatermarked() return an error: %v", err)
+		}
+		book, err := Load(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+		if err != nil {
+			t.Fatalf("Load() return an error: %v", err)
+		}
+		if book.opf.spineLength() != length+1 {
+			t.Fatalf("The colophon page was not added to the spine")
+		}
+		last := book.opf.spineURL(book.opf.spineLength() - 1)
+		data := readBookFile(t, book, last)
+		if !strings.Contains(data, "<p>"+test.text+"</p>") || strings.Contains(data, "%!") {
+			t.Errorf("Wrong colophon page for %q: %v", test.colophon, data)
+		}
+		if meta, _ := book.MetadataAttr("meta"); meta[len(meta)-1]["content"] != purchaserID {
+			t.Errorf("The watermark was not found on the metadata: %v", meta)
+		}
+	}
+	if f.opf.spineLength() != length {
+		t.Errorf("WriteWatermarked() modified the book")
+	}
+}
+
+func TestImageWatermark(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i)
+	}
+	img.Set(0, 0, color.RGBA{255, 255, 255, 255})
+	var buff bytes.Buffer
+	png.Encode(&buff, img)
+
+	r, err := watermarkPNG(&buff, purchaserID)
+	if err != nil {
+		t.Fatalf("watermarkPNG() return an error: %v", err)
+	}
+	id, err := ImageWatermark(r)
+	if err != nil {
+		t.Fatalf("ImageWatermark() return an error: %v", err)
+	}
+	if id != purchaserID {
+		t.Errorf("ImageWatermark() return: %v when was expected: %v", id, purchaserID)
+	}
+}
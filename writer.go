@@ -0,0 +1,502 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	dcNamespace  = "http://purl.org/dc/elements/1.1/"
+	opfNamespace = "http://www.idpf.org/2007/opf"
+	xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+)
+
+// metadataFieldOrder is the order of the metadata fields as defined by the
+// Dublin Core, followed by the meta elements
+var metadataFieldOrder = []string{
+	"title", "creator", "subject", "description", "publisher", "contributor",
+	"date", "type", "format", "identifier", "source", "language", "relation",
+	"coverage", "rights", "meta",
+}
+
+// attributes of the dc elements that belong to the opf namespace
+var opfAttributes = map[string]bool{
+	"file-as": true, "role": true, "scheme": true, "event": true,
+}
+
+var (
+	packageTagRegexp  = regexp.MustCompile(`<(?:[\w-]+:)?package\b[^>]*>`)
+	packageNameRegexp = regexp.MustCompile(`<([\w-]+:)?package\b`)
+	spineTagRegexp    = regexp.MustCompile(`<(?:[\w-]+:)?spine\b[^>]*?(/?)>`)
+)
+
+// SetMetadata replaces the values of a metadata field
+//
+// An empty elems removes the field. The OPF is updated when the epub is
+// written with Repack. See Metadata() for the valid field names.
+func (e *Epub) SetMetadata(field string, elems []MdataElement) error {
+	if !validField(field) {
+		return errors.New("Metadata field " + field + " does not exist")
+	}
+	if len(elems) == 0 {
+		delete(e.metadata, field)
+		return nil
+	}
+	e.metadata[field] = make([]MdataElement, len(elems))
+	for i, elem := range elems {
+		e.metadata[field][i].Content = elem.Content
+		e.metadata[field][i].Index = elem.Index
+		e.metadata[field][i].Attr = make(map[string]string)
+		for k, v := range elem.Attr {
+			e.metadata[field][i].Attr[k] = v
+		}
+	}
+	return nil
+}
+
+func validField(field string) bool {
+	for _, f := range metadataFieldOrder {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// pendingFiles returns the staged files plus the OPF regenerated if the
+// metadata, manifest or guide were modified
+func (e Epub) pendingFiles() (map[string][]byte, error) {
+	opfData, err := e.readFile(e.opfPath)
+	if err != nil {
+		return nil, err
+	}
+	orig, err := parseOPF(bytes.NewReader(opfData))
+	if err != nil {
+		return nil, err
+	}
+
+	newOPF := opfData
+	extensionsChanged := !reflect.DeepEqual(orig.Metadata.extensions, e.opf.Metadata.extensions)
+	if extensionsChanged || !reflect.DeepEqual(orig.toMData(), e.metadata) {
+		var extensions func(prefix string) []string
+		if extensionsChanged {
+			extensions = e.marshalExtensions
+		}
+		newOPF = replaceMetadata(newOPF, e.metadata, extensions)
+	}
+	if orig.Prefix != e.opf.Prefix {
+		newOPF = setPrefixAttr(newOPF, e.opf.Prefix)
+	}
+	if !reflect.DeepEqual(orig.Manifest, e.opf.Manifest) {
+		newOPF, _ = replaceSection(newOPF, "manifest", nil, e.opf.marshalManifest)
+	}
+	if orig.Spine.PageProgression != e.opf.Spine.PageProgression {
+		newOPF = setSpineAttr(newOPF, "page-progression-direction", e.opf.Spine.PageProgression)
+	}
+	if !reflect.DeepEqual(orig.Spine.Items, e.opf.Spine.Items) {
+		newOPF, _ = replaceSection(newOPF, "spine", nil, e.opf.marshalSpine)
+	}
+	if !reflect.DeepEqual(orig.Guide, e.opf.Guide) {
+		var found bool
+		newOPF, found = replaceSection(newOPF, "guide", nil, e.opf.marshalGuide)
+		if !found {
+			newOPF = insertSection(newOPF, "guide", e.opf.marshalGuide)
+		}
+	}
+	newNCX, err := e.pendingNCX()
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(newOPF, opfData) && newNCX == nil {
+		return e.staged, nil
+	}
+
+	files := make(map[string][]byte, len(e.staged)+2)
+	for name, data := range e.staged {
+		files[name] = data
+	}
+	files[e.opfPath] = newOPF
+	if newNCX != nil {
+		files[e.rootPath+e.opf.ncxPath()] = newNCX
+	}
+	return files, nil
+}
+
+// pendingNCX returns the NCX regenerated if the navigation was modified, or
+// nil if it was not
+func (e Epub) pendingNCX() ([]byte, error) {
+	if e.ncx == nil {
+		return nil, nil
+	}
+	ncxData, err := e.readFile(e.rootPath + e.opf.ncxPath())
+	if err != nil {
+		return nil, err
+	}
+	orig, err := parseNCX(bytes.NewReader(ncxData))
+	if err != nil {
+		return nil, err
+	}
+	if reflect.DeepEqual(orig.NavMap, e.ncx.NavMap) {
+		return nil, nil
+	}
+	newNCX, _ := replaceSection(ncxData, "navMap", nil, e.ncx.marshalNavMap)
+	return newNCX, nil
+}
+
+// replaceMetadata replaces the content of the metadata element of the OPF
+//
+// The elements that are not Dublin Core or meta are kept as they are on the
+// OPF, unless extensions is not nil, then they are replaced by the ones it
+// returns for the prefix of the metadata (the comments and processing
+// instructions are always kept).
+func replaceMetadata(opf []byte, m mdata, extensions func(prefix string) []string) []byte {
+	packageTag := packageTagRegexp.Find(opf)
+	nsAttrs := func(start []byte) string {
+		attrs := ""
+		if !hasNamespace(packageTag, dcNamespace) && !hasNamespace(start, dcNamespace) {
+			attrs += ` xmlns:dc="` + dcNamespace + `"`
+		}
+		if !hasNamespace(packageTag, opfNamespace) && !hasNamespace(start, opfNamespace) {
+			attrs += ` xmlns:opf="` + opfNamespace + `"`
+		}
+		return attrs
+	}
+	raw := extensionElements(opf)
+	body := func(prefix string) string {
+		elements := raw
+		if extensions != nil {
+			elements = extensions(prefix)
+			for _, r := range raw {
+				if strings.HasPrefix(r, "<!--") || strings.HasPrefix(r, "<?") {
+					elements = append(elements, r)
+				}
+			}
+		}
+		content := m.marshal(prefix)
+		for _, elem := range elements {
+			content += "\n    " + elem
+		}
+		return content
+	}
+	opf, _ = replaceSection(opf, "metadata", nsAttrs, body)
+	return opf
+}
+
+var metadataRegexp = regexp.MustCompile(`(?s)<(?:[\w-]+:)?metadata\b[^>]*>(.*?)</(?:[\w-]+:)?metadata>`)
+
+// extensionElements returns the raw XML of the children of the OPF metadata
+// that are not written by marshal: the elements that are not Dublin Core or
+// meta (extensions, EPUB 3 links, ...), the comments and the processing
+// instructions, to keep them verbatim when the metadata is rewritten
+func extensionElements(opf []byte) []string {
+	sub := metadataRegexp.FindSubmatch(opf)
+	if sub == nil {
+		return nil
+	}
+	inner := sub[1]
+	decoder := xml.NewDecoder(bytes.NewReader(inner))
+	decoder.Strict = false
+
+	var elements []string
+	depth, start, keep := 0, int64(0), false
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.RawToken()
+		if err != nil {
+			break
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				if isMetadataWrapper(t.Name.Local) {
+					continue
+				}
+				start, keep = offset, !writtenMetadata(t.Name)
+			}
+			depth++
+		case xml.EndElement:
+			if depth == 0 {
+				continue
+			}
+			depth--
+			if depth == 0 && keep {
+				elements = append(elements, string(inner[start:decoder.InputOffset()]))
+			}
+		case xml.Comment, xml.ProcInst:
+			if depth == 0 {
+				elements = append(elements, string(inner[offset:decoder.InputOffset()]))
+			}
+		}
+	}
+	return elements
+}
+
+// writtenMetadata returns whether the metadata element is parsed as a field
+// and written by marshal
+func writtenMetadata(name xml.Name) bool {
+	local := name.Local
+	if name.Space == "dc" {
+		local = strings.ToLower(local)
+	}
+	return validField(local)
+}
+
+// replaceSection replaces the content of the first element called name of
+// the OPF keeping its start tag, extraAttrs can add attributes to it
+//
+// body receives the namespace prefix of the element (like "opf:") to be used
+// on its children. Returns false if the element is not found.
+func replaceSection(opf []byte, name string, extraAttrs func(start []byte) string, body func(prefix string) string) ([]byte, bool) {
+	re := regexp.MustCompile(`(?s)(<((?:[\w-]+:)?)` + name + `\b[^>]*?)(/>|>.*?</(?:[\w-]+:)?` + name + `>)`)
+	loc := re.FindSubmatchIndex(opf)
+	if loc == nil {
+		return opf, false
+	}
+	start := opf[loc[2]:loc[3]]
+	prefix := string(opf[loc[4]:loc[5]])
+
+	var buff bytes.Buffer
+	buff.Write(opf[:loc[0]])
+	buff.Write(start)
+	if extraAttrs != nil {
+		buff.WriteString(extraAttrs(start))
+	}
+	buff.WriteString(">" + body(prefix) + "\n  </" + prefix + name + ">")
+	buff.Write(opf[loc[1]:])
+	return buff.Bytes(), true
+}
+
+// insertSection adds a new element called name at the end of the package
+func insertSection(opf []byte, name string, body func(prefix string) string) []byte {
+	prefix := ""
+	if sub := packageNameRegexp.FindSubmatch(opf); sub != nil {
+		prefix = string(sub[1])
+	}
+	section := "  <" + prefix + name + ">" + body(prefix) + "\n  </" + prefix + name + ">\n"
+	end := bytes.LastIndex(opf, []byte("</"+prefix+"package>"))
+	if end == -1 {
+		return opf
+	}
+
+	var buff bytes.Buffer
+	buff.Write(opf[:end])
+	buff.WriteString(section)
+	buff.Write(opf[end:])
+	return buff.Bytes()
+}
+
+// setSpineAttr sets the attribute name of the spine tag of the OPF, an empty
+// value removes it
+func setSpineAttr(opf []byte, name, value string) []byte {
+	loc := spineTagRegexp.FindSubmatchIndex(opf)
+	if loc == nil {
+		return opf
+	}
+	tag := string(opf[loc[0]:loc[2]])
+	attrRegexp := regexp.MustCompile(`\s` + regexp.QuoteMeta(name) + `\s*=\s*("[^"]*"|'[^']*')`)
+	tag = attrRegexp.ReplaceAllString(tag, "")
+	if value != "" {
+		tag += " " + name + `="` + escapeXML(value) + `"`
+	}
+
+	var buff bytes.Buffer
+	buff.Write(opf[:loc[0]])
+	buff.WriteString(tag)
+	buff.Write(opf[loc[2]:])
+	return buff.Bytes()
+}
+
+func hasNamespace(tag []byte, namespace string) bool {
+	return bytes.Contains(tag, []byte(`"`+namespace+`"`)) || bytes.Contains(tag, []byte(`'`+namespace+`'`))
+}
+
+// marshal serializes the metadata fields as OPF metadata elements
+func (m mdata) marshal(prefix string) string {
+	var buff bytes.Buffer
+	for _, field := range m.fields() {
+		for _, elem := range m[field] {
+			buff.WriteString("\n    ")
+			if field == "meta" {
+				writeMeta(&buff, prefix, elem)
+			} else {
+				writeDC(&buff, field, elem)
+			}
+		}
+	}
+	return buff.String()
+}
+
+// fields returns the fields present in the metadata in their canonical order
+func (m mdata) fields() []string {
+	var fields []string
+	for _, field := range metadataFieldOrder {
+		if _, ok := m[field]; ok {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+func writeDC(buff *bytes.Buffer, field string, elem MdataElement) {
+	buff.WriteString("<dc:" + field)
+	for _, k := range sortedKeys(elem.Attr) {
+		v := elem.Attr[k]
+		name, ok := attrName(k, elem.Attr)
+		if v == "" || !ok {
+			continue
+		}
+		switch {
+		case name == "lang":
+			name = "xml:lang"
+		case opfAttributes[name]:
+			name = "opf:" + name
+		}
+		buff.WriteString(" " + name + `="` + escapeXML(v) + `"`)
+	}
+	buff.WriteString(">" + escapeXML(elem.Content) + "</dc:" + field + ">")
+}
+
+func writeMeta(buff *bytes.Buffer, prefix string, elem MdataElement) {
+	buff.WriteString("<" + prefix + "meta")
+	if elem.Attr["property"] == "" {
+		buff.WriteString(` name="` + escapeXML(elem.Attr["name"]) + `" content="` + escapeXML(elem.Content) + `"/>`)
+		return
+	}
+	for _, k := range sortedKeys(elem.Attr) {
+		v := elem.Attr[k]
+		name, ok := attrName(k, elem.Attr)
+		if v == "" || !ok || k == "name" || k == "content" {
+			continue
+		}
+		if name == "lang" {
+			name = "xml:lang"
+		}
+		buff.WriteString(" " + name + `="` + escapeXML(v) + `"`)
+	}
+	buff.WriteString(">" + escapeXML(elem.Content) + "</" + prefix + "meta>")
+}
+
+// attrName returns the name to write the attribute k of a metadata element,
+// or false if it should not be written
+//
+// The qualified keys (like "opf:role") only give the prefix of their local
+// key, that has the value to write. The qualified keys without a local one
+// are written if they are on the opf or xml namespaces.
+func attrName(k string, attrs map[string]string) (string, bool) {
+	if i := strings.Index(k, ":"); i != -1 {
+		prefix, local := k[:i], k[i+1:]
+		if _, ok := attrs[local]; ok || (prefix != "opf" && prefix != "xml") {
+			return "", false
+		}
+		return k, true
+	}
+	for _, prefix := range []string{"opf", "xml"} {
+		if _, ok := attrs[prefix+":"+k]; ok {
+			return prefix + ":" + k, true
+		}
+	}
+	return k, true
+}
+
+// marshalManifest serializes the manifest items
+func (opf xmlOPF) marshalManifest(prefix string) string {
+	var buff bytes.Buffer
+	for _, item := range opf.Manifest {
+		buff.WriteString("\n    <" + prefix + "item")
+		writeAttrs(&buff, "id", item.ID, "href", item.Href, "media-type", item.MediaType,
+			"fallback", item.Fallback, "properties", item.Properties, "media-overlay", item.MediaOverlay)
+		buff.WriteString("/>")
+	}
+	return buff.String()
+}
+
+// marshalSpine serializes the spine itemrefs
+func (opf xmlOPF) marshalSpine(prefix string) string {
+	var buff bytes.Buffer
+	for _, item := range opf.Spine.Items {
+		buff.WriteString("\n    <" + prefix + "itemref")
+		writeAttrs(&buff, "idref", item.IDref, "linear", item.Linear, "id", item.ID, "properties", item.Properties)
+		buff.WriteString("/>")
+	}
+	return buff.String()
+}
+
+// marshalGuide serializes the guide references
+func (opf xmlOPF) marshalGuide(prefix string) string {
+	var buff bytes.Buffer
+	for _, ref := range opf.Guide {
+		buff.WriteString("\n    <" + prefix + "reference")
+		writeAttrs(&buff, "type", ref.Type, "title", ref.Title, "href", ref.Href)
+		buff.WriteString("/>")
+	}
+	return buff.String()
+}
+
+// marshalNavMap serializes the navPoints of the NCX, the playOrder follows
+// the order of the navPoints and the ones without id get a new one
+func (ncx xmlNCX) marshalNavMap(prefix string) string {
+	ids := make(map[string]bool)
+	var collect func(points []navpoint)
+	collect = func(points []navpoint) {
+		for _, point := range points {
+			ids[point.ID] = true
+			collect(point.NavPoint)
+		}
+	}
+	collect(ncx.NavMap)
+
+	var buff bytes.Buffer
+	playOrder := 0
+	var write func(points []navpoint, indent string)
+	write = func(points []navpoint, indent string) {
+		for _, point := range points {
+			playOrder++
+			id := point.ID
+			for i := playOrder; id == ""; i++ {
+				if candidate := "navpoint-" + strconv.Itoa(i); !ids[candidate] {
+					id = candidate
+					ids[id] = true
+				}
+			}
+			buff.WriteString(indent + "<" + prefix + "navPoint")
+			writeAttrs(&buff, "id", id, "playOrder", strconv.Itoa(playOrder))
+			buff.WriteString(">" + indent + "  <" + prefix + "navLabel><" + prefix + "text>" + escapeXML(point.Text) + "</" + prefix + "text></" + prefix + "navLabel>")
+			buff.WriteString(indent + "  <" + prefix + "content")
+			writeAttrs(&buff, "src", point.Content.Src)
+			buff.WriteString("/>")
+			write(point.NavPoint, indent+"  ")
+			buff.WriteString(indent + "</" + prefix + "navPoint>")
+		}
+	}
+	write(ncx.NavMap, "\n    ")
+	return buff.String()
+}
+
+// writeAttrs writes the pairs of attribute names and values, skipping the
+// empty values
+func writeAttrs(buff *bytes.Buffer, pairs ...string) {
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] != "" {
+			buff.WriteString(" " + pairs[i] + `="` + escapeXML(pairs[i+1]) + `"`)
+		}
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,59 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+const (
+	newTitle = "A Dog's Tale & Other Stories"
+)
+
+func TestSetMetadata(t *testing.T) {
+	f, _ := Open(bookPath)
+	defer f.Close()
+
+	if err := f.SetMetadata("foo", nil); err == nil {
+		t.Errorf("SetMetadata(foo) didn't return an error")
+	}
+	if err := f.SetMetadata("title", []MdataElement{{Content: newTitle}}); err != nil {
+		t.Errorf("SetMetadata(title) return an error: %v", err)
+	}
+	if err := f.SetMetadata("subject", nil); err != nil {
+		t.Errorf("SetMetadata(subject) return an error: %v", err)
+	}
+
+	book := repackBook(t, f)
+	if title, _ := book.Metadata("title"); title[0] != newTitle {
+		t.Errorf("Metadata title '%v', the expected was '%v'", title[0], newTitle)
+	}
+	if _, err := book.Metadata("subject"); err == nil {
+		t.Errorf("The subject was not removed")
+	}
+	if len(book.MetadataFields()) != lenMetadatafields-1 {
+		t.Errorf("len(MetadataFields()) should be %v, but was %v", lenMetadatafields-1, len(book.MetadataFields()))
+	}
+	if identifier, _ := book.MetadataAttr("identifier"); identifier[0]["scheme"] != identifierScheme {
+		t.Errorf("Metadata identifier attr scheme '%v', the expected was '%v'", identifier[0]["scheme"], identifierScheme)
+	}
+	if meta, _ := book.MetadataAttr("meta"); meta[0]["name"] != metaName {
+		t.Errorf("Metadata meta attr name '%v', the expected was '%v'", meta[0]["name"], metaName)
+	}
+	if _, err := book.Navigation(); err != nil {
+		t.Errorf("Navigation() return an error: %v", err)
+	}
+}
+
+func TestSetMetadataEPUB3(t *testing.T) {
+	f := buildEpub(t, epub3OPF, nil)
+	f.SetMetadata("publisher", []MdataElement{{Content: "HarperCollins"}})
+
+	book := repackBook(t, f)
+	if title := book.FullTitle(); title != epub3Full {
+		t.Errorf("FullTitle() return: %v when was expected: %v", title, epub3Full)
+	}
+	if collections := book.Collections(); len(collections) != 2 {
+		t.Errorf("Collections() return: %v", collections)
+	}
+}
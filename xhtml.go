@@ -0,0 +1,296 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+	"strings"
+)
+
+const (
+	xhtmlNamespace  = "http://www.w3.org/1999/xhtml"
+	epubNamespace   = "http://www.idpf.org/2007/ops"
+	svgNamespace    = "http://www.w3.org/2000/svg"
+	xlinkNamespace  = "http://www.w3.org/1999/xlink"
+	mathmlNamespace = "http://www.w3.org/1998/Math/MathML"
+)
+
+var (
+	xhtmlTokenRegexp = regexp.MustCompile(`(?s)<!--.*?-->|<!\[CDATA\[.*?\]\]>|<![^>]*>|<\?.*?>|</?([A-Za-z][\w:.-]*)((?:\s*[^\s"'>/=]+(?:\s*=\s*(?:"[^"]*"|'[^']*'|[^\s"'=<>` + "`" + `]+))?)*)\s*(/?)>`)
+	xhtmlAttrRegexp  = regexp.MustCompile(`([^\s"'>/=]+)(?:\s*=\s*("[^"]*"|'[^']*'|[^\s"'=<>` + "`" + `]+))?`)
+	xmlNameRegexp    = regexp.MustCompile(`^[A-Za-z_][\w.-]*(?::[A-Za-z_][\w.-]*)?$`)
+	htmlStartRegexp  = regexp.MustCompile(`(?i)<html\b([^>]*)>`)
+	headRegexp       = regexp.MustCompile(`(?is)<head\b[^>]*>(.*?)(?:</head\s*>|<body\b)`)
+	bodyRegexp       = regexp.MustCompile(`(?is)<body\b([^>]*)>(.*?)(?:</body\s*>|</html\s*>|$)`)
+	titleTagRegexp   = regexp.MustCompile(`<title\b`)
+)
+
+// attrPrefixes are the namespace prefixes of the attributes kept on the
+// XHTML, the ones declared on the documents
+var attrPrefixes = map[string]bool{
+	"xml": true, "xmlns": true, "epub": true, "xlink": true,
+}
+
+// rawTextElements are the elements which content is not markup
+var rawTextElements = map[string]bool{
+	"script": true, "style": true,
+}
+
+// impliedEnd are the elements closed by the start of each element, and the
+// elements that limit the search of the open one
+var impliedEnd = map[string]struct{ closes, scope []string }{
+	"li":     {[]string{"li"}, []string{"ul", "ol"}},
+	"dt":     {[]string{"dt", "dd"}, []string{"dl"}},
+	"dd":     {[]string{"dt", "dd"}, []string{"dl"}},
+	"tr":     {[]string{"tr", "td", "th"}, []string{"table", "thead", "tbody", "tfoot"}},
+	"td":     {[]string{"td", "th"}, []string{"tr", "table"}},
+	"th":     {[]string{"td", "th"}, []string{"tr", "table"}},
+	"thead":  {[]string{"thead", "tbody", "tfoot", "tr", "td", "th"}, []string{"table"}},
+	"tbody":  {[]string{"thead", "tbody", "tfoot", "tr", "td", "th"}, []string{"table"}},
+	"tfoot":  {[]string{"thead", "tbody", "tfoot", "tr", "td", "th"}, []string{"table"}},
+	"option": {[]string{"option"}, []string{"select", "datalist"}},
+}
+
+// closesParagraph are the elements which start closes an open paragraph
+var closesParagraph = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true,
+	"details": true, "div": true, "dl": true, "fieldset": true,
+	"figcaption": true, "figure": true, "footer": true, "form": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"header": true, "hr": true, "main": true, "menu": true, "nav": true,
+	"ol": true, "p": true, "pre": true, "section": true, "table": true,
+	"ul": true,
+}
+
+// ToXHTML converts the HTML markup into an XHTML5 content document
+//
+// The markup can be a whole document or a fragment of the body, like the
+// output of a Markdown renderer. The void elements are closed, the unclosed
+// elements are closed where HTML implies it, the stray end tags are dropped,
+// the names of the HTML elements and attributes are lowercased and the
+// attributes are quoted. The HTML entities are decoded, as XML only knows
+// the basic ones, and the markup characters on the text are escaped. The
+// XHTML, EPUB, SVG, XLink and MathML namespaces are declared and the
+// attributes with other prefixes are dropped. The title and lang are used if
+// the document doesn't have them.
+func ToXHTML(markup []byte, title, lang string) []byte {
+	head := []byte{}
+	body := markup
+	bodyAttrs := ""
+	if sub := headRegexp.FindSubmatch(markup); sub != nil {
+		head = sub[1]
+	}
+	if sub := bodyRegexp.FindSubmatch(markup); sub != nil {
+		bodyAttrs = string(sub[1])
+		body = sub[2]
+	} else if htmlStartRegexp.Match(markup) {
+		body = nil
+	}
+	if sub := htmlStartRegexp.FindSubmatch(markup); sub != nil {
+		var attrs bytes.Buffer
+		writeXHTMLAttrs(&attrs, string(sub[1]), false)
+		if l := attrValue(attrs.String(), "xml:lang"); l != "" {
+			lang = l
+		} else if l := attrValue(attrs.String(), "lang"); l != "" {
+			lang = l
+		}
+	}
+
+	var buff bytes.Buffer
+	buff.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n<!DOCTYPE html>\n")
+	buff.WriteString(`<html xmlns="` + xhtmlNamespace + `" xmlns:epub="` + epubNamespace + `"`)
+	if lang != "" {
+		buff.WriteString(` xml:lang="` + escapeAttr(lang) + `" lang="` + escapeAttr(lang) + `"`)
+	}
+	buff.WriteString(">\n<head>\n")
+	head = cleanXHTML(head)
+	if !titleTagRegexp.Match(head) {
+		buff.WriteString("<title>" + escapeHTMLChars(title) + "</title>\n")
+	}
+	buff.Write(bytes.TrimSpace(head))
+	buff.WriteString("\n</head>\n<body")
+	writeXHTMLAttrs(&buff, bodyAttrs, false)
+	buff.WriteString(">\n")
+	buff.Write(bytes.TrimSpace(cleanXHTML(body)))
+	buff.WriteString("\n</body>\n</html>\n")
+	return buff.Bytes()
+}
+
+// cleanXHTML returns the fragment of HTML markup as well formed XHTML
+func cleanXHTML(markup []byte) []byte {
+	var buff bytes.Buffer
+	var open []string
+	// foreign is the number of open svg and math elements
+	foreign := 0
+	closeTo := func(i int) {
+		for j := len(open) - 1; j >= i; j-- {
+			buff.WriteString("</" + open[j] + ">")
+			if open[j] == "svg" || open[j] == "math" {
+				foreign--
+			}
+		}
+		open = open[:i]
+	}
+	// openIndex returns the position of the innermost open element called
+	// any of names, not looking past the elements of scope
+	openIndex := func(names, scope []string) int {
+		for i := len(open) - 1; i >= 0; i-- {
+			if contains(names, open[i]) {
+				return i
+			}
+			if contains(scope, open[i]) {
+				break
+			}
+		}
+		return -1
+	}
+
+	last := 0
+	for last < len(markup) {
+		loc := xhtmlTokenRegexp.FindSubmatchIndex(markup[last:])
+		if loc == nil {
+			break
+		}
+		for i := range loc {
+			if loc[i] != -1 {
+				loc[i] += last
+			}
+		}
+		buff.WriteString(escapeHTMLChars(html.UnescapeString(string(markup[last:loc[0]]))))
+		last = loc[1]
+
+		token := string(markup[loc[0]:loc[1]])
+		switch {
+		case strings.HasPrefix(token, "<!--"):
+			comment := strings.Replace(token[4:len(token)-3], "--", "- -", -1)
+			buff.WriteString("<!--" + strings.TrimSuffix(comment, "-") + "-->")
+			continue
+		case strings.HasPrefix(token, "<![CDATA["):
+			buff.WriteString(token)
+			continue
+		case loc[2] == -1:
+			// doctypes and processing instructions
+			continue
+		}
+
+		name := string(markup[loc[2]:loc[3]])
+		if foreign == 0 {
+			name = strings.ToLower(name)
+		}
+		if !xmlNameRegexp.MatchString(name) || strings.Contains(name, ":") {
+			continue
+		}
+		closing := token[1] == '/'
+		selfClosing := loc[7] > loc[6]
+
+		if closing {
+			i := openIndex([]string{name}, nil)
+			switch {
+			case i != -1:
+				closeTo(i)
+			case name == "br":
+				buff.WriteString("<br/>")
+			case name == "p":
+				buff.WriteString("<p></p>")
+			}
+			continue
+		}
+		if name == "html" || name == "head" || name == "body" {
+			continue
+		}
+
+		if foreign == 0 {
+			if closesParagraph[name] {
+				if i := openIndex([]string{"p"}, []string{"table", "td", "th", "button"}); i != -1 {
+					closeTo(i)
+				}
+			}
+			if implied, ok := impliedEnd[name]; ok {
+				if i := openIndex(implied.closes, implied.scope); i != -1 {
+					closeTo(i)
+				}
+			}
+		}
+
+		buff.WriteString("<" + name)
+		switch name {
+		case "svg":
+			buff.WriteString(` xmlns="` + svgNamespace + `" xmlns:xlink="` + xlinkNamespace + `"`)
+		case "math":
+			buff.WriteString(` xmlns="` + mathmlNamespace + `"`)
+		}
+		writeXHTMLAttrs(&buff, string(markup[loc[4]:loc[5]]), foreign > 0 || name == "svg" || name == "math")
+
+		switch {
+		case foreign == 0 && voidElements[name], foreign > 0 && selfClosing:
+			buff.WriteString("/>")
+		case foreign == 0 && rawTextElements[name]:
+			buff.WriteString(">")
+			end := regexp.MustCompile(`(?i)</` + name + `\s*>`).FindIndex(markup[last:])
+			content := markup[last:]
+			if end != nil {
+				content = markup[last : last+end[0]]
+				last += end[1]
+			} else {
+				last = len(markup)
+			}
+			if bytes.ContainsAny(content, "<&") {
+				buff.WriteString("/*<![CDATA[*/" + strings.Replace(string(content), "]]>", "]]]]><![CDATA[>", -1) + "/*]]>*/")
+			} else {
+				buff.Write(content)
+			}
+			buff.WriteString("</" + name + ">")
+		default:
+			buff.WriteString(">")
+			open = append(open, name)
+			if name == "svg" || name == "math" {
+				foreign++
+			}
+		}
+	}
+	if last < len(markup) {
+		buff.WriteString(escapeHTMLChars(html.UnescapeString(string(markup[last:]))))
+	}
+	closeTo(0)
+	return buff.Bytes()
+}
+
+// writeXHTMLAttrs writes the HTML attributes quoted, without duplicates and
+// without the undeclared namespace prefixes. The names are lowercased unless
+// keepCase.
+func writeXHTMLAttrs(buff *bytes.Buffer, attrs string, keepCase bool) {
+	seen := make(map[string]bool)
+	for _, sub := range xhtmlAttrRegexp.FindAllStringSubmatch(attrs, -1) {
+		name := sub[1]
+		if !keepCase {
+			name = strings.ToLower(name)
+		}
+		if !xmlNameRegexp.MatchString(name) || seen[name] {
+			continue
+		}
+		if i := strings.Index(name, ":"); i != -1 && !attrPrefixes[name[:i]] {
+			continue
+		}
+		if name == "xmlns" || strings.HasPrefix(name, "xmlns:") {
+			continue
+		}
+		seen[name] = true
+
+		value := sub[2]
+		switch {
+		case value == "":
+			if strings.Contains(sub[0], "=") {
+				value = ""
+			} else {
+				value = name
+			}
+		case value[0] == '"' || value[0] == '\'':
+			value = value[1 : len(value)-1]
+		}
+		buff.WriteString(" " + name + `="` + escapeAttr(html.UnescapeString(value)) + `"`)
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"bytes"
+	"strings"
+)
+
+func TestToXHTMLFragment(t *testing.T) {
+	markup := `<P CLASS=intro>Fish &amp; chips&nbsp;&mdash; A &lt; B & C<br>
+<IMG SRC="a.png" alt=cover hidden>
+<ul><li>One<li>Two</ul>
+<p>Stray</span> end<hr>
+<o:p>Word</o:p><div id="a" id="b" epub:type=chapter onclick='x("y")'>Div</div>
+<svg viewBox="0 0 10 10"><image xlink:href="a.png" width="10" height="10"/></svg>
+<script>if (a < b && c) {}</script>
+<!-- a -- comment -->`
+	xhtml := ToXHTML([]byte(markup), "Title & more", "en")
+	var doc struct{}
+	if err := decodeXML(bytes.NewReader(xhtml), &doc); err != nil {
+		t.Fatalf("The XHTML is not well formed: %v\n%s", err, xhtml)
+	}
+
+	for _, expected := range []string{
+		`<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="en" lang="en">`,
+		`<title>Title &amp; more</title>`,
+		`<p class="intro">Fish &amp; chips` + "\u00a0\u2014" + ` A &lt; B &amp; C<br/>`,
+		`<img src="a.png" alt="cover" hidden="hidden"/>`,
+		`</p>`,
+		`<ul><li>One</li><li>Two</li></ul>`,
+		`<p>Stray end</p><hr/>`,
+		`Word<div id="a" epub:type="chapter" onclick="x(&quot;y&quot;)">Div</div>`,
+		`<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" viewBox="0 0 10 10"><image xlink:href="a.png" width="10" height="10"/></svg>`,
+		`<script>/*<![CDATA[*/if (a < b && c) {}/*]]>*/</script>`,
+		`<!-- a - - comment -->`,
+	} {
+		if !strings.Contains(string(xhtml), expected) {
+			t.Errorf("ToXHTML() doesn't contain %q:\n%s", expected, xhtml)
+		}
+	}
+}
+
+func TestToXHTMLDocument(t *testing.T) {
+	markup := `<!DOCTYPE html PUBLIC "-//W3C//DTD HTML 4.01//EN">
+<HTML LANG="fr"><HEAD><TITLE>Titre</TITLE><META charset=utf-8></HEAD>
+<BODY class=main><P>Texte</BODY></HTML>`
+	xhtml := string(ToXHTML([]byte(markup), "Other", "en"))
+	var doc struct{}
+	if err := decodeXML(strings.NewReader(xhtml), &doc); err != nil {
+		t.Fatalf("The XHTML is not well formed: %v\n%s", err, xhtml)
+	}
+	for _, expected := range []string{
+		`xml:lang="fr" lang="fr">`,
+		"<head>\n<title>Titre</title><meta charset=\"utf-8\"/>\n</head>",
+		"<body class=\"main\">\n<p>Texte</p>\n</body>",
+	} {
+		if !strings.Contains(xhtml, expected) {
+			t.Errorf("ToXHTML() doesn't contain %q:\n%s", expected, xhtml)
+		}
+	}
+	if strings.Contains(xhtml, "Other") || strings.Contains(xhtml, "DTD") {
+		t.Errorf("ToXHTML() return: %s", xhtml)
+	}
+}
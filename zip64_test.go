@@ -0,0 +1,88 @@
+// Copyright 2012 Ruben Pollan <meskio@sindominio.net>
+// Use of this source code is governed by a LGPL licence
+// version 3 or later that can be found in the LICENSE file.
+
+package epubgo
+
+import "testing"
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"strconv"
+)
+
+// zip64Entries is over the 65535 entries of the classic zip format, the
+// zip writer switches to Zip64 for the end of central directory
+const zip64Entries = 70000
+
+func buildZip64Epub(t *testing.T, entries int) []byte {
+	opf, err := ioutil.ReadFile(epub3OPF)
+	if err != nil {
+		t.Fatalf("ReadFile(%v) return an error: %v", epub3OPF, err)
+	}
+	var buff bytes.Buffer
+	w := zip.NewWriter(&buff)
+	mimetype, _ := w.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	mimetype.Write([]byte("application/epub+zip"))
+	container, _ := w.Create("META-INF/container.xml")
+	container.Write([]byte(`<?xml version="1.0"?><container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container"><rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles></container>`))
+	content, _ := w.Create("OEBPS/content.opf")
+	content.Write(opf)
+	for i := 0; i < entries; i++ {
+		f, _ := w.CreateHeader(&zip.FileHeader{Name: "OEBPS/audio/" + strconv.Itoa(i) + ".txt", Method: zip.Store})
+		f.Write([]byte(strconv.Itoa(i)))
+	}
+	w.Close()
+	return buff.Bytes()
+}
+
+func TestZip64Entries(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the Zip64 epub in short mode")
+	}
+	data := buildZip64Epub(t, zip64Entries)
+	book, err := Load(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Load() return an error: %v", err)
+	}
+	name := "audio/" + strconv.Itoa(zip64Entries-1) + ".txt"
+	f, err := book.OpenFile(name)
+	if err != nil {
+		t.Fatalf("OpenFile(%v) return an error: %v", name, err)
+	}
+	content, _ := ioutil.ReadAll(f)
+	f.Close()
+	if string(content) != strconv.Itoa(zip64Entries-1) {
+		t.Errorf("OpenFile(%v) return: %s", name, content)
+	}
+
+	var buff bytes.Buffer
+	book.SetMetadata("title", []MdataElement{{Content: "Zip64"}})
+	if err := book.Repack(&buff); err != nil {
+		t.Fatalf("Repack() return an error: %v", err)
+	}
+	repacked, err := Load(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+	if err != nil {
+		t.Fatalf("Load() of the repacked epub return an error: %v", err)
+	}
+	if len(repacked.zip.File) != zip64Entries+3 {
+		t.Errorf("Repack() wrote %v entries", len(repacked.zip.File))
+	}
+	if title, _ := repacked.Metadata("title"); title[0] != "Zip64" {
+		t.Errorf("Repack() title: %v", title)
+	}
+}
+
+func TestMaxEntries(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the huge epub in short mode")
+	}
+	data := buildZip64Epub(t, maxEntries)
+	_, err := Load(bytes.NewReader(data), int64(len(data)))
+	if !errors.Is(err, ErrMalformed) {
+		t.Errorf("Load() with %v entries return: %v", maxEntries+3, err)
+	}
+}